@@ -35,7 +35,10 @@ func main() {
 	}
 	log.Println("Migrations applied successfully")
 
-	server := api.NewServer(cfg, pool)
+	server, err := api.NewServer(cfg, pool)
+	if err != nil {
+		log.Fatalf("Failed to create server: %v", err)
+	}
 	if err := server.Start(ctx); err != nil {
 		log.Fatalf("Server error: %v", err)
 	}
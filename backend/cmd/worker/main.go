@@ -18,6 +18,10 @@ func main() {
 	configPath := flag.String("config", "config.yaml", "path to config file")
 	runImport := flag.Bool("import", false, "run INPX import and exit")
 	reloadGenres := flag.Bool("reload-genres", false, "force reload genre tree from .glst file and exit")
+	backfillTranslit := flag.Bool("backfill-translit", false, "fill missing transliteration search keys and exit")
+	migrateLocators := flag.Bool("migrate-locators", false, "convert percentage-only reading positions to paragraph locators and exit")
+	hashDocuments := flag.Bool("hash-documents", false, "record KOReader document digests of all books and exit")
+	indexContent := flag.Bool("index-content", false, "update the full-text index of book contents for enabled collections and exit")
 	flag.Parse()
 
 	cfg, err := config.Load(*configPath)
@@ -65,6 +69,62 @@ func main() {
 		return
 	}
 
+	if *backfillTranslit {
+		n, err := repository.BackfillTranslit(ctx, pool)
+		if err != nil {
+			log.Fatalf("Translit backfill failed: %v", err)
+		}
+		log.Printf("Translit backfill completed: %d rows updated", n)
+		return
+	}
+
+	if *migrateLocators {
+		bookRepo := repository.NewBookRepo(pool)
+		progressRepo := repository.NewReadingProgressRepo(pool)
+		readerCache, err := service.NewReaderCacheStore(cfg.Reader, pool)
+		if err != nil {
+			log.Fatalf("Failed to create reader cache: %v", err)
+		}
+		readerSvc := service.NewReaderService(bookRepo, cfg.Library, cfg.Reader, readerCache)
+
+		n, err := service.NewLocatorMigrationService(progressRepo, readerSvc).MigrateProgress(ctx)
+		if err != nil {
+			log.Fatalf("Locator migration failed: %v", err)
+		}
+		log.Printf("Locator migration completed: %d positions converted", n)
+		return
+	}
+
+	if *hashDocuments {
+		bookRepo := repository.NewBookRepo(pool)
+		// Backfill reads every book once; keeping them would only flush the cache
+		downloadSvc := service.NewDownloadService(bookRepo, cfg.Library, repository.NewDocumentHashRepo(pool), nil)
+
+		n, err := downloadSvc.BackfillDocumentHashes(ctx)
+		if err != nil {
+			log.Fatalf("Document hashing failed: %v", err)
+		}
+		log.Printf("Document hashing completed: %d books digested", n)
+		return
+	}
+
+	if *indexContent {
+		bookRepo := repository.NewBookRepo(pool)
+		readerCache, err := service.NewReaderCacheStore(cfg.Reader, pool)
+		if err != nil {
+			log.Fatalf("Failed to create reader cache: %v", err)
+		}
+		readerSvc := service.NewReaderService(bookRepo, cfg.Library, cfg.Reader, readerCache)
+		contentSvc := service.NewContentIndexService(repository.NewContentIndexRepo(pool), readerSvc)
+
+		n, err := contentSvc.IndexBooks(ctx)
+		if err != nil {
+			log.Fatalf("Content indexing failed: %v", err)
+		}
+		log.Printf("Content indexing completed: %d books indexed", n)
+		return
+	}
+
 	if *runImport {
 		bookRepo := repository.NewBookRepo(pool)
 		authorRepo := repository.NewAuthorRepo(pool)
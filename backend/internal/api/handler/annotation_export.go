@@ -0,0 +1,89 @@
+package handler
+
+import (
+	"errors"
+	"mime"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/grom-alex/homelib/backend/internal/service"
+)
+
+type AnnotationExportHandler struct {
+	exportSvc          AnnotationExporter
+	restrictionChecker BookRestrictionChecker
+}
+
+func NewAnnotationExportHandler(exportSvc AnnotationExporter, restrictionChecker BookRestrictionChecker) *AnnotationExportHandler {
+	return &AnnotationExportHandler{exportSvc: exportSvc, restrictionChecker: restrictionChecker}
+}
+
+// attachment sets the Content-Disposition header for a downloaded file.
+func attachment(c *gin.Context, filename string) {
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{
+		"filename": filename,
+	}))
+}
+
+// ExportBookAnnotations handles GET /api/me/books/:bookId/annotations/export.
+// Returns the book's annotations as a Markdown file.
+func (h *AnnotationExportHandler) ExportBookAnnotations(c *gin.Context) {
+	userID, bookID, ok := bookScope(c, h.restrictionChecker)
+	if !ok {
+		return
+	}
+
+	file, err := h.exportSvc.BookMarkdown(c.Request.Context(), userID, bookID)
+	if errors.Is(err, service.ErrBookNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not_found", "message": "Книга не найдена"})
+		return
+	}
+	if err != nil {
+		annotationInternalError(c)
+		return
+	}
+
+	attachment(c, file.Filename)
+	c.Data(http.StatusOK, "text/markdown; charset=utf-8", file.Data)
+}
+
+// ExportAnnotations handles GET /api/me/annotations/export?format=json|zip.
+// json returns a dump of all annotations, zip a Markdown file per book.
+func (h *AnnotationExportHandler) ExportAnnotations(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized", "message": "Пользователь не авторизован"})
+		return
+	}
+	restrictedIDs := getRestrictedGenreIDs(c)
+
+	switch c.DefaultQuery("format", "json") {
+	case "json":
+		dump, err := h.exportSvc.Dump(c.Request.Context(), userID, restrictedIDs)
+		if err != nil {
+			annotationInternalError(c)
+			return
+		}
+		attachment(c, "homelib-annotations.json")
+		c.JSON(http.StatusOK, dump)
+
+	case "zip":
+		attachment(c, "homelib-annotations.zip")
+		c.Header("Content-Type", "application/zip")
+		c.Status(http.StatusOK)
+		if err := h.exportSvc.WriteMarkdownZip(c.Request.Context(), userID, restrictedIDs, c.Writer); err != nil {
+			// Nothing is written before the annotations are collected, so
+			// most failures can still be reported as JSON.
+			if !c.Writer.Written() {
+				c.Writer.Header().Del("Content-Disposition")
+				c.Writer.Header().Del("Content-Type")
+				annotationInternalError(c)
+			}
+			return
+		}
+
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "validation_error", "message": "Неизвестный формат экспорта"})
+	}
+}
@@ -0,0 +1,152 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/grom-alex/homelib/backend/internal/models"
+	"github.com/grom-alex/homelib/backend/internal/service"
+)
+
+func TestAnnotationExportHandler_Book_Success(t *testing.T) {
+	svc := &mockAnnotationExporter{
+		bookMarkdownFn: func(_ context.Context, userID string, bookID int64) (*service.ExportFile, error) {
+			assert.Equal(t, "user-123", userID)
+			assert.Equal(t, int64(42), bookID)
+			return &service.ExportFile{Filename: "Булгаков - Мастер.md", Data: []byte("# Мастер\n")}, nil
+		},
+	}
+	h := NewAnnotationExportHandler(svc, &mockBookRestrictionChecker{})
+
+	c, w := newAnnotationContext(http.MethodGet, "/api/me/books/42/annotations/export", "",
+		gin.Params{{Key: "bookId", Value: "42"}})
+	h.ExportBookAnnotations(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "text/markdown; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Contains(t, w.Header().Get("Content-Disposition"), "attachment; filename*=utf-8''")
+	assert.Equal(t, "# Мастер\n", w.Body.String())
+}
+
+func TestAnnotationExportHandler_Book_NotFound(t *testing.T) {
+	svc := &mockAnnotationExporter{
+		bookMarkdownFn: func(_ context.Context, _ string, _ int64) (*service.ExportFile, error) {
+			return nil, service.ErrBookNotFound
+		},
+	}
+	h := NewAnnotationExportHandler(svc, &mockBookRestrictionChecker{})
+
+	c, w := newAnnotationContext(http.MethodGet, "/api/me/books/42/annotations/export", "",
+		gin.Params{{Key: "bookId", Value: "42"}})
+	h.ExportBookAnnotations(c)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestAnnotationExportHandler_Book_Restricted(t *testing.T) {
+	checker := &mockBookRestrictionChecker{
+		isBookRestrictedFn: func(_ context.Context, _ int64, _ []int) (bool, error) { return true, nil },
+	}
+	h := NewAnnotationExportHandler(&mockAnnotationExporter{}, checker)
+
+	c, w := newAnnotationContext(http.MethodGet, "/api/me/books/42/annotations/export", "",
+		gin.Params{{Key: "bookId", Value: "42"}})
+	c.Set("restricted_genre_ids", []int{5})
+	h.ExportBookAnnotations(c)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestAnnotationExportHandler_Book_Error(t *testing.T) {
+	h := NewAnnotationExportHandler(&mockAnnotationExporter{}, &mockBookRestrictionChecker{})
+
+	c, w := newAnnotationContext(http.MethodGet, "/api/me/books/42/annotations/export", "",
+		gin.Params{{Key: "bookId", Value: "42"}})
+	h.ExportBookAnnotations(c)
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+}
+
+func TestAnnotationExportHandler_JSON(t *testing.T) {
+	svc := &mockAnnotationExporter{
+		dumpFn: func(_ context.Context, userID string, ids []int) (*models.AnnotationExport, error) {
+			assert.Equal(t, "user-123", userID)
+			assert.Equal(t, []int{5}, ids)
+			return &models.AnnotationExport{
+				ExportedAt: time.Date(2026, 3, 15, 0, 0, 0, 0, time.UTC),
+				Books:      []models.AnnotatedBook{},
+			}, nil
+		},
+	}
+	h := NewAnnotationExportHandler(svc, &mockBookRestrictionChecker{})
+
+	c, w := newAnnotationContext(http.MethodGet, "/api/me/annotations/export", "", nil)
+	c.Set("restricted_genre_ids", []int{5})
+	h.ExportAnnotations(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Disposition"), "homelib-annotations.json")
+	assert.JSONEq(t, `{"exportedAt":"2026-03-15T00:00:00Z","books":[]}`, w.Body.String())
+}
+
+func TestAnnotationExportHandler_Zip(t *testing.T) {
+	svc := &mockAnnotationExporter{
+		writeMarkdownZipFn: func(_ context.Context, _ string, _ []int, w io.Writer) error {
+			_, err := w.Write([]byte("PK"))
+			return err
+		},
+	}
+	h := NewAnnotationExportHandler(svc, &mockBookRestrictionChecker{})
+
+	c, w := newAnnotationContext(http.MethodGet, "/api/me/annotations/export?format=zip", "", nil)
+	h.ExportAnnotations(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/zip", w.Header().Get("Content-Type"))
+	assert.Contains(t, w.Header().Get("Content-Disposition"), "homelib-annotations.zip")
+	assert.Equal(t, "PK", w.Body.String())
+}
+
+func TestAnnotationExportHandler_Zip_ErrorBeforeWrite(t *testing.T) {
+	svc := &mockAnnotationExporter{
+		writeMarkdownZipFn: func(_ context.Context, _ string, _ []int, _ io.Writer) error {
+			return fmt.Errorf("db error")
+		},
+	}
+	h := NewAnnotationExportHandler(svc, &mockBookRestrictionChecker{})
+
+	c, w := newAnnotationContext(http.MethodGet, "/api/me/annotations/export?format=zip", "", nil)
+	h.ExportAnnotations(c)
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Empty(t, w.Header().Get("Content-Disposition"))
+	assert.Contains(t, w.Header().Get("Content-Type"), "application/json")
+}
+
+func TestAnnotationExportHandler_UnknownFormat(t *testing.T) {
+	h := NewAnnotationExportHandler(&mockAnnotationExporter{}, &mockBookRestrictionChecker{})
+
+	c, w := newAnnotationContext(http.MethodGet, "/api/me/annotations/export?format=pdf", "", nil)
+	h.ExportAnnotations(c)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestAnnotationExportHandler_NoUser(t *testing.T) {
+	h := NewAnnotationExportHandler(&mockAnnotationExporter{}, &mockBookRestrictionChecker{})
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/api/me/annotations/export", nil)
+	h.ExportAnnotations(c)
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
@@ -0,0 +1,217 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/grom-alex/homelib/backend/internal/models"
+	"github.com/grom-alex/homelib/backend/internal/service"
+)
+
+type AnnotationsHandler struct {
+	annotationRepo     AnnotationRepository
+	restrictionChecker BookRestrictionChecker
+}
+
+func NewAnnotationsHandler(repo AnnotationRepository, restrictionChecker BookRestrictionChecker) *AnnotationsHandler {
+	return &AnnotationsHandler{annotationRepo: repo, restrictionChecker: restrictionChecker}
+}
+
+// bookScope extracts the user and the :bookId of a per-user book request and
+// applies the parental filter. Returns ok=false after writing an error response.
+func bookScope(c *gin.Context, checker BookRestrictionChecker) (userID string, bookID int64, ok bool) {
+	userID = c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized", "message": "Пользователь не авторизован"})
+		return "", 0, false
+	}
+
+	bookID, err := strconv.ParseInt(c.Param("bookId"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_id", "message": "Некорректный ID книги"})
+		return "", 0, false
+	}
+
+	if denyRestrictedBook(c, checker, bookID) {
+		return "", 0, false
+	}
+	return userID, bookID, true
+}
+
+// annotationID parses the :annotationId path parameter.
+func annotationID(c *gin.Context) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param("annotationId"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_id", "message": "Некорректный ID заметки"})
+		return 0, false
+	}
+	return id, true
+}
+
+func annotationNotFound(c *gin.Context) {
+	c.JSON(http.StatusNotFound, gin.H{"error": "not_found", "message": "Заметка не найдена"})
+}
+
+func annotationInternalError(c *gin.Context) {
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "internal_error", "message": "Внутренняя ошибка сервера"})
+}
+
+// ListAnnotations handles GET /api/me/books/:bookId/annotations.
+func (h *AnnotationsHandler) ListAnnotations(c *gin.Context) {
+	userID, bookID, ok := bookScope(c, h.restrictionChecker)
+	if !ok {
+		return
+	}
+
+	var f models.AnnotationFilter
+	if err := c.ShouldBindQuery(&f); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "validation_error", "message": "Некорректные параметры запроса"})
+		return
+	}
+
+	items, err := h.annotationRepo.ListByBook(c.Request.Context(), userID, bookID, f)
+	if err != nil {
+		annotationInternalError(c)
+		return
+	}
+
+	c.JSON(http.StatusOK, items)
+}
+
+// CreateAnnotation handles POST /api/me/books/:bookId/annotations.
+func (h *AnnotationsHandler) CreateAnnotation(c *gin.Context) {
+	userID, bookID, ok := bookScope(c, h.restrictionChecker)
+	if !ok {
+		return
+	}
+
+	var input models.CreateAnnotationInput
+	if err := c.ShouldBindJSON(&input); err != nil || input.Validate() != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "validation_error", "message": "Невалидные данные заметки"})
+		return
+	}
+
+	// Chapter IDs are also used as cache file names by the reader
+	if err := service.ValidateResourceID(input.ChapterID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_chapter", "message": "Некорректный ID главы"})
+		return
+	}
+
+	a := &models.Annotation{
+		UserID:    userID,
+		BookID:    bookID,
+		Type:      input.Type,
+		ChapterID: input.ChapterID,
+		Locator:   input.Locator,
+		Color:     input.Color,
+		Note:      input.Note,
+	}
+
+	created, err := h.annotationRepo.Create(c.Request.Context(), a)
+	if err != nil {
+		annotationInternalError(c)
+		return
+	}
+	if !created {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not_found", "message": "Книга не найдена"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, a)
+}
+
+// GetAnnotation handles GET /api/me/books/:bookId/annotations/:annotationId.
+func (h *AnnotationsHandler) GetAnnotation(c *gin.Context) {
+	userID, bookID, ok := bookScope(c, h.restrictionChecker)
+	if !ok {
+		return
+	}
+	id, ok := annotationID(c)
+	if !ok {
+		return
+	}
+
+	a, err := h.annotationRepo.Get(c.Request.Context(), userID, bookID, id)
+	if err != nil {
+		annotationInternalError(c)
+		return
+	}
+	if a == nil {
+		annotationNotFound(c)
+		return
+	}
+
+	c.JSON(http.StatusOK, a)
+}
+
+// UpdateAnnotation handles PATCH /api/me/books/:bookId/annotations/:annotationId.
+func (h *AnnotationsHandler) UpdateAnnotation(c *gin.Context) {
+	userID, bookID, ok := bookScope(c, h.restrictionChecker)
+	if !ok {
+		return
+	}
+	id, ok := annotationID(c)
+	if !ok {
+		return
+	}
+
+	var input models.UpdateAnnotationInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "validation_error", "message": "Невалидные данные заметки"})
+		return
+	}
+
+	a, err := h.annotationRepo.Get(c.Request.Context(), userID, bookID, id)
+	if err != nil {
+		annotationInternalError(c)
+		return
+	}
+	if a == nil {
+		annotationNotFound(c)
+		return
+	}
+
+	if err := input.Apply(a); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "validation_error", "message": "Невалидные данные заметки"})
+		return
+	}
+
+	updated, err := h.annotationRepo.Update(c.Request.Context(), a)
+	if err != nil {
+		annotationInternalError(c)
+		return
+	}
+	if !updated {
+		annotationNotFound(c)
+		return
+	}
+
+	c.JSON(http.StatusOK, a)
+}
+
+// DeleteAnnotation handles DELETE /api/me/books/:bookId/annotations/:annotationId.
+func (h *AnnotationsHandler) DeleteAnnotation(c *gin.Context) {
+	userID, bookID, ok := bookScope(c, h.restrictionChecker)
+	if !ok {
+		return
+	}
+	id, ok := annotationID(c)
+	if !ok {
+		return
+	}
+
+	deleted, err := h.annotationRepo.Delete(c.Request.Context(), userID, bookID, id)
+	if err != nil {
+		annotationInternalError(c)
+		return
+	}
+	if !deleted {
+		annotationNotFound(c)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+	c.Writer.WriteHeaderNow()
+}
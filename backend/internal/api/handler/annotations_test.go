@@ -0,0 +1,395 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grom-alex/homelib/backend/internal/models"
+)
+
+func newAnnotationContext(method, target, body string, params gin.Params) (*gin.Context, *httptest.ResponseRecorder) {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	var req *http.Request
+	if body != "" {
+		req = httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+	} else {
+		req = httptest.NewRequest(method, target, nil)
+	}
+	c.Request = req
+	c.Params = params
+	c.Set("user_id", "user-123")
+	return c, w
+}
+
+func sampleAnnotation() *models.Annotation {
+	return &models.Annotation{
+		ID:        7,
+		UserID:    "user-123",
+		BookID:    42,
+		Type:      models.AnnotationHighlight,
+		ChapterID: "ch3",
+		Locator:   models.TextLocator{Start: models.Locator{Offset: 10}, End: models.Locator{Offset: 25}, Quote: "Мастер и Маргарита"},
+		Color:     "yellow",
+		CreatedAt: time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC),
+		UpdatedAt: time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC),
+	}
+}
+
+// --- ListAnnotations ---
+
+func TestAnnotationsHandler_List_Success(t *testing.T) {
+	repo := &mockAnnotationRepo{
+		listByBookFn: func(_ context.Context, userID string, bookID int64, f models.AnnotationFilter) ([]models.Annotation, error) {
+			assert.Equal(t, "user-123", userID)
+			assert.Equal(t, int64(42), bookID)
+			assert.Equal(t, "ch3", f.ChapterID)
+			assert.Equal(t, "highlight", f.Type)
+			return []models.Annotation{*sampleAnnotation()}, nil
+		},
+	}
+	h := NewAnnotationsHandler(repo, &mockBookRestrictionChecker{})
+
+	c, w := newAnnotationContext(http.MethodGet, "/api/me/books/42/annotations?chapterId=ch3&type=highlight", "",
+		gin.Params{{Key: "bookId", Value: "42"}})
+	h.ListAnnotations(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var resp []map[string]any
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Len(t, resp, 1)
+	assert.Equal(t, "highlight", resp[0]["type"])
+	assert.Equal(t, "ch3", resp[0]["chapterId"])
+	assert.Equal(t, "Мастер и Маргарита", resp[0]["locator"].(map[string]any)["quote"])
+	assert.NotContains(t, resp[0], "userId")
+}
+
+func TestAnnotationsHandler_List_InvalidType(t *testing.T) {
+	h := NewAnnotationsHandler(&mockAnnotationRepo{}, &mockBookRestrictionChecker{})
+
+	c, w := newAnnotationContext(http.MethodGet, "/api/me/books/42/annotations?type=scribble", "",
+		gin.Params{{Key: "bookId", Value: "42"}})
+	h.ListAnnotations(c)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestAnnotationsHandler_List_NoUser(t *testing.T) {
+	h := NewAnnotationsHandler(&mockAnnotationRepo{}, &mockBookRestrictionChecker{})
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/api/me/books/42/annotations", nil)
+	c.Params = gin.Params{{Key: "bookId", Value: "42"}}
+	h.ListAnnotations(c)
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestAnnotationsHandler_List_InvalidBookID(t *testing.T) {
+	h := NewAnnotationsHandler(&mockAnnotationRepo{}, &mockBookRestrictionChecker{})
+
+	c, w := newAnnotationContext(http.MethodGet, "/api/me/books/abc/annotations", "",
+		gin.Params{{Key: "bookId", Value: "abc"}})
+	h.ListAnnotations(c)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestAnnotationsHandler_List_Restricted(t *testing.T) {
+	checker := &mockBookRestrictionChecker{
+		isBookRestrictedFn: func(_ context.Context, bookID int64, ids []int) (bool, error) {
+			assert.Equal(t, int64(42), bookID)
+			assert.Equal(t, []int{5}, ids)
+			return true, nil
+		},
+	}
+	h := NewAnnotationsHandler(&mockAnnotationRepo{}, checker)
+
+	c, w := newAnnotationContext(http.MethodGet, "/api/me/books/42/annotations", "",
+		gin.Params{{Key: "bookId", Value: "42"}})
+	c.Set("restricted_genre_ids", []int{5})
+	h.ListAnnotations(c)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestAnnotationsHandler_List_RestrictionCheckFails(t *testing.T) {
+	checker := &mockBookRestrictionChecker{
+		isBookRestrictedFn: func(_ context.Context, _ int64, _ []int) (bool, error) {
+			return false, fmt.Errorf("db error")
+		},
+	}
+	h := NewAnnotationsHandler(&mockAnnotationRepo{}, checker)
+
+	c, w := newAnnotationContext(http.MethodGet, "/api/me/books/42/annotations", "",
+		gin.Params{{Key: "bookId", Value: "42"}})
+	c.Set("restricted_genre_ids", []int{5})
+	h.ListAnnotations(c)
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+}
+
+func TestAnnotationsHandler_List_DBError(t *testing.T) {
+	repo := &mockAnnotationRepo{
+		listByBookFn: func(_ context.Context, _ string, _ int64, _ models.AnnotationFilter) ([]models.Annotation, error) {
+			return nil, fmt.Errorf("db error")
+		},
+	}
+	h := NewAnnotationsHandler(repo, &mockBookRestrictionChecker{})
+
+	c, w := newAnnotationContext(http.MethodGet, "/api/me/books/42/annotations", "",
+		gin.Params{{Key: "bookId", Value: "42"}})
+	h.ListAnnotations(c)
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+}
+
+// --- CreateAnnotation ---
+
+func TestAnnotationsHandler_Create_Success(t *testing.T) {
+	repo := &mockAnnotationRepo{
+		createFn: func(_ context.Context, a *models.Annotation) (bool, error) {
+			assert.Equal(t, "user-123", a.UserID)
+			assert.Equal(t, int64(42), a.BookID)
+			assert.Equal(t, models.AnnotationNote, a.Type)
+			assert.Equal(t, "ch3", a.ChapterID)
+			assert.Equal(t, 10, a.Locator.Start.Offset)
+			assert.Equal(t, 25, a.Locator.End.Offset)
+			assert.Equal(t, "Важно", a.Note)
+			a.ID = 99
+			return true, nil
+		},
+	}
+	h := NewAnnotationsHandler(repo, &mockBookRestrictionChecker{})
+
+	body := `{"type":"note","chapterId":"ch3","locator":{"start":{"paragraph":0,"offset":10},"end":{"paragraph":0,"offset":25},"quote":"цитата"},"color":"green","note":"Важно"}`
+	c, w := newAnnotationContext(http.MethodPost, "/api/me/books/42/annotations", body,
+		gin.Params{{Key: "bookId", Value: "42"}})
+	h.CreateAnnotation(c)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var resp models.Annotation
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, int64(99), resp.ID)
+	assert.Equal(t, "green", resp.Color)
+}
+
+func TestAnnotationsHandler_Create_Bookmark(t *testing.T) {
+	repo := &mockAnnotationRepo{
+		createFn: func(_ context.Context, _ *models.Annotation) (bool, error) { return true, nil },
+	}
+	h := NewAnnotationsHandler(repo, &mockBookRestrictionChecker{})
+
+	body := `{"type":"bookmark","chapterId":"ch1","locator":{"start":{"paragraph":0,"offset":120},"end":{"paragraph":0,"offset":120}}}`
+	c, w := newAnnotationContext(http.MethodPost, "/api/me/books/42/annotations", body,
+		gin.Params{{Key: "bookId", Value: "42"}})
+	h.CreateAnnotation(c)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+}
+
+func TestAnnotationsHandler_Create_ValidationErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		code int
+	}{
+		{"invalid json", `{`, http.StatusBadRequest},
+		{"missing type", `{"chapterId":"ch1"}`, http.StatusBadRequest},
+		{"unknown type", `{"type":"scribble","chapterId":"ch1"}`, http.StatusBadRequest},
+		{"missing chapter", `{"type":"bookmark"}`, http.StatusBadRequest},
+		{"unknown color", `{"type":"bookmark","chapterId":"ch1","color":"black"}`, http.StatusBadRequest},
+		{"highlight without range", `{"type":"highlight","chapterId":"ch1","locator":{"start":{"paragraph":0,"offset":5},"end":{"paragraph":0,"offset":5},"quote":"x"}}`, http.StatusBadRequest},
+		{"highlight without quote", `{"type":"highlight","chapterId":"ch1","locator":{"start":{"paragraph":0,"offset":5},"end":{"paragraph":0,"offset":9}}}`, http.StatusBadRequest},
+		{"note without text", `{"type":"note","chapterId":"ch1","locator":{"start":{"paragraph":0,"offset":5},"end":{"paragraph":0,"offset":9},"quote":"x"}}`, http.StatusBadRequest},
+		{"reversed range", `{"type":"bookmark","chapterId":"ch1","locator":{"start":{"paragraph":0,"offset":9},"end":{"paragraph":0,"offset":5}}}`, http.StatusBadRequest},
+		{"negative offset", `{"type":"bookmark","chapterId":"ch1","locator":{"start":{"paragraph":0,"offset":-1},"end":{"paragraph":0,"offset":5}}}`, http.StatusBadRequest},
+		{"reversed paragraphs", `{"type":"bookmark","chapterId":"ch1","locator":{"start":{"paragraph":3,"offset":0},"end":{"paragraph":2,"offset":50}}}`, http.StatusBadRequest},
+		{"negative paragraph", `{"type":"bookmark","chapterId":"ch1","locator":{"start":{"paragraph":-1,"offset":0},"end":{"paragraph":0,"offset":0}}}`, http.StatusBadRequest},
+		{"unsafe chapter id", `{"type":"bookmark","chapterId":"../etc"}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewAnnotationsHandler(&mockAnnotationRepo{}, &mockBookRestrictionChecker{})
+			c, w := newAnnotationContext(http.MethodPost, "/api/me/books/42/annotations", tt.body,
+				gin.Params{{Key: "bookId", Value: "42"}})
+			h.CreateAnnotation(c)
+			assert.Equal(t, tt.code, w.Code)
+		})
+	}
+}
+
+func TestAnnotationsHandler_Create_SpansParagraphs(t *testing.T) {
+	repo := &mockAnnotationRepo{
+		createFn: func(_ context.Context, a *models.Annotation) (bool, error) {
+			assert.Equal(t, models.Locator{Paragraph: 4, Offset: 120}, a.Locator.Start)
+			assert.Equal(t, models.Locator{Paragraph: 5, Offset: 3}, a.Locator.End)
+			return true, nil
+		},
+	}
+	h := NewAnnotationsHandler(repo, &mockBookRestrictionChecker{})
+
+	body := `{"type":"highlight","chapterId":"ch1","locator":{"start":{"paragraph":4,"offset":120},"end":{"paragraph":5,"offset":3},"quote":"конец. Нача"}}`
+	c, w := newAnnotationContext(http.MethodPost, "/api/me/books/42/annotations", body,
+		gin.Params{{Key: "bookId", Value: "42"}})
+	h.CreateAnnotation(c)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+}
+
+func TestAnnotationsHandler_Create_BookNotFound(t *testing.T) {
+	repo := &mockAnnotationRepo{
+		createFn: func(_ context.Context, _ *models.Annotation) (bool, error) { return false, nil },
+	}
+	h := NewAnnotationsHandler(repo, &mockBookRestrictionChecker{})
+
+	c, w := newAnnotationContext(http.MethodPost, "/api/me/books/999/annotations",
+		`{"type":"bookmark","chapterId":"ch1"}`, gin.Params{{Key: "bookId", Value: "999"}})
+	h.CreateAnnotation(c)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestAnnotationsHandler_Create_Restricted(t *testing.T) {
+	checker := &mockBookRestrictionChecker{
+		isBookRestrictedFn: func(_ context.Context, _ int64, _ []int) (bool, error) { return true, nil },
+	}
+	h := NewAnnotationsHandler(&mockAnnotationRepo{}, checker)
+
+	c, w := newAnnotationContext(http.MethodPost, "/api/me/books/42/annotations",
+		`{"type":"bookmark","chapterId":"ch1"}`, gin.Params{{Key: "bookId", Value: "42"}})
+	c.Set("restricted_genre_ids", []int{5})
+	h.CreateAnnotation(c)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+// --- GetAnnotation ---
+
+func TestAnnotationsHandler_Get(t *testing.T) {
+	repo := &mockAnnotationRepo{
+		getFn: func(_ context.Context, userID string, bookID, id int64) (*models.Annotation, error) {
+			if id == 7 && userID == "user-123" && bookID == 42 {
+				return sampleAnnotation(), nil
+			}
+			return nil, nil
+		},
+	}
+	h := NewAnnotationsHandler(repo, &mockBookRestrictionChecker{})
+
+	c, w := newAnnotationContext(http.MethodGet, "/api/me/books/42/annotations/7", "",
+		gin.Params{{Key: "bookId", Value: "42"}, {Key: "annotationId", Value: "7"}})
+	h.GetAnnotation(c)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	c, w = newAnnotationContext(http.MethodGet, "/api/me/books/42/annotations/8", "",
+		gin.Params{{Key: "bookId", Value: "42"}, {Key: "annotationId", Value: "8"}})
+	h.GetAnnotation(c)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	c, w = newAnnotationContext(http.MethodGet, "/api/me/books/42/annotations/x", "",
+		gin.Params{{Key: "bookId", Value: "42"}, {Key: "annotationId", Value: "x"}})
+	h.GetAnnotation(c)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+// --- UpdateAnnotation ---
+
+func TestAnnotationsHandler_Update_Success(t *testing.T) {
+	repo := &mockAnnotationRepo{
+		getFn: func(_ context.Context, _ string, _, _ int64) (*models.Annotation, error) {
+			return sampleAnnotation(), nil
+		},
+		updateFn: func(_ context.Context, a *models.Annotation) (bool, error) {
+			assert.Equal(t, "blue", a.Color)
+			assert.Equal(t, "Перечитать", a.Note)
+			// Locator is kept when not supplied
+			assert.Equal(t, 10, a.Locator.Start.Offset)
+			return true, nil
+		},
+	}
+	h := NewAnnotationsHandler(repo, &mockBookRestrictionChecker{})
+
+	c, w := newAnnotationContext(http.MethodPatch, "/api/me/books/42/annotations/7",
+		`{"color":"blue","note":"Перечитать"}`,
+		gin.Params{{Key: "bookId", Value: "42"}, {Key: "annotationId", Value: "7"}})
+	h.UpdateAnnotation(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestAnnotationsHandler_Update_InvalidLocator(t *testing.T) {
+	repo := &mockAnnotationRepo{
+		getFn: func(_ context.Context, _ string, _, _ int64) (*models.Annotation, error) {
+			return sampleAnnotation(), nil
+		},
+	}
+	h := NewAnnotationsHandler(repo, &mockBookRestrictionChecker{})
+
+	c, w := newAnnotationContext(http.MethodPatch, "/api/me/books/42/annotations/7",
+		`{"locator":{"start":{"paragraph":0,"offset":10},"end":{"paragraph":0,"offset":10}}}`,
+		gin.Params{{Key: "bookId", Value: "42"}, {Key: "annotationId", Value: "7"}})
+	h.UpdateAnnotation(c)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestAnnotationsHandler_Update_NotFound(t *testing.T) {
+	repo := &mockAnnotationRepo{
+		getFn: func(_ context.Context, _ string, _, _ int64) (*models.Annotation, error) { return nil, nil },
+	}
+	h := NewAnnotationsHandler(repo, &mockBookRestrictionChecker{})
+
+	c, w := newAnnotationContext(http.MethodPatch, "/api/me/books/42/annotations/7", `{"color":"blue"}`,
+		gin.Params{{Key: "bookId", Value: "42"}, {Key: "annotationId", Value: "7"}})
+	h.UpdateAnnotation(c)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+// --- DeleteAnnotation ---
+
+func TestAnnotationsHandler_Delete(t *testing.T) {
+	repo := &mockAnnotationRepo{
+		deleteFn: func(_ context.Context, userID string, bookID, id int64) (bool, error) {
+			return id == 7, nil
+		},
+	}
+	h := NewAnnotationsHandler(repo, &mockBookRestrictionChecker{})
+
+	c, w := newAnnotationContext(http.MethodDelete, "/api/me/books/42/annotations/7", "",
+		gin.Params{{Key: "bookId", Value: "42"}, {Key: "annotationId", Value: "7"}})
+	h.DeleteAnnotation(c)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	c, w = newAnnotationContext(http.MethodDelete, "/api/me/books/42/annotations/8", "",
+		gin.Params{{Key: "bookId", Value: "42"}, {Key: "annotationId", Value: "8"}})
+	h.DeleteAnnotation(c)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestAnnotationsHandler_Delete_DBError(t *testing.T) {
+	repo := &mockAnnotationRepo{
+		deleteFn: func(_ context.Context, _ string, _, _ int64) (bool, error) {
+			return false, fmt.Errorf("db error")
+		},
+	}
+	h := NewAnnotationsHandler(repo, &mockBookRestrictionChecker{})
+
+	c, w := newAnnotationContext(http.MethodDelete, "/api/me/books/42/annotations/7", "",
+		gin.Params{{Key: "bookId", Value: "42"}, {Key: "annotationId", Value: "7"}})
+	h.DeleteAnnotation(c)
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+}
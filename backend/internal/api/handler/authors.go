@@ -1,10 +1,13 @@
 package handler
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
+
+	"github.com/grom-alex/homelib/backend/internal/models"
 )
 
 type AuthorsHandler struct {
@@ -17,22 +20,24 @@ func NewAuthorsHandler(catalogSvc CatalogServicer) *AuthorsHandler {
 
 // ListAuthors handles GET /api/authors.
 func (h *AuthorsHandler) ListAuthors(c *gin.Context) {
-	query := c.Query("q")
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	var f models.ListFilter
+	if err := c.ShouldBindQuery(&f); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid query parameters"})
+		return
+	}
+	f.SetDefaults()
 
-	authors, total, err := h.catalogSvc.ListAuthors(c.Request.Context(), query, page, limit)
+	authors, info, err := h.catalogSvc.ListAuthors(c.Request.Context(), f)
 	if err != nil {
+		if errors.Is(err, models.ErrInvalidCursor) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid cursor"})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list authors"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"items": authors,
-		"total": total,
-		"page":  page,
-		"limit": limit,
-	})
+	c.JSON(http.StatusOK, listResponse(authors, info, f.Page, f.Limit))
 }
 
 // GetAuthor handles GET /api/authors/:id.
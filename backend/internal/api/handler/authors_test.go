@@ -17,11 +17,11 @@ import (
 
 func TestAuthorsHandler_ListAuthors_Success(t *testing.T) {
 	svc := &mockCatalogService{
-		listAuthorsFn: func(_ context.Context, query string, page, limit int) ([]models.AuthorListItem, int, error) {
-			assert.Equal(t, "tolkien", query)
+		listAuthorsFn: func(_ context.Context, f models.ListFilter) ([]models.AuthorListItem, models.PageInfo, error) {
+			assert.Equal(t, "tolkien", f.Query)
 			return []models.AuthorListItem{
 				{ID: 1, Name: "J.R.R. Tolkien", BooksCount: 12},
-			}, 1, nil
+			}, models.PageInfo{Total: 1}, nil
 		},
 	}
 	h := NewAuthorsHandler(svc)
@@ -42,8 +42,8 @@ func TestAuthorsHandler_ListAuthors_Success(t *testing.T) {
 
 func TestAuthorsHandler_ListAuthors_Error(t *testing.T) {
 	svc := &mockCatalogService{
-		listAuthorsFn: func(_ context.Context, _ string, _, _ int) ([]models.AuthorListItem, int, error) {
-			return nil, 0, fmt.Errorf("db error")
+		listAuthorsFn: func(_ context.Context, _ models.ListFilter) ([]models.AuthorListItem, models.PageInfo, error) {
+			return nil, models.PageInfo{}, fmt.Errorf("db error")
 		},
 	}
 	h := NewAuthorsHandler(svc)
@@ -117,3 +117,41 @@ func TestAuthorsHandler_GetAuthor_NotFound(t *testing.T) {
 
 	assert.Equal(t, http.StatusNotFound, w.Code)
 }
+
+func TestAuthorsHandler_ListAuthors_Cursor(t *testing.T) {
+	svc := &mockCatalogService{
+		listAuthorsFn: func(_ context.Context, f models.ListFilter) ([]models.AuthorListItem, models.PageInfo, error) {
+			assert.Equal(t, "cur", f.Cursor)
+			return []models.AuthorListItem{{ID: 2, Name: "B"}}, models.PageInfo{Total: 10, NextCursor: "cur2"}, nil
+		},
+	}
+	h := NewAuthorsHandler(svc)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/api/authors?cursor=cur", nil)
+
+	h.ListAuthors(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var resp map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "cur2", resp["next_cursor"])
+}
+
+func TestAuthorsHandler_ListAuthors_InvalidCursor(t *testing.T) {
+	svc := &mockCatalogService{
+		listAuthorsFn: func(_ context.Context, _ models.ListFilter) ([]models.AuthorListItem, models.PageInfo, error) {
+			return nil, models.PageInfo{}, models.ErrInvalidCursor
+		},
+	}
+	h := NewAuthorsHandler(svc)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/api/authors?cursor=bad", nil)
+
+	h.ListAuthors(c)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
@@ -1,12 +1,14 @@
 package handler
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
 
 	"github.com/grom-alex/homelib/backend/internal/models"
+	"github.com/grom-alex/homelib/backend/internal/searchquery"
 )
 
 type BooksHandler struct {
@@ -26,21 +28,43 @@ func (h *BooksHandler) ListBooks(c *gin.Context) {
 		return
 	}
 
+	// Advanced syntax: qualifiers in q are merged into the filter
+	if f.Syntax == "advanced" {
+		if err := searchquery.Apply(&f); err != nil {
+			var qerr *searchquery.Error
+			if errors.As(err, &qerr) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid search query", "message": qerr.Msg, "position": qerr.Pos})
+				return
+			}
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid search query"})
+			return
+		}
+	}
+
+	// Deleted books are visible to admins only. Admins see them by default,
+	// as the catalog always listed them, and hide them with include_deleted=false.
+	if c.GetString("user_role") != "admin" {
+		f.IncludeDeleted = false
+	} else if _, set := c.GetQuery("include_deleted"); !set {
+		f.IncludeDeleted = true
+	}
+
 	// Apply parental content filter
 	f.ExcludeGenreIDs = getRestrictedGenreIDs(c)
+	// Shelf filters see the caller's shelves only
+	f.UserID = c.GetString("user_id")
 
-	books, total, err := h.catalogSvc.ListBooks(c.Request.Context(), f)
+	books, info, err := h.catalogSvc.ListBooks(c.Request.Context(), f)
 	if err != nil {
+		if errors.Is(err, models.ErrInvalidCursor) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid cursor"})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list books"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"items": books,
-		"total": total,
-		"page":  f.Page,
-		"limit": f.Limit,
-	})
+	c.JSON(http.StatusOK, listResponse(books, info, f.Page, f.Limit))
 }
 
 // GetBook handles GET /api/books/:id.
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"testing"
 
 	"github.com/gin-gonic/gin"
@@ -18,11 +19,11 @@ import (
 
 func TestBooksHandler_ListBooks_Success(t *testing.T) {
 	svc := &mockCatalogService{
-		listBooksFn: func(_ context.Context, f models.BookFilter) ([]models.BookListItem, int, error) {
+		listBooksFn: func(_ context.Context, f models.BookFilter) ([]models.BookListItem, models.PageInfo, error) {
 			return []models.BookListItem{
 				{ID: 1, Title: "Book 1", Format: "fb2"},
 				{ID: 2, Title: "Book 2", Format: "epub"},
-			}, 2, nil
+			}, models.PageInfo{Total: 2}, nil
 		},
 	}
 	h := NewBooksHandler(svc, &mockBookRestrictionChecker{})
@@ -55,8 +56,8 @@ func TestBooksHandler_ListBooks_BadParams(t *testing.T) {
 
 func TestBooksHandler_ListBooks_ServiceError(t *testing.T) {
 	svc := &mockCatalogService{
-		listBooksFn: func(_ context.Context, _ models.BookFilter) ([]models.BookListItem, int, error) {
-			return nil, 0, fmt.Errorf("db error")
+		listBooksFn: func(_ context.Context, _ models.BookFilter) ([]models.BookListItem, models.PageInfo, error) {
+			return nil, models.PageInfo{}, fmt.Errorf("db error")
 		},
 	}
 	h := NewBooksHandler(svc, &mockBookRestrictionChecker{})
@@ -132,12 +133,11 @@ func TestBooksHandler_ListBooks_GenreIDFilter(t *testing.T) {
 	// The cascading behavior (parent genre includes descendants) is handled
 	// at the repository SQL level via materialized path queries.
 	svc := &mockCatalogService{
-		listBooksFn: func(_ context.Context, f models.BookFilter) ([]models.BookListItem, int, error) {
-			assert.NotNil(t, f.GenreID, "GenreID filter should be set")
-			assert.Equal(t, 42, *f.GenreID)
+		listBooksFn: func(_ context.Context, f models.BookFilter) ([]models.BookListItem, models.PageInfo, error) {
+			assert.Equal(t, []int{42}, f.GenreIDs, "GenreIDs filter should be set")
 			return []models.BookListItem{
 				{ID: 1, Title: "Sci-Fi Book", Format: "fb2"},
-			}, 1, nil
+			}, models.PageInfo{Total: 1}, nil
 		},
 	}
 	h := NewBooksHandler(svc, &mockBookRestrictionChecker{})
@@ -154,6 +154,40 @@ func TestBooksHandler_ListBooks_GenreIDFilter(t *testing.T) {
 	assert.Equal(t, float64(1), resp["total"])
 }
 
+func TestBooksHandler_ListBooks_ShelfFilter(t *testing.T) {
+	svc := &mockCatalogService{
+		listBooksFn: func(_ context.Context, f models.BookFilter) ([]models.BookListItem, models.PageInfo, error) {
+			require.NotNil(t, f.ShelfID)
+			assert.Equal(t, int64(5), *f.ShelfID)
+			assert.Equal(t, "finished", f.Shelf)
+			assert.Equal(t, "user-123", f.UserID)
+			return nil, models.PageInfo{}, nil
+		},
+	}
+	h := NewBooksHandler(svc, &mockBookRestrictionChecker{})
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/api/books?shelf_id=5&shelf=finished", nil)
+	c.Set("user_id", "user-123")
+
+	h.ListBooks(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestBooksHandler_ListBooks_UnknownShelfStatus(t *testing.T) {
+	h := NewBooksHandler(&mockCatalogService{}, &mockBookRestrictionChecker{})
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/api/books?shelf=favourites", nil)
+
+	h.ListBooks(c)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
 func TestBooksHandler_GetStats_Success(t *testing.T) {
 	svc := &mockCatalogService{
 		getStatsFn: func(_ context.Context) (*service.Stats, error) {
@@ -197,3 +231,210 @@ func TestBooksHandler_GetStats_Error(t *testing.T) {
 
 	assert.Equal(t, http.StatusInternalServerError, w.Code)
 }
+
+func TestBooksHandler_ListBooks_CursorMode(t *testing.T) {
+	svc := &mockCatalogService{
+		listBooksFn: func(_ context.Context, f models.BookFilter) ([]models.BookListItem, models.PageInfo, error) {
+			assert.Equal(t, "abc", f.Cursor)
+			assert.Equal(t, "estimate", f.Count)
+			return []models.BookListItem{{ID: 3, Title: "Book 3"}},
+				models.PageInfo{Total: 1000, TotalEstimated: true, NextCursor: "next"}, nil
+		},
+	}
+	h := NewBooksHandler(svc, &mockBookRestrictionChecker{})
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/api/books?cursor=abc&count=estimate&limit=1", nil)
+
+	h.ListBooks(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var resp map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, float64(1000), resp["total"])
+	assert.Equal(t, true, resp["total_estimated"])
+	assert.Equal(t, "next", resp["next_cursor"])
+}
+
+func TestBooksHandler_ListBooks_CountNone(t *testing.T) {
+	svc := &mockCatalogService{
+		listBooksFn: func(_ context.Context, _ models.BookFilter) ([]models.BookListItem, models.PageInfo, error) {
+			return []models.BookListItem{}, models.PageInfo{Total: -1}, nil
+		},
+	}
+	h := NewBooksHandler(svc, &mockBookRestrictionChecker{})
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/api/books?count=none", nil)
+
+	h.ListBooks(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var resp map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.NotContains(t, resp, "total")
+	assert.NotContains(t, resp, "next_cursor")
+}
+
+func TestBooksHandler_ListBooks_InvalidCursor(t *testing.T) {
+	svc := &mockCatalogService{
+		listBooksFn: func(_ context.Context, _ models.BookFilter) ([]models.BookListItem, models.PageInfo, error) {
+			return nil, models.PageInfo{}, fmt.Errorf("%w: issued for sort year asc", models.ErrInvalidCursor)
+		},
+	}
+	h := NewBooksHandler(svc, &mockBookRestrictionChecker{})
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/api/books?cursor=zzz", nil)
+
+	h.ListBooks(c)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestBooksHandler_ListBooks_MultiValueAndRanges(t *testing.T) {
+	svc := &mockCatalogService{
+		listBooksFn: func(_ context.Context, f models.BookFilter) ([]models.BookListItem, models.PageInfo, error) {
+			assert.Equal(t, []int{1, 2}, f.GenreIDs)
+			assert.Equal(t, []int{9}, f.NotGenreIDs)
+			assert.Equal(t, []string{"ru", "en"}, f.Langs)
+			assert.Equal(t, []string{"fb2"}, f.Formats)
+			assert.Equal(t, []string{"космос"}, f.Keywords)
+			require.NotNil(t, f.YearFrom)
+			assert.Equal(t, 1970, *f.YearFrom)
+			require.NotNil(t, f.LibRateMin)
+			assert.Equal(t, 4, *f.LibRateMin)
+			require.NotNil(t, f.DateAddedFrom)
+			assert.Equal(t, "2020-01-31", f.DateAddedFrom.Format("2006-01-02"))
+			assert.Equal(t, "a", f.SeriesType)
+			return []models.BookListItem{}, models.PageInfo{}, nil
+		},
+	}
+	h := NewBooksHandler(svc, &mockBookRestrictionChecker{})
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet,
+		"/api/books?genre_id=1&genre_id=2&not_genre_id=9&lang=ru&lang=en&format=fb2"+
+			"&keyword=%D0%BA%D0%BE%D1%81%D0%BC%D0%BE%D1%81&year_from=1970&lib_rate_min=4"+
+			"&date_added_from=2020-01-31&series_type=a", nil)
+
+	h.ListBooks(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestBooksHandler_ListBooks_InvalidSeriesType(t *testing.T) {
+	h := NewBooksHandler(nil, &mockBookRestrictionChecker{})
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/api/books?series_type=x", nil)
+
+	h.ListBooks(c)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestBooksHandler_ListBooks_IncludeDeleted_AdminOnly(t *testing.T) {
+	var got bool
+	svc := &mockCatalogService{
+		listBooksFn: func(_ context.Context, f models.BookFilter) ([]models.BookListItem, models.PageInfo, error) {
+			got = f.IncludeDeleted
+			return []models.BookListItem{}, models.PageInfo{}, nil
+		},
+	}
+	h := NewBooksHandler(svc, &mockBookRestrictionChecker{})
+
+	for _, tc := range []struct {
+		role  string
+		query string
+		want  bool
+	}{
+		{"user", "?include_deleted=true", false},
+		{"user", "", false},
+		{"admin", "?include_deleted=true", true},
+		// Admins see deleted books unless they hide them
+		{"admin", "", true},
+		{"admin", "?include_deleted=false", false},
+	} {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/api/books"+tc.query, nil)
+		c.Set("user_role", tc.role)
+
+		h.ListBooks(c)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, tc.want, got, "role %s%s", tc.role, tc.query)
+	}
+}
+
+func TestBooksHandler_ListBooks_AdvancedSyntax(t *testing.T) {
+	svc := &mockCatalogService{
+		listBooksFn: func(_ context.Context, f models.BookFilter) ([]models.BookListItem, models.PageInfo, error) {
+			assert.Equal(t, "стругацкие", f.AuthorName)
+			assert.Equal(t, []string{"sf_horror"}, f.NotGenreCodes)
+			require.NotNil(t, f.YearFrom)
+			assert.Equal(t, 1971, *f.YearFrom)
+			assert.Equal(t, "пикник", f.Query)
+			return []models.BookListItem{}, models.PageInfo{}, nil
+		},
+	}
+	h := NewBooksHandler(svc, &mockBookRestrictionChecker{})
+
+	q := url.Values{}
+	q.Set("q", "author:стругацкие year:>1970 -genre:sf_horror пикник")
+	q.Set("syntax", "advanced")
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/api/books?"+q.Encode(), nil)
+
+	h.ListBooks(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestBooksHandler_ListBooks_AdvancedSyntaxError(t *testing.T) {
+	h := NewBooksHandler(nil, &mockBookRestrictionChecker{})
+
+	q := url.Values{}
+	q.Set("q", "пикник year:abc")
+	q.Set("syntax", "advanced")
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/api/books?"+q.Encode(), nil)
+
+	h.ListBooks(c)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	var resp map[string]any
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "invalid search query", resp["error"])
+	assert.Contains(t, resp["message"], "invalid year value")
+	assert.Equal(t, float64(12), resp["position"])
+}
+
+func TestBooksHandler_ListBooks_PlainSyntaxKeepsQuery(t *testing.T) {
+	svc := &mockCatalogService{
+		listBooksFn: func(_ context.Context, f models.BookFilter) ([]models.BookListItem, models.PageInfo, error) {
+			assert.Equal(t, "author:x", f.Query)
+			assert.Empty(t, f.AuthorName)
+			return []models.BookListItem{}, models.PageInfo{}, nil
+		},
+	}
+	h := NewBooksHandler(svc, &mockBookRestrictionChecker{})
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/api/books?q=author:x", nil)
+
+	h.ListBooks(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+}
@@ -0,0 +1,80 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/grom-alex/homelib/backend/internal/models"
+)
+
+type ContentSearchHandler struct {
+	contentSvc ContentIndexServicer
+}
+
+func NewContentSearchHandler(contentSvc ContentIndexServicer) *ContentSearchHandler {
+	return &ContentSearchHandler{contentSvc: contentSvc}
+}
+
+// Search handles GET /api/search/content.
+// Only books of collections with content indexing enabled are found.
+func (h *ContentSearchHandler) Search(c *gin.Context) {
+	var f models.ContentSearchFilter
+	err := c.ShouldBindQuery(&f)
+	f.SetDefaults()
+	if err != nil || f.Query == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid query parameters"})
+		return
+	}
+
+	// Apply parental content filter
+	f.ExcludeGenreIDs = getRestrictedGenreIDs(c)
+
+	results, info, err := h.contentSvc.Search(c.Request.Context(), f)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to search book contents"})
+		return
+	}
+
+	c.JSON(http.StatusOK, listResponse(results, info, f.Page, f.Limit))
+}
+
+// Usage handles GET /api/admin/content-index.
+func (h *ContentSearchHandler) Usage(c *gin.Context) {
+	usage, err := h.contentSvc.Usage(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get content index usage"})
+		return
+	}
+	c.JSON(http.StatusOK, usage)
+}
+
+// SetCollectionEnabled handles PUT /api/admin/content-index/collections/:id.
+// The worker (--index-content) applies the change to the index.
+func (h *ContentSearchHandler) SetCollectionEnabled(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid collection id"})
+		return
+	}
+
+	var input models.SetContentIndexInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+
+	found, err := h.contentSvc.SetCollectionEnabled(c.Request.Context(), id, *input.Enabled)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update collection"})
+		return
+	}
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{"error": "collection not found"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+	c.Writer.WriteHeaderNow()
+}
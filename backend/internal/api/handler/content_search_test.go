@@ -0,0 +1,95 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grom-alex/homelib/backend/internal/models"
+)
+
+func TestContentSearchHandler_Search(t *testing.T) {
+	svc := &mockContentIndexService{
+		searchFn: func(_ context.Context, f models.ContentSearchFilter) ([]models.ContentSearchResult, models.PageInfo, error) {
+			assert.Equal(t, "белая гвардия", f.Query)
+			assert.Equal(t, 2, f.Page)
+			assert.Equal(t, []int{5}, f.ExcludeGenreIDs)
+			return []models.ContentSearchResult{{BookID: 42, Title: "Белая гвардия", Matches: 2}}, models.PageInfo{Total: 21}, nil
+		},
+	}
+	h := NewContentSearchHandler(svc)
+
+	c, w := newAnnotationContext(http.MethodGet, "/api/search/content?q=%D0%B1%D0%B5%D0%BB%D0%B0%D1%8F+%D0%B3%D0%B2%D0%B0%D1%80%D0%B4%D0%B8%D1%8F&page=2", "", nil)
+	c.Set("restricted_genre_ids", []int{5})
+	h.Search(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var resp struct {
+		Items []models.ContentSearchResult `json:"items"`
+		Total int                          `json:"total"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, 21, resp.Total)
+	require.Len(t, resp.Items, 1)
+	assert.Equal(t, int64(42), resp.Items[0].BookID)
+}
+
+func TestContentSearchHandler_Search_EmptyQuery(t *testing.T) {
+	h := NewContentSearchHandler(&mockContentIndexService{})
+
+	c, w := newAnnotationContext(http.MethodGet, "/api/search/content?q=", "", nil)
+	h.Search(c)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestContentSearchHandler_Usage(t *testing.T) {
+	svc := &mockContentIndexService{
+		usageFn: func(context.Context) (*models.ContentIndexUsage, error) {
+			return &models.ContentIndexUsage{
+				Collections: []models.ContentIndexCollection{{ID: 1, Name: "lib", Enabled: true, IndexedBooks: 10}},
+				TotalBytes:  4096,
+			}, nil
+		},
+	}
+	h := NewContentSearchHandler(svc)
+
+	c, w := newAnnotationContext(http.MethodGet, "/api/admin/content-index", "", nil)
+	h.Usage(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var resp models.ContentIndexUsage
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, int64(4096), resp.TotalBytes)
+	assert.Equal(t, 10, resp.Collections[0].IndexedBooks)
+}
+
+func TestContentSearchHandler_SetCollectionEnabled(t *testing.T) {
+	var got []any
+	svc := &mockContentIndexService{
+		setCollectionEnabledFn: func(_ context.Context, id int, enabled bool) (bool, error) {
+			got = []any{id, enabled}
+			return id == 3, nil
+		},
+	}
+	h := NewContentSearchHandler(svc)
+
+	c, w := newAnnotationContext(http.MethodPut, "/api/admin/content-index/collections/3", `{"enabled":true}`, gin.Params{{Key: "id", Value: "3"}})
+	h.SetCollectionEnabled(c)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, []any{3, true}, got)
+
+	c, w = newAnnotationContext(http.MethodPut, "/api/admin/content-index/collections/4", `{"enabled":false}`, gin.Params{{Key: "id", Value: "4"}})
+	h.SetCollectionEnabled(c)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, []any{4, false}, got)
+
+	c, w = newAnnotationContext(http.MethodPut, "/api/admin/content-index/collections/3", `{}`, gin.Params{{Key: "id", Value: "3"}})
+	h.SetCollectionEnabled(c)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
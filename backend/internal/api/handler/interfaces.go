@@ -3,6 +3,7 @@ package handler
 import (
 	"context"
 	"encoding/json"
+	"io"
 
 	"github.com/gin-gonic/gin"
 
@@ -21,6 +22,27 @@ func getRestrictedGenreIDs(c *gin.Context) []int {
 	return nil
 }
 
+// listResponse builds the JSON body shared by paginated list endpoints.
+// total is omitted when counting was disabled; next_cursor is present only
+// when more rows follow.
+func listResponse(items any, info models.PageInfo, page, limit int) gin.H {
+	resp := gin.H{
+		"items": items,
+		"page":  page,
+		"limit": limit,
+	}
+	if info.Total >= 0 {
+		resp["total"] = info.Total
+	}
+	if info.TotalEstimated {
+		resp["total_estimated"] = true
+	}
+	if info.NextCursor != "" {
+		resp["next_cursor"] = info.NextCursor
+	}
+	return resp
+}
+
 // AuthServicer is the interface that auth handlers need from the auth service.
 type AuthServicer interface {
 	Register(ctx context.Context, input models.CreateUserInput) (*service.AuthResult, error)
@@ -31,15 +53,27 @@ type AuthServicer interface {
 
 // CatalogServicer is the interface that catalog handlers need from the catalog service.
 type CatalogServicer interface {
-	ListBooks(ctx context.Context, f models.BookFilter) ([]models.BookListItem, int, error)
+	ListBooks(ctx context.Context, f models.BookFilter) ([]models.BookListItem, models.PageInfo, error)
 	GetBook(ctx context.Context, id int64) (*models.BookDetail, error)
-	ListAuthors(ctx context.Context, query string, page, limit int) ([]models.AuthorListItem, int, error)
+	ListAuthors(ctx context.Context, f models.ListFilter) ([]models.AuthorListItem, models.PageInfo, error)
 	GetAuthor(ctx context.Context, id int64) (*models.AuthorDetail, error)
 	ListGenres(ctx context.Context, excludeIDs []int) ([]models.GenreTreeItem, error)
-	ListSeries(ctx context.Context, query string, page, limit int) ([]models.SeriesListItem, int, error)
+	ListSeries(ctx context.Context, f models.ListFilter) ([]models.SeriesListItem, models.PageInfo, error)
 	GetStats(ctx context.Context) (*service.Stats, error)
 }
 
+// SuggestServicer is the interface that the autocomplete handler needs.
+type SuggestServicer interface {
+	Suggest(ctx context.Context, q models.SuggestQuery) (*models.SuggestResult, error)
+}
+
+// ContentIndexServicer is the interface that the content search handler needs.
+type ContentIndexServicer interface {
+	Search(ctx context.Context, f models.ContentSearchFilter) ([]models.ContentSearchResult, models.PageInfo, error)
+	Usage(ctx context.Context) (*models.ContentIndexUsage, error)
+	SetCollectionEnabled(ctx context.Context, collectionID int, enabled bool) (bool, error)
+}
+
 // BookRestrictionChecker checks if a book belongs to restricted genres.
 type BookRestrictionChecker interface {
 	IsBookRestricted(ctx context.Context, bookID int64, restrictedGenreIDs []int) (bool, error)
@@ -71,18 +105,90 @@ type GenreTreeServicer interface {
 type ReaderServicer interface {
 	GetBookContent(ctx context.Context, bookID int64) (*bookfile.BookContent, error)
 	GetChapter(ctx context.Context, bookID int64, chapterID string) (*bookfile.ChapterContent, error)
-	GetBookImage(ctx context.Context, bookID int64, imageID string) (*bookfile.ImageData, error)
+	GetBookImage(ctx context.Context, bookID int64, imageID string, opts bookfile.ImageOptions) (*bookfile.ImageData, error)
+	GetNote(ctx context.Context, bookID int64, noteID string) (*bookfile.NoteContent, error)
+	SearchBook(ctx context.Context, bookID int64, f models.BookSearchFilter) ([]models.BookSearchHit, int, error)
+	Bundle(ctx context.Context, bookID int64, opts bookfile.ImageOptions, emit func(*service.BundleItem) error) error
+}
+
+// ReaderCacheServicer is the interface that reader cache admin handlers need from the reader service.
+type ReaderCacheServicer interface {
+	CacheStats(ctx context.Context) (*models.ReaderCacheStats, error)
+	PurgeBookCache(ctx context.Context, bookID int64) (bool, error)
+	PurgeCache(ctx context.Context) (int, error)
 }
 
 // ProgressRepository is the interface that progress handlers need from the reading progress repo.
 type ProgressRepository interface {
 	Get(ctx context.Context, userID string, bookID int64) (*models.ReadingProgress, error)
 	Upsert(ctx context.Context, p *models.ReadingProgress) error
+	UpsertIfNewer(ctx context.Context, p *models.ReadingProgress) (bool, error)
 	GetByUser(ctx context.Context, userID string) ([]models.ReadingProgress, error)
 }
 
+// ProgressRecorder is the interface that progress handlers need to record reading sessions.
+type ProgressRecorder interface {
+	RecordProgress(ctx context.Context, p *models.ReadingProgress, source string)
+}
+
+// AnnotationRepository is the interface that annotation handlers need from the annotation repo.
+type AnnotationRepository interface {
+	Create(ctx context.Context, a *models.Annotation) (bool, error)
+	Get(ctx context.Context, userID string, bookID, id int64) (*models.Annotation, error)
+	ListByBook(ctx context.Context, userID string, bookID int64, f models.AnnotationFilter) ([]models.Annotation, error)
+	Update(ctx context.Context, a *models.Annotation) (bool, error)
+	Delete(ctx context.Context, userID string, bookID, id int64) (bool, error)
+}
+
+// ShelfRepository is the interface that shelf handlers need from the shelf repo.
+type ShelfRepository interface {
+	List(ctx context.Context, userID string) ([]models.Shelf, error)
+	Get(ctx context.Context, userID string, id int64) (*models.Shelf, error)
+	Create(ctx context.Context, s *models.Shelf) error
+	Update(ctx context.Context, s *models.Shelf) (bool, error)
+	Delete(ctx context.Context, userID string, id int64) (bool, error)
+	Reorder(ctx context.Context, userID string, ids []int64) error
+	ListEntries(ctx context.Context, userID string, shelfID int64) ([]models.ShelfEntry, error)
+	BookEntries(ctx context.Context, userID string, bookID int64) ([]models.ShelfEntry, error)
+	AddBook(ctx context.Context, userID string, shelfID, bookID int64, note *string) (*models.ShelfEntry, error)
+	RemoveBook(ctx context.Context, userID string, shelfID, bookID int64) (bool, error)
+	ReorderBooks(ctx context.Context, userID string, shelfID int64, bookIDs []int64) error
+}
+
+// RatingRepository is the interface that rating handlers need from the rating repo.
+type RatingRepository interface {
+	Get(ctx context.Context, userID string, bookID int64) (*models.BookRating, error)
+	ListByBook(ctx context.Context, bookID int64) ([]models.BookReview, error)
+	Save(ctx context.Context, r *models.BookRating) (bool, error)
+	Delete(ctx context.Context, userID string, bookID int64) (bool, error)
+}
+
+// AnnotationExporter is the interface that export handlers need from the annotation export service.
+type AnnotationExporter interface {
+	BookMarkdown(ctx context.Context, userID string, bookID int64) (*service.ExportFile, error)
+	Dump(ctx context.Context, userID string, restrictedGenreIDs []int) (*models.AnnotationExport, error)
+	WriteMarkdownZip(ctx context.Context, userID string, restrictedGenreIDs []int, w io.Writer) error
+}
+
 // SettingsRepository is the interface that settings handlers need from the user repo.
 type SettingsRepository interface {
 	GetSettings(ctx context.Context, userID string) (json.RawMessage, error)
 	UpdateSettings(ctx context.Context, userID string, patch json.RawMessage) (json.RawMessage, error)
 }
+
+// KosyncServicer is the interface that KOReader sync handlers need from the kosync service.
+type KosyncServicer interface {
+	Authorize(ctx context.Context, username, key string) (string, error)
+	UsernameTaken(ctx context.Context, username string) (bool, error)
+	Account(ctx context.Context, userID string) (*models.KosyncAccount, error)
+	SetAccount(ctx context.Context, userID string, input models.SetKosyncAccountInput) (*models.KosyncAccount, error)
+	DeleteAccount(ctx context.Context, userID string) (bool, error)
+	UpdateProgress(ctx context.Context, userID string, p *models.KosyncProgress) error
+	GetProgress(ctx context.Context, userID, document string) (*models.KosyncProgress, error)
+}
+
+// ReadingStatsServicer is the interface that stats handlers need from the reading stats service.
+type ReadingStatsServicer interface {
+	Stats(ctx context.Context, userID string, q models.StatsQuery) (*models.ReadingStats, error)
+	ImportKOReaderStats(ctx context.Context, userID, path string) (*models.StatsImportResult, error)
+}
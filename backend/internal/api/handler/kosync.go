@@ -0,0 +1,233 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/grom-alex/homelib/backend/internal/models"
+	"github.com/grom-alex/homelib/backend/internal/service"
+)
+
+// Error codes of the kosync protocol. KOReader matches on the HTTP status;
+// the codes and messages follow the reference server.
+const (
+	kosyncCodeInternal            = 2000
+	kosyncCodeUnauthorized        = 2001
+	kosyncCodeUserExists          = 2002
+	kosyncCodeInvalidFields       = 2003
+	kosyncCodeDocumentMissing     = 2004
+	kosyncCodeRegistrationDisable = 2005
+)
+
+// Limits for values stored verbatim from KOReader.
+const (
+	maxKosyncDocumentLen = 128
+	maxKosyncProgressLen = 2048
+	maxKosyncDeviceLen   = 128
+)
+
+type KosyncHandler struct {
+	svc KosyncServicer
+}
+
+func NewKosyncHandler(svc KosyncServicer) *KosyncHandler {
+	return &KosyncHandler{svc: svc}
+}
+
+func kosyncError(c *gin.Context, status, code int, message string) {
+	c.AbortWithStatusJSON(status, gin.H{"code": code, "message": message})
+}
+
+// RequireAuth authenticates KOReader requests by the x-auth-user and
+// x-auth-key headers and sets user_id.
+func (h *KosyncHandler) RequireAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := h.svc.Authorize(c.Request.Context(), c.GetHeader("x-auth-user"), c.GetHeader("x-auth-key"))
+		if errors.Is(err, service.ErrKosyncUnauthorized) {
+			kosyncError(c, http.StatusUnauthorized, kosyncCodeUnauthorized, "Unauthorized")
+			return
+		}
+		if err != nil {
+			kosyncError(c, http.StatusBadGateway, kosyncCodeInternal, "Unknown server error.")
+			return
+		}
+		c.Set("user_id", userID)
+		c.Next()
+	}
+}
+
+// CreateUser handles POST /api/kosync/users/create. Sync accounts are set up
+// in the web interface, where the user is known, so KOReader registration
+// only reports whether the username exists.
+func (h *KosyncHandler) CreateUser(c *gin.Context) {
+	var input struct {
+		Username string `json:"username"`
+		Password string `json:"password"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil || input.Username == "" || input.Password == "" {
+		kosyncError(c, http.StatusForbidden, kosyncCodeInvalidFields, "Invalid request")
+		return
+	}
+
+	taken, err := h.svc.UsernameTaken(c.Request.Context(), input.Username)
+	if err != nil {
+		kosyncError(c, http.StatusBadGateway, kosyncCodeInternal, "Unknown server error.")
+		return
+	}
+	if taken {
+		kosyncError(c, http.StatusPaymentRequired, kosyncCodeUserExists, "Username is already registered.")
+		return
+	}
+	kosyncError(c, http.StatusForbidden, kosyncCodeRegistrationDisable,
+		"User registration is disabled. Set up KOReader sync in the HomeLib web interface.")
+}
+
+// AuthUser handles GET /api/kosync/users/auth. Credentials are checked by RequireAuth.
+func (h *KosyncHandler) AuthUser(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"authorized": "OK"})
+}
+
+// UpdateProgress handles PUT /api/kosync/syncs/progress.
+func (h *KosyncHandler) UpdateProgress(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	var input struct {
+		Document   string   `json:"document"`
+		Progress   string   `json:"progress"`
+		Percentage *float64 `json:"percentage"`
+		Device     string   `json:"device"`
+		DeviceID   string   `json:"device_id"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		kosyncError(c, http.StatusForbidden, kosyncCodeInvalidFields, "Invalid request")
+		return
+	}
+	if input.Document == "" {
+		kosyncError(c, http.StatusForbidden, kosyncCodeDocumentMissing, "Field 'document' not provided.")
+		return
+	}
+	if input.Progress == "" || input.Percentage == nil || input.Device == "" ||
+		len(input.Document) > maxKosyncDocumentLen || len(input.Progress) > maxKosyncProgressLen ||
+		len(input.Device) > maxKosyncDeviceLen || len(input.DeviceID) > maxKosyncDeviceLen {
+		kosyncError(c, http.StatusForbidden, kosyncCodeInvalidFields, "Invalid request")
+		return
+	}
+
+	p := &models.KosyncProgress{
+		Document:   input.Document,
+		Progress:   input.Progress,
+		Percentage: *input.Percentage,
+		Device:     input.Device,
+		DeviceID:   input.DeviceID,
+	}
+	if err := h.svc.UpdateProgress(c.Request.Context(), userID, p); err != nil {
+		kosyncError(c, http.StatusBadGateway, kosyncCodeInternal, "Unknown server error.")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"document": p.Document, "timestamp": p.UpdatedAt.Unix()})
+}
+
+// GetProgress handles GET /api/kosync/syncs/progress/:document.
+// Responds with an empty object if the document has no position.
+func (h *KosyncHandler) GetProgress(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	document := c.Param("document")
+	if document == "" || len(document) > maxKosyncDocumentLen {
+		kosyncError(c, http.StatusForbidden, kosyncCodeDocumentMissing, "Field 'document' not provided.")
+		return
+	}
+
+	p, err := h.svc.GetProgress(c.Request.Context(), userID, document)
+	if err != nil {
+		kosyncError(c, http.StatusBadGateway, kosyncCodeInternal, "Unknown server error.")
+		return
+	}
+	if p == nil {
+		c.JSON(http.StatusOK, gin.H{})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"document":   p.Document,
+		"progress":   p.Progress,
+		"percentage": p.Percentage,
+		"device":     p.Device,
+		"device_id":  p.DeviceID,
+		"timestamp":  p.UpdatedAt.Unix(),
+	})
+}
+
+// GetAccount handles GET /api/me/kosync.
+func (h *KosyncHandler) GetAccount(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized", "message": "Пользователь не авторизован"})
+		return
+	}
+
+	a, err := h.svc.Account(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal_error", "message": "Внутренняя ошибка сервера"})
+		return
+	}
+	if a == nil {
+		c.Status(http.StatusNoContent)
+		c.Writer.WriteHeaderNow()
+		return
+	}
+
+	c.JSON(http.StatusOK, a)
+}
+
+// SetAccount handles PUT /api/me/kosync.
+func (h *KosyncHandler) SetAccount(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized", "message": "Пользователь не авторизован"})
+		return
+	}
+
+	var input models.SetKosyncAccountInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "validation_error", "message": "Укажите имя пользователя и пароль не короче 6 символов"})
+		return
+	}
+
+	a, err := h.svc.SetAccount(c.Request.Context(), userID, input)
+	if errors.Is(err, service.ErrKosyncUsernameTaken) {
+		c.JSON(http.StatusConflict, gin.H{"error": "username_taken", "message": "Имя пользователя уже занято"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal_error", "message": "Внутренняя ошибка сервера"})
+		return
+	}
+
+	c.JSON(http.StatusOK, a)
+}
+
+// DeleteAccount handles DELETE /api/me/kosync.
+func (h *KosyncHandler) DeleteAccount(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized", "message": "Пользователь не авторизован"})
+		return
+	}
+
+	found, err := h.svc.DeleteAccount(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal_error", "message": "Внутренняя ошибка сервера"})
+		return
+	}
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not_found", "message": "Синхронизация KOReader не настроена"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+	c.Writer.WriteHeaderNow()
+}
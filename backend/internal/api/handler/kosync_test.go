@@ -0,0 +1,253 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grom-alex/homelib/backend/internal/models"
+	"github.com/grom-alex/homelib/backend/internal/service"
+)
+
+func decodeKosyncBody(t *testing.T, w *httptest.ResponseRecorder) map[string]any {
+	t.Helper()
+	var body map[string]any
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	return body
+}
+
+func TestKosyncHandler_RequireAuth(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+		wantCode   float64
+	}{
+		{"authorized", nil, http.StatusOK, 0},
+		{"bad credentials", service.ErrKosyncUnauthorized, http.StatusUnauthorized, 2001},
+		{"store failure", assert.AnError, http.StatusBadGateway, 2000},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := &mockKosyncService{
+				authorizeFn: func(_ context.Context, username, key string) (string, error) {
+					assert.Equal(t, "reader", username)
+					assert.Equal(t, "5f4dcc3b5aa765d61d8327deb882cf99", key)
+					if tt.err != nil {
+						return "", tt.err
+					}
+					return "user-123", nil
+				},
+			}
+			h := NewKosyncHandler(svc)
+
+			w := httptest.NewRecorder()
+			_, r := gin.CreateTestContext(w)
+			r.GET("/users/auth", h.RequireAuth(), func(c *gin.Context) {
+				assert.Equal(t, "user-123", c.GetString("user_id"))
+				h.AuthUser(c)
+			})
+			req := httptest.NewRequest(http.MethodGet, "/users/auth", nil)
+			req.Header.Set("x-auth-user", "reader")
+			req.Header.Set("x-auth-key", "5f4dcc3b5aa765d61d8327deb882cf99")
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			body := decodeKosyncBody(t, w)
+			if tt.wantCode == 0 {
+				assert.Equal(t, "OK", body["authorized"])
+			} else {
+				assert.Equal(t, tt.wantCode, body["code"])
+			}
+		})
+	}
+}
+
+func TestKosyncHandler_CreateUser(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		taken      bool
+		wantStatus int
+		wantCode   float64
+	}{
+		{"username taken", `{"username":"reader","password":"x"}`, true, http.StatusPaymentRequired, 2002},
+		{"registration disabled", `{"username":"reader","password":"x"}`, false, http.StatusForbidden, 2005},
+		{"missing password", `{"username":"reader"}`, false, http.StatusForbidden, 2003},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := &mockKosyncService{
+				usernameTakenFn: func(_ context.Context, _ string) (bool, error) { return tt.taken, nil },
+			}
+			h := NewKosyncHandler(svc)
+
+			c, w := newAnnotationContext(http.MethodPost, "/api/kosync/users/create", tt.body, nil)
+			h.CreateUser(c)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			assert.Equal(t, tt.wantCode, decodeKosyncBody(t, w)["code"])
+		})
+	}
+}
+
+func TestKosyncHandler_UpdateProgress_Success(t *testing.T) {
+	saved := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	svc := &mockKosyncService{
+		updateProgressFn: func(_ context.Context, userID string, p *models.KosyncProgress) error {
+			assert.Equal(t, "user-123", userID)
+			assert.Equal(t, "0123456789abcdef0123456789abcdef", p.Document)
+			assert.Equal(t, "/body/DocFragment[3]/body/div/p[2]/text().0", p.Progress)
+			assert.InDelta(t, 0.25, p.Percentage, 1e-9)
+			assert.Equal(t, "Kobo", p.Device)
+			assert.Equal(t, "A1B2", p.DeviceID)
+			p.UpdatedAt = saved
+			return nil
+		},
+	}
+	h := NewKosyncHandler(svc)
+
+	body := `{"document":"0123456789abcdef0123456789abcdef","progress":"/body/DocFragment[3]/body/div/p[2]/text().0",` +
+		`"percentage":0.25,"device":"Kobo","device_id":"A1B2"}`
+	c, w := newAnnotationContext(http.MethodPut, "/api/kosync/syncs/progress", body, nil)
+	h.UpdateProgress(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	resp := decodeKosyncBody(t, w)
+	assert.Equal(t, "0123456789abcdef0123456789abcdef", resp["document"])
+	assert.Equal(t, float64(saved.Unix()), resp["timestamp"])
+}
+
+func TestKosyncHandler_UpdateProgress_Validation(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		wantCode float64
+	}{
+		{"missing document", `{"progress":"/body","percentage":0.1,"device":"Kobo"}`, 2004},
+		{"missing percentage", `{"document":"abc","progress":"/body","device":"Kobo"}`, 2003},
+		{"missing device", `{"document":"abc","progress":"/body","percentage":0.1}`, 2003},
+		{"oversized progress", `{"document":"abc","progress":"` + strings.Repeat("p", 3000) + `","percentage":0.1,"device":"Kobo"}`, 2003},
+		{"malformed", `{`, 2003},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewKosyncHandler(&mockKosyncService{})
+
+			c, w := newAnnotationContext(http.MethodPut, "/api/kosync/syncs/progress", tt.body, nil)
+			h.UpdateProgress(c)
+
+			assert.Equal(t, http.StatusForbidden, w.Code)
+			assert.Equal(t, tt.wantCode, decodeKosyncBody(t, w)["code"])
+		})
+	}
+}
+
+func TestKosyncHandler_GetProgress(t *testing.T) {
+	updated := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	svc := &mockKosyncService{
+		getProgressFn: func(_ context.Context, userID, document string) (*models.KosyncProgress, error) {
+			assert.Equal(t, "user-123", userID)
+			if document != "abc" {
+				return nil, nil
+			}
+			return &models.KosyncProgress{
+				Document: "abc", Progress: "/body/DocFragment[2]/body/div/p", Percentage: 0.5,
+				Device: "web", DeviceID: "homelib", UpdatedAt: updated,
+			}, nil
+		},
+	}
+	h := NewKosyncHandler(svc)
+
+	c, w := newAnnotationContext(http.MethodGet, "/api/kosync/syncs/progress/abc", "", gin.Params{{Key: "document", Value: "abc"}})
+	h.GetProgress(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	body := decodeKosyncBody(t, w)
+	assert.Equal(t, "/body/DocFragment[2]/body/div/p", body["progress"])
+	assert.Equal(t, 0.5, body["percentage"])
+	assert.Equal(t, "homelib", body["device_id"])
+	assert.Equal(t, float64(updated.Unix()), body["timestamp"])
+
+	c, w = newAnnotationContext(http.MethodGet, "/api/kosync/syncs/progress/other", "", gin.Params{{Key: "document", Value: "other"}})
+	h.GetProgress(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "{}", w.Body.String())
+}
+
+func TestKosyncHandler_SetAccount(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		err        error
+		wantStatus int
+	}{
+		{"success", `{"username":"reader","password":"secret1"}`, nil, http.StatusOK},
+		{"short password", `{"username":"reader","password":"123"}`, nil, http.StatusBadRequest},
+		{"username taken", `{"username":"reader","password":"secret1"}`, service.ErrKosyncUsernameTaken, http.StatusConflict},
+		{"store failure", `{"username":"reader","password":"secret1"}`, assert.AnError, http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := &mockKosyncService{
+				setAccountFn: func(_ context.Context, userID string, input models.SetKosyncAccountInput) (*models.KosyncAccount, error) {
+					assert.Equal(t, "user-123", userID)
+					if tt.err != nil {
+						return nil, tt.err
+					}
+					return &models.KosyncAccount{Username: input.Username}, nil
+				},
+			}
+			h := NewKosyncHandler(svc)
+
+			c, w := newAnnotationContext(http.MethodPut, "/api/me/kosync", tt.body, nil)
+			h.SetAccount(c)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			if tt.wantStatus == http.StatusOK {
+				body := decodeKosyncBody(t, w)
+				assert.Equal(t, "reader", body["username"])
+				assert.NotContains(t, body, "key_hash")
+			}
+		})
+	}
+}
+
+func TestKosyncHandler_GetAccount_NotConfigured(t *testing.T) {
+	svc := &mockKosyncService{
+		accountFn: func(_ context.Context, _ string) (*models.KosyncAccount, error) { return nil, nil },
+	}
+	h := NewKosyncHandler(svc)
+
+	c, w := newAnnotationContext(http.MethodGet, "/api/me/kosync", "", nil)
+	h.GetAccount(c)
+
+	assert.Equal(t, http.StatusNoContent, w.Code)
+}
+
+func TestKosyncHandler_DeleteAccount(t *testing.T) {
+	for _, found := range []bool{true, false} {
+		svc := &mockKosyncService{
+			deleteAccountFn: func(_ context.Context, _ string) (bool, error) { return found, nil },
+		}
+		h := NewKosyncHandler(svc)
+
+		c, w := newAnnotationContext(http.MethodDelete, "/api/me/kosync", "", nil)
+		h.DeleteAccount(c)
+
+		if found {
+			assert.Equal(t, http.StatusNoContent, w.Code)
+		} else {
+			assert.Equal(t, http.StatusNotFound, w.Code)
+		}
+	}
+}
@@ -51,20 +51,20 @@ func (m *mockAuthService) Logout(ctx context.Context, tokenStr string) error {
 // --- Catalog service mock ---
 
 type mockCatalogService struct {
-	listBooksFn   func(ctx context.Context, f models.BookFilter) ([]models.BookListItem, int, error)
+	listBooksFn   func(ctx context.Context, f models.BookFilter) ([]models.BookListItem, models.PageInfo, error)
 	getBookFn     func(ctx context.Context, id int64) (*models.BookDetail, error)
-	listAuthorsFn func(ctx context.Context, query string, page, limit int) ([]models.AuthorListItem, int, error)
+	listAuthorsFn func(ctx context.Context, f models.ListFilter) ([]models.AuthorListItem, models.PageInfo, error)
 	getAuthorFn   func(ctx context.Context, id int64) (*models.AuthorDetail, error)
 	listGenresFn  func(ctx context.Context, excludeIDs []int) ([]models.GenreTreeItem, error)
-	listSeriesFn  func(ctx context.Context, query string, page, limit int) ([]models.SeriesListItem, int, error)
+	listSeriesFn  func(ctx context.Context, f models.ListFilter) ([]models.SeriesListItem, models.PageInfo, error)
 	getStatsFn    func(ctx context.Context) (*service.Stats, error)
 }
 
-func (m *mockCatalogService) ListBooks(ctx context.Context, f models.BookFilter) ([]models.BookListItem, int, error) {
+func (m *mockCatalogService) ListBooks(ctx context.Context, f models.BookFilter) ([]models.BookListItem, models.PageInfo, error) {
 	if m.listBooksFn != nil {
 		return m.listBooksFn(ctx, f)
 	}
-	return nil, 0, fmt.Errorf("not implemented")
+	return nil, models.PageInfo{}, fmt.Errorf("not implemented")
 }
 
 func (m *mockCatalogService) GetBook(ctx context.Context, id int64) (*models.BookDetail, error) {
@@ -74,11 +74,11 @@ func (m *mockCatalogService) GetBook(ctx context.Context, id int64) (*models.Boo
 	return nil, fmt.Errorf("not implemented")
 }
 
-func (m *mockCatalogService) ListAuthors(ctx context.Context, query string, page, limit int) ([]models.AuthorListItem, int, error) {
+func (m *mockCatalogService) ListAuthors(ctx context.Context, f models.ListFilter) ([]models.AuthorListItem, models.PageInfo, error) {
 	if m.listAuthorsFn != nil {
-		return m.listAuthorsFn(ctx, query, page, limit)
+		return m.listAuthorsFn(ctx, f)
 	}
-	return nil, 0, fmt.Errorf("not implemented")
+	return nil, models.PageInfo{}, fmt.Errorf("not implemented")
 }
 
 func (m *mockCatalogService) GetAuthor(ctx context.Context, id int64) (*models.AuthorDetail, error) {
@@ -95,11 +95,11 @@ func (m *mockCatalogService) ListGenres(ctx context.Context, excludeIDs []int) (
 	return nil, fmt.Errorf("not implemented")
 }
 
-func (m *mockCatalogService) ListSeries(ctx context.Context, query string, page, limit int) ([]models.SeriesListItem, int, error) {
+func (m *mockCatalogService) ListSeries(ctx context.Context, f models.ListFilter) ([]models.SeriesListItem, models.PageInfo, error) {
 	if m.listSeriesFn != nil {
-		return m.listSeriesFn(ctx, query, page, limit)
+		return m.listSeriesFn(ctx, f)
 	}
-	return nil, 0, fmt.Errorf("not implemented")
+	return nil, models.PageInfo{}, fmt.Errorf("not implemented")
 }
 
 func (m *mockCatalogService) GetStats(ctx context.Context) (*service.Stats, error) {
@@ -187,7 +187,10 @@ func (m *mockGenreTreeService) ForceReload(ctx context.Context) (*service.GenreT
 type mockReaderService struct {
 	getBookContentFn func(ctx context.Context, bookID int64) (*bookfile.BookContent, error)
 	getChapterFn     func(ctx context.Context, bookID int64, chapterID string) (*bookfile.ChapterContent, error)
-	getBookImageFn   func(ctx context.Context, bookID int64, imageID string) (*bookfile.ImageData, error)
+	getBookImageFn   func(ctx context.Context, bookID int64, imageID string, opts bookfile.ImageOptions) (*bookfile.ImageData, error)
+	getNoteFn        func(ctx context.Context, bookID int64, noteID string) (*bookfile.NoteContent, error)
+	searchBookFn     func(ctx context.Context, bookID int64, f models.BookSearchFilter) ([]models.BookSearchHit, int, error)
+	bundleFn         func(ctx context.Context, bookID int64, opts bookfile.ImageOptions, emit func(*service.BundleItem) error) error
 }
 
 func (m *mockReaderService) GetBookContent(ctx context.Context, bookID int64) (*bookfile.BookContent, error) {
@@ -204,19 +207,42 @@ func (m *mockReaderService) GetChapter(ctx context.Context, bookID int64, chapte
 	return nil, fmt.Errorf("not implemented")
 }
 
-func (m *mockReaderService) GetBookImage(ctx context.Context, bookID int64, imageID string) (*bookfile.ImageData, error) {
+func (m *mockReaderService) GetBookImage(ctx context.Context, bookID int64, imageID string, opts bookfile.ImageOptions) (*bookfile.ImageData, error) {
 	if m.getBookImageFn != nil {
-		return m.getBookImageFn(ctx, bookID, imageID)
+		return m.getBookImageFn(ctx, bookID, imageID, opts)
+	}
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockReaderService) GetNote(ctx context.Context, bookID int64, noteID string) (*bookfile.NoteContent, error) {
+	if m.getNoteFn != nil {
+		return m.getNoteFn(ctx, bookID, noteID)
 	}
 	return nil, fmt.Errorf("not implemented")
 }
 
+func (m *mockReaderService) SearchBook(ctx context.Context, bookID int64, f models.BookSearchFilter) ([]models.BookSearchHit, int, error) {
+	if m.searchBookFn != nil {
+		return m.searchBookFn(ctx, bookID, f)
+	}
+	return nil, 0, fmt.Errorf("not implemented")
+}
+
+func (m *mockReaderService) Bundle(ctx context.Context, bookID int64, opts bookfile.ImageOptions, emit func(*service.BundleItem) error) error {
+	if m.bundleFn != nil {
+		return m.bundleFn(ctx, bookID, opts, emit)
+	}
+	return fmt.Errorf("not implemented")
+}
+
 // --- Progress repo mock ---
 
 type mockProgressRepo struct {
 	getFn       func(ctx context.Context, userID string, bookID int64) (*models.ReadingProgress, error)
 	upsertFn    func(ctx context.Context, p *models.ReadingProgress) error
 	getByUserFn func(ctx context.Context, userID string) ([]models.ReadingProgress, error)
+
+	upsertIfNewerFn func(ctx context.Context, p *models.ReadingProgress) (bool, error)
 }
 
 func (m *mockProgressRepo) Get(ctx context.Context, userID string, bookID int64) (*models.ReadingProgress, error) {
@@ -233,6 +259,13 @@ func (m *mockProgressRepo) Upsert(ctx context.Context, p *models.ReadingProgress
 	return fmt.Errorf("not implemented")
 }
 
+func (m *mockProgressRepo) UpsertIfNewer(ctx context.Context, p *models.ReadingProgress) (bool, error) {
+	if m.upsertIfNewerFn != nil {
+		return m.upsertIfNewerFn(ctx, p)
+	}
+	return false, fmt.Errorf("not implemented")
+}
+
 func (m *mockProgressRepo) GetByUser(ctx context.Context, userID string) ([]models.ReadingProgress, error) {
 	if m.getByUserFn != nil {
 		return m.getByUserFn(ctx, userID)
@@ -240,6 +273,80 @@ func (m *mockProgressRepo) GetByUser(ctx context.Context, userID string) ([]mode
 	return nil, fmt.Errorf("not implemented")
 }
 
+// --- Annotation repo mock ---
+
+type mockAnnotationRepo struct {
+	createFn     func(ctx context.Context, a *models.Annotation) (bool, error)
+	getFn        func(ctx context.Context, userID string, bookID, id int64) (*models.Annotation, error)
+	listByBookFn func(ctx context.Context, userID string, bookID int64, f models.AnnotationFilter) ([]models.Annotation, error)
+	updateFn     func(ctx context.Context, a *models.Annotation) (bool, error)
+	deleteFn     func(ctx context.Context, userID string, bookID, id int64) (bool, error)
+}
+
+func (m *mockAnnotationRepo) Create(ctx context.Context, a *models.Annotation) (bool, error) {
+	if m.createFn != nil {
+		return m.createFn(ctx, a)
+	}
+	return false, fmt.Errorf("not implemented")
+}
+
+func (m *mockAnnotationRepo) Get(ctx context.Context, userID string, bookID, id int64) (*models.Annotation, error) {
+	if m.getFn != nil {
+		return m.getFn(ctx, userID, bookID, id)
+	}
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockAnnotationRepo) ListByBook(ctx context.Context, userID string, bookID int64, f models.AnnotationFilter) ([]models.Annotation, error) {
+	if m.listByBookFn != nil {
+		return m.listByBookFn(ctx, userID, bookID, f)
+	}
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockAnnotationRepo) Update(ctx context.Context, a *models.Annotation) (bool, error) {
+	if m.updateFn != nil {
+		return m.updateFn(ctx, a)
+	}
+	return false, fmt.Errorf("not implemented")
+}
+
+func (m *mockAnnotationRepo) Delete(ctx context.Context, userID string, bookID, id int64) (bool, error) {
+	if m.deleteFn != nil {
+		return m.deleteFn(ctx, userID, bookID, id)
+	}
+	return false, fmt.Errorf("not implemented")
+}
+
+// --- Annotation exporter mock ---
+
+type mockAnnotationExporter struct {
+	bookMarkdownFn     func(ctx context.Context, userID string, bookID int64) (*service.ExportFile, error)
+	dumpFn             func(ctx context.Context, userID string, restrictedGenreIDs []int) (*models.AnnotationExport, error)
+	writeMarkdownZipFn func(ctx context.Context, userID string, restrictedGenreIDs []int, w io.Writer) error
+}
+
+func (m *mockAnnotationExporter) BookMarkdown(ctx context.Context, userID string, bookID int64) (*service.ExportFile, error) {
+	if m.bookMarkdownFn != nil {
+		return m.bookMarkdownFn(ctx, userID, bookID)
+	}
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockAnnotationExporter) Dump(ctx context.Context, userID string, restrictedGenreIDs []int) (*models.AnnotationExport, error) {
+	if m.dumpFn != nil {
+		return m.dumpFn(ctx, userID, restrictedGenreIDs)
+	}
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockAnnotationExporter) WriteMarkdownZip(ctx context.Context, userID string, restrictedGenreIDs []int, w io.Writer) error {
+	if m.writeMarkdownZipFn != nil {
+		return m.writeMarkdownZipFn(ctx, userID, restrictedGenreIDs, w)
+	}
+	return fmt.Errorf("not implemented")
+}
+
 // --- Settings repo mock ---
 
 type mockSettingsRepo struct {
@@ -261,6 +368,113 @@ func (m *mockSettingsRepo) UpdateSettings(ctx context.Context, userID string, pa
 	return nil, fmt.Errorf("not implemented")
 }
 
+// --- Mock SuggestServicer ---
+
+type mockSuggestService struct {
+	suggestFn func(ctx context.Context, q models.SuggestQuery) (*models.SuggestResult, error)
+}
+
+func (m *mockSuggestService) Suggest(ctx context.Context, q models.SuggestQuery) (*models.SuggestResult, error) {
+	if m.suggestFn != nil {
+		return m.suggestFn(ctx, q)
+	}
+	return nil, fmt.Errorf("not implemented")
+}
+
+// --- Kosync service mock ---
+
+type mockKosyncService struct {
+	authorizeFn      func(ctx context.Context, username, key string) (string, error)
+	usernameTakenFn  func(ctx context.Context, username string) (bool, error)
+	accountFn        func(ctx context.Context, userID string) (*models.KosyncAccount, error)
+	setAccountFn     func(ctx context.Context, userID string, input models.SetKosyncAccountInput) (*models.KosyncAccount, error)
+	deleteAccountFn  func(ctx context.Context, userID string) (bool, error)
+	updateProgressFn func(ctx context.Context, userID string, p *models.KosyncProgress) error
+	getProgressFn    func(ctx context.Context, userID, document string) (*models.KosyncProgress, error)
+}
+
+func (m *mockKosyncService) Authorize(ctx context.Context, username, key string) (string, error) {
+	if m.authorizeFn != nil {
+		return m.authorizeFn(ctx, username, key)
+	}
+	return "", fmt.Errorf("not implemented")
+}
+
+func (m *mockKosyncService) UsernameTaken(ctx context.Context, username string) (bool, error) {
+	if m.usernameTakenFn != nil {
+		return m.usernameTakenFn(ctx, username)
+	}
+	return false, fmt.Errorf("not implemented")
+}
+
+func (m *mockKosyncService) Account(ctx context.Context, userID string) (*models.KosyncAccount, error) {
+	if m.accountFn != nil {
+		return m.accountFn(ctx, userID)
+	}
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockKosyncService) SetAccount(ctx context.Context, userID string, input models.SetKosyncAccountInput) (*models.KosyncAccount, error) {
+	if m.setAccountFn != nil {
+		return m.setAccountFn(ctx, userID, input)
+	}
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockKosyncService) DeleteAccount(ctx context.Context, userID string) (bool, error) {
+	if m.deleteAccountFn != nil {
+		return m.deleteAccountFn(ctx, userID)
+	}
+	return false, fmt.Errorf("not implemented")
+}
+
+func (m *mockKosyncService) UpdateProgress(ctx context.Context, userID string, p *models.KosyncProgress) error {
+	if m.updateProgressFn != nil {
+		return m.updateProgressFn(ctx, userID, p)
+	}
+	return fmt.Errorf("not implemented")
+}
+
+func (m *mockKosyncService) GetProgress(ctx context.Context, userID, document string) (*models.KosyncProgress, error) {
+	if m.getProgressFn != nil {
+		return m.getProgressFn(ctx, userID, document)
+	}
+	return nil, fmt.Errorf("not implemented")
+}
+
+// --- Reading stats service mock ---
+
+type mockReadingStatsService struct {
+	statsFn  func(ctx context.Context, userID string, q models.StatsQuery) (*models.ReadingStats, error)
+	importFn func(ctx context.Context, userID, path string) (*models.StatsImportResult, error)
+}
+
+func (m *mockReadingStatsService) Stats(ctx context.Context, userID string, q models.StatsQuery) (*models.ReadingStats, error) {
+	if m.statsFn != nil {
+		return m.statsFn(ctx, userID, q)
+	}
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockReadingStatsService) ImportKOReaderStats(ctx context.Context, userID, path string) (*models.StatsImportResult, error) {
+	if m.importFn != nil {
+		return m.importFn(ctx, userID, path)
+	}
+	return nil, fmt.Errorf("not implemented")
+}
+
+// --- Progress recorder mock ---
+
+type mockProgressRecorder struct {
+	recorded []*models.ReadingProgress
+	sources  []string
+}
+
+func (m *mockProgressRecorder) RecordProgress(_ context.Context, p *models.ReadingProgress, source string) {
+	m.recorded = append(m.recorded, p)
+	m.sources = append(m.sources, source)
+}
+
 // --- Helper: nopCloser wraps an io.Reader to satisfy io.ReadCloser ---
 
 type nopReadCloser struct {
@@ -284,3 +498,189 @@ func mockAuthResult() *service.AuthResult {
 		RefreshToken: "mock-refresh-token",
 	}
 }
+
+// --- Shelf repository mock ---
+
+type mockShelfRepo struct {
+	listFn         func(ctx context.Context, userID string) ([]models.Shelf, error)
+	getFn          func(ctx context.Context, userID string, id int64) (*models.Shelf, error)
+	createFn       func(ctx context.Context, s *models.Shelf) error
+	updateFn       func(ctx context.Context, s *models.Shelf) (bool, error)
+	deleteFn       func(ctx context.Context, userID string, id int64) (bool, error)
+	reorderFn      func(ctx context.Context, userID string, ids []int64) error
+	listEntriesFn  func(ctx context.Context, userID string, shelfID int64) ([]models.ShelfEntry, error)
+	bookEntriesFn  func(ctx context.Context, userID string, bookID int64) ([]models.ShelfEntry, error)
+	addBookFn      func(ctx context.Context, userID string, shelfID, bookID int64, note *string) (*models.ShelfEntry, error)
+	removeBookFn   func(ctx context.Context, userID string, shelfID, bookID int64) (bool, error)
+	reorderBooksFn func(ctx context.Context, userID string, shelfID int64, bookIDs []int64) error
+}
+
+func (m *mockShelfRepo) List(ctx context.Context, userID string) ([]models.Shelf, error) {
+	if m.listFn != nil {
+		return m.listFn(ctx, userID)
+	}
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockShelfRepo) Get(ctx context.Context, userID string, id int64) (*models.Shelf, error) {
+	if m.getFn != nil {
+		return m.getFn(ctx, userID, id)
+	}
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockShelfRepo) Create(ctx context.Context, s *models.Shelf) error {
+	if m.createFn != nil {
+		return m.createFn(ctx, s)
+	}
+	return fmt.Errorf("not implemented")
+}
+
+func (m *mockShelfRepo) Update(ctx context.Context, s *models.Shelf) (bool, error) {
+	if m.updateFn != nil {
+		return m.updateFn(ctx, s)
+	}
+	return false, fmt.Errorf("not implemented")
+}
+
+func (m *mockShelfRepo) Delete(ctx context.Context, userID string, id int64) (bool, error) {
+	if m.deleteFn != nil {
+		return m.deleteFn(ctx, userID, id)
+	}
+	return false, fmt.Errorf("not implemented")
+}
+
+func (m *mockShelfRepo) Reorder(ctx context.Context, userID string, ids []int64) error {
+	if m.reorderFn != nil {
+		return m.reorderFn(ctx, userID, ids)
+	}
+	return fmt.Errorf("not implemented")
+}
+
+func (m *mockShelfRepo) ListEntries(ctx context.Context, userID string, shelfID int64) ([]models.ShelfEntry, error) {
+	if m.listEntriesFn != nil {
+		return m.listEntriesFn(ctx, userID, shelfID)
+	}
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockShelfRepo) BookEntries(ctx context.Context, userID string, bookID int64) ([]models.ShelfEntry, error) {
+	if m.bookEntriesFn != nil {
+		return m.bookEntriesFn(ctx, userID, bookID)
+	}
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockShelfRepo) AddBook(ctx context.Context, userID string, shelfID, bookID int64, note *string) (*models.ShelfEntry, error) {
+	if m.addBookFn != nil {
+		return m.addBookFn(ctx, userID, shelfID, bookID, note)
+	}
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockShelfRepo) RemoveBook(ctx context.Context, userID string, shelfID, bookID int64) (bool, error) {
+	if m.removeBookFn != nil {
+		return m.removeBookFn(ctx, userID, shelfID, bookID)
+	}
+	return false, fmt.Errorf("not implemented")
+}
+
+func (m *mockShelfRepo) ReorderBooks(ctx context.Context, userID string, shelfID int64, bookIDs []int64) error {
+	if m.reorderBooksFn != nil {
+		return m.reorderBooksFn(ctx, userID, shelfID, bookIDs)
+	}
+	return fmt.Errorf("not implemented")
+}
+
+// --- Rating repository mock ---
+
+type mockRatingRepo struct {
+	getFn        func(ctx context.Context, userID string, bookID int64) (*models.BookRating, error)
+	listByBookFn func(ctx context.Context, bookID int64) ([]models.BookReview, error)
+	saveFn       func(ctx context.Context, r *models.BookRating) (bool, error)
+	deleteFn     func(ctx context.Context, userID string, bookID int64) (bool, error)
+}
+
+func (m *mockRatingRepo) Get(ctx context.Context, userID string, bookID int64) (*models.BookRating, error) {
+	if m.getFn != nil {
+		return m.getFn(ctx, userID, bookID)
+	}
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockRatingRepo) ListByBook(ctx context.Context, bookID int64) ([]models.BookReview, error) {
+	if m.listByBookFn != nil {
+		return m.listByBookFn(ctx, bookID)
+	}
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockRatingRepo) Save(ctx context.Context, r *models.BookRating) (bool, error) {
+	if m.saveFn != nil {
+		return m.saveFn(ctx, r)
+	}
+	return false, fmt.Errorf("not implemented")
+}
+
+func (m *mockRatingRepo) Delete(ctx context.Context, userID string, bookID int64) (bool, error) {
+	if m.deleteFn != nil {
+		return m.deleteFn(ctx, userID, bookID)
+	}
+	return false, fmt.Errorf("not implemented")
+}
+
+// --- Content index service mock ---
+
+type mockContentIndexService struct {
+	searchFn               func(ctx context.Context, f models.ContentSearchFilter) ([]models.ContentSearchResult, models.PageInfo, error)
+	usageFn                func(ctx context.Context) (*models.ContentIndexUsage, error)
+	setCollectionEnabledFn func(ctx context.Context, collectionID int, enabled bool) (bool, error)
+}
+
+func (m *mockContentIndexService) Search(ctx context.Context, f models.ContentSearchFilter) ([]models.ContentSearchResult, models.PageInfo, error) {
+	if m.searchFn != nil {
+		return m.searchFn(ctx, f)
+	}
+	return nil, models.PageInfo{}, fmt.Errorf("not implemented")
+}
+
+func (m *mockContentIndexService) Usage(ctx context.Context) (*models.ContentIndexUsage, error) {
+	if m.usageFn != nil {
+		return m.usageFn(ctx)
+	}
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockContentIndexService) SetCollectionEnabled(ctx context.Context, collectionID int, enabled bool) (bool, error) {
+	if m.setCollectionEnabledFn != nil {
+		return m.setCollectionEnabledFn(ctx, collectionID, enabled)
+	}
+	return false, fmt.Errorf("not implemented")
+}
+
+type mockReaderCacheService struct {
+	cacheStatsFn     func(ctx context.Context) (*models.ReaderCacheStats, error)
+	purgeBookCacheFn func(ctx context.Context, bookID int64) (bool, error)
+	purgeCacheFn     func(ctx context.Context) (int, error)
+}
+
+func (m *mockReaderCacheService) CacheStats(ctx context.Context) (*models.ReaderCacheStats, error) {
+	if m.cacheStatsFn != nil {
+		return m.cacheStatsFn(ctx)
+	}
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockReaderCacheService) PurgeBookCache(ctx context.Context, bookID int64) (bool, error) {
+	if m.purgeBookCacheFn != nil {
+		return m.purgeBookCacheFn(ctx, bookID)
+	}
+	return false, fmt.Errorf("not implemented")
+}
+
+func (m *mockReaderCacheService) PurgeCache(ctx context.Context) (int, error) {
+	if m.purgeCacheFn != nil {
+		return m.purgeCacheFn(ctx)
+	}
+	return 0, fmt.Errorf("not implemented")
+}
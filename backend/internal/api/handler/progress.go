@@ -3,6 +3,7 @@ package handler
 import (
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 
@@ -12,10 +13,13 @@ import (
 
 type ProgressHandler struct {
 	progressRepo ProgressRepository
+	sessions     ProgressRecorder
 }
 
-func NewProgressHandler(repo ProgressRepository) *ProgressHandler {
-	return &ProgressHandler{progressRepo: repo}
+// NewProgressHandler creates a progress handler. sessions may be nil, in
+// which case reading sessions are not recorded.
+func NewProgressHandler(repo ProgressRepository, sessions ProgressRecorder) *ProgressHandler {
+	return &ProgressHandler{progressRepo: repo, sessions: sessions}
 }
 
 // GetReadingProgress handles GET /api/me/books/:bookId/progress.
@@ -77,19 +81,64 @@ func (h *ProgressHandler) SaveReadingProgress(c *gin.Context) {
 		UserID:          userID,
 		BookID:          bookID,
 		ChapterID:       input.ChapterID,
+		Locator:         input.Locator,
 		ChapterProgress: input.ChapterProgress,
 		TotalProgress:   input.TotalProgress,
 		Device:          input.Device,
 	}
 
+	if input.UpdatedAt != nil {
+		h.reconcileOfflineProgress(c, progress, *input.UpdatedAt)
+		return
+	}
+
 	if err := h.progressRepo.Upsert(c.Request.Context(), progress); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal_error", "message": "Внутренняя ошибка сервера"})
 		return
 	}
+	if h.sessions != nil {
+		h.sessions.RecordProgress(c.Request.Context(), progress, models.SessionSourceWeb)
+	}
 
 	c.JSON(http.StatusOK, progress)
 }
 
+// reconcileOfflineProgress stores a position saved while offline at the
+// time it was reached. If the stored progress is newer (e.g. the book was
+// read further on another device), it is kept and returned with 409, so
+// that the client can move to it.
+func (h *ProgressHandler) reconcileOfflineProgress(c *gin.Context, progress *models.ReadingProgress, at time.Time) {
+	// Client clocks may run ahead; a position cannot be newer than now
+	progress.UpdatedAt = at
+	if now := time.Now(); at.After(now) {
+		progress.UpdatedAt = now
+	}
+
+	applied, err := h.progressRepo.UpsertIfNewer(c.Request.Context(), progress)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal_error", "message": "Внутренняя ошибка сервера"})
+		return
+	}
+	if applied {
+		if h.sessions != nil {
+			h.sessions.RecordProgress(c.Request.Context(), progress, models.SessionSourceWeb)
+		}
+		c.JSON(http.StatusOK, progress)
+		return
+	}
+
+	current, err := h.progressRepo.Get(c.Request.Context(), progress.UserID, progress.BookID)
+	if err != nil || current == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal_error", "message": "Внутренняя ошибка сервера"})
+		return
+	}
+	c.JSON(http.StatusConflict, gin.H{
+		"error":    "stale_progress",
+		"message":  "На сервере сохранена более поздняя позиция чтения",
+		"progress": current,
+	})
+}
+
 // GetAllProgress handles GET /api/me/progress.
 // Returns a compact map of bookID → totalProgress for the authenticated user.
 func (h *ProgressHandler) GetAllProgress(c *gin.Context) {
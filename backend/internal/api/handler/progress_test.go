@@ -34,7 +34,7 @@ func TestProgressHandler_GetReadingProgress_Found(t *testing.T) {
 			}, nil
 		},
 	}
-	h := NewProgressHandler(repo)
+	h := NewProgressHandler(repo, nil)
 
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
@@ -60,7 +60,7 @@ func TestProgressHandler_GetReadingProgress_NoContent(t *testing.T) {
 			return nil, nil
 		},
 	}
-	h := NewProgressHandler(repo)
+	h := NewProgressHandler(repo, nil)
 
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
@@ -75,7 +75,7 @@ func TestProgressHandler_GetReadingProgress_NoContent(t *testing.T) {
 }
 
 func TestProgressHandler_GetReadingProgress_InvalidBookID(t *testing.T) {
-	h := NewProgressHandler(&mockProgressRepo{})
+	h := NewProgressHandler(&mockProgressRepo{}, nil)
 
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
@@ -90,7 +90,7 @@ func TestProgressHandler_GetReadingProgress_InvalidBookID(t *testing.T) {
 }
 
 func TestProgressHandler_GetReadingProgress_NoUserID(t *testing.T) {
-	h := NewProgressHandler(&mockProgressRepo{})
+	h := NewProgressHandler(&mockProgressRepo{}, nil)
 
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
@@ -108,7 +108,7 @@ func TestProgressHandler_GetReadingProgress_DBError(t *testing.T) {
 			return nil, fmt.Errorf("connection lost")
 		},
 	}
-	h := NewProgressHandler(repo)
+	h := NewProgressHandler(repo, nil)
 
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
@@ -138,7 +138,7 @@ func TestProgressHandler_SaveReadingProgress_Success(t *testing.T) {
 			return nil
 		},
 	}
-	h := NewProgressHandler(repo)
+	h := NewProgressHandler(repo, nil)
 
 	body := `{"chapterId":"ch5","chapterProgress":75,"totalProgress":50,"device":"mobile"}`
 	w := httptest.NewRecorder()
@@ -159,8 +159,115 @@ func TestProgressHandler_SaveReadingProgress_Success(t *testing.T) {
 	assert.Equal(t, 50, resp.TotalProgress)
 }
 
+func TestProgressHandler_SaveReadingProgress_RecordsSession(t *testing.T) {
+	repo := &mockProgressRepo{
+		upsertFn: func(_ context.Context, p *models.ReadingProgress) error { return nil },
+	}
+	sessions := &mockProgressRecorder{}
+	h := NewProgressHandler(repo, sessions)
+
+	body := `{"chapterId":"ch5","chapterProgress":75,"totalProgress":50,"device":"mobile"}`
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPut, "/api/me/books/42/progress", strings.NewReader(body))
+	c.Request.Header.Set("Content-Type", "application/json")
+	c.Params = gin.Params{{Key: "bookId", Value: "42"}}
+	c.Set("user_id", "user-123")
+
+	h.SaveReadingProgress(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	require.Len(t, sessions.recorded, 1)
+	assert.Equal(t, "ch5", sessions.recorded[0].ChapterID)
+	assert.Equal(t, []string{models.SessionSourceWeb}, sessions.sources)
+}
+
+func TestProgressHandler_SaveReadingProgress_Offline(t *testing.T) {
+	at := time.Date(2026, 5, 1, 8, 30, 0, 0, time.UTC)
+	repo := &mockProgressRepo{
+		upsertIfNewerFn: func(_ context.Context, p *models.ReadingProgress) (bool, error) {
+			assert.Equal(t, "ch7", p.ChapterID)
+			assert.Equal(t, "mobile", p.Device)
+			assert.True(t, at.Equal(p.UpdatedAt))
+			return true, nil
+		},
+	}
+	sessions := &mockProgressRecorder{}
+	h := NewProgressHandler(repo, sessions)
+
+	body := `{"chapterId":"ch7","chapterProgress":10,"totalProgress":60,"device":"mobile","updatedAt":"2026-05-01T08:30:00Z"}`
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPut, "/api/me/books/42/progress", strings.NewReader(body))
+	c.Request.Header.Set("Content-Type", "application/json")
+	c.Params = gin.Params{{Key: "bookId", Value: "42"}}
+	c.Set("user_id", "user-123")
+
+	h.SaveReadingProgress(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Len(t, sessions.recorded, 1)
+}
+
+func TestProgressHandler_SaveReadingProgress_OfflineFutureTime(t *testing.T) {
+	repo := &mockProgressRepo{
+		upsertIfNewerFn: func(_ context.Context, p *models.ReadingProgress) (bool, error) {
+			assert.False(t, p.UpdatedAt.After(time.Now()), "client clock ahead is clamped")
+			return true, nil
+		},
+	}
+	h := NewProgressHandler(repo, nil)
+
+	body := `{"chapterId":"ch7","chapterProgress":10,"totalProgress":60,"device":"mobile","updatedAt":"2099-01-01T00:00:00Z"}`
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPut, "/api/me/books/42/progress", strings.NewReader(body))
+	c.Request.Header.Set("Content-Type", "application/json")
+	c.Params = gin.Params{{Key: "bookId", Value: "42"}}
+	c.Set("user_id", "user-123")
+
+	h.SaveReadingProgress(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestProgressHandler_SaveReadingProgress_OfflineStale(t *testing.T) {
+	repo := &mockProgressRepo{
+		upsertIfNewerFn: func(_ context.Context, _ *models.ReadingProgress) (bool, error) { return false, nil },
+		getFn: func(_ context.Context, userID string, bookID int64) (*models.ReadingProgress, error) {
+			assert.Equal(t, "user-123", userID)
+			assert.Equal(t, int64(42), bookID)
+			return &models.ReadingProgress{ChapterID: "ch9", TotalProgress: 80, Device: "desktop"}, nil
+		},
+	}
+	sessions := &mockProgressRecorder{}
+	h := NewProgressHandler(repo, sessions)
+
+	body := `{"chapterId":"ch7","chapterProgress":10,"totalProgress":60,"device":"mobile","updatedAt":"2026-05-01T08:30:00Z"}`
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPut, "/api/me/books/42/progress", strings.NewReader(body))
+	c.Request.Header.Set("Content-Type", "application/json")
+	c.Params = gin.Params{{Key: "bookId", Value: "42"}}
+	c.Set("user_id", "user-123")
+
+	h.SaveReadingProgress(c)
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Empty(t, sessions.recorded)
+
+	var resp struct {
+		Error    string                 `json:"error"`
+		Progress models.ReadingProgress `json:"progress"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "stale_progress", resp.Error)
+	assert.Equal(t, "ch9", resp.Progress.ChapterID)
+	assert.Equal(t, "desktop", resp.Progress.Device)
+}
+
 func TestProgressHandler_SaveReadingProgress_EmptyChapterID(t *testing.T) {
-	h := NewProgressHandler(&mockProgressRepo{})
+	h := NewProgressHandler(&mockProgressRepo{}, nil)
 
 	body := `{"chapterId":"","chapterProgress":50,"totalProgress":25}`
 	w := httptest.NewRecorder()
@@ -177,7 +284,7 @@ func TestProgressHandler_SaveReadingProgress_EmptyChapterID(t *testing.T) {
 }
 
 func TestProgressHandler_SaveReadingProgress_ProgressOutOfRange(t *testing.T) {
-	h := NewProgressHandler(&mockProgressRepo{})
+	h := NewProgressHandler(&mockProgressRepo{}, nil)
 
 	body := `{"chapterId":"ch1","chapterProgress":150,"totalProgress":50}`
 	w := httptest.NewRecorder()
@@ -194,7 +301,7 @@ func TestProgressHandler_SaveReadingProgress_ProgressOutOfRange(t *testing.T) {
 }
 
 func TestProgressHandler_SaveReadingProgress_NegativeProgress(t *testing.T) {
-	h := NewProgressHandler(&mockProgressRepo{})
+	h := NewProgressHandler(&mockProgressRepo{}, nil)
 
 	body := `{"chapterId":"ch1","chapterProgress":-5,"totalProgress":50}`
 	w := httptest.NewRecorder()
@@ -210,7 +317,7 @@ func TestProgressHandler_SaveReadingProgress_NegativeProgress(t *testing.T) {
 }
 
 func TestProgressHandler_SaveReadingProgress_InvalidJSON(t *testing.T) {
-	h := NewProgressHandler(&mockProgressRepo{})
+	h := NewProgressHandler(&mockProgressRepo{}, nil)
 
 	body := `not json`
 	w := httptest.NewRecorder()
@@ -226,7 +333,7 @@ func TestProgressHandler_SaveReadingProgress_InvalidJSON(t *testing.T) {
 }
 
 func TestProgressHandler_SaveReadingProgress_InvalidBookID(t *testing.T) {
-	h := NewProgressHandler(&mockProgressRepo{})
+	h := NewProgressHandler(&mockProgressRepo{}, nil)
 
 	body := `{"chapterId":"ch1","chapterProgress":50,"totalProgress":25}`
 	w := httptest.NewRecorder()
@@ -242,7 +349,7 @@ func TestProgressHandler_SaveReadingProgress_InvalidBookID(t *testing.T) {
 }
 
 func TestProgressHandler_SaveReadingProgress_NoUserID(t *testing.T) {
-	h := NewProgressHandler(&mockProgressRepo{})
+	h := NewProgressHandler(&mockProgressRepo{}, nil)
 
 	body := `{"chapterId":"ch1","chapterProgress":50,"totalProgress":25}`
 	w := httptest.NewRecorder()
@@ -257,7 +364,7 @@ func TestProgressHandler_SaveReadingProgress_NoUserID(t *testing.T) {
 }
 
 func TestProgressHandler_SaveReadingProgress_PathTraversalChapterID(t *testing.T) {
-	h := NewProgressHandler(&mockProgressRepo{})
+	h := NewProgressHandler(&mockProgressRepo{}, nil)
 
 	body := `{"chapterId":"../../../etc/passwd","chapterProgress":50,"totalProgress":25}`
 	w := httptest.NewRecorder()
@@ -274,7 +381,7 @@ func TestProgressHandler_SaveReadingProgress_PathTraversalChapterID(t *testing.T
 }
 
 func TestProgressHandler_SaveReadingProgress_SpecialCharsChapterID(t *testing.T) {
-	h := NewProgressHandler(&mockProgressRepo{})
+	h := NewProgressHandler(&mockProgressRepo{}, nil)
 
 	tests := []struct {
 		name      string
@@ -317,7 +424,7 @@ func TestProgressHandler_GetAllProgress_Success(t *testing.T) {
 			}, nil
 		},
 	}
-	h := NewProgressHandler(repo)
+	h := NewProgressHandler(repo, nil)
 
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
@@ -342,7 +449,7 @@ func TestProgressHandler_GetAllProgress_Empty(t *testing.T) {
 			return nil, nil
 		},
 	}
-	h := NewProgressHandler(repo)
+	h := NewProgressHandler(repo, nil)
 
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
@@ -359,7 +466,7 @@ func TestProgressHandler_GetAllProgress_Empty(t *testing.T) {
 }
 
 func TestProgressHandler_GetAllProgress_NoUserID(t *testing.T) {
-	h := NewProgressHandler(&mockProgressRepo{})
+	h := NewProgressHandler(&mockProgressRepo{}, nil)
 
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
@@ -376,7 +483,7 @@ func TestProgressHandler_GetAllProgress_DBError(t *testing.T) {
 			return nil, fmt.Errorf("connection lost")
 		},
 	}
-	h := NewProgressHandler(repo)
+	h := NewProgressHandler(repo, nil)
 
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
@@ -395,7 +502,7 @@ func TestProgressHandler_SaveReadingProgress_DBError(t *testing.T) {
 			return fmt.Errorf("disk full")
 		},
 	}
-	h := NewProgressHandler(repo)
+	h := NewProgressHandler(repo, nil)
 
 	body := `{"chapterId":"ch1","chapterProgress":50,"totalProgress":25}`
 	w := httptest.NewRecorder()
@@ -0,0 +1,120 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/grom-alex/homelib/backend/internal/models"
+)
+
+type RatingsHandler struct {
+	ratingRepo         RatingRepository
+	restrictionChecker BookRestrictionChecker
+}
+
+func NewRatingsHandler(repo RatingRepository, restrictionChecker BookRestrictionChecker) *RatingsHandler {
+	return &RatingsHandler{ratingRepo: repo, restrictionChecker: restrictionChecker}
+}
+
+// ListBookRatings handles GET /api/books/:id/ratings.
+// Returns the ratings and reviews of all users, newest first.
+func (h *RatingsHandler) ListBookRatings(c *gin.Context) {
+	bookID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_id", "message": "Некорректный ID книги"})
+		return
+	}
+	if denyRestrictedBook(c, h.restrictionChecker, bookID) {
+		return
+	}
+
+	reviews, err := h.ratingRepo.ListByBook(c.Request.Context(), bookID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal_error", "message": "Внутренняя ошибка сервера"})
+		return
+	}
+
+	userID := c.GetString("user_id")
+	for i := range reviews {
+		reviews[i].Own = reviews[i].UserID == userID
+	}
+
+	c.JSON(http.StatusOK, reviews)
+}
+
+// GetRating handles GET /api/me/books/:bookId/rating.
+func (h *RatingsHandler) GetRating(c *gin.Context) {
+	userID, bookID, ok := bookScope(c, h.restrictionChecker)
+	if !ok {
+		return
+	}
+
+	rating, err := h.ratingRepo.Get(c.Request.Context(), userID, bookID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal_error", "message": "Внутренняя ошибка сервера"})
+		return
+	}
+	if rating == nil {
+		c.Status(http.StatusNoContent)
+		c.Writer.WriteHeaderNow()
+		return
+	}
+
+	c.JSON(http.StatusOK, rating)
+}
+
+// SaveRating handles PUT /api/me/books/:bookId/rating.
+func (h *RatingsHandler) SaveRating(c *gin.Context) {
+	userID, bookID, ok := bookScope(c, h.restrictionChecker)
+	if !ok {
+		return
+	}
+
+	var input models.SaveRatingInput
+	if err := c.ShouldBindJSON(&input); err != nil || input.Validate() != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "validation_error", "message": "Невалидная оценка"})
+		return
+	}
+
+	rating := &models.BookRating{
+		UserID: userID,
+		BookID: bookID,
+		Rating: input.Rating,
+		Scale:  input.Scale,
+		Review: input.Review,
+	}
+	saved, err := h.ratingRepo.Save(c.Request.Context(), rating)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal_error", "message": "Внутренняя ошибка сервера"})
+		return
+	}
+	if !saved {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not_found", "message": "Книга не найдена"})
+		return
+	}
+
+	c.JSON(http.StatusOK, rating)
+}
+
+// DeleteRating handles DELETE /api/me/books/:bookId/rating.
+func (h *RatingsHandler) DeleteRating(c *gin.Context) {
+	userID, bookID, ok := bookScope(c, h.restrictionChecker)
+	if !ok {
+		return
+	}
+
+	deleted, err := h.ratingRepo.Delete(c.Request.Context(), userID, bookID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal_error", "message": "Внутренняя ошибка сервера"})
+		return
+	}
+	if !deleted {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not_found", "message": "Оценка не найдена"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+	c.Writer.WriteHeaderNow()
+}
@@ -0,0 +1,129 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grom-alex/homelib/backend/internal/models"
+)
+
+func TestRatingsHandler_ListBookRatings(t *testing.T) {
+	four, eight := 4, 8
+	repo := &mockRatingRepo{
+		listByBookFn: func(_ context.Context, bookID int64) ([]models.BookReview, error) {
+			assert.Equal(t, int64(42), bookID)
+			return []models.BookReview{
+				{BookRating: models.BookRating{UserID: "user-123", BookID: 42, Rating: &four, Scale: 5}, UserName: "Алиса"},
+				{BookRating: models.BookRating{UserID: "user-456", BookID: 42, Rating: &eight, Scale: 10, Review: "Сильно"}, UserName: "Борис"},
+			}, nil
+		},
+	}
+	h := NewRatingsHandler(repo, &mockBookRestrictionChecker{})
+
+	c, w := newAnnotationContext(http.MethodGet, "/api/books/42/ratings", "", gin.Params{{Key: "id", Value: "42"}})
+	h.ListBookRatings(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var resp []map[string]any
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Len(t, resp, 2)
+	assert.Equal(t, true, resp[0]["own"])
+	assert.Equal(t, false, resp[1]["own"])
+	assert.Equal(t, "Борис", resp[1]["userName"])
+	assert.NotContains(t, resp[0], "userId")
+}
+
+func TestRatingsHandler_ListBookRatings_Restricted(t *testing.T) {
+	checker := &mockBookRestrictionChecker{
+		isBookRestrictedFn: func(_ context.Context, _ int64, _ []int) (bool, error) { return true, nil },
+	}
+	h := NewRatingsHandler(&mockRatingRepo{}, checker)
+
+	c, w := newAnnotationContext(http.MethodGet, "/api/books/42/ratings", "", gin.Params{{Key: "id", Value: "42"}})
+	c.Set("restricted_genre_ids", []int{5})
+	h.ListBookRatings(c)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestRatingsHandler_GetRating(t *testing.T) {
+	five := 5
+	repo := &mockRatingRepo{
+		getFn: func(_ context.Context, userID string, bookID int64) (*models.BookRating, error) {
+			if bookID != 42 {
+				return nil, nil
+			}
+			return &models.BookRating{UserID: userID, BookID: bookID, Rating: &five, Scale: 5}, nil
+		},
+	}
+	h := NewRatingsHandler(repo, &mockBookRestrictionChecker{})
+
+	c, w := newAnnotationContext(http.MethodGet, "/api/me/books/42/rating", "", gin.Params{{Key: "bookId", Value: "42"}})
+	h.GetRating(c)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"rating":5`)
+
+	c, w = newAnnotationContext(http.MethodGet, "/api/me/books/43/rating", "", gin.Params{{Key: "bookId", Value: "43"}})
+	h.GetRating(c)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+}
+
+func TestRatingsHandler_SaveRating(t *testing.T) {
+	var saved *models.BookRating
+	repo := &mockRatingRepo{
+		saveFn: func(_ context.Context, r *models.BookRating) (bool, error) {
+			saved = r
+			return r.BookID == 42, nil
+		},
+	}
+	h := NewRatingsHandler(repo, &mockBookRestrictionChecker{})
+	params := gin.Params{{Key: "bookId", Value: "42"}}
+
+	c, w := newAnnotationContext(http.MethodPut, "/api/me/books/42/rating", `{"rating":9,"scale":10,"review":"Перечитаю"}`, params)
+	h.SaveRating(c)
+	assert.Equal(t, http.StatusOK, w.Code)
+	require.NotNil(t, saved)
+	assert.Equal(t, "user-123", saved.UserID)
+	require.NotNil(t, saved.Rating)
+	assert.Equal(t, 9, *saved.Rating)
+	assert.Equal(t, 10, saved.Scale)
+
+	c, w = newAnnotationContext(http.MethodPut, "/api/me/books/43/rating", `{"rating":3}`, gin.Params{{Key: "bookId", Value: "43"}})
+	h.SaveRating(c)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, 5, saved.Scale)
+}
+
+func TestRatingsHandler_SaveRating_Invalid(t *testing.T) {
+	h := NewRatingsHandler(&mockRatingRepo{}, &mockBookRestrictionChecker{})
+	params := gin.Params{{Key: "bookId", Value: "42"}}
+
+	for _, body := range []string{`{"rating":6}`, `{"rating":3,"scale":7}`, `{}`} {
+		c, w := newAnnotationContext(http.MethodPut, "/api/me/books/42/rating", body, params)
+		h.SaveRating(c)
+		assert.Equal(t, http.StatusBadRequest, w.Code, body)
+	}
+}
+
+func TestRatingsHandler_DeleteRating(t *testing.T) {
+	repo := &mockRatingRepo{
+		deleteFn: func(_ context.Context, _ string, bookID int64) (bool, error) {
+			return bookID == 42, nil
+		},
+	}
+	h := NewRatingsHandler(repo, &mockBookRestrictionChecker{})
+
+	c, w := newAnnotationContext(http.MethodDelete, "/api/me/books/42/rating", "", gin.Params{{Key: "bookId", Value: "42"}})
+	h.DeleteRating(c)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	c, w = newAnnotationContext(http.MethodDelete, "/api/me/books/43/rating", "", gin.Params{{Key: "bookId", Value: "43"}})
+	h.DeleteRating(c)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
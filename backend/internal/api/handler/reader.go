@@ -1,13 +1,18 @@
 package handler
 
 import (
+	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
+	"slices"
 	"strconv"
 	"strings"
 
 	"github.com/gin-gonic/gin"
 
+	"github.com/grom-alex/homelib/backend/internal/bookfile"
+	"github.com/grom-alex/homelib/backend/internal/models"
 	"github.com/grom-alex/homelib/backend/internal/service"
 )
 
@@ -23,8 +28,14 @@ func NewReaderHandler(readerSvc ReaderServicer, restrictionChecker BookRestricti
 // checkBookRestriction returns true (and writes error response) if the book is restricted or check fails.
 // Follows fail-closed principle: blocks access on errors.
 func (h *ReaderHandler) checkBookRestriction(c *gin.Context, bookID int64) bool {
+	return denyRestrictedBook(c, h.restrictionChecker, bookID)
+}
+
+// denyRestrictedBook implements checkBookRestriction for handlers serving
+// per-book user data.
+func denyRestrictedBook(c *gin.Context, checker BookRestrictionChecker, bookID int64) bool {
 	if restrictedIDs := getRestrictedGenreIDs(c); len(restrictedIDs) > 0 {
-		restricted, err := h.restrictionChecker.IsBookRestricted(c.Request.Context(), bookID, restrictedIDs)
+		restricted, err := checker.IsBookRestricted(c.Request.Context(), bookID, restrictedIDs)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal_error", "message": "Ошибка проверки ограничений"})
 			return true
@@ -103,7 +114,13 @@ func (h *ReaderHandler) GetBookImage(c *gin.Context) {
 		return
 	}
 
-	img, err := h.readerSvc.GetBookImage(c.Request.Context(), id, imageID)
+	opts, err := imageOptions(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_image_options", "message": "Некорректные параметры изображения"})
+		return
+	}
+
+	img, err := h.readerSvc.GetBookImage(c.Request.Context(), id, imageID, opts)
 	if err != nil {
 		h.handleReaderError(c, err)
 		return
@@ -119,6 +136,143 @@ func (h *ReaderHandler) GetBookImage(c *gin.Context) {
 	c.Data(http.StatusOK, contentType, img.Data)
 }
 
+// GetBookBundle handles GET /api/books/:id/bundle.
+// Streams the whole book for offline reading as NDJSON, one service.BundleItem
+// per line: the book content, chapters, notes and images, then an "end" item.
+// Accepts the transcoding parameters of GetBookImage for the images.
+func (h *ReaderHandler) GetBookBundle(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_id", "message": "Некорректный ID книги"})
+		return
+	}
+
+	if h.checkBookRestriction(c, id) {
+		return
+	}
+
+	opts, err := imageOptions(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_image_options", "message": "Некорректные параметры изображения"})
+		return
+	}
+
+	enc := json.NewEncoder(c.Writer)
+	started := false
+	err = h.readerSvc.Bundle(c.Request.Context(), id, opts, func(item *service.BundleItem) error {
+		if !started {
+			c.Header("Content-Type", "application/x-ndjson")
+			c.Header("Cache-Control", "no-store")
+			c.Status(http.StatusOK)
+			started = true
+		}
+		if err := enc.Encode(item); err != nil {
+			return err
+		}
+		c.Writer.Flush()
+		return nil
+	})
+	if err != nil {
+		if !started {
+			h.handleReaderError(c, err)
+			return
+		}
+		// Too late for an error status: the missing end item tells the
+		// client that the bundle is incomplete
+		_ = c.Error(err)
+	}
+}
+
+// imageOptions parses the transcoding parameters of an image request:
+// w (maximum width, one of bookfile.ImageWidths), format (webp or jpeg) and grayscale.
+func imageOptions(c *gin.Context) (bookfile.ImageOptions, error) {
+	var opts bookfile.ImageOptions
+	if w := c.Query("w"); w != "" {
+		width, err := strconv.Atoi(w)
+		if err != nil || !slices.Contains(bookfile.ImageWidths, width) {
+			return opts, fmt.Errorf("invalid width %q", w)
+		}
+		opts.Width = width
+	}
+	switch format := c.Query("format"); format {
+	case "":
+	case bookfile.ImageFormatJPEG, "jpg":
+		opts.Format = bookfile.ImageFormatJPEG
+	case bookfile.ImageFormatWebP:
+		opts.Format = bookfile.ImageFormatWebP
+	default:
+		return opts, fmt.Errorf("unsupported format %q", format)
+	}
+	if gray, ok := c.GetQuery("grayscale"); ok {
+		// A bare ?grayscale enables it
+		if gray == "" {
+			opts.Grayscale = true
+		} else if v, err := strconv.ParseBool(gray); err == nil {
+			opts.Grayscale = v
+		} else {
+			return opts, fmt.Errorf("invalid grayscale %q", gray)
+		}
+	}
+	return opts, nil
+}
+
+// GetNote handles GET /api/books/:id/notes/:noteId.
+// Returns a single footnote for popup display.
+func (h *ReaderHandler) GetNote(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_id", "message": "Некорректный ID книги"})
+		return
+	}
+
+	if h.checkBookRestriction(c, id) {
+		return
+	}
+
+	noteID := c.Param("noteId")
+	if err := service.ValidateResourceID(noteID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_note", "message": "Некорректный ID сноски"})
+		return
+	}
+
+	note, err := h.readerSvc.GetNote(c.Request.Context(), id, noteID)
+	if err != nil {
+		h.handleReaderError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, note)
+}
+
+// SearchBook handles GET /api/books/:id/search.
+func (h *ReaderHandler) SearchBook(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_id", "message": "Некорректный ID книги"})
+		return
+	}
+
+	if h.checkBookRestriction(c, id) {
+		return
+	}
+
+	var f models.BookSearchFilter
+	err = c.ShouldBindQuery(&f)
+	f.SetDefaults()
+	if err != nil || f.Query == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_query", "message": "Некорректный поисковый запрос"})
+		return
+	}
+
+	hits, total, err := h.readerSvc.SearchBook(c.Request.Context(), id, f)
+	if err != nil {
+		h.handleReaderError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, listResponse(hits, models.PageInfo{Total: total}, f.Page, f.Limit))
+}
+
 // handleReaderError maps service errors to HTTP responses per contract.
 func (h *ReaderHandler) handleReaderError(c *gin.Context, err error) {
 	switch {
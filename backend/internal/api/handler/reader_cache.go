@@ -0,0 +1,58 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ReaderCacheHandler struct {
+	cacheSvc ReaderCacheServicer
+}
+
+func NewReaderCacheHandler(cacheSvc ReaderCacheServicer) *ReaderCacheHandler {
+	return &ReaderCacheHandler{cacheSvc: cacheSvc}
+}
+
+// Stats handles GET /api/admin/reader-cache.
+func (h *ReaderCacheHandler) Stats(c *gin.Context) {
+	stats, err := h.cacheSvc.CacheStats(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get cache stats"})
+		return
+	}
+	c.JSON(http.StatusOK, stats)
+}
+
+// PurgeBook handles DELETE /api/admin/reader-cache/books/:id.
+func (h *ReaderCacheHandler) PurgeBook(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || id <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid book id"})
+		return
+	}
+
+	found, err := h.cacheSvc.PurgeBookCache(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to purge cache"})
+		return
+	}
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{"error": "book is not cached"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+	c.Writer.WriteHeaderNow()
+}
+
+// PurgeAll handles DELETE /api/admin/reader-cache.
+func (h *ReaderCacheHandler) PurgeAll(c *gin.Context) {
+	removed, err := h.cacheSvc.PurgeCache(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to purge cache"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"removed": removed})
+}
@@ -0,0 +1,89 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grom-alex/homelib/backend/internal/models"
+)
+
+func TestReaderCacheHandler_Stats(t *testing.T) {
+	svc := &mockReaderCacheService{
+		cacheStatsFn: func(context.Context) (*models.ReaderCacheStats, error) {
+			return &models.ReaderCacheStats{SizeBytes: 4096, MaxSizeBytes: 1 << 30, Entries: 2, Files: 7, Hits: 3, Misses: 1, HitRatio: 0.75}, nil
+		},
+	}
+	h := NewReaderCacheHandler(svc)
+
+	c, w := newAnnotationContext(http.MethodGet, "/api/admin/reader-cache", "", nil)
+	h.Stats(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var resp models.ReaderCacheStats
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, int64(4096), resp.SizeBytes)
+	assert.Equal(t, 2, resp.Entries)
+	assert.InDelta(t, 0.75, resp.HitRatio, 1e-9)
+}
+
+func TestReaderCacheHandler_Stats_Error(t *testing.T) {
+	h := NewReaderCacheHandler(&mockReaderCacheService{})
+
+	c, w := newAnnotationContext(http.MethodGet, "/api/admin/reader-cache", "", nil)
+	h.Stats(c)
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+}
+
+func TestReaderCacheHandler_PurgeBook(t *testing.T) {
+	svc := &mockReaderCacheService{
+		purgeBookCacheFn: func(_ context.Context, bookID int64) (bool, error) {
+			return bookID == 42, nil
+		},
+	}
+	h := NewReaderCacheHandler(svc)
+
+	c, w := newAnnotationContext(http.MethodDelete, "/api/admin/reader-cache/books/42", "", gin.Params{{Key: "id", Value: "42"}})
+	h.PurgeBook(c)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	c, w = newAnnotationContext(http.MethodDelete, "/api/admin/reader-cache/books/7", "", gin.Params{{Key: "id", Value: "7"}})
+	h.PurgeBook(c)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	c, w = newAnnotationContext(http.MethodDelete, "/api/admin/reader-cache/books/abc", "", gin.Params{{Key: "id", Value: "abc"}})
+	h.PurgeBook(c)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestReaderCacheHandler_PurgeAll(t *testing.T) {
+	svc := &mockReaderCacheService{
+		purgeCacheFn: func(context.Context) (int, error) { return 5, nil },
+	}
+	h := NewReaderCacheHandler(svc)
+
+	c, w := newAnnotationContext(http.MethodDelete, "/api/admin/reader-cache", "", nil)
+	h.PurgeAll(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"removed":5}`, w.Body.String())
+}
+
+func TestReaderCacheHandler_PurgeAll_Error(t *testing.T) {
+	svc := &mockReaderCacheService{
+		purgeCacheFn: func(context.Context) (int, error) { return 0, fmt.Errorf("disk error") },
+	}
+	h := NewReaderCacheHandler(svc)
+
+	c, w := newAnnotationContext(http.MethodDelete, "/api/admin/reader-cache", "", nil)
+	h.PurgeAll(c)
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/gin-gonic/gin"
@@ -13,6 +14,7 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/grom-alex/homelib/backend/internal/bookfile"
+	"github.com/grom-alex/homelib/backend/internal/models"
 	"github.com/grom-alex/homelib/backend/internal/service"
 )
 
@@ -193,12 +195,162 @@ func TestReaderHandler_GetChapter_ChapterNotFound(t *testing.T) {
 	assert.Equal(t, http.StatusNotFound, w.Code)
 }
 
+// --- GetNote ---
+
+func TestReaderHandler_GetNote_Success(t *testing.T) {
+	svc := &mockReaderService{
+		getNoteFn: func(_ context.Context, bookID int64, noteID string) (*bookfile.NoteContent, error) {
+			assert.Equal(t, int64(42), bookID)
+			assert.Equal(t, "note1", noteID)
+			return &bookfile.NoteContent{ID: "note1", Title: "1", HTML: `<p>Сноска</p>`}, nil
+		},
+	}
+	h := NewReaderHandler(svc, &mockBookRestrictionChecker{})
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/api/books/42/notes/note1", nil)
+	c.Params = gin.Params{{Key: "id", Value: "42"}, {Key: "noteId", Value: "note1"}}
+
+	h.GetNote(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var resp bookfile.NoteContent
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "note1", resp.ID)
+	assert.Equal(t, "1", resp.Title)
+	assert.Equal(t, `<p>Сноска</p>`, resp.HTML)
+}
+
+func TestReaderHandler_GetNote_InvalidNoteID(t *testing.T) {
+	h := NewReaderHandler(&mockReaderService{}, &mockBookRestrictionChecker{})
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/api/books/42/notes/bad", nil)
+	c.Params = gin.Params{{Key: "id", Value: "42"}, {Key: "noteId", Value: "../note1"}}
+
+	h.GetNote(c)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestReaderHandler_GetNote_NotFound(t *testing.T) {
+	svc := &mockReaderService{
+		getNoteFn: func(_ context.Context, _ int64, _ string) (*bookfile.NoteContent, error) {
+			return nil, fmt.Errorf("%w: note \"missing\"", service.ErrBookNotFound)
+		},
+	}
+	h := NewReaderHandler(svc, &mockBookRestrictionChecker{})
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/api/books/1/notes/missing", nil)
+	c.Params = gin.Params{{Key: "id", Value: "1"}, {Key: "noteId", Value: "missing"}}
+
+	h.GetNote(c)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestReaderHandler_GetNote_UnsupportedFormat(t *testing.T) {
+	svc := &mockReaderService{
+		getNoteFn: func(_ context.Context, _ int64, _ string) (*bookfile.NoteContent, error) {
+			return nil, fmt.Errorf("%w: no footnote support", service.ErrUnsupportedFormat)
+		},
+	}
+	h := NewReaderHandler(svc, &mockBookRestrictionChecker{})
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/api/books/1/notes/n1", nil)
+	c.Params = gin.Params{{Key: "id", Value: "1"}, {Key: "noteId", Value: "n1"}}
+
+	h.GetNote(c)
+
+	assert.Equal(t, http.StatusUnsupportedMediaType, w.Code)
+}
+
+// --- SearchBook ---
+
+func TestReaderHandler_SearchBook_Success(t *testing.T) {
+	svc := &mockReaderService{
+		searchBookFn: func(_ context.Context, bookID int64, f models.BookSearchFilter) ([]models.BookSearchHit, int, error) {
+			assert.Equal(t, int64(42), bookID)
+			assert.Equal(t, "ёжик", f.Query)
+			assert.Equal(t, 2, f.Page)
+			assert.Equal(t, 20, f.Limit)
+			return []models.BookSearchHit{{
+				ChapterID:   "ch2",
+				Locator:     models.Locator{Paragraph: 3, Offset: 5},
+				Snippet:     "…и ёжик",
+				MatchOffset: 3,
+				MatchLength: 4,
+			}}, 21, nil
+		},
+	}
+	h := NewReaderHandler(svc, &mockBookRestrictionChecker{})
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/api/books/42/search?q=%D1%91%D0%B6%D0%B8%D0%BA&page=2", nil)
+	c.Params = gin.Params{{Key: "id", Value: "42"}}
+
+	h.SearchBook(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var resp struct {
+		Items []models.BookSearchHit `json:"items"`
+		Total int                    `json:"total"`
+		Page  int                    `json:"page"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, 21, resp.Total)
+	assert.Equal(t, 2, resp.Page)
+	require.Len(t, resp.Items, 1)
+	assert.Equal(t, "ch2", resp.Items[0].ChapterID)
+	assert.Equal(t, models.Locator{Paragraph: 3, Offset: 5}, resp.Items[0].Locator)
+}
+
+func TestReaderHandler_SearchBook_EmptyQuery(t *testing.T) {
+	h := NewReaderHandler(&mockReaderService{}, &mockBookRestrictionChecker{})
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/api/books/42/search?q=+", nil)
+	c.Params = gin.Params{{Key: "id", Value: "42"}}
+
+	h.SearchBook(c)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestReaderHandler_SearchBook_Restricted(t *testing.T) {
+	checker := &mockBookRestrictionChecker{
+		isBookRestrictedFn: func(_ context.Context, _ int64, _ []int) (bool, error) { return true, nil },
+	}
+	h := NewReaderHandler(&mockReaderService{}, checker)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/api/books/42/search?q=x", nil)
+	c.Params = gin.Params{{Key: "id", Value: "42"}}
+	c.Set("restricted_genre_ids", []int{5})
+
+	h.SearchBook(c)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
 // --- GetBookImage ---
 
 func TestReaderHandler_GetBookImage_Success(t *testing.T) {
 	imgData := []byte{0x89, 'P', 'N', 'G', 0x0d, 0x0a, 0x1a, 0x0a}
 	svc := &mockReaderService{
-		getBookImageFn: func(_ context.Context, bookID int64, imageID string) (*bookfile.ImageData, error) {
+		getBookImageFn: func(_ context.Context, bookID int64, imageID string, opts bookfile.ImageOptions) (*bookfile.ImageData, error) {
+			assert.True(t, opts.IsZero())
 			assert.Equal(t, int64(42), bookID)
 			assert.Equal(t, "cover.jpg", imageID)
 			return &bookfile.ImageData{
@@ -225,7 +377,7 @@ func TestReaderHandler_GetBookImage_Success(t *testing.T) {
 
 func TestReaderHandler_GetBookImage_PNG(t *testing.T) {
 	svc := &mockReaderService{
-		getBookImageFn: func(_ context.Context, _ int64, _ string) (*bookfile.ImageData, error) {
+		getBookImageFn: func(_ context.Context, _ int64, _ string, _ bookfile.ImageOptions) (*bookfile.ImageData, error) {
 			return &bookfile.ImageData{
 				ID:          "img1.png",
 				ContentType: "image/png",
@@ -261,7 +413,7 @@ func TestReaderHandler_GetBookImage_InvalidID(t *testing.T) {
 
 func TestReaderHandler_GetBookImage_NotFound(t *testing.T) {
 	svc := &mockReaderService{
-		getBookImageFn: func(_ context.Context, _ int64, _ string) (*bookfile.ImageData, error) {
+		getBookImageFn: func(_ context.Context, _ int64, _ string, _ bookfile.ImageOptions) (*bookfile.ImageData, error) {
 			return nil, fmt.Errorf("%w: image \"nonexistent\"", service.ErrBookNotFound)
 		},
 	}
@@ -277,6 +429,154 @@ func TestReaderHandler_GetBookImage_NotFound(t *testing.T) {
 	assert.Equal(t, http.StatusNotFound, w.Code)
 }
 
+func TestReaderHandler_GetBookImage_Transcoding(t *testing.T) {
+	var got bookfile.ImageOptions
+	svc := &mockReaderService{
+		getBookImageFn: func(_ context.Context, _ int64, _ string, opts bookfile.ImageOptions) (*bookfile.ImageData, error) {
+			got = opts
+			return &bookfile.ImageData{ID: "img1.png", ContentType: "image/webp", Data: []byte("RIFF")}, nil
+		},
+	}
+	h := NewReaderHandler(svc, &mockBookRestrictionChecker{})
+
+	tests := []struct {
+		query string
+		want  bookfile.ImageOptions
+	}{
+		{"v=2", bookfile.ImageOptions{}},
+		{"v=2&w=600&format=webp&grayscale=1", bookfile.ImageOptions{Width: 600, Format: bookfile.ImageFormatWebP, Grayscale: true}},
+		{"format=jpg&grayscale", bookfile.ImageOptions{Format: bookfile.ImageFormatJPEG, Grayscale: true}},
+		{"w=4096&grayscale=false", bookfile.ImageOptions{Width: bookfile.MaxImageWidth}},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/api/books/1/image/img1.png?"+tt.query, nil)
+		c.Params = gin.Params{{Key: "id", Value: "1"}, {Key: "imageId", Value: "img1.png"}}
+
+		h.GetBookImage(c)
+
+		assert.Equal(t, http.StatusOK, w.Code, tt.query)
+		assert.Equal(t, "image/webp", w.Header().Get("Content-Type"), tt.query)
+		assert.Equal(t, tt.want, got, tt.query)
+	}
+}
+
+func TestReaderHandler_GetBookImage_InvalidOptions(t *testing.T) {
+	h := NewReaderHandler(&mockReaderService{}, &mockBookRestrictionChecker{})
+
+	for _, query := range []string{"w=0", "w=abc", "w=601", "w=100000", "format=bmp", "grayscale=maybe"} {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/api/books/1/image/img1.png?"+query, nil)
+		c.Params = gin.Params{{Key: "id", Value: "1"}, {Key: "imageId", Value: "img1.png"}}
+
+		h.GetBookImage(c)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code, query)
+		assert.Contains(t, w.Body.String(), "invalid_image_options", query)
+	}
+}
+
+// --- GetBookBundle ---
+
+func TestReaderHandler_GetBookBundle_Success(t *testing.T) {
+	var got bookfile.ImageOptions
+	svc := &mockReaderService{
+		bundleFn: func(_ context.Context, bookID int64, opts bookfile.ImageOptions, emit func(*service.BundleItem) error) error {
+			assert.Equal(t, int64(7), bookID)
+			got = opts
+			for _, item := range []*service.BundleItem{
+				{Type: service.BundleItemContent, Content: &bookfile.BookContent{ChapterIDs: []string{"ch1"}}},
+				{Type: service.BundleItemChapter, Chapter: &bookfile.ChapterContent{ID: "ch1", HTML: "<p>Hi</p>"}},
+				{Type: service.BundleItemEnd, Chapters: 1},
+			} {
+				if err := emit(item); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+	h := NewReaderHandler(svc, &mockBookRestrictionChecker{})
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/api/books/7/bundle?w=600&format=webp", nil)
+	c.Params = gin.Params{{Key: "id", Value: "7"}}
+
+	h.GetBookBundle(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/x-ndjson", w.Header().Get("Content-Type"))
+	assert.Equal(t, bookfile.ImageOptions{Width: 600, Format: bookfile.ImageFormatWebP}, got)
+
+	lines := strings.Split(strings.TrimSpace(w.Body.String()), "\n")
+	require.Len(t, lines, 3)
+	var types []string
+	for _, line := range lines {
+		var item service.BundleItem
+		require.NoError(t, json.Unmarshal([]byte(line), &item))
+		types = append(types, item.Type)
+	}
+	assert.Equal(t, []string{"content", "chapter", "end"}, types)
+}
+
+func TestReaderHandler_GetBookBundle_NotFound(t *testing.T) {
+	svc := &mockReaderService{
+		bundleFn: func(_ context.Context, _ int64, _ bookfile.ImageOptions, _ func(*service.BundleItem) error) error {
+			return service.ErrBookNotFound
+		},
+	}
+	h := NewReaderHandler(svc, &mockBookRestrictionChecker{})
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/api/books/7/bundle", nil)
+	c.Params = gin.Params{{Key: "id", Value: "7"}}
+
+	h.GetBookBundle(c)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Contains(t, w.Body.String(), "not_found")
+}
+
+func TestReaderHandler_GetBookBundle_FailsMidStream(t *testing.T) {
+	svc := &mockReaderService{
+		bundleFn: func(_ context.Context, _ int64, _ bookfile.ImageOptions, emit func(*service.BundleItem) error) error {
+			if err := emit(&service.BundleItem{Type: service.BundleItemContent, Content: &bookfile.BookContent{}}); err != nil {
+				return err
+			}
+			return fmt.Errorf("disk error")
+		},
+	}
+	h := NewReaderHandler(svc, &mockBookRestrictionChecker{})
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/api/books/7/bundle", nil)
+	c.Params = gin.Params{{Key: "id", Value: "7"}}
+
+	h.GetBookBundle(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, w.Body.String(), `"end"`, "a cut-off bundle has no end item")
+	assert.Len(t, c.Errors, 1)
+}
+
+func TestReaderHandler_GetBookBundle_InvalidOptions(t *testing.T) {
+	h := NewReaderHandler(&mockReaderService{}, &mockBookRestrictionChecker{})
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/api/books/7/bundle?format=bmp", nil)
+	c.Params = gin.Params{{Key: "id", Value: "7"}}
+
+	h.GetBookBundle(c)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
 // --- Error mapping ---
 
 func TestReaderHandler_InternalError(t *testing.T) {
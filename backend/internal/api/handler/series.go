@@ -1,10 +1,12 @@
 package handler
 
 import (
+	"errors"
 	"net/http"
-	"strconv"
 
 	"github.com/gin-gonic/gin"
+
+	"github.com/grom-alex/homelib/backend/internal/models"
 )
 
 type SeriesHandler struct {
@@ -17,20 +19,22 @@ func NewSeriesHandler(catalogSvc CatalogServicer) *SeriesHandler {
 
 // ListSeries handles GET /api/series.
 func (h *SeriesHandler) ListSeries(c *gin.Context) {
-	query := c.Query("q")
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	var f models.ListFilter
+	if err := c.ShouldBindQuery(&f); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid query parameters"})
+		return
+	}
+	f.SetDefaults()
 
-	series, total, err := h.catalogSvc.ListSeries(c.Request.Context(), query, page, limit)
+	series, info, err := h.catalogSvc.ListSeries(c.Request.Context(), f)
 	if err != nil {
+		if errors.Is(err, models.ErrInvalidCursor) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid cursor"})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list series"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"items": series,
-		"total": total,
-		"page":  page,
-		"limit": limit,
-	})
+	c.JSON(http.StatusOK, listResponse(series, info, f.Page, f.Limit))
 }
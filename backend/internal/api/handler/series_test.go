@@ -17,11 +17,11 @@ import (
 
 func TestSeriesHandler_ListSeries_Success(t *testing.T) {
 	svc := &mockCatalogService{
-		listSeriesFn: func(_ context.Context, query string, page, limit int) ([]models.SeriesListItem, int, error) {
-			assert.Equal(t, "ring", query)
+		listSeriesFn: func(_ context.Context, f models.ListFilter) ([]models.SeriesListItem, models.PageInfo, error) {
+			assert.Equal(t, "ring", f.Query)
 			return []models.SeriesListItem{
 				{ID: 1, Name: "Lord of the Rings", BooksCount: 3},
-			}, 1, nil
+			}, models.PageInfo{Total: 1}, nil
 		},
 	}
 	h := NewSeriesHandler(svc)
@@ -41,8 +41,8 @@ func TestSeriesHandler_ListSeries_Success(t *testing.T) {
 
 func TestSeriesHandler_ListSeries_Error(t *testing.T) {
 	svc := &mockCatalogService{
-		listSeriesFn: func(_ context.Context, _ string, _, _ int) ([]models.SeriesListItem, int, error) {
-			return nil, 0, fmt.Errorf("db error")
+		listSeriesFn: func(_ context.Context, _ models.ListFilter) ([]models.SeriesListItem, models.PageInfo, error) {
+			return nil, models.PageInfo{}, fmt.Errorf("db error")
 		},
 	}
 	h := NewSeriesHandler(svc)
@@ -58,11 +58,11 @@ func TestSeriesHandler_ListSeries_Error(t *testing.T) {
 
 func TestSeriesHandler_ListSeries_DefaultParams(t *testing.T) {
 	svc := &mockCatalogService{
-		listSeriesFn: func(_ context.Context, query string, page, limit int) ([]models.SeriesListItem, int, error) {
-			assert.Equal(t, "", query)
-			assert.Equal(t, 1, page)
-			assert.Equal(t, 20, limit)
-			return []models.SeriesListItem{}, 0, nil
+		listSeriesFn: func(_ context.Context, f models.ListFilter) ([]models.SeriesListItem, models.PageInfo, error) {
+			assert.Equal(t, "", f.Query)
+			assert.Equal(t, 1, f.Page)
+			assert.Equal(t, 20, f.Limit)
+			return []models.SeriesListItem{}, models.PageInfo{Total: 0}, nil
 		},
 	}
 	h := NewSeriesHandler(svc)
@@ -0,0 +1,338 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/grom-alex/homelib/backend/internal/models"
+)
+
+type ShelvesHandler struct {
+	shelfRepo          ShelfRepository
+	restrictionChecker BookRestrictionChecker
+}
+
+func NewShelvesHandler(repo ShelfRepository, restrictionChecker BookRestrictionChecker) *ShelvesHandler {
+	return &ShelvesHandler{shelfRepo: repo, restrictionChecker: restrictionChecker}
+}
+
+// shelfScope extracts the user and the :shelfId path parameter.
+// Returns ok=false after writing an error response.
+func shelfScope(c *gin.Context) (userID string, shelfID int64, ok bool) {
+	userID = c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized", "message": "Пользователь не авторизован"})
+		return "", 0, false
+	}
+
+	shelfID, err := strconv.ParseInt(c.Param("shelfId"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_id", "message": "Некорректный ID полки"})
+		return "", 0, false
+	}
+	return userID, shelfID, true
+}
+
+// shelfBookID parses the :bookId path parameter and applies the parental filter.
+func shelfBookID(c *gin.Context, checker BookRestrictionChecker) (int64, bool) {
+	bookID, err := strconv.ParseInt(c.Param("bookId"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_id", "message": "Некорректный ID книги"})
+		return 0, false
+	}
+	if denyRestrictedBook(c, checker, bookID) {
+		return 0, false
+	}
+	return bookID, true
+}
+
+func shelfNotFound(c *gin.Context) {
+	c.JSON(http.StatusNotFound, gin.H{"error": "not_found", "message": "Полка не найдена"})
+}
+
+func shelfInternalError(c *gin.Context) {
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "internal_error", "message": "Внутренняя ошибка сервера"})
+}
+
+// ListShelves handles GET /api/me/shelves.
+func (h *ShelvesHandler) ListShelves(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized", "message": "Пользователь не авторизован"})
+		return
+	}
+
+	shelves, err := h.shelfRepo.List(c.Request.Context(), userID)
+	if err != nil {
+		shelfInternalError(c)
+		return
+	}
+
+	c.JSON(http.StatusOK, shelves)
+}
+
+// CreateShelf handles POST /api/me/shelves.
+func (h *ShelvesHandler) CreateShelf(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized", "message": "Пользователь не авторизован"})
+		return
+	}
+
+	var input models.CreateShelfInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "validation_error", "message": "Невалидные данные полки"})
+		return
+	}
+
+	s := &models.Shelf{UserID: userID, Name: input.Name, Note: input.Note}
+	if err := h.shelfRepo.Create(c.Request.Context(), s); err != nil {
+		shelfInternalError(c)
+		return
+	}
+
+	c.JSON(http.StatusCreated, s)
+}
+
+// ReorderShelves handles PUT /api/me/shelves/order.
+func (h *ShelvesHandler) ReorderShelves(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized", "message": "Пользователь не авторизован"})
+		return
+	}
+
+	var input models.ReorderInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "validation_error", "message": "Невалидный порядок полок"})
+		return
+	}
+
+	if err := h.shelfRepo.Reorder(c.Request.Context(), userID, input.IDs); err != nil {
+		shelfInternalError(c)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+	c.Writer.WriteHeaderNow()
+}
+
+// GetShelf handles GET /api/me/shelves/:shelfId.
+// Book details are listed by GET /api/books?shelf_id=….
+func (h *ShelvesHandler) GetShelf(c *gin.Context) {
+	userID, shelfID, ok := shelfScope(c)
+	if !ok {
+		return
+	}
+
+	s, err := h.shelfRepo.Get(c.Request.Context(), userID, shelfID)
+	if err != nil {
+		shelfInternalError(c)
+		return
+	}
+	if s == nil {
+		shelfNotFound(c)
+		return
+	}
+
+	entries, err := h.shelfRepo.ListEntries(c.Request.Context(), userID, shelfID)
+	if err != nil {
+		shelfInternalError(c)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.ShelfDetail{Shelf: *s, Books: entries})
+}
+
+// UpdateShelf handles PATCH /api/me/shelves/:shelfId.
+// Status shelves keep their names; only their notes can change.
+func (h *ShelvesHandler) UpdateShelf(c *gin.Context) {
+	userID, shelfID, ok := shelfScope(c)
+	if !ok {
+		return
+	}
+
+	var input models.UpdateShelfInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "validation_error", "message": "Невалидные данные полки"})
+		return
+	}
+
+	s, err := h.shelfRepo.Get(c.Request.Context(), userID, shelfID)
+	if err != nil {
+		shelfInternalError(c)
+		return
+	}
+	if s == nil {
+		shelfNotFound(c)
+		return
+	}
+
+	if input.Name != nil && *input.Name != s.Name {
+		if s.Status != "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "builtin_shelf", "message": "Встроенную полку нельзя переименовать"})
+			return
+		}
+		s.Name = *input.Name
+	}
+	if input.Note != nil {
+		s.Note = *input.Note
+	}
+
+	updated, err := h.shelfRepo.Update(c.Request.Context(), s)
+	if err != nil {
+		shelfInternalError(c)
+		return
+	}
+	if !updated {
+		shelfNotFound(c)
+		return
+	}
+
+	c.JSON(http.StatusOK, s)
+}
+
+// DeleteShelf handles DELETE /api/me/shelves/:shelfId.
+func (h *ShelvesHandler) DeleteShelf(c *gin.Context) {
+	userID, shelfID, ok := shelfScope(c)
+	if !ok {
+		return
+	}
+
+	s, err := h.shelfRepo.Get(c.Request.Context(), userID, shelfID)
+	if err != nil {
+		shelfInternalError(c)
+		return
+	}
+	if s == nil {
+		shelfNotFound(c)
+		return
+	}
+	if s.Status != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "builtin_shelf", "message": "Встроенную полку нельзя удалить"})
+		return
+	}
+
+	deleted, err := h.shelfRepo.Delete(c.Request.Context(), userID, shelfID)
+	if err != nil {
+		shelfInternalError(c)
+		return
+	}
+	if !deleted {
+		shelfNotFound(c)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+	c.Writer.WriteHeaderNow()
+}
+
+// AddShelfBook handles PUT /api/me/shelves/:shelfId/books/:bookId.
+// Adds the book to the end of the shelf or updates its note.
+func (h *ShelvesHandler) AddShelfBook(c *gin.Context) {
+	userID, shelfID, ok := shelfScope(c)
+	if !ok {
+		return
+	}
+	bookID, ok := shelfBookID(c, h.restrictionChecker)
+	if !ok {
+		return
+	}
+
+	var input models.ShelfEntryInput
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "validation_error", "message": "Невалидная заметка к книге"})
+			return
+		}
+	}
+
+	entry, err := h.shelfRepo.AddBook(c.Request.Context(), userID, shelfID, bookID, input.Note)
+	if err != nil {
+		shelfInternalError(c)
+		return
+	}
+	if entry == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not_found", "message": "Полка или книга не найдена"})
+		return
+	}
+
+	c.JSON(http.StatusOK, entry)
+}
+
+// RemoveShelfBook handles DELETE /api/me/shelves/:shelfId/books/:bookId.
+func (h *ShelvesHandler) RemoveShelfBook(c *gin.Context) {
+	userID, shelfID, ok := shelfScope(c)
+	if !ok {
+		return
+	}
+	bookID, err := strconv.ParseInt(c.Param("bookId"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_id", "message": "Некорректный ID книги"})
+		return
+	}
+
+	removed, err := h.shelfRepo.RemoveBook(c.Request.Context(), userID, shelfID, bookID)
+	if err != nil {
+		shelfInternalError(c)
+		return
+	}
+	if !removed {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not_found", "message": "Книги нет на полке"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+	c.Writer.WriteHeaderNow()
+}
+
+// ReorderShelfBooks handles PUT /api/me/shelves/:shelfId/books/order.
+func (h *ShelvesHandler) ReorderShelfBooks(c *gin.Context) {
+	userID, shelfID, ok := shelfScope(c)
+	if !ok {
+		return
+	}
+
+	var input models.ReorderInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "validation_error", "message": "Невалидный порядок книг"})
+		return
+	}
+
+	s, err := h.shelfRepo.Get(c.Request.Context(), userID, shelfID)
+	if err != nil {
+		shelfInternalError(c)
+		return
+	}
+	if s == nil {
+		shelfNotFound(c)
+		return
+	}
+
+	if err := h.shelfRepo.ReorderBooks(c.Request.Context(), userID, shelfID, input.IDs); err != nil {
+		shelfInternalError(c)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+	c.Writer.WriteHeaderNow()
+}
+
+// BookShelves handles GET /api/me/books/:bookId/shelves.
+// Lists the user's shelves holding the book.
+func (h *ShelvesHandler) BookShelves(c *gin.Context) {
+	userID, bookID, ok := bookScope(c, h.restrictionChecker)
+	if !ok {
+		return
+	}
+
+	entries, err := h.shelfRepo.BookEntries(c.Request.Context(), userID, bookID)
+	if err != nil {
+		shelfInternalError(c)
+		return
+	}
+
+	c.JSON(http.StatusOK, entries)
+}
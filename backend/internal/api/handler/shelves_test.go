@@ -0,0 +1,274 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grom-alex/homelib/backend/internal/models"
+)
+
+func sampleStatusShelf() *models.Shelf {
+	return &models.Shelf{ID: 3, UserID: "user-123", Status: models.ShelfFinished, Name: "Прочитано", Position: 2}
+}
+
+func sampleCustomShelf() *models.Shelf {
+	return &models.Shelf{ID: 9, UserID: "user-123", Name: "Лето 2026", Position: 4}
+}
+
+func TestShelvesHandler_ListShelves(t *testing.T) {
+	repo := &mockShelfRepo{
+		listFn: func(_ context.Context, userID string) ([]models.Shelf, error) {
+			assert.Equal(t, "user-123", userID)
+			return []models.Shelf{*sampleStatusShelf(), *sampleCustomShelf()}, nil
+		},
+	}
+	h := NewShelvesHandler(repo, &mockBookRestrictionChecker{})
+
+	c, w := newAnnotationContext(http.MethodGet, "/api/me/shelves", "", nil)
+	h.ListShelves(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var resp []map[string]any
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Len(t, resp, 2)
+	assert.Equal(t, "finished", resp[0]["status"])
+	assert.NotContains(t, resp[1], "status")
+}
+
+func TestShelvesHandler_CreateShelf(t *testing.T) {
+	repo := &mockShelfRepo{
+		createFn: func(_ context.Context, s *models.Shelf) error {
+			assert.Equal(t, "user-123", s.UserID)
+			assert.Equal(t, "Лето 2026", s.Name)
+			s.ID = 9
+			s.CreatedAt = time.Now()
+			return nil
+		},
+	}
+	h := NewShelvesHandler(repo, &mockBookRestrictionChecker{})
+
+	c, w := newAnnotationContext(http.MethodPost, "/api/me/shelves", `{"name":"Лето 2026","note":"на дачу"}`, nil)
+	h.CreateShelf(c)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Contains(t, w.Body.String(), `"id":9`)
+
+	c, w = newAnnotationContext(http.MethodPost, "/api/me/shelves", `{"name":""}`, nil)
+	h.CreateShelf(c)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestShelvesHandler_GetShelf(t *testing.T) {
+	repo := &mockShelfRepo{
+		getFn: func(_ context.Context, _ string, id int64) (*models.Shelf, error) {
+			if id != 9 {
+				return nil, nil
+			}
+			return sampleCustomShelf(), nil
+		},
+		listEntriesFn: func(_ context.Context, _ string, shelfID int64) ([]models.ShelfEntry, error) {
+			return []models.ShelfEntry{{ShelfID: shelfID, BookID: 42, Note: "взять в отпуск"}}, nil
+		},
+	}
+	h := NewShelvesHandler(repo, &mockBookRestrictionChecker{})
+
+	c, w := newAnnotationContext(http.MethodGet, "/api/me/shelves/9", "", gin.Params{{Key: "shelfId", Value: "9"}})
+	h.GetShelf(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var resp models.ShelfDetail
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "Лето 2026", resp.Name)
+	require.Len(t, resp.Books, 1)
+	assert.Equal(t, int64(42), resp.Books[0].BookID)
+
+	c, w = newAnnotationContext(http.MethodGet, "/api/me/shelves/10", "", gin.Params{{Key: "shelfId", Value: "10"}})
+	h.GetShelf(c)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestShelvesHandler_UpdateShelf(t *testing.T) {
+	var saved *models.Shelf
+	repo := &mockShelfRepo{
+		getFn: func(_ context.Context, _ string, id int64) (*models.Shelf, error) {
+			if id == 3 {
+				return sampleStatusShelf(), nil
+			}
+			return sampleCustomShelf(), nil
+		},
+		updateFn: func(_ context.Context, s *models.Shelf) (bool, error) {
+			saved = s
+			return true, nil
+		},
+	}
+	h := NewShelvesHandler(repo, &mockBookRestrictionChecker{})
+
+	c, w := newAnnotationContext(http.MethodPatch, "/api/me/shelves/9", `{"name":"Осень 2026"}`, gin.Params{{Key: "shelfId", Value: "9"}})
+	h.UpdateShelf(c)
+	assert.Equal(t, http.StatusOK, w.Code)
+	require.NotNil(t, saved)
+	assert.Equal(t, "Осень 2026", saved.Name)
+
+	// Status shelves keep their names but take notes
+	saved = nil
+	c, w = newAnnotationContext(http.MethodPatch, "/api/me/shelves/3", `{"name":"Готово"}`, gin.Params{{Key: "shelfId", Value: "3"}})
+	h.UpdateShelf(c)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "builtin_shelf")
+	assert.Nil(t, saved)
+
+	c, w = newAnnotationContext(http.MethodPatch, "/api/me/shelves/3", `{"name":"Прочитано","note":"2026"}`, gin.Params{{Key: "shelfId", Value: "3"}})
+	h.UpdateShelf(c)
+	assert.Equal(t, http.StatusOK, w.Code)
+	require.NotNil(t, saved)
+	assert.Equal(t, "2026", saved.Note)
+}
+
+func TestShelvesHandler_DeleteShelf(t *testing.T) {
+	deleted := false
+	repo := &mockShelfRepo{
+		getFn: func(_ context.Context, _ string, id int64) (*models.Shelf, error) {
+			if id == 3 {
+				return sampleStatusShelf(), nil
+			}
+			return sampleCustomShelf(), nil
+		},
+		deleteFn: func(_ context.Context, _ string, id int64) (bool, error) {
+			deleted = true
+			return true, nil
+		},
+	}
+	h := NewShelvesHandler(repo, &mockBookRestrictionChecker{})
+
+	c, w := newAnnotationContext(http.MethodDelete, "/api/me/shelves/3", "", gin.Params{{Key: "shelfId", Value: "3"}})
+	h.DeleteShelf(c)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.False(t, deleted)
+
+	c, w = newAnnotationContext(http.MethodDelete, "/api/me/shelves/9", "", gin.Params{{Key: "shelfId", Value: "9"}})
+	h.DeleteShelf(c)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.True(t, deleted)
+}
+
+func TestShelvesHandler_AddShelfBook(t *testing.T) {
+	var gotNote *string
+	repo := &mockShelfRepo{
+		addBookFn: func(_ context.Context, userID string, shelfID, bookID int64, note *string) (*models.ShelfEntry, error) {
+			gotNote = note
+			if bookID != 42 {
+				return nil, nil
+			}
+			e := &models.ShelfEntry{ShelfID: shelfID, BookID: bookID, Position: 3}
+			if note != nil {
+				e.Note = *note
+			}
+			return e, nil
+		},
+	}
+	h := NewShelvesHandler(repo, &mockBookRestrictionChecker{})
+	params := gin.Params{{Key: "shelfId", Value: "9"}, {Key: "bookId", Value: "42"}}
+
+	c, w := newAnnotationContext(http.MethodPut, "/api/me/shelves/9/books/42", `{"note":"перечитать"}`, params)
+	h.AddShelfBook(c)
+	assert.Equal(t, http.StatusOK, w.Code)
+	require.NotNil(t, gotNote)
+	assert.Equal(t, "перечитать", *gotNote)
+
+	// No body keeps the note
+	c, w = newAnnotationContext(http.MethodPut, "/api/me/shelves/9/books/42", "", params)
+	h.AddShelfBook(c)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Nil(t, gotNote)
+
+	c, w = newAnnotationContext(http.MethodPut, "/api/me/shelves/9/books/43", "", gin.Params{{Key: "shelfId", Value: "9"}, {Key: "bookId", Value: "43"}})
+	h.AddShelfBook(c)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestShelvesHandler_AddShelfBook_Restricted(t *testing.T) {
+	checker := &mockBookRestrictionChecker{
+		isBookRestrictedFn: func(_ context.Context, _ int64, _ []int) (bool, error) { return true, nil },
+	}
+	h := NewShelvesHandler(&mockShelfRepo{}, checker)
+
+	c, w := newAnnotationContext(http.MethodPut, "/api/me/shelves/9/books/42", "",
+		gin.Params{{Key: "shelfId", Value: "9"}, {Key: "bookId", Value: "42"}})
+	c.Set("restricted_genre_ids", []int{5})
+	h.AddShelfBook(c)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestShelvesHandler_RemoveShelfBook(t *testing.T) {
+	repo := &mockShelfRepo{
+		removeBookFn: func(_ context.Context, _ string, _, bookID int64) (bool, error) {
+			return bookID == 42, nil
+		},
+	}
+	h := NewShelvesHandler(repo, &mockBookRestrictionChecker{})
+
+	c, w := newAnnotationContext(http.MethodDelete, "/api/me/shelves/9/books/42", "",
+		gin.Params{{Key: "shelfId", Value: "9"}, {Key: "bookId", Value: "42"}})
+	h.RemoveShelfBook(c)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	c, w = newAnnotationContext(http.MethodDelete, "/api/me/shelves/9/books/43", "",
+		gin.Params{{Key: "shelfId", Value: "9"}, {Key: "bookId", Value: "43"}})
+	h.RemoveShelfBook(c)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestShelvesHandler_Reorder(t *testing.T) {
+	var shelves, books []int64
+	repo := &mockShelfRepo{
+		getFn: func(_ context.Context, _ string, _ int64) (*models.Shelf, error) { return sampleCustomShelf(), nil },
+		reorderFn: func(_ context.Context, _ string, ids []int64) error {
+			shelves = ids
+			return nil
+		},
+		reorderBooksFn: func(_ context.Context, _ string, shelfID int64, ids []int64) error {
+			assert.Equal(t, int64(9), shelfID)
+			books = ids
+			return nil
+		},
+	}
+	h := NewShelvesHandler(repo, &mockBookRestrictionChecker{})
+
+	c, w := newAnnotationContext(http.MethodPut, "/api/me/shelves/order", `{"ids":[9,3]}`, nil)
+	h.ReorderShelves(c)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, []int64{9, 3}, shelves)
+
+	c, w = newAnnotationContext(http.MethodPut, "/api/me/shelves/9/books/order", `{"ids":[43,42]}`, gin.Params{{Key: "shelfId", Value: "9"}})
+	h.ReorderShelfBooks(c)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, []int64{43, 42}, books)
+
+	c, w = newAnnotationContext(http.MethodPut, "/api/me/shelves/order", `{}`, nil)
+	h.ReorderShelves(c)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestShelvesHandler_BookShelves(t *testing.T) {
+	repo := &mockShelfRepo{
+		bookEntriesFn: func(_ context.Context, userID string, bookID int64) ([]models.ShelfEntry, error) {
+			assert.Equal(t, int64(42), bookID)
+			return []models.ShelfEntry{{ShelfID: 3, BookID: 42}, {ShelfID: 9, BookID: 42}}, nil
+		},
+	}
+	h := NewShelvesHandler(repo, &mockBookRestrictionChecker{})
+
+	c, w := newAnnotationContext(http.MethodGet, "/api/me/books/42/shelves", "", gin.Params{{Key: "bookId", Value: "42"}})
+	h.BookShelves(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"shelfId":9`)
+}
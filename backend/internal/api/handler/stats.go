@@ -0,0 +1,119 @@
+package handler
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/grom-alex/homelib/backend/internal/models"
+	"github.com/grom-alex/homelib/backend/internal/service"
+)
+
+// maxStatsImportSize limits uploaded KOReader statistics databases.
+const maxStatsImportSize = 64 << 20
+
+var sqliteMagic = []byte("SQLite format 3\x00")
+
+type StatsHandler struct {
+	svc ReadingStatsServicer
+}
+
+func NewStatsHandler(svc ReadingStatsServicer) *StatsHandler {
+	return &StatsHandler{svc: svc}
+}
+
+// GetStats handles GET /api/me/stats.
+func (h *StatsHandler) GetStats(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized", "message": "Пользователь не авторизован"})
+		return
+	}
+
+	var q models.StatsQuery
+	if err := c.ShouldBindQuery(&q); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_query", "message": "Некорректные параметры статистики"})
+		return
+	}
+
+	stats, err := h.svc.Stats(c.Request.Context(), userID, q)
+	if errors.Is(err, service.ErrInvalidStatsQuery) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_query", "message": "Некорректный период или часовой пояс"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal_error", "message": "Внутренняя ошибка сервера"})
+		return
+	}
+
+	c.JSON(http.StatusOK, stats)
+}
+
+// ImportKOReaderStats handles POST /api/me/stats/import/koreader.
+// Expects KOReader's statistics.sqlite3 as the multipart field "file".
+func (h *StatsHandler) ImportKOReaderStats(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized", "message": "Пользователь не авторизован"})
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxStatsImportSize)
+	fh, err := c.FormFile("file")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "file_too_large", "message": "Файл статистики слишком большой"})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "missing_file", "message": "Загрузите файл statistics.sqlite3"})
+		return
+	}
+
+	src, err := fh.Open()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal_error", "message": "Внутренняя ошибка сервера"})
+		return
+	}
+	defer src.Close()
+
+	// SQLite reads from a file, so the upload is stored for the import
+	tmp, err := os.CreateTemp("", "koreader-stats-*.sqlite3")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal_error", "message": "Внутренняя ошибка сервера"})
+		return
+	}
+	defer os.Remove(tmp.Name())
+
+	header := make([]byte, len(sqliteMagic))
+	n, _ := io.ReadFull(src, header)
+	if !bytes.Equal(header[:n], sqliteMagic) {
+		_ = tmp.Close()
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_file", "message": "Файл не является базой статистики KOReader"})
+		return
+	}
+	_, err = io.Copy(tmp, io.MultiReader(bytes.NewReader(header), src))
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal_error", "message": "Внутренняя ошибка сервера"})
+		return
+	}
+
+	result, err := h.svc.ImportKOReaderStats(c.Request.Context(), userID, tmp.Name())
+	if errors.Is(err, service.ErrInvalidStatistics) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_file", "message": "Файл не является базой статистики KOReader"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal_error", "message": "Внутренняя ошибка сервера"})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,119 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grom-alex/homelib/backend/internal/models"
+	"github.com/grom-alex/homelib/backend/internal/service"
+)
+
+func TestStatsHandler_GetStats(t *testing.T) {
+	svc := &mockReadingStatsService{
+		statsFn: func(_ context.Context, userID string, q models.StatsQuery) (*models.ReadingStats, error) {
+			assert.Equal(t, "user-123", userID)
+			assert.Equal(t, models.StatsQuery{From: "2026-01-01", To: "2026-01-31", TZ: "Europe/Moscow"}, q)
+			return &models.ReadingStats{From: q.From, To: q.To, Totals: models.StatsPeriod{Minutes: 90}}, nil
+		},
+	}
+	h := NewStatsHandler(svc)
+
+	c, w := newAnnotationContext(http.MethodGet, "/api/me/stats?from=2026-01-01&to=2026-01-31&tz=Europe/Moscow", "", nil)
+	h.GetStats(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"minutes":90`)
+}
+
+func TestStatsHandler_GetStats_InvalidQuery(t *testing.T) {
+	svc := &mockReadingStatsService{
+		statsFn: func(_ context.Context, _ string, _ models.StatsQuery) (*models.ReadingStats, error) {
+			return nil, service.ErrInvalidStatsQuery
+		},
+	}
+	h := NewStatsHandler(svc)
+
+	c, w := newAnnotationContext(http.MethodGet, "/api/me/stats?tz=Mars", "", nil)
+	h.GetStats(c)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "invalid_query")
+}
+
+func newStatsUploadContext(t *testing.T, content []byte) (*gin.Context, *httptest.ResponseRecorder) {
+	t.Helper()
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	if content != nil {
+		fw, err := mw.CreateFormFile("file", "statistics.sqlite3")
+		require.NoError(t, err)
+		_, err = fw.Write(content)
+		require.NoError(t, err)
+	}
+	require.NoError(t, mw.Close())
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/api/me/stats/import/koreader", &body)
+	c.Request.Header.Set("Content-Type", mw.FormDataContentType())
+	c.Set("user_id", "user-123")
+	return c, w
+}
+
+func TestStatsHandler_ImportKOReaderStats_Success(t *testing.T) {
+	content := append([]byte("SQLite format 3\x00"), make([]byte, 100)...)
+	svc := &mockReadingStatsService{
+		importFn: func(_ context.Context, userID, path string) (*models.StatsImportResult, error) {
+			assert.Equal(t, "user-123", userID)
+			data, err := os.ReadFile(path)
+			require.NoError(t, err)
+			assert.Equal(t, content, data)
+			return &models.StatsImportResult{Books: 2, Sessions: 5, Unmatched: []string{}}, nil
+		},
+	}
+	h := NewStatsHandler(svc)
+
+	c, w := newStatsUploadContext(t, content)
+	h.ImportKOReaderStats(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"books":2,"sessions":5,"unmatched":[]}`, w.Body.String())
+}
+
+func TestStatsHandler_ImportKOReaderStats_Invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		content []byte
+		svcErr  error
+		want    string
+	}{
+		{"missing file", nil, nil, "missing_file"},
+		{"not sqlite", []byte("PK\x03\x04 zip archive"), nil, "invalid_file"},
+		{"not statistics", []byte("SQLite format 3\x00"), service.ErrInvalidStatistics, "invalid_file"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := &mockReadingStatsService{
+				importFn: func(_ context.Context, _, _ string) (*models.StatsImportResult, error) {
+					return nil, tt.svcErr
+				},
+			}
+			h := NewStatsHandler(svc)
+
+			c, w := newStatsUploadContext(t, tt.content)
+			h.ImportKOReaderStats(c)
+
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+			assert.Contains(t, w.Body.String(), tt.want)
+		})
+	}
+}
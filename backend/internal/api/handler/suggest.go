@@ -0,0 +1,37 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/grom-alex/homelib/backend/internal/models"
+)
+
+type SuggestHandler struct {
+	suggestSvc SuggestServicer
+}
+
+func NewSuggestHandler(suggestSvc SuggestServicer) *SuggestHandler {
+	return &SuggestHandler{suggestSvc: suggestSvc}
+}
+
+// Suggest handles GET /api/suggest.
+func (h *SuggestHandler) Suggest(c *gin.Context) {
+	var q models.SuggestQuery
+	if err := c.ShouldBindQuery(&q); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid query parameters"})
+		return
+	}
+
+	// Apply parental content filter
+	q.ExcludeGenreIDs = getRestrictedGenreIDs(c)
+
+	result, err := h.suggestSvc.Suggest(c.Request.Context(), q)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get suggestions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,79 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grom-alex/homelib/backend/internal/models"
+)
+
+func TestSuggestHandler_Success(t *testing.T) {
+	svc := &mockSuggestService{
+		suggestFn: func(_ context.Context, q models.SuggestQuery) (*models.SuggestResult, error) {
+			assert.Equal(t, "vfcnth", q.Query)
+			assert.Equal(t, []string{"title,author"}, q.Types)
+			assert.Equal(t, 5, q.Limit)
+			assert.Equal(t, []int{7}, q.ExcludeGenreIDs)
+			return &models.SuggestResult{
+				Items:     []models.Suggestion{{Type: models.SuggestTitle, ID: 1, Text: "Мастер и Маргарита", Score: 1.5}},
+				Corrected: "мастер",
+			}, nil
+		},
+	}
+	h := NewSuggestHandler(svc)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/api/suggest?q=vfcnth&types=title,author&limit=5", nil)
+	c.Set("restricted_genre_ids", []int{7})
+
+	h.Suggest(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var resp map[string]any
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "мастер", resp["corrected"])
+	items := resp["items"].([]any)
+	require.Len(t, items, 1)
+	item := items[0].(map[string]any)
+	assert.Equal(t, "title", item["type"])
+	assert.Equal(t, "Мастер и Маргарита", item["text"])
+	assert.NotContains(t, item, "score")
+}
+
+func TestSuggestHandler_BadParams(t *testing.T) {
+	h := NewSuggestHandler(&mockSuggestService{})
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/api/suggest?q=x&limit=abc", nil)
+
+	h.Suggest(c)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestSuggestHandler_ServiceError(t *testing.T) {
+	svc := &mockSuggestService{
+		suggestFn: func(_ context.Context, _ models.SuggestQuery) (*models.SuggestResult, error) {
+			return nil, fmt.Errorf("db down")
+		},
+	}
+	h := NewSuggestHandler(svc)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/api/suggest?q=мастер", nil)
+
+	h.Suggest(c)
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+}
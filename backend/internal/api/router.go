@@ -8,17 +8,26 @@ import (
 )
 
 type Handlers struct {
-	Books    *handler.BooksHandler
-	Authors  *handler.AuthorsHandler
-	Genres   *handler.GenresHandler
-	Series   *handler.SeriesHandler
-	Admin    *handler.AdminHandler
-	Auth     *handler.AuthHandler
-	Download *handler.DownloadHandler
-	Reader   *handler.ReaderHandler
-	Progress *handler.ProgressHandler
-	Settings *handler.SettingsHandler
-	Parental *handler.ParentalHandler
+	Books            *handler.BooksHandler
+	Authors          *handler.AuthorsHandler
+	Genres           *handler.GenresHandler
+	Series           *handler.SeriesHandler
+	Admin            *handler.AdminHandler
+	Auth             *handler.AuthHandler
+	Download         *handler.DownloadHandler
+	Reader           *handler.ReaderHandler
+	ReaderCache      *handler.ReaderCacheHandler
+	Progress         *handler.ProgressHandler
+	Settings         *handler.SettingsHandler
+	Parental         *handler.ParentalHandler
+	Suggest          *handler.SuggestHandler
+	Annotations      *handler.AnnotationsHandler
+	AnnotationExport *handler.AnnotationExportHandler
+	Kosync           *handler.KosyncHandler
+	Stats            *handler.StatsHandler
+	Shelves          *handler.ShelvesHandler
+	Ratings          *handler.RatingsHandler
+	ContentSearch    *handler.ContentSearchHandler
 }
 
 func SetupRouter(h Handlers, authMw *middleware.AuthMiddleware, parentalMw gin.HandlerFunc) *gin.Engine {
@@ -50,6 +59,16 @@ func SetupRouter(h Handlers, authMw *middleware.AuthMiddleware, parentalMw gin.H
 			}
 		}
 
+		// KOReader progress sync: authenticated by kosync credentials, not JWT
+		if h.Kosync != nil {
+			kosync := api.Group("/kosync")
+			kosync.POST("/users/create", h.Kosync.CreateUser)
+			kosyncAuth := kosync.Group("", h.Kosync.RequireAuth())
+			kosyncAuth.GET("/users/auth", h.Kosync.AuthUser)
+			kosyncAuth.PUT("/syncs/progress", h.Kosync.UpdateProgress)
+			kosyncAuth.GET("/syncs/progress/:document", h.Kosync.GetProgress)
+		}
+
 		// Authenticated endpoints (with parental filter)
 		authorized := api.Group("")
 		if authMw != nil {
@@ -67,6 +86,9 @@ func SetupRouter(h Handlers, authMw *middleware.AuthMiddleware, parentalMw gin.H
 			if h.Reader != nil {
 				authorized.GET("/books/:id/content", h.Reader.GetBookContent)
 				authorized.GET("/books/:id/chapter/:chapterId", h.Reader.GetChapter)
+				authorized.GET("/books/:id/notes/:noteId", h.Reader.GetNote)
+				authorized.GET("/books/:id/search", h.Reader.SearchBook)
+				authorized.GET("/books/:id/bundle", h.Reader.GetBookBundle)
 			}
 			if h.Progress != nil {
 				authorized.GET("/me/progress", h.Progress.GetAllProgress)
@@ -76,6 +98,44 @@ func SetupRouter(h Handlers, authMw *middleware.AuthMiddleware, parentalMw gin.H
 				// which only supports POST requests.
 				authorized.POST("/me/books/:bookId/progress", h.Progress.SaveReadingProgress)
 			}
+			if h.Annotations != nil {
+				authorized.GET("/me/books/:bookId/annotations", h.Annotations.ListAnnotations)
+				authorized.POST("/me/books/:bookId/annotations", h.Annotations.CreateAnnotation)
+				authorized.GET("/me/books/:bookId/annotations/:annotationId", h.Annotations.GetAnnotation)
+				authorized.PATCH("/me/books/:bookId/annotations/:annotationId", h.Annotations.UpdateAnnotation)
+				authorized.DELETE("/me/books/:bookId/annotations/:annotationId", h.Annotations.DeleteAnnotation)
+			}
+			if h.AnnotationExport != nil {
+				authorized.GET("/me/books/:bookId/annotations/export", h.AnnotationExport.ExportBookAnnotations)
+				authorized.GET("/me/annotations/export", h.AnnotationExport.ExportAnnotations)
+			}
+			if h.Kosync != nil {
+				authorized.GET("/me/kosync", h.Kosync.GetAccount)
+				authorized.PUT("/me/kosync", h.Kosync.SetAccount)
+				authorized.DELETE("/me/kosync", h.Kosync.DeleteAccount)
+			}
+			if h.Stats != nil {
+				authorized.GET("/me/stats", h.Stats.GetStats)
+				authorized.POST("/me/stats/import/koreader", h.Stats.ImportKOReaderStats)
+			}
+			if h.Ratings != nil {
+				authorized.GET("/books/:id/ratings", h.Ratings.ListBookRatings)
+				authorized.GET("/me/books/:bookId/rating", h.Ratings.GetRating)
+				authorized.PUT("/me/books/:bookId/rating", h.Ratings.SaveRating)
+				authorized.DELETE("/me/books/:bookId/rating", h.Ratings.DeleteRating)
+			}
+			if h.Shelves != nil {
+				authorized.GET("/me/shelves", h.Shelves.ListShelves)
+				authorized.POST("/me/shelves", h.Shelves.CreateShelf)
+				authorized.PUT("/me/shelves/order", h.Shelves.ReorderShelves)
+				authorized.GET("/me/shelves/:shelfId", h.Shelves.GetShelf)
+				authorized.PATCH("/me/shelves/:shelfId", h.Shelves.UpdateShelf)
+				authorized.DELETE("/me/shelves/:shelfId", h.Shelves.DeleteShelf)
+				authorized.PUT("/me/shelves/:shelfId/books/order", h.Shelves.ReorderShelfBooks)
+				authorized.PUT("/me/shelves/:shelfId/books/:bookId", h.Shelves.AddShelfBook)
+				authorized.DELETE("/me/shelves/:shelfId/books/:bookId", h.Shelves.RemoveShelfBook)
+				authorized.GET("/me/books/:bookId/shelves", h.Shelves.BookShelves)
+			}
 			if h.Settings != nil {
 				authorized.GET("/me/settings", h.Settings.GetUserSettings)
 				authorized.PUT("/me/settings", h.Settings.UpdateUserSettings)
@@ -89,6 +149,12 @@ func SetupRouter(h Handlers, authMw *middleware.AuthMiddleware, parentalMw gin.H
 			authorized.GET("/authors/:id", h.Authors.GetAuthor)
 			authorized.GET("/genres", h.Genres.ListGenres)
 			authorized.GET("/series", h.Series.ListSeries)
+			if h.Suggest != nil {
+				authorized.GET("/suggest", h.Suggest.Suggest)
+			}
+			if h.ContentSearch != nil {
+				authorized.GET("/search/content", h.ContentSearch.Search)
+			}
 		}
 
 		// Admin endpoints
@@ -110,6 +176,15 @@ func SetupRouter(h Handlers, authMw *middleware.AuthMiddleware, parentalMw gin.H
 				admin.GET("/parental/users", h.Parental.ListUsersAdultStatus)
 				admin.PUT("/parental/users/:userId", h.Parental.SetUserAdultContent)
 			}
+			if h.ContentSearch != nil {
+				admin.GET("/content-index", h.ContentSearch.Usage)
+				admin.PUT("/content-index/collections/:id", h.ContentSearch.SetCollectionEnabled)
+			}
+			if h.ReaderCache != nil {
+				admin.GET("/reader-cache", h.ReaderCache.Stats)
+				admin.DELETE("/reader-cache", h.ReaderCache.PurgeAll)
+				admin.DELETE("/reader-cache/books/:id", h.ReaderCache.PurgeBook)
+			}
 		}
 	}
 
@@ -24,6 +24,7 @@ type Server struct {
 	readerSvc    *service.ReaderService
 	genreTreeSvc *service.GenreTreeService
 	parentalSvc  *service.ParentalService
+	locatorSvc   *service.LocatorMigrationService
 }
 
 // loadGenreData reads genre file from the path specified in config.
@@ -40,7 +41,7 @@ func loadGenreData(cfg config.GenreTreeConfig) []byte {
 	return data
 }
 
-func NewServer(cfg *config.Config, pool *pgxpool.Pool) *Server {
+func NewServer(cfg *config.Config, pool *pgxpool.Pool) (*Server, error) {
 	// Repositories
 	bookRepo := repository.NewBookRepo(pool)
 	authorRepo := repository.NewAuthorRepo(pool)
@@ -50,6 +51,7 @@ func NewServer(cfg *config.Config, pool *pgxpool.Pool) *Server {
 	userRepo := repository.NewUserRepo(pool)
 	refreshRepo := repository.NewRefreshTokenRepo(pool)
 	metadataRepo := repository.NewMetadataRepo(pool)
+	suggestRepo := repository.NewSuggestRepo(pool)
 
 	// Genre tree service (nil if no genre file configured)
 	var genreTreeSvc *service.GenreTreeService
@@ -61,12 +63,26 @@ func NewServer(cfg *config.Config, pool *pgxpool.Pool) *Server {
 	catalogSvc := service.NewCatalogService(pool, bookRepo, authorRepo, genreRepo, seriesRepo, collectionRepo)
 	importSvc := service.NewImportService(pool, cfg.Import, cfg.Library, bookRepo, authorRepo, genreRepo, seriesRepo, collectionRepo)
 	authSvc := service.NewAuthService(cfg.Auth, userRepo, refreshRepo)
-	downloadSvc := service.NewDownloadService(bookRepo, cfg.Library)
-	readerSvc := service.NewReaderService(bookRepo, cfg.Library, cfg.Reader)
+	documentHashRepo := repository.NewDocumentHashRepo(pool)
+	readerCache, err := service.NewReaderCacheStore(cfg.Reader, pool)
+	if err != nil {
+		return nil, fmt.Errorf("create reader cache: %w", err)
+	}
+	readerSvc := service.NewReaderService(bookRepo, cfg.Library, cfg.Reader, readerCache)
+	downloadSvc := service.NewDownloadService(bookRepo, cfg.Library, documentHashRepo, readerSvc)
 	parentalSvc := service.NewParentalService(metadataRepo, genreRepo, userRepo)
+	suggestSvc := service.NewSuggestService(suggestRepo)
 
-	// Reading progress repository
+	// Per-user reading data repositories
 	progressRepo := repository.NewReadingProgressRepo(pool)
+	annotationRepo := repository.NewAnnotationRepo(pool)
+	annotationExportSvc := service.NewAnnotationExportService(annotationRepo, bookRepo, readerSvc)
+	locatorSvc := service.NewLocatorMigrationService(progressRepo, readerSvc)
+	statsSvc := service.NewReadingStatsService(repository.NewReadingSessionRepo(pool), documentHashRepo, readerSvc)
+	shelfRepo := repository.NewShelfRepo(pool)
+	// Saved positions extend reading sessions and mark finished books
+	progressRecorders := service.ProgressRecorders{statsSvc, service.NewShelfService(shelfRepo)}
+	kosyncSvc := service.NewKosyncService(repository.NewKosyncRepo(pool), documentHashRepo, progressRepo, readerSvc, progressRecorders)
 
 	// Auth middleware using AuthService as validator
 	authValidator := &authServiceValidator{authSvc: authSvc}
@@ -77,17 +93,26 @@ func NewServer(cfg *config.Config, pool *pgxpool.Pool) *Server {
 
 	// Handlers
 	h := Handlers{
-		Books:    handler.NewBooksHandler(catalogSvc, bookRepo),
-		Authors:  handler.NewAuthorsHandler(catalogSvc),
-		Genres:   handler.NewGenresHandler(catalogSvc),
-		Series:   handler.NewSeriesHandler(catalogSvc),
-		Admin:    handler.NewAdminHandler(importSvc, genreTreeSvc, parentalSvc),
-		Auth:     handler.NewAuthHandler(authSvc, cfg.Auth.RefreshTokenTTL, cfg.Auth.CookieSecure),
-		Download: handler.NewDownloadHandler(downloadSvc, bookRepo),
-		Reader:   handler.NewReaderHandler(readerSvc, bookRepo),
-		Progress: handler.NewProgressHandler(progressRepo),
-		Settings: handler.NewSettingsHandler(userRepo),
-		Parental: handler.NewParentalHandler(parentalSvc),
+		Books:            handler.NewBooksHandler(catalogSvc, bookRepo),
+		Authors:          handler.NewAuthorsHandler(catalogSvc),
+		Genres:           handler.NewGenresHandler(catalogSvc),
+		Series:           handler.NewSeriesHandler(catalogSvc),
+		Admin:            handler.NewAdminHandler(importSvc, genreTreeSvc, parentalSvc),
+		Auth:             handler.NewAuthHandler(authSvc, cfg.Auth.RefreshTokenTTL, cfg.Auth.CookieSecure),
+		Download:         handler.NewDownloadHandler(downloadSvc, bookRepo),
+		Reader:           handler.NewReaderHandler(readerSvc, bookRepo),
+		ReaderCache:      handler.NewReaderCacheHandler(readerSvc),
+		Progress:         handler.NewProgressHandler(progressRepo, progressRecorders),
+		Settings:         handler.NewSettingsHandler(userRepo),
+		Parental:         handler.NewParentalHandler(parentalSvc),
+		Suggest:          handler.NewSuggestHandler(suggestSvc),
+		Annotations:      handler.NewAnnotationsHandler(annotationRepo, bookRepo),
+		AnnotationExport: handler.NewAnnotationExportHandler(annotationExportSvc, bookRepo),
+		Kosync:           handler.NewKosyncHandler(kosyncSvc),
+		Stats:            handler.NewStatsHandler(statsSvc),
+		Shelves:          handler.NewShelvesHandler(shelfRepo, bookRepo),
+		Ratings:          handler.NewRatingsHandler(repository.NewRatingRepo(pool), bookRepo),
+		ContentSearch:    handler.NewContentSearchHandler(service.NewContentIndexService(repository.NewContentIndexRepo(pool), readerSvc)),
 	}
 
 	router := SetupRouter(h, authMw, parentalMw)
@@ -106,7 +131,8 @@ func NewServer(cfg *config.Config, pool *pgxpool.Pool) *Server {
 		readerSvc:    readerSvc,
 		genreTreeSvc: genreTreeSvc,
 		parentalSvc:  parentalSvc,
-	}
+		locatorSvc:   locatorSvc,
+	}, nil
 }
 
 // authServiceValidator adapts AuthService to the middleware.TokenValidator interface.
@@ -143,6 +169,26 @@ func (s *Server) Start(ctx context.Context) error {
 		}
 	}
 
+	// Fill transliteration search keys for rows imported before they existed
+	go func() {
+		n, err := repository.BackfillTranslit(ctx, s.pool)
+		if err != nil {
+			log.Printf("WARNING: translit backfill failed: %v", err)
+		} else if n > 0 {
+			log.Printf("Translit backfill: %d rows updated", n)
+		}
+	}()
+
+	// Convert percentage-only reading positions saved before locators existed
+	go func() {
+		n, err := s.locatorSvc.MigrateProgress(ctx)
+		if err != nil {
+			log.Printf("WARNING: progress locator migration failed: %v", err)
+		} else if n > 0 {
+			log.Printf("Progress locator migration: %d positions converted", n)
+		}
+	}()
+
 	// Start periodic cache cleanup (stops on ctx cancellation)
 	s.readerSvc.StartCacheCleanup(ctx)
 
@@ -10,17 +10,22 @@ import (
 
 // ContentTypes maps file extensions to MIME types.
 var ContentTypes = map[string]string{
-	"fb2":  "application/x-fictionbook+xml",
-	"epub": "application/epub+zip",
-	"pdf":  "application/pdf",
-	"djvu": "image/vnd.djvu",
-	"doc":  "application/msword",
-	"txt":  "text/plain; charset=utf-8",
-	"rtf":  "application/rtf",
-	"htm":  "text/html; charset=utf-8",
-	"html": "text/html; charset=utf-8",
-	"mobi": "application/x-mobipocket-ebook",
-	"azw3": "application/vnd.amazon.ebook",
+	"fb2":      "application/x-fictionbook+xml",
+	"epub":     "application/epub+zip",
+	"pdf":      "application/pdf",
+	"djvu":     "image/vnd.djvu",
+	"doc":      "application/msword",
+	"docx":     "application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+	"txt":      "text/plain; charset=utf-8",
+	"rtf":      "application/rtf",
+	"htm":      "text/html; charset=utf-8",
+	"html":     "text/html; charset=utf-8",
+	"md":       "text/markdown; charset=utf-8",
+	"markdown": "text/markdown; charset=utf-8",
+	"mobi":     "application/x-mobipocket-ebook",
+	"azw3":     "application/vnd.amazon.ebook",
+	"cbz":      "application/vnd.comicbook+zip",
+	"cbr":      "application/vnd.comicbook-rar",
 }
 
 // GetContentType returns the MIME type for a file extension.
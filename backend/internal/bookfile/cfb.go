@@ -0,0 +1,196 @@
+package bookfile
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"strings"
+	"unicode/utf16"
+)
+
+var cfbSignature = []byte{0xD0, 0xCF, 0x11, 0xE0, 0xA1, 0xB1, 0x1A, 0xE1}
+
+var errCFBCorrupt = errors.New("corrupt compound file")
+
+const (
+	cfbHeaderSize   = 512
+	cfbEndOfChain   = 0xFFFFFFFE
+	cfbDirEntrySize = 128
+	cfbHeaderDIFAT  = 109
+	cfbTypeStream   = 2
+	cfbTypeRoot     = 5
+)
+
+// cfbFile is an OLE compound file, the container of legacy Office
+// documents: a FAT file system of streams in a single file.
+type cfbFile struct {
+	data       []byte
+	sectorSize int
+	fat        []uint32
+	miniFAT    []uint32
+	miniStream []byte
+	cutoff     uint64
+	entries    []cfbEntry
+}
+
+type cfbEntry struct {
+	name  string
+	typ   byte
+	start uint32
+	size  uint64
+}
+
+func openCFB(data []byte) (*cfbFile, error) {
+	if len(data) < cfbHeaderSize || !bytes.HasPrefix(data, cfbSignature) {
+		return nil, errors.New("not a compound file")
+	}
+	shift := binary.LittleEndian.Uint16(data[0x1E:])
+	if shift != 9 && shift != 12 {
+		return nil, errCFBCorrupt
+	}
+	f := &cfbFile{
+		data:       data,
+		sectorSize: 1 << shift,
+		cutoff:     uint64(binary.LittleEndian.Uint32(data[0x38:])),
+	}
+
+	// The FAT sectors are listed in the header and then in a chain of
+	// DIFAT sectors, each ending with the next one's number.
+	var fatSectors []uint32
+	for i := range cfbHeaderDIFAT {
+		fatSectors = append(fatSectors, binary.LittleEndian.Uint32(data[0x4C+4*i:]))
+	}
+	next := binary.LittleEndian.Uint32(data[0x44:])
+	perSector := f.sectorSize/4 - 1
+	for seen := 0; next < cfbEndOfChain-3; seen++ {
+		sector, err := f.sector(next)
+		if err != nil || seen > len(data)/f.sectorSize {
+			return nil, errCFBCorrupt
+		}
+		for i := range perSector {
+			fatSectors = append(fatSectors, binary.LittleEndian.Uint32(sector[4*i:]))
+		}
+		next = binary.LittleEndian.Uint32(sector[4*perSector:])
+	}
+	for _, n := range fatSectors {
+		if n >= cfbEndOfChain-3 {
+			continue
+		}
+		sector, err := f.sector(n)
+		if err != nil {
+			return nil, err
+		}
+		for i := 0; i < len(sector); i += 4 {
+			f.fat = append(f.fat, binary.LittleEndian.Uint32(sector[i:]))
+		}
+	}
+
+	dir, err := f.chain(binary.LittleEndian.Uint32(data[0x30:]), -1, false)
+	if err != nil {
+		return nil, err
+	}
+	for i := 0; i+cfbDirEntrySize <= len(dir); i += cfbDirEntrySize {
+		e := dir[i : i+cfbDirEntrySize]
+		nameLen := min(int(binary.LittleEndian.Uint16(e[0x40:])), 64)
+		name := make([]uint16, 0, 32)
+		for j := 0; j+1 < nameLen; j += 2 {
+			if c := binary.LittleEndian.Uint16(e[j:]); c != 0 {
+				name = append(name, c)
+			}
+		}
+		size := binary.LittleEndian.Uint64(e[0x78:])
+		if shift == 9 {
+			// Version 3 files may have garbage in the high half.
+			size &= 0xFFFFFFFF
+		}
+		f.entries = append(f.entries, cfbEntry{
+			name:  string(utf16.Decode(name)),
+			typ:   e[0x42],
+			start: binary.LittleEndian.Uint32(e[0x74:]),
+			size:  size,
+		})
+	}
+	if len(f.entries) == 0 || f.entries[0].typ != cfbTypeRoot {
+		return nil, errCFBCorrupt
+	}
+
+	// Small streams are stored in 64-byte sectors of the mini stream,
+	// which is the root entry's data.
+	root := f.entries[0]
+	if root.start < cfbEndOfChain-3 {
+		if f.miniStream, err = f.chain(root.start, int64(root.size), false); err != nil {
+			return nil, err
+		}
+		miniFAT, err := f.chain(binary.LittleEndian.Uint32(data[0x3C:]), -1, false)
+		if err != nil {
+			return nil, err
+		}
+		for i := 0; i+4 <= len(miniFAT); i += 4 {
+			f.miniFAT = append(f.miniFAT, binary.LittleEndian.Uint32(miniFAT[i:]))
+		}
+	}
+	return f, nil
+}
+
+func (f *cfbFile) sector(n uint32) ([]byte, error) {
+	start := (int64(n) + 1) * int64(f.sectorSize)
+	if start+int64(f.sectorSize) > int64(len(f.data)) {
+		return nil, errCFBCorrupt
+	}
+	return f.data[start : start+int64(f.sectorSize)], nil
+}
+
+func (f *cfbFile) miniSector(n uint32) ([]byte, error) {
+	start := int64(n) * 64
+	if start+64 > int64(len(f.miniStream)) {
+		return nil, errCFBCorrupt
+	}
+	return f.miniStream[start : start+64], nil
+}
+
+// chain reads the sectors of a chain starting at start, up to size bytes
+// (or to the end of the chain if size is negative), from the mini stream if
+// mini is set.
+func (f *cfbFile) chain(start uint32, size int64, mini bool) ([]byte, error) {
+	fat, read := f.fat, f.sector
+	if mini {
+		fat, read = f.miniFAT, f.miniSector
+	}
+	var out []byte
+	for n, steps := start, 0; n < cfbEndOfChain-3; steps++ {
+		if steps > len(fat) || int(n) >= len(fat) {
+			return nil, errCFBCorrupt
+		}
+		sector, err := read(n)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, sector...)
+		if size >= 0 && int64(len(out)) >= size {
+			return out[:size], nil
+		}
+		n = fat[n]
+	}
+	if size > int64(len(out)) {
+		return nil, errCFBCorrupt
+	}
+	return out, nil
+}
+
+// stream returns the data of the named stream, found anywhere in the
+// directory tree.
+func (f *cfbFile) stream(name string) ([]byte, error) {
+	for _, e := range f.entries {
+		if e.typ != cfbTypeStream || !strings.EqualFold(e.name, name) {
+			continue
+		}
+		if e.size > uint64(len(f.data)) {
+			return nil, errCFBCorrupt
+		}
+		if e.size < f.cutoff {
+			return f.chain(e.start, int64(e.size), true)
+		}
+		return f.chain(e.start, int64(e.size), false)
+	}
+	return nil, errors.New("stream not found: " + name)
+}
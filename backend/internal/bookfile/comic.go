@@ -0,0 +1,432 @@
+package bookfile
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/nwaples/rardecode/v2"
+)
+
+const (
+	// maxComicPageSize limits the size of a single extracted page image
+	// (64 MB), guarding against archive bombs.
+	maxComicPageSize = 64 * 1024 * 1024
+	// maxComicRARPages limits the total size of the pages extracted from a
+	// RAR archive (512 MB).
+	maxComicRARPages = 512 * 1024 * 1024
+	// comicThumbWidth is the width of page thumbnails.
+	comicThumbWidth = 200
+	// estimatedComicPageSize is the rendered size (bytes) assumed for a
+	// page, so that page estimation counts every page as about one screen.
+	estimatedComicPageSize = estimatedCoverImageSize
+)
+
+// Comic archive image IDs are the 1-based page number with one of these
+// prefixes. Chapters use the page image ID.
+const (
+	comicPagePrefix  = "page-"
+	comicThumbPrefix = "thumb-"
+)
+
+var (
+	zipMagic = []byte("PK\x03\x04")
+	rarMagic = []byte("Rar!\x1a\x07")
+)
+
+// comicImageExts lists the page image extensions; other files (ComicInfo.xml,
+// text files, thumbnails databases) are not pages.
+var comicImageExts = map[string]bool{
+	".jpg": true, ".jpeg": true, ".png": true, ".gif": true, ".webp": true, ".bmp": true,
+}
+
+// ComicInfo.xml structures (the ComicRack metadata schema)
+
+type comicInfo struct {
+	Title       string          `xml:"Title"`
+	Series      string          `xml:"Series"`
+	Number      string          `xml:"Number"`
+	Writer      string          `xml:"Writer"`
+	Penciller   string          `xml:"Penciller"`
+	LanguageISO string          `xml:"LanguageISO"`
+	Pages       []comicInfoPage `xml:"Pages>Page"`
+}
+
+type comicInfoPage struct {
+	// Image is the 0-based index of the page in archive order.
+	Image    int    `xml:"Image,attr"`
+	Type     string `xml:"Type,attr"`
+	Bookmark string `xml:"Bookmark,attr"`
+}
+
+// ComicConverter converts comic book archives (CBZ, CBR). Every page image
+// becomes a chapter; pages are ordered by natural sort of their file names.
+// Page images are extracted on demand, so parsing only reads the archive
+// index and ComicInfo.xml. RAR archives, which may be solid, are extracted
+// whole on the first page access.
+type ComicConverter struct {
+	format  string
+	bookID  int64
+	data    []byte
+	isRAR   bool
+	zip     *zip.Reader
+	pages   []string // archive file names in page order
+	content *BookContent
+
+	rarOnce  sync.Once
+	rarPages map[string][]byte // by file name
+	rarErr   error
+}
+
+func (c *ComicConverter) Parse(data []byte, bookID int64) error {
+	c.bookID = bookID
+	c.data = data
+
+	var names []string
+	var info []byte
+	var err error
+	switch {
+	case bytes.HasPrefix(data, zipMagic):
+		names, info, err = c.indexZIP()
+	case bytes.HasPrefix(data, rarMagic):
+		c.isRAR = true
+		names, info, err = c.indexRAR()
+	default:
+		err = errors.New("not a ZIP or RAR archive")
+	}
+	if err != nil {
+		return fmt.Errorf("read comic archive: %w", err)
+	}
+
+	for _, name := range names {
+		if isComicPage(name) {
+			c.pages = append(c.pages, name)
+		}
+	}
+	if len(c.pages) == 0 {
+		return errors.New("comic archive has no page images")
+	}
+	sort.SliceStable(c.pages, func(i, j int) bool { return naturalLess(c.pages[i], c.pages[j]) })
+
+	var ci comicInfo
+	if info != nil {
+		if err := xml.Unmarshal(info, &ci); err != nil {
+			return fmt.Errorf("parse ComicInfo.xml: %w", err)
+		}
+	}
+	c.buildContent(&ci)
+	return nil
+}
+
+func (c *ComicConverter) indexZIP() (names []string, info []byte, err error) {
+	c.zip, err = zip.NewReader(bytes.NewReader(c.data), int64(len(c.data)))
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, f := range c.zip.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		if isComicInfo(f.Name) {
+			if info, err = readZIPFile(f); err != nil {
+				return nil, nil, err
+			}
+			continue
+		}
+		names = append(names, f.Name)
+	}
+	return names, info, nil
+}
+
+func (c *ComicConverter) indexRAR() (names []string, info []byte, err error) {
+	r, err := rardecode.NewReader(bytes.NewReader(c.data))
+	if err != nil {
+		return nil, nil, err
+	}
+	for {
+		h, err := r.Next()
+		if errors.Is(err, io.EOF) {
+			return names, info, nil
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		if h.IsDir {
+			continue
+		}
+		if isComicInfo(h.Name) {
+			if info, err = readLimited(r); err != nil {
+				return nil, nil, err
+			}
+			continue
+		}
+		names = append(names, h.Name)
+	}
+}
+
+func (c *ComicConverter) buildContent(ci *comicInfo) {
+	title := ci.Title
+	if title == "" && ci.Series != "" {
+		title = ci.Series
+		if ci.Number != "" {
+			title += " #" + ci.Number
+		}
+	}
+	author := ci.Writer
+	if author == "" {
+		author = ci.Penciller
+	}
+	author, _, _ = strings.Cut(author, ",")
+
+	cover := -1
+	bookmarks := make(map[int]string)
+	for _, p := range ci.Pages {
+		if p.Image < 0 || p.Image >= len(c.pages) {
+			continue
+		}
+		if p.Type == "FrontCover" && cover < 0 {
+			cover = p.Image
+		}
+		if p.Bookmark != "" {
+			bookmarks[p.Image] = p.Bookmark
+		}
+	}
+
+	ids := make([]string, len(c.pages))
+	sizes := make(map[string]int, len(c.pages))
+	var toc []TOCEntry
+	for i := range c.pages {
+		ids[i] = comicPagePrefix + strconv.Itoa(i+1)
+		sizes[ids[i]] = estimatedComicPageSize
+		// With bookmarks the table of contents lists them only; otherwise
+		// every page, so that pages can still be reached from it.
+		if bookmark, ok := bookmarks[i]; ok {
+			toc = append(toc, TOCEntry{ID: ids[i], Title: bookmark})
+		} else if len(bookmarks) == 0 {
+			toc = append(toc, TOCEntry{ID: ids[i], Title: comicPageTitle(i + 1)})
+		}
+	}
+
+	cover = max(cover, 0)
+
+	c.content = &BookContent{
+		Metadata: BookMetadata{
+			Title:    title,
+			Author:   strings.TrimSpace(author),
+			Cover:    c.imageURL(comicPagePrefix + strconv.Itoa(cover+1)),
+			Language: ci.LanguageISO,
+			Format:   c.format,
+		},
+		TOC:           toc,
+		ChapterIDs:    ids,
+		TotalChapters: len(ids),
+		ChapterSizes:  sizes,
+		FormatVersion: FormatVersion,
+	}
+}
+
+func (c *ComicConverter) Content() *BookContent {
+	return c.content
+}
+
+func (c *ComicConverter) Chapter(chapterID string) (*ChapterContent, error) {
+	n, err := c.pageNumber(chapterID, comicPagePrefix)
+	if err != nil {
+		return nil, fmt.Errorf("chapter %q not found", chapterID)
+	}
+	title := comicPageTitle(n)
+	return &ChapterContent{
+		ID:    chapterID,
+		Title: title,
+		HTML: fmt.Sprintf("<div class=\"comic-page\"><img src=\"%s\" alt=\"%s\"/></div>\n",
+			c.imageURL(chapterID), title),
+		FormatVersion: FormatVersion,
+	}, nil
+}
+
+// Image returns a page image ("page-N") or its thumbnail ("thumb-N").
+func (c *ComicConverter) Image(imageID string) (*ImageData, error) {
+	if n, err := c.pageNumber(imageID, comicThumbPrefix); err == nil {
+		page, err := c.Image(comicPagePrefix + strconv.Itoa(n))
+		if err != nil {
+			return nil, err
+		}
+		thumb, err := TranscodeImage(page, ImageOptions{Width: comicThumbWidth, Format: ImageFormatJPEG})
+		if err != nil {
+			return nil, fmt.Errorf("thumbnail of page %d: %w", n, err)
+		}
+		thumb.ID = imageID
+		return thumb, nil
+	}
+
+	n, err := c.pageNumber(imageID, comicPagePrefix)
+	if err != nil {
+		return nil, fmt.Errorf("image %q not found", imageID)
+	}
+	data, err := c.readPage(c.pages[n-1])
+	if err != nil {
+		return nil, fmt.Errorf("read page %d: %w", n, err)
+	}
+	return &ImageData{
+		ID:          imageID,
+		ContentType: http.DetectContentType(data),
+		Data:        data,
+	}, nil
+}
+
+// pageNumber parses a 1-based page number from an ID with the given prefix.
+func (c *ComicConverter) pageNumber(id, prefix string) (int, error) {
+	s, ok := strings.CutPrefix(id, prefix)
+	if !ok {
+		return 0, fmt.Errorf("no %q prefix", prefix)
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 1 || n > len(c.pages) || strconv.Itoa(n) != s {
+		return 0, fmt.Errorf("invalid page number %q", s)
+	}
+	return n, nil
+}
+
+func (c *ComicConverter) readPage(name string) ([]byte, error) {
+	if !c.isRAR {
+		for _, f := range c.zip.File {
+			if f.Name == name {
+				return readZIPFile(f)
+			}
+		}
+		return nil, fmt.Errorf("file %q not found", name)
+	}
+
+	c.rarOnce.Do(func() { c.rarPages, c.rarErr = c.extractRAR() })
+	if c.rarErr != nil {
+		return nil, c.rarErr
+	}
+	data, ok := c.rarPages[name]
+	if !ok {
+		return nil, fmt.Errorf("file %q not found", name)
+	}
+	return data, nil
+}
+
+// extractRAR reads all pages of a RAR archive in one pass: archives may be
+// solid, so files can only be read in order.
+func (c *ComicConverter) extractRAR() (map[string][]byte, error) {
+	wanted := make(map[string]bool, len(c.pages))
+	for _, name := range c.pages {
+		wanted[name] = true
+	}
+	pages := make(map[string][]byte, len(c.pages))
+	r, err := rardecode.NewReader(bytes.NewReader(c.data))
+	if err != nil {
+		return nil, err
+	}
+	total := 0
+	for {
+		h, err := r.Next()
+		if errors.Is(err, io.EOF) {
+			return pages, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if _, done := pages[h.Name]; !wanted[h.Name] || done {
+			continue
+		}
+		data, err := readLimited(r)
+		if err != nil {
+			return nil, fmt.Errorf("read %q: %w", h.Name, err)
+		}
+		if total += len(data); total > maxComicRARPages {
+			return nil, fmt.Errorf("pages exceed %d bytes", maxComicRARPages)
+		}
+		pages[h.Name] = data
+	}
+}
+
+func (c *ComicConverter) imageURL(imageID string) string {
+	return fmt.Sprintf("/api/books/%d/image/%s?v=%s", c.bookID, imageID, imageURLVersion)
+}
+
+func comicPageTitle(n int) string {
+	return fmt.Sprintf("Страница %d", n)
+}
+
+func isComicInfo(name string) bool {
+	return strings.EqualFold(path.Base(name), "ComicInfo.xml")
+}
+
+// isComicPage reports whether an archive file is a page image, skipping
+// macOS resource forks and hidden files.
+func isComicPage(name string) bool {
+	name = strings.ReplaceAll(name, "\\", "/")
+	if strings.HasPrefix(name, "__MACOSX/") || strings.HasPrefix(path.Base(name), ".") {
+		return false
+	}
+	return comicImageExts[strings.ToLower(path.Ext(name))]
+}
+
+func readZIPFile(f *zip.File) ([]byte, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rc.Close() }()
+	return readLimited(rc)
+}
+
+func readLimited(r io.Reader) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(r, maxComicPageSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxComicPageSize {
+		return nil, fmt.Errorf("file exceeds %d bytes", maxComicPageSize)
+	}
+	return data, nil
+}
+
+// naturalLess orders file names case-insensitively with digit runs compared
+// by value, so that "page2.jpg" sorts before "page10.jpg".
+func naturalLess(a, b string) bool {
+	ar, br := []rune(strings.ToLower(a)), []rune(strings.ToLower(b))
+	i, j := 0, 0
+	for i < len(ar) && j < len(br) {
+		if isDigit(ar[i]) && isDigit(br[j]) {
+			si, sj := i, j
+			for i < len(ar) && isDigit(ar[i]) {
+				i++
+			}
+			for j < len(br) && isDigit(br[j]) {
+				j++
+			}
+			na := strings.TrimLeft(string(ar[si:i]), "0")
+			nb := strings.TrimLeft(string(br[sj:j]), "0")
+			if len(na) != len(nb) {
+				return len(na) < len(nb)
+			}
+			if na != nb {
+				return na < nb
+			}
+			continue
+		}
+		if ar[i] != br[j] {
+			return ar[i] < br[j]
+		}
+		i++
+		j++
+	}
+	return len(ar)-i < len(br)-j
+}
+
+func isDigit(r rune) bool {
+	return r >= '0' && r <= '9'
+}
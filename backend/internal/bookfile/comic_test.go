@@ -0,0 +1,234 @@
+package bookfile
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type comicFile struct {
+	name string
+	data []byte
+}
+
+func testPageImage(t *testing.T, width int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, image.NewGray(image.Rect(0, 0, width, width*3/2))))
+	return buf.Bytes()
+}
+
+func buildCBZ(t *testing.T, files []comicFile) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, f := range files {
+		w, err := zw.Create(f.name)
+		require.NoError(t, err)
+		_, err = w.Write(f.data)
+		require.NoError(t, err)
+	}
+	require.NoError(t, zw.Close())
+	return buf.Bytes()
+}
+
+// buildCBR writes a RAR 4 archive with stored (uncompressed) files.
+func buildCBR(files []comicFile) []byte {
+	var buf bytes.Buffer
+	writeBlock := func(header []byte) {
+		// header starts at the block type; the CRC covers everything after it
+		crc := uint16(crc32.ChecksumIEEE(header))
+		_ = binary.Write(&buf, binary.LittleEndian, crc)
+		buf.Write(header)
+	}
+	buf.WriteString("Rar!\x1a\x07\x00")
+	// main archive header: type, flags, size, reserved
+	writeBlock([]byte{0x73, 0, 0, 13, 0, 0, 0, 0, 0, 0, 0})
+	for _, f := range files {
+		var h bytes.Buffer
+		h.WriteByte(0x74)
+		_ = binary.Write(&h, binary.LittleEndian, uint16(0x8000))
+		_ = binary.Write(&h, binary.LittleEndian, uint16(32+len(f.name)))
+		_ = binary.Write(&h, binary.LittleEndian, uint32(len(f.data))) // packed size
+		_ = binary.Write(&h, binary.LittleEndian, uint32(len(f.data))) // unpacked size
+		h.WriteByte(2)                                                 // host OS: Windows
+		_ = binary.Write(&h, binary.LittleEndian, crc32.ChecksumIEEE(f.data))
+		_ = binary.Write(&h, binary.LittleEndian, uint32(0x5a000000)) // DOS time
+		h.WriteByte(20)                                               // version to unpack
+		h.WriteByte(0x30)                                             // method: store
+		_ = binary.Write(&h, binary.LittleEndian, uint16(len(f.name)))
+		_ = binary.Write(&h, binary.LittleEndian, uint32(0x20)) // attributes
+		h.WriteString(f.name)
+		writeBlock(h.Bytes())
+		buf.Write(f.data)
+	}
+	writeBlock([]byte{0x7b, 0x00, 0x40, 7, 0})
+	return buf.Bytes()
+}
+
+const testComicInfo = `<?xml version="1.0" encoding="utf-8"?>
+<ComicInfo>
+  <Series>Хроники</Series>
+  <Number>3</Number>
+  <Writer>Иван Петров, Пётр Иванов</Writer>
+  <Penciller>Анна Смирнова</Penciller>
+  <LanguageISO>ru</LanguageISO>
+  <Pages>
+    <Page Image="0" Type="FrontCover"/>
+    <Page Image="1" Bookmark="Пролог"/>
+    <Page Image="3" Bookmark="Глава 1"/>
+  </Pages>
+</ComicInfo>`
+
+func testComicFiles(t *testing.T) []comicFile {
+	page := testPageImage(t, 400)
+	return []comicFile{
+		{"Chronicles/page10.png", page},
+		{"Chronicles/page2.png", page},
+		{"Chronicles/Page1.png", page},
+		{"Chronicles/page9.png", page},
+		{"__MACOSX/Chronicles/._page1.png", []byte("resource fork")},
+		{"Chronicles/.hidden.png", page},
+		{"Chronicles/readme.txt", []byte("scanned by ...")},
+		{"ComicInfo.xml", []byte(testComicInfo)},
+	}
+}
+
+func TestComicConverter_CBZ(t *testing.T) {
+	conv, err := GetConverter("cbz")
+	require.NoError(t, err)
+	require.NoError(t, conv.Parse(buildCBZ(t, testComicFiles(t)), 5))
+
+	content := conv.Content()
+	assert.Equal(t, BookMetadata{
+		Title:    "Хроники #3",
+		Author:   "Иван Петров",
+		Cover:    "/api/books/5/image/page-1?v=2",
+		Language: "ru",
+		Format:   "cbz",
+	}, content.Metadata)
+	assert.Equal(t, []string{"page-1", "page-2", "page-3", "page-4"}, content.ChapterIDs)
+	assert.Equal(t, 4, content.TotalChapters)
+	assert.Equal(t, []TOCEntry{
+		{ID: "page-2", Title: "Пролог"},
+		{ID: "page-4", Title: "Глава 1"},
+	}, content.TOC)
+	assert.Len(t, content.ChapterSizes, 4)
+
+	cc := conv.(*ComicConverter)
+	assert.Equal(t, []string{"Chronicles/Page1.png", "Chronicles/page2.png", "Chronicles/page9.png", "Chronicles/page10.png"}, cc.pages)
+}
+
+func TestComicConverter_CBR(t *testing.T) {
+	conv, err := GetConverter("cbr")
+	require.NoError(t, err)
+	require.NoError(t, conv.Parse(buildCBR(testComicFiles(t)), 5))
+
+	content := conv.Content()
+	assert.Equal(t, "Хроники #3", content.Metadata.Title)
+	assert.Equal(t, "cbr", content.Metadata.Format)
+	assert.Len(t, content.ChapterIDs, 4)
+
+	img, err := conv.Image("page-4")
+	require.NoError(t, err)
+	assert.Equal(t, "image/png", img.ContentType)
+	assert.Equal(t, testPageImage(t, 400), img.Data)
+
+	// All pages were extracted by the first access
+	conv.(*ComicConverter).data = nil
+	for _, id := range content.ChapterIDs {
+		_, err := conv.Image(id)
+		require.NoError(t, err, id)
+	}
+}
+
+func TestComicConverter_WithoutComicInfo(t *testing.T) {
+	page := testPageImage(t, 100)
+	conv := &ComicConverter{format: "cbz"}
+	require.NoError(t, conv.Parse(buildCBZ(t, []comicFile{{"b.png", page}, {"a.png", page}}), 1))
+
+	content := conv.Content()
+	assert.Empty(t, content.Metadata.Title)
+	assert.Equal(t, []TOCEntry{
+		{ID: "page-1", Title: "Страница 1"},
+		{ID: "page-2", Title: "Страница 2"},
+	}, content.TOC)
+}
+
+func TestComicConverter_Chapter(t *testing.T) {
+	conv := &ComicConverter{format: "cbz"}
+	require.NoError(t, conv.Parse(buildCBZ(t, testComicFiles(t)), 5))
+
+	ch, err := conv.Chapter("page-3")
+	require.NoError(t, err)
+	assert.Equal(t, "Страница 3", ch.Title)
+	assert.Equal(t, FormatVersion, ch.FormatVersion)
+	assert.Contains(t, ch.HTML, `<img src="/api/books/5/image/page-3?v=2" alt="Страница 3"/>`)
+
+	for _, id := range []string{"page-0", "page-5", "page-03", "page-x", "chapter-1", "thumb-1"} {
+		_, err := conv.Chapter(id)
+		assert.Error(t, err, id)
+	}
+}
+
+func TestComicConverter_Image(t *testing.T) {
+	conv := &ComicConverter{format: "cbz"}
+	require.NoError(t, conv.Parse(buildCBZ(t, testComicFiles(t)), 5))
+
+	img, err := conv.Image("page-1")
+	require.NoError(t, err)
+	assert.Equal(t, "page-1", img.ID)
+	assert.Equal(t, "image/png", img.ContentType)
+
+	thumb, err := conv.Image("thumb-2")
+	require.NoError(t, err)
+	assert.Equal(t, "thumb-2", thumb.ID)
+	assert.Equal(t, "image/jpeg", thumb.ContentType)
+	cfg, err := jpeg.DecodeConfig(bytes.NewReader(thumb.Data))
+	require.NoError(t, err)
+	assert.Equal(t, comicThumbWidth, cfg.Width)
+	assert.Equal(t, 300, cfg.Height)
+
+	for _, id := range []string{"page-9", "thumb-0", "cover.png"} {
+		_, err := conv.Image(id)
+		assert.Error(t, err, id)
+	}
+}
+
+func TestComicConverter_Invalid(t *testing.T) {
+	conv := &ComicConverter{}
+	assert.ErrorContains(t, conv.Parse([]byte("%PDF-1.4"), 1), "not a ZIP or RAR archive")
+
+	noPages := buildCBZ(t, []comicFile{{"ComicInfo.xml", []byte(testComicInfo)}, {"notes.txt", []byte("x")}})
+	assert.ErrorContains(t, (&ComicConverter{}).Parse(noPages, 1), "no page images")
+
+	badInfo := buildCBZ(t, []comicFile{{"1.png", testPageImage(t, 10)}, {"ComicInfo.xml", []byte("<ComicInfo>")}})
+	assert.ErrorContains(t, (&ComicConverter{}).Parse(badInfo, 1), "ComicInfo.xml")
+}
+
+func TestNaturalLess(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"page2.jpg", "page10.jpg", true},
+		{"page10.jpg", "page2.jpg", false},
+		{"Page1.jpg", "page2.jpg", true},
+		{"p007.jpg", "p8.jpg", true},
+		{"ch1/10.jpg", "ch2/1.jpg", true},
+		{"a.jpg", "a.jpg", false},
+		{"a", "a1", true},
+		{"v1/p1", "v1 extra/p1", false},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, naturalLess(tt.a, tt.b), "%s < %s", tt.a, tt.b)
+	}
+}
@@ -18,27 +18,62 @@ type TOCEntry struct {
 	Level int    `json:"level"`
 }
 
+// ChapterPart locates a virtual chapter split from an oversized section.
+// Paragraphs are numbered across the whole section, so positions saved
+// against the section ID (the ID of its first part) stay valid.
+type ChapterPart struct {
+	Section        string `json:"section"`
+	FirstParagraph int    `json:"firstParagraph"`
+}
+
 // BookContent is the result of converting a book (metadata + structure, no chapter text).
 type BookContent struct {
-	Metadata      BookMetadata   `json:"metadata"`
-	TOC           []TOCEntry     `json:"toc"`
-	ChapterIDs    []string       `json:"chapters"`
-	TotalChapters int            `json:"totalChapters"`
-	ChapterSizes  map[string]int `json:"chapterSizes,omitempty"`
+	Metadata      BookMetadata           `json:"metadata"`
+	TOC           []TOCEntry             `json:"toc"`
+	ChapterIDs    []string               `json:"chapters"`
+	TotalChapters int                    `json:"totalChapters"`
+	ChapterSizes  map[string]int         `json:"chapterSizes,omitempty"`
+	Parts         map[string]ChapterPart `json:"parts,omitempty"`
+	FormatVersion int                    `json:"formatVersion"`
+}
+
+// ResolveChapter returns the chapter that holds a paragraph of the given
+// chapter: for split sections, the part the paragraph falls into.
+func (bc *BookContent) ResolveChapter(chapterID string, paragraph int) string {
+	part, ok := bc.Parts[chapterID]
+	if !ok {
+		return chapterID
+	}
+	resolved := part.Section
+	for _, id := range bc.ChapterIDs {
+		if p, ok := bc.Parts[id]; ok && p.Section == part.Section && p.FirstParagraph <= paragraph {
+			resolved = id
+		}
+	}
+	return resolved
 }
 
 // ChapterContent holds the HTML content of a single chapter.
 type ChapterContent struct {
-	ID    string `json:"id"`
-	Title string `json:"title"`
-	HTML  string `json:"html"`
+	ID            string `json:"id"`
+	Title         string `json:"title"`
+	HTML          string `json:"html"`
+	FormatVersion int    `json:"formatVersion"`
+}
+
+// NoteContent holds the HTML content of a single footnote.
+type NoteContent struct {
+	ID            string `json:"id"`
+	Title         string `json:"title"`
+	HTML          string `json:"html"`
+	FormatVersion int    `json:"formatVersion"`
 }
 
 // ImageData holds binary image data extracted from a book.
 type ImageData struct {
-	ID          string
-	ContentType string
-	Data        []byte
+	ID          string `json:"id"`
+	ContentType string `json:"contentType"`
+	Data        []byte `json:"data"`
 }
 
 // BookConverter is the interface for converting book files to HTML.
@@ -57,11 +92,53 @@ type BookConverter interface {
 	Image(imageID string) (*ImageData, error)
 }
 
+// XPointerMapper is implemented by converters of formats that KOReader
+// renders from the original document tree (crengine), so that reading
+// positions can be exchanged with KOReader as XPointers.
+type XPointerMapper interface {
+	// XPointerMap returns the element paths of all paragraphs of the book.
+	XPointerMap() *XPointerMap
+}
+
+// SupportsXPointer reports whether the converter of a format implements
+// XPointerMapper.
+func SupportsXPointer(format string) bool {
+	conv, err := GetConverter(format)
+	if err != nil {
+		return false
+	}
+	_, ok := conv.(XPointerMapper)
+	return ok
+}
+
+// NoteProvider is implemented by converters of formats with footnotes
+// kept apart from the text, so that a note can be shown in a popup.
+type NoteProvider interface {
+	// Note returns the HTML content of a footnote.
+	Note(noteID string) (*NoteContent, error)
+}
+
 // GetConverter returns the appropriate converter for the given book format.
 func GetConverter(format string) (BookConverter, error) {
 	switch format {
 	case "fb2":
 		return &FB2Converter{}, nil
+	case "cbz", "cbr":
+		return &ComicConverter{format: format}, nil
+	case "txt":
+		return &TextConverter{}, nil
+	case "htm", "html":
+		return &HTMLConverter{format: format}, nil
+	case "md", "markdown":
+		return &MarkdownConverter{}, nil
+	case "mobi", "azw3":
+		return &MOBIConverter{format: format}, nil
+	case "docx":
+		return &DOCXConverter{}, nil
+	case "rtf":
+		return &RTFConverter{}, nil
+	case "doc":
+		return &DOCConverter{}, nil
 	default:
 		return nil, fmt.Errorf("unsupported format: %s", format)
 	}
@@ -0,0 +1,304 @@
+package bookfile
+
+import (
+	"encoding/binary"
+	"errors"
+	"strings"
+	"unicode/utf16"
+)
+
+const (
+	docWordIdent    = 0xA5EC
+	docNFibWord97   = 0xC1
+	docFlagEncrypt  = 0x0100
+	docFlag1Table   = 0x0200
+	docCompressedFC = 0x40000000
+	// docClxIndex is the index of fcClx/lcbClx in the FIB's FC/LCB pairs.
+	docClxIndex = 33
+)
+
+// Property IDs and types of the SummaryInformation property set.
+const (
+	pidCodepage = 1
+	pidTitle    = 2
+	pidAuthor   = 4
+	vtI2        = 2
+	vtLPSTR     = 30
+	vtLPWSTR    = 31
+)
+
+// DOCConverter extracts the text of legacy Word documents (Word 97 and
+// later from the piece table, older ones from their single text run).
+// Formatting and pictures are dropped; paragraphs are split into chapters
+// at heading lines as in plain text.
+type DOCConverter struct {
+	htmlBook
+}
+
+func (c *DOCConverter) Parse(data []byte, bookID int64) error {
+	f, err := openCFB(data)
+	if err != nil {
+		return err
+	}
+	text, err := docText(f)
+	if err != nil {
+		return err
+	}
+	body, err := textBody(docParagraphs(text))
+	if err != nil {
+		return err
+	}
+	meta := BookMetadata{Format: "doc"}
+	if summary, err := f.stream("\x05SummaryInformation"); err == nil {
+		props := summaryProperties(summary)
+		meta.Title, meta.Author = props[pidTitle], props[pidAuthor]
+	}
+	return c.build(bookID, meta, body, htmlBookOptions{})
+}
+
+// docText returns the text of the main document of a Word file, with
+// control characters still in.
+func docText(f *cfbFile) (string, error) {
+	word, err := f.stream("WordDocument")
+	if err != nil {
+		return "", err
+	}
+	if len(word) < 0x22 || binary.LittleEndian.Uint16(word) != docWordIdent {
+		return "", errors.New("not a Word document")
+	}
+	flags := binary.LittleEndian.Uint16(word[0x0A:])
+	if flags&docFlagEncrypt != 0 {
+		return "", errors.New("encrypted Word documents are not supported")
+	}
+	if binary.LittleEndian.Uint16(word[2:]) < docNFibWord97 {
+		// Word 6 and 95 keep the text in one run between fcMin and fcMac,
+		// in the system code page.
+		fcMin, fcMac := binary.LittleEndian.Uint32(word[0x18:]), binary.LittleEndian.Uint32(word[0x1C:])
+		if fcMin > fcMac || int64(fcMac) > int64(len(word)) {
+			return "", errCFBCorrupt
+		}
+		return decodeText(word[fcMin:fcMac]), nil
+	}
+
+	// The FIB is a sequence of counted arrays: 16-bit values, 32-bit
+	// values (ccpText is the fourth) and FC/LCB pairs.
+	u16 := func(off int) int {
+		if off < 0 || off+2 > len(word) {
+			return -1
+		}
+		return int(binary.LittleEndian.Uint16(word[off:]))
+	}
+	u32 := func(off int) int64 {
+		if off < 0 || off+4 > len(word) {
+			return -1
+		}
+		return int64(binary.LittleEndian.Uint32(word[off:]))
+	}
+	lw := 0x22 + 2*u16(0x20) + 2
+	pairs := lw + 4*u16(lw-2) + 2
+	ccpText := u32(lw + 12)
+	fcClx, lcbClx := u32(pairs+8*docClxIndex), u32(pairs+8*docClxIndex+4)
+	if ccpText < 0 || u16(pairs-2) <= docClxIndex || fcClx < 0 || lcbClx <= 0 {
+		return "", errCFBCorrupt
+	}
+
+	tableName := "0Table"
+	if flags&docFlag1Table != 0 {
+		tableName = "1Table"
+	}
+	table, err := f.stream(tableName)
+	if err != nil {
+		return "", err
+	}
+	if fcClx+lcbClx > int64(len(table)) {
+		return "", errCFBCorrupt
+	}
+	pieces, err := docPieces(table[fcClx : fcClx+lcbClx])
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	for _, p := range pieces {
+		if p.cp >= ccpText {
+			break
+		}
+		n := min(p.length, ccpText-p.cp)
+		if p.compressed {
+			if p.fc+n > int64(len(word)) {
+				return "", errCFBCorrupt
+			}
+			b.WriteString(decodeWith(codepageEncoding(1252), word[p.fc:p.fc+n]))
+			continue
+		}
+		if p.fc+2*n > int64(len(word)) {
+			return "", errCFBCorrupt
+		}
+		units := make([]uint16, n)
+		for i := range units {
+			units[i] = binary.LittleEndian.Uint16(word[p.fc+2*int64(i):])
+		}
+		b.WriteString(string(utf16.Decode(units)))
+	}
+	return b.String(), nil
+}
+
+// docPiece is a run of text stored contiguously in the WordDocument
+// stream: length characters from character position cp, at byte offset
+// fc.
+type docPiece struct {
+	cp, length, fc int64
+	compressed     bool
+}
+
+// docPieces reads the piece table from a Clx, which starts with optional
+// property modifiers (Prc) followed by the Pcdt.
+func docPieces(clx []byte) ([]docPiece, error) {
+	for len(clx) > 0 && clx[0] == 0x01 {
+		if len(clx) < 3 {
+			return nil, errCFBCorrupt
+		}
+		n := 3 + int(int16(binary.LittleEndian.Uint16(clx[1:])))
+		if n < 3 || n > len(clx) {
+			return nil, errCFBCorrupt
+		}
+		clx = clx[n:]
+	}
+	if len(clx) < 5 || clx[0] != 0x02 {
+		return nil, errCFBCorrupt
+	}
+	plc := clx[5:]
+	if lcb := int64(binary.LittleEndian.Uint32(clx[1:])); lcb < int64(len(plc)) {
+		plc = plc[:lcb]
+	}
+	// A PlcPcd has n+1 character positions followed by n 8-byte piece
+	// descriptors, whose fc is at offset 2.
+	n := (len(plc) - 4) / 12
+	if n <= 0 {
+		return nil, errCFBCorrupt
+	}
+	pieces := make([]docPiece, 0, n)
+	for i := range n {
+		cp := int64(binary.LittleEndian.Uint32(plc[4*i:]))
+		end := int64(binary.LittleEndian.Uint32(plc[4*i+4:]))
+		fc := int64(binary.LittleEndian.Uint32(plc[4*(n+1)+8*i+2:]))
+		if end < cp {
+			return nil, errCFBCorrupt
+		}
+		p := docPiece{cp: cp, length: end - cp, fc: fc}
+		if fc&docCompressedFC != 0 {
+			p.compressed = true
+			p.fc = (fc &^ docCompressedFC) / 2
+		}
+		pieces = append(pieces, p)
+	}
+	return pieces, nil
+}
+
+// docParagraphs splits Word text into paragraphs, dropping field
+// instructions and special characters.
+func docParagraphs(text string) []string {
+	var paragraphs []string
+	var b strings.Builder
+	flush := func() {
+		if p := strings.Join(strings.Fields(b.String()), " "); p != "" {
+			paragraphs = append(paragraphs, p)
+		}
+		b.Reset()
+	}
+	// fields holds, for each open field, whether its instructions are
+	// still being read; the result after the separator is text.
+	var fields []bool
+	for _, r := range text {
+		switch r {
+		case 0x13:
+			fields = append(fields, true)
+			continue
+		case 0x14:
+			if len(fields) > 0 {
+				fields[len(fields)-1] = false
+			}
+			continue
+		case 0x15:
+			if len(fields) > 0 {
+				fields = fields[:len(fields)-1]
+			}
+			continue
+		}
+		if len(fields) > 0 && fields[len(fields)-1] {
+			continue
+		}
+		switch {
+		case r == '\r' || r == 0x07 || r == 0x0C:
+			flush()
+		case r == '\t' || r == 0x0B:
+			b.WriteByte(' ')
+		case r == 0x1E:
+			b.WriteByte('-')
+		case r < 0x20:
+		default:
+			b.WriteRune(r)
+		}
+	}
+	flush()
+	return paragraphs
+}
+
+// summaryProperties reads the string properties of the first section of
+// an OLE property set stream.
+func summaryProperties(data []byte) map[int]string {
+	props := make(map[int]string)
+	u32 := func(off int) int {
+		if off < 0 || off+4 > len(data) {
+			return -1
+		}
+		return int(binary.LittleEndian.Uint32(data[off:]))
+	}
+	if u32(24) < 1 {
+		return props
+	}
+	section := u32(44)
+	count := u32(section + 4)
+	if section < 0 || count < 0 || count > len(data)/8 {
+		return props
+	}
+	codepage := 1252
+	type entry struct{ id, off int }
+	var strs []entry
+	for i := range count {
+		id, off := u32(section+8+8*i), u32(section+12+8*i)
+		if id < 0 || off < 0 {
+			break
+		}
+		off += section
+		switch {
+		case id == pidCodepage && u32(off) == vtI2 && off+6 <= len(data):
+			codepage = int(binary.LittleEndian.Uint16(data[off+4:]))
+		case id == pidTitle || id == pidAuthor:
+			strs = append(strs, entry{id, off})
+		}
+	}
+	for _, e := range strs {
+		n := u32(e.off + 4)
+		start := e.off + 8
+		var s string
+		switch u32(e.off) {
+		case vtLPSTR:
+			if n < 0 || start+n > len(data) {
+				continue
+			}
+			s = decodeWith(codepageEncoding(codepage), data[start:start+n])
+		case vtLPWSTR:
+			if n < 0 || start+2*n > len(data) {
+				continue
+			}
+			units := make([]uint16, n)
+			for i := range units {
+				units[i] = binary.LittleEndian.Uint16(data[start+2*i:])
+			}
+			s = string(utf16.Decode(units))
+		}
+		props[e.id] = strings.TrimSpace(strings.TrimRight(s, "\x00"))
+	}
+	return props
+}
@@ -0,0 +1,282 @@
+package bookfile
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+	"unicode/utf16"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/text/encoding/charmap"
+)
+
+type cfbTestStream struct {
+	name string
+	data []byte
+}
+
+// buildCFB writes a version 3 compound file. Streams are padded to the
+// mini stream cutoff so that all are stored in regular sectors.
+func buildCFB(streams []cfbTestStream) []byte {
+	const sectorSize = 512
+	le := binary.LittleEndian
+	var sectors []byte
+	var fat []uint32
+	addChain := func(data []byte) uint32 {
+		start := uint32(len(fat))
+		n := (len(data) + sectorSize - 1) / sectorSize
+		for i := range n {
+			next := uint32(cfbEndOfChain)
+			if i < n-1 {
+				next = start + uint32(i) + 1
+			}
+			fat = append(fat, next)
+		}
+		sectors = append(sectors, data...)
+		sectors = append(sectors, make([]byte, n*sectorSize-len(data))...)
+		return start
+	}
+
+	dir := make([]byte, cfbDirEntrySize*(len(streams)+1))
+	entry := func(i int, name string, typ byte, start uint32, size int) {
+		e := dir[i*cfbDirEntrySize:]
+		units := utf16.Encode([]rune(name))
+		for j, u := range units {
+			le.PutUint16(e[2*j:], u)
+		}
+		le.PutUint16(e[0x40:], uint16(2*len(units)+2))
+		e[0x42] = typ
+		le.PutUint32(e[0x44:], 0xFFFFFFFF)
+		le.PutUint32(e[0x48:], 0xFFFFFFFF)
+		le.PutUint32(e[0x4C:], 0xFFFFFFFF)
+		if i > 0 && i < len(streams) {
+			le.PutUint32(e[0x48:], uint32(i+1))
+		}
+		le.PutUint32(e[0x74:], start)
+		le.PutUint64(e[0x78:], uint64(size))
+	}
+	entry(0, "Root Entry", cfbTypeRoot, cfbEndOfChain, 0)
+	le.PutUint32(dir[0x4C:], 1)
+	for i, s := range streams {
+		data := s.data
+		if len(data) < 4096 {
+			data = append(data, make([]byte, 4096-len(data))...)
+		}
+		entry(i+1, s.name, cfbTypeStream, addChain(data), len(data))
+	}
+	dirStart := addChain(dir)
+
+	fatSectors := (len(fat) + sectorSize/4) / (sectorSize/4 - 1)
+	fatStart := uint32(len(fat))
+	for range fatSectors {
+		fat = append(fat, 0xFFFFFFFD)
+	}
+	fatData := make([]byte, fatSectors*sectorSize)
+	for i := range fatData {
+		fatData[i] = 0xFF
+	}
+	for i, n := range fat {
+		le.PutUint32(fatData[4*i:], n)
+	}
+
+	header := make([]byte, cfbHeaderSize)
+	copy(header, cfbSignature)
+	le.PutUint16(header[0x18:], 0x3E)
+	le.PutUint16(header[0x1A:], 3)
+	le.PutUint16(header[0x1C:], 0xFFFE)
+	le.PutUint16(header[0x1E:], 9)
+	le.PutUint16(header[0x20:], 6)
+	le.PutUint32(header[0x2C:], uint32(fatSectors))
+	le.PutUint32(header[0x30:], dirStart)
+	le.PutUint32(header[0x38:], 4096)
+	le.PutUint32(header[0x3C:], cfbEndOfChain)
+	le.PutUint32(header[0x44:], cfbEndOfChain)
+	for i := range cfbHeaderDIFAT {
+		n := uint32(0xFFFFFFFF)
+		if i < fatSectors {
+			n = fatStart + uint32(i)
+		}
+		le.PutUint32(header[0x4C+4*i:], n)
+	}
+	return bytes.Join([][]byte{header, sectors, fatData}, nil)
+}
+
+// buildWordDocument writes a Word 97 document with the given pieces, in
+// Windows-1252 if compressed and in UTF-16 otherwise. Text past ccpText
+// belongs to other stories such as footnotes.
+func buildWordDocument(pieces []string, compressed []bool, ccpText int, summary []byte) []byte {
+	le := binary.LittleEndian
+	word := make([]byte, 0x400)
+	le.PutUint16(word[0:], docWordIdent)
+	le.PutUint16(word[2:], docNFibWord97)
+	le.PutUint16(word[0x0A:], docFlag1Table)
+	le.PutUint16(word[0x20:], 14)
+	le.PutUint16(word[0x3E:], 22)
+	le.PutUint32(word[0x4C:], uint32(ccpText))
+	le.PutUint16(word[0x98:], 93)
+
+	cps := []uint32{0}
+	var pcds []byte
+	for i, p := range pieces {
+		fc := uint32(len(word))
+		var n int
+		if compressed[i] {
+			data, _ := charmap.Windows1252.NewEncoder().Bytes([]byte(p))
+			word = append(word, data...)
+			n = len(data)
+			fc = fc*2 | docCompressedFC
+		} else {
+			units := utf16.Encode([]rune(p))
+			for _, u := range units {
+				word = le.AppendUint16(word, u)
+			}
+			n = len(units)
+		}
+		cps = append(cps, cps[len(cps)-1]+uint32(n))
+		pcd := make([]byte, 8)
+		le.PutUint32(pcd[2:], fc)
+		pcds = append(pcds, pcd...)
+	}
+
+	var plc []byte
+	for _, cp := range cps {
+		plc = le.AppendUint32(plc, cp)
+	}
+	plc = append(plc, pcds...)
+	// A property modifier precedes the piece table.
+	clx := []byte{0x01, 0x02, 0x00, 0xAA, 0xBB, 0x02}
+	clx = le.AppendUint32(clx, uint32(len(plc)))
+	clx = append(clx, plc...)
+	table := append(make([]byte, 16), clx...)
+	le.PutUint32(word[0x9A+8*docClxIndex:], 16)
+	le.PutUint32(word[0x9A+8*docClxIndex+4:], uint32(len(clx)))
+
+	streams := []cfbTestStream{{"WordDocument", word}, {"1Table", table}}
+	if summary != nil {
+		streams = append(streams, cfbTestStream{"\x05SummaryInformation", summary})
+	}
+	return buildCFB(streams)
+}
+
+// buildSummaryInformation writes a property set with a Windows-1251 title
+// and a UTF-16 author.
+func buildSummaryInformation(title, author string) []byte {
+	le := binary.LittleEndian
+	var props []byte
+	props = le.AppendUint32(props, vtI2)
+	props = le.AppendUint32(props, 1251)
+	titleOff := len(props)
+	titleData, _ := charmap.Windows1251.NewEncoder().Bytes([]byte(title + "\x00"))
+	props = le.AppendUint32(props, vtLPSTR)
+	props = le.AppendUint32(props, uint32(len(titleData)))
+	props = append(props, titleData...)
+	for len(props)%4 != 0 {
+		props = append(props, 0)
+	}
+	authorOff := len(props)
+	units := utf16.Encode([]rune(author + "\x00"))
+	props = le.AppendUint32(props, vtLPWSTR)
+	props = le.AppendUint32(props, uint32(len(units)))
+	for _, u := range units {
+		props = le.AppendUint16(props, u)
+	}
+
+	const headerSize = 8 + 3*8
+	section := le.AppendUint32(nil, uint32(headerSize+len(props)))
+	section = le.AppendUint32(section, 3)
+	for _, p := range [][2]int{{pidCodepage, 0}, {pidTitle, titleOff}, {pidAuthor, authorOff}} {
+		section = le.AppendUint32(section, uint32(p[0]))
+		section = le.AppendUint32(section, uint32(headerSize+p[1]))
+	}
+	section = append(section, props...)
+
+	data := make([]byte, 48)
+	le.PutUint16(data, 0xFFFE)
+	le.PutUint32(data[24:], 1)
+	le.PutUint32(data[44:], 48)
+	return append(data, section...)
+}
+
+func TestDOCConverter(t *testing.T) {
+	pieces := []string{
+		"Chapter 1\rHello, \x13 HYPERLINK \"http://example.com\" \x14world\x15!\r",
+		"Глава 2\rТекст\x0bещё\tи\x1eещё\x07\x0c",
+		"Footnote\r",
+	}
+	ccpText := len([]rune(pieces[0])) + len([]rune(pieces[1]))
+	data := buildWordDocument(pieces, []bool{true, false, true}, ccpText, buildSummaryInformation("Записки", "Иван Петров"))
+
+	conv, err := GetConverter("doc")
+	require.NoError(t, err)
+	require.NoError(t, conv.Parse(data, 6))
+
+	content := conv.Content()
+	assert.Equal(t, BookMetadata{Title: "Записки", Author: "Иван Петров", Format: "doc"}, content.Metadata)
+	assert.Equal(t, []TOCEntry{
+		{ID: "ch1", Title: "Chapter 1"},
+		{ID: "ch2", Title: "Глава 2"},
+	}, content.TOC)
+
+	ch, err := conv.Chapter("ch1")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Chapter 1", "Hello, world!"}, ParagraphTexts(ch.HTML))
+	ch, err = conv.Chapter("ch2")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Глава 2", "Текст ещё и-ещё"}, ParagraphTexts(ch.HTML))
+}
+
+func TestDOCConverter_Word95(t *testing.T) {
+	text, _ := charmap.Windows1251.NewEncoder().Bytes([]byte("Глава 1\r\rСтарый текст в кодировке Windows.\r"))
+	word := make([]byte, 0x400)
+	binary.LittleEndian.PutUint16(word, docWordIdent)
+	binary.LittleEndian.PutUint16(word[2:], 0x65)
+	binary.LittleEndian.PutUint32(word[0x18:], 0x400)
+	binary.LittleEndian.PutUint32(word[0x1C:], uint32(0x400+len(text)))
+	word = append(word, text...)
+
+	conv, err := GetConverter("doc")
+	require.NoError(t, err)
+	require.NoError(t, conv.Parse(buildCFB([]cfbTestStream{{"WordDocument", word}}), 1))
+
+	ch, err := conv.Chapter("ch1")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Глава 1", "Старый текст в кодировке Windows."}, ParagraphTexts(ch.HTML))
+}
+
+func TestDOCConverter_Invalid(t *testing.T) {
+	conv, err := GetConverter("doc")
+	require.NoError(t, err)
+	assert.Error(t, conv.Parse([]byte("not a compound file"), 1))
+	assert.Error(t, conv.Parse(buildCFB([]cfbTestStream{{"Other", []byte("x")}}), 1))
+
+	encrypted := buildWordDocument([]string{"Secret\r"}, []bool{true}, 7, nil)
+	cfb, err := openCFB(encrypted)
+	require.NoError(t, err)
+	word, err := cfb.stream("WordDocument")
+	require.NoError(t, err)
+	// Streams are stored contiguously, so the flags can be patched in place.
+	offset := bytes.Index(encrypted, word[:0x40])
+	binary.LittleEndian.PutUint16(encrypted[offset+0x0A:], docFlag1Table|docFlagEncrypt)
+	err = conv.Parse(encrypted, 1)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "encrypted")
+}
+
+func TestCFB_Corrupt(t *testing.T) {
+	data := buildCFB([]cfbTestStream{{"WordDocument", []byte("text")}})
+	_, err := openCFB(data)
+	require.NoError(t, err)
+
+	// The directory follows the stream's eight sectors; make its chain
+	// loop back on itself.
+	loop := bytes.Clone(data)
+	binary.LittleEndian.PutUint32(loop[len(loop)-512+4*8:], 8)
+	_, err = openCFB(loop)
+	assert.Error(t, err)
+
+	for n := 0; n < len(data); n += 97 {
+		_, err := openCFB(data[:n])
+		assert.Error(t, err)
+	}
+}
@@ -0,0 +1,613 @@
+package bookfile
+
+import (
+	"archive/zip"
+	"bytes"
+	"cmp"
+	"encoding/xml"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+
+	xhtml "golang.org/x/net/html"
+)
+
+// docxHeadingStyle matches the names of the built-in heading styles, which
+// Word keeps in English whatever the interface language.
+var docxHeadingStyle = regexp.MustCompile(`^(?i:heading)\s*([1-9])$`)
+
+// docxImageIDChars matches characters not allowed in image IDs.
+var docxImageIDChars = regexp.MustCompile(`[^a-zA-Z0-9._-]`)
+
+// xmlElement is an element of a generic XML tree, as used for
+// WordprocessingML: elements are matched by local name, and text is only
+// kept for elements that hold nothing else.
+type xmlElement struct {
+	name     string
+	attrs    []xml.Attr
+	children []*xmlElement
+	text     string
+}
+
+// parseXMLTree parses an XML document into a tree of elements.
+func parseXMLTree(data []byte) (*xmlElement, error) {
+	d := xml.NewDecoder(bytes.NewReader(data))
+	root := &xmlElement{}
+	stack := []*xmlElement{root}
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		top := stack[len(stack)-1]
+		switch t := tok.(type) {
+		case xml.StartElement:
+			e := &xmlElement{name: t.Name.Local, attrs: t.Attr}
+			top.children = append(top.children, e)
+			stack = append(stack, e)
+		case xml.EndElement:
+			if len(stack) > 1 {
+				stack = stack[:len(stack)-1]
+			}
+		case xml.CharData:
+			if len(top.children) == 0 {
+				top.text += string(t)
+			}
+		}
+	}
+	if len(root.children) == 0 {
+		return nil, fmt.Errorf("empty XML document")
+	}
+	return root.children[0], nil
+}
+
+// attr returns the value of the attribute with the given local name.
+func (e *xmlElement) attr(name string) string {
+	for _, a := range e.attrs {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// child returns the first child element with the given local name.
+func (e *xmlElement) child(name string) *xmlElement {
+	if e == nil {
+		return nil
+	}
+	for _, c := range e.children {
+		if c.name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+// find returns the first descendant element with the given local name.
+func (e *xmlElement) find(name string) *xmlElement {
+	for _, c := range e.children {
+		if c.name == name {
+			return c
+		}
+		if found := c.find(name); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// childrenOf returns the children of e, which may be nil.
+func (e *xmlElement) childrenOf() []*xmlElement {
+	if e == nil {
+		return nil
+	}
+	return e.children
+}
+
+// val returns the w:val attribute of the named child, and whether the
+// child is present.
+func (e *xmlElement) val(name string) (string, bool) {
+	c := e.child(name)
+	if c == nil {
+		return "", false
+	}
+	return c.attr("val"), true
+}
+
+// docxStyle is what conversion needs of a paragraph style.
+type docxStyle struct {
+	name    string
+	basedOn string
+	// outline is the 0-based outline level, -1 for body text.
+	outline int
+}
+
+// docxRel is a relationship of a document part.
+type docxRel struct {
+	typ      string
+	target   string
+	external bool
+}
+
+// DOCXConverter converts Word documents (Office Open XML). Paragraphs in
+// heading styles, or with an outline level, become headings that split the
+// document into chapters; a paragraph in the Title style at the start is
+// the book title. Embedded raster images are kept.
+type DOCXConverter struct {
+	htmlBook
+	files  map[string]*zip.File
+	rels   map[string]docxRel
+	styles map[string]*docxStyle
+	// lists maps numbering IDs and levels to whether the list is ordered.
+	lists map[string]map[string]bool
+}
+
+func (c *DOCXConverter) Parse(data []byte, bookID int64) error {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return fmt.Errorf("open DOCX: %w", err)
+	}
+	c.files = make(map[string]*zip.File)
+	for _, f := range zr.File {
+		c.files[f.Name] = f
+	}
+
+	docPath := "word/document.xml"
+	if rels, err := c.readRels("_rels/.rels", ""); err == nil {
+		for _, rel := range rels {
+			if strings.HasSuffix(rel.typ, "/officeDocument") {
+				docPath = rel.target
+			}
+		}
+	}
+	doc, err := c.readXML(docPath)
+	if err != nil {
+		return err
+	}
+	body := doc.child("body")
+	if body == nil {
+		return fmt.Errorf("DOCX document has no body")
+	}
+
+	dir := path.Dir(docPath)
+	base := strings.TrimSuffix(path.Base(docPath), ".xml")
+	c.rels, err = c.readRels(path.Join(dir, "_rels", base+".xml.rels"), dir)
+	if err != nil {
+		c.rels = make(map[string]docxRel)
+	}
+	c.readStyles(c.relTarget("/styles", path.Join(dir, "styles.xml")))
+	c.readNumbering(c.relTarget("/numbering", path.Join(dir, "numbering.xml")))
+
+	meta := BookMetadata{Format: "docx"}
+	if core, err := c.readXML("docProps/core.xml"); err == nil {
+		for _, e := range core.children {
+			switch e.name {
+			case "title":
+				meta.Title = strings.TrimSpace(e.text)
+			case "creator":
+				meta.Author = strings.TrimSpace(e.text)
+			case "language":
+				meta.Language = strings.TrimSpace(e.text)
+			}
+		}
+	}
+
+	r := docxRenderer{c: c, meta: &meta}
+	r.blocks(body.children)
+	r.closeList()
+	htmlBody, err := parseHTMLBody(r.out.String())
+	if err != nil {
+		return err
+	}
+	// Without a Title paragraph, a lone top-level heading is the title.
+	return c.build(bookID, meta, htmlBody, htmlBookOptions{titleHeading: !r.titled, image: c.resolveImage})
+}
+
+func (c *DOCXConverter) readXML(name string) (*xmlElement, error) {
+	f, ok := c.files[name]
+	if !ok {
+		return nil, fmt.Errorf("DOCX part %q not found", name)
+	}
+	data, err := readZIPFile(f)
+	if err != nil {
+		return nil, err
+	}
+	return parseXMLTree(data)
+}
+
+// readRels reads a relationships part. Internal targets are resolved
+// against dir.
+func (c *DOCXConverter) readRels(name, dir string) (map[string]docxRel, error) {
+	root, err := c.readXML(name)
+	if err != nil {
+		return nil, err
+	}
+	rels := make(map[string]docxRel)
+	for _, e := range root.children {
+		rel := docxRel{
+			typ:      e.attr("Type"),
+			target:   e.attr("Target"),
+			external: e.attr("TargetMode") == "External",
+		}
+		if !rel.external {
+			if strings.HasPrefix(rel.target, "/") {
+				rel.target = strings.TrimPrefix(rel.target, "/")
+			} else {
+				rel.target = path.Join(dir, rel.target)
+			}
+		}
+		rels[e.attr("Id")] = rel
+	}
+	return rels, nil
+}
+
+// relTarget returns the target of the document relationship of the given
+// type, or fallback.
+func (c *DOCXConverter) relTarget(typeSuffix, fallback string) string {
+	for _, rel := range c.rels {
+		if strings.HasSuffix(rel.typ, typeSuffix) && !rel.external {
+			return rel.target
+		}
+	}
+	return fallback
+}
+
+func (c *DOCXConverter) readStyles(name string) {
+	c.styles = make(map[string]*docxStyle)
+	root, err := c.readXML(name)
+	if err != nil {
+		return
+	}
+	for _, e := range root.children {
+		if e.name != "style" || e.attr("type") != "paragraph" {
+			continue
+		}
+		s := &docxStyle{outline: -1}
+		s.name, _ = e.val("name")
+		s.basedOn, _ = e.val("basedOn")
+		if v, ok := e.child("pPr").val("outlineLvl"); ok {
+			s.outline = docxOutline(v)
+		} else if m := docxHeadingStyle.FindStringSubmatch(s.name); m != nil {
+			s.outline = int(m[1][0] - '1')
+		}
+		c.styles[e.attr("styleId")] = s
+	}
+}
+
+// styleOutline returns the outline level of a style, following the styles
+// it is based on.
+func (c *DOCXConverter) styleOutline(id string) int {
+	for range 10 {
+		s, ok := c.styles[id]
+		if !ok {
+			break
+		}
+		if s.outline >= 0 {
+			return s.outline
+		}
+		id = s.basedOn
+	}
+	return -1
+}
+
+func (c *DOCXConverter) styleName(id string) string {
+	if s, ok := c.styles[id]; ok {
+		return strings.ToLower(s.name)
+	}
+	return ""
+}
+
+func (c *DOCXConverter) readNumbering(name string) {
+	c.lists = make(map[string]map[string]bool)
+	root, err := c.readXML(name)
+	if err != nil {
+		return
+	}
+	abstract := make(map[string]map[string]bool)
+	for _, e := range root.children {
+		switch e.name {
+		case "abstractNum":
+			levels := make(map[string]bool)
+			for _, lvl := range e.children {
+				if lvl.name == "lvl" {
+					format, _ := lvl.val("numFmt")
+					levels[lvl.attr("ilvl")] = format != "bullet" && format != "none"
+				}
+			}
+			abstract[e.attr("abstractNumId")] = levels
+		case "num":
+			id, _ := e.val("abstractNumId")
+			c.lists[e.attr("numId")] = abstract[id]
+		}
+	}
+}
+
+// docxOutline parses an outline level; level 9 is body text.
+func docxOutline(v string) int {
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 0 || n > 8 {
+		return -1
+	}
+	return n
+}
+
+// resolveImage reads the image part an image refers to. Images in formats
+// browsers cannot show, such as EMF, are dropped.
+func (c *DOCXConverter) resolveImage(img *xhtml.Node) *ImageData {
+	f, ok := c.files[nodeAttr(img, "src")]
+	if !ok {
+		return nil
+	}
+	data, err := readZIPFile(f)
+	if err != nil {
+		return nil
+	}
+	contentType := http.DetectContentType(data)
+	if _, ok := dataURIImageTypes[contentType]; !ok {
+		return nil
+	}
+	id := docxImageIDChars.ReplaceAllString(path.Base(f.Name), "_")
+	return &ImageData{ID: id, ContentType: contentType, Data: data}
+}
+
+// docxRenderer renders the body of a document as HTML.
+type docxRenderer struct {
+	c    *DOCXConverter
+	meta *BookMetadata
+	out  strings.Builder
+	// list is the tag of the open list, if any.
+	list       string
+	hasContent bool
+	// titled is set if the document starts with a Title paragraph.
+	titled bool
+}
+
+func (r *docxRenderer) blocks(elements []*xmlElement) {
+	for _, e := range elements {
+		switch e.name {
+		case "p":
+			r.paragraph(e)
+		case "tbl":
+			r.closeList()
+			r.table(e)
+		case "sdt":
+			r.blocks(e.child("sdtContent").childrenOf())
+		case "customXml":
+			r.blocks(e.children)
+		}
+	}
+}
+
+func (r *docxRenderer) paragraph(p *xmlElement) {
+	pPr := p.child("pPr")
+	styleID, _ := pPr.val("pStyle")
+	styleName := r.c.styleName(styleID)
+	if strings.HasPrefix(styleName, "toc ") || styleName == "toc heading" {
+		// Word's generated table of contents duplicates the headings.
+		return
+	}
+
+	var inline strings.Builder
+	r.inline(&inline, p.children)
+	content := inline.String()
+	text := strings.TrimSpace(docxPlainText(p))
+	if text == "" && !strings.Contains(content, "<img") {
+		return
+	}
+
+	if styleName == "title" && !r.hasContent {
+		r.titled = true
+		if r.meta.Title == "" {
+			r.meta.Title = text
+		}
+		return
+	}
+	r.hasContent = true
+
+	outline := r.c.styleOutline(styleID)
+	if v, ok := pPr.val("outlineLvl"); ok {
+		outline = docxOutline(v)
+	}
+	numPr := pPr.child("numPr")
+	// Numbering ID 0 removes the numbering of the style.
+	numID, _ := numPr.val("numId")
+	switch {
+	case outline >= 0:
+		r.closeList()
+		level := min(outline+1, 6)
+		fmt.Fprintf(&r.out, "<h%d>%s</h%d>\n", level, content, level)
+	case numID != "" && numID != "0":
+		ilvl, _ := numPr.val("ilvl")
+		tag := "ul"
+		if r.c.lists[numID][cmp.Or(ilvl, "0")] {
+			tag = "ol"
+		}
+		if r.list != tag {
+			r.closeList()
+			fmt.Fprintf(&r.out, "<%s>\n", tag)
+			r.list = tag
+		}
+		fmt.Fprintf(&r.out, "<li>%s</li>\n", content)
+	default:
+		r.closeList()
+		fmt.Fprintf(&r.out, "<p>%s</p>\n", content)
+	}
+}
+
+func (r *docxRenderer) closeList() {
+	if r.list != "" {
+		fmt.Fprintf(&r.out, "</%s>\n", r.list)
+		r.list = ""
+	}
+}
+
+func (r *docxRenderer) table(tbl *xmlElement) {
+	r.out.WriteString("<table>\n")
+	for _, tr := range tbl.children {
+		if tr.name != "tr" {
+			continue
+		}
+		r.out.WriteString("<tr>")
+		for _, tc := range tr.children {
+			if tc.name != "tc" {
+				continue
+			}
+			if span, ok := tc.child("tcPr").val("gridSpan"); ok && span != "1" {
+				fmt.Fprintf(&r.out, "<td colspan=\"%s\">", html.EscapeString(span))
+			} else {
+				r.out.WriteString("<td>")
+			}
+			for _, e := range tc.children {
+				switch e.name {
+				case "p":
+					var inline strings.Builder
+					r.inline(&inline, e.children)
+					if strings.TrimSpace(docxPlainText(e)) != "" || strings.Contains(inline.String(), "<img") {
+						fmt.Fprintf(&r.out, "<p>%s</p>", inline.String())
+					}
+				case "tbl":
+					r.table(e)
+				}
+			}
+			r.out.WriteString("</td>")
+		}
+		r.out.WriteString("</tr>\n")
+	}
+	r.out.WriteString("</table>\n")
+}
+
+// inline renders the runs of a paragraph.
+func (r *docxRenderer) inline(out *strings.Builder, elements []*xmlElement) {
+	for _, e := range elements {
+		switch e.name {
+		case "r":
+			r.run(out, e)
+		case "hyperlink":
+			rel, ok := r.c.rels[e.attr("id")]
+			if ok && rel.external {
+				fmt.Fprintf(out, "<a href=\"%s\">", html.EscapeString(rel.target))
+				r.inline(out, e.children)
+				out.WriteString("</a>")
+			} else {
+				r.inline(out, e.children)
+			}
+		case "ins", "smartTag", "fldSimple", "customXml":
+			r.inline(out, e.children)
+		case "sdt":
+			r.inline(out, e.child("sdtContent").childrenOf())
+		}
+	}
+}
+
+// docxRunFormats maps run properties to the HTML elements they render as.
+var docxRunFormats = []struct{ prop, tag string }{
+	{"b", "strong"},
+	{"i", "em"},
+	{"u", "u"},
+	{"strike", "s"},
+	{"dstrike", "s"},
+}
+
+func (r *docxRenderer) run(out *strings.Builder, run *xmlElement) {
+	var content strings.Builder
+	for _, e := range run.children {
+		switch e.name {
+		case "t":
+			content.WriteString(html.EscapeString(e.text))
+		case "tab":
+			content.WriteString(" ")
+		case "br", "cr":
+			if e.attr("type") != "page" {
+				content.WriteString("<br/>")
+			}
+		case "noBreakHyphen":
+			content.WriteString("‑")
+		case "softHyphen":
+			content.WriteString("­")
+		case "drawing", "pict", "object":
+			content.WriteString(r.image(e))
+		}
+	}
+	if content.Len() == 0 {
+		return
+	}
+
+	rPr := run.child("rPr")
+	var tags []string
+	for _, f := range docxRunFormats {
+		if v, ok := rPr.val(f.prop); ok && docxToggle(v) {
+			tags = append(tags, f.tag)
+		}
+	}
+	switch v, _ := rPr.val("vertAlign"); v {
+	case "superscript":
+		tags = append(tags, "sup")
+	case "subscript":
+		tags = append(tags, "sub")
+	}
+	for _, tag := range tags {
+		fmt.Fprintf(out, "<%s>", tag)
+	}
+	out.WriteString(content.String())
+	for i := len(tags) - 1; i >= 0; i-- {
+		fmt.Fprintf(out, "</%s>", tags[i])
+	}
+}
+
+// docxToggle reports whether the value of a toggle property turns it on.
+func docxToggle(v string) bool {
+	switch v {
+	case "0", "false", "off", "none":
+		return false
+	}
+	return true
+}
+
+// image renders the image of a DrawingML or VML object, pointing its
+// source at the image part for resolveImage.
+func (r *docxRenderer) image(e *xmlElement) string {
+	var id string
+	if blip := e.find("blip"); blip != nil {
+		id = blip.attr("embed")
+	} else if data := e.find("imagedata"); data != nil {
+		id = data.attr("id")
+	}
+	rel, ok := r.c.rels[id]
+	if !ok || rel.external {
+		return ""
+	}
+	var alt string
+	if pr := e.find("docPr"); pr != nil {
+		alt = pr.attr("descr")
+	}
+	return fmt.Sprintf(`<img src="%s" alt="%s"/>`, html.EscapeString(rel.target), html.EscapeString(alt))
+}
+
+// docxPlainText returns the text of the runs under e.
+func docxPlainText(e *xmlElement) string {
+	var sb strings.Builder
+	var walk func(*xmlElement)
+	walk = func(e *xmlElement) {
+		switch e.name {
+		case "t":
+			sb.WriteString(e.text)
+			return
+		case "del", "instrText", "delText":
+			return
+		}
+		for _, c := range e.children {
+			walk(c)
+		}
+	}
+	walk(e)
+	return sb.String()
+}
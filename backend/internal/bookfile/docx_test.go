@@ -0,0 +1,127 @@
+package bookfile
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const docxNS = `xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main" ` +
+	`xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships" ` +
+	`xmlns:a="http://schemas.openxmlformats.org/drawingml/2006/main" ` +
+	`xmlns:wp="http://schemas.openxmlformats.org/drawingml/2006/wordprocessingDrawing"`
+
+const testDOCXDocument = `<?xml version="1.0" encoding="UTF-8"?>
+<w:document ` + docxNS + `><w:body>
+<w:p><w:pPr><w:pStyle w:val="Title"/></w:pPr><w:r><w:t>Записки</w:t></w:r></w:p>
+<w:p><w:pPr><w:pStyle w:val="TOC1"/></w:pPr><w:r><w:t>Глава 1</w:t></w:r></w:p>
+<w:p><w:pPr><w:pStyle w:val="Heading1"/></w:pPr><w:r><w:t>Глава 1</w:t></w:r></w:p>
+<w:p><w:r><w:t xml:space="preserve">Обычный </w:t></w:r><w:r><w:rPr><w:b/></w:rPr><w:t>жирный</w:t></w:r>` +
+	`<w:r><w:rPr><w:i w:val="0"/></w:rPr><w:t xml:space="preserve"> текст</w:t></w:r>` +
+	`<w:r><w:rPr><w:vertAlign w:val="superscript"/></w:rPr><w:t>1</w:t></w:r></w:p>
+<w:p><w:pPr><w:numPr><w:ilvl w:val="0"/><w:numId w:val="1"/></w:numPr></w:pPr><w:r><w:t>Первый</w:t></w:r></w:p>
+<w:p><w:pPr><w:numPr><w:ilvl w:val="0"/><w:numId w:val="1"/></w:numPr></w:pPr><w:r><w:t>Второй</w:t></w:r></w:p>
+<w:p><w:hyperlink r:id="rLink"><w:r><w:t>Ссылка</w:t></w:r></w:hyperlink></w:p>
+<w:p><w:r><w:drawing><wp:inline><wp:docPr id="1" name="Рисунок" descr="Схема"/>` +
+	`<a:graphic><a:graphicData><a:blip r:embed="rImg"/></a:graphicData></a:graphic></wp:inline></w:drawing></w:r></w:p>
+<w:p><w:pPr><w:outlineLvl w:val="1"/></w:pPr><w:r><w:t>Раздел</w:t></w:r></w:p>
+<w:tbl><w:tr><w:tc><w:tcPr><w:gridSpan w:val="2"/></w:tcPr><w:p><w:r><w:t>Ячейка</w:t></w:r></w:p></w:tc></w:tr></w:tbl>
+<w:p><w:r><w:t>Конец.</w:t></w:r></w:p>
+<w:sectPr/>
+</w:body></w:document>`
+
+const testDOCXStyles = `<?xml version="1.0" encoding="UTF-8"?>
+<w:styles ` + docxNS + `>
+<w:style w:type="paragraph" w:styleId="Title"><w:name w:val="Title"/></w:style>
+<w:style w:type="paragraph" w:styleId="Heading1"><w:name w:val="heading 1"/></w:style>
+<w:style w:type="paragraph" w:styleId="TOC1"><w:name w:val="toc 1"/></w:style>
+</w:styles>`
+
+const testDOCXNumbering = `<?xml version="1.0" encoding="UTF-8"?>
+<w:numbering ` + docxNS + `>
+<w:abstractNum w:abstractNumId="7"><w:lvl w:ilvl="0"><w:numFmt w:val="decimal"/></w:lvl></w:abstractNum>
+<w:num w:numId="1"><w:abstractNumId w:val="7"/></w:num>
+</w:numbering>`
+
+const testDOCXRels = `<?xml version="1.0" encoding="UTF-8"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rStyles" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>
+<Relationship Id="rNum" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/numbering" Target="numbering.xml"/>
+<Relationship Id="rImg" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/image" Target="media/image1.png"/>
+<Relationship Id="rLink" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/hyperlink" Target="https://example.com/" TargetMode="External"/>
+</Relationships>`
+
+const testDOCXPackageRels = `<?xml version="1.0" encoding="UTF-8"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="word/document.xml"/>
+</Relationships>`
+
+const testDOCXCore = `<?xml version="1.0" encoding="UTF-8"?>
+<cp:coreProperties xmlns:cp="http://schemas.openxmlformats.org/package/2006/metadata/core-properties" ` +
+	`xmlns:dc="http://purl.org/dc/elements/1.1/"><dc:creator>Иван Петров</dc:creator><dc:language>ru-RU</dc:language></cp:coreProperties>`
+
+func parseTestDOCX(t *testing.T) *DOCXConverter {
+	t.Helper()
+	data := buildCBZ(t, []comicFile{
+		{name: "_rels/.rels", data: []byte(testDOCXPackageRels)},
+		{name: "docProps/core.xml", data: []byte(testDOCXCore)},
+		{name: "word/document.xml", data: []byte(testDOCXDocument)},
+		{name: "word/_rels/document.xml.rels", data: []byte(testDOCXRels)},
+		{name: "word/styles.xml", data: []byte(testDOCXStyles)},
+		{name: "word/numbering.xml", data: []byte(testDOCXNumbering)},
+		{name: "word/media/image1.png", data: testPageImage(t, 4)},
+	})
+	conv, err := GetConverter("docx")
+	require.NoError(t, err)
+	require.NoError(t, conv.Parse(data, 5))
+	return conv.(*DOCXConverter)
+}
+
+func TestDOCXConverter_Content(t *testing.T) {
+	conv := parseTestDOCX(t)
+
+	content := conv.Content()
+	assert.Equal(t, BookMetadata{Title: "Записки", Author: "Иван Петров", Language: "ru-RU", Format: "docx"}, content.Metadata)
+	assert.Equal(t, []TOCEntry{
+		{ID: "ch1", Title: "Глава 1"},
+		{ID: "ch2", Title: "Раздел", Level: 1},
+	}, content.TOC)
+}
+
+func TestDOCXConverter_Chapter(t *testing.T) {
+	conv := parseTestDOCX(t)
+
+	ch, err := conv.Chapter("ch1")
+	require.NoError(t, err)
+	assert.Equal(t, "Глава 1", ch.Title)
+	assert.Contains(t, ch.HTML, "Обычный <strong>жирный</strong> текст<sup>1</sup>")
+	assert.NotContains(t, ch.HTML, "<em>")
+	assert.Contains(t, ch.HTML, "<ol>")
+	assert.Contains(t, ch.HTML, `<a href="https://example.com/"`)
+	assert.Contains(t, ch.HTML, `/api/books/5/image/image1.png?v=`)
+	assert.Contains(t, ch.HTML, `alt="Схема"`)
+
+	ch, err = conv.Chapter("ch2")
+	require.NoError(t, err)
+	assert.Contains(t, ch.HTML, `<td colspan="2">`)
+	assert.Contains(t, ParagraphTexts(ch.HTML), "Конец.")
+}
+
+func TestDOCXConverter_Image(t *testing.T) {
+	conv := parseTestDOCX(t)
+
+	img, err := conv.Image("image1.png")
+	require.NoError(t, err)
+	assert.Equal(t, "image/png", img.ContentType)
+	assert.Equal(t, testPageImage(t, 4), img.Data)
+}
+
+func TestDOCXConverter_Invalid(t *testing.T) {
+	conv, err := GetConverter("docx")
+	require.NoError(t, err)
+	assert.Error(t, conv.Parse([]byte("not a zip"), 1))
+
+	data := buildCBZ(t, []comicFile{{name: "word/other.xml", data: []byte("<x/>")}})
+	assert.Error(t, conv.Parse(data, 1))
+}
@@ -0,0 +1,85 @@
+package bookfile
+
+import (
+	"bytes"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/unicode"
+)
+
+// encodingSampleSize is how much text is decoded to guess an encoding.
+const encodingSampleSize = 64 * 1024
+
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// cyrillicEncodings are the single-byte encodings guessed for text that is
+// not UTF-8, as found in Russian-language libraries.
+var cyrillicEncodings = []encoding.Encoding{charmap.Windows1251, charmap.KOI8R, charmap.CodePage866}
+
+// codepages maps Windows code page numbers to encodings.
+var codepages = map[int]encoding.Encoding{
+	866: charmap.CodePage866, 874: charmap.Windows874,
+	1250: charmap.Windows1250, 1251: charmap.Windows1251, 1252: charmap.Windows1252, 1253: charmap.Windows1253,
+	1254: charmap.Windows1254, 1255: charmap.Windows1255, 1256: charmap.Windows1256, 1257: charmap.Windows1257,
+	1258: charmap.Windows1258, 10007: charmap.MacintoshCyrillic, 20866: charmap.KOI8R, 65001: encoding.Nop,
+}
+
+// codepageEncoding returns the encoding of a Windows code page, or
+// Windows-1252 for unknown ones.
+func codepageEncoding(cp int) encoding.Encoding {
+	if enc, ok := codepages[cp]; ok {
+		return enc
+	}
+	return charmap.Windows1252
+}
+
+// decodeText converts text of unknown encoding to UTF-8. A byte order mark
+// decides if present; otherwise valid UTF-8 is kept as is, and anything
+// else is decoded with the Cyrillic encoding that yields the most lowercase
+// Cyrillic letters (Windows-1252 if none yields any).
+func decodeText(data []byte) string {
+	switch {
+	case bytes.HasPrefix(data, utf8BOM):
+		return string(data[len(utf8BOM):])
+	case bytes.HasPrefix(data, []byte{0xFF, 0xFE}), bytes.HasPrefix(data, []byte{0xFE, 0xFF}):
+		return decodeWith(unicode.UTF16(unicode.LittleEndian, unicode.ExpectBOM), data)
+	case utf8.Valid(data):
+		return string(data)
+	}
+	return decodeWith(guessEncoding(data), data)
+}
+
+// guessEncoding picks the Cyrillic encoding of non-UTF-8 text. Wrong
+// encodings turn lowercase letters into uppercase ones (KOI8-R) or into
+// pseudo-graphics (CP866), so lowercase letters outweigh uppercase ones only
+// in the right one.
+func guessEncoding(data []byte) encoding.Encoding {
+	sample := data[:min(len(data), encodingSampleSize)]
+	var best encoding.Encoding = charmap.Windows1252
+	bestScore := 0
+	for _, enc := range cyrillicEncodings {
+		score := 0
+		for _, r := range decodeWith(enc, sample) {
+			switch {
+			case r >= 'а' && r <= 'я', r == 'ё':
+				score++
+			case r >= 'А' && r <= 'Я', r == 'Ё':
+				score--
+			}
+		}
+		if score > bestScore {
+			best, bestScore = enc, score
+		}
+	}
+	return best
+}
+
+func decodeWith(enc encoding.Encoding, data []byte) string {
+	out, err := enc.NewDecoder().Bytes(data)
+	if err != nil {
+		return string(data)
+	}
+	return string(out)
+}
@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"html"
 	"net/url"
+	"regexp"
+	"strconv"
 	"strings"
 
 	"github.com/microcosm-cc/bluemonday"
@@ -15,6 +17,11 @@ import (
 // <img> tag, used to adjust page estimation for the first chapter.
 const estimatedCoverImageSize = 2000
 
+// maxChapterSize is the rendered HTML size (bytes) above which a section is
+// split into virtual chapters, so that the reader never loads megabytes of
+// text at once and page estimates stay meaningful.
+const maxChapterSize = 128 * 1024
+
 // imageURLVersion is appended as a query parameter to book image URLs.
 // Increment this value to bust browser caches when URL routing changes
 // (e.g., after fixing nginx static asset rules that incorrectly cached
@@ -27,9 +34,12 @@ const imageURLVersion = "2"
 var htmlPolicy = func() *bluemonday.Policy {
 	p := bluemonday.NewPolicy()
 	p.AllowElements("p", "br", "div", "blockquote", "h2", "h3",
-		"em", "strong", "del", "code", "sup", "sub", "a", "img")
+		"em", "strong", "del", "code", "sup", "sub", "span", "a", "img",
+		"table", "tr", "th", "td")
 	p.AllowAttrs("class", "id").Globally()
-	p.AllowAttrs("href", "data-note-id").OnElements("a")
+	p.AllowAttrs(ParagraphAttr).Matching(bluemonday.Integer).OnElements("p", "h2", "h3", "th", "td")
+	p.AllowAttrs("colspan", "rowspan").Matching(bluemonday.Integer).OnElements("th", "td")
+	p.AllowAttrs("href", "data-note-id", "data-note-url", "data-note-title").OnElements("a")
 	p.AllowAttrs("src", "alt", "loading").OnElements("img")
 	p.RequireParseableURLs(true)
 	p.AllowRelativeURLs(true)
@@ -112,8 +122,7 @@ func (t *fb2Title) Text() string {
 }
 
 type fb2Epigraph struct {
-	Paragraphs []fb2Paragraph `xml:"p"`
-	TextAuthor string         `xml:"text-author"`
+	Content []fb2Element `xml:",any"`
 }
 
 type fb2Paragraph struct {
@@ -157,6 +166,16 @@ type fb2Element struct {
 	Attrs   []xml.Attr `xml:",any,attr"`
 }
 
+// attr returns the value of an attribute of the element, or "".
+func (e *fb2Element) attr(name string) string {
+	for _, a := range e.Attrs {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
+
 type fb2Poem struct {
 	Title      *fb2Title       `xml:"title"`
 	Stanzas    []fb2Stanza     `xml:"stanza"`
@@ -167,9 +186,18 @@ type fb2Stanza struct {
 	Verses []fb2Paragraph `xml:"v"`
 }
 
-type fb2Cite struct {
-	Paragraphs []fb2Paragraph `xml:"p"`
-	TextAuthor string         `xml:"text-author"`
+// fb2Blocks holds the block elements of a cite or an annotation.
+type fb2Blocks struct {
+	Content []fb2Element `xml:",any"`
+}
+
+type fb2Table struct {
+	Rows []fb2TableRow `xml:"tr"`
+}
+
+type fb2TableRow struct {
+	Align string       `xml:"align,attr"`
+	Cells []fb2Element `xml:",any"`
 }
 
 type fb2Image struct {
@@ -182,15 +210,28 @@ type fb2Binary struct {
 	Data        string `xml:",chardata"`
 }
 
+// fb2Chapter is a chapter of the reader: a whole section, or a part of an
+// oversized one (see maxChapterSize). Paragraphs are numbered across the
+// whole section, so a part's numbering starts at firstParagraph.
+type fb2Chapter struct {
+	sec            *fb2Section
+	title          string
+	from, to       int // range of sec.Content rendered in this chapter
+	firstParagraph int
+	size           int // length of the rendered HTML
+}
+
 // FB2Converter implements BookConverter for FB2 format.
 type FB2Converter struct {
 	book    *fb2FictionBook
 	bookID  int64
 	content *BookContent
-	// Pre-parsed chapter data: chapterID -> section
-	chapters map[string]*fb2Section
+	// Pre-parsed chapter data: chapterID -> section or part of a section
+	chapters map[string]*fb2Chapter
 	// Note bodies from <body name="notes">
 	notes map[string]*fb2Section
+	// Element path of every chapter section in the document (see XPointer)
+	sectionPaths map[string]string
 }
 
 func (c *FB2Converter) Parse(data []byte, bookID int64) error {
@@ -200,8 +241,9 @@ func (c *FB2Converter) Parse(data []byte, bookID int64) error {
 		return fmt.Errorf("parse FB2 XML: %w", err)
 	}
 
-	c.chapters = make(map[string]*fb2Section)
+	c.chapters = make(map[string]*fb2Chapter)
 	c.notes = make(map[string]*fb2Section)
+	c.sectionPaths = make(map[string]string)
 
 	// Extract notes from <body name="notes">
 	for i := range c.book.Bodies {
@@ -224,9 +266,13 @@ func (c *FB2Converter) Parse(data []byte, bookID int64) error {
 		if c.book.Bodies[i].Name != "" {
 			continue // skip notes and other named bodies
 		}
-		c.buildTOC(&c.book.Bodies[i].Sections, 0, &toc, &chapterIDs, &chapterCounter)
+		bodyPath := "/FictionBook" + xpathStep("body", i, len(c.book.Bodies))
+		c.buildTOC(&c.book.Bodies[i].Sections, 0, bodyPath, &toc, &chapterIDs, &chapterCounter)
 	}
 
+	// Split oversized sections into virtual chapters
+	toc, chapterIDs, parts := c.splitChapters(toc, chapterIDs)
+
 	// Build metadata
 	ti := c.book.Description.TitleInfo
 	author := ""
@@ -253,13 +299,15 @@ func (c *FB2Converter) Parse(data []byte, bookID int64) error {
 		TOC:           toc,
 		ChapterIDs:    chapterIDs,
 		TotalChapters: len(chapterIDs),
+		Parts:         parts,
+		FormatVersion: FormatVersion,
 	}
 
-	// Pre-compute chapter HTML sizes for page estimation
+	// Chapter HTML sizes for page estimation, measured by splitChapters
 	sizes := make(map[string]int, len(chapterIDs))
 	for _, id := range chapterIDs {
-		if sec, ok := c.chapters[id]; ok {
-			sizes[id] = len(c.convertSection(sec))
+		if ch, ok := c.chapters[id]; ok {
+			sizes[id] = ch.size
 		}
 	}
 	// Add cover image size to first chapter estimate
@@ -271,13 +319,15 @@ func (c *FB2Converter) Parse(data []byte, bookID int64) error {
 	return nil
 }
 
-func (c *FB2Converter) buildTOC(sections *[]fb2Section, level int, toc *[]TOCEntry, ids *[]string, counter *int) {
+func (c *FB2Converter) buildTOC(sections *[]fb2Section, level int, parentPath string, toc *[]TOCEntry, ids *[]string, counter *int) {
 	for i := range *sections {
 		sec := &(*sections)[i]
 		*counter++
 		if sec.ID == "" {
 			sec.ID = fmt.Sprintf("ch%d", *counter)
 		}
+		path := parentPath + xpathStep("section", i, len(*sections))
+		c.sectionPaths[sec.ID] = path
 
 		title := ""
 		if sec.Title != nil {
@@ -293,30 +343,125 @@ func (c *FB2Converter) buildTOC(sections *[]fb2Section, level int, toc *[]TOCEnt
 			Level: level,
 		})
 		*ids = append(*ids, sec.ID)
-		c.chapters[sec.ID] = sec
+		c.chapters[sec.ID] = &fb2Chapter{sec: sec, title: title, to: len(sec.Content)}
 
 		if len(sec.Sections) > 0 {
-			c.buildTOC(&sec.Sections, level+1, toc, ids, counter)
+			c.buildTOC(&sec.Sections, level+1, path, toc, ids, counter)
 		}
 	}
 }
 
+// splitChapters splits the chapters whose HTML exceeds maxChapterSize into
+// parts and records the HTML size of every chapter. The first part keeps the
+// section ID; the following ones get derived IDs and TOC entries one level
+// below the section.
+func (c *FB2Converter) splitChapters(toc []TOCEntry, ids []string) ([]TOCEntry, []string, map[string]ChapterPart) {
+	var parts map[string]ChapterPart
+	newTOC := make([]TOCEntry, 0, len(toc))
+	newIDs := make([]string, 0, len(ids))
+
+	for _, entry := range toc {
+		newTOC = append(newTOC, entry)
+		newIDs = append(newIDs, entry.ID)
+
+		ch := c.chapters[entry.ID]
+		ch.size = len(c.convertChapter(ch, &paragraphCounter{}))
+		if ch.size <= maxChapterSize {
+			continue
+		}
+		split := c.splitSection(ch)
+		if len(split) < 2 {
+			continue
+		}
+		if parts == nil {
+			parts = make(map[string]ChapterPart)
+		}
+
+		c.chapters[entry.ID] = split[0]
+		parts[entry.ID] = ChapterPart{Section: entry.ID}
+		for i, part := range split[1:] {
+			id := fmt.Sprintf("%s-part%d", entry.ID, i+2)
+			for c.chapters[id] != nil {
+				id += "_"
+			}
+			if part.title == "" {
+				part.title = fmt.Sprintf("%s (%d)", entry.Title, i+2)
+			}
+			c.chapters[id] = part
+			parts[id] = ChapterPart{Section: entry.ID, FirstParagraph: part.firstParagraph}
+			newTOC = append(newTOC, TOCEntry{ID: id, Title: part.title, Level: entry.Level + 1})
+			newIDs = append(newIDs, id)
+		}
+	}
+
+	return newTOC, newIDs, parts
+}
+
+// splitSection divides the content of a section into parts of at most
+// maxChapterSize at element boundaries. Once a part is half full, it ends
+// before the next subtitle; a part starting with a subtitle takes its title.
+// The parts are rendered along the way to record their sizes.
+func (c *FB2Converter) splitSection(ch *fb2Chapter) []*fb2Chapter {
+	sec := ch.sec
+	base := c.sectionPaths[sec.ID]
+	paths := contentPaths(sec, base)
+
+	pc := &paragraphCounter{}
+	var part strings.Builder
+	c.convertHeader(&part, sec, pc, base)
+
+	current := &fb2Chapter{sec: sec, title: ch.title}
+	parts := []*fb2Chapter{current}
+	// finish closes the current part, which ends before element i
+	finish := func(i int) {
+		current.to = i
+		c.appendFootnoteBodies(&part, sec)
+		current.size = part.Len()
+		part.Reset()
+	}
+	for i := range sec.Content {
+		elem := &sec.Content[i]
+		first := pc.n
+		var b strings.Builder
+		c.convertElement(&b, elem, pc, paths[i])
+		if b.Len() == 0 {
+			continue
+		}
+
+		isSubtitle := elem.XMLName.Local == "subtitle"
+		if size := part.Len(); size > 0 && (size+b.Len() > maxChapterSize || (isSubtitle && size >= maxChapterSize/2)) {
+			finish(i)
+			current = &fb2Chapter{sec: sec, from: i, firstParagraph: first}
+			if isSubtitle {
+				current.title = fb2Paragraph{Content: elem.Content}.Text()
+			}
+			parts = append(parts, current)
+		}
+		part.WriteString(b.String())
+	}
+	finish(len(sec.Content))
+
+	return parts
+}
+
 func (c *FB2Converter) Content() *BookContent {
 	return c.content
 }
 
 func (c *FB2Converter) Chapter(chapterID string) (*ChapterContent, error) {
-	sec, ok := c.chapters[chapterID]
+	ch, ok := c.chapters[chapterID]
 	if !ok {
 		return nil, fmt.Errorf("chapter %q not found", chapterID)
 	}
 
 	title := ""
-	if sec.Title != nil {
-		title = sec.Title.Text()
+	if ch.from > 0 {
+		title = ch.title
+	} else if ch.sec.Title != nil {
+		title = ch.sec.Title.Text()
 	}
 
-	htmlContent := c.convertSection(sec)
+	htmlContent := c.convertChapter(ch, &paragraphCounter{n: ch.firstParagraph})
 
 	// Prepend cover image to the first chapter
 	if c.content != nil && len(c.content.ChapterIDs) > 0 &&
@@ -330,9 +475,10 @@ func (c *FB2Converter) Chapter(chapterID string) (*ChapterContent, error) {
 	htmlContent = htmlPolicy.Sanitize(htmlContent)
 
 	return &ChapterContent{
-		ID:    chapterID,
-		Title: title,
-		HTML:  htmlContent,
+		ID:            chapterID,
+		Title:         title,
+		HTML:          htmlContent,
+		FormatVersion: FormatVersion,
 	}, nil
 }
 
@@ -353,13 +499,66 @@ func (c *FB2Converter) Image(imageID string) (*ImageData, error) {
 	return nil, fmt.Errorf("image %q not found", imageID)
 }
 
-// convertSection renders a section to HTML.
-func (c *FB2Converter) convertSection(sec *fb2Section) string {
+// Note returns the HTML content of a note from <body name="notes">.
+// Paragraphs are numbered within the note.
+func (c *FB2Converter) Note(noteID string) (*NoteContent, error) {
+	sec, ok := c.notes[noteID]
+	if !ok {
+		return nil, fmt.Errorf("note %q not found", noteID)
+	}
+
+	var b strings.Builder
+	c.convertContent(&b, sec, &paragraphCounter{}, "")
+
+	return &NoteContent{
+		ID:            noteID,
+		Title:         c.noteTitle(noteID),
+		HTML:          htmlPolicy.Sanitize(b.String()),
+		FormatVersion: FormatVersion,
+	}, nil
+}
+
+// noteTitle returns the title of a note (usually its number), or "".
+func (c *FB2Converter) noteTitle(noteID string) string {
+	if sec, ok := c.notes[noteID]; ok {
+		return strings.TrimSpace(sec.Title.Text())
+	}
+	return ""
+}
+
+// convertSection renders a whole section to HTML, numbering its paragraphs
+// with pc.
+func (c *FB2Converter) convertSection(sec *fb2Section, pc *paragraphCounter) string {
+	return c.convertChapter(&fb2Chapter{sec: sec, to: len(sec.Content)}, pc)
+}
+
+// convertChapter renders a section or a part of it to HTML, numbering its
+// paragraphs with pc. Only the first part has the title and epigraphs.
+func (c *FB2Converter) convertChapter(ch *fb2Chapter, pc *paragraphCounter) string {
 	var b strings.Builder
+	sec := ch.sec
+	base := c.sectionPaths[sec.ID]
 
+	if ch.from == 0 {
+		c.convertHeader(&b, sec, pc, base)
+	}
+
+	paths := contentPaths(sec, base)
+	for i := ch.from; i < ch.to; i++ {
+		c.convertElement(&b, &sec.Content[i], pc, paths[i])
+	}
+
+	// Append footnote bodies referenced in this chapter
+	c.appendFootnoteBodies(&b, sec)
+
+	return b.String()
+}
+
+// convertHeader renders the title and epigraphs of a section into b.
+func (c *FB2Converter) convertHeader(b *strings.Builder, sec *fb2Section, pc *paragraphCounter, base string) {
 	// Title
 	if sec.Title != nil {
-		b.WriteString(`<h2 class="chapter-title">`)
+		fmt.Fprintf(b, `<h2 class="chapter-title"%s>`, pc.next(base+"/title"))
 		for _, p := range sec.Title.Paragraphs {
 			b.WriteString(html.EscapeString(p.Text()))
 			b.WriteString(" ")
@@ -368,86 +567,130 @@ func (c *FB2Converter) convertSection(sec *fb2Section) string {
 	}
 
 	// Epigraphs
-	for _, ep := range sec.Epigraphs {
-		b.WriteString(c.convertEpigraph(&ep))
-	}
-
-	// Content elements
-	for _, elem := range sec.Content {
-		switch elem.XMLName.Local {
-		case "p":
-			b.WriteString("<p>")
-			b.WriteString(c.convertInline(elem.Content))
-			b.WriteString("</p>\n")
-		case "poem":
-			b.WriteString(c.convertPoemFromXML(elem.Content))
-		case "cite":
-			b.WriteString(c.convertCiteFromXML(elem.Content))
-		case "subtitle":
-			b.WriteString(`<p class="subtitle">`)
-			b.WriteString(c.convertInline(elem.Content))
-			b.WriteString("</p>\n")
-		case "empty-line":
-			b.WriteString("<br/>\n")
-		case "image":
-			b.WriteString(c.convertImageElem(&elem))
-		case "epigraph":
-			// already handled above via sec.Epigraphs
-		case "section":
-			// nested sections handled via TOC building
-		case "title":
-			// already handled above
+	for i, ep := range sec.Epigraphs {
+		b.WriteString(c.convertEpigraph(&ep, pc, base+xpathStep("epigraph", i, len(sec.Epigraphs))))
+	}
+}
+
+// convertContent renders the content elements of a section (without its
+// title, epigraphs and subsections) into b.
+func (c *FB2Converter) convertContent(b *strings.Builder, sec *fb2Section, pc *paragraphCounter, base string) {
+	paths := contentPaths(sec, base)
+	for i := range sec.Content {
+		c.convertElement(b, &sec.Content[i], pc, paths[i])
+	}
+}
+
+// contentPaths returns the element paths of the content elements of a
+// section.
+func contentPaths(sec *fb2Section, base string) []string {
+	return elementPaths(sec.Content, base)
+}
+
+// elementPaths returns the element paths of sibling elements under base.
+// Element paths index siblings of the same name.
+func elementPaths(elems []fb2Element, base string) []string {
+	counts := make(map[string]int)
+	for _, elem := range elems {
+		counts[elem.XMLName.Local]++
+	}
+	paths := make([]string, len(elems))
+	seen := make(map[string]int)
+	for i, elem := range elems {
+		name := elem.XMLName.Local
+		paths[i] = base + xpathStep(name, seen[name], counts[name])
+		seen[name]++
+	}
+	return paths
+}
+
+// convertElement renders one content element of a section into b.
+func (c *FB2Converter) convertElement(b *strings.Builder, elem *fb2Element, pc *paragraphCounter, path string) {
+	switch elem.XMLName.Local {
+	case "p":
+		fmt.Fprintf(b, "<p%s>", pc.next(path))
+		b.WriteString(c.convertInline(elem.Content))
+		b.WriteString("</p>\n")
+	case "poem":
+		b.WriteString(c.convertPoemFromXML(elem.Content, pc, path))
+	case "cite":
+		b.WriteString(c.convertCiteFromXML(elem.Content, pc, path))
+	case "subtitle":
+		fmt.Fprintf(b, `<p class="subtitle"%s>`, pc.next(path))
+		b.WriteString(c.convertInline(elem.Content))
+		b.WriteString("</p>\n")
+	case "empty-line":
+		b.WriteString("<br/>\n")
+	case "image":
+		b.WriteString(c.convertImageElem(elem))
+	case "table":
+		b.WriteString(c.convertTableFromXML(elem.Content, pc, path))
+	case "text-author":
+		c.convertTextAuthor(b, elem, pc, path, "text-author")
+	case "annotation":
+		b.WriteString(c.convertAnnotationFromXML(elem.Content, pc, path))
+	case "epigraph":
+		// handled by convertHeader via sec.Epigraphs
+	case "section":
+		// nested sections handled via TOC building
+	case "title":
+		// handled by convertHeader
+	}
+}
+
+// convertBlocks renders the block elements of an epigraph, cite or
+// annotation into b. Their text-author lines get the authorClass class.
+func (c *FB2Converter) convertBlocks(b *strings.Builder, elems []fb2Element, pc *paragraphCounter, base, authorClass string) {
+	paths := elementPaths(elems, base)
+	for i := range elems {
+		if elems[i].XMLName.Local == "text-author" {
+			c.convertTextAuthor(b, &elems[i], pc, paths[i], authorClass)
+			continue
 		}
+		c.convertElement(b, &elems[i], pc, paths[i])
 	}
+}
 
-	// Append footnote bodies referenced in this chapter
-	c.appendFootnoteBodies(&b, sec)
-
-	return b.String()
+func (c *FB2Converter) convertTextAuthor(b *strings.Builder, elem *fb2Element, pc *paragraphCounter, path, class string) {
+	fmt.Fprintf(b, `<p class="%s"%s>`, class, pc.next(path))
+	b.WriteString(c.convertInline(elem.Content))
+	b.WriteString("</p>\n")
 }
 
-func (c *FB2Converter) convertEpigraph(ep *fb2Epigraph) string {
+func (c *FB2Converter) convertEpigraph(ep *fb2Epigraph, pc *paragraphCounter, path string) string {
 	var b strings.Builder
 	b.WriteString(`<blockquote class="epigraph">`)
-	for _, p := range ep.Paragraphs {
-		b.WriteString("<p>")
-		b.WriteString(c.convertInline(p.Content))
-		b.WriteString("</p>")
-	}
-	if ep.TextAuthor != "" {
-		b.WriteString(`<p class="epigraph-author">`)
-		b.WriteString(html.EscapeString(ep.TextAuthor))
-		b.WriteString("</p>")
-	}
+	c.convertBlocks(&b, ep.Content, pc, path, "epigraph-author")
 	b.WriteString("</blockquote>\n")
 	return b.String()
 }
 
-func (c *FB2Converter) convertPoemFromXML(innerXML string) string {
+func (c *FB2Converter) convertPoemFromXML(innerXML string, pc *paragraphCounter, path string) string {
 	var poem fb2Poem
 	wrapped := "<poem>" + innerXML + "</poem>"
 	if err := xml.Unmarshal([]byte(wrapped), &poem); err != nil {
-		return "<p>" + html.EscapeString(innerXML) + "</p>"
+		return "<p" + pc.next(path) + ">" + html.EscapeString(innerXML) + "</p>"
 	}
 
 	var b strings.Builder
 	b.WriteString(`<div class="poem">`)
 	if poem.Title != nil {
-		b.WriteString(`<p class="subtitle">`)
+		fmt.Fprintf(&b, `<p class="subtitle"%s>`, pc.next(path+"/title"))
 		b.WriteString(html.EscapeString(poem.Title.Text()))
 		b.WriteString("</p>")
 	}
-	for _, st := range poem.Stanzas {
+	for i, st := range poem.Stanzas {
+		stanzaPath := path + xpathStep("stanza", i, len(poem.Stanzas))
 		b.WriteString(`<div class="stanza">`)
-		for _, v := range st.Verses {
-			b.WriteString(`<p class="verse">`)
+		for j, v := range st.Verses {
+			fmt.Fprintf(&b, `<p class="verse"%s>`, pc.next(stanzaPath+xpathStep("v", j, len(st.Verses))))
 			b.WriteString(c.convertInline(v.Content))
 			b.WriteString("</p>")
 		}
 		b.WriteString("</div>")
 	}
 	if poem.TextAuthor != "" {
-		b.WriteString(`<p class="poem-author">`)
+		fmt.Fprintf(&b, `<p class="poem-author"%s>`, pc.next(path+"/text-author"))
 		b.WriteString(html.EscapeString(poem.TextAuthor))
 		b.WriteString("</p>")
 	}
@@ -455,26 +698,91 @@ func (c *FB2Converter) convertPoemFromXML(innerXML string) string {
 	return b.String()
 }
 
-func (c *FB2Converter) convertCiteFromXML(innerXML string) string {
-	var cite fb2Cite
+func (c *FB2Converter) convertCiteFromXML(innerXML string, pc *paragraphCounter, path string) string {
+	var cite fb2Blocks
 	wrapped := "<cite>" + innerXML + "</cite>"
 	if err := xml.Unmarshal([]byte(wrapped), &cite); err != nil {
-		return "<p>" + html.EscapeString(innerXML) + "</p>"
+		return "<p" + pc.next(path) + ">" + html.EscapeString(innerXML) + "</p>"
 	}
 
 	var b strings.Builder
 	b.WriteString(`<blockquote class="cite">`)
-	for _, p := range cite.Paragraphs {
-		b.WriteString("<p>")
-		b.WriteString(c.convertInline(p.Content))
-		b.WriteString("</p>")
+	c.convertBlocks(&b, cite.Content, pc, path, "epigraph-author")
+	b.WriteString("</blockquote>\n")
+	return b.String()
+}
+
+// convertAnnotationFromXML renders a section annotation, a summary placed
+// after the section title.
+func (c *FB2Converter) convertAnnotationFromXML(innerXML string, pc *paragraphCounter, path string) string {
+	var annotation fb2Blocks
+	wrapped := "<annotation>" + innerXML + "</annotation>"
+	if err := xml.Unmarshal([]byte(wrapped), &annotation); err != nil {
+		return "<p" + pc.next(path) + ">" + html.EscapeString(innerXML) + "</p>"
 	}
-	if cite.TextAuthor != "" {
-		b.WriteString(`<p class="epigraph-author">`)
-		b.WriteString(html.EscapeString(cite.TextAuthor))
-		b.WriteString("</p>")
+
+	var b strings.Builder
+	b.WriteString(`<div class="annotation">`)
+	c.convertBlocks(&b, annotation.Content, pc, path, "text-author")
+	b.WriteString("</div>\n")
+	return b.String()
+}
+
+// convertTableFromXML renders a table. Every cell is a paragraph of its own.
+func (c *FB2Converter) convertTableFromXML(innerXML string, pc *paragraphCounter, path string) string {
+	var table fb2Table
+	wrapped := "<table>" + innerXML + "</table>"
+	if err := xml.Unmarshal([]byte(wrapped), &table); err != nil {
+		return "<p" + pc.next(path) + ">" + html.EscapeString(innerXML) + "</p>"
+	}
+
+	var b strings.Builder
+	b.WriteString(`<table class="fb2-table">`)
+	for i, row := range table.Rows {
+		b.WriteString("<tr>")
+		paths := elementPaths(row.Cells, path+xpathStep("tr", i, len(table.Rows)))
+		for j := range row.Cells {
+			cell := &row.Cells[j]
+			tag := cell.XMLName.Local
+			if tag != "th" && tag != "td" {
+				continue
+			}
+			fmt.Fprintf(&b, "<%s%s%s>", tag, tableCellAttrs(cell, row.Align), pc.next(paths[j]))
+			b.WriteString(c.convertInline(cell.Content))
+			fmt.Fprintf(&b, "</%s>", tag)
+		}
+		b.WriteString("</tr>")
+	}
+	b.WriteString("</table>\n")
+	return b.String()
+}
+
+// tableCellAttrs renders the spans and the alignment of a table cell. A cell
+// without its own horizontal alignment inherits the one of its row.
+func tableCellAttrs(cell *fb2Element, rowAlign string) string {
+	var b strings.Builder
+	for _, name := range []string{"colspan", "rowspan"} {
+		if n, err := strconv.Atoi(cell.attr(name)); err == nil && n > 1 {
+			fmt.Fprintf(&b, ` %s="%d"`, name, n)
+		}
+	}
+
+	var classes []string
+	align := cell.attr("align")
+	if align == "" {
+		align = rowAlign
+	}
+	switch align {
+	case "left", "right", "center":
+		classes = append(classes, "align-"+align)
+	}
+	switch valign := cell.attr("valign"); valign {
+	case "top", "middle", "bottom":
+		classes = append(classes, "valign-"+valign)
+	}
+	if len(classes) > 0 {
+		fmt.Fprintf(&b, ` class="%s"`, strings.Join(classes, " "))
 	}
-	b.WriteString("</blockquote>\n")
 	return b.String()
 }
 
@@ -508,9 +816,13 @@ func (c *FB2Converter) convertInline(content string) string {
 		"</sup>", "</sup>",
 		"<sub>", "<sub>",
 		"</sub>", "</sub>",
+		"</style>", "</span>",
 	)
 	result := r.Replace(content)
 
+	// Convert named styles: <style name="..."> → <span class="style-...">
+	result = convertStyles(result)
+
 	// Convert footnote references: <a ... type="note" ...> → <a class="footnote-ref" data-note-id="...">
 	result = c.convertFootnoteRefs(result)
 
@@ -520,6 +832,56 @@ func (c *FB2Converter) convertInline(content string) string {
 	return result
 }
 
+// styleNameUnsafe matches the characters of an FB2 style name that cannot
+// be used in a class name.
+var styleNameUnsafe = regexp.MustCompile(`[^A-Za-z0-9_-]+`)
+
+// convertStyles converts the opening tags of named FB2 styles to spans. The
+// style name becomes a "style-<name>" class that reader themes may define.
+func convertStyles(content string) string {
+	var result strings.Builder
+	remaining := content
+
+	for {
+		idx := strings.Index(remaining, "<style")
+		if idx == -1 {
+			result.WriteString(remaining)
+			break
+		}
+
+		result.WriteString(remaining[:idx])
+		remaining = remaining[idx:]
+
+		endTag := strings.Index(remaining, ">")
+		if endTag == -1 {
+			result.WriteString(remaining)
+			break
+		}
+
+		tagContent := remaining[:endTag+1]
+		remaining = remaining[endTag+1:]
+
+		// Skip other tags sharing the prefix, e.g. <stylesheet>
+		if next := tagContent[len("<style")]; next != ' ' && next != '>' && next != '/' {
+			result.WriteString(tagContent)
+			continue
+		}
+
+		if strings.HasSuffix(tagContent, "/>") {
+			continue // empty style
+		}
+
+		name := styleNameUnsafe.ReplaceAllString(extractAttrValue(tagContent, "name"), "-")
+		if name == "" {
+			result.WriteString("<span>")
+		} else {
+			fmt.Fprintf(&result, `<span class="style-%s">`, name)
+		}
+	}
+
+	return result.String()
+}
+
 // convertFootnoteRefs converts <a type="note" l:href="#noteID">text</a> to footnote-ref links.
 func (c *FB2Converter) convertFootnoteRefs(content string) string {
 	// Simple parser for <a ...type="note"...> elements
@@ -549,7 +911,12 @@ func (c *FB2Converter) convertFootnoteRefs(content string) string {
 		if strings.Contains(tagContent, `type="note"`) {
 			href := extractAttrValue(tagContent, "href")
 			noteID := strings.TrimPrefix(href, "#")
-			fmt.Fprintf(&result, `<a class="footnote-ref" data-note-id="%s">`, html.EscapeString(noteID))
+			fmt.Fprintf(&result, `<a class="footnote-ref" data-note-id="%s" data-note-url="/api/books/%d/notes/%s"`,
+				html.EscapeString(noteID), c.bookID, html.EscapeString(url.PathEscape(noteID)))
+			if title := c.noteTitle(noteID); title != "" {
+				fmt.Fprintf(&result, ` data-note-title="%s"`, html.EscapeString(title))
+			}
+			result.WriteString(">")
 		} else {
 			result.WriteString(tagContent)
 		}
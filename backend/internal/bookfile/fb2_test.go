@@ -1,6 +1,7 @@
 package bookfile
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
 	"runtime"
@@ -169,7 +170,7 @@ func TestFB2Converter_Chapter_Paragraphs(t *testing.T) {
 	ch, err := conv.Chapter(content.ChapterIDs[0])
 	require.NoError(t, err)
 
-	assert.Contains(t, ch.HTML, "<p>")
+	assert.Contains(t, ch.HTML, `<p data-p="1">`)
 	assert.Contains(t, ch.HTML, "Первый параграф первой главы")
 }
 
@@ -180,7 +181,7 @@ func TestFB2Converter_Chapter_HasTitleTag(t *testing.T) {
 	ch, err := conv.Chapter(content.ChapterIDs[0])
 	require.NoError(t, err)
 
-	assert.Contains(t, ch.HTML, `<h2 class="chapter-title">`)
+	assert.Contains(t, ch.HTML, `<h2 class="chapter-title" data-p="0">`)
 	assert.Contains(t, ch.HTML, "Глава первая")
 }
 
@@ -246,7 +247,7 @@ func TestFB2Converter_Subtitle(t *testing.T) {
 	ch, err := conv.Chapter(content.ChapterIDs[2])
 	require.NoError(t, err)
 
-	assert.Contains(t, ch.HTML, `<p class="subtitle">`)
+	assert.Contains(t, ch.HTML, `<p class="subtitle" data-p="1">`)
 	assert.Contains(t, ch.HTML, "Подзаголовок третьей главы")
 }
 
@@ -274,7 +275,7 @@ func TestFB2Converter_Epigraph(t *testing.T) {
 
 	assert.Contains(t, ch.HTML, `<blockquote class="epigraph">`)
 	assert.Contains(t, ch.HTML, "Быть или не быть")
-	assert.Contains(t, ch.HTML, `<p class="epigraph-author">`)
+	assert.Contains(t, ch.HTML, `<p class="epigraph-author" data-p="2">`)
 	assert.Contains(t, ch.HTML, "У. Шекспир")
 }
 
@@ -290,9 +291,9 @@ func TestFB2Converter_Poem(t *testing.T) {
 
 	assert.Contains(t, ch.HTML, `<div class="poem">`)
 	assert.Contains(t, ch.HTML, `<div class="stanza">`)
-	assert.Contains(t, ch.HTML, `<p class="verse">`)
+	assert.Contains(t, ch.HTML, `<p class="verse" data-p="2">`)
 	assert.Contains(t, ch.HTML, "Мороз и солнце; день чудесный!")
-	assert.Contains(t, ch.HTML, `<p class="poem-author">`)
+	assert.Contains(t, ch.HTML, `<p class="poem-author" data-p="8">`)
 	assert.Contains(t, ch.HTML, "А.С. Пушкин")
 }
 
@@ -333,6 +334,85 @@ func TestFB2Converter_Cite(t *testing.T) {
 	assert.Contains(t, ch.HTML, "Л.Н. Толстой")
 }
 
+// --- Tables, styles, annotations ---
+
+func TestFB2Converter_Table(t *testing.T) {
+	conv := parseTestFB2(t, "elements.fb2", 7)
+
+	ch, err := conv.Chapter("tables")
+	require.NoError(t, err)
+
+	assert.Contains(t, ch.HTML, `<table class="fb2-table"><tr><th colspan="2" class="align-center" data-p="2">Город</th>`)
+	assert.Contains(t, ch.HTML, `<td rowspan="2" class="valign-middle" data-p="4">Россия</td>`)
+	assert.Contains(t, ch.HTML, `<td class="align-right" data-p="8">1,3</td>`, "invalid colspan is dropped")
+	assert.Contains(t, ch.HTML, `<p data-p="9">После таблицы.</p>`)
+
+	// Footnotes referenced from cells are attached to the chapter
+	assert.Contains(t, ch.HTML, `class="footnote-ref" data-note-id="n1"`)
+	assert.Contains(t, ch.HTML, `<div class="footnote-body" id="n1">`)
+
+	// Every cell is a paragraph
+	texts := ParagraphTexts(ch.HTML)
+	require.Len(t, texts, 10)
+	assert.Equal(t, "Москва", texts[5])
+}
+
+func TestFB2Converter_Styles(t *testing.T) {
+	conv := parseTestFB2(t, "elements.fb2", 7)
+
+	ch, err := conv.Chapter("styles")
+	require.NoError(t, err)
+
+	assert.Contains(t, ch.HTML, `<span class="style-small-caps">малые прописные</span>`)
+	assert.Contains(t, ch.HTML, `<span>безымянный</span>`)
+	assert.NotContains(t, ch.HTML, "onclick=")
+	assert.NotContains(t, ch.HTML, "<style")
+}
+
+func TestFB2Converter_TextAuthor(t *testing.T) {
+	conv := parseTestFB2(t, "elements.fb2", 7)
+
+	ch, err := conv.Chapter("styles")
+	require.NoError(t, err)
+	assert.Contains(t, ch.HTML, `<p class="text-author" data-p="2">Автор раздела</p>`)
+
+	// A cite keeps all its authors and nested poems
+	ch, err = conv.Chapter("annotated")
+	require.NoError(t, err)
+	assert.Contains(t, ch.HTML, `<p class="verse" data-p="8">Строка стиха</p>`)
+	assert.Contains(t, ch.HTML, `<p class="epigraph-author" data-p="9">Первый автор</p>`)
+	assert.Contains(t, ch.HTML, `<p class="epigraph-author" data-p="10">Второй автор</p>`)
+}
+
+func TestFB2Converter_SectionAnnotation(t *testing.T) {
+	conv := parseTestFB2(t, "elements.fb2", 7)
+
+	ch, err := conv.Chapter("annotated")
+	require.NoError(t, err)
+
+	assert.Contains(t, ch.HTML, `<p class="epigraph-author" data-p="3">Неизвестный</p>`)
+	assert.Contains(t, ch.HTML, `<div class="annotation"><p data-p="4">Краткое содержание <em>раздела</em>.</p>`)
+	assert.Contains(t, ch.HTML, `<p class="text-author" data-p="5">Составитель</p>`)
+	assert.Less(t, strings.Index(ch.HTML, `class="epigraph"`), strings.Index(ch.HTML, `class="annotation"`))
+	assert.Less(t, strings.Index(ch.HTML, `class="annotation"`), strings.Index(ch.HTML, "Текст раздела."))
+}
+
+func TestConvertStyles(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{`<style name="red">a</style>`, `<span class="style-red">a</style>`},
+		{`<style name="a b">x`, `<span class="style-a-b">x`},
+		{`<style>x`, `<span>x`},
+		{`x<style name="e"/>y`, `xy`},
+		{`<stylesheet>x`, `<stylesheet>x`},
+		{`<style name="x"`, `<style name="x"`},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, convertStyles(tt.in), tt.in)
+	}
+}
+
 // --- Image References ---
 
 func TestFB2Converter_InlineImage(t *testing.T) {
@@ -395,6 +475,39 @@ func TestFB2Converter_FootnoteRef(t *testing.T) {
 	assert.Contains(t, ch.HTML, `data-note-id="note2"`)
 }
 
+func TestFB2Converter_FootnoteRef_PopupAttrs(t *testing.T) {
+	conv := parseTestFB2(t, "complex.fb2", 7)
+	content := conv.Content()
+
+	ch, err := conv.Chapter(content.ChapterIDs[3])
+	require.NoError(t, err)
+
+	assert.Contains(t, ch.HTML, `data-note-url="/api/books/7/notes/note1"`)
+	assert.Contains(t, ch.HTML, `data-note-title="1"`)
+	assert.Contains(t, ch.HTML, `data-note-title="2"`)
+}
+
+func TestFB2Converter_Note(t *testing.T) {
+	conv := parseTestFB2(t, "complex.fb2", 1)
+
+	note, err := conv.Note("note2")
+	require.NoError(t, err)
+
+	assert.Equal(t, "note2", note.ID)
+	assert.Equal(t, "2", note.Title)
+	assert.Equal(t, FormatVersion, note.FormatVersion)
+	assert.Contains(t, note.HTML, "<em>форматированием</em>")
+	assert.NotContains(t, note.HTML, "chapter-title")
+}
+
+func TestFB2Converter_Note_NotFound(t *testing.T) {
+	conv := parseTestFB2(t, "complex.fb2", 1)
+
+	_, err := conv.Note("nonexistent")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "not found")
+}
+
 func TestFB2Converter_FootnoteBody(t *testing.T) {
 	conv := parseTestFB2(t, "complex.fb2", 1)
 	content := conv.Content()
@@ -457,7 +570,7 @@ func TestGetConverter_FB2(t *testing.T) {
 }
 
 func TestGetConverter_Unsupported(t *testing.T) {
-	_, err := GetConverter("doc")
+	_, err := GetConverter("djvu")
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "unsupported format")
 }
@@ -620,3 +733,166 @@ func TestFB2Converter_ConvertInline_AllTags(t *testing.T) {
 	assert.Contains(t, result, "<sup>s</sup>")
 	assert.Contains(t, result, "<sub>b</sub>")
 }
+
+// --- Paragraph locators ---
+
+func TestFB2Converter_ParagraphNumbering(t *testing.T) {
+	conv := parseTestFB2(t, "complex.fb2", 1)
+	content := conv.Content()
+	assert.Equal(t, FormatVersion, content.FormatVersion)
+
+	// The cover prepended to the first chapter is not numbered, so paragraph
+	// indices do not depend on it
+	ch, err := conv.Chapter(content.ChapterIDs[0])
+	require.NoError(t, err)
+	assert.Equal(t, FormatVersion, ch.FormatVersion)
+	assert.NotContains(t, ch.HTML, `book-cover" data-p`)
+	assert.Contains(t, ch.HTML, `<p data-p="1">Быть или не быть`)
+	assert.Contains(t, ch.HTML, `<p data-p="3">Вступительный текст`)
+
+	// Footnote bodies are not part of the reading flow
+	for _, id := range content.ChapterIDs {
+		ch, err := conv.Chapter(id)
+		require.NoError(t, err)
+		if i := strings.Index(ch.HTML, `class="footnote-body"`); i >= 0 {
+			assert.NotContains(t, ch.HTML[i:], ParagraphAttr)
+		}
+	}
+}
+
+func TestFB2Converter_ParagraphLengthsMatchChapter(t *testing.T) {
+	conv := parseTestFB2(t, "complex.fb2", 1)
+	content := conv.Content()
+
+	ch, err := conv.Chapter(content.ChapterIDs[1])
+	require.NoError(t, err)
+	lengths := ParagraphLengths(ch.HTML)
+	require.Len(t, lengths, 10)
+	assert.Equal(t, len([]rune("Зимнее утро")), lengths[1])
+	assert.Equal(t, len([]rune("Мороз и солнце; день чудесный!")), lengths[2])
+}
+
+// --- Oversized sections ---
+
+// longSectionFB2 builds a book whose first section renders far above
+// maxChapterSize, with a subtitle every 200 paragraphs and a footnote
+// reference in paragraph 1500.
+func longSectionFB2() []byte {
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8"?>
+<FictionBook xmlns="http://www.gribuser.ru/xml/fictionbook/2.0" xmlns:l="http://www.w3.org/1999/xlink">
+  <description><title-info><book-title>Длинная книга</book-title><lang>ru</lang></title-info></description>
+  <body>
+    <section id="long">
+      <title><p>Длинная глава</p></title>
+`)
+	for i := 1; i <= 2000; i++ {
+		if i%200 == 0 {
+			fmt.Fprintf(&b, "      <subtitle>Часть %d</subtitle>\n", i/200+1)
+		}
+		if i == 1500 {
+			b.WriteString(`      <p>Абзац со сноской<a l:href="#n1" type="note">1</a>.</p>` + "\n")
+			continue
+		}
+		fmt.Fprintf(&b, "      <p>Абзац %d: %s</p>\n", i, strings.Repeat("текст ", 20))
+	}
+	b.WriteString(`    </section>
+    <section><title><p>Короткая глава</p></title><p>Конец.</p></section>
+  </body>
+  <body name="notes">
+    <section id="n1"><title><p>1</p></title><p>Текст сноски.</p></section>
+  </body>
+</FictionBook>`)
+	return []byte(b.String())
+}
+
+func TestFB2Converter_SplitOversizedSection(t *testing.T) {
+	conv := &FB2Converter{}
+	require.NoError(t, conv.Parse(longSectionFB2(), 1))
+	content := conv.Content()
+
+	require.Greater(t, len(content.ChapterIDs), 3)
+	assert.Equal(t, "long", content.ChapterIDs[0])
+	assert.Equal(t, "long-part2", content.ChapterIDs[1])
+	assert.Equal(t, len(content.ChapterIDs), content.TotalChapters)
+	assert.Len(t, content.TOC, len(content.ChapterIDs))
+	assert.Equal(t, TOCEntry{ID: "long", Title: "Длинная глава", Level: 0}, content.TOC[0])
+	assert.Equal(t, "Часть 3", content.TOC[1].Title)
+	assert.Equal(t, 1, content.TOC[1].Level)
+	assert.Equal(t, "Короткая глава", content.TOC[len(content.TOC)-1].Title)
+
+	next := 0
+	for _, id := range content.ChapterIDs[:len(content.ChapterIDs)-1] {
+		part, ok := content.Parts[id]
+		require.True(t, ok, id)
+		assert.Equal(t, "long", part.Section)
+
+		ch, err := conv.Chapter(id)
+		require.NoError(t, err)
+		assert.LessOrEqual(t, len(ch.HTML), maxChapterSize, id)
+		assert.Equal(t, len(ch.HTML), content.ChapterSizes[id])
+
+		// Paragraph numbering continues across parts
+		assert.Equal(t, next, part.FirstParagraph, id)
+		assert.Contains(t, ch.HTML, fmt.Sprintf(`data-p="%d"`, part.FirstParagraph))
+		next = len(ParagraphLengths(ch.HTML))
+		if id == "long" {
+			assert.Contains(t, ch.HTML, `<h2 class="chapter-title"`)
+		} else {
+			assert.NotContains(t, ch.HTML, `<h2 class="chapter-title"`)
+			assert.Contains(t, ch.HTML, fmt.Sprintf(`<p class="subtitle" data-p="%d">%s</p>`, part.FirstParagraph, ch.Title))
+		}
+	}
+	assert.NotContains(t, content.Parts, content.ChapterIDs[len(content.ChapterIDs)-1])
+}
+
+func TestFB2Converter_SplitOversizedSection_Footnotes(t *testing.T) {
+	conv := &FB2Converter{}
+	require.NoError(t, conv.Parse(longSectionFB2(), 1))
+	content := conv.Content()
+
+	var withRef []string
+	for _, id := range content.ChapterIDs {
+		ch, err := conv.Chapter(id)
+		require.NoError(t, err)
+		if strings.Contains(ch.HTML, `class="footnote-ref" data-note-id="n1"`) {
+			withRef = append(withRef, id)
+			assert.Contains(t, ch.HTML, `<div class="footnote-body" id="n1">`)
+		} else {
+			assert.NotContains(t, ch.HTML, `class="footnote-body"`)
+		}
+	}
+	require.Len(t, withRef, 1)
+	assert.NotEqual(t, "long", withRef[0])
+
+	note, err := conv.Note("n1")
+	require.NoError(t, err)
+	assert.Contains(t, note.HTML, "Текст сноски.")
+}
+
+func TestFB2Converter_SplitOversizedSection_Locators(t *testing.T) {
+	conv := &FB2Converter{}
+	require.NoError(t, conv.Parse(longSectionFB2(), 1))
+	content := conv.Content()
+	last := content.ChapterIDs[len(content.ChapterIDs)-2]
+	first := content.Parts[last].FirstParagraph
+
+	// Positions saved against the section resolve to the part holding them
+	assert.Equal(t, "long", content.ResolveChapter("long", 0))
+	assert.Equal(t, "long", content.ResolveChapter("long", content.Parts["long-part2"].FirstParagraph-1))
+	assert.Equal(t, "long-part2", content.ResolveChapter("long", content.Parts["long-part2"].FirstParagraph))
+	assert.Equal(t, last, content.ResolveChapter("long", first+1))
+	assert.Equal(t, last, content.ResolveChapter("long-part2", first))
+	assert.Equal(t, "other", content.ResolveChapter("other", 5))
+
+	// XPointers address the original section and map back to the part
+	xpm := conv.XPointerMap()
+	xp, err := xpm.XPointer(last, first+1)
+	require.NoError(t, err)
+	assert.Regexp(t, `^/FictionBook/body\[1\]/section\[1\]/p\[\d+\]$`, xp)
+
+	chapter, paragraph, err := xpm.ResolveXPointer(xp + "/text().0")
+	require.NoError(t, err)
+	assert.Equal(t, last, chapter)
+	assert.Equal(t, first+1, paragraph)
+}
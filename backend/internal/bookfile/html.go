@@ -0,0 +1,73 @@
+package bookfile
+
+import (
+	"fmt"
+	"strings"
+
+	xhtml "golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"golang.org/x/net/html/charset"
+)
+
+// HTMLConverter converts HTML documents. The body is split into chapters at
+// its top two heading levels; images are kept if embedded as data URIs.
+type HTMLConverter struct {
+	htmlBook
+	format string
+}
+
+func (c *HTMLConverter) Parse(data []byte, bookID int64) error {
+	doc, err := xhtml.Parse(strings.NewReader(decodeHTML(data)))
+	if err != nil {
+		return fmt.Errorf("parse HTML: %w", err)
+	}
+
+	meta := BookMetadata{Format: c.format}
+	var body *xhtml.Node
+	var walk func(*xhtml.Node)
+	walk = func(n *xhtml.Node) {
+		if n.Type == xhtml.ElementNode {
+			switch n.DataAtom {
+			case atom.Html:
+				meta.Language = nodeAttr(n, "lang")
+			case atom.Title:
+				meta.Title = nodeText(n)
+			case atom.Meta:
+				switch strings.ToLower(nodeAttr(n, "name")) {
+				case "author", "dc.creator":
+					if meta.Author == "" {
+						meta.Author = strings.TrimSpace(nodeAttr(n, "content"))
+					}
+				}
+			case atom.Body:
+				body = n
+				return
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+	if body == nil {
+		return fmt.Errorf("HTML document has no body")
+	}
+
+	return c.build(bookID, meta, body, htmlBookOptions{titleHeading: true})
+}
+
+// decodeHTML converts an HTML document to UTF-8. A byte order mark or a
+// charset declaration decides; otherwise the encoding is guessed as for
+// plain text.
+func decodeHTML(data []byte) string {
+	// Without a BOM or a declaration, DetermineEncoding falls back to
+	// Windows-1252, which is wrong for Russian books; decodeText tries the
+	// Cyrillic encodings first.
+	enc, name, certain := charset.DetermineEncoding(data, "")
+	if !certain && name != "windows-1252" {
+		if out, err := enc.NewDecoder().Bytes(data); err == nil {
+			return string(out)
+		}
+	}
+	return decodeText(data)
+}
@@ -0,0 +1,142 @@
+package bookfile
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/text/encoding/charmap"
+)
+
+var testPNG = base64.StdEncoding.EncodeToString([]byte("\x89PNG\r\n\x1a\n"))
+
+var testHTML = `<!DOCTYPE html>
+<html lang="ru">
+<head>
+  <meta charset="utf-8">
+  <title>Повесть</title>
+  <meta name="author" content="Иван Петров">
+  <style>p { color: red }</style>
+  <script>alert(1)</script>
+</head>
+<body>
+  <div class="book">
+    <h1>Повесть о главном</h1>
+    <p class="epigraph">Эпиграф.</p>
+    <h2 id="one">Первая глава</h2>
+    <p>Текст <b>первой</b> главы.<script>alert(2)</script></p>
+    <p onclick="alert(3)"><img src="data:image/png;base64,` + testPNG + `" alt="схема"> Подпись.</p>
+    <img src="images/missing.png">
+    <img src="data:image/svg+xml;base64,PHN2Zy8+">
+    <h3>Подглава</h3>
+    <ul><li>Пункт</li><li><p>Пункт с абзацем</p></li></ul>
+    Свободный текст
+    <table><tr><td>Ячейка</td><td colspan="2">Широкая</td></tr></table>
+    <h2>Вторая глава</h2>
+    <blockquote><p>Цитата</p></blockquote>
+    <a href="javascript:alert(4)">ссылка</a>
+  </div>
+</body>
+</html>`
+
+func parseTestHTML(t *testing.T, doc string) *HTMLConverter {
+	t.Helper()
+	conv, err := GetConverter("html")
+	require.NoError(t, err)
+	require.NoError(t, conv.Parse([]byte(doc), 9))
+	return conv.(*HTMLConverter)
+}
+
+func TestHTMLConverter_Metadata(t *testing.T) {
+	conv := parseTestHTML(t, testHTML)
+
+	content := conv.Content()
+	assert.Equal(t, BookMetadata{Title: "Повесть", Author: "Иван Петров", Language: "ru", Format: "html"}, content.Metadata)
+	assert.Equal(t, []TOCEntry{
+		{ID: "ch1", Title: "Повесть"},
+		{ID: "ch2", Title: "Первая глава"},
+		{ID: "ch3", Title: "Подглава", Level: 1},
+		{ID: "ch4", Title: "Вторая глава"},
+	}, content.TOC)
+	assert.Equal(t, FormatVersion, content.FormatVersion)
+}
+
+func TestHTMLConverter_TitleHeading(t *testing.T) {
+	conv := parseTestHTML(t, "<body><h1>Заглавие</h1><h2>Один</h2><p>1</p><h2>Два</h2><p>2</p></body>")
+
+	content := conv.Content()
+	assert.Equal(t, "Заглавие", content.Metadata.Title)
+	assert.Equal(t, []TOCEntry{{ID: "ch1", Title: "Один"}, {ID: "ch2", Title: "Два"}}, content.TOC)
+}
+
+func TestHTMLConverter_Chapter(t *testing.T) {
+	conv := parseTestHTML(t, testHTML)
+
+	ch, err := conv.Chapter("ch2")
+	require.NoError(t, err)
+	assert.Equal(t, "Первая глава", ch.Title)
+	assert.Equal(t, []string{
+		"Первая глава",
+		"Текст первой главы.",
+		" Подпись.",
+	}, ParagraphTexts(ch.HTML))
+	assert.Contains(t, ch.HTML, `<h2 class="chapter-title" data-p="0">Первая глава</h2>`)
+	assert.Contains(t, ch.HTML, `<img src="/api/books/9/image/img1.png?v=2" alt="схема"/>`)
+	assert.NotContains(t, ch.HTML, "script")
+	assert.NotContains(t, ch.HTML, "onclick")
+	assert.NotContains(t, ch.HTML, "missing.png")
+	assert.NotContains(t, ch.HTML, "svg")
+	assert.NotContains(t, ch.HTML, `id="one"`)
+
+	sub, err := conv.Chapter("ch3")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Подглава", "Пункт", "Пункт с абзацем", "Свободный текст", "Ячейка", "Широкая"}, ParagraphTexts(sub.HTML))
+	assert.Contains(t, sub.HTML, `<td colspan="2" data-p="5">Широкая</td>`)
+
+	last, err := conv.Chapter("ch4")
+	require.NoError(t, err)
+	assert.NotContains(t, last.HTML, "javascript")
+}
+
+func TestHTMLConverter_Image(t *testing.T) {
+	conv := parseTestHTML(t, testHTML)
+
+	img, err := conv.Image("img1.png")
+	require.NoError(t, err)
+	assert.Equal(t, "image/png", img.ContentType)
+	assert.Equal(t, []byte("\x89PNG\r\n\x1a\n"), img.Data)
+
+	_, err = conv.Image("img2.png")
+	assert.Error(t, err)
+}
+
+func TestHTMLConverter_NoHeadings(t *testing.T) {
+	conv := parseTestHTML(t, "<p>Один абзац.</p><p>Другой.</p>")
+
+	content := conv.Content()
+	assert.Equal(t, []TOCEntry{{ID: "ch1", Title: "Начало"}}, content.TOC)
+}
+
+func TestHTMLConverter_Encoding(t *testing.T) {
+	encode := func(s string, enc *charmap.Charmap) []byte {
+		data, err := enc.NewEncoder().Bytes([]byte(s))
+		require.NoError(t, err)
+		return data
+	}
+
+	declared := encode(`<html><head><meta http-equiv="Content-Type" content="text/html; charset=koi8-r"></head><body><p>Привет, мир</p></body></html>`, charmap.KOI8R)
+	guessed := encode(`<html><body><p>Привет, мир</p></body></html>`, charmap.Windows1251)
+	for _, data := range [][]byte{declared, guessed} {
+		conv := &HTMLConverter{format: "htm"}
+		require.NoError(t, conv.Parse(data, 1))
+		ch, err := conv.Chapter("ch1")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"Привет, мир"}, ParagraphTexts(ch.HTML))
+	}
+}
+
+func TestHTMLConverter_Empty(t *testing.T) {
+	conv := &HTMLConverter{}
+	assert.Error(t, conv.Parse([]byte("<html><body><script>x</script></body></html>"), 1))
+}
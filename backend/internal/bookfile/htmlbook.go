@@ -0,0 +1,600 @@
+package bookfile
+
+import (
+	"cmp"
+	"encoding/base64"
+	"fmt"
+	"html"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/microcosm-cc/bluemonday"
+	xhtml "golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// htmlBookPolicy sanitizes the body of HTML documents before they are split
+// into chapters. Classes and IDs are dropped, so that documents cannot pick
+// up reader styles; images are only kept once extracted into the book.
+var htmlBookPolicy = func() *bluemonday.Policy {
+	p := bluemonday.NewPolicy()
+	p.AllowElements("p", "br", "hr", "div", "blockquote", "pre",
+		"h1", "h2", "h3", "h4", "h5", "h6",
+		"em", "strong", "b", "i", "u", "s", "del", "ins", "code", "kbd", "sup", "sub", "small", "span", "a", "img",
+		"ul", "ol", "li", "dl", "dt", "dd",
+		"table", "thead", "tbody", "tfoot", "caption", "tr", "th", "td",
+		"figure", "figcaption", "section", "article")
+	p.AllowAttrs("colspan", "rowspan").Matching(bluemonday.Integer).OnElements("th", "td")
+	p.AllowAttrs("href").OnElements("a")
+	p.AllowAttrs("src", "alt").OnElements("img")
+	p.RequireParseableURLs(true)
+	p.AllowRelativeURLs(true)
+	p.AllowURLSchemes("http", "https")
+	return p
+}()
+
+// Element classes used when splitting documents into chapters.
+var (
+	// htmlWrapperTags may hold the whole document; they are descended into
+	// when they contain headings.
+	htmlWrapperTags = map[atom.Atom]bool{
+		atom.Div: true, atom.Section: true, atom.Article: true,
+	}
+	// htmlBlockTags hold paragraphs; an element is numbered as a paragraph
+	// when it has text and no block descendants.
+	htmlBlockTags = map[atom.Atom]bool{
+		atom.P: true, atom.Div: true, atom.Blockquote: true, atom.Pre: true,
+		atom.H1: true, atom.H2: true, atom.H3: true, atom.H4: true, atom.H5: true, atom.H6: true,
+		atom.Ul: true, atom.Ol: true, atom.Li: true, atom.Dl: true, atom.Dt: true, atom.Dd: true,
+		atom.Table: true, atom.Thead: true, atom.Tbody: true, atom.Tfoot: true, atom.Caption: true,
+		atom.Tr: true, atom.Th: true, atom.Td: true,
+		atom.Figure: true, atom.Figcaption: true, atom.Section: true, atom.Article: true, atom.Hr: true,
+	}
+)
+
+// htmlBook is a book built from an HTML body split into chapters at its
+// headings. The HTML, Markdown and plain text converters embed it and only
+// differ in how they produce the body.
+type htmlBook struct {
+	bookID   int64
+	chapters map[string]*htmlChapter
+	images   map[string]*ImageData
+	content  *BookContent
+}
+
+type htmlChapter struct {
+	title string
+	html  string
+}
+
+// htmlBookOptions tune how a document is split into chapters.
+type htmlBookOptions struct {
+	// titleHeading takes a lone heading of the top level at the start of
+	// the document as the book title rather than as a chapter.
+	titleHeading bool
+	// image resolves images that are not data URIs, returning nil for
+	// images that are not part of the book.
+	image func(img *xhtml.Node) *ImageData
+}
+
+// htmlSection is a chapter of a document that its own table of contents
+// splits into chapters.
+type htmlSection struct {
+	title string
+	level int
+	body  *xhtml.Node
+}
+
+// rawChapter is a chapter being assembled from top-level blocks.
+type rawChapter struct {
+	id    string
+	title string
+	level int
+	// headed is set if the first block is the heading of the chapter.
+	headed bool
+	blocks []*xhtml.Node
+}
+
+// build splits body into chapters. meta.Title is filled from the title
+// heading if empty.
+func (b *htmlBook) build(bookID int64, meta BookMetadata, body *xhtml.Node, opts htmlBookOptions) error {
+	b.init(bookID)
+	body, err := b.sanitize(body, opts)
+	if err != nil {
+		return err
+	}
+
+	blocks := collectBlocks(body)
+	if !hasContent(blocks) {
+		return fmt.Errorf("document has no text")
+	}
+
+	splitLevels := headingSplitLevels(blocks)
+	if opts.titleHeading && len(splitLevels) == 2 {
+		// A single heading of the top level, before anything else, is the
+		// title of the book rather than a chapter.
+		first := firstContentBlock(blocks)
+		if headingLevel(blocks[first]) == splitLevels[0] && countHeadings(blocks, splitLevels[0]) == 1 {
+			if meta.Title == "" {
+				meta.Title = nodeText(blocks[first])
+			}
+			blocks = append(blocks[:first:first], blocks[first+1:]...)
+			splitLevels = headingSplitLevels(blocks)
+		}
+	}
+
+	b.finish(meta, splitAtHeadings(blocks, splitLevels, meta.Title))
+	return nil
+}
+
+// buildSections makes a chapter of every section that has content or a
+// title. A section is headed by its first block if that is a heading;
+// sections without content get a heading with their title.
+func (b *htmlBook) buildSections(bookID int64, meta BookMetadata, sections []htmlSection, opts htmlBookOptions) error {
+	b.init(bookID)
+	var raw []*rawChapter
+	for _, section := range sections {
+		body, err := b.sanitize(section.body, opts)
+		if err != nil {
+			return err
+		}
+		blocks := collectBlocks(body)
+		if !hasContent(blocks) && section.title == "" {
+			continue
+		}
+		rc := &rawChapter{id: fmt.Sprintf("ch%d", len(raw)+1), title: section.title, level: section.level}
+		if first := firstContentBlock(blocks); len(blocks) > 0 && headingLevel(blocks[first]) > 0 {
+			blocks = blocks[first:]
+			rc.headed = true
+		} else if !hasContent(blocks) {
+			blocks = []*xhtml.Node{{Type: xhtml.ElementNode, Data: "h2", DataAtom: atom.H2}}
+			rc.headed = true
+		}
+		if rc.title == "" {
+			if rc.headed {
+				rc.title = nodeText(blocks[0])
+			}
+			if rc.title == "" {
+				rc.title = cmp.Or(meta.Title, "Начало")
+			}
+		}
+		rc.blocks = blocks
+		raw = append(raw, rc)
+	}
+	if len(raw) == 0 {
+		return fmt.Errorf("document has no text")
+	}
+	b.finish(meta, raw)
+	return nil
+}
+
+func (b *htmlBook) init(bookID int64) {
+	b.bookID = bookID
+	b.chapters = make(map[string]*htmlChapter)
+	b.images = make(map[string]*ImageData)
+}
+
+// sanitize extracts the images of body and returns its sanitized copy.
+func (b *htmlBook) sanitize(body *xhtml.Node, opts htmlBookOptions) (*xhtml.Node, error) {
+	b.extractImages(body, opts)
+	var rendered strings.Builder
+	for c := body.FirstChild; c != nil; c = c.NextSibling {
+		if err := xhtml.Render(&rendered, c); err != nil {
+			return nil, fmt.Errorf("render document: %w", err)
+		}
+	}
+	return parseHTMLBody(htmlBookPolicy.Sanitize(rendered.String()))
+}
+
+// finish renders the chapters and sets the book content.
+func (b *htmlBook) finish(meta BookMetadata, raw []*rawChapter) {
+	var toc []TOCEntry
+	var ids []string
+	var parts map[string]ChapterPart
+	sizes := make(map[string]int)
+	for _, rc := range raw {
+		chParts := b.renderChapter(rc)
+		for i, part := range chParts {
+			id, level := rc.id, rc.level
+			if i > 0 {
+				id, level = fmt.Sprintf("%s-part%d", rc.id, i+1), rc.level+1
+				if part.title == "" {
+					part.title = fmt.Sprintf("%s (%d)", rc.title, i+1)
+				}
+				if parts == nil {
+					parts = make(map[string]ChapterPart)
+				}
+				parts[rc.id] = ChapterPart{Section: rc.id}
+				parts[id] = ChapterPart{Section: rc.id, FirstParagraph: part.firstParagraph}
+			}
+			b.chapters[id] = &htmlChapter{title: part.title, html: part.html}
+			toc = append(toc, TOCEntry{ID: id, Title: part.title, Level: level})
+			ids = append(ids, id)
+			sizes[id] = len(part.html)
+		}
+	}
+
+	b.content = &BookContent{
+		Metadata:      meta,
+		TOC:           toc,
+		ChapterIDs:    ids,
+		TotalChapters: len(ids),
+		ChapterSizes:  sizes,
+		Parts:         parts,
+		FormatVersion: FormatVersion,
+	}
+}
+
+func (b *htmlBook) Content() *BookContent {
+	return b.content
+}
+
+func (b *htmlBook) Chapter(chapterID string) (*ChapterContent, error) {
+	ch, ok := b.chapters[chapterID]
+	if !ok {
+		return nil, fmt.Errorf("chapter %q not found", chapterID)
+	}
+	return &ChapterContent{
+		ID:            chapterID,
+		Title:         ch.title,
+		HTML:          ch.html,
+		FormatVersion: FormatVersion,
+	}, nil
+}
+
+func (b *htmlBook) Image(imageID string) (*ImageData, error) {
+	img, ok := b.images[imageID]
+	if !ok {
+		return nil, fmt.Errorf("image %q not found", imageID)
+	}
+	return img, nil
+}
+
+// extractImages moves images embedded as data URIs, or resolved by
+// opts.image, into the book and points them at the image endpoint. Other
+// images are removed: the files they refer to are not part of the book file.
+func (b *htmlBook) extractImages(n *xhtml.Node, opts htmlBookOptions) {
+	for c := n.FirstChild; c != nil; {
+		next := c.NextSibling
+		if c.Type == xhtml.ElementNode && c.DataAtom == atom.Img {
+			img := decodeDataURI(nodeAttr(c, "src"))
+			if img != nil {
+				img.ID = fmt.Sprintf("img%d%s", len(b.images)+1, dataURIImageTypes[img.ContentType])
+			} else if opts.image != nil {
+				img = opts.image(c)
+			}
+			if img != nil {
+				b.images[img.ID] = img
+				setNodeAttr(c, "src", fmt.Sprintf("/api/books/%d/image/%s?v=%s", b.bookID, img.ID, imageURLVersion))
+			} else {
+				n.RemoveChild(c)
+			}
+		} else {
+			b.extractImages(c, opts)
+		}
+		c = next
+	}
+}
+
+// htmlPart is a rendered chapter or a part of an oversized one.
+type htmlPart struct {
+	title          string
+	html           string
+	firstParagraph int
+}
+
+// renderChapter numbers the paragraphs of a chapter and renders it, split
+// into parts of at most maxChapterSize at block boundaries. As with FB2
+// sections, a part that is half full ends before the next subheading and
+// paragraphs are numbered across parts.
+func (b *htmlBook) renderChapter(rc *rawChapter) []htmlPart {
+	n := 0
+	var sb strings.Builder
+	current := htmlPart{title: rc.title}
+	var parts []htmlPart
+	for i, block := range rc.blocks {
+		first := n
+		var s string
+		if i == 0 && rc.headed {
+			s = fmt.Sprintf("<h2 class=\"chapter-title\" %s=\"%d\">%s</h2>\n", ParagraphAttr, n, html.EscapeString(cmp.Or(nodeText(block), rc.title)))
+			n++
+		} else {
+			if level := headingLevel(block); level > 0 && level < 3 {
+				block.Data, block.DataAtom = "h3", atom.H3
+			}
+			numberParagraphs(block, &n)
+			var rb strings.Builder
+			_ = xhtml.Render(&rb, block)
+			s = rb.String() + "\n"
+		}
+
+		isSubheading := headingLevel(block) > 0 && !(i == 0 && rc.headed)
+		if sb.Len() > 0 && (sb.Len()+len(s) > maxChapterSize || (isSubheading && sb.Len() >= maxChapterSize/2)) {
+			current.html = sb.String()
+			parts = append(parts, current)
+			current = htmlPart{firstParagraph: first}
+			if isSubheading {
+				current.title = nodeText(block)
+			}
+			sb.Reset()
+		}
+		sb.WriteString(s)
+	}
+	current.html = sb.String()
+	return append(parts, current)
+}
+
+// parseHTMLBody parses an HTML fragment into a body element.
+func parseHTMLBody(s string) (*xhtml.Node, error) {
+	body := &xhtml.Node{Type: xhtml.ElementNode, Data: "body", DataAtom: atom.Body}
+	nodes, err := xhtml.ParseFragment(strings.NewReader(s), body)
+	if err != nil {
+		return nil, fmt.Errorf("parse HTML: %w", err)
+	}
+	for _, n := range nodes {
+		body.AppendChild(n)
+	}
+	return body, nil
+}
+
+// collectBlocks returns the top-level blocks of a document, descending into
+// wrappers that contain headings. Runs of loose inline content are wrapped
+// in paragraphs.
+func collectBlocks(n *xhtml.Node) []*xhtml.Node {
+	var blocks, wrapped []*xhtml.Node
+	var inline *xhtml.Node
+	for c := n.FirstChild; c != nil; {
+		next := c.NextSibling
+		switch {
+		case c.Type == xhtml.ElementNode && htmlWrapperTags[c.DataAtom] && containsHeading(c):
+			inline = nil
+			blocks = append(blocks, collectBlocks(c)...)
+		case c.Type == xhtml.ElementNode && htmlBlockTags[c.DataAtom]:
+			inline = nil
+			n.RemoveChild(c)
+			blocks = append(blocks, c)
+		case c.Type == xhtml.TextNode || c.Type == xhtml.ElementNode:
+			if inline == nil {
+				if c.Type == xhtml.TextNode && strings.TrimSpace(c.Data) == "" {
+					break
+				}
+				inline = &xhtml.Node{Type: xhtml.ElementNode, Data: "p", DataAtom: atom.P}
+				blocks = append(blocks, inline)
+				wrapped = append(wrapped, inline)
+			}
+			n.RemoveChild(c)
+			inline.AppendChild(c)
+		}
+		c = next
+	}
+	for _, p := range wrapped {
+		trimText(p.FirstChild, strings.TrimLeftFunc)
+		trimText(p.LastChild, strings.TrimRightFunc)
+	}
+	return blocks
+}
+
+// trimText trims the whitespace of a text node with trim.
+func trimText(n *xhtml.Node, trim func(string, func(rune) bool) string) {
+	if n.Type == xhtml.TextNode {
+		n.Data = trim(n.Data, unicode.IsSpace)
+	}
+}
+
+// headingSplitLevels returns the heading levels that start chapters: the
+// highest level present and the one below it, if any.
+func headingSplitLevels(blocks []*xhtml.Node) []int {
+	var present [7]bool
+	for _, block := range blocks {
+		present[headingLevel(block)] = true
+	}
+	var levels []int
+	for level := 1; level <= 6 && len(levels) < 2; level++ {
+		if present[level] {
+			levels = append(levels, level)
+		}
+	}
+	return levels
+}
+
+// splitAtHeadings groups blocks into chapters starting at headings of the
+// split levels. Content before the first heading forms a chapter of its own.
+func splitAtHeadings(blocks []*xhtml.Node, splitLevels []int, bookTitle string) []*rawChapter {
+	var chapters []*rawChapter
+	var current *rawChapter
+	for _, block := range blocks {
+		level := headingLevel(block)
+		tocLevel := -1
+		for i, l := range splitLevels {
+			if level == l {
+				tocLevel = i
+			}
+		}
+		if tocLevel >= 0 || current == nil {
+			if tocLevel < 0 && !hasContent([]*xhtml.Node{block}) {
+				continue
+			}
+			current = &rawChapter{id: fmt.Sprintf("ch%d", len(chapters)+1), level: max(tocLevel, 0), headed: tocLevel >= 0}
+			switch {
+			case tocLevel >= 0:
+				current.title = nodeText(block)
+			case bookTitle != "":
+				current.title = bookTitle
+			default:
+				current.title = "Начало"
+			}
+			if current.title == "" {
+				current.title = fmt.Sprintf("Глава %d", len(chapters)+1)
+			}
+			chapters = append(chapters, current)
+		}
+		current.blocks = append(current.blocks, block)
+	}
+	return chapters
+}
+
+// numberParagraphs puts the paragraph attribute on every element of n that
+// has text and no block descendants, in document order.
+func numberParagraphs(n *xhtml.Node, counter *int) {
+	if n.Type != xhtml.ElementNode {
+		return
+	}
+	if !hasBlockDescendant(n) {
+		if strings.TrimSpace(nodeText(n)) != "" {
+			setNodeAttr(n, ParagraphAttr, strconv.Itoa(*counter))
+			*counter++
+		}
+		return
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		numberParagraphs(c, counter)
+	}
+}
+
+func hasBlockDescendant(n *xhtml.Node) bool {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == xhtml.ElementNode && (htmlBlockTags[c.DataAtom] || hasBlockDescendant(c)) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsHeading(n *xhtml.Node) bool {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if headingLevel(c) > 0 || containsHeading(c) {
+			return true
+		}
+	}
+	return false
+}
+
+func countHeadings(blocks []*xhtml.Node, level int) int {
+	count := 0
+	for _, block := range blocks {
+		if headingLevel(block) == level {
+			count++
+		}
+	}
+	return count
+}
+
+// firstContentBlock returns the index of the first block with text or
+// images.
+func firstContentBlock(blocks []*xhtml.Node) int {
+	for i, block := range blocks {
+		if hasContent([]*xhtml.Node{block}) {
+			return i
+		}
+	}
+	return 0
+}
+
+func hasContent(blocks []*xhtml.Node) bool {
+	for _, block := range blocks {
+		if strings.TrimSpace(nodeText(block)) != "" || containsImage(block) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsImage(n *xhtml.Node) bool {
+	if n.Type == xhtml.ElementNode && n.DataAtom == atom.Img {
+		return true
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if containsImage(c) {
+			return true
+		}
+	}
+	return false
+}
+
+// headingLevel returns 1-6 for h1-h6 elements and 0 otherwise.
+func headingLevel(n *xhtml.Node) int {
+	if n.Type != xhtml.ElementNode {
+		return 0
+	}
+	switch n.DataAtom {
+	case atom.H1:
+		return 1
+	case atom.H2:
+		return 2
+	case atom.H3:
+		return 3
+	case atom.H4:
+		return 4
+	case atom.H5:
+		return 5
+	case atom.H6:
+		return 6
+	}
+	return 0
+}
+
+// nodeText returns the text of n with whitespace runs collapsed.
+func nodeText(n *xhtml.Node) string {
+	var sb strings.Builder
+	var walk func(*xhtml.Node)
+	walk = func(n *xhtml.Node) {
+		if n.Type == xhtml.TextNode {
+			sb.WriteString(n.Data)
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+	return strings.Join(strings.Fields(sb.String()), " ")
+}
+
+func nodeAttr(n *xhtml.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+func setNodeAttr(n *xhtml.Node, key, val string) {
+	for i := range n.Attr {
+		if n.Attr[i].Key == key {
+			n.Attr[i].Val = val
+			return
+		}
+	}
+	n.Attr = append(n.Attr, xhtml.Attribute{Key: key, Val: val})
+}
+
+// dataURIImageTypes are the image types taken from data URIs. SVG is left
+// out, as it may carry scripts.
+var dataURIImageTypes = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+	"image/webp": ".webp",
+}
+
+// decodeDataURI decodes a base64 data URI of a raster image, or returns nil.
+func decodeDataURI(uri string) *ImageData {
+	rest, ok := strings.CutPrefix(uri, "data:")
+	if !ok {
+		return nil
+	}
+	header, payload, ok := strings.Cut(rest, ",")
+	if !ok {
+		return nil
+	}
+	contentType, isBase64 := strings.CutSuffix(header, ";base64")
+	if _, ok := dataURIImageTypes[contentType]; !ok || !isBase64 {
+		return nil
+	}
+	data, err := base64.StdEncoding.DecodeString(strings.TrimSpace(payload))
+	if err != nil {
+		return nil
+	}
+	return &ImageData{ContentType: contentType, Data: data}
+}
@@ -0,0 +1,158 @@
+package bookfile
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif" // register the GIF decoder
+	"image/jpeg"
+	"image/png"
+	"slices"
+	"strings"
+
+	"github.com/HugoSmits86/nativewebp"
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp" // register the WebP decoder
+)
+
+// Image transcoding limits.
+const (
+	// MaxImageWidth is the largest width an image can be scaled to.
+	MaxImageWidth = 4096
+	// maxImagePixels guards against decompression bombs (50 megapixels).
+	maxImagePixels = 50_000_000
+	jpegQuality    = 85
+)
+
+// Output formats of TranscodeImage.
+const (
+	ImageFormatJPEG = "jpeg"
+	ImageFormatWebP = "webp"
+)
+
+// ImageWidths lists the widths images can be scaled to, in ascending order.
+// A small fixed set bounds the number of cached variants per image.
+var ImageWidths = []int{400, 600, 800, 1200, 1600, 2400, 3200, MaxImageWidth}
+
+// ErrImageNotTranscodable is returned for images that cannot be decoded,
+// e.g. SVG or oversized ones.
+var ErrImageNotTranscodable = errors.New("image cannot be transcoded")
+
+// ImageOptions describes a transcoded variant of an image. The zero value
+// means the original image.
+type ImageOptions struct {
+	// Width is the maximum width in pixels; 0 keeps the original width.
+	// Images are never upscaled.
+	Width int
+	// Format is ImageFormatJPEG, ImageFormatWebP or "" to keep JPEG images
+	// as JPEG and encode everything else as PNG.
+	Format string
+	// Grayscale converts the image to shades of gray for e-ink screens.
+	Grayscale bool
+}
+
+// Normalize rounds the width up to the next of ImageWidths.
+func (o ImageOptions) Normalize() ImageOptions {
+	if o.Width > 0 {
+		i, _ := slices.BinarySearch(ImageWidths, o.Width)
+		o.Width = ImageWidths[min(i, len(ImageWidths)-1)]
+	}
+	return o
+}
+
+// IsZero reports whether the options request the original image.
+func (o ImageOptions) IsZero() bool {
+	return o == ImageOptions{}
+}
+
+// Key identifies the variant in cache file names, e.g. "w600-webp-gray".
+func (o ImageOptions) Key() string {
+	var parts []string
+	if o.Width > 0 {
+		parts = append(parts, fmt.Sprintf("w%d", o.Width))
+	}
+	if o.Format != "" {
+		parts = append(parts, o.Format)
+	}
+	if o.Grayscale {
+		parts = append(parts, "gray")
+	}
+	return strings.Join(parts, "-")
+}
+
+// TranscodeImage scales, converts to grayscale and re-encodes an image.
+// GIF animations are reduced to their first frame.
+func TranscodeImage(img *ImageData, opts ImageOptions) (*ImageData, error) {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(img.Data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrImageNotTranscodable, err)
+	}
+	if cfg.Width*cfg.Height > maxImagePixels {
+		return nil, fmt.Errorf("%w: %dx%d pixels", ErrImageNotTranscodable, cfg.Width, cfg.Height)
+	}
+	src, srcFormat, err := image.Decode(bytes.NewReader(img.Data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrImageNotTranscodable, err)
+	}
+
+	format := opts.Format
+	if format == "" {
+		format = "png"
+		if srcFormat == "jpeg" {
+			format = ImageFormatJPEG
+		}
+	}
+
+	dst := scaleImage(src, opts.Width, format == ImageFormatJPEG || opts.Grayscale)
+	if opts.Grayscale {
+		gray := image.NewGray(dst.Bounds())
+		draw.Draw(gray, gray.Bounds(), dst, dst.Bounds().Min, draw.Src)
+		dst = gray
+	}
+
+	var buf bytes.Buffer
+	switch format {
+	case ImageFormatJPEG:
+		err = jpeg.Encode(&buf, dst, &jpeg.Options{Quality: jpegQuality})
+	case ImageFormatWebP:
+		err = nativewebp.Encode(&buf, dst, nil)
+	default:
+		err = png.Encode(&buf, dst)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("encode %s image: %w", format, err)
+	}
+
+	return &ImageData{
+		ID:          img.ID,
+		ContentType: "image/" + format,
+		Data:        buf.Bytes(),
+	}, nil
+}
+
+// scaleImage scales src down to width (0 keeps the size). Transparent areas
+// are filled with white if the target format has no alpha channel.
+func scaleImage(src image.Image, width int, opaque bool) image.Image {
+	b := src.Bounds()
+	scale := width > 0 && width < b.Dx()
+	if !scale && !opaque {
+		return src
+	}
+
+	w, h := b.Dx(), b.Dy()
+	if scale {
+		w, h = width, max(h*width/w, 1)
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	if opaque {
+		draw.Draw(dst, dst.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	}
+	if scale {
+		draw.CatmullRom.Scale(dst, dst.Bounds(), src, b, draw.Over, nil)
+	} else {
+		draw.Draw(dst, dst.Bounds(), src, b.Min, draw.Over)
+	}
+	return dst
+}
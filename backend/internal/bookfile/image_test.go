@@ -0,0 +1,118 @@
+package bookfile
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/image/webp"
+)
+
+// testImage encodes a w×h image, red on the left half and transparent on
+// the right half.
+func testImage(t *testing.T, w, h int, contentType string) *ImageData {
+	t.Helper()
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := range h {
+		for x := range w / 2 {
+			img.Set(x, y, color.NRGBA{R: 255, A: 255})
+		}
+	}
+
+	var buf bytes.Buffer
+	switch contentType {
+	case "image/gif":
+		require.NoError(t, gif.Encode(&buf, img, nil))
+	default:
+		require.NoError(t, png.Encode(&buf, img))
+	}
+	return &ImageData{ID: "img.png", ContentType: contentType, Data: buf.Bytes()}
+}
+
+func TestTranscodeImage_Resize(t *testing.T) {
+	out, err := TranscodeImage(testImage(t, 800, 400, "image/png"), ImageOptions{Width: 300})
+	require.NoError(t, err)
+	assert.Equal(t, "img.png", out.ID)
+	assert.Equal(t, "image/png", out.ContentType)
+
+	img, err := png.Decode(bytes.NewReader(out.Data))
+	require.NoError(t, err)
+	assert.Equal(t, image.Rect(0, 0, 300, 150), img.Bounds())
+	_, _, _, a := img.At(299, 0).RGBA()
+	assert.Zero(t, a, "transparency is kept")
+}
+
+func TestTranscodeImage_NoUpscale(t *testing.T) {
+	out, err := TranscodeImage(testImage(t, 200, 100, "image/png"), ImageOptions{Width: 1000})
+	require.NoError(t, err)
+
+	cfg, err := png.DecodeConfig(bytes.NewReader(out.Data))
+	require.NoError(t, err)
+	assert.Equal(t, 200, cfg.Width)
+}
+
+func TestTranscodeImage_JPEG(t *testing.T) {
+	out, err := TranscodeImage(testImage(t, 100, 50, "image/png"), ImageOptions{Format: ImageFormatJPEG})
+	require.NoError(t, err)
+	assert.Equal(t, "image/jpeg", out.ContentType)
+
+	img, err := jpeg.Decode(bytes.NewReader(out.Data))
+	require.NoError(t, err)
+	r, g, b, _ := img.At(90, 25).RGBA()
+	assert.Greater(t, r>>8, uint32(240), "transparency becomes white")
+	assert.Greater(t, g>>8, uint32(240))
+	assert.Greater(t, b>>8, uint32(240))
+}
+
+func TestTranscodeImage_GIFToPNG(t *testing.T) {
+	out, err := TranscodeImage(testImage(t, 100, 50, "image/gif"), ImageOptions{Width: 50})
+	require.NoError(t, err)
+	assert.Equal(t, "image/png", out.ContentType)
+
+	cfg, err := png.DecodeConfig(bytes.NewReader(out.Data))
+	require.NoError(t, err)
+	assert.Equal(t, 50, cfg.Width)
+	assert.Equal(t, 25, cfg.Height)
+}
+
+func TestTranscodeImage_WebPGrayscale(t *testing.T) {
+	out, err := TranscodeImage(testImage(t, 100, 50, "image/png"), ImageOptions{Format: ImageFormatWebP, Grayscale: true})
+	require.NoError(t, err)
+	assert.Equal(t, "image/webp", out.ContentType)
+
+	img, err := webp.Decode(bytes.NewReader(out.Data))
+	require.NoError(t, err)
+	assert.Equal(t, image.Rect(0, 0, 100, 50), img.Bounds())
+	r, g, b, a := img.At(10, 10).RGBA()
+	assert.Equal(t, r, g)
+	assert.Equal(t, g, b)
+	assert.Less(t, r>>8, uint32(128), "red becomes dark gray")
+	assert.Equal(t, uint32(0xffff), a)
+	r, _, _, _ = img.At(90, 10).RGBA()
+	assert.Equal(t, uint32(0xffff), r, "transparency becomes white")
+}
+
+func TestTranscodeImage_NotTranscodable(t *testing.T) {
+	svg := &ImageData{ID: "a.svg", ContentType: "image/svg+xml", Data: []byte(`<svg xmlns="http://www.w3.org/2000/svg"/>`)}
+	_, err := TranscodeImage(svg, ImageOptions{Width: 100})
+	assert.ErrorIs(t, err, ErrImageNotTranscodable)
+}
+
+func TestImageOptions(t *testing.T) {
+	assert.True(t, ImageOptions{}.IsZero())
+	assert.Equal(t, "", ImageOptions{}.Key())
+
+	opts := ImageOptions{Width: 601, Format: ImageFormatWebP, Grayscale: true}.Normalize()
+	assert.Equal(t, 800, opts.Width)
+	assert.Equal(t, "w800-webp-gray", opts.Key())
+	assert.Equal(t, 400, ImageOptions{Width: 1}.Normalize().Width)
+	assert.Equal(t, 1200, ImageOptions{Width: 1200}.Normalize().Width)
+	assert.Equal(t, MaxImageWidth, ImageOptions{Width: 5000}.Normalize().Width)
+	assert.Equal(t, "jpeg", ImageOptions{Format: ImageFormatJPEG}.Key())
+}
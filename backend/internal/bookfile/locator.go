@@ -0,0 +1,148 @@
+package bookfile
+
+import (
+	"strconv"
+	"strings"
+	"unicode/utf16"
+
+	"golang.org/x/net/html"
+)
+
+// FormatVersion identifies the HTML produced by converters. Increment it
+// when the output changes incompatibly (e.g. paragraph numbering), so that
+// cached chapters are regenerated.
+const FormatVersion = 5
+
+// ParagraphAttr is the attribute converters put on every text block of a
+// chapter. Its value is the block's index within the chapter, counted in
+// source order, so it does not depend on how the block is rendered. Reading
+// positions refer to a paragraph index plus a character offset in it.
+const ParagraphAttr = "data-p"
+
+// paragraphCounter numbers the text blocks of one chapter and records where
+// each block comes from in the source document.
+type paragraphCounter struct {
+	n     int
+	paths []string
+}
+
+// next returns the paragraph attribute for the next block. path is the
+// block's element path in the source document (see XPointerMapper).
+func (pc *paragraphCounter) next(path string) string {
+	s := ` ` + ParagraphAttr + `="` + strconv.Itoa(pc.n) + `"`
+	pc.n++
+	pc.paths = append(pc.paths, path)
+	return s
+}
+
+// ParagraphLengths returns the text length of every numbered paragraph in
+// chapter HTML, indexed by paragraph. Lengths are in UTF-16 code units, like
+// DOM text offsets in the browser.
+func ParagraphLengths(chapterHTML string) []int {
+	texts := ParagraphTexts(chapterHTML)
+	if texts == nil {
+		return nil
+	}
+	lengths := make([]int, len(texts))
+	for i, t := range texts {
+		lengths[i] = utf16Len(t)
+	}
+	return lengths
+}
+
+// ParagraphTexts returns the plain text of every numbered paragraph in
+// chapter HTML, indexed by paragraph.
+func ParagraphTexts(chapterHTML string) []string {
+	var texts []string
+	z := html.NewTokenizer(strings.NewReader(chapterHTML))
+	current, depth := -1, 0
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			return texts
+		case html.StartTagToken:
+			name, hasAttr := z.TagName()
+			if current >= 0 {
+				if !voidElements[string(name)] {
+					depth++
+				}
+				continue
+			}
+			if !hasAttr {
+				continue
+			}
+			if idx, ok := paragraphIndex(z); ok {
+				current, depth = idx, 1
+				for len(texts) <= idx {
+					texts = append(texts, "")
+				}
+			}
+		case html.EndTagToken:
+			if current >= 0 {
+				depth--
+				if depth == 0 {
+					current = -1
+				}
+			}
+		case html.TextToken:
+			if current >= 0 {
+				texts[current] += string(z.Text())
+			}
+		}
+	}
+}
+
+// voidElements never have an end tag.
+var voidElements = map[string]bool{"br": true, "img": true, "hr": true, "wbr": true}
+
+// paragraphIndex reads the paragraph attribute of the current start tag.
+func paragraphIndex(z *html.Tokenizer) (int, bool) {
+	for {
+		key, val, more := z.TagAttr()
+		if string(key) == ParagraphAttr {
+			idx, err := strconv.Atoi(string(val))
+			return idx, err == nil && idx >= 0
+		}
+		if !more {
+			return 0, false
+		}
+	}
+}
+
+func utf16Len(s string) int {
+	n := 0
+	for _, r := range s {
+		n += utf16.RuneLen(r)
+	}
+	return n
+}
+
+// LocateFraction converts a fraction (0..1) of a chapter's text into a
+// paragraph index and an offset within it. Used to migrate percentage-based
+// positions.
+func LocateFraction(lengths []int, fraction float64) (paragraph, offset int) {
+	total := 0
+	for _, l := range lengths {
+		total += l
+	}
+	fraction = min(max(fraction, 0), 1)
+	return LocateOffset(lengths, int(fraction*float64(total)))
+}
+
+// LocateOffset converts an offset from the start of the chapter text into a
+// paragraph index and an offset within it. Offsets past the end map to the
+// end of the last paragraph.
+func LocateOffset(lengths []int, chapterOffset int) (paragraph, offset int) {
+	if len(lengths) == 0 {
+		return 0, 0
+	}
+	rest := max(chapterOffset, 0)
+	for i, l := range lengths {
+		if rest < l {
+			return i, rest
+		}
+		rest -= l
+	}
+	last := len(lengths) - 1
+	return last, lengths[last]
+}
@@ -0,0 +1,68 @@
+package bookfile
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParagraphLengths(t *testing.T) {
+	html := `<div class="book-cover"><img src="/x" alt="Обложка"/></div>` +
+		`<h2 class="chapter-title" data-p="0">Глава </h2>` +
+		`<p data-p="1">Текст с <em>курсивом</em> и&nbsp;&amp;</p>` +
+		`<div class="poem"><p class="verse" data-p="2">A<br>B</p></div>` +
+		`<p data-p="3">😀x</p>` +
+		`<div class="footnote-body" id="n1"><p>Сноска</p></div>`
+
+	assert.Equal(t, []int{6, 20, 2, 3}, ParagraphLengths(html))
+	assert.Nil(t, ParagraphLengths("<p>без номеров</p>"))
+}
+
+func TestParagraphTexts(t *testing.T) {
+	html := `<h2 class="chapter-title" data-p="0">Глава </h2>` +
+		`<p data-p="1">Текст с <em>курсивом</em> и&nbsp;&amp;</p>` +
+		`<div class="footnote-body" id="n1"><p>Сноска</p></div>`
+
+	assert.Equal(t, []string{"Глава ", "Текст с курсивом и\u00a0&"}, ParagraphTexts(html))
+}
+
+func TestLocateOffset(t *testing.T) {
+	lengths := []int{6, 10, 0, 4}
+	tests := []struct {
+		offset    int
+		paragraph int
+		inside    int
+	}{
+		{0, 0, 0},
+		{5, 0, 5},
+		{6, 1, 0},
+		{15, 1, 9},
+		{16, 3, 0}, // empty paragraph is skipped
+		{19, 3, 3},
+		{20, 3, 4},
+		{100, 3, 4},
+		{-3, 0, 0},
+	}
+	for _, tt := range tests {
+		p, o := LocateOffset(lengths, tt.offset)
+		assert.Equal(t, tt.paragraph, p, "offset %d", tt.offset)
+		assert.Equal(t, tt.inside, o, "offset %d", tt.offset)
+	}
+
+	p, o := LocateOffset(nil, 10)
+	assert.Equal(t, 0, p)
+	assert.Equal(t, 0, o)
+}
+
+func TestLocateFraction(t *testing.T) {
+	lengths := []int{10, 30, 60}
+
+	p, o := LocateFraction(lengths, 0)
+	assert.Equal(t, [2]int{0, 0}, [2]int{p, o})
+	p, o = LocateFraction(lengths, 0.25)
+	assert.Equal(t, [2]int{1, 15}, [2]int{p, o})
+	p, o = LocateFraction(lengths, 0.5)
+	assert.Equal(t, [2]int{2, 10}, [2]int{p, o})
+	p, o = LocateFraction(lengths, 1.5)
+	assert.Equal(t, [2]int{2, 60}, [2]int{p, o})
+}
@@ -0,0 +1,72 @@
+package bookfile
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/renderer/html"
+)
+
+// markdownRenderer renders Markdown with GitHub extensions. Raw HTML is
+// passed through, as the output is sanitized like HTML documents.
+var markdownRenderer = goldmark.New(
+	goldmark.WithExtensions(extension.GFM, extension.Footnote),
+	goldmark.WithRendererOptions(html.WithUnsafe()),
+)
+
+// MarkdownConverter converts Markdown documents. A leading front matter
+// block supplies the metadata (title, author, lang); otherwise a lone
+// top-level heading is the title. Chapters start at the top two heading
+// levels.
+type MarkdownConverter struct {
+	htmlBook
+}
+
+func (c *MarkdownConverter) Parse(data []byte, bookID int64) error {
+	text := decodeText(data)
+	meta := BookMetadata{Format: "md"}
+	text = parseFrontMatter(text, &meta)
+
+	var rendered bytes.Buffer
+	if err := markdownRenderer.Convert([]byte(text), &rendered); err != nil {
+		return fmt.Errorf("render Markdown: %w", err)
+	}
+	body, err := parseHTMLBody(rendered.String())
+	if err != nil {
+		return err
+	}
+	return c.build(bookID, meta, body, htmlBookOptions{titleHeading: true})
+}
+
+// parseFrontMatter reads the "key: value" lines of a YAML front matter
+// block at the start of text into meta and returns the rest of the text.
+func parseFrontMatter(text string, meta *BookMetadata) string {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	rest, ok := strings.CutPrefix(text, "---\n")
+	if !ok {
+		return text
+	}
+	header, body, ok := strings.Cut(rest, "\n---\n")
+	if !ok {
+		return text
+	}
+	for _, line := range strings.Split(header, "\n") {
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		value = strings.Trim(strings.TrimSpace(value), `"'`)
+		switch strings.ToLower(strings.TrimSpace(key)) {
+		case "title":
+			meta.Title = value
+		case "author":
+			meta.Author = value
+		case "lang", "language":
+			meta.Language = value
+		}
+	}
+	return body
+}
@@ -0,0 +1,81 @@
+package bookfile
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testMarkdown = `---
+title: "Заметки путешественника"
+author: Анна Смирнова
+lang: ru
+---
+
+# Заметки
+
+Вступительное слово.
+
+## День первый
+
+Мы вышли **рано утром**.
+
+![карта](data:image/png;base64,` + "iVBORw0KGgo=" + `)
+
+| Город | Км |
+|-------|----|
+| Тверь | 180 |
+
+<script>alert(1)</script>
+
+## День второй
+
+- Дождь
+- Туман
+`
+
+func TestMarkdownConverter(t *testing.T) {
+	conv, err := GetConverter("md")
+	require.NoError(t, err)
+	require.NoError(t, conv.Parse([]byte(testMarkdown), 4))
+
+	content := conv.Content()
+	assert.Equal(t, BookMetadata{Title: "Заметки путешественника", Author: "Анна Смирнова", Language: "ru", Format: "md"}, content.Metadata)
+	assert.Equal(t, []TOCEntry{
+		{ID: "ch1", Title: "Заметки путешественника"},
+		{ID: "ch2", Title: "День первый"},
+		{ID: "ch3", Title: "День второй"},
+	}, content.TOC)
+
+	ch, err := conv.Chapter("ch2")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"День первый", "Мы вышли рано утром.", "Город", "Км", "Тверь", "180"}, ParagraphTexts(ch.HTML))
+	assert.Contains(t, ch.HTML, "<strong>рано утром</strong>")
+	assert.Contains(t, ch.HTML, `<img src="/api/books/4/image/img1.png?v=2" alt="карта"/>`)
+	assert.NotContains(t, ch.HTML, "script")
+
+	img, err := conv.Image("img1.png")
+	require.NoError(t, err)
+	assert.Equal(t, "image/png", img.ContentType)
+}
+
+func TestMarkdownConverter_TitleHeading(t *testing.T) {
+	conv := &MarkdownConverter{}
+	require.NoError(t, conv.Parse([]byte("# Книга\n\n## Первая\n\nТекст.\n\n## Вторая\n\nЕщё.\n"), 1))
+
+	content := conv.Content()
+	assert.Equal(t, "Книга", content.Metadata.Title)
+	assert.Equal(t, []string{"ch1", "ch2"}, content.ChapterIDs)
+}
+
+func TestParseFrontMatter(t *testing.T) {
+	var meta BookMetadata
+	rest := parseFrontMatter("---\r\ntitle: 'Книга'\r\nauthor: Автор\r\n---\r\n# Текст\r\n", &meta)
+	assert.Equal(t, "# Текст\n", rest)
+	assert.Equal(t, BookMetadata{Title: "Книга", Author: "Автор"}, meta)
+
+	meta = BookMetadata{}
+	assert.Equal(t, "---\nне закрыт\n", parseFrontMatter("---\nне закрыт\n", &meta))
+	assert.Equal(t, BookMetadata{}, meta)
+}
@@ -0,0 +1,505 @@
+package bookfile
+
+import (
+	"bytes"
+	"cmp"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	xhtml "golang.org/x/net/html"
+	"golang.org/x/text/encoding/charmap"
+)
+
+// EXTH record types read from MOBI headers.
+const (
+	exthAuthor      = 100
+	exthKF8Boundary = 121
+	exthCoverOffset = 201
+	exthTitle       = 503
+	exthLanguage    = 524
+)
+
+// mobiHeader holds the fields of record 0 of a MOBI book (the PalmDOC and
+// MOBI headers and the EXTH metadata) used for conversion. Record indexes
+// are absolute.
+type mobiHeader struct {
+	// start is the index of record 0 of the book.
+	start         int
+	compression   int
+	textLength    int
+	textRecords   int
+	encrypted     bool
+	utf8          bool
+	version       int
+	fullName      []byte
+	firstResource int
+	huffRecord    int
+	huffCount     int
+	extraFlags    int
+	ncxIndex      int
+	// KF8 only.
+	fdstIndex int
+	skelIndex int
+	fragIndex int
+	exth      map[int][][]byte
+}
+
+// MOBIConverter converts MOBI and AZW3 books without DRM. The text of KF8
+// books (AZW3, or the KF8 part of combined MOBI files) is assembled from
+// its skeleton and fragment indexes; older MOBI books are a single HTML
+// document. Chapters start at the positions of the NCX entries; books
+// without an NCX are split at their headings.
+type MOBIConverter struct {
+	htmlBook
+	format  string
+	records [][]byte
+	header  *mobiHeader
+}
+
+// mobiTarget is the position of an NCX entry in a part of the text.
+type mobiTarget struct {
+	part, offset int
+	title        string
+	level        int
+}
+
+func (c *MOBIConverter) Parse(data []byte, bookID int64) error {
+	if len(data) < 78 || string(data[60:68]) != "BOOKMOBI" {
+		return fmt.Errorf("not a MOBI book")
+	}
+	records, err := pdbRecords(data)
+	if err != nil {
+		return err
+	}
+	c.records = records
+	h, err := parseMobiHeader(records, 0)
+	if err != nil {
+		return err
+	}
+	// Combined files carry a KF8 version of the book after the old one.
+	if b, ok := h.exth[exthKF8Boundary]; ok && len(b[0]) == 4 {
+		if kf8, err := parseMobiHeader(records, be32(b[0], 0)); err == nil && kf8.version == 8 {
+			h = kf8
+		}
+	}
+	if h.encrypted {
+		return fmt.Errorf("book is DRM-protected")
+	}
+	c.header = h
+
+	text, err := c.text()
+	if err != nil {
+		return err
+	}
+	var parts [][]byte
+	var targets []mobiTarget
+	if h.version == 8 {
+		parts, targets, err = c.kf8Parts(text)
+	} else {
+		parts = [][]byte{text}
+		targets, err = c.mobiTargets(func(e mobiIndexEntry) (int, int, bool) {
+			pos, ok := firstTagValue(e, 1)
+			return 0, pos, ok
+		})
+	}
+	if err != nil {
+		return err
+	}
+
+	meta := BookMetadata{
+		Title:    cmp.Or(c.exthString(exthTitle), strings.TrimSpace(c.decode(h.fullName))),
+		Author:   c.exthString(exthAuthor),
+		Language: c.exthString(exthLanguage),
+		Format:   c.format,
+	}
+	opts := htmlBookOptions{titleHeading: true, image: c.resolveImage}
+	if len(targets) == 0 {
+		var sb strings.Builder
+		for _, part := range parts {
+			start, end := htmlBodyBounds(part)
+			sb.WriteString(c.decode(part[start:end]))
+		}
+		body, err := parseHTMLBody(sb.String())
+		if err != nil {
+			return err
+		}
+		err = c.build(bookID, meta, body, opts)
+	} else {
+		var sections []htmlSection
+		sections, err = c.sections(parts, targets)
+		if err == nil {
+			err = c.buildSections(bookID, meta, sections, opts)
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	if b, ok := h.exth[exthCoverOffset]; ok && len(b[0]) == 4 {
+		if img := c.resource(be32(b[0], 0) + 1); img != nil {
+			c.images[img.ID] = img
+			c.content.Metadata.Cover = fmt.Sprintf("/api/books/%d/image/%s?v=%s", bookID, img.ID, imageURLVersion)
+		}
+	}
+	return nil
+}
+
+// parseMobiHeader reads the headers of the book whose record 0 is
+// records[start].
+func parseMobiHeader(records [][]byte, start int) (*mobiHeader, error) {
+	if start < 0 || start >= len(records) {
+		return nil, fmt.Errorf("%w: header record out of range", errMobiCorrupt)
+	}
+	rec := records[start]
+	if len(rec) < 0x28 || string(rec[16:20]) != "MOBI" {
+		return nil, fmt.Errorf("%w: no MOBI header", errMobiCorrupt)
+	}
+	headerEnd := min(16+be32(rec, 20), len(rec))
+	field := func(off int) int {
+		if off+4 > headerEnd {
+			return mobiNoIndex
+		}
+		return be32(rec[:headerEnd], off)
+	}
+	// Record indexes are relative to record 0 of the book.
+	index := func(off int) int {
+		if v := field(off); v != mobiNoIndex {
+			return start + v
+		}
+		return mobiNoIndex
+	}
+
+	h := &mobiHeader{
+		start:         start,
+		compression:   be16(rec, 0),
+		textLength:    be32(rec, 4),
+		textRecords:   be16(rec, 8),
+		encrypted:     be16(rec, 12) != 0,
+		utf8:          field(0x1C) == 65001,
+		version:       field(0x24),
+		firstResource: index(0x6C),
+		huffRecord:    index(0x70),
+		huffCount:     field(0x74),
+		ncxIndex:      index(0xF4),
+		exth:          make(map[int][][]byte),
+	}
+	if v := field(0xF0); v != mobiNoIndex {
+		h.extraFlags = v & 0xFFFF
+	}
+	if h.version == 8 {
+		h.fdstIndex = index(0xC0)
+		h.fragIndex = index(0xF8)
+		h.skelIndex = index(0xFC)
+	}
+	if off, n := field(0x54), field(0x58); off != mobiNoIndex && off+n <= len(rec) {
+		h.fullName = rec[off : off+n]
+	}
+
+	if field(0x80)&0x40 != 0 && headerEnd+12 <= len(rec) && string(rec[headerEnd:headerEnd+4]) == "EXTH" {
+		count := be32(rec, headerEnd+8)
+		for i, off := 0, headerEnd+12; i < count && off+8 <= len(rec); i++ {
+			typ, size := be32(rec, off), be32(rec, off+4)
+			if size < 8 || off+size > len(rec) {
+				break
+			}
+			h.exth[typ] = append(h.exth[typ], rec[off+8:off+size])
+			off += size
+		}
+	}
+	return h, nil
+}
+
+// maxMobiText limits the decompressed text of a book.
+const maxMobiText = 64 << 20
+
+// text decompresses the text records of the book, up to the text length
+// in the header.
+func (c *MOBIConverter) text() ([]byte, error) {
+	h := c.header
+	start := h.start + 1
+	if start+h.textRecords > len(c.records) {
+		return nil, fmt.Errorf("%w: text records out of range", errMobiCorrupt)
+	}
+	var decompress func([]byte) ([]byte, error)
+	switch h.compression {
+	case mobiNoCompression:
+		decompress = func(b []byte) ([]byte, error) { return b, nil }
+	case mobiPalmDOC:
+		decompress = palmDOCDecompress
+	case mobiHuffCDIC:
+		if h.huffRecord == mobiNoIndex || h.huffRecord+h.huffCount > len(c.records) {
+			return nil, fmt.Errorf("%w: Huffman records out of range", errMobiCorrupt)
+		}
+		huff, err := newHuffCDIC(c.records[h.huffRecord:h.huffRecord+h.huffCount], maxMobiText)
+		if err != nil {
+			return nil, err
+		}
+		decompress = huff.decompress
+	default:
+		return nil, fmt.Errorf("unsupported MOBI compression %d", h.compression)
+	}
+
+	var text []byte
+	for _, rec := range c.records[start : start+h.textRecords] {
+		if h.textLength > 0 && len(text) >= h.textLength {
+			break
+		}
+		out, err := decompress(trimTrailingEntries(rec, h.extraFlags))
+		if err != nil {
+			return nil, err
+		}
+		if len(text)+len(out) > maxMobiText {
+			return nil, fmt.Errorf("%w: text too large", errMobiCorrupt)
+		}
+		text = append(text, out...)
+	}
+	if h.textLength >= 0 && h.textLength < len(text) {
+		text = text[:h.textLength]
+	}
+	return text, nil
+}
+
+// kf8Parts assembles the files of a KF8 book from the skeletons and
+// fragments of its main text flow and maps the NCX entries into them.
+func (c *MOBIConverter) kf8Parts(text []byte) ([][]byte, []mobiTarget, error) {
+	h := c.header
+	flow := text
+	if h.fdstIndex != mobiNoIndex && h.fdstIndex < len(c.records) {
+		fdst := c.records[h.fdstIndex]
+		if len(fdst) >= 20 && string(fdst[:4]) == "FDST" && be32(fdst, 8) > 0 {
+			start, end := be32(fdst, 12), be32(fdst, 16)
+			if start < 0 || start > end || end > len(text) {
+				return nil, nil, fmt.Errorf("%w: bad FDST", errMobiCorrupt)
+			}
+			flow = text[start:end]
+		}
+	}
+	if h.skelIndex == mobiNoIndex || h.fragIndex == mobiNoIndex {
+		return [][]byte{flow}, nil, nil
+	}
+
+	skels, _, err := readMobiIndex(c.records, h.skelIndex)
+	if err != nil {
+		return nil, nil, err
+	}
+	frags, _, err := readMobiIndex(c.records, h.fragIndex)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// A file is a skeleton with its fragments inserted in order. Text
+	// positions run through the skeleton and then its fragments, so a
+	// file spans the positions [skelPos, ends[i]).
+	var parts [][]byte
+	var skelPos, ends []int
+	fragInsert := make([]int, len(frags))
+	next := 0
+	for _, skel := range skels {
+		pos, length, ok := tagPair(skel, 6)
+		count, _ := firstTagValue(skel, 1)
+		if !ok || pos+length > len(flow) || next+count > len(frags) {
+			return nil, nil, fmt.Errorf("%w: bad skeleton index", errMobiCorrupt)
+		}
+		file := slices.Clone(flow[pos : pos+length])
+		base := pos + length
+		for _, frag := range frags[next : next+count] {
+			insert, err := strconv.Atoi(frag.label)
+			_, fragLen, ok := tagPair(frag, 6)
+			if err != nil || !ok || insert < pos || insert-pos > len(file) || base+fragLen > len(flow) {
+				return nil, nil, fmt.Errorf("%w: bad fragment index", errMobiCorrupt)
+			}
+			file = slices.Insert(file, insert-pos, flow[base:base+fragLen]...)
+			fragInsert[next] = insert
+			base += fragLen
+			next++
+		}
+		parts = append(parts, file)
+		skelPos = append(skelPos, pos)
+		ends = append(ends, base)
+	}
+
+	targets, err := c.mobiTargets(func(e mobiIndexEntry) (int, int, bool) {
+		pos, ok := firstTagValue(e, 1)
+		if fid, off, hasFid := tagPair(e, 6); hasFid && fid < len(frags) {
+			pos, ok = fragInsert[fid]+off, true
+		}
+		if !ok {
+			return 0, 0, false
+		}
+		for i := range parts {
+			if pos >= skelPos[i] && pos < ends[i] {
+				return i, pos - skelPos[i], true
+			}
+		}
+		return 0, 0, false
+	})
+	return parts, targets, err
+}
+
+// mobiTargets reads the NCX entries, locating each with locate.
+func (c *MOBIConverter) mobiTargets(locate func(mobiIndexEntry) (part, offset int, ok bool)) ([]mobiTarget, error) {
+	if c.header.ncxIndex == mobiNoIndex {
+		return nil, nil
+	}
+	entries, cncx, err := readMobiIndex(c.records, c.header.ncxIndex)
+	if err != nil {
+		return nil, err
+	}
+	var targets []mobiTarget
+	for _, e := range entries {
+		part, offset, ok := locate(e)
+		if !ok {
+			continue
+		}
+		t := mobiTarget{part: part, offset: offset}
+		if off, ok := firstTagValue(e, 3); ok {
+			t.title = strings.TrimSpace(c.decode(cncx[off]))
+		}
+		t.level, _ = firstTagValue(e, 4)
+		targets = append(targets, t)
+	}
+	slices.SortStableFunc(targets, func(a, b mobiTarget) int {
+		return cmp.Or(cmp.Compare(a.part, b.part), cmp.Compare(a.offset, b.offset))
+	})
+	return targets, nil
+}
+
+// sections cuts the bodies of the parts at the targets. A section runs
+// from its target to the next one, across parts; content before the first
+// target forms an untitled section.
+func (c *MOBIConverter) sections(parts [][]byte, targets []mobiTarget) ([]htmlSection, error) {
+	sections := []htmlSection{{}}
+	var texts []string
+	var current strings.Builder
+	next := 0
+	for i, part := range parts {
+		start, end := htmlBodyBounds(part)
+		cursor := start
+		for ; next < len(targets) && targets[next].part == i; next++ {
+			t := targets[next]
+			offset := tagStart(part, min(max(t.offset, cursor), end), cursor)
+			current.WriteString(c.decode(part[cursor:offset]))
+			cursor = offset
+			texts = append(texts, current.String())
+			current.Reset()
+			sections = append(sections, htmlSection{title: t.title, level: t.level})
+		}
+		current.WriteString(c.decode(part[cursor:end]))
+	}
+	texts = append(texts, current.String())
+	for i := range sections {
+		body, err := parseHTMLBody(texts[i])
+		if err != nil {
+			return nil, err
+		}
+		sections[i].body = body
+	}
+	return sections, nil
+}
+
+// htmlBodyBounds returns the range of the body content of an HTML
+// document, or the whole document if it has no body element.
+func htmlBodyBounds(doc []byte) (int, int) {
+	lower := asciiLower(doc)
+	start, end := 0, len(doc)
+	if i := bytes.Index(lower, []byte("<body")); i >= 0 {
+		if j := bytes.IndexByte(doc[i:], '>'); j >= 0 {
+			start = i + j + 1
+		}
+	}
+	if i := bytes.LastIndex(lower, []byte("</body")); i >= start {
+		end = i
+	}
+	return start, end
+}
+
+// asciiLower returns a copy of b with ASCII letters lower-cased, so that
+// offsets stay valid whatever the encoding.
+func asciiLower(b []byte) []byte {
+	lower := make([]byte, len(b))
+	for i, c := range b {
+		if 'A' <= c && c <= 'Z' {
+			c += 'a' - 'A'
+		}
+		lower[i] = c
+	}
+	return lower
+}
+
+// tagStart moves offset back to the start of the tag it falls into, but
+// not before from.
+func tagStart(doc []byte, offset, from int) int {
+	if lt := bytes.LastIndexByte(doc[from:offset], '<'); lt > bytes.LastIndexByte(doc[from:offset], '>') {
+		return from + lt
+	}
+	return offset
+}
+
+// resolveImage finds the resource an image refers to: by its recindex
+// attribute in old MOBI books and by a kindle:embed URL in KF8 books, both
+// 1-based.
+func (c *MOBIConverter) resolveImage(img *xhtml.Node) *ImageData {
+	if rec := nodeAttr(img, "recindex"); rec != "" {
+		n, _ := strconv.Atoi(rec)
+		return c.resource(n)
+	}
+	if ref, ok := strings.CutPrefix(nodeAttr(img, "src"), "kindle:embed:"); ok {
+		ref, _, _ = strings.Cut(ref, "?")
+		n, _ := strconv.ParseInt(ref, 32, 0)
+		return c.resource(int(n))
+	}
+	return nil
+}
+
+// resource returns the n-th (1-based) resource record if it is an image.
+func (c *MOBIConverter) resource(n int) *ImageData {
+	// Check n first: a huge one would overflow the record index
+	if n < 1 || n > len(c.records) || c.header.firstResource == mobiNoIndex {
+		return nil
+	}
+	idx := c.header.firstResource + n - 1
+	if idx >= len(c.records) {
+		return nil
+	}
+	data := c.records[idx]
+	contentType := http.DetectContentType(data)
+	ext, ok := dataURIImageTypes[contentType]
+	if !ok {
+		return nil
+	}
+	return &ImageData{ID: fmt.Sprintf("image%05d%s", n, ext), ContentType: contentType, Data: data}
+}
+
+// decode converts text of the book to UTF-8.
+func (c *MOBIConverter) decode(b []byte) string {
+	if c.header.utf8 {
+		return strings.ToValidUTF8(string(b), "�")
+	}
+	return decodeWith(charmap.Windows1252, b)
+}
+
+func (c *MOBIConverter) exthString(typ int) string {
+	if values := c.header.exth[typ]; len(values) > 0 {
+		return strings.TrimSpace(c.decode(values[0]))
+	}
+	return ""
+}
+
+func firstTagValue(e mobiIndexEntry, tag int) (int, bool) {
+	if values := e.tags[tag]; len(values) > 0 {
+		return values[0], true
+	}
+	return 0, false
+}
+
+// tagPair returns the first two values of a tag, such as the position and
+// length of skeletons and fragments.
+func tagPair(e mobiIndexEntry, tag int) (int, int, bool) {
+	if values := e.tags[tag]; len(values) >= 2 {
+		return values[0], values[1], true
+	}
+	return 0, 0, false
+}
@@ -0,0 +1,453 @@
+package bookfile
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// Low-level decoding of MOBI files: the Palm database container, text
+// record compression and the INDX tables holding the NCX and the KF8
+// skeleton and fragment indexes.
+
+var errMobiCorrupt = errors.New("corrupt MOBI file")
+
+// mobiNoIndex marks an absent record index in MOBI headers.
+const mobiNoIndex = 0xFFFFFFFF
+
+// MOBI text record compression types.
+const (
+	mobiNoCompression = 1
+	mobiPalmDOC       = 2
+	mobiHuffCDIC      = 17480
+)
+
+// be16 and be32 read big-endian values, returning -1 when out of range.
+func be16(b []byte, off int) int {
+	if off < 0 || off+2 > len(b) {
+		return -1
+	}
+	return int(binary.BigEndian.Uint16(b[off:]))
+}
+
+func be32(b []byte, off int) int {
+	if off < 0 || off+4 > len(b) {
+		return -1
+	}
+	return int(binary.BigEndian.Uint32(b[off:]))
+}
+
+// pdbRecords splits a Palm database into its records.
+func pdbRecords(data []byte) ([][]byte, error) {
+	const headerSize = 78
+	count := be16(data, 76)
+	if count <= 0 || headerSize+8*count > len(data) {
+		return nil, errMobiCorrupt
+	}
+	offsets := make([]int, count+1)
+	for i := range count {
+		offsets[i] = be32(data, headerSize+8*i)
+	}
+	offsets[count] = len(data)
+	records := make([][]byte, count)
+	for i := range count {
+		if offsets[i] > offsets[i+1] || offsets[i+1] > len(data) {
+			return nil, errMobiCorrupt
+		}
+		records[i] = data[offsets[i]:offsets[i+1]]
+	}
+	return records, nil
+}
+
+// trimTrailingEntries strips the trailing entries that the extra data flags
+// of the MOBI header announce at the end of every text record.
+func trimTrailingEntries(rec []byte, flags int) []byte {
+	for f := flags >> 1; f != 0; f >>= 1 {
+		if f&1 == 0 {
+			continue
+		}
+		// The entry size, itself included, is a variable-width value
+		// stored backwards at the end of the entry.
+		size, shift := 0, 0
+		for i := len(rec) - 1; i >= 0 && shift < 28; i-- {
+			size |= int(rec[i]&0x7F) << shift
+			shift += 7
+			if rec[i]&0x80 != 0 {
+				break
+			}
+		}
+		if size > len(rec) {
+			return nil
+		}
+		rec = rec[:len(rec)-size]
+	}
+	if flags&1 != 0 && len(rec) > 0 {
+		// Multibyte overlap: the low two bits of the last byte count the
+		// bytes of a character continued from the next record.
+		size := int(rec[len(rec)-1]&3) + 1
+		if size > len(rec) {
+			return nil
+		}
+		rec = rec[:len(rec)-size]
+	}
+	return rec
+}
+
+// palmDOCDecompress expands a text record compressed with the PalmDOC
+// LZ77 variant.
+func palmDOCDecompress(src []byte) ([]byte, error) {
+	out := make([]byte, 0, 2*len(src))
+	for i := 0; i < len(src); {
+		c := src[i]
+		i++
+		switch {
+		case c >= 1 && c <= 8:
+			if i+int(c) > len(src) {
+				return nil, errMobiCorrupt
+			}
+			out = append(out, src[i:i+int(c)]...)
+			i += int(c)
+		case c < 0x80:
+			out = append(out, c)
+		case c >= 0xC0:
+			out = append(out, ' ', c^0x80)
+		default:
+			if i >= len(src) {
+				return nil, errMobiCorrupt
+			}
+			pair := int(c)<<8 | int(src[i])
+			i++
+			dist, n := (pair&0x3FFF)>>3, pair&7+3
+			if dist == 0 || dist > len(out) {
+				return nil, errMobiCorrupt
+			}
+			for range n {
+				out = append(out, out[len(out)-dist])
+			}
+		}
+	}
+	return out, nil
+}
+
+// huffCDIC decompresses text records compressed with the Huffman coding
+// of MOBI files: a HUFF record with the code tables and CDIC records with
+// the phrase dictionary. Phrases may be compressed themselves and are
+// expanded on first use.
+type huffCDIC struct {
+	codes   [256]huffCode
+	minCode [33]uint64
+	maxCode [33]uint64
+	phrases []huffPhrase
+	// Bytes left to produce, phrase expansions included: nested phrases
+	// can otherwise expand a small file exponentially
+	left int
+}
+
+type huffCode struct {
+	length  int
+	term    bool
+	maxCode uint64
+}
+
+type huffPhrase struct {
+	data     []byte
+	expanded bool
+	busy     bool
+}
+
+// maxHuffDepth limits the nesting of compressed phrases.
+const maxHuffDepth = 32
+
+// newHuffCDIC reads the code tables and phrases. The decompressor produces
+// at most limit bytes in total.
+func newHuffCDIC(records [][]byte, limit int) (*huffCDIC, error) {
+	if len(records) < 2 {
+		return nil, errMobiCorrupt
+	}
+	huff := records[0]
+	if len(huff) < 16 || string(huff[:8]) != "HUFF\x00\x00\x00\x18" {
+		return nil, fmt.Errorf("%w: bad HUFF record", errMobiCorrupt)
+	}
+	h := &huffCDIC{left: limit}
+	off1, off2 := be32(huff, 8), be32(huff, 12)
+	if off1 < 0 || off1+256*4 > len(huff) || off2 < 0 || off2+64*4 > len(huff) {
+		return nil, errMobiCorrupt
+	}
+	for i := range h.codes {
+		v := be32(huff, off1+4*i)
+		length := v & 0x1F
+		if length == 0 {
+			return nil, fmt.Errorf("%w: bad HUFF code table", errMobiCorrupt)
+		}
+		h.codes[i] = huffCode{
+			length:  length,
+			term:    v&0x80 != 0,
+			maxCode: (uint64(v>>8)+1)<<(32-length) - 1,
+		}
+	}
+	for length := 1; length <= 32; length++ {
+		h.minCode[length] = uint64(be32(huff, off2+8*(length-1))) << (32 - length)
+		h.maxCode[length] = (uint64(be32(huff, off2+8*(length-1)+4))+1)<<(32-length) - 1
+	}
+
+	for _, cdic := range records[1:] {
+		if len(cdic) < 16 || string(cdic[:8]) != "CDIC\x00\x00\x00\x10" {
+			return nil, fmt.Errorf("%w: bad CDIC record", errMobiCorrupt)
+		}
+		total, bits := be32(cdic, 8), be32(cdic, 12)
+		n := min(1<<min(bits, 16), total-len(h.phrases))
+		for i := range n {
+			off := be16(cdic, 16+2*i)
+			header := be16(cdic, 16+off)
+			if off < 0 || header < 0 || 18+off+header&0x7FFF > len(cdic) {
+				return nil, errMobiCorrupt
+			}
+			// The high bit of the length marks phrases stored expanded.
+			h.phrases = append(h.phrases, huffPhrase{
+				data:     cdic[18+off : 18+off+header&0x7FFF],
+				expanded: header&0x8000 != 0,
+			})
+		}
+	}
+	return h, nil
+}
+
+func (h *huffCDIC) decompress(src []byte) ([]byte, error) {
+	return h.unpack(src, 0)
+}
+
+func (h *huffCDIC) unpack(src []byte, depth int) ([]byte, error) {
+	if depth > maxHuffDepth {
+		return nil, fmt.Errorf("%w: phrases nested too deep", errMobiCorrupt)
+	}
+	bitsLeft := 8 * len(src)
+	buf := make([]byte, len(src)+8)
+	copy(buf, src)
+	pos, n := 0, 32
+	x := binary.BigEndian.Uint64(buf)
+	var out []byte
+	for {
+		if n <= 0 {
+			pos += 4
+			x = binary.BigEndian.Uint64(buf[pos:])
+			n += 32
+		}
+		code := (x >> n) & 0xFFFFFFFF
+		c := h.codes[code>>24]
+		length, maxCode := c.length, c.maxCode
+		if !c.term {
+			for length < 32 && code < h.minCode[length] {
+				length++
+			}
+			maxCode = h.maxCode[length]
+		}
+		n -= length
+		bitsLeft -= length
+		if bitsLeft < 0 {
+			return out, nil
+		}
+
+		r := (maxCode - code) >> (32 - length)
+		if r >= uint64(len(h.phrases)) {
+			return nil, fmt.Errorf("%w: phrase out of range", errMobiCorrupt)
+		}
+		p := &h.phrases[r]
+		if !p.expanded {
+			if p.busy {
+				return nil, fmt.Errorf("%w: recursive phrase", errMobiCorrupt)
+			}
+			p.busy = true
+			data, err := h.unpack(p.data, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			p.data, p.expanded, p.busy = data, true, false
+		}
+		if len(p.data) > h.left {
+			return nil, fmt.Errorf("%w: text too large", errMobiCorrupt)
+		}
+		h.left -= len(p.data)
+		out = append(out, p.data...)
+	}
+}
+
+// mobiIndexEntry is an entry of an INDX table: a label and the values of
+// its tags.
+type mobiIndexEntry struct {
+	label string
+	tags  map[int][]int
+}
+
+// mobiTag describes a tag of an INDX table (a TAGX entry).
+type mobiTag struct {
+	tag, valuesPerEntry, mask int
+	endFlag                   bool
+}
+
+// readMobiIndex reads the INDX table starting at record idx. It returns
+// the entries and the strings of its CNCX records, by offset.
+func readMobiIndex(records [][]byte, idx int) ([]mobiIndexEntry, map[int][]byte, error) {
+	if idx < 0 || idx >= len(records) {
+		return nil, nil, fmt.Errorf("%w: index record out of range", errMobiCorrupt)
+	}
+	main := records[idx]
+	if len(main) < 56 || string(main[:4]) != "INDX" {
+		return nil, nil, fmt.Errorf("%w: bad INDX record", errMobiCorrupt)
+	}
+	headerLen, count, cncxCount := be32(main, 4), be32(main, 24), be32(main, 52)
+	if idx+1+count+cncxCount > len(records) {
+		return nil, nil, fmt.Errorf("%w: index records out of range", errMobiCorrupt)
+	}
+
+	cncx := make(map[int][]byte)
+	for j := range cncxCount {
+		rec := records[idx+1+count+j]
+		for off := 0; off < len(rec) && rec[off] != 0; {
+			start := off
+			consumed, length := readVWI(rec, off)
+			off += consumed
+			if consumed == 0 || off+length > len(rec) {
+				break
+			}
+			cncx[j<<16+start] = rec[off : off+length]
+			off += length
+		}
+	}
+
+	tags, controlBytes, err := readTagx(main, headerLen)
+	if err != nil {
+		return nil, nil, err
+	}
+	var entries []mobiIndexEntry
+	for i := idx + 1; i <= idx+count; i++ {
+		rec := records[i]
+		if len(rec) < 28 || string(rec[:4]) != "INDX" {
+			return nil, nil, fmt.Errorf("%w: bad INDX record", errMobiCorrupt)
+		}
+		idxt, n := be32(rec, 20), be32(rec, 24)
+		if idxt < 0 || idxt+4+2*n > len(rec) {
+			return nil, nil, fmt.Errorf("%w: bad IDXT", errMobiCorrupt)
+		}
+		for j := range n {
+			start := be16(rec, idxt+4+2*j)
+			end := idxt
+			if j+1 < n {
+				end = be16(rec, idxt+4+2*(j+1))
+			}
+			if start >= end || end > len(rec) || start+1+int(rec[start]) > end {
+				return nil, nil, fmt.Errorf("%w: bad index entry", errMobiCorrupt)
+			}
+			labelEnd := start + 1 + int(rec[start])
+			entries = append(entries, mobiIndexEntry{
+				label: string(rec[start+1 : labelEnd]),
+				tags:  readTagValues(rec[labelEnd:end], tags, controlBytes),
+			})
+		}
+	}
+	return entries, cncx, nil
+}
+
+// readTagx reads the TAGX section describing the tags of index entries.
+func readTagx(rec []byte, off int) ([]mobiTag, int, error) {
+	if off < 0 || off+12 > len(rec) || string(rec[off:off+4]) != "TAGX" {
+		return nil, 0, fmt.Errorf("%w: bad TAGX", errMobiCorrupt)
+	}
+	size, controlBytes := be32(rec, off+4), be32(rec, off+8)
+	if off+size > len(rec) {
+		return nil, 0, fmt.Errorf("%w: bad TAGX", errMobiCorrupt)
+	}
+	var tags []mobiTag
+	for i := off + 12; i+4 <= off+size; i += 4 {
+		tags = append(tags, mobiTag{
+			tag:            int(rec[i]),
+			valuesPerEntry: int(rec[i+1]),
+			mask:           int(rec[i+2]),
+			endFlag:        rec[i+3] == 1,
+		})
+	}
+	return tags, controlBytes, nil
+}
+
+// readTagValues decodes the tag values of an index entry. Control bytes
+// say which tags are present and how many values they have; the values
+// follow as variable-width integers.
+func readTagValues(data []byte, tags []mobiTag, controlBytes int) map[int][]int {
+	type present struct {
+		tag, count, byteLen, valuesPerEntry int
+	}
+	var found []present
+	control := 0
+	for _, t := range tags {
+		if t.endFlag {
+			control++
+			continue
+		}
+		if control >= len(data) || t.mask == 0 {
+			break
+		}
+		value := int(data[control]) & t.mask
+		switch {
+		case value == 0:
+		case value == t.mask && bitCount(t.mask) > 1:
+			// The values take the number of bytes given by the next
+			// variable-width value.
+			found = append(found, present{tag: t.tag, byteLen: -1, valuesPerEntry: t.valuesPerEntry})
+		default:
+			mask := t.mask
+			for mask&1 == 0 {
+				mask >>= 1
+				value >>= 1
+			}
+			found = append(found, present{tag: t.tag, count: value, valuesPerEntry: t.valuesPerEntry})
+		}
+	}
+
+	values := make(map[int][]int)
+	pos := controlBytes
+	for _, p := range found {
+		if p.byteLen < 0 {
+			consumed, length := readVWI(data, pos)
+			pos += consumed
+			for end := pos + length; pos < end; {
+				consumed, v := readVWI(data, pos)
+				if consumed == 0 {
+					break
+				}
+				pos += consumed
+				values[p.tag] = append(values[p.tag], v)
+			}
+			continue
+		}
+		for range p.count * p.valuesPerEntry {
+			consumed, v := readVWI(data, pos)
+			if consumed == 0 {
+				break
+			}
+			pos += consumed
+			values[p.tag] = append(values[p.tag], v)
+		}
+	}
+	return values
+}
+
+// readVWI reads a forward variable-width integer, whose last byte has the
+// high bit set. It returns the number of bytes consumed, 0 at the end of
+// data.
+func readVWI(data []byte, off int) (int, int) {
+	value := 0
+	for i := off; i < len(data) && i-off < 5; i++ {
+		value = value<<7 | int(data[i]&0x7F)
+		if data[i]&0x80 != 0 {
+			return i - off + 1, value
+		}
+	}
+	return 0, 0
+}
+
+func bitCount(v int) int {
+	n := 0
+	for ; v != 0; v >>= 1 {
+		n += v & 1
+	}
+	return n
+}
@@ -0,0 +1,624 @@
+package bookfile
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	xhtml "golang.org/x/net/html"
+	"golang.org/x/text/encoding/charmap"
+)
+
+// Test fixtures are generated by a minimal MOBI writer: a Palm database
+// with record 0, the text records and the index and resource records the
+// fixture asks for, in that order.
+
+type mobiFixture struct {
+	text        []byte
+	compression int
+	cp1252      bool
+	kf8         bool
+	encrypted   bool
+	fullName    string
+	exth        map[int][]byte
+	ncx         []ncxFixture
+	skeletons   []kf8SkeletonFixture
+	// flows are the lengths of the text flows after the first, for FDST.
+	flows     []int
+	resources [][]byte
+}
+
+type ncxFixture struct {
+	title    string
+	pos      int
+	fid, off int
+	level    int
+}
+
+type kf8SkeletonFixture struct {
+	pos, length int
+	fragments   []kf8FragmentFixture
+}
+
+type kf8FragmentFixture struct {
+	insert, pos, length int
+}
+
+const mobiTestHeaderLen = 0x108
+
+func buildMOBI(t *testing.T, f mobiFixture) []byte {
+	t.Helper()
+	compression := f.compression
+	if compression == 0 {
+		compression = mobiPalmDOC
+	}
+
+	// Text records of 4096 bytes, with a multibyte byte and a 3-byte
+	// trailing entry each.
+	var textRecords [][]byte
+	var huffRecords [][]byte
+	var huff *testHuffEncoder
+	if compression == mobiHuffCDIC {
+		huff = newTestHuffEncoder(f.text)
+		huffRecords = huff.records()
+	}
+	for start := 0; start < len(f.text); start += 4096 {
+		chunk := f.text[start:min(start+4096, len(f.text))]
+		var rec []byte
+		switch compression {
+		case mobiPalmDOC:
+			rec = testPalmDOCCompress(chunk)
+		case mobiHuffCDIC:
+			rec = huff.encode(chunk)
+		default:
+			rec = bytes.Clone(chunk)
+		}
+		rec = append(rec, 0x00, 0xAA, 0xBB, 0x83)
+		textRecords = append(textRecords, rec)
+	}
+
+	records := [][]byte{nil}
+	records = append(records, textRecords...)
+	fields := map[int]int{}
+	addRecords := func(field int, recs ...[]byte) {
+		fields[field] = len(records)
+		records = append(records, recs...)
+	}
+	if huff != nil {
+		addRecords(0x70, huffRecords...)
+		fields[0x74] = len(huffRecords)
+	}
+	if len(f.ncx) > 0 {
+		var cncx []byte
+		var entries [][]byte
+		for i, e := range f.ncx {
+			labelOff := len(cncx)
+			cncx = append(cncx, testVWI(len(e.title))...)
+			cncx = append(cncx, e.title...)
+			values := []int{e.pos, labelOff, e.level}
+			control := byte(0x07)
+			if f.kf8 {
+				values = append(values, e.fid, e.off)
+				control = 0x0F
+			}
+			entries = append(entries, testIndexEntry(fmt.Sprintf("%d", i), control, values...))
+		}
+		addRecords(0xF4, testIndex([]mobiTag{
+			{tag: 1, valuesPerEntry: 1, mask: 0x01},
+			{tag: 3, valuesPerEntry: 1, mask: 0x02},
+			{tag: 4, valuesPerEntry: 1, mask: 0x04},
+			{tag: 6, valuesPerEntry: 2, mask: 0x08},
+			{endFlag: true},
+		}, entries, [][]byte{cncx})...)
+	}
+	if f.kf8 {
+		var skels, frags [][]byte
+		for i, s := range f.skeletons {
+			skels = append(skels, testIndexEntry(fmt.Sprintf("SKEL%010d", i), 0x05, len(s.fragments), s.pos, s.length))
+			for _, fr := range s.fragments {
+				frags = append(frags, testIndexEntry(fmt.Sprintf("%d", fr.insert), 0x01, fr.pos, fr.length))
+			}
+		}
+		addRecords(0xFC, testIndex([]mobiTag{
+			{tag: 1, valuesPerEntry: 1, mask: 0x03},
+			{tag: 6, valuesPerEntry: 2, mask: 0x0C},
+			{endFlag: true},
+		}, skels, nil)...)
+		addRecords(0xF8, testIndex([]mobiTag{
+			{tag: 6, valuesPerEntry: 2, mask: 0x01},
+			{endFlag: true},
+		}, frags, nil)...)
+
+		flowEnds := []int{len(f.text)}
+		for _, n := range f.flows {
+			flowEnds[0] -= n
+		}
+		for _, n := range f.flows {
+			flowEnds = append(flowEnds, flowEnds[len(flowEnds)-1]+n)
+		}
+		fdst := append([]byte("FDST"), be32Bytes(12, len(flowEnds))...)
+		start := 0
+		for _, end := range flowEnds {
+			fdst = append(fdst, be32Bytes(start, end)...)
+			start = end
+		}
+		addRecords(0xC0, fdst)
+	}
+	if len(f.resources) > 0 {
+		addRecords(0x6C, f.resources...)
+	}
+
+	// Record 0: the PalmDOC header, the MOBI header, EXTH and the full name.
+	rec0 := make([]byte, 16+mobiTestHeaderLen)
+	binary.BigEndian.PutUint16(rec0[0:], uint16(compression))
+	binary.BigEndian.PutUint32(rec0[4:], uint32(len(f.text)))
+	binary.BigEndian.PutUint16(rec0[8:], uint16(len(textRecords)))
+	binary.BigEndian.PutUint16(rec0[10:], 4096)
+	if f.encrypted {
+		binary.BigEndian.PutUint16(rec0[12:], 2)
+	}
+	copy(rec0[16:], "MOBI")
+	for off := 0x28; off < 16+mobiTestHeaderLen; off += 4 {
+		binary.BigEndian.PutUint32(rec0[off:], mobiNoIndex)
+	}
+	put := func(off, v int) { binary.BigEndian.PutUint32(rec0[off:], uint32(v)) }
+	put(0x14, mobiTestHeaderLen)
+	put(0x18, 2)
+	put(0x1C, 65001)
+	if f.cp1252 {
+		put(0x1C, 1252)
+	}
+	put(0x24, 6)
+	if f.kf8 {
+		put(0x24, 8)
+	}
+	put(0x80, 0x40)
+	put(0xF0, 3)
+	for off, v := range fields {
+		put(off, v)
+	}
+	exth := []byte("EXTH")
+	var exthRecords []byte
+	for typ, data := range f.exth {
+		exthRecords = append(exthRecords, be32Bytes(typ, len(data)+8)...)
+		exthRecords = append(exthRecords, data...)
+	}
+	exth = append(exth, be32Bytes(12+len(exthRecords), len(f.exth))...)
+	exth = append(exth, exthRecords...)
+	put(0x54, len(rec0)+len(exth))
+	put(0x58, len(f.fullName))
+	rec0 = append(rec0, exth...)
+	rec0 = append(rec0, f.fullName...)
+	records[0] = rec0
+
+	// The Palm database header and record list.
+	pdb := make([]byte, 78+8*len(records)+2)
+	copy(pdb, "test-book")
+	copy(pdb[60:], "BOOKMOBI")
+	binary.BigEndian.PutUint16(pdb[76:], uint16(len(records)))
+	for i, rec := range records {
+		binary.BigEndian.PutUint32(pdb[78+8*i:], uint32(len(pdb)))
+		binary.BigEndian.PutUint32(pdb[78+8*i+4:], uint32(i))
+		pdb = append(pdb, rec...)
+	}
+	return pdb
+}
+
+func be32Bytes(values ...int) []byte {
+	var b []byte
+	for _, v := range values {
+		b = binary.BigEndian.AppendUint32(b, uint32(v))
+	}
+	return b
+}
+
+func testVWI(v int) []byte {
+	b := []byte{byte(v&0x7F) | 0x80}
+	for v >>= 7; v > 0; v >>= 7 {
+		b = append([]byte{byte(v & 0x7F)}, b...)
+	}
+	return b
+}
+
+func testIndexEntry(label string, control byte, values ...int) []byte {
+	entry := append([]byte{byte(len(label))}, label...)
+	entry = append(entry, control)
+	for _, v := range values {
+		entry = append(entry, testVWI(v)...)
+	}
+	return entry
+}
+
+// testIndex builds an INDX table: the main record with the TAGX section,
+// one record with the entries and the CNCX records.
+func testIndex(tags []mobiTag, entries [][]byte, cncx [][]byte) [][]byte {
+	const headerLen = 192
+	header := func(start, count, cncxCount int) []byte {
+		h := make([]byte, headerLen)
+		copy(h, "INDX")
+		binary.BigEndian.PutUint32(h[4:], headerLen)
+		binary.BigEndian.PutUint32(h[20:], uint32(start))
+		binary.BigEndian.PutUint32(h[24:], uint32(count))
+		binary.BigEndian.PutUint32(h[52:], uint32(cncxCount))
+		return h
+	}
+
+	main := header(0, 1, len(cncx))
+	main = append(main, "TAGX"...)
+	main = append(main, be32Bytes(12+4*len(tags), 1)...)
+	for _, t := range tags {
+		end := byte(0)
+		if t.endFlag {
+			end = 1
+		}
+		main = append(main, byte(t.tag), byte(t.valuesPerEntry), byte(t.mask), end)
+	}
+
+	var body []byte
+	var offsets []int
+	for _, e := range entries {
+		offsets = append(offsets, headerLen+len(body))
+		body = append(body, e...)
+	}
+	rec := header(headerLen+len(body), len(entries), 0)
+	rec = append(rec, body...)
+	rec = append(rec, "IDXT"...)
+	for _, off := range offsets {
+		rec = binary.BigEndian.AppendUint16(rec, uint16(off))
+	}
+	return append([][]byte{main, rec}, cncx...)
+}
+
+// testPalmDOCCompress compresses with back references, space pairs and
+// literal runs, so that decompression meets every kind of code.
+func testPalmDOCCompress(src []byte) []byte {
+	var out []byte
+	for i := 0; i < len(src); {
+		bestLen, bestDist := 0, 0
+		for dist := 1; dist <= min(i, 2047); dist++ {
+			n := 0
+			for n < 10 && i+n < len(src) && src[i+n-dist] == src[i+n] {
+				n++
+			}
+			if n > bestLen {
+				bestLen, bestDist = n, dist
+			}
+		}
+		c := src[i]
+		switch {
+		case bestLen >= 3:
+			pair := 0x8000 | bestDist<<3 | (bestLen - 3)
+			out = append(out, byte(pair>>8), byte(pair))
+			i += bestLen
+		case c == ' ' && i+1 < len(src) && src[i+1] >= 0x40 && src[i+1] < 0x80:
+			out = append(out, src[i+1]^0x80)
+			i += 2
+		case c == 0 || (c >= 9 && c < 0x80):
+			out = append(out, c)
+			i++
+		default:
+			out = append(out, 1, c)
+			i++
+		}
+	}
+	return out
+}
+
+// testHuffEncoder encodes text with 8-bit Huffman codes: code b stands for
+// phrase 255-b. The dictionary holds the bytes of the text, a few words and
+// a word stored compressed.
+type testHuffEncoder struct {
+	phrases  [][]byte
+	expanded [][]byte
+}
+
+func newTestHuffEncoder(text []byte) *testHuffEncoder {
+	e := &testHuffEncoder{}
+	seen := map[byte]bool{}
+	for _, b := range text {
+		if !seen[b] {
+			seen[b] = true
+			e.phrases = append(e.phrases, []byte{b})
+			e.expanded = append(e.expanded, []byte{b})
+		}
+	}
+	for _, word := range []string{"<p>", "</p>", "<h2>", "</h2>"} {
+		e.phrases = append(e.phrases, []byte(word))
+		e.expanded = append(e.expanded, []byte(word))
+	}
+	// A compressed phrase, expanded through the other phrases.
+	e.phrases = append(e.phrases, nil)
+	e.expanded = append(e.expanded, []byte("сказ"))
+	last := len(e.phrases) - 1
+	e.phrases[last] = e.encodeWith([]byte("сказ"), last)
+	return e
+}
+
+func (e *testHuffEncoder) encode(text []byte) []byte {
+	return e.encodeWith(text, len(e.phrases))
+}
+
+// encodeWith encodes greedily with the first n phrases.
+func (e *testHuffEncoder) encodeWith(text []byte, n int) []byte {
+	var out []byte
+	for len(text) > 0 {
+		best := -1
+		for i, p := range e.expanded[:n] {
+			if bytes.HasPrefix(text, p) && (best < 0 || len(p) > len(e.expanded[best])) {
+				best = i
+			}
+		}
+		out = append(out, byte(255-best))
+		text = text[len(e.expanded[best]):]
+	}
+	return out
+}
+
+func (e *testHuffEncoder) records() [][]byte {
+	huff := append([]byte("HUFF"), be32Bytes(24, 24, 24+1024, 0, 0)...)
+	for range 256 {
+		huff = binary.BigEndian.AppendUint32(huff, 255<<8|0x80|8)
+	}
+	huff = append(huff, make([]byte, 64*4)...)
+
+	cdic := append([]byte("CDIC"), be32Bytes(16, len(e.phrases), 8)...)
+	var data []byte
+	for i, p := range e.phrases {
+		cdic = binary.BigEndian.AppendUint16(cdic, uint16(2*len(e.phrases)+len(data)))
+		flag := 0x8000
+		if i == len(e.phrases)-1 {
+			flag = 0
+		}
+		data = binary.BigEndian.AppendUint16(data, uint16(len(p)|flag))
+		data = append(data, p...)
+	}
+	return [][]byte{huff, append(cdic, data...)}
+}
+
+const testMOBIText = `<html><head><guide><reference type="toc" title="Содержание" filepos=0000000000 /></guide></head><body>` +
+	`<p>Титульная страница</p><mbp:pagebreak/>` +
+	`<h2>Глава первая</h2><p>Текст <b>первой</b> главы.</p><p><img recindex="00001" /></p><mbp:pagebreak/>` +
+	`<h2>Глава вторая</h2><p>Её текст.</p><p><a filepos=0000000000>ссылка</a></p>`
+
+func testMOBIBook(t *testing.T) []byte {
+	t.Helper()
+	var text strings.Builder
+	text.WriteString(testMOBIText)
+	for i := range 150 {
+		fmt.Fprintf(&text, "<p>Абзац %d второй главы.</p>", i+1)
+	}
+	text.WriteString("</body></html>")
+	first := strings.Index(text.String(), "<h2>Глава первая")
+	second := strings.Index(text.String(), "<h2>Глава вторая")
+
+	return buildMOBI(t, mobiFixture{
+		text:     []byte(text.String()),
+		fullName: "kniga",
+		exth: map[int][]byte{
+			exthTitle:       []byte("Книга"),
+			exthAuthor:      []byte("Иван Петров"),
+			exthLanguage:    []byte("ru"),
+			exthCoverOffset: be32Bytes(0),
+		},
+		ncx: []ncxFixture{
+			{title: "Часть 1", pos: first},
+			{title: "Глава первая", pos: first, level: 1},
+			// Inside the heading tag: the chapter starts at the tag.
+			{title: "Глава 2", pos: second + 2, level: 1},
+		},
+		resources: [][]byte{testPageImage(t, 10), []byte("FONT data")},
+	})
+}
+
+func TestMOBIConverter(t *testing.T) {
+	conv, err := GetConverter("mobi")
+	require.NoError(t, err)
+	require.NoError(t, conv.Parse(testMOBIBook(t), 7))
+
+	content := conv.Content()
+	assert.Equal(t, BookMetadata{
+		Title:    "Книга",
+		Author:   "Иван Петров",
+		Language: "ru",
+		Format:   "mobi",
+		Cover:    "/api/books/7/image/image00001.png?v=2",
+	}, content.Metadata)
+	assert.Equal(t, []TOCEntry{
+		{ID: "ch1", Title: "Книга"},
+		{ID: "ch2", Title: "Часть 1"},
+		{ID: "ch3", Title: "Глава первая", Level: 1},
+		{ID: "ch4", Title: "Глава 2", Level: 1},
+	}, content.TOC)
+
+	preamble, err := conv.Chapter("ch1")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Титульная страница"}, ParagraphTexts(preamble.HTML))
+
+	part, err := conv.Chapter("ch2")
+	require.NoError(t, err)
+	assert.Equal(t, `<h2 class="chapter-title" data-p="0">Часть 1</h2>`+"\n", part.HTML)
+
+	first, err := conv.Chapter("ch3")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Глава первая", "Текст первой главы."}, ParagraphTexts(first.HTML))
+	assert.Contains(t, first.HTML, `<img src="/api/books/7/image/image00001.png?v=2"/>`)
+	assert.NotContains(t, first.HTML, "pagebreak")
+
+	second, err := conv.Chapter("ch4")
+	require.NoError(t, err)
+	texts := ParagraphTexts(second.HTML)
+	assert.Equal(t, []string{"Глава вторая", "Её текст.", "ссылка"}, texts[:3])
+	assert.Equal(t, "Абзац 150 второй главы.", texts[len(texts)-1])
+	assert.NotContains(t, second.HTML, "filepos")
+
+	img, err := conv.Image("image00001.png")
+	require.NoError(t, err)
+	assert.Equal(t, "image/png", img.ContentType)
+	_, err = conv.Image("image00002.png")
+	assert.Error(t, err)
+}
+
+func TestMOBIConverter_HuffCDICWithoutNCX(t *testing.T) {
+	text := "<html><body><h1>Сказка</h1><h2>Начало</h2><p>Жили-были дед да баба, а сказка долгая.</p>" +
+		"<h2>Конец</h2><p>Вот и сказке конец.</p></body></html>"
+	conv := &MOBIConverter{format: "mobi"}
+	require.NoError(t, conv.Parse(buildMOBI(t, mobiFixture{text: []byte(text), compression: mobiHuffCDIC}), 1))
+
+	content := conv.Content()
+	assert.Equal(t, "Сказка", content.Metadata.Title)
+	assert.Equal(t, []TOCEntry{{ID: "ch1", Title: "Начало"}, {ID: "ch2", Title: "Конец"}}, content.TOC)
+	ch, err := conv.Chapter("ch2")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Конец", "Вот и сказке конец."}, ParagraphTexts(ch.HTML))
+}
+
+func TestMOBIConverter_CP1252(t *testing.T) {
+	encode := func(s string) []byte {
+		b, err := charmap.Windows1252.NewEncoder().Bytes([]byte(s))
+		require.NoError(t, err)
+		return b
+	}
+	conv := &MOBIConverter{format: "mobi"}
+	require.NoError(t, conv.Parse(buildMOBI(t, mobiFixture{
+		text:        encode("<html><body><p>Café crème</p></body></html>"),
+		compression: mobiNoCompression,
+		cp1252:      true,
+		fullName:    string(encode("Crème brûlée")),
+	}), 1))
+
+	assert.Equal(t, "Crème brûlée", conv.Content().Metadata.Title)
+	ch, err := conv.Chapter("ch1")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Café crème"}, ParagraphTexts(ch.HTML))
+}
+
+func TestMOBIConverter_KF8(t *testing.T) {
+	skeleton := `<html><head><title>t</title><link href="kindle:flow:0001?mime=text/css"/></head><body aid="0"></body></html>`
+	frag1 := `<div><h1>Глава 1</h1><p>Первый текст.</p></div>`
+	frag2 := `<div><h1>Глава 2</h1><p>Второй текст.</p><p><img src="kindle:embed:0002?mime=image/png"/></p>`
+	frag3 := `<h2 id="x">Раздел</h2><p>Ещё.</p></div>`
+	css := "p { margin: 0 }"
+	insert := strings.Index(skeleton, "</body>")
+
+	// Flow: skeleton 1, its fragment, skeleton 2, its two fragments.
+	skel2 := len(skeleton) + len(frag1)
+	flow := skeleton + frag1 + skeleton + frag2 + frag3
+	data := buildMOBI(t, mobiFixture{
+		text:     []byte(flow + css),
+		kf8:      true,
+		fullName: "Книга KF8",
+		skeletons: []kf8SkeletonFixture{
+			{pos: 0, length: len(skeleton), fragments: []kf8FragmentFixture{
+				{insert: insert, pos: 0, length: len(frag1)},
+			}},
+			{pos: skel2, length: len(skeleton), fragments: []kf8FragmentFixture{
+				{insert: skel2 + insert, pos: 0, length: len(frag2)},
+				{insert: skel2 + insert + len(frag2), pos: len(frag2), length: len(frag3)},
+			}},
+		},
+		flows: []int{len(css)},
+		ncx: []ncxFixture{
+			{title: "Глава 1", fid: 0},
+			{title: "Глава 2", fid: 1},
+			{title: "Раздел", fid: 2, level: 1},
+		},
+		resources: [][]byte{[]byte("RESC"), testPageImage(t, 10)},
+	})
+
+	conv, err := GetConverter("azw3")
+	require.NoError(t, err)
+	require.NoError(t, conv.Parse(data, 5))
+
+	content := conv.Content()
+	assert.Equal(t, "Книга KF8", content.Metadata.Title)
+	assert.Equal(t, []TOCEntry{
+		{ID: "ch1", Title: "Глава 1"},
+		{ID: "ch2", Title: "Глава 2"},
+		{ID: "ch3", Title: "Раздел", Level: 1},
+	}, content.TOC)
+
+	ch, err := conv.Chapter("ch2")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Глава 2", "Второй текст."}, ParagraphTexts(ch.HTML))
+	assert.Contains(t, ch.HTML, `<img src="/api/books/5/image/image00002.png?v=2"/>`)
+	assert.NotContains(t, ch.HTML, "margin")
+
+	sub, err := conv.Chapter("ch3")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Раздел", "Ещё."}, ParagraphTexts(sub.HTML))
+}
+
+func TestMOBIConverter_Errors(t *testing.T) {
+	conv := &MOBIConverter{}
+	assert.Error(t, conv.Parse([]byte("not a book"), 1))
+
+	drm := buildMOBI(t, mobiFixture{text: []byte("<p>x</p>"), encrypted: true})
+	err := conv.Parse(drm, 1)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "DRM")
+
+	corrupt := buildMOBI(t, mobiFixture{text: []byte(testMOBIText)})
+	binary.BigEndian.PutUint16(corrupt[76:], 500)
+	assert.Error(t, conv.Parse(corrupt, 1))
+}
+
+func TestMOBIConverter_ResourceOutOfRange(t *testing.T) {
+	conv := &MOBIConverter{format: "mobi"}
+	require.NoError(t, conv.Parse(testMOBIBook(t), 1))
+
+	assert.NotNil(t, conv.resource(1))
+	// Indexes past the records, including ones that overflow the record index
+	assert.Nil(t, conv.resource(len(conv.records)))
+	assert.Nil(t, conv.resource(math.MaxInt))
+	img := &xhtml.Node{Type: xhtml.ElementNode, Data: "img", Attr: []xhtml.Attribute{{Key: "src", Val: "kindle:embed:7VVVVVVVVVVVV"}}}
+	assert.Nil(t, conv.resolveImage(img))
+}
+
+// testNestedHuffCDIC returns a decompressor whose phrase k expands to
+// phrase k-1 twice, 2^(k+1) bytes in all. Byte k of the input codes
+// phrase k.
+func testNestedHuffCDIC(limit int) *huffCDIC {
+	h := &huffCDIC{left: limit}
+	for b := range h.codes {
+		h.codes[b] = huffCode{length: 8, term: true, maxCode: uint64(2*b)<<24 | 0xFFFFFF}
+	}
+	h.phrases = []huffPhrase{{data: []byte("ab"), expanded: true}}
+	for k := 1; k <= 30; k++ {
+		h.phrases = append(h.phrases, huffPhrase{data: []byte{byte(k - 1), byte(k - 1)}})
+	}
+	return h
+}
+
+func TestHuffCDIC_OutputLimit(t *testing.T) {
+	out, err := testNestedHuffCDIC(1 << 20).decompress([]byte{10})
+	require.NoError(t, err)
+	assert.Equal(t, strings.Repeat("ab", 1<<10), string(out))
+
+	_, err = testNestedHuffCDIC(1 << 20).decompress([]byte{30})
+	assert.ErrorIs(t, err, errMobiCorrupt)
+}
+
+func TestPalmDOCDecompress(t *testing.T) {
+	// Literal, literal run, space pair and a back reference of 5 bytes
+	// at distance 3.
+	out, err := palmDOCDecompress([]byte{'a', 2, 0xD0, 0xAF, 0xE2, 'c', 0x80, 3<<3 | 2})
+	require.NoError(t, err)
+	assert.Equal(t, "aЯ bc bc b", string(out))
+
+	_, err = palmDOCDecompress([]byte{0x80, 0x08})
+	assert.Error(t, err)
+	_, err = palmDOCDecompress([]byte{5, 'a'})
+	assert.Error(t, err)
+}
+
+func TestTrimTrailingEntries(t *testing.T) {
+	rec := []byte("text\xE2\x82\xAC\x03entry\x86")
+	assert.Equal(t, "text", string(trimTrailingEntries(rec, 3)))
+	assert.Equal(t, "text\xE2\x82\xAC\x03", string(trimTrailingEntries(rec, 2)))
+	assert.Nil(t, trimTrailingEntries([]byte{0x90}, 2))
+}
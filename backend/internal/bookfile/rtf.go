@@ -0,0 +1,550 @@
+package bookfile
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+)
+
+// rtfDest is the destination text of an RTF group goes to.
+type rtfDest int
+
+const (
+	rtfBody rtfDest = iota
+	rtfSkip
+	rtfFontTable
+	rtfStylesheet
+	rtfInfo
+	rtfTitle
+	rtfAuthor
+	rtfPicture
+	// rtfKeep marks destinations whose content is read as body text.
+	rtfKeep
+)
+
+// rtfDestinations maps the destination control words handled; groups of
+// other destinations marked ignorable (\*) are skipped.
+var rtfDestinations = map[string]rtfDest{
+	"fonttbl": rtfFontTable, "stylesheet": rtfStylesheet, "info": rtfInfo,
+	"title": rtfTitle, "author": rtfAuthor, "pict": rtfPicture,
+	"shppict": rtfKeep, "field": rtfKeep, "fldrslt": rtfKeep,
+	"colortbl": rtfSkip, "fldinst": rtfSkip, "nonshppict": rtfSkip, "object": rtfSkip,
+	"header": rtfSkip, "headerl": rtfSkip, "headerr": rtfSkip, "headerf": rtfSkip,
+	"footer": rtfSkip, "footerl": rtfSkip, "footerr": rtfSkip, "footerf": rtfSkip,
+	"footnote": rtfSkip, "annotation": rtfSkip, "listtable": rtfSkip, "listoverridetable": rtfSkip,
+	"listtext": rtfSkip, "pntext": rtfSkip, "rsidtbl": rtfSkip, "revtbl": rtfSkip, "filetbl": rtfSkip,
+	"subject": rtfSkip, "keywords": rtfSkip, "comment": rtfSkip, "doccomm": rtfSkip,
+	"operator": rtfSkip, "company": rtfSkip, "manager": rtfSkip, "category": rtfSkip,
+	"ftnsep": rtfSkip, "ftnsepc": rtfSkip, "ftncn": rtfSkip, "aftnsep": rtfSkip, "aftnsepc": rtfSkip,
+	"template": rtfSkip, "xe": rtfSkip, "tc": rtfSkip, "txe": rtfSkip,
+}
+
+// rtfCharsets maps font charsets to code pages; 0 stands for the document
+// code page.
+var rtfCharsets = map[int]int{
+	0: 1252, 1: 0, 161: 1253, 162: 1254, 177: 1255, 178: 1256, 186: 1257, 163: 1258, 204: 1251, 222: 874, 238: 1250,
+}
+
+// rtfHeadingStyle matches the names of heading styles, in English or as
+// named by Russian versions of Word.
+var rtfHeadingStyle = regexp.MustCompile(`^(?i:heading|заголовок)\s*([1-9])$`)
+
+// rtfSymbols are control words that stand for a character.
+var rtfSymbols = map[string]string{
+	"emdash": "—", "endash": "–", "bullet": "•", "lquote": "‘", "rquote": "’",
+	"ldblquote": "“", "rdblquote": "”", "emspace": " ", "enspace": " ", "qmspace": " ", "tab": " ",
+}
+
+// RTFConverter converts RTF documents: text with bold, italic, underline,
+// strike-through and super- and subscript, tables and PNG and JPEG pictures.
+// Paragraphs with an outline level, directly or through a heading style,
+// become headings that split the document into chapters. Text in 8-bit
+// code pages (\'hh escapes) is decoded with the charset of its font or the
+// document code page.
+type RTFConverter struct {
+	htmlBook
+}
+
+func (c *RTFConverter) Parse(data []byte, bookID int64) error {
+	if !bytes.HasPrefix(data, []byte(`{\rtf`)) {
+		return fmt.Errorf("not an RTF document")
+	}
+	p := &rtfParser{
+		data:         data,
+		ansiCodepage: 1252,
+		fonts:        make(map[int]int),
+		styles:       make(map[int]int),
+		state:        rtfState{outline: -1, uc: 1},
+	}
+	p.parse()
+
+	body, err := parseHTMLBody(p.out.String())
+	if err != nil {
+		return err
+	}
+	meta := BookMetadata{
+		Title:  strings.TrimSpace(p.title.String()),
+		Author: strings.TrimSpace(p.author.String()),
+		Format: "rtf",
+	}
+	// Without a title in \info, a lone top-level heading is the title.
+	return c.build(bookID, meta, body, htmlBookOptions{titleHeading: meta.Title == ""})
+}
+
+type rtfFormat struct {
+	bold, italic, underline, strike bool
+	// vert is 1 for superscript and -1 for subscript.
+	vert int
+}
+
+// rtfState is the state kept per group.
+type rtfState struct {
+	dest   rtfDest
+	format rtfFormat
+	font   int
+	uc     int
+	// Paragraph properties.
+	style   int
+	outline int
+	inTable bool
+}
+
+type rtfParser struct {
+	data  []byte
+	pos   int
+	state rtfState
+	stack []rtfState
+
+	ansiCodepage int
+	// fonts maps font numbers to code pages.
+	fonts map[int]int
+	// styles maps paragraph style numbers to outline levels.
+	styles map[int]int
+	// The font or style being defined.
+	defFont         int
+	defStyle        int
+	defStyleOutline int
+	defStyleName    strings.Builder
+
+	// ignorable is set after \* until the next control word.
+	ignorable bool
+	// skipChars counts the fallback characters left to skip after \u.
+	skipChars int
+	// text holds bytes not yet decoded.
+	text []byte
+
+	run       strings.Builder
+	runFormat rtfFormat
+	para      strings.Builder
+	paraText  bool
+	cell      strings.Builder
+	row       []string
+	table     strings.Builder
+	out       strings.Builder
+
+	title, author strings.Builder
+	pictType      string
+	pict          []byte
+}
+
+func (p *rtfParser) parse() {
+	for p.pos < len(p.data) {
+		c := p.data[p.pos]
+		p.pos++
+		switch c {
+		case '{':
+			p.flushText()
+			p.stack = append(p.stack, p.state)
+			if p.state.dest == rtfStylesheet {
+				p.defStyle, p.defStyleOutline = 0, -1
+				p.defStyleName.Reset()
+			}
+		case '}':
+			p.flushText()
+			if len(p.stack) == 0 {
+				p.pos = len(p.data)
+				break
+			}
+			p.endGroup()
+		case '\\':
+			p.control()
+		case '\r', '\n':
+		default:
+			p.addByte(c)
+		}
+	}
+	p.flushText()
+	p.endParagraph()
+	p.closeTable()
+}
+
+func (p *rtfParser) endGroup() {
+	inner := p.state
+	p.state = p.stack[len(p.stack)-1]
+	p.stack = p.stack[:len(p.stack)-1]
+	p.skipChars = 0
+	switch {
+	case inner.dest == rtfStylesheet && p.state.dest == rtfStylesheet:
+		name := strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(p.defStyleName.String()), ";"))
+		if p.defStyleOutline < 0 {
+			if m := rtfHeadingStyle.FindStringSubmatch(name); m != nil {
+				p.defStyleOutline = int(m[1][0] - '1')
+			}
+		}
+		if p.defStyle >= 0 && p.defStyleOutline >= 0 && p.defStyleOutline < 9 {
+			p.styles[p.defStyle] = p.defStyleOutline
+		}
+	case inner.dest == rtfPicture && p.state.dest != rtfPicture:
+		p.picture()
+	}
+}
+
+// control reads a control word or symbol after a backslash.
+func (p *rtfParser) control() {
+	if p.pos >= len(p.data) {
+		return
+	}
+	c := p.data[p.pos]
+	if !isASCIILetter(c) {
+		p.pos++
+		p.symbol(c)
+		return
+	}
+	start := p.pos
+	for p.pos < len(p.data) && isASCIILetter(p.data[p.pos]) && p.pos-start < 32 {
+		p.pos++
+	}
+	word := string(p.data[start:p.pos])
+	param, hasParam := 0, false
+	neg := p.pos < len(p.data) && p.data[p.pos] == '-'
+	if neg {
+		p.pos++
+	}
+	for digits := 0; p.pos < len(p.data) && p.data[p.pos] >= '0' && p.data[p.pos] <= '9' && digits < 10; digits++ {
+		param = param*10 + int(p.data[p.pos]-'0')
+		hasParam = true
+		p.pos++
+	}
+	if neg {
+		param = -param
+	}
+	if p.pos < len(p.data) && p.data[p.pos] == ' ' {
+		p.pos++
+	}
+	p.flushText()
+	p.word(word, param, hasParam)
+}
+
+func (p *rtfParser) symbol(c byte) {
+	switch c {
+	case '\'':
+		if p.pos+2 <= len(p.data) {
+			if b, err := hex.DecodeString(string(p.data[p.pos : p.pos+2])); err == nil {
+				p.addByte(b[0])
+			}
+			p.pos += 2
+		}
+	case '\\', '{', '}':
+		p.addByte(c)
+	case '~':
+		p.flushText()
+		p.emit("\u00a0")
+	case '_':
+		p.flushText()
+		p.emit("\u2011")
+	case '*':
+		p.ignorable = true
+	case '\r', '\n':
+		p.flushText()
+		p.word("par", 0, false)
+	}
+}
+
+func (p *rtfParser) word(word string, param int, hasParam bool) {
+	ignorable := p.ignorable
+	p.ignorable = false
+	if word == "bin" {
+		// Binary data follows: picture data, or skipped.
+		n := min(max(param, 0), len(p.data)-p.pos)
+		if p.state.dest == rtfPicture {
+			p.pict = append(p.pict, []byte(hex.EncodeToString(p.data[p.pos:p.pos+n]))...)
+		}
+		p.pos += n
+		return
+	}
+	if p.state.dest == rtfSkip {
+		return
+	}
+	if dest, ok := rtfDestinations[word]; ok {
+		switch {
+		case dest == rtfKeep:
+		case (dest == rtfTitle || dest == rtfAuthor) && p.state.dest != rtfInfo:
+		case dest == rtfPicture:
+			p.state.dest = rtfPicture
+			p.pictType, p.pict = "", nil
+		default:
+			p.state.dest = dest
+		}
+		return
+	}
+	if ignorable {
+		p.state.dest = rtfSkip
+		return
+	}
+
+	on := !hasParam || param != 0
+	switch word {
+	case "ansicpg":
+		p.ansiCodepage = param
+	case "f":
+		if p.state.dest == rtfFontTable {
+			p.defFont = param
+		} else {
+			p.state.font = param
+		}
+	case "fcharset":
+		if cp, ok := rtfCharsets[param]; ok && p.state.dest == rtfFontTable {
+			p.fonts[p.defFont] = cp
+		}
+	case "cpg":
+		if p.state.dest == rtfFontTable {
+			p.fonts[p.defFont] = param
+		}
+	case "s":
+		if p.state.dest == rtfStylesheet {
+			p.defStyle = param
+		} else {
+			p.state.style = param
+		}
+	case "cs", "ds", "ts":
+		if p.state.dest == rtfStylesheet {
+			p.defStyle = -1
+		}
+	case "outlinelevel":
+		if p.state.dest == rtfStylesheet {
+			p.defStyleOutline = param
+		} else {
+			p.state.outline = param
+		}
+	case "pngblip":
+		p.pictType = "image/png"
+	case "jpegblip":
+		p.pictType = "image/jpeg"
+	case "uc":
+		p.state.uc = max(param, 0)
+	case "u":
+		if param < 0 {
+			param += 0x10000
+		}
+		p.emit(string(rune(param)))
+		p.skipChars = p.state.uc
+	case "par", "sect", "page":
+		p.endParagraph()
+	case "pard":
+		p.state.style, p.state.outline, p.state.inTable = 0, -1, false
+	case "intbl":
+		p.state.inTable = true
+	case "cell":
+		p.endCell()
+	case "row":
+		p.endRow()
+	case "line":
+		p.addMarkup("<br/>", false)
+	case "plain":
+		p.state.format = rtfFormat{}
+	case "b":
+		p.state.format.bold = on
+	case "i":
+		p.state.format.italic = on
+	case "ul":
+		p.state.format.underline = on
+	case "ulnone":
+		p.state.format.underline = false
+	case "strike", "striked":
+		p.state.format.strike = on
+	case "super":
+		p.state.format.vert = 1
+	case "sub":
+		p.state.format.vert = -1
+	case "nosupersub":
+		p.state.format.vert = 0
+	default:
+		if s, ok := rtfSymbols[word]; ok {
+			p.emit(s)
+		}
+	}
+}
+
+// addByte adds a byte of text in the current destination.
+func (p *rtfParser) addByte(b byte) {
+	if p.skipChars > 0 {
+		p.skipChars--
+		return
+	}
+	switch p.state.dest {
+	case rtfSkip, rtfFontTable, rtfInfo:
+	case rtfPicture:
+		p.pict = append(p.pict, b)
+	default:
+		p.text = append(p.text, b)
+	}
+}
+
+// flushText decodes the pending bytes with the code page of the current
+// font.
+func (p *rtfParser) flushText() {
+	if len(p.text) == 0 {
+		return
+	}
+	cp := p.fonts[p.state.font]
+	if cp == 0 {
+		cp = p.ansiCodepage
+	}
+	text := decodeWith(codepageEncoding(cp), p.text)
+	p.text = p.text[:0]
+	p.emit(text)
+}
+
+// emit adds decoded text to the current destination.
+func (p *rtfParser) emit(s string) {
+	switch p.state.dest {
+	case rtfBody:
+		if p.run.Len() > 0 && p.runFormat != p.state.format {
+			p.flushRun()
+		}
+		p.runFormat = p.state.format
+		p.run.WriteString(html.EscapeString(s))
+		if strings.TrimSpace(s) != "" {
+			p.paraText = true
+		}
+	case rtfStylesheet:
+		p.defStyleName.WriteString(s)
+	case rtfTitle:
+		p.title.WriteString(s)
+	case rtfAuthor:
+		p.author.WriteString(s)
+	}
+}
+
+// addMarkup adds HTML to the current paragraph.
+func (p *rtfParser) addMarkup(s string, content bool) {
+	if p.state.dest != rtfBody {
+		return
+	}
+	p.flushRun()
+	p.para.WriteString(s)
+	p.paraText = p.paraText || content
+}
+
+func (p *rtfParser) flushRun() {
+	if p.run.Len() == 0 {
+		return
+	}
+	var tags []string
+	f := p.runFormat
+	for _, t := range []struct {
+		on  bool
+		tag string
+	}{{f.bold, "strong"}, {f.italic, "em"}, {f.underline, "u"}, {f.strike, "s"}, {f.vert > 0, "sup"}, {f.vert < 0, "sub"}} {
+		if t.on {
+			tags = append(tags, t.tag)
+		}
+	}
+	for _, tag := range tags {
+		fmt.Fprintf(&p.para, "<%s>", tag)
+	}
+	p.para.WriteString(p.run.String())
+	for i := len(tags) - 1; i >= 0; i-- {
+		fmt.Fprintf(&p.para, "</%s>", tags[i])
+	}
+	p.run.Reset()
+}
+
+// takeParagraph returns the HTML of the current paragraph, if it has
+// content, and starts a new one.
+func (p *rtfParser) takeParagraph() (string, bool) {
+	p.flushRun()
+	content, hasText := p.para.String(), p.paraText
+	p.para.Reset()
+	p.paraText = false
+	return content, hasText
+}
+
+func (p *rtfParser) endParagraph() {
+	content, ok := p.takeParagraph()
+	if p.state.inTable {
+		if ok {
+			fmt.Fprintf(&p.cell, "<p>%s</p>", content)
+		}
+		return
+	}
+	p.closeTable()
+	if !ok {
+		return
+	}
+	outline := p.state.outline
+	if outline < 0 {
+		if level, ok := p.styles[p.state.style]; ok {
+			outline = level
+		}
+	}
+	if outline >= 0 && outline < 9 {
+		level := min(outline+1, 6)
+		fmt.Fprintf(&p.out, "<h%d>%s</h%d>\n", level, content, level)
+	} else {
+		fmt.Fprintf(&p.out, "<p>%s</p>\n", content)
+	}
+}
+
+func (p *rtfParser) endCell() {
+	if content, ok := p.takeParagraph(); ok {
+		fmt.Fprintf(&p.cell, "<p>%s</p>", content)
+	}
+	p.row = append(p.row, p.cell.String())
+	p.cell.Reset()
+}
+
+func (p *rtfParser) endRow() {
+	p.table.WriteString("<tr>")
+	for _, cell := range p.row {
+		fmt.Fprintf(&p.table, "<td>%s</td>", cell)
+	}
+	p.table.WriteString("</tr>\n")
+	p.row = nil
+}
+
+func (p *rtfParser) closeTable() {
+	if p.table.Len() == 0 {
+		return
+	}
+	fmt.Fprintf(&p.out, "<table>\n%s</table>\n", p.table.String())
+	p.table.Reset()
+}
+
+// picture adds the picture just read as a data URI, if it is a PNG or
+// JPEG image.
+func (p *rtfParser) picture() {
+	if p.pictType == "" {
+		return
+	}
+	hexData := bytes.Map(func(r rune) rune {
+		if strings.ContainsRune("0123456789abcdefABCDEF", r) {
+			return r
+		}
+		return -1
+	}, p.pict)
+	data := make([]byte, len(hexData)/2)
+	if _, err := hex.Decode(data, hexData[:2*len(data)]); err != nil {
+		return
+	}
+	p.addMarkup(fmt.Sprintf(`<img src="data:%s;base64,%s" alt=""/>`, p.pictType, base64.StdEncoding.EncodeToString(data)), true)
+	p.pict = nil
+}
+
+func isASCIILetter(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
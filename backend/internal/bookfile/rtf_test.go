@@ -0,0 +1,88 @@
+package bookfile
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testRTF = `{\rtf1\ansi\ansicpg1251\deff0
+{\fonttbl{\f0\froman\fcharset204 Times New Roman;}{\f1\fswiss\fcharset0 Arial;}}
+{\colortbl;\red0\green0\blue0;}
+{\stylesheet{\s0 Normal;}{\s1\sbasedon0 heading 1;}{\s2\outlinelevel1 Subhead;}}
+{\info{\title \'c7\'e0\'ef\'e8\'f1\'ea\'e8}{\author Ivan Petrov}}
+{\header \pard Running header\par}
+\pard\s1 \'c3\'eb\'e0\'e2\'e0 1\par
+\pard \'d2\'e5\'ea\'f1\'f2 {\b \'e6\'e8\'f0\'ed\'fb\'e9} \i \'ea\'f3\'f0\'f1\'e8\'e2\i0 , H{\sub 2}O\super 2\nosupersub .\par
+\pard\uc1 \u1070?\u1085?\u1080?\u1082?\u1086?\u1076?\emdash \ldblquote quoted\rdblquote\par
+{\*\unknowndest hidden}{\field{\*\fldinst HYPERLINK "x"}{\fldrslt link}}\par
+\pard\s2 Section\par
+\pard\intbl A1\cell B1\cell\row
+\pard\intbl A2\cell B2\cell\row
+\pard {\f1 Caf\'e9}\line next\par
+{\pict\pngblip\picw1\pich1 PICT}\par
+}`
+
+func parseTestRTF(t *testing.T, doc string) *RTFConverter {
+	t.Helper()
+	conv, err := GetConverter("rtf")
+	require.NoError(t, err)
+	require.NoError(t, conv.Parse([]byte(doc), 4))
+	return conv.(*RTFConverter)
+}
+
+func TestRTFConverter_Content(t *testing.T) {
+	conv := parseTestRTF(t, strings.Replace(testRTF, "PICT", hex.EncodeToString(testPageImage(t, 2)), 1))
+
+	content := conv.Content()
+	assert.Equal(t, BookMetadata{Title: "Записки", Author: "Ivan Petrov", Format: "rtf"}, content.Metadata)
+	assert.Equal(t, []TOCEntry{
+		{ID: "ch1", Title: "Глава 1"},
+		{ID: "ch2", Title: "Section", Level: 1},
+	}, content.TOC)
+
+	ch, err := conv.Chapter("ch1")
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"Глава 1",
+		"Текст жирный курсив, H2O2.",
+		"Юникод—“quoted”",
+		"link",
+	}, ParagraphTexts(ch.HTML))
+	assert.Contains(t, ch.HTML, "<strong>жирный</strong>")
+	assert.Contains(t, ch.HTML, "<em>курсив</em>")
+	assert.Contains(t, ch.HTML, "H<sub>2</sub>O<sup>2</sup>.")
+	assert.NotContains(t, ch.HTML, "Running header")
+	assert.NotContains(t, ch.HTML, "hidden")
+
+	ch, err = conv.Chapter("ch2")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Section", "A1", "B1", "A2", "B2", "Cafénext"}, ParagraphTexts(ch.HTML))
+	assert.Contains(t, ch.HTML, "<td><p data-p=\"1\">A1</p></td>")
+	assert.Contains(t, ch.HTML, "Café<br/>next")
+	assert.Contains(t, ch.HTML, "/api/books/4/image/img1.png?v=")
+
+	img, err := conv.Image("img1.png")
+	require.NoError(t, err)
+	assert.Equal(t, testPageImage(t, 2), img.Data)
+}
+
+func TestRTFConverter_NoHeadings(t *testing.T) {
+	conv := parseTestRTF(t, `{\rtf1\ansi Hello \{world\}\par Second\par}`)
+
+	assert.Equal(t, []TOCEntry{{ID: "ch1", Title: "Начало"}}, conv.Content().TOC)
+	ch, err := conv.Chapter("ch1")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Hello {world}", "Second"}, ParagraphTexts(ch.HTML))
+}
+
+func TestRTFConverter_Invalid(t *testing.T) {
+	conv, err := GetConverter("rtf")
+	require.NoError(t, err)
+	assert.Error(t, conv.Parse([]byte("plain text"), 1))
+	// Unbalanced groups and truncated escapes are read as far as they go.
+	assert.NoError(t, conv.Parse([]byte(`{\rtf1 {\b text\'`), 1))
+}
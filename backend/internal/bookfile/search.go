@@ -0,0 +1,130 @@
+package bookfile
+
+import (
+	"fmt"
+	"slices"
+	"unicode"
+)
+
+// snippetRadius is the number of characters of context kept on each side
+// of a search match.
+const snippetRadius = 60
+
+// ChapterText is the plain text of a chapter, by numbered paragraph.
+type ChapterText struct {
+	ID         string   `json:"id"`
+	Title      string   `json:"title"`
+	Paragraphs []string `json:"paragraphs"`
+}
+
+// ExtractText returns the plain text of every chapter of a parsed book.
+func ExtractText(conv BookConverter) ([]ChapterText, error) {
+	content := conv.Content()
+	chapters := make([]ChapterText, 0, len(content.ChapterIDs))
+	for _, id := range content.ChapterIDs {
+		ch, err := conv.Chapter(id)
+		if err != nil {
+			return nil, fmt.Errorf("chapter %q: %w", id, err)
+		}
+		chapters = append(chapters, ChapterText{ID: id, Title: ch.Title, Paragraphs: ParagraphTexts(ch.HTML)})
+	}
+	return chapters, nil
+}
+
+// TextMatch is an occurrence of a search query in the paragraphs of a
+// chapter. Offsets and lengths are in UTF-16 code units, like locators.
+type TextMatch struct {
+	Paragraph int
+	Offset    int
+	Length    int
+	// Snippet is the text around the match; the match starts at
+	// SnippetOffset within it.
+	Snippet       string
+	SnippetOffset int
+}
+
+// foldSearchRune maps a character to its search form: lower case, with ё
+// treated as е. Every character maps to exactly one, so positions in
+// folded text are positions in the original.
+func foldSearchRune(r rune) rune {
+	r = unicode.ToLower(r)
+	if r == 'ё' {
+		return 'е'
+	}
+	return r
+}
+
+func foldSearchText(s string) []rune {
+	runes := []rune(s)
+	for i, r := range runes {
+		runes[i] = foldSearchRune(r)
+	}
+	return runes
+}
+
+// FindMatches returns the non-overlapping occurrences of query in the
+// paragraphs, in text order, ignoring case and the difference between ё and
+// е. It stops after limit matches when limit is positive.
+func FindMatches(paragraphs []string, query string, limit int) []TextMatch {
+	q := foldSearchText(query)
+	if len(q) == 0 {
+		return nil
+	}
+
+	var matches []TextMatch
+	for p, text := range paragraphs {
+		orig := []rune(text)
+		folded := foldSearchText(text)
+		for i := 0; i+len(q) <= len(folded); i++ {
+			if folded[i] != q[0] || !slices.Equal(folded[i:i+len(q)], q) {
+				continue
+			}
+			m := TextMatch{
+				Paragraph: p,
+				Offset:    utf16Len(string(orig[:i])),
+				Length:    utf16Len(string(orig[i : i+len(q)])),
+			}
+			m.Snippet, m.SnippetOffset = snippet(orig, i, i+len(q))
+			matches = append(matches, m)
+			if limit > 0 && len(matches) >= limit {
+				return matches
+			}
+			i += len(q) - 1
+		}
+	}
+	return matches
+}
+
+// snippet cuts the text around runes [start, end) at word boundaries and
+// returns it with the UTF-16 offset of the match in it.
+func snippet(text []rune, start, end int) (string, int) {
+	from := max(start-snippetRadius, 0)
+	if from > 0 {
+		// Do not begin in the middle of a word
+		for i := from; i < start; i++ {
+			if unicode.IsSpace(text[i]) {
+				from = i + 1
+				break
+			}
+		}
+	}
+	to := min(end+snippetRadius, len(text))
+	if to < len(text) {
+		for i := to; i > end; i-- {
+			if unicode.IsSpace(text[i-1]) {
+				to = i - 1
+				break
+			}
+		}
+	}
+
+	prefix, suffix := "", ""
+	if from > 0 {
+		prefix = "…"
+	}
+	if to < len(text) {
+		suffix = "…"
+	}
+	s := prefix + string(text[from:to]) + suffix
+	return s, utf16Len(prefix + string(text[from:start]))
+}
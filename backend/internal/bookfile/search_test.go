@@ -0,0 +1,63 @@
+package bookfile
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFindMatches_CaseAndYo(t *testing.T) {
+	paragraphs := []string{
+		"Глава первая",
+		"Ёлка стояла в углу, а ёж — под ёлкой.",
+	}
+
+	matches := FindMatches(paragraphs, "ЕЛК", 0)
+	require.Len(t, matches, 2)
+
+	assert.Equal(t, 1, matches[0].Paragraph)
+	assert.Equal(t, 0, matches[0].Offset)
+	assert.Equal(t, 3, matches[0].Length)
+	assert.Equal(t, paragraphs[1], matches[0].Snippet)
+	assert.Equal(t, 0, matches[0].SnippetOffset)
+
+	assert.Equal(t, 1, matches[1].Paragraph)
+	assert.Equal(t, 31, matches[1].Offset)
+	assert.Equal(t, 31, matches[1].SnippetOffset)
+}
+
+func TestFindMatches_UTF16Offsets(t *testing.T) {
+	matches := FindMatches([]string{"😀 слово"}, "слово", 0)
+	require.Len(t, matches, 1)
+	assert.Equal(t, 3, matches[0].Offset)
+	assert.Equal(t, 5, matches[0].Length)
+}
+
+func TestFindMatches_NonOverlapping(t *testing.T) {
+	matches := FindMatches([]string{"аааа"}, "аа", 0)
+	require.Len(t, matches, 2)
+	assert.Equal(t, 0, matches[0].Offset)
+	assert.Equal(t, 2, matches[1].Offset)
+}
+
+func TestFindMatches_Limit(t *testing.T) {
+	matches := FindMatches([]string{"да да да", "да"}, "да", 2)
+	assert.Len(t, matches, 2)
+}
+
+func TestFindMatches_EmptyQuery(t *testing.T) {
+	assert.Nil(t, FindMatches([]string{"текст"}, "", 0))
+}
+
+func TestFindMatches_Snippet(t *testing.T) {
+	text := strings.Repeat("слово ", 30) + "искомое " + strings.Repeat("текст ", 30)
+	matches := FindMatches([]string{text}, "искомое", 0)
+	require.Len(t, matches, 1)
+
+	m := matches[0]
+	assert.True(t, strings.HasPrefix(m.Snippet, "…слово"))
+	assert.True(t, strings.HasSuffix(m.Snippet, "текст…"))
+	assert.Equal(t, "искомое", string([]rune(m.Snippet)[m.SnippetOffset:m.SnippetOffset+7]))
+}
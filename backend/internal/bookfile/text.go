@@ -0,0 +1,140 @@
+package bookfile
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	xhtml "golang.org/x/net/html"
+)
+
+// maxTextHeadingLength is the longest line (in characters) taken for a
+// chapter heading.
+const maxTextHeadingLength = 80
+
+// Patterns of heading lines. A keyword must be followed by a number or a
+// number word, then by the end of the line, punctuation or a capitalized
+// title, so that wrapped lines such as "Part of the…" are not headings.
+const (
+	textHeadingNumber = `(?:\d{1,3}|[IVXLC]{1,7}|(?i:перв|втор|трет|четв[её]рт|пят|шест|седьм|восьм|девят|десят|одиннадцат|двенадцат|двадцат|тридцат|` +
+		`one|two|three|four|five|six|seven|eight|nine|ten|eleven|twelve|twenty|thirty|first|second|third|fifth|eighth|ninth|twelfth|\S+teen)\S*)`
+	textHeadingTail = `(?:$|[.:—–-]|\s+[\p{Lu}\d«"])`
+)
+
+var (
+	// textPartHeading matches headings of parts, which group chapters.
+	textPartHeading = regexp.MustCompile(`^(?i:часть|книга|том|part|book|volume)\s+` + textHeadingNumber + textHeadingTail)
+	// textChapterHeading matches chapter headings ("Глава 5", "Глава
+	// пятая. Встреча", "CHAPTER V. THE RETURN"), prologues and the like, and
+	// bare chapter numbers.
+	textChapterHeading = regexp.MustCompile(`^(?:(?i:глава|chapter)\s+` + textHeadingNumber + textHeadingTail +
+		`|(?i:пролог|эпилог|предисловие|послесловие|вступление|prologue|epilogue|preface|afterword|introduction)(?:$|[.:—–-])` +
+		`|[IVXLC]{1,7}\.?$|\d{1,3}\.?$)`)
+)
+
+// TextConverter converts plain text files. Paragraphs are separated by blank
+// lines or indented first lines, or are single lines in files that have
+// neither; lines such as "Глава 1" or "Chapter One" start chapters.
+type TextConverter struct {
+	htmlBook
+}
+
+func (c *TextConverter) Parse(data []byte, bookID int64) error {
+	body, err := textBody(textParagraphs(decodeText(data)))
+	if err != nil {
+		return err
+	}
+	return c.build(bookID, BookMetadata{Format: "txt"}, body, htmlBookOptions{})
+}
+
+// textBody renders paragraphs of plain text, with heading lines as
+// headings.
+func textBody(paragraphs []string) (*xhtml.Node, error) {
+	var b strings.Builder
+	for _, p := range paragraphs {
+		tag := "p"
+		switch textHeadingLevel(p) {
+		case 1:
+			tag = "h1"
+		case 2:
+			tag = "h2"
+		}
+		fmt.Fprintf(&b, "<%s>%s</%s>\n", tag, html.EscapeString(p), tag)
+	}
+	return parseHTMLBody(b.String())
+}
+
+// textParagraphs splits text into paragraphs with whitespace collapsed.
+// Lines are joined into paragraphs only if the text looks hard-wrapped,
+// that is, has blank lines or indented lines between runs of other lines.
+func textParagraphs(text string) []string {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	text = strings.ReplaceAll(text, "\r", "\n")
+	lines := strings.Split(text, "\n")
+
+	blank, indented, nonEmpty := 0, 0, 0
+	for _, line := range lines {
+		switch {
+		case strings.TrimSpace(line) == "":
+			blank++
+		case isIndented(line):
+			indented++
+			nonEmpty++
+		default:
+			nonEmpty++
+		}
+	}
+	wrapped := blank*10 >= nonEmpty || (indented*10 >= nonEmpty && indented*10 < nonEmpty*9)
+
+	var paragraphs []string
+	var current []string
+	flush := func() {
+		if len(current) > 0 {
+			paragraphs = append(paragraphs, strings.Join(strings.Fields(strings.Join(current, " ")), " "))
+			current = nil
+		}
+	}
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" {
+			flush()
+			continue
+		}
+		if textHeadingLevel(trimmed) > 0 {
+			flush()
+			paragraphs = append(paragraphs, trimmed)
+			continue
+		}
+		if !wrapped || isIndented(line) {
+			flush()
+		}
+		current = append(current, trimmed)
+	}
+	flush()
+	return paragraphs
+}
+
+// textHeadingLevel returns 1 for part headings, 2 for chapter headings and
+// 0 for other lines. Headings are short and start with a capital letter or a
+// digit.
+func textHeadingLevel(line string) int {
+	first, _ := utf8.DecodeRuneInString(line)
+	if utf8.RuneCountInString(line) > maxTextHeadingLength || strings.HasSuffix(line, ",") ||
+		!(unicode.IsUpper(first) || unicode.IsDigit(first)) {
+		return 0
+	}
+	switch {
+	case textPartHeading.MatchString(line):
+		return 1
+	case textChapterHeading.MatchString(line):
+		return 2
+	}
+	return 0
+}
+
+func isIndented(line string) bool {
+	return strings.HasPrefix(line, "\t") || strings.HasPrefix(line, "  ")
+}
@@ -0,0 +1,196 @@
+package bookfile
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/text/encoding/charmap"
+)
+
+const testText = `Глава 1. Начало
+
+    Был тёплый вечер. Герои собрались
+у костра и долго молчали.
+    Потом кто-то запел.
+
+Глава вторая
+
+    Наутро пошёл дождь.
+`
+
+func parseTestText(t *testing.T, text string) *TextConverter {
+	t.Helper()
+	conv, err := GetConverter("txt")
+	require.NoError(t, err)
+	require.NoError(t, conv.Parse([]byte(text), 3))
+	return conv.(*TextConverter)
+}
+
+func TestTextConverter_Chapters(t *testing.T) {
+	conv := parseTestText(t, testText)
+
+	content := conv.Content()
+	assert.Equal(t, "txt", content.Metadata.Format)
+	assert.Equal(t, []string{"ch1", "ch2"}, content.ChapterIDs)
+	assert.Equal(t, []TOCEntry{
+		{ID: "ch1", Title: "Глава 1. Начало"},
+		{ID: "ch2", Title: "Глава вторая"},
+	}, content.TOC)
+
+	ch, err := conv.Chapter("ch1")
+	require.NoError(t, err)
+	assert.Equal(t, "Глава 1. Начало", ch.Title)
+	assert.Equal(t, []string{
+		"Глава 1. Начало",
+		"Был тёплый вечер. Герои собрались у костра и долго молчали.",
+		"Потом кто-то запел.",
+	}, ParagraphTexts(ch.HTML))
+	assert.Contains(t, ch.HTML, `<h2 class="chapter-title" data-p="0">Глава 1. Начало</h2>`)
+
+	_, err = conv.Chapter("ch3")
+	assert.Error(t, err)
+	_, err = conv.Image("img1.png")
+	assert.Error(t, err)
+}
+
+func TestTextConverter_PartsAndPreamble(t *testing.T) {
+	text := "Посвящается маме.\n\nЧасть первая\n\nГлава 1\n\nТекст.\n\nГлава 2\n\nЕщё текст.\n\nЧасть II\n\nIII\n\nКонец.\n"
+	conv := parseTestText(t, text)
+
+	assert.Equal(t, []TOCEntry{
+		{ID: "ch1", Title: "Начало"},
+		{ID: "ch2", Title: "Часть первая"},
+		{ID: "ch3", Title: "Глава 1", Level: 1},
+		{ID: "ch4", Title: "Глава 2", Level: 1},
+		{ID: "ch5", Title: "Часть II"},
+		{ID: "ch6", Title: "III", Level: 1},
+	}, conv.Content().TOC)
+}
+
+func TestTextConverter_EscapesMarkup(t *testing.T) {
+	conv := parseTestText(t, "<script>alert(1)</script> & <b>bold</b>\n")
+	ch, err := conv.Chapter("ch1")
+	require.NoError(t, err)
+	assert.NotContains(t, ch.HTML, "<script>")
+	assert.Equal(t, []string{"<script>alert(1)</script> & <b>bold</b>"}, ParagraphTexts(ch.HTML))
+}
+
+func TestTextConverter_SplitsLongChapters(t *testing.T) {
+	var b strings.Builder
+	for i := range 3000 {
+		fmt.Fprintf(&b, "Абзац номер %d, в котором ничего не происходит, но текста в нём достаточно много.\n", i)
+	}
+	conv := parseTestText(t, b.String())
+
+	content := conv.Content()
+	require.Greater(t, len(content.ChapterIDs), 1)
+	assert.Equal(t, "ch1-part2", content.ChapterIDs[1])
+	assert.Equal(t, "Начало (2)", content.TOC[1].Title)
+	assert.Equal(t, 1, content.TOC[1].Level)
+
+	first, err := conv.Chapter("ch1")
+	require.NoError(t, err)
+	next := len(ParagraphLengths(first.HTML))
+	assert.Equal(t, ChapterPart{Section: "ch1", FirstParagraph: next}, content.Parts["ch1-part2"])
+	second, err := conv.Chapter("ch1-part2")
+	require.NoError(t, err)
+	assert.Contains(t, second.HTML, fmt.Sprintf(`data-p="%d"`, next))
+	for _, id := range content.ChapterIDs {
+		assert.LessOrEqual(t, content.ChapterSizes[id], maxChapterSize)
+	}
+}
+
+func TestTextParagraphs(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{
+			name: "line per paragraph",
+			text: "Первый абзац.\nВторой абзац.\r\nТретий абзац.",
+			want: []string{"Первый абзац.", "Второй абзац.", "Третий абзац."},
+		},
+		{
+			name: "blank-separated wrapped paragraphs",
+			text: "First paragraph\nwrapped here.\n\nSecond\nparagraph.\n",
+			want: []string{"First paragraph wrapped here.", "Second paragraph."},
+		},
+		{
+			name: "indented first lines",
+			text: "   Первый абзац\nпродолжается.\n   Второй\nабзац.\nИ ещё строка.\n",
+			want: []string{"Первый абзац продолжается.", "Второй абзац. И ещё строка."},
+		},
+		{
+			name: "heading without blank lines",
+			text: "Конец главы\nпервой.\n\nCHAPTER II. THE STORM\nIt rained\nall night.\n",
+			want: []string{"Конец главы первой.", "CHAPTER II. THE STORM", "It rained all night."},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, textParagraphs(tt.text))
+		})
+	}
+}
+
+func TestTextHeadingLevel(t *testing.T) {
+	tests := []struct {
+		line string
+		want int
+	}{
+		{"Глава 1", 2},
+		{"ГЛАВА XII", 2},
+		{"Глава пятая. Встреча", 2},
+		{"Chapter One", 2},
+		{"CHAPTER V. THE RETURN", 2},
+		{"Chapter 5 The Return", 2},
+		{"Пролог", 2},
+		{"Epilogue.", 2},
+		{"XIV", 2},
+		{"12.", 2},
+		{"Часть первая", 1},
+		{"Part Two", 1},
+		{"Том 3", 1},
+		{"Part of the problem was the rain.", 0},
+		{"глава семьи сказала", 0},
+		{"Главная мысль была простой", 0},
+		{"Prologue to the story of how we met", 0},
+		{"Глава 1,", 0},
+		{"1234", 0},
+		{"Обычная строка", 0},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, textHeadingLevel(tt.line), tt.line)
+	}
+}
+
+func TestTextConverter_Empty(t *testing.T) {
+	conv := &TextConverter{}
+	assert.Error(t, conv.Parse([]byte("\n\n   \n"), 1))
+}
+
+func TestDecodeText(t *testing.T) {
+	text := "Съешь же ещё этих мягких французских булок, да выпей чаю."
+	encode := func(enc *charmap.Charmap) []byte {
+		data, err := enc.NewEncoder().Bytes([]byte(text))
+		require.NoError(t, err)
+		return data
+	}
+
+	assert.Equal(t, text, decodeText([]byte(text)))
+	assert.Equal(t, text, decodeText(append([]byte{0xEF, 0xBB, 0xBF}, text...)))
+	assert.Equal(t, text, decodeText(encode(charmap.Windows1251)))
+	assert.Equal(t, text, decodeText(encode(charmap.KOI8R)))
+	assert.Equal(t, text, decodeText(encode(charmap.CodePage866)))
+
+	utf16 := []byte{0xFF, 0xFE}
+	for _, r := range "Привет" {
+		utf16 = append(utf16, byte(r), byte(r>>8))
+	}
+	assert.Equal(t, "Привет", decodeText(utf16))
+	assert.Equal(t, "Ça coûte", decodeText([]byte{0xC7, 'a', ' ', 'c', 'o', 0xFB, 't', 'e'}))
+}
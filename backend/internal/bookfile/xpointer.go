@@ -0,0 +1,137 @@
+package bookfile
+
+import (
+	"fmt"
+	"strings"
+)
+
+// xpathStep returns one step of a crengine element path for the i-th
+// (0-based) of count siblings with the same name. crengine omits the index
+// when an element has no same-named siblings.
+func xpathStep(name string, i, count int) string {
+	if count <= 1 {
+		return "/" + name
+	}
+	return fmt.Sprintf("/%s[%d]", name, i+1)
+}
+
+// XPointerMap holds the crengine element path of every paragraph of a
+// book, so that positions can be mapped without the parsed document. It is
+// kept in the reader cache.
+type XPointerMap struct {
+	// Chapter sections by chapter ID; split sections are under the ID of
+	// their first part
+	Sections map[string]XPointerSection `json:"sections"`
+	// Parts of split sections, as in BookContent
+	Parts         map[string]ChapterPart `json:"parts,omitempty"`
+	FormatVersion int                    `json:"formatVersion"`
+}
+
+// XPointerSection is the element path of a chapter section and of the
+// paragraphs rendered from it, indexed by paragraph.
+type XPointerSection struct {
+	Path       string   `json:"path"`
+	Paragraphs []string `json:"paragraphs"`
+}
+
+// XPointerMap returns the element paths of all chapter paragraphs, e.g.
+// /FictionBook/body/section[2]/p[5]. Paragraphs of split sections are
+// numbered across the whole section.
+func (c *FB2Converter) XPointerMap() *XPointerMap {
+	m := &XPointerMap{
+		Sections:      make(map[string]XPointerSection, len(c.sectionPaths)),
+		Parts:         c.content.Parts,
+		FormatVersion: FormatVersion,
+	}
+	for id, path := range c.sectionPaths {
+		var pc paragraphCounter
+		c.convertSection(c.chapters[id].sec, &pc)
+		m.Sections[id] = XPointerSection{Path: path, Paragraphs: pc.paths}
+	}
+	return m
+}
+
+// XPointer returns the XPointer of the start of a chapter paragraph.
+func (m *XPointerMap) XPointer(chapterID string, paragraph int) (string, error) {
+	sectionID := chapterID
+	if part, ok := m.Parts[chapterID]; ok {
+		sectionID = part.Section
+	}
+	sec, ok := m.Sections[sectionID]
+	if !ok {
+		return "", fmt.Errorf("chapter %q not found", chapterID)
+	}
+	if len(sec.Paragraphs) == 0 {
+		return sec.Path, nil
+	}
+	return sec.Paragraphs[min(max(paragraph, 0), len(sec.Paragraphs)-1)], nil
+}
+
+// ResolveXPointer finds the chapter whose section contains the pointer and
+// the paragraph whose element contains it; for split sections, the part
+// holding the paragraph. Pointers to elements that are not rendered as
+// paragraphs (images, empty lines) resolve to the chapter start.
+func (m *XPointerMap) ResolveXPointer(xpointer string) (string, int, error) {
+	path := elementPath(xpointer)
+
+	sectionID, best := "", -1
+	for id, sec := range m.Sections {
+		if hasPathPrefix(path, sec.Path) && len(sec.Path) > best {
+			sectionID, best = id, len(sec.Path)
+		}
+	}
+	if sectionID == "" {
+		return "", 0, fmt.Errorf("xpointer %q is outside the book's chapters", xpointer)
+	}
+
+	for i, p := range m.Sections[sectionID].Paragraphs {
+		if hasPathPrefix(path, p) {
+			return m.part(sectionID, i), i, nil
+		}
+	}
+	return sectionID, 0, nil
+}
+
+// part returns the part of a split section that holds a paragraph.
+func (m *XPointerMap) part(sectionID string, paragraph int) string {
+	resolved, first := sectionID, 0
+	for id, p := range m.Parts {
+		if p.Section == sectionID && p.FirstParagraph <= paragraph && p.FirstParagraph > first {
+			resolved, first = id, p.FirstParagraph
+		}
+	}
+	return resolved
+}
+
+// elementPath strips the text node and character offset from an XPointer:
+// /a/b[2]/text().15 and /a/b[2].3 both become /a/b[2].
+func elementPath(xpointer string) string {
+	p := strings.TrimSpace(xpointer)
+	if i := strings.Index(p, "/text()"); i >= 0 {
+		p = p[:i]
+	}
+	if i := strings.LastIndexByte(p, '/'); i >= 0 {
+		if j := strings.IndexByte(p[i:], '.'); j >= 0 {
+			p = p[:i+j]
+		}
+	}
+	return strings.TrimSuffix(p, "/")
+}
+
+// hasPathPrefix reports whether path is prefix or lies below it. Bare steps
+// match their first sibling: /section and /section[1] are the same element.
+func hasPathPrefix(path, prefix string) bool {
+	path, prefix = normalizePath(path), normalizePath(prefix)
+	return path == prefix || strings.HasPrefix(path, prefix+"/")
+}
+
+// normalizePath adds the implicit [1] index to every step.
+func normalizePath(p string) string {
+	steps := strings.Split(p, "/")
+	for i, s := range steps {
+		if s != "" && !strings.HasSuffix(s, "]") {
+			steps[i] = s + "[1]"
+		}
+	}
+	return strings.Join(steps, "/")
+}
@@ -0,0 +1,135 @@
+package bookfile
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFB2Converter_XPointer(t *testing.T) {
+	conv := parseTestFB2(t, "complex.fb2", 1)
+	xpm := conv.XPointerMap()
+	ids := conv.Content().ChapterIDs
+
+	tests := []struct {
+		chapter   string
+		paragraph int
+		want      string
+	}{
+		{ids[0], 0, "/FictionBook/body[1]/section[1]/title"},
+		{ids[0], 1, "/FictionBook/body[1]/section[1]/epigraph/p"},
+		{ids[0], 3, "/FictionBook/body[1]/section[1]/p"},
+		{ids[1], 1, "/FictionBook/body[1]/section[1]/section[1]/poem/title"},
+		{ids[1], 2, "/FictionBook/body[1]/section[1]/section[1]/poem/stanza[1]/v[1]"},
+		{ids[1], 7, "/FictionBook/body[1]/section[1]/section[1]/poem/stanza[2]/v[3]"},
+		{ids[2], 1, "/FictionBook/body[1]/section[1]/section[2]/cite/p"},
+		{ids[3], 2, "/FictionBook/body[1]/section[2]/p[2]"},
+		{ids[3], 99, "/FictionBook/body[1]/section[2]/p[2]"},
+	}
+	for _, tt := range tests {
+		xp, err := xpm.XPointer(tt.chapter, tt.paragraph)
+		require.NoError(t, err)
+		assert.Equal(t, tt.want, xp)
+	}
+
+	_, err := xpm.XPointer("missing", 0)
+	assert.Error(t, err)
+}
+
+func TestFB2Converter_ResolveXPointer(t *testing.T) {
+	conv := parseTestFB2(t, "complex.fb2", 1)
+	xpm := conv.XPointerMap()
+	ids := conv.Content().ChapterIDs
+
+	tests := []struct {
+		xpointer  string
+		chapter   string
+		paragraph int
+	}{
+		{"/FictionBook/body[1]/section[2]/p[2]/text().5", ids[3], 2},
+		{"/FictionBook/body[1]/section[2]/p[1]/a/text().1", ids[3], 1},
+		{"/FictionBook/body[1]/section[1]/section[1]/poem/stanza[2]/v[1].0", ids[1], 5},
+		{"/FictionBook/body[1]/section[1]/section[1]/poem[1]/stanza[1]/v[2]/text().3", ids[1], 3},
+		{"/FictionBook/body[1]/section[1]/p/text().0", ids[0], 3},
+		{"/FictionBook/body[1]/section[1]/section[2]", ids[2], 0},
+		{"/FictionBook/body[1]/section[1]/section[2]/p[3]/image", ids[2], 5},
+	}
+	for _, tt := range tests {
+		chapter, paragraph, err := xpm.ResolveXPointer(tt.xpointer)
+		require.NoError(t, err, tt.xpointer)
+		assert.Equal(t, tt.chapter, chapter, tt.xpointer)
+		assert.Equal(t, tt.paragraph, paragraph, tt.xpointer)
+	}
+
+	_, _, err := xpm.ResolveXPointer("/FictionBook/body[2]/section[1]/p/text().0")
+	assert.Error(t, err)
+}
+
+func TestFB2Converter_XPointer_Elements(t *testing.T) {
+	conv := parseTestFB2(t, "elements.fb2", 1)
+	xpm := conv.XPointerMap()
+
+	tests := []struct {
+		chapter   string
+		paragraph int
+		want      string
+	}{
+		{"tables", 2, "/FictionBook/body[1]/section[1]/table/tr[1]/th[1]"},
+		{"tables", 6, "/FictionBook/body[1]/section[1]/table/tr[2]/td[3]"},
+		{"styles", 2, "/FictionBook/body[1]/section[2]/text-author"},
+		{"annotated", 5, "/FictionBook/body[1]/section[3]/annotation/text-author"},
+		{"annotated", 10, "/FictionBook/body[1]/section[3]/cite/text-author[2]"},
+	}
+	for _, tt := range tests {
+		xp, err := xpm.XPointer(tt.chapter, tt.paragraph)
+		require.NoError(t, err)
+		assert.Equal(t, tt.want, xp)
+
+		chapter, paragraph, err := xpm.ResolveXPointer(xp + "/text().0")
+		require.NoError(t, err)
+		assert.Equal(t, tt.chapter, chapter)
+		assert.Equal(t, tt.paragraph, paragraph)
+	}
+}
+
+func TestFB2Converter_XPointerRoundTrip(t *testing.T) {
+	conv := parseTestFB2(t, "complex.fb2", 1)
+	xpm := conv.XPointerMap()
+	for _, id := range conv.Content().ChapterIDs {
+		ch, err := conv.Chapter(id)
+		require.NoError(t, err)
+		for p := range ParagraphLengths(ch.HTML) {
+			xp, err := xpm.XPointer(id, p)
+			require.NoError(t, err)
+			gotID, gotP, err := xpm.ResolveXPointer(xp + "/text().0")
+			require.NoError(t, err)
+			assert.Equal(t, id, gotID, xp)
+			assert.Equal(t, p, gotP, xp)
+		}
+	}
+}
+
+func TestXPointerMap_JSON(t *testing.T) {
+	conv := &FB2Converter{}
+	require.NoError(t, conv.Parse(longSectionFB2(), 1))
+	content := conv.Content()
+
+	data, err := json.Marshal(conv.XPointerMap())
+	require.NoError(t, err)
+	var xpm XPointerMap
+	require.NoError(t, json.Unmarshal(data, &xpm))
+	assert.Equal(t, FormatVersion, xpm.FormatVersion)
+
+	// Split parts resolve from the cached map as from the document
+	for _, id := range content.ChapterIDs {
+		first := content.Parts[id].FirstParagraph
+		xp, err := xpm.XPointer(id, first)
+		require.NoError(t, err)
+		chapter, paragraph, err := xpm.ResolveXPointer(xp + "/text().0")
+		require.NoError(t, err)
+		assert.Equal(t, id, chapter, xp)
+		assert.Equal(t, first, paragraph, xp)
+	}
+}
@@ -25,10 +25,20 @@ type GenreTreeConfig struct {
 	FilePath string `yaml:"file_path"` // Override path to .glst file (empty = use embedded)
 }
 
+// Reader cache backends.
+const (
+	CacheBackendFS       = "fs"
+	CacheBackendMemory   = "memory"
+	CacheBackendPostgres = "postgres"
+)
+
 type ReaderConfig struct {
-	CachePath   string        `yaml:"cache_path"`
-	CacheTTLRaw string        `yaml:"cache_ttl"`
-	CacheTTL    time.Duration `yaml:"-"`
+	CacheBackend string        `yaml:"cache_backend"`
+	CachePath    string        `yaml:"cache_path"`
+	CacheTTLRaw  string        `yaml:"cache_ttl"`
+	CacheTTL     time.Duration `yaml:"-"`
+	MaxSizeRaw   string        `yaml:"cache_max_size"`
+	MaxSize      int64         `yaml:"-"`
 }
 
 type ServerConfig struct {
@@ -99,8 +109,9 @@ func Load(path string) (*Config, error) {
 			LogEvery:  10000,
 		},
 		Reader: ReaderConfig{
-			CachePath: "./cache/books",
-			CacheTTL:  30 * 24 * time.Hour,
+			CacheBackend: CacheBackendFS,
+			CachePath:    "./cache/books",
+			CacheTTL:     30 * 24 * time.Hour,
 		},
 	}
 
@@ -117,6 +128,15 @@ func Load(path string) (*Config, error) {
 		cfg.Reader.CacheTTL = d
 	}
 
+	// Parse cache_max_size (e.g. "10GB", "512MB")
+	if cfg.Reader.MaxSizeRaw != "" {
+		n, err := parseSize(cfg.Reader.MaxSizeRaw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid reader.cache_max_size %q: %w", cfg.Reader.MaxSizeRaw, err)
+		}
+		cfg.Reader.MaxSize = n
+	}
+
 	applyEnvOverrides(cfg)
 
 	if err := cfg.Validate(); err != nil {
@@ -138,6 +158,15 @@ func (c *Config) Validate() error {
 	if c.Database.DBName == "" {
 		return fmt.Errorf("database name is required")
 	}
+	switch c.Reader.CacheBackend {
+	case CacheBackendFS, CacheBackendMemory, CacheBackendPostgres:
+	default:
+		return fmt.Errorf("unknown reader cache backend %q", c.Reader.CacheBackend)
+	}
+	// Without a size limit the memory backend grows until the TTL cleanup
+	if c.Reader.CacheBackend == CacheBackendMemory && c.Reader.MaxSize <= 0 {
+		return fmt.Errorf("reader cache backend %q requires a positive cache_max_size", CacheBackendMemory)
+	}
 	return nil
 }
 
@@ -163,6 +192,9 @@ func applyEnvOverrides(cfg *Config) {
 	if v := os.Getenv("INPX_PATH"); v != "" {
 		cfg.Library.INPXPath = v
 	}
+	if v := os.Getenv("READER_CACHE_BACKEND"); v != "" {
+		cfg.Reader.CacheBackend = v
+	}
 	if v := os.Getenv("READER_CACHE_PATH"); v != "" {
 		cfg.Reader.CachePath = v
 	}
@@ -171,6 +203,11 @@ func applyEnvOverrides(cfg *Config) {
 			cfg.Reader.CacheTTL = d
 		}
 	}
+	if v := os.Getenv("READER_CACHE_MAX_SIZE"); v != "" {
+		if n, err := parseSize(v); err == nil {
+			cfg.Reader.MaxSize = n
+		}
+	}
 }
 
 // parseDuration extends time.ParseDuration with support for "d" (days) suffix.
@@ -188,3 +225,38 @@ func parseDuration(s string) (time.Duration, error) {
 	}
 	return 0, fmt.Errorf("invalid duration: %s", s)
 }
+
+// sizeUnits maps size suffixes to byte multipliers (binary units).
+var sizeUnits = []struct {
+	suffix string
+	mult   float64
+}{
+	{"TB", 1 << 40},
+	{"GB", 1 << 30},
+	{"MB", 1 << 20},
+	{"KB", 1 << 10},
+	{"T", 1 << 40},
+	{"G", 1 << 30},
+	{"M", 1 << 20},
+	{"K", 1 << 10},
+	{"B", 1},
+}
+
+// parseSize parses a byte size with an optional unit suffix.
+// Examples: "10GB" → 10*2^30, "512M", "1024", "0".
+func parseSize(s string) (int64, error) {
+	v := strings.ToUpper(strings.TrimSpace(s))
+	mult := 1.0
+	for _, u := range sizeUnits {
+		if strings.HasSuffix(v, u.suffix) {
+			v = strings.TrimSpace(strings.TrimSuffix(v, u.suffix))
+			mult = u.mult
+			break
+		}
+	}
+	n, err := strconv.ParseFloat(v, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size: %s", s)
+	}
+	return int64(n * mult), nil
+}
@@ -82,6 +82,7 @@ auth:
 	assert.Equal(t, 3000, cfg.Import.BatchSize)
 	assert.Equal(t, 10000, cfg.Import.LogEvery)
 	assert.Equal(t, 30*24*time.Hour, cfg.Reader.CacheTTL)
+	assert.Equal(t, CacheBackendFS, cfg.Reader.CacheBackend)
 }
 
 func TestLoad_MissingJWTSecret(t *testing.T) {
@@ -266,6 +267,91 @@ func TestParseDuration(t *testing.T) {
 	}
 }
 
+func TestLoad_CacheMaxSize(t *testing.T) {
+	content := `
+database:
+  host: "localhost"
+  user: "app"
+  password: "pw"
+  dbname: "homelib"
+auth:
+  jwt_secret: "default-test-secret-must-be-at-least-32-chars"
+reader:
+  cache_max_size: "10GB"
+`
+	path := writeTemp(t, content)
+
+	cfg, err := Load(path)
+	require.NoError(t, err)
+	assert.Equal(t, int64(10<<30), cfg.Reader.MaxSize)
+
+	t.Setenv("READER_CACHE_MAX_SIZE", "512MB")
+	cfg, err = Load(path)
+	require.NoError(t, err)
+	assert.Equal(t, int64(512<<20), cfg.Reader.MaxSize)
+}
+
+func TestLoad_CacheBackend(t *testing.T) {
+	content := `
+database:
+  host: "localhost"
+  user: "app"
+  password: "pw"
+  dbname: "homelib"
+auth:
+  jwt_secret: "default-test-secret-must-be-at-least-32-chars"
+reader:
+  cache_backend: "postgres"
+`
+	path := writeTemp(t, content)
+
+	cfg, err := Load(path)
+	require.NoError(t, err)
+	assert.Equal(t, CacheBackendPostgres, cfg.Reader.CacheBackend)
+
+	t.Setenv("READER_CACHE_BACKEND", "memory")
+	_, err = Load(path)
+	assert.ErrorContains(t, err, "requires a positive cache_max_size")
+
+	t.Setenv("READER_CACHE_MAX_SIZE", "256MB")
+	cfg, err = Load(path)
+	require.NoError(t, err)
+	assert.Equal(t, CacheBackendMemory, cfg.Reader.CacheBackend)
+
+	t.Setenv("READER_CACHE_BACKEND", "redis")
+	_, err = Load(path)
+	assert.ErrorContains(t, err, "unknown reader cache backend")
+}
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+		wantErr  bool
+	}{
+		{"10GB", 10 << 30, false},
+		{"512mb", 512 << 20, false},
+		{"1.5G", 3 << 29, false},
+		{"64K", 64 << 10, false},
+		{"1024", 1024, false},
+		{"100 B", 100, false},
+		{"0", 0, false},
+		{"-1GB", 0, true},
+		{"lots", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			n, err := parseSize(tt.input)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, tt.expected, n)
+			}
+		})
+	}
+}
+
 func writeTemp(t *testing.T, content string) string {
 	t.Helper()
 	dir := t.TempDir()
@@ -0,0 +1,89 @@
+// Package kosync implements the parts of the KOReader integration that do
+// not depend on storage: document digests and statistics files.
+package kosync
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"hash"
+)
+
+// KOReader identifies a document by the MD5 of up to 1 KiB samples taken at
+// offsets 0, 1 KiB, 4 KiB, 16 KiB, … 1 GiB (1024 << 2i for i = -1..10,
+// where the i = -1 shift wraps to 0 in LuaJIT's 32-bit arithmetic).
+const (
+	sampleSize  = 1024
+	sampleCount = 12
+)
+
+// sampleOffset returns the file offset of the i-th sample.
+func sampleOffset(i int) int64 {
+	if i == 0 {
+		return 0
+	}
+	return int64(1024) << (2 * (i - 1))
+}
+
+// PartialMD5 returns the KOReader "binary" document digest of data.
+func PartialMD5(data []byte) string {
+	h := md5.New()
+	for i := range sampleCount {
+		off := sampleOffset(i)
+		if off >= int64(len(data)) {
+			break
+		}
+		h.Write(data[off:min(off+sampleSize, int64(len(data)))])
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// FilenameMD5 returns the KOReader "filename" document digest: the MD5 of
+// the file's base name.
+func FilenameMD5(name string) string {
+	sum := md5.Sum([]byte(name))
+	return hex.EncodeToString(sum[:])
+}
+
+// DigestWriter computes PartialMD5 of a stream without buffering it, so a
+// digest can be taken while a file is being served. Samples are read in
+// order, so a single pass suffices.
+type DigestWriter struct {
+	h      hash.Hash
+	pos    int64
+	sample int
+}
+
+// NewDigestWriter returns a writer that digests everything written to it.
+func NewDigestWriter() *DigestWriter {
+	return &DigestWriter{h: md5.New()}
+}
+
+// Write feeds the bytes that fall into sample windows to the digest.
+func (w *DigestWriter) Write(p []byte) (int, error) {
+	n := len(p)
+	for len(p) > 0 && w.sample < sampleCount {
+		start := sampleOffset(w.sample)
+		end := start + sampleSize
+		if w.pos >= end {
+			w.sample++
+			continue
+		}
+		if w.pos < start {
+			skip := min(start-w.pos, int64(len(p)))
+			p = p[skip:]
+			w.pos += skip
+			continue
+		}
+		take := min(end-w.pos, int64(len(p)))
+		w.h.Write(p[:take])
+		p = p[take:]
+		w.pos += take
+	}
+	w.pos += int64(len(p))
+	return n, nil
+}
+
+// Sum returns the hex digest of the data written so far.
+func (w *DigestWriter) Sum() string {
+	return hex.EncodeToString(w.h.Sum(nil))
+}
@@ -0,0 +1,50 @@
+package kosync
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/hex"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPartialMD5_SmallFile(t *testing.T) {
+	// Files under 1 KiB are digested whole.
+	data := []byte("<FictionBook/>")
+	sum := md5.Sum(data)
+	assert.Equal(t, hex.EncodeToString(sum[:]), PartialMD5(data))
+}
+
+func TestPartialMD5_Samples(t *testing.T) {
+	data := make([]byte, 20000)
+	for i := range data {
+		data[i] = byte(i * 7)
+	}
+	h := md5.New()
+	h.Write(data[0:1024])
+	h.Write(data[1024:2048])
+	h.Write(data[4096:5120])
+	h.Write(data[16384:17408])
+	assert.Equal(t, hex.EncodeToString(h.Sum(nil)), PartialMD5(data))
+}
+
+func TestDigestWriter_MatchesPartialMD5(t *testing.T) {
+	for _, size := range []int{0, 10, 1024, 1500, 5000, 70000} {
+		data := make([]byte, size)
+		for i := range data {
+			data[i] = byte(i*31 + 5)
+		}
+		w := NewDigestWriter()
+		// Odd buffer size so that writes straddle sample windows.
+		_, err := io.CopyBuffer(w, bytes.NewReader(data), make([]byte, 333))
+		assert.NoError(t, err)
+		assert.Equal(t, PartialMD5(data), w.Sum(), "size %d", size)
+	}
+}
+
+func TestFilenameMD5(t *testing.T) {
+	assert.Equal(t, "d41d8cd98f00b204e9800998ecf8427e", FilenameMD5(""))
+	assert.Len(t, FilenameMD5("123456.fb2"), 32)
+}
@@ -0,0 +1,151 @@
+package kosync
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/url"
+	"sort"
+	"time"
+
+	// Pure Go driver: the binaries are built without cgo.
+	_ "modernc.org/sqlite"
+)
+
+// StatsBook is a book in KOReader's statistics.sqlite3. MD5 is the
+// document digest (see PartialMD5); Authors are newline-separated.
+type StatsBook struct {
+	ID      int64
+	Title   string
+	Authors string
+	MD5     string
+	Pages   int
+}
+
+// PageView is a visit of a page as recorded by KOReader's statistics
+// plugin. TotalPages is the book's page count at the time, which changes
+// with font settings.
+type PageView struct {
+	BookID     int64
+	Page       int
+	Start      time.Time
+	Duration   time.Duration
+	TotalPages int
+}
+
+// Statistics is the content of a statistics.sqlite3 file.
+type Statistics struct {
+	Books []StatsBook
+	Views []PageView
+}
+
+// ReadStatistics reads a KOReader statistics database. Only the schema
+// with the page_stat_data table (KOReader 2020.11 and later) is supported.
+func ReadStatistics(ctx context.Context, path string) (*Statistics, error) {
+	dsn := "file:" + (&url.URL{Path: path}).EscapedPath() + "?mode=ro&immutable=1"
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("open statistics: %w", err)
+	}
+	defer db.Close()
+
+	var n int
+	if err := db.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name IN ('book', 'page_stat_data')`,
+	).Scan(&n); err != nil {
+		return nil, fmt.Errorf("read statistics schema: %w", err)
+	}
+	if n != 2 {
+		return nil, fmt.Errorf("not a KOReader statistics database")
+	}
+
+	stats := &Statistics{}
+
+	rows, err := db.QueryContext(ctx,
+		`SELECT id, COALESCE(title, ''), COALESCE(authors, ''), COALESCE(md5, ''), COALESCE(pages, 0) FROM book`)
+	if err != nil {
+		return nil, fmt.Errorf("read statistics books: %w", err)
+	}
+	for rows.Next() {
+		var b StatsBook
+		if err := rows.Scan(&b.ID, &b.Title, &b.Authors, &b.MD5, &b.Pages); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("scan statistics book: %w", err)
+		}
+		stats.Books = append(stats.Books, b)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("read statistics books: %w", err)
+	}
+
+	rows, err = db.QueryContext(ctx,
+		`SELECT id_book, page, start_time, duration, total_pages FROM page_stat_data
+		 WHERE duration > 0 ORDER BY id_book, start_time`)
+	if err != nil {
+		return nil, fmt.Errorf("read statistics pages: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var v PageView
+		var start, duration int64
+		if err := rows.Scan(&v.BookID, &v.Page, &start, &duration, &v.TotalPages); err != nil {
+			return nil, fmt.Errorf("scan statistics page: %w", err)
+		}
+		v.Start = time.Unix(start, 0).UTC()
+		v.Duration = time.Duration(duration) * time.Second
+		stats.Views = append(stats.Views, v)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("read statistics pages: %w", err)
+	}
+	return stats, nil
+}
+
+// Session is a run of page views of one book without a pause longer than
+// the gap passed to Sessions. Pages counts distinct pages; FirstPage and
+// LastPage are relative to TotalPages, the page count at the end.
+type Session struct {
+	BookID     int64
+	Start      time.Time
+	End        time.Time
+	Duration   time.Duration
+	Pages      int
+	FirstPage  int
+	LastPage   int
+	TotalPages int
+}
+
+// Sessions groups page views into reading sessions.
+func Sessions(views []PageView, gap time.Duration) []Session {
+	sorted := append([]PageView(nil), views...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].BookID != sorted[j].BookID {
+			return sorted[i].BookID < sorted[j].BookID
+		}
+		return sorted[i].Start.Before(sorted[j].Start)
+	})
+
+	var out []Session
+	var cur *Session
+	var pages map[int]bool
+	for _, v := range sorted {
+		end := v.Start.Add(v.Duration)
+		if cur == nil || cur.BookID != v.BookID || v.Start.Sub(cur.End) > gap {
+			out = append(out, Session{BookID: v.BookID, Start: v.Start, FirstPage: v.Page})
+			cur = &out[len(out)-1]
+			pages = make(map[int]bool)
+		}
+		if end.After(cur.End) {
+			cur.End = end
+		}
+		cur.Duration += v.Duration
+		cur.LastPage = v.Page
+		cur.TotalPages = v.TotalPages
+		if !pages[v.Page] {
+			pages[v.Page] = true
+			cur.Pages++
+		}
+	}
+	return out
+}
@@ -0,0 +1,58 @@
+package kosync
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadStatistics(t *testing.T) {
+	stats, err := ReadStatistics(context.Background(), filepath.Join("testdata", "statistics.sqlite3"))
+	require.NoError(t, err)
+
+	require.Len(t, stats.Books, 2)
+	assert.Equal(t, StatsBook{ID: 1, Title: "Мастер и Маргарита", Authors: "Михаил Булгаков",
+		MD5: "0123456789abcdef0123456789abcdef", Pages: 400}, stats.Books[0])
+	assert.Equal(t, "Somebody\nElse", stats.Books[1].Authors)
+
+	// The zero-duration view is skipped
+	require.Len(t, stats.Views, 6)
+	assert.Equal(t, PageView{BookID: 1, Page: 10, Start: time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC),
+		Duration: 2 * time.Minute, TotalPages: 400}, stats.Views[0])
+}
+
+func TestReadStatistics_NotStatistics(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "other.sqlite3")
+	require.NoError(t, os.WriteFile(path, []byte("not a database"), 0o644))
+
+	_, err := ReadStatistics(context.Background(), path)
+	assert.Error(t, err)
+}
+
+func TestSessions(t *testing.T) {
+	t0 := time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC)
+	views := []PageView{
+		{BookID: 1, Page: 13, Start: t0.Add(time.Hour), Duration: 90 * time.Second, TotalPages: 410},
+		{BookID: 1, Page: 10, Start: t0, Duration: 2 * time.Minute, TotalPages: 400},
+		{BookID: 1, Page: 11, Start: t0.Add(2 * time.Minute), Duration: 3 * time.Minute, TotalPages: 400},
+		{BookID: 1, Page: 12, Start: t0.Add(5 * time.Minute), Duration: time.Minute, TotalPages: 400},
+		{BookID: 1, Page: 12, Start: t0.Add(6 * time.Minute), Duration: 30 * time.Second, TotalPages: 400},
+		{BookID: 2, Page: 1, Start: t0.Add(100 * time.Second), Duration: time.Minute, TotalPages: 100},
+	}
+
+	sessions := Sessions(views, 10*time.Minute)
+	require.Len(t, sessions, 3)
+
+	assert.Equal(t, Session{
+		BookID: 1, Start: t0, End: t0.Add(6*time.Minute + 30*time.Second),
+		Duration: 6*time.Minute + 30*time.Second, Pages: 3, FirstPage: 10, LastPage: 12, TotalPages: 400,
+	}, sessions[0])
+	assert.Equal(t, 13, sessions[1].FirstPage)
+	assert.Equal(t, 410, sessions[1].TotalPages)
+	assert.Equal(t, int64(2), sessions[2].BookID)
+}
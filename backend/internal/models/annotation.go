@@ -0,0 +1,132 @@
+package models
+
+import (
+	"errors"
+	"slices"
+	"time"
+)
+
+// Annotation types.
+const (
+	AnnotationBookmark  = "bookmark"
+	AnnotationHighlight = "highlight"
+	AnnotationNote      = "note"
+)
+
+// AnnotationColors lists the highlight colours offered by the reader.
+var AnnotationColors = []string{"yellow", "green", "blue", "pink", "purple", "orange"}
+
+// ErrInvalidAnnotation is returned when an annotation is internally inconsistent.
+var ErrInvalidAnnotation = errors.New("invalid annotation")
+
+// TextLocator pins a position or range inside a chapter. Start and End are
+// paragraph locators; Quote, Prefix and Suffix keep the selected text and its
+// surroundings so the range can be re-anchored if the chapter text changes.
+type TextLocator struct {
+	Start  Locator `json:"start"`
+	End    Locator `json:"end"`
+	Quote  string  `json:"quote,omitempty" binding:"max=5000"`
+	Prefix string  `json:"prefix,omitempty" binding:"max=200"`
+	Suffix string  `json:"suffix,omitempty" binding:"max=200"`
+}
+
+// Annotation is a bookmark, highlight or note of a user in a book.
+type Annotation struct {
+	ID        int64       `json:"id"`
+	UserID    string      `json:"-"`
+	BookID    int64       `json:"bookId"`
+	Type      string      `json:"type"`
+	ChapterID string      `json:"chapterId"`
+	Locator   TextLocator `json:"locator"`
+	Color     string      `json:"color,omitempty"`
+	Note      string      `json:"note,omitempty"`
+	CreatedAt time.Time   `json:"createdAt"`
+	UpdatedAt time.Time   `json:"updatedAt"`
+}
+
+type CreateAnnotationInput struct {
+	Type      string      `json:"type" binding:"required,oneof=bookmark highlight note"`
+	ChapterID string      `json:"chapterId" binding:"required"`
+	Locator   TextLocator `json:"locator"`
+	Color     string      `json:"color"`
+	Note      string      `json:"note" binding:"max=10000"`
+}
+
+// UpdateAnnotationInput changes selected fields; nil fields are kept.
+type UpdateAnnotationInput struct {
+	Locator *TextLocator `json:"locator"`
+	Color   *string      `json:"color"`
+	Note    *string      `json:"note" binding:"omitempty,max=10000"`
+}
+
+// AnnotationFilter narrows a book's annotation list.
+type AnnotationFilter struct {
+	ChapterID string `form:"chapterId"`
+	Type      string `form:"type" binding:"omitempty,oneof=bookmark highlight note"`
+}
+
+// AnnotationExport is the full JSON dump of a user's annotations.
+type AnnotationExport struct {
+	ExportedAt time.Time       `json:"exportedAt"`
+	Books      []AnnotatedBook `json:"books"`
+}
+
+// AnnotatedBook groups the annotations of one book with its catalog data.
+// Chapters maps chapter IDs to their TOC titles when the book file could be read.
+type AnnotatedBook struct {
+	Book        BookDetail        `json:"book"`
+	Chapters    map[string]string `json:"chapters,omitempty"`
+	Annotations []Annotation      `json:"annotations"`
+}
+
+// validColor reports whether c is empty (no colour) or a known colour.
+func validColor(c string) bool {
+	return c == "" || slices.Contains(AnnotationColors, c)
+}
+
+// validate checks that the locator fits the annotation type: bookmarks may
+// point at a single position, highlights and notes need a non-empty range.
+func (l TextLocator) validate(typ string) error {
+	cmp := l.End.Compare(l.Start)
+	if cmp < 0 {
+		return ErrInvalidAnnotation
+	}
+	if typ != AnnotationBookmark && (cmp == 0 || l.Quote == "") {
+		return ErrInvalidAnnotation
+	}
+	return nil
+}
+
+// Validate checks cross-field rules not expressible with binding tags.
+func (in CreateAnnotationInput) Validate() error {
+	if !validColor(in.Color) {
+		return ErrInvalidAnnotation
+	}
+	if in.Type == AnnotationNote && in.Note == "" {
+		return ErrInvalidAnnotation
+	}
+	return in.Locator.validate(in.Type)
+}
+
+// Apply validates the update against the existing annotation and merges it in.
+func (in UpdateAnnotationInput) Apply(a *Annotation) error {
+	if in.Color != nil && !validColor(*in.Color) {
+		return ErrInvalidAnnotation
+	}
+	if in.Note != nil && a.Type == AnnotationNote && *in.Note == "" {
+		return ErrInvalidAnnotation
+	}
+	if in.Locator != nil {
+		if err := in.Locator.validate(a.Type); err != nil {
+			return err
+		}
+		a.Locator = *in.Locator
+	}
+	if in.Color != nil {
+		a.Color = *in.Color
+	}
+	if in.Note != nil {
+		a.Note = *in.Note
+	}
+	return nil
+}
@@ -34,6 +34,8 @@ type BookListItem struct {
 	Format    string            `json:"format"`
 	FileSize  *int64            `json:"file_size,omitempty"`
 	LibRate   *int16            `json:"lib_rate,omitempty"`
+	HouseholdRate      *float64 `json:"household_rate,omitempty"`       // Average household rating, 5-point scale
+	HouseholdRateCount int      `json:"household_rate_count,omitempty"`
 	IsDeleted bool              `json:"is_deleted"`
 	Authors   []BookAuthorRef   `json:"authors"`
 	Genres    []BookGenreRef    `json:"genres"`
@@ -64,6 +66,8 @@ type BookDetail struct {
 	Format      string            `json:"format"`
 	FileSize    *int64            `json:"file_size,omitempty"`
 	LibRate     *int16            `json:"lib_rate,omitempty"`
+	HouseholdRate      *float64 `json:"household_rate,omitempty"`       // Average household rating, 5-point scale
+	HouseholdRateCount int      `json:"household_rate_count,omitempty"`
 	IsDeleted   bool              `json:"is_deleted"`
 	Description *string           `json:"description,omitempty"`
 	Keywords    []string          `json:"keywords,omitempty"`
@@ -94,19 +98,39 @@ type BookCollectionRef struct {
 }
 
 type BookFilter struct {
-	Query           string `form:"q"`
-	AuthorID        *int64 `form:"author_id"`
-	AuthorName      string `form:"author_name"`
-	GenreID         *int   `form:"genre_id"`
-	SeriesID        *int64 `form:"series_id"`
-	SeriesName      string `form:"series_name"`
-	Lang            string `form:"lang"`
-	Format          string `form:"format"`
-	Page            int    `form:"page"`
-	Limit           int    `form:"limit"`
-	Sort            string `form:"sort"`
-	Order           string `form:"order"`
-	ExcludeGenreIDs []int  `form:"-"` // Parental control: set by middleware, not from query params
+	Query           string     `form:"q"`
+	AuthorID        *int64     `form:"author_id"`
+	AuthorName      string     `form:"author_name"`
+	GenreIDs        []int      `form:"genre_id"`     // Any of the genres (with descendants)
+	NotGenreIDs     []int      `form:"not_genre_id"` // None of the genres (with descendants)
+	GenreCodes      []string   `form:"genre"`        // Any of the genre codes (with descendants)
+	NotGenreCodes   []string   `form:"not_genre"`    // None of the genre codes (with descendants)
+	SeriesID        *int64     `form:"series_id"`
+	SeriesName      string     `form:"series_name"`
+	SeriesType      string     `form:"series_type" binding:"omitempty,oneof=a p"`
+	Langs           []string   `form:"lang"`
+	Formats         []string   `form:"format"`
+	Keywords        []string   `form:"keyword"` // All of the keywords
+	YearFrom        *int       `form:"year_from"`
+	YearTo          *int       `form:"year_to"`
+	LibRateMin      *int       `form:"lib_rate_min"`
+	LibRateMax      *int       `form:"lib_rate_max"`
+	SizeMin         *int64     `form:"size_min"`
+	SizeMax         *int64     `form:"size_max"`
+	DateAddedFrom   *time.Time `form:"date_added_from" time_format:"2006-01-02"`
+	DateAddedTo     *time.Time `form:"date_added_to" time_format:"2006-01-02"`
+	IncludeDeleted  bool       `form:"include_deleted"` // Honored for admins only; unset means true for them
+	Page            int        `form:"page"`
+	Limit           int        `form:"limit"`
+	Sort            string     `form:"sort"`
+	Order           string     `form:"order"`
+	Cursor          string     `form:"cursor"` // Opaque keyset cursor; takes precedence over Page
+	Count           string     `form:"count"`  // Total count mode: exact, estimate or none
+	Syntax          string     `form:"syntax" binding:"omitempty,oneof=plain advanced"` // advanced: q uses the search query language
+	ShelfID         *int64     `form:"shelf_id"` // Books on one of the user's shelves
+	Shelf           string     `form:"shelf" binding:"omitempty,oneof=want_to_read reading finished abandoned"` // Books with the reading status
+	UserID          string     `form:"-"`      // Owner of the shelves; set by the handler
+	ExcludeGenreIDs []int      `form:"-"`      // Parental control: set by middleware, not from query params
 }
 
 func (f *BookFilter) SetDefaults() {
@@ -122,8 +146,14 @@ func (f *BookFilter) SetDefaults() {
 	if f.Order == "" {
 		f.Order = "asc"
 	}
+	f.Count = NormalizeCountMode(f.Count)
 }
 
+// Offset returns the row offset for page mode. In cursor mode the position
+// is encoded in the cursor itself, so the offset is always zero.
 func (f *BookFilter) Offset() int {
+	if f.Cursor != "" {
+		return 0
+	}
 	return (f.Page - 1) * f.Limit
 }
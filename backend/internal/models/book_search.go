@@ -0,0 +1,37 @@
+package models
+
+import "strings"
+
+// BookSearchFilter holds the query and pagination parameters of a search
+// inside one book.
+type BookSearchFilter struct {
+	Query string `form:"q" binding:"required,max=200"`
+	Page  int    `form:"page"`
+	Limit int    `form:"limit"`
+}
+
+func (f *BookSearchFilter) SetDefaults() {
+	f.Query = strings.TrimSpace(f.Query)
+	if f.Page < 1 {
+		f.Page = 1
+	}
+	if f.Limit < 1 || f.Limit > 100 {
+		f.Limit = 20
+	}
+}
+
+func (f *BookSearchFilter) Offset() int {
+	return (f.Page - 1) * f.Limit
+}
+
+// BookSearchHit is an occurrence of the query in the text of a book. The
+// locator points at the start of the match; the match starts at
+// MatchOffset within Snippet and is MatchLength long (UTF-16 code units).
+type BookSearchHit struct {
+	ChapterID    string  `json:"chapterId"`
+	ChapterTitle string  `json:"chapterTitle"`
+	Locator      Locator `json:"locator"`
+	Snippet      string  `json:"snippet"`
+	MatchOffset  int     `json:"matchOffset"`
+	MatchLength  int     `json:"matchLength"`
+}
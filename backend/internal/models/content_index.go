@@ -0,0 +1,82 @@
+package models
+
+import "strings"
+
+// ContentPassagesPerBook is the number of passages returned for each book
+// found by a content search.
+const ContentPassagesPerBook = 3
+
+// ContentSearchFilter holds the parameters of a search in the text of
+// indexed books.
+type ContentSearchFilter struct {
+	Query           string `form:"q" binding:"required,max=200"`
+	Page            int    `form:"page"`
+	Limit           int    `form:"limit"`
+	ExcludeGenreIDs []int  `form:"-"` // Parental filter
+}
+
+func (f *ContentSearchFilter) SetDefaults() {
+	f.Query = strings.TrimSpace(f.Query)
+	if f.Page < 1 {
+		f.Page = 1
+	}
+	if f.Limit < 1 || f.Limit > 50 {
+		f.Limit = 20
+	}
+}
+
+func (f *ContentSearchFilter) Offset() int {
+	return (f.Page - 1) * f.Limit
+}
+
+// ContentPassage is a passage of a book matching a content search. Snippet
+// is HTML: escaped text with the matched words in <mark>. Locator points at
+// the start of the passage in its chapter.
+type ContentPassage struct {
+	ChapterID string  `json:"chapter_id"`
+	Locator   Locator `json:"locator"`
+	Snippet   string  `json:"snippet"`
+}
+
+// ContentSearchResult is a book found by a content search with its best
+// passages. Matches counts the matching passages in the whole book.
+type ContentSearchResult struct {
+	BookID   int64            `json:"book_id"`
+	Title    string           `json:"title"`
+	Authors  []BookAuthorRef  `json:"authors"`
+	Matches  int              `json:"matches"`
+	Passages []ContentPassage `json:"passages"`
+}
+
+// ContentIndexChunk is a run of consecutive paragraphs of a chapter stored
+// in the content index. Paragraph is the index of its first paragraph.
+type ContentIndexChunk struct {
+	ChapterID string
+	Paragraph int
+	Body      string
+}
+
+// ContentIndexCollection reports the content index state of a collection.
+// Bytes is the stored size of its text and search vectors.
+type ContentIndexCollection struct {
+	ID           int    `json:"id"`
+	Name         string `json:"name"`
+	Enabled      bool   `json:"enabled"`
+	Books        int    `json:"books"`
+	IndexedBooks int    `json:"indexed_books"`
+	FailedBooks  int    `json:"failed_books"`
+	Chunks       int64  `json:"chunks"`
+	Bytes        int64  `json:"bytes"`
+}
+
+// ContentIndexUsage is the disk usage report of the content index.
+// TotalBytes is the on-disk size of its tables including their indexes.
+type ContentIndexUsage struct {
+	Collections []ContentIndexCollection `json:"collections"`
+	TotalBytes  int64                    `json:"total_bytes"`
+}
+
+// SetContentIndexInput enables or disables content indexing of a collection.
+type SetContentIndexInput struct {
+	Enabled *bool `json:"enabled" binding:"required"`
+}
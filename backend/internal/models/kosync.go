@@ -0,0 +1,39 @@
+package models
+
+import "time"
+
+// Kinds of KOReader document digests (see kosync.PartialMD5).
+const (
+	DocumentHashBinary   = "binary"
+	DocumentHashFilename = "filename"
+)
+
+// KosyncAccount holds the credentials a user's KOReader devices sync with.
+// KeyHash is a bcrypt hash of the key KOReader sends: the MD5 of the password.
+// UserActive mirrors users.is_active; deactivated users cannot sync.
+type KosyncAccount struct {
+	UserID     string    `json:"-"`
+	Username   string    `json:"username"`
+	KeyHash    string    `json:"-"`
+	UserActive bool      `json:"-"`
+	CreatedAt  time.Time `json:"createdAt"`
+	UpdatedAt  time.Time `json:"updatedAt"`
+}
+
+type SetKosyncAccountInput struct {
+	Username string `json:"username" binding:"required,max=64"`
+	Password string `json:"password" binding:"required,min=6,max=72"`
+}
+
+// KosyncProgress is a reading position in KOReader's terms. Progress is an
+// XPointer for reflowable documents or a page number for fixed-layout ones;
+// Percentage is the position in the whole document (0..1).
+type KosyncProgress struct {
+	UserID     string    `json:"-"`
+	Document   string    `json:"document"`
+	Progress   string    `json:"progress"`
+	Percentage float64   `json:"percentage"`
+	Device     string    `json:"device"`
+	DeviceID   string    `json:"device_id"`
+	UpdatedAt  time.Time `json:"-"`
+}
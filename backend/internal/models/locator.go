@@ -0,0 +1,29 @@
+package models
+
+// Locator is a stable position inside a chapter: the index of a text block
+// (numbered by the converters, see bookfile.ParagraphAttr) and an offset in
+// UTF-16 code units within its text. Unlike percentages it survives layout
+// and font changes as well as changes to how blocks are rendered.
+//
+// An offset past the end of its paragraph continues into the following
+// paragraphs; positions migrated from chapter-wide offsets rely on this.
+type Locator struct {
+	Paragraph int `json:"paragraph" binding:"min=0"`
+	Offset    int `json:"offset" binding:"min=0"`
+}
+
+// Compare orders locators within a chapter, returning -1, 0 or +1.
+func (l Locator) Compare(o Locator) int {
+	switch {
+	case l.Paragraph != o.Paragraph:
+		if l.Paragraph < o.Paragraph {
+			return -1
+		}
+		return 1
+	case l.Offset < o.Offset:
+		return -1
+	case l.Offset > o.Offset:
+		return 1
+	}
+	return 0
+}
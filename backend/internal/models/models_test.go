@@ -106,3 +106,107 @@ func TestUser_ToUserInfo(t *testing.T) {
 	assert.Equal(t, "Test User", info.DisplayName)
 	assert.Equal(t, "admin", info.Role)
 }
+
+func TestBookFilter_Offset_CursorMode(t *testing.T) {
+	f := BookFilter{Page: 5, Limit: 20, Cursor: "abc"}
+	assert.Equal(t, 0, f.Offset())
+}
+
+func TestNormalizeCountMode(t *testing.T) {
+	assert.Equal(t, CountExact, NormalizeCountMode(""))
+	assert.Equal(t, CountExact, NormalizeCountMode("bogus"))
+	assert.Equal(t, CountEstimate, NormalizeCountMode("estimate"))
+	assert.Equal(t, CountNone, NormalizeCountMode("none"))
+}
+
+func TestCursor_RoundTrip(t *testing.T) {
+	key := "Пикник на обочине"
+	c := Cursor{Sort: "title", Order: "asc", Key: &key, ID: 42}
+
+	decoded, err := DecodeCursor(c.Encode())
+	assert.NoError(t, err)
+	assert.Equal(t, c, *decoded)
+}
+
+func TestCursor_RoundTrip_NullKey(t *testing.T) {
+	c := Cursor{Sort: "year", Order: "desc", ID: 7}
+
+	decoded, err := DecodeCursor(c.Encode())
+	assert.NoError(t, err)
+	assert.Nil(t, decoded.Key)
+	assert.Equal(t, int64(7), decoded.ID)
+}
+
+func TestDecodeCursor_Invalid(t *testing.T) {
+	for _, raw := range []string{"", "!!!", "bm90LWpzb24", "e30"} {
+		_, err := DecodeCursor(raw)
+		assert.ErrorIs(t, err, ErrInvalidCursor, "cursor %q", raw)
+	}
+}
+
+func TestListFilter_SetDefaults(t *testing.T) {
+	f := ListFilter{Page: -1, Limit: 500, Count: "estimate"}
+	f.SetDefaults()
+	assert.Equal(t, 1, f.Page)
+	assert.Equal(t, 20, f.Limit)
+	assert.Equal(t, CountEstimate, f.Count)
+
+	f = ListFilter{Page: 3, Limit: 10, Cursor: "x"}
+	f.SetDefaults()
+	assert.Equal(t, CountExact, f.Count)
+	assert.Equal(t, 0, f.Offset())
+}
+
+func TestBookSearchFilter_SetDefaults(t *testing.T) {
+	f := BookSearchFilter{Query: " Наташа ", Page: 0, Limit: 1000}
+	f.SetDefaults()
+	assert.Equal(t, "Наташа", f.Query)
+	assert.Equal(t, 1, f.Page)
+	assert.Equal(t, 20, f.Limit)
+
+	f = BookSearchFilter{Query: "x", Page: 3, Limit: 10}
+	f.SetDefaults()
+	assert.Equal(t, 20, f.Offset())
+}
+
+func TestSuggestQuery_SetDefaults(t *testing.T) {
+	q := SuggestQuery{Query: "  мастер ", Types: []string{"Author,title", "author", "bogus"}, Limit: 100}
+	q.SetDefaults()
+
+	assert.Equal(t, "мастер", q.Query)
+	assert.Equal(t, []string{SuggestAuthor, SuggestTitle}, q.Types)
+	assert.Equal(t, SuggestMaxLimit, q.Limit)
+
+	empty := SuggestQuery{Types: []string{"bogus"}}
+	empty.SetDefaults()
+	assert.Equal(t, SuggestTypes, empty.Types)
+	assert.Equal(t, SuggestDefaultLimit, empty.Limit)
+}
+
+func TestSaveRatingInput_Validate(t *testing.T) {
+	rating := func(n int) *int { return &n }
+	tests := []struct {
+		name  string
+		input SaveRatingInput
+		scale int
+		ok    bool
+	}{
+		{"stars by default", SaveRatingInput{Rating: rating(4)}, 5, true},
+		{"out of stars", SaveRatingInput{Rating: rating(8)}, 5, false},
+		{"points", SaveRatingInput{Rating: rating(8), Scale: 10}, 10, true},
+		{"zero", SaveRatingInput{Rating: rating(0), Scale: 10}, 10, false},
+		{"review only", SaveRatingInput{Review: "Прекрасно"}, 5, true},
+		{"empty", SaveRatingInput{}, 5, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.input.Validate()
+			if tt.ok {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, ErrInvalidRating)
+			}
+			assert.Equal(t, tt.scale, tt.input.Scale)
+		})
+	}
+}
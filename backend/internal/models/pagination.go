@@ -0,0 +1,92 @@
+package models
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+)
+
+// ErrInvalidCursor is returned when a pagination cursor cannot be decoded
+// or was issued for a different sort order than the current request.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Total count modes for list endpoints.
+const (
+	CountExact    = "exact"    // SELECT COUNT(*) — precise but slow on large result sets
+	CountEstimate = "estimate" // planner row estimate via EXPLAIN — fast, approximate
+	CountNone     = "none"     // total is not computed
+)
+
+// NormalizeCountMode returns a known count mode, falling back to CountExact.
+func NormalizeCountMode(mode string) string {
+	switch mode {
+	case CountEstimate, CountNone:
+		return mode
+	default:
+		return CountExact
+	}
+}
+
+// Cursor is the decoded form of an opaque keyset pagination cursor.
+// It records the sort it was issued for and the position of the last
+// returned row: its sort key (nil when NULL) and its id.
+type Cursor struct {
+	Sort  string  `json:"s"`
+	Order string  `json:"o"`
+	Key   *string `json:"k,omitempty"`
+	ID    int64   `json:"i"`
+}
+
+// Encode returns the opaque URL-safe representation of the cursor.
+func (c Cursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeCursor parses a cursor produced by Cursor.Encode.
+func DecodeCursor(s string) (*Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var c Cursor
+	if err := json.Unmarshal(data, &c); err != nil || c.Sort == "" || c.ID <= 0 {
+		return nil, ErrInvalidCursor
+	}
+	return &c, nil
+}
+
+// PageInfo describes the pagination state of a list result.
+type PageInfo struct {
+	Total          int    // exact or estimated total; -1 when CountNone was requested
+	TotalEstimated bool   // true when Total comes from planner statistics
+	NextCursor     string // cursor for the next page; empty on the last page
+}
+
+// ListFilter holds search and pagination parameters for author and series lists.
+type ListFilter struct {
+	Query  string `form:"q"`
+	Page   int    `form:"page"`
+	Limit  int    `form:"limit"`
+	Cursor string `form:"cursor"`
+	Count  string `form:"count"`
+}
+
+func (f *ListFilter) SetDefaults() {
+	if f.Page < 1 {
+		f.Page = 1
+	}
+	if f.Limit < 1 || f.Limit > 100 {
+		f.Limit = 20
+	}
+	f.Count = NormalizeCountMode(f.Count)
+}
+
+// Offset returns the row offset for page mode. In cursor mode the position
+// is encoded in the cursor itself, so the offset is always zero.
+func (f *ListFilter) Offset() int {
+	if f.Cursor != "" {
+		return 0
+	}
+	return (f.Page - 1) * f.Limit
+}
@@ -0,0 +1,58 @@
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+// Rating scales: 5 stars or 10 points.
+const (
+	RatingScaleStars  = 5
+	RatingScalePoints = 10
+)
+
+// ErrInvalidRating is returned when a rating is out of its scale or empty.
+var ErrInvalidRating = errors.New("invalid rating")
+
+// BookRating is a user's rating and/or review of a book. Rating is nil for
+// a review without a rating.
+type BookRating struct {
+	UserID    string    `json:"-"`
+	BookID    int64     `json:"bookId"`
+	Rating    *int      `json:"rating,omitempty"`
+	Scale     int       `json:"scale"`
+	Review    string    `json:"review,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// BookReview is a rating of a book shown to the household, with its author.
+type BookReview struct {
+	BookRating
+	UserName string `json:"userName"`
+	Own      bool   `json:"own"`
+}
+
+type SaveRatingInput struct {
+	Rating *int   `json:"rating"`
+	Scale  int    `json:"scale" binding:"omitempty,oneof=5 10"`
+	Review string `json:"review" binding:"max=5000"`
+}
+
+// Validate checks the rating against its scale (5 by default) and that
+// the input is not empty.
+func (in *SaveRatingInput) Validate() error {
+	if in.Scale == 0 {
+		in.Scale = RatingScaleStars
+	}
+	if in.Rating == nil {
+		if in.Review == "" {
+			return ErrInvalidRating
+		}
+		return nil
+	}
+	if *in.Rating < 1 || *in.Rating > in.Scale {
+		return ErrInvalidRating
+	}
+	return nil
+}
@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// ReaderCacheStats describes the reader cache on disk. Entries are cached
+// books; hits and misses are counted since the server started.
+type ReaderCacheStats struct {
+	SizeBytes    int64   `json:"size_bytes"`
+	MaxSizeBytes int64   `json:"max_size_bytes"`
+	Entries      int     `json:"entries"`
+	Files        int     `json:"files"`
+	Hits         int64   `json:"hits"`
+	Misses       int64   `json:"misses"`
+	HitRatio     float64 `json:"hit_ratio"`
+}
+
+// ReaderCacheEntry is the cached data of one book in a reader cache backend.
+// Accessed is the last time any file of the book was read or written.
+type ReaderCacheEntry struct {
+	BookID   int64
+	Size     int64
+	Files    int
+	Accessed time.Time
+}
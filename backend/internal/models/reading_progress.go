@@ -2,11 +2,16 @@ package models
 
 import "time"
 
+// ReadingProgress is the last reading position of a user in a book.
+// Locator is the precise position in the chapter; it is nil for positions
+// saved before locators were introduced and not yet migrated, in which case
+// ChapterProgress (0-100) is the only hint.
 type ReadingProgress struct {
 	ID              int64     `json:"-"`
 	UserID          string    `json:"-"`
 	BookID          int64     `json:"-"`
 	ChapterID       string    `json:"chapterId"`
+	Locator         *Locator  `json:"locator,omitempty"`
 	ChapterProgress int       `json:"chapterProgress"`
 	TotalProgress   int       `json:"totalProgress"`
 	Device          string    `json:"device"`
@@ -14,8 +19,12 @@ type ReadingProgress struct {
 }
 
 type SaveProgressInput struct {
-	ChapterID       string `json:"chapterId" binding:"required"`
-	ChapterProgress int    `json:"chapterProgress" binding:"min=0,max=100"`
-	TotalProgress   int    `json:"totalProgress" binding:"min=0,max=100"`
-	Device          string `json:"device"`
+	ChapterID       string   `json:"chapterId" binding:"required"`
+	Locator         *Locator `json:"locator"`
+	ChapterProgress int      `json:"chapterProgress" binding:"min=0,max=100"`
+	TotalProgress   int      `json:"totalProgress" binding:"min=0,max=100"`
+	Device          string   `json:"device"`
+	// UpdatedAt is set for positions saved while offline: the time the
+	// position was reached. Such saves do not override newer progress.
+	UpdatedAt *time.Time `json:"updatedAt"`
 }
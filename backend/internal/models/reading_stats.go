@@ -0,0 +1,99 @@
+package models
+
+import "time"
+
+// Sources of reading sessions.
+const (
+	SessionSourceWeb      = "web"      // web reader progress updates
+	SessionSourceKosync   = "kosync"   // progress synced by KOReader devices
+	SessionSourceKOReader = "koreader" // imported KOReader statistics
+)
+
+// ReadingSession is a stretch of continuous reading of one book on one
+// device. Duration is the reading time in seconds; Pages is the number of
+// pages turned, estimated from the text covered for web sessions.
+// Positions are estimated character offsets from the start of the book.
+type ReadingSession struct {
+	ID             int64     `json:"id"`
+	UserID         string    `json:"-"`
+	BookID         int64     `json:"bookId"`
+	Source         string    `json:"source"`
+	Device         string    `json:"device"`
+	StartedAt      time.Time `json:"startedAt"`
+	EndedAt        time.Time `json:"endedAt"`
+	Duration       int       `json:"duration"`
+	StartChapterID string    `json:"startChapterId"`
+	EndChapterID   string    `json:"endChapterId"`
+	StartProgress  int       `json:"startProgress"`
+	EndProgress    int       `json:"endProgress"`
+	StartPosition  int64     `json:"-"`
+	EndPosition    int64     `json:"-"`
+	Pages          int       `json:"pages"`
+	ExternalID     *string   `json:"-"`
+}
+
+// StatsQuery selects the range of /api/me/stats. From and To are calendar
+// days in TZ, inclusive.
+type StatsQuery struct {
+	From string `form:"from"`
+	To   string `form:"to"`
+	TZ   string `form:"tz"`
+}
+
+// StatsPeriod is the reading done in a day, week ("2026-01-05", its Monday),
+// month ("2026-01") or year ("2026").
+type StatsPeriod struct {
+	Period        string `json:"period"`
+	Seconds       int64  `json:"-"`
+	Minutes       int    `json:"minutes"`
+	Pages         int    `json:"pages"`
+	Sessions      int    `json:"sessions"`
+	BooksFinished int    `json:"booksFinished,omitempty"`
+}
+
+// StatsTotal is the reading done in books of a genre or by an author.
+type StatsTotal struct {
+	ID      int64  `json:"id"`
+	Name    string `json:"name"`
+	Seconds int64  `json:"-"`
+	Minutes int    `json:"minutes"`
+	Pages   int    `json:"pages"`
+}
+
+// StatsYearGenres lists the most read genres of a year.
+type StatsYearGenres struct {
+	Year   string       `json:"year"`
+	Genres []StatsTotal `json:"genres"`
+}
+
+// StatsStreak counts consecutive days with reading. Current is 0 unless
+// the user read today or yesterday.
+type StatsStreak struct {
+	Current int `json:"current"`
+	Longest int `json:"longest"`
+}
+
+// ReadingStats is the response of /api/me/stats. Daily, weekly, monthly
+// figures and genre/author totals cover the requested range; yearly
+// figures, genres by year and streaks cover the whole history.
+type ReadingStats struct {
+	From         string            `json:"from"`
+	To           string            `json:"to"`
+	TZ           string            `json:"tz"`
+	Totals       StatsPeriod       `json:"totals"`
+	Streak       StatsStreak       `json:"streak"`
+	Daily        []StatsPeriod     `json:"daily"`
+	Weekly       []StatsPeriod     `json:"weekly"`
+	Monthly      []StatsPeriod     `json:"monthly"`
+	Yearly       []StatsPeriod     `json:"yearly"`
+	Genres       []StatsTotal      `json:"genres"`
+	Authors      []StatsTotal      `json:"authors"`
+	GenresByYear []StatsYearGenres `json:"genresByYear"`
+}
+
+// StatsImportResult reports a KOReader statistics import.
+type StatsImportResult struct {
+	Books     int      `json:"books"`
+	Sessions  int      `json:"sessions"`
+	Unmatched []string `json:"unmatched"`
+}
@@ -0,0 +1,75 @@
+package models
+
+import "time"
+
+// Reading statuses. Each has a built-in shelf; a book is on at most one of
+// them at a time.
+const (
+	ShelfWantToRead = "want_to_read"
+	ShelfReading    = "reading"
+	ShelfFinished   = "finished"
+	ShelfAbandoned  = "abandoned"
+)
+
+// ShelfStatuses lists the reading statuses in their default shelf order.
+var ShelfStatuses = []string{ShelfWantToRead, ShelfReading, ShelfFinished, ShelfAbandoned}
+
+// ShelfStatusNames are the names of the built-in status shelves.
+var ShelfStatusNames = map[string]string{
+	ShelfWantToRead: "Хочу прочитать",
+	ShelfReading:    "Читаю",
+	ShelfFinished:   "Прочитано",
+	ShelfAbandoned:  "Брошено",
+}
+
+// Shelf is a named list of books of a user. Status is set for the built-in
+// status shelves, which cannot be renamed or deleted.
+type Shelf struct {
+	ID        int64     `json:"id"`
+	UserID    string    `json:"-"`
+	Status    string    `json:"status,omitempty"`
+	Name      string    `json:"name"`
+	Note      string    `json:"note"`
+	Position  int       `json:"position"`
+	BookCount int       `json:"bookCount"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// ShelfEntry is a book on a shelf with its place and the user's note.
+type ShelfEntry struct {
+	ShelfID  int64     `json:"shelfId"`
+	BookID   int64     `json:"bookId"`
+	Position int       `json:"position"`
+	Note     string    `json:"note"`
+	AddedAt  time.Time `json:"addedAt"`
+}
+
+// ShelfDetail is a shelf with its books in shelf order.
+type ShelfDetail struct {
+	Shelf
+	Books []ShelfEntry `json:"books"`
+}
+
+type CreateShelfInput struct {
+	Name string `json:"name" binding:"required,max=100"`
+	Note string `json:"note" binding:"max=2000"`
+}
+
+// UpdateShelfInput changes selected fields; nil fields are kept.
+type UpdateShelfInput struct {
+	Name *string `json:"name" binding:"omitempty,min=1,max=100"`
+	Note *string `json:"note" binding:"omitempty,max=2000"`
+}
+
+// ShelfEntryInput adds a book to a shelf or changes its note. A nil Note
+// keeps the note of a book already on the shelf.
+type ShelfEntryInput struct {
+	Note *string `json:"note" binding:"omitempty,max=2000"`
+}
+
+// ReorderInput lists shelf or book IDs in their new order. IDs left out
+// keep their relative order after the listed ones.
+type ReorderInput struct {
+	IDs []int64 `json:"ids" binding:"required,max=10000"`
+}
@@ -0,0 +1,77 @@
+package models
+
+import "strings"
+
+// Suggestion types returned by the autocomplete endpoint.
+const (
+	SuggestTitle  = "title"
+	SuggestAuthor = "author"
+	SuggestSeries = "series"
+)
+
+// SuggestTypes lists all suggestion types in display order.
+var SuggestTypes = []string{SuggestTitle, SuggestAuthor, SuggestSeries}
+
+// Autocomplete limits: results are capped to keep responses small and fast.
+const (
+	SuggestDefaultLimit = 10
+	SuggestMaxLimit     = 25
+	SuggestMinQueryLen  = 2 // in runes
+)
+
+// SuggestQuery holds parameters of GET /api/suggest.
+type SuggestQuery struct {
+	Query           string   `form:"q"`
+	Types           []string `form:"types"` // Repeated or comma-separated; empty means all
+	Limit           int      `form:"limit"`
+	ExcludeGenreIDs []int    `form:"-"` // Parental control: set by middleware, not from query params
+}
+
+// SetDefaults trims the query, normalizes types and clamps the limit.
+// Unknown types are dropped; if none remain, all types are used.
+func (q *SuggestQuery) SetDefaults() {
+	q.Query = strings.TrimSpace(q.Query)
+	if q.Limit < 1 {
+		q.Limit = SuggestDefaultLimit
+	}
+	if q.Limit > SuggestMaxLimit {
+		q.Limit = SuggestMaxLimit
+	}
+
+	seen := make(map[string]bool)
+	var types []string
+	for _, raw := range q.Types {
+		for _, t := range strings.Split(raw, ",") {
+			t = strings.ToLower(strings.TrimSpace(t))
+			if seen[t] {
+				continue
+			}
+			switch t {
+			case SuggestTitle, SuggestAuthor, SuggestSeries:
+				seen[t] = true
+				types = append(types, t)
+			}
+		}
+	}
+	if len(types) == 0 {
+		types = SuggestTypes
+	}
+	q.Types = types
+}
+
+// Suggestion is a single autocomplete entry.
+type Suggestion struct {
+	Type   string  `json:"type"`
+	ID     int64   `json:"id"`
+	Text   string  `json:"text"`
+	Detail *string `json:"detail,omitempty"` // e.g. the first author of a title
+	Score  float64 `json:"-"`
+}
+
+// SuggestResult is the response of the autocomplete endpoint.
+// Corrected holds the keyboard-layout–fixed query when the suggestions
+// were found by it rather than by the query as typed.
+type SuggestResult struct {
+	Items     []Suggestion `json:"items"`
+	Corrected string       `json:"corrected,omitempty"`
+}
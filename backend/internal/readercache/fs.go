@@ -0,0 +1,158 @@
+package readercache
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/grom-alex/homelib/backend/internal/models"
+)
+
+// compressedExt is appended to the names of zstd-compressed cache files.
+const compressedExt = ".zst"
+
+// FS stores the files of each book zstd-compressed in a directory of its
+// own, {dir}/{bookID}/{name}.zst. The directory mtime records the last access.
+type FS struct {
+	dir string
+	enc *zstd.Encoder
+	dec *zstd.Decoder
+}
+
+func NewFS(dir string) (*FS, error) {
+	enc, err := zstd.NewWriter(nil)
+	if err != nil {
+		return nil, fmt.Errorf("create zstd encoder: %w", err)
+	}
+	dec, err := zstd.NewReader(nil)
+	if err != nil {
+		return nil, fmt.Errorf("create zstd decoder: %w", err)
+	}
+	return &FS{dir: dir, enc: enc, dec: dec}, nil
+}
+
+// BookDir returns the cache directory of a book.
+func (s *FS) BookDir(bookID int64) string {
+	return filepath.Join(s.dir, strconv.FormatInt(bookID, 10))
+}
+
+func (s *FS) filePath(bookID int64, name string) string {
+	return filepath.Join(s.BookDir(bookID), name+compressedExt)
+}
+
+func (s *FS) Get(_ context.Context, bookID int64, name string) ([]byte, bool, error) {
+	compressed, err := os.ReadFile(s.filePath(bookID, name))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, false, nil
+		}
+		return nil, false, fmt.Errorf("read cache file: %w", err)
+	}
+	data, err := s.dec.DecodeAll(compressed, nil)
+	if err != nil {
+		return nil, false, fmt.Errorf("decompress cache file %q: %w", name, err)
+	}
+	return data, true, nil
+}
+
+func (s *FS) Put(_ context.Context, bookID int64, name string, data []byte) (int64, error) {
+	if err := os.MkdirAll(s.BookDir(bookID), 0o755); err != nil {
+		return 0, fmt.Errorf("create cache dir: %w", err)
+	}
+	compressed := s.enc.EncodeAll(data, nil)
+	if err := atomicWriteFile(s.filePath(bookID, name), compressed, 0o644); err != nil {
+		return 0, err
+	}
+	return int64(len(compressed)), nil
+}
+
+func (s *FS) Touch(_ context.Context, bookID int64) error {
+	now := time.Now()
+	if err := os.Chtimes(s.BookDir(bookID), now, now); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("touch cache dir: %w", err)
+	}
+	return nil
+}
+
+// List sizes entries by their compressed size on disk.
+func (s *FS) List(_ context.Context) ([]models.ReaderCacheEntry, error) {
+	dirEntries, err := os.ReadDir(s.dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("read cache dir: %w", err)
+	}
+
+	entries := make([]models.ReaderCacheEntry, 0, len(dirEntries))
+	for _, de := range dirEntries {
+		if !de.IsDir() {
+			continue
+		}
+		bookID, err := strconv.ParseInt(de.Name(), 10, 64)
+		if err != nil {
+			continue
+		}
+		info, err := de.Info()
+		if err != nil {
+			continue
+		}
+		e := models.ReaderCacheEntry{BookID: bookID, Accessed: info.ModTime()}
+		e.Size, e.Files = dirSize(filepath.Join(s.dir, de.Name()))
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+func (s *FS) Delete(_ context.Context, bookID int64) (bool, error) {
+	dir := s.BookDir(bookID)
+	if _, err := os.Stat(dir); err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("stat cache dir: %w", err)
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		return false, fmt.Errorf("remove cache dir: %w", err)
+	}
+	return true, nil
+}
+
+// dirSize returns the total size and number of cache files under dir.
+// Temporary files of unfinished writes are not counted as cache files.
+func dirSize(dir string) (int64, int) {
+	var size int64
+	var files int
+	_ = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		size += info.Size()
+		if !strings.HasSuffix(path, ".tmp") {
+			files++
+		}
+		return nil
+	})
+	return size, files
+}
+
+// atomicWriteFile writes data to a temporary file and renames it into place,
+// preventing partial reads on concurrent access.
+func atomicWriteFile(path string, data []byte, perm os.FileMode) error {
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, perm); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
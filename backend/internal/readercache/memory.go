@@ -0,0 +1,81 @@
+package readercache
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/grom-alex/homelib/backend/internal/models"
+)
+
+// Memory keeps the cache in process memory. It is not shared between
+// replicas and is lost on restart; bound it with reader.cache_max_size.
+// Returned data is shared with the cache and must not be modified.
+type Memory struct {
+	mu    sync.Mutex
+	books map[int64]*memoryBook
+}
+
+type memoryBook struct {
+	files    map[string][]byte
+	size     int64
+	accessed time.Time
+}
+
+func NewMemory() *Memory {
+	return &Memory{books: make(map[int64]*memoryBook)}
+}
+
+func (s *Memory) Get(_ context.Context, bookID int64, name string) ([]byte, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b, ok := s.books[bookID]
+	if !ok {
+		return nil, false, nil
+	}
+	data, ok := b.files[name]
+	return data, ok, nil
+}
+
+func (s *Memory) Put(_ context.Context, bookID int64, name string, data []byte) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b, ok := s.books[bookID]
+	if !ok {
+		b = &memoryBook{files: make(map[string][]byte)}
+		s.books[bookID] = b
+	}
+	b.size += int64(len(data) - len(b.files[name]))
+	b.files[name] = data
+	b.accessed = time.Now()
+	return int64(len(data)), nil
+}
+
+func (s *Memory) Touch(_ context.Context, bookID int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if b, ok := s.books[bookID]; ok {
+		b.accessed = time.Now()
+	}
+	return nil
+}
+
+func (s *Memory) List(_ context.Context) ([]models.ReaderCacheEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entries := make([]models.ReaderCacheEntry, 0, len(s.books))
+	for id, b := range s.books {
+		entries = append(entries, models.ReaderCacheEntry{BookID: id, Size: b.size, Files: len(b.files), Accessed: b.accessed})
+	}
+	return entries, nil
+}
+
+func (s *Memory) Delete(_ context.Context, bookID int64) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.books[bookID]; !ok {
+		return false, nil
+	}
+	delete(s.books, bookID)
+	return true, nil
+}
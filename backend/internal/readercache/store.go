@@ -0,0 +1,27 @@
+// Package readercache stores files derived from books for the reader:
+// converted book structure, chapters, notes and embedded images.
+package readercache
+
+import (
+	"context"
+
+	"github.com/grom-alex/homelib/backend/internal/models"
+)
+
+// Store is a reader cache backend. Files are grouped per book, so that a
+// book is touched, evicted and purged as a whole. File names are chosen by
+// the caller from validated resource IDs.
+type Store interface {
+	// Get returns a cached file; found is false on a cache miss.
+	Get(ctx context.Context, bookID int64, name string) (data []byte, found bool, err error)
+	// Put stores a file, replacing the previous one with the same name.
+	// Returns the number of bytes the stored file takes, as List counts
+	// them.
+	Put(ctx context.Context, bookID int64, name string, data []byte) (int64, error)
+	// Touch marks the book as recently used.
+	Touch(ctx context.Context, bookID int64) error
+	// List returns one entry per cached book.
+	List(ctx context.Context) ([]models.ReaderCacheEntry, error)
+	// Delete removes all files of a book. Returns false if nothing was cached.
+	Delete(ctx context.Context, bookID int64) (bool, error)
+}
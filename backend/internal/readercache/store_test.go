@@ -0,0 +1,156 @@
+package readercache
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testStore checks the behaviour shared by all Store implementations.
+func testStore(t *testing.T, s Store) {
+	ctx := context.Background()
+
+	_, found, err := s.Get(ctx, 1, "content.json")
+	require.NoError(t, err)
+	assert.False(t, found)
+
+	put := func(bookID int64, name string, data []byte) {
+		t.Helper()
+		stored, err := s.Put(ctx, bookID, name, data)
+		require.NoError(t, err)
+		assert.Positive(t, stored)
+	}
+	put(1, "content.json", []byte(`{"title":"Мастер и Маргарита"}`))
+	put(1, "ch_1.html", []byte("old"))
+	put(1, "ch_1.html", []byte("<p>Глава первая</p>"))
+	put(2, "img_c.bin", []byte{0xff, 0xd8})
+
+	data, found, err := s.Get(ctx, 1, "ch_1.html")
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, "<p>Глава первая</p>", string(data))
+
+	require.NoError(t, s.Touch(ctx, 1))
+	require.NoError(t, s.Touch(ctx, 99))
+
+	entries, err := s.List(ctx)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	byID := map[int64]int{}
+	for _, e := range entries {
+		byID[e.BookID] = e.Files
+		assert.Positive(t, e.Size)
+		assert.WithinDuration(t, time.Now(), e.Accessed, time.Minute)
+	}
+	assert.Equal(t, map[int64]int{1: 2, 2: 1}, byID)
+
+	deleted, err := s.Delete(ctx, 1)
+	require.NoError(t, err)
+	assert.True(t, deleted)
+	deleted, err = s.Delete(ctx, 1)
+	require.NoError(t, err)
+	assert.False(t, deleted)
+
+	_, found, err = s.Get(ctx, 1, "content.json")
+	require.NoError(t, err)
+	assert.False(t, found)
+
+	entries, err = s.List(ctx)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+}
+
+func TestFS(t *testing.T) {
+	s, err := NewFS(t.TempDir())
+	require.NoError(t, err)
+	testStore(t, s)
+}
+
+func TestMemory(t *testing.T) {
+	testStore(t, NewMemory())
+}
+
+func TestMemory_Size(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemory()
+	for _, f := range []struct {
+		name string
+		size int
+	}{{"a", 100}, {"a", 40}, {"b", 10}} {
+		stored, err := s.Put(ctx, 1, f.name, make([]byte, f.size))
+		require.NoError(t, err)
+		assert.Equal(t, int64(f.size), stored)
+	}
+
+	entries, err := s.List(ctx)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, int64(50), entries[0].Size)
+}
+
+func TestFS_CompressesFiles(t *testing.T) {
+	ctx := context.Background()
+	s, err := NewFS(t.TempDir())
+	require.NoError(t, err)
+
+	html := bytes.Repeat([]byte("<p>Однажды весною, в час небывало жаркого заката, в Москве…</p>\n"), 200)
+	stored, err := s.Put(ctx, 42, "ch_1.html", html)
+	require.NoError(t, err)
+
+	info, err := os.Stat(filepath.Join(s.BookDir(42), "ch_1.html.zst"))
+	require.NoError(t, err)
+	assert.Less(t, info.Size(), int64(len(html)/10))
+	assert.Equal(t, info.Size(), stored)
+
+	entries, err := s.List(ctx)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, info.Size(), entries[0].Size)
+}
+
+func TestFS_CorruptFile(t *testing.T) {
+	ctx := context.Background()
+	s, err := NewFS(t.TempDir())
+	require.NoError(t, err)
+
+	require.NoError(t, os.MkdirAll(s.BookDir(1), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(s.BookDir(1), "content.json.zst"), []byte("{}"), 0o644))
+
+	_, found, err := s.Get(ctx, 1, "content.json")
+	assert.Error(t, err)
+	assert.False(t, found)
+}
+
+func TestFS_TouchUpdatesMtime(t *testing.T) {
+	ctx := context.Background()
+	s, err := NewFS(t.TempDir())
+	require.NoError(t, err)
+	_, err = s.Put(ctx, 1, "content.json", []byte("{}"))
+	require.NoError(t, err)
+
+	oldTime := time.Now().Add(-48 * time.Hour)
+	require.NoError(t, os.Chtimes(s.BookDir(1), oldTime, oldTime))
+	require.NoError(t, s.Touch(ctx, 1))
+
+	info, err := os.Stat(s.BookDir(1))
+	require.NoError(t, err)
+	assert.True(t, info.ModTime().After(oldTime))
+}
+
+func TestFS_ListSkipsForeignDirs(t *testing.T) {
+	dir := t.TempDir()
+	s, err := NewFS(dir)
+	require.NoError(t, err)
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "lost+found"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "README"), []byte("x"), 0o644))
+
+	entries, err := s.List(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
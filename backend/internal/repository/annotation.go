@@ -0,0 +1,151 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/grom-alex/homelib/backend/internal/models"
+)
+
+type AnnotationRepo struct {
+	pool Pool
+}
+
+func NewAnnotationRepo(pool Pool) *AnnotationRepo {
+	return &AnnotationRepo{pool: pool}
+}
+
+const annotationColumns = `id, user_id, book_id, type, chapter_id,
+	start_paragraph, start_offset, end_paragraph, end_offset,
+	quote, prefix, suffix, color, note, created_at, updated_at`
+
+func scanAnnotation(row pgx.Row, a *models.Annotation) error {
+	return row.Scan(&a.ID, &a.UserID, &a.BookID, &a.Type, &a.ChapterID,
+		&a.Locator.Start.Paragraph, &a.Locator.Start.Offset, &a.Locator.End.Paragraph, &a.Locator.End.Offset,
+		&a.Locator.Quote, &a.Locator.Prefix, &a.Locator.Suffix,
+		&a.Color, &a.Note, &a.CreatedAt, &a.UpdatedAt)
+}
+
+// Create inserts an annotation and fills its ID and timestamps.
+// Returns false if the book does not exist.
+func (r *AnnotationRepo) Create(ctx context.Context, a *models.Annotation) (bool, error) {
+	err := r.pool.QueryRow(ctx,
+		`INSERT INTO annotations (user_id, book_id, type, chapter_id,
+			start_paragraph, start_offset, end_paragraph, end_offset, quote, prefix, suffix, color, note)
+		 SELECT $1, b.id, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13 FROM books b WHERE b.id = $2
+		 RETURNING id, created_at, updated_at`,
+		a.UserID, a.BookID, a.Type, a.ChapterID,
+		a.Locator.Start.Paragraph, a.Locator.Start.Offset, a.Locator.End.Paragraph, a.Locator.End.Offset,
+		a.Locator.Quote, a.Locator.Prefix, a.Locator.Suffix, a.Color, a.Note,
+	).Scan(&a.ID, &a.CreatedAt, &a.UpdatedAt)
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("create annotation: %w", err)
+	}
+	return true, nil
+}
+
+// Get returns an annotation of the user in the book, or nil if not found.
+func (r *AnnotationRepo) Get(ctx context.Context, userID string, bookID, id int64) (*models.Annotation, error) {
+	var a models.Annotation
+	err := scanAnnotation(r.pool.QueryRow(ctx,
+		`SELECT `+annotationColumns+` FROM annotations WHERE id = $1 AND user_id = $2 AND book_id = $3`,
+		id, userID, bookID,
+	), &a)
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get annotation: %w", err)
+	}
+	return &a, nil
+}
+
+// ListByBook returns the user's annotations in a book in creation order.
+func (r *AnnotationRepo) ListByBook(ctx context.Context, userID string, bookID int64, f models.AnnotationFilter) ([]models.Annotation, error) {
+	rows, err := r.pool.Query(ctx,
+		`SELECT `+annotationColumns+` FROM annotations
+		 WHERE user_id = $1 AND book_id = $2
+		   AND ($3 = '' OR chapter_id = $3) AND ($4 = '' OR type = $4)
+		 ORDER BY id`,
+		userID, bookID, f.ChapterID, f.Type,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("list annotations: %w", err)
+	}
+	defer rows.Close()
+
+	result := []models.Annotation{}
+	for rows.Next() {
+		var a models.Annotation
+		if err := scanAnnotation(rows, &a); err != nil {
+			return nil, fmt.Errorf("scan annotation: %w", err)
+		}
+		result = append(result, a)
+	}
+	return result, rows.Err()
+}
+
+// ListByUser returns all annotations of the user grouped by book, each book
+// in creation order. Used by exports.
+func (r *AnnotationRepo) ListByUser(ctx context.Context, userID string) ([]models.Annotation, error) {
+	rows, err := r.pool.Query(ctx,
+		`SELECT `+annotationColumns+` FROM annotations WHERE user_id = $1 ORDER BY book_id, id`,
+		userID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("list user annotations: %w", err)
+	}
+	defer rows.Close()
+
+	result := []models.Annotation{}
+	for rows.Next() {
+		var a models.Annotation
+		if err := scanAnnotation(rows, &a); err != nil {
+			return nil, fmt.Errorf("scan annotation: %w", err)
+		}
+		result = append(result, a)
+	}
+	return result, rows.Err()
+}
+
+// Update saves the locator, colour and note of an annotation and refreshes
+// its UpdatedAt. Returns false if the annotation does not belong to the user.
+func (r *AnnotationRepo) Update(ctx context.Context, a *models.Annotation) (bool, error) {
+	err := r.pool.QueryRow(ctx,
+		`UPDATE annotations SET start_paragraph = $4, start_offset = $5, end_paragraph = $6, end_offset = $7,
+			quote = $8, prefix = $9, suffix = $10, color = $11, note = $12, updated_at = NOW()
+		 WHERE id = $1 AND user_id = $2 AND book_id = $3
+		 RETURNING updated_at`,
+		a.ID, a.UserID, a.BookID,
+		a.Locator.Start.Paragraph, a.Locator.Start.Offset, a.Locator.End.Paragraph, a.Locator.End.Offset,
+		a.Locator.Quote, a.Locator.Prefix, a.Locator.Suffix, a.Color, a.Note,
+	).Scan(&a.UpdatedAt)
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("update annotation: %w", err)
+	}
+	return true, nil
+}
+
+// Delete removes an annotation of the user. Returns false if nothing was deleted.
+func (r *AnnotationRepo) Delete(ctx context.Context, userID string, bookID, id int64) (bool, error) {
+	tag, err := r.pool.Exec(ctx,
+		`DELETE FROM annotations WHERE id = $1 AND user_id = $2 AND book_id = $3`,
+		id, userID, bookID,
+	)
+	if err != nil {
+		return false, fmt.Errorf("delete annotation: %w", err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
@@ -0,0 +1,218 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grom-alex/homelib/backend/internal/models"
+)
+
+var annotationRowColumns = []string{
+	"id", "user_id", "book_id", "type", "chapter_id",
+	"start_paragraph", "start_offset", "end_paragraph", "end_offset",
+	"quote", "prefix", "suffix", "color", "note", "created_at", "updated_at",
+}
+
+func TestAnnotationRepo_Create(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	repo := NewAnnotationRepo(mock)
+	now := time.Now()
+	a := &models.Annotation{
+		UserID: "user-1", BookID: 42, Type: models.AnnotationHighlight, ChapterID: "ch3",
+		Locator: models.TextLocator{Start: models.Locator{Paragraph: 2, Offset: 10}, End: models.Locator{Paragraph: 3, Offset: 20}, Quote: "текст", Prefix: "до", Suffix: "после"},
+		Color:   "yellow",
+	}
+
+	mock.ExpectQuery("INSERT INTO annotations .+ SELECT .+ FROM books b WHERE b.id = \\$2").
+		WithArgs("user-1", int64(42), "highlight", "ch3", 2, 10, 3, 20, "текст", "до", "после", "yellow", "").
+		WillReturnRows(pgxmock.NewRows([]string{"id", "created_at", "updated_at"}).AddRow(int64(5), now, now))
+
+	created, err := repo.Create(context.Background(), a)
+	require.NoError(t, err)
+	assert.True(t, created)
+	assert.Equal(t, int64(5), a.ID)
+	assert.Equal(t, now, a.CreatedAt)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAnnotationRepo_Create_BookNotFound(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	repo := NewAnnotationRepo(mock)
+	mock.ExpectQuery("INSERT INTO annotations").
+		WithArgs(pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg()).
+		WillReturnError(pgx.ErrNoRows)
+
+	created, err := repo.Create(context.Background(), &models.Annotation{UserID: "user-1", BookID: 999})
+	require.NoError(t, err)
+	assert.False(t, created)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAnnotationRepo_Create_Error(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	repo := NewAnnotationRepo(mock)
+	mock.ExpectQuery("INSERT INTO annotations").
+		WithArgs(pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg()).
+		WillReturnError(fmt.Errorf("db error"))
+
+	_, err = repo.Create(context.Background(), &models.Annotation{UserID: "user-1", BookID: 42})
+	assert.ErrorContains(t, err, "create annotation")
+}
+
+func TestAnnotationRepo_Get(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	repo := NewAnnotationRepo(mock)
+	now := time.Now()
+
+	mock.ExpectQuery("SELECT .+ FROM annotations WHERE id = \\$1 AND user_id = \\$2 AND book_id = \\$3").
+		WithArgs(int64(5), "user-1", int64(42)).
+		WillReturnRows(pgxmock.NewRows(annotationRowColumns).
+			AddRow(int64(5), "user-1", int64(42), "note", "ch3", 1, 10, 1, 20, "текст", "", "", "", "мысль", now, now))
+
+	a, err := repo.Get(context.Background(), "user-1", 42, 5)
+	require.NoError(t, err)
+	require.NotNil(t, a)
+	assert.Equal(t, models.AnnotationNote, a.Type)
+	assert.Equal(t, models.Locator{Paragraph: 1, Offset: 20}, a.Locator.End)
+	assert.Equal(t, "мысль", a.Note)
+
+	mock.ExpectQuery("SELECT .+ FROM annotations").
+		WithArgs(int64(6), "user-1", int64(42)).
+		WillReturnError(pgx.ErrNoRows)
+
+	a, err = repo.Get(context.Background(), "user-1", 42, 6)
+	require.NoError(t, err)
+	assert.Nil(t, a)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAnnotationRepo_ListByBook(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	repo := NewAnnotationRepo(mock)
+	now := time.Now()
+
+	mock.ExpectQuery("SELECT .+ FROM annotations\\s+WHERE user_id = \\$1 AND book_id = \\$2").
+		WithArgs("user-1", int64(42), "ch3", "").
+		WillReturnRows(pgxmock.NewRows(annotationRowColumns).
+			AddRow(int64(1), "user-1", int64(42), "bookmark", "ch3", 0, 0, 0, 0, "", "", "", "", "", now, now).
+			AddRow(int64(2), "user-1", int64(42), "highlight", "ch3", 0, 5, 0, 9, "abcd", "", "", "green", "", now, now))
+
+	items, err := repo.ListByBook(context.Background(), "user-1", 42, models.AnnotationFilter{ChapterID: "ch3"})
+	require.NoError(t, err)
+	require.Len(t, items, 2)
+	assert.Equal(t, int64(1), items[0].ID)
+	assert.Equal(t, "green", items[1].Color)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAnnotationRepo_ListByBook_Empty(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	repo := NewAnnotationRepo(mock)
+	mock.ExpectQuery("SELECT .+ FROM annotations").
+		WithArgs("user-1", int64(42), "", "").
+		WillReturnRows(pgxmock.NewRows(annotationRowColumns))
+
+	items, err := repo.ListByBook(context.Background(), "user-1", 42, models.AnnotationFilter{})
+	require.NoError(t, err)
+	assert.NotNil(t, items)
+	assert.Empty(t, items)
+}
+
+func TestAnnotationRepo_ListByUser(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	repo := NewAnnotationRepo(mock)
+	now := time.Now()
+
+	mock.ExpectQuery("SELECT .+ FROM annotations WHERE user_id = \\$1 ORDER BY book_id, id").
+		WithArgs("user-1").
+		WillReturnRows(pgxmock.NewRows(annotationRowColumns).
+			AddRow(int64(3), "user-1", int64(7), "bookmark", "ch1", 0, 0, 0, 0, "", "", "", "", "", now, now).
+			AddRow(int64(1), "user-1", int64(42), "highlight", "ch3", 0, 5, 0, 9, "abcd", "", "", "green", "", now, now))
+
+	items, err := repo.ListByUser(context.Background(), "user-1")
+	require.NoError(t, err)
+	require.Len(t, items, 2)
+	assert.Equal(t, int64(7), items[0].BookID)
+	assert.Equal(t, int64(42), items[1].BookID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAnnotationRepo_Update(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	repo := NewAnnotationRepo(mock)
+	now := time.Now()
+	a := &models.Annotation{ID: 5, UserID: "user-1", BookID: 42, Color: "blue", Note: "новая"}
+
+	mock.ExpectQuery("UPDATE annotations SET .+ WHERE id = \\$1 AND user_id = \\$2 AND book_id = \\$3").
+		WithArgs(int64(5), "user-1", int64(42), 0, 0, 0, 0, "", "", "", "blue", "новая").
+		WillReturnRows(pgxmock.NewRows([]string{"updated_at"}).AddRow(now))
+
+	updated, err := repo.Update(context.Background(), a)
+	require.NoError(t, err)
+	assert.True(t, updated)
+	assert.Equal(t, now, a.UpdatedAt)
+
+	mock.ExpectQuery("UPDATE annotations").
+		WithArgs(int64(5), "user-1", int64(42), 0, 0, 0, 0, "", "", "", "blue", "новая").
+		WillReturnError(pgx.ErrNoRows)
+
+	updated, err = repo.Update(context.Background(), a)
+	require.NoError(t, err)
+	assert.False(t, updated)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAnnotationRepo_Delete(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	repo := NewAnnotationRepo(mock)
+
+	mock.ExpectExec("DELETE FROM annotations WHERE id = \\$1 AND user_id = \\$2 AND book_id = \\$3").
+		WithArgs(int64(5), "user-1", int64(42)).
+		WillReturnResult(pgxmock.NewResult("DELETE", 1))
+	mock.ExpectExec("DELETE FROM annotations").
+		WithArgs(int64(6), "user-1", int64(42)).
+		WillReturnResult(pgxmock.NewResult("DELETE", 0))
+
+	deleted, err := repo.Delete(context.Background(), "user-1", 42, 5)
+	require.NoError(t, err)
+	assert.True(t, deleted)
+
+	deleted, err = repo.Delete(context.Background(), "user-1", 42, 6)
+	require.NoError(t, err)
+	assert.False(t, deleted)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -3,11 +3,13 @@ package repository
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 
 	"github.com/grom-alex/homelib/backend/internal/models"
+	"github.com/grom-alex/homelib/backend/internal/translit"
 )
 
 type AuthorRepo struct {
@@ -26,14 +28,14 @@ func (r *AuthorRepo) UpsertAuthors(ctx context.Context, tx pgx.Tx, authors []mod
 		return make(map[string]int64), nil
 	}
 
-	const upsertSQL = `INSERT INTO authors (name, name_sort)
-		 VALUES ($1, $2)
-		 ON CONFLICT (name_sort) DO UPDATE SET name = EXCLUDED.name
+	const upsertSQL = `INSERT INTO authors (name, name_sort, name_translit)
+		 VALUES ($1, $2, $3)
+		 ON CONFLICT (name_sort) DO UPDATE SET name = EXCLUDED.name, name_translit = EXCLUDED.name_translit
 		 RETURNING id`
 
 	batch := &pgx.Batch{}
 	for _, a := range authors {
-		batch.Queue(upsertSQL, a.Name, a.NameSort)
+		batch.Queue(upsertSQL, a.Name, a.NameSort, translit.Key(a.Name))
 	}
 
 	br := tx.SendBatch(ctx, batch)
@@ -62,22 +64,46 @@ func (r *AuthorRepo) GetByID(ctx context.Context, id int64) (*models.Author, err
 	return &a, nil
 }
 
-func (r *AuthorRepo) ListWithBookCount(ctx context.Context, query string, limit, offset int) ([]models.AuthorListItem, int, error) {
+// authorSortKey orders authors by their sort name (surname first).
+var authorSortKey = sortKey{expr: "a.name_sort", sqlType: "text"}
+
+// ListWithBookCount returns authors matching the filter with their book counts.
+// Supports both page/limit (OFFSET) and keyset pagination via f.Cursor.
+func (r *AuthorRepo) ListWithBookCount(ctx context.Context, f models.ListFilter) ([]models.AuthorListItem, models.PageInfo, error) {
+	var page models.PageInfo
+
+	cursor, err := decodeCursorFor(f.Cursor, "name", "asc")
+	if err != nil {
+		return nil, page, err
+	}
+
+	var conditions []string
 	var args []any
-	where := ""
 	argIdx := 1
 
-	if query != "" {
-		where = fmt.Sprintf(" WHERE a.name ILIKE '%%' || $%d || '%%'", argIdx)
-		args = append(args, query)
-		argIdx++
+	if f.Query != "" {
+		cond, condArgs, next := nameMatchCondition("a.name", "a.name_translit", f.Query, argIdx)
+		conditions = append(conditions, cond)
+		args = append(args, condArgs...)
+		argIdx = next
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = " WHERE " + strings.Join(conditions, " AND ")
 	}
 
-	var total int
-	countQuery := "SELECT COUNT(*) FROM authors a" + where
-	err := r.pool.QueryRow(ctx, countQuery, args...).Scan(&total)
+	page.Total, page.TotalEstimated, err = countRows(ctx, r.pool, f.Count, "authors a"+where, args)
 	if err != nil {
-		return nil, 0, fmt.Errorf("count authors: %w", err)
+		return nil, page, fmt.Errorf("count authors: %w", err)
+	}
+
+	if cursor != nil {
+		cond, cursorArgs, next := keysetCondition(authorSortKey, "a.id", false, cursor, argIdx)
+		conditions = append(conditions, cond)
+		args = append(args, cursorArgs...)
+		argIdx = next
+		where = " WHERE " + strings.Join(conditions, " AND ")
 	}
 
 	listQuery := fmt.Sprintf(
@@ -85,32 +111,46 @@ func (r *AuthorRepo) ListWithBookCount(ctx context.Context, query string, limit,
 		   SELECT a.id, a.name, a.name_sort
 		   FROM authors a
 		   %s
-		   ORDER BY a.name_sort
+		   ORDER BY a.name_sort, a.id
 		   LIMIT $%d OFFSET $%d
 		 )
-		 SELECT pa.id, pa.name, COUNT(ba.book_id) AS books_count
+		 SELECT pa.id, pa.name, pa.name_sort, COUNT(ba.book_id) AS books_count
 		 FROM page_authors pa
 		 LEFT JOIN book_authors ba ON ba.author_id = pa.id
 		 GROUP BY pa.id, pa.name, pa.name_sort
-		 ORDER BY pa.name_sort`,
+		 ORDER BY pa.name_sort, pa.id`,
 		where, argIdx, argIdx+1,
 	)
-	args = append(args, limit, offset)
+	// Fetch one extra row to detect whether a next page exists
+	args = append(args, f.Limit+1, f.Offset())
 
 	rows, err := r.pool.Query(ctx, listQuery, args...)
 	if err != nil {
-		return nil, 0, fmt.Errorf("list authors: %w", err)
+		return nil, page, fmt.Errorf("list authors: %w", err)
 	}
 	defer rows.Close()
 
 	var items []models.AuthorListItem
+	var keys []*string
+	var ids []int64
 	for rows.Next() {
 		var item models.AuthorListItem
-		if err := rows.Scan(&item.ID, &item.Name, &item.BooksCount); err != nil {
-			return nil, 0, fmt.Errorf("scan author: %w", err)
+		var nameSort string
+		if err := rows.Scan(&item.ID, &item.Name, &nameSort, &item.BooksCount); err != nil {
+			return nil, page, fmt.Errorf("scan author: %w", err)
 		}
 		items = append(items, item)
+		keys = append(keys, &nameSort)
+		ids = append(ids, item.ID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, page, err
+	}
+
+	page.NextCursor = nextCursor("name", "asc", f.Limit, keys, ids)
+	if len(items) > f.Limit {
+		items = items[:f.Limit]
 	}
 
-	return items, total, rows.Err()
+	return items, page, nil
 }
@@ -9,6 +9,7 @@ import (
 	"github.com/jackc/pgx/v5/pgxpool"
 
 	"github.com/grom-alex/homelib/backend/internal/models"
+	"github.com/grom-alex/homelib/backend/internal/translit"
 )
 
 type BookRepo struct {
@@ -29,10 +30,11 @@ func (r *BookRepo) BatchUpsert(ctx context.Context, tx pgx.Tx, books []models.Bo
 
 	const upsertSQL = `INSERT INTO books (collection_id, title, lang, year, format, file_size,
 			archive_name, file_in_archive, series_id, series_num, series_type,
-			lib_id, lib_rate, is_deleted, description, keywords, date_added)
-		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17)
+			lib_id, lib_rate, is_deleted, description, keywords, date_added, title_translit)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18)
 		 ON CONFLICT (collection_id, lib_id) DO UPDATE SET
 			title = EXCLUDED.title,
+			title_translit = EXCLUDED.title_translit,
 			lang = EXCLUDED.lang,
 			year = EXCLUDED.year,
 			format = EXCLUDED.format,
@@ -56,6 +58,7 @@ func (r *BookRepo) BatchUpsert(ctx context.Context, tx pgx.Tx, books []models.Bo
 			books[i].CollectionID, books[i].Title, books[i].Lang, books[i].Year, books[i].Format, books[i].FileSize,
 			books[i].ArchiveName, books[i].FileInArchive, books[i].SeriesID, books[i].SeriesNum, books[i].SeriesType,
 			books[i].LibID, books[i].LibRate, books[i].IsDeleted, books[i].Description, books[i].Keywords, books[i].DateAdded,
+			translit.Key(books[i].Title),
 		)
 	}
 
@@ -168,10 +171,12 @@ func (r *BookRepo) GetByID(ctx context.Context, id int64) (*models.BookDetail, e
 	var b models.BookDetail
 	err := r.pool.QueryRow(ctx,
 		`SELECT b.id, b.title, b.lang, b.year, b.format, b.file_size,
-				b.lib_rate, b.is_deleted, b.description, b.keywords, b.date_added
+				b.lib_rate, b.household_rate, b.household_rate_count,
+				b.is_deleted, b.description, b.keywords, b.date_added
 		 FROM books b WHERE b.id = $1`, id,
 	).Scan(&b.ID, &b.Title, &b.Lang, &b.Year, &b.Format, &b.FileSize,
-		&b.LibRate, &b.IsDeleted, &b.Description, &b.Keywords, &b.DateAdded)
+		&b.LibRate, &b.HouseholdRate, &b.HouseholdRateCount,
+		&b.IsDeleted, &b.Description, &b.Keywords, &b.DateAdded)
 	if err != nil {
 		return nil, fmt.Errorf("get book %d: %w", id, err)
 	}
@@ -253,129 +258,245 @@ func (r *BookRepo) GetByID(ctx context.Context, id int64) (*models.BookDetail, e
 	return &b, nil
 }
 
-// List returns books matching the filter with pagination.
-func (r *BookRepo) List(ctx context.Context, f models.BookFilter) ([]models.BookListItem, int, error) {
+// bookSortKeys maps BookFilter.Sort values to sortable columns.
+var bookSortKeys = map[string]sortKey{
+	"title":          {expr: "b.title", sqlType: "text"},
+	"year":           {expr: "b.year", sqlType: "int", nullable: true},
+	"added_at":       {expr: "b.added_at", sqlType: "timestamptz", nullable: true},
+	"lib_rate":       {expr: "b.lib_rate", sqlType: "smallint", nullable: true},
+	"lang":           {expr: "b.lang", sqlType: "text"},
+	"format":         {expr: "b.format", sqlType: "text"},
+	"file_size":      {expr: "b.file_size", sqlType: "bigint", nullable: true},
+	"household_rate": {expr: "b.household_rate", sqlType: "numeric", nullable: true},
+}
+
+// genreSubtreeCondition returns an EXISTS fragment matching books linked to
+// any of the root genres selected by rootPredicate or to their descendants
+// (cascading via materialized path). rootPredicate refers to the parameter
+// $argIdx, e.g. "root.id = ANY($%d::int[])".
+func genreSubtreeCondition(alias, rootPredicate string, argIdx int) string {
+	return fmt.Sprintf(
+		`EXISTS (SELECT 1 FROM book_genres %[1]s WHERE %[1]s.book_id = b.id AND %[1]s.genre_id IN (
+				SELECT g.id FROM genres g JOIN genres root ON %[2]s AND root.is_active = TRUE
+				WHERE g.is_active = TRUE AND (g.id = root.id OR g.position LIKE root.position || '.%%')
+			))`, alias, fmt.Sprintf(rootPredicate, argIdx))
+}
+
+const (
+	genreByID   = "root.id = ANY($%d::int[])"
+	genreByCode = "root.code = ANY($%d::text[])"
+)
+
+// buildBookConditions translates filter fields into WHERE conditions.
+// Placeholders are numbered from 1; the next free index is returned.
+func buildBookConditions(f models.BookFilter) ([]string, []any, int) {
 	var conditions []string
 	var args []any
 	argIdx := 1
 
-	if f.Query != "" {
-		conditions = append(conditions, fmt.Sprintf(
-			"b.search_vector @@ plainto_tsquery('russian', $%d)", argIdx))
-		args = append(args, f.Query)
+	add := func(format string, value any) {
+		conditions = append(conditions, fmt.Sprintf(format, argIdx))
+		args = append(args, value)
 		argIdx++
 	}
+
+	if !f.IncludeDeleted {
+		conditions = append(conditions, "NOT b.is_deleted")
+	}
+	if f.Query != "" {
+		if f.Syntax == "advanced" {
+			// Remainder of the search query language: quoted phrases and -words
+			add("b.search_vector @@ websearch_to_tsquery('russian', $%d)", f.Query)
+		} else if key := translit.Key(f.Query); len(key) >= minTranslitKeyLen {
+			// Also match titles typed in the other script
+			conditions = append(conditions, fmt.Sprintf(
+				"(b.search_vector @@ plainto_tsquery('russian', $%d) OR b.title_translit LIKE '%%' || $%d || '%%')",
+				argIdx, argIdx+1))
+			args = append(args, f.Query, key)
+			argIdx += 2
+		} else {
+			add("b.search_vector @@ plainto_tsquery('russian', $%d)", f.Query)
+		}
+	}
 	if f.AuthorID != nil {
-		conditions = append(conditions, fmt.Sprintf(
-			"EXISTS (SELECT 1 FROM book_authors ba WHERE ba.book_id = b.id AND ba.author_id = $%d)", argIdx))
-		args = append(args, *f.AuthorID)
-		argIdx++
+		add("EXISTS (SELECT 1 FROM book_authors ba WHERE ba.book_id = b.id AND ba.author_id = $%d)", *f.AuthorID)
 	}
 	if f.AuthorName != "" {
-		conditions = append(conditions, fmt.Sprintf(
-			"EXISTS (SELECT 1 FROM book_authors ba JOIN authors a ON a.id = ba.author_id WHERE ba.book_id = b.id AND a.name ILIKE '%%' || $%d || '%%')", argIdx))
-		args = append(args, f.AuthorName)
+		cond, condArgs, next := nameMatchCondition("a.name", "a.name_translit", f.AuthorName, argIdx)
+		conditions = append(conditions, "EXISTS (SELECT 1 FROM book_authors ba JOIN authors a ON a.id = ba.author_id WHERE ba.book_id = b.id AND "+cond+")")
+		args = append(args, condArgs...)
+		argIdx = next
+	}
+	if len(f.GenreIDs) > 0 {
+		conditions = append(conditions, genreSubtreeCondition("bg", genreByID, argIdx))
+		args = append(args, f.GenreIDs)
 		argIdx++
 	}
-	if f.GenreID != nil {
-		// Cascading filter: match the genre itself AND all its descendants via materialized path
-		conditions = append(conditions, fmt.Sprintf(
-			`EXISTS (SELECT 1 FROM book_genres bg WHERE bg.book_id = b.id AND bg.genre_id IN (
-				SELECT g.id FROM genres g WHERE g.is_active = TRUE AND (
-					g.id = $%d OR g.position LIKE (SELECT position || '.%%' FROM genres WHERE id = $%d AND is_active = TRUE)
-				)
-			))`, argIdx, argIdx))
-		args = append(args, *f.GenreID)
+	if len(f.NotGenreIDs) > 0 {
+		conditions = append(conditions, "NOT "+genreSubtreeCondition("bg3", genreByID, argIdx))
+		args = append(args, f.NotGenreIDs)
 		argIdx++
 	}
-	if f.SeriesID != nil {
-		conditions = append(conditions, fmt.Sprintf("b.series_id = $%d", argIdx))
-		args = append(args, *f.SeriesID)
+	if len(f.GenreCodes) > 0 {
+		conditions = append(conditions, genreSubtreeCondition("bg4", genreByCode, argIdx))
+		args = append(args, f.GenreCodes)
 		argIdx++
 	}
-	if f.SeriesName != "" {
-		conditions = append(conditions, fmt.Sprintf(
-			"EXISTS (SELECT 1 FROM series s WHERE s.id = b.series_id AND s.name ILIKE '%%' || $%d || '%%')", argIdx))
-		args = append(args, f.SeriesName)
+	if len(f.NotGenreCodes) > 0 {
+		conditions = append(conditions, "NOT "+genreSubtreeCondition("bg5", genreByCode, argIdx))
+		args = append(args, f.NotGenreCodes)
 		argIdx++
 	}
-	if f.Lang != "" {
-		conditions = append(conditions, fmt.Sprintf("b.lang = $%d", argIdx))
-		args = append(args, f.Lang)
-		argIdx++
+	if f.SeriesID != nil {
+		add("b.series_id = $%d", *f.SeriesID)
+	}
+	if f.SeriesName != "" {
+		cond, condArgs, next := nameMatchCondition("s.name", "s.name_translit", f.SeriesName, argIdx)
+		conditions = append(conditions, "EXISTS (SELECT 1 FROM series s WHERE s.id = b.series_id AND "+cond+")")
+		args = append(args, condArgs...)
+		argIdx = next
+	}
+	if f.SeriesType != "" {
+		add("b.series_type = $%d", f.SeriesType)
+	}
+	if len(f.Langs) > 0 {
+		add("b.lang = ANY($%d::text[])", f.Langs)
+	}
+	if len(f.Formats) > 0 {
+		add("b.format = ANY($%d::text[])", f.Formats)
+	}
+	if len(f.Keywords) > 0 {
+		// Array containment is served by the idx_books_keywords GIN index
+		add("b.keywords @> $%d::text[]", f.Keywords)
+	}
+	if f.YearFrom != nil {
+		add("b.year >= $%d", *f.YearFrom)
 	}
-	if f.Format != "" {
-		conditions = append(conditions, fmt.Sprintf("b.format = $%d", argIdx))
-		args = append(args, f.Format)
+	if f.YearTo != nil {
+		add("b.year <= $%d", *f.YearTo)
+	}
+	if f.LibRateMin != nil {
+		add("b.lib_rate >= $%d", *f.LibRateMin)
+	}
+	if f.LibRateMax != nil {
+		add("b.lib_rate <= $%d", *f.LibRateMax)
+	}
+	if f.SizeMin != nil {
+		add("b.file_size >= $%d", *f.SizeMin)
+	}
+	if f.SizeMax != nil {
+		add("b.file_size <= $%d", *f.SizeMax)
+	}
+	if f.DateAddedFrom != nil {
+		add("b.date_added >= $%d::date", *f.DateAddedFrom)
+	}
+	if f.DateAddedTo != nil {
+		add("b.date_added <= $%d::date", *f.DateAddedTo)
+	}
+	if f.ShelfID != nil || f.Shelf != "" {
+		// Shelves are private: without a user nothing matches
+		shelfConds := []string{fmt.Sprintf("sh.user_id = $%d::uuid", argIdx)}
+		args = append(args, f.UserID)
 		argIdx++
+		if f.ShelfID != nil {
+			shelfConds = append(shelfConds, fmt.Sprintf("sh.id = $%d", argIdx))
+			args = append(args, *f.ShelfID)
+			argIdx++
+		}
+		if f.Shelf != "" {
+			shelfConds = append(shelfConds, fmt.Sprintf("sh.status = $%d", argIdx))
+			args = append(args, f.Shelf)
+			argIdx++
+		}
+		conditions = append(conditions, "EXISTS (SELECT 1 FROM shelf_books sb JOIN shelves sh ON sh.id = sb.shelf_id WHERE sb.book_id = b.id AND "+
+			strings.Join(shelfConds, " AND ")+")")
 	}
 	if len(f.ExcludeGenreIDs) > 0 {
-		conditions = append(conditions, fmt.Sprintf(
-			"NOT EXISTS (SELECT 1 FROM book_genres bg2 WHERE bg2.book_id = b.id AND bg2.genre_id = ANY($%d::int[]))", argIdx))
-		args = append(args, f.ExcludeGenreIDs)
-		argIdx++
+		add("NOT EXISTS (SELECT 1 FROM book_genres bg2 WHERE bg2.book_id = b.id AND bg2.genre_id = ANY($%d::int[]))", f.ExcludeGenreIDs)
 	}
 
+	return conditions, args, argIdx
+}
+
+// List returns books matching the filter with pagination.
+// Supports both page/limit (OFFSET) and keyset pagination via f.Cursor.
+func (r *BookRepo) List(ctx context.Context, f models.BookFilter) ([]models.BookListItem, models.PageInfo, error) {
+	var page models.PageInfo
+
+	sortName := f.Sort
+	key, ok := bookSortKeys[sortName]
+	if !ok {
+		sortName = "title"
+		key = bookSortKeys[sortName]
+	}
+	order := orderDirection(f.Order)
+
+	cursor, err := decodeCursorFor(f.Cursor, sortName, order)
+	if err != nil {
+		return nil, page, err
+	}
+
+	conditions, args, argIdx := buildBookConditions(f)
+
 	where := ""
 	if len(conditions) > 0 {
 		where = "WHERE " + strings.Join(conditions, " AND ")
 	}
 
-	// Count
-	var total int
-	countQuery := fmt.Sprintf("SELECT COUNT(*) FROM books b %s", where)
-	err := r.pool.QueryRow(ctx, countQuery, args...).Scan(&total)
+	// Total is computed over the whole filtered set, independent of the cursor
+	page.Total, page.TotalEstimated, err = countRows(ctx, r.pool, f.Count, "books b "+where, args)
 	if err != nil {
-		return nil, 0, fmt.Errorf("count books: %w", err)
-	}
-
-	// Sort
-	orderCol := "b.title"
-	switch f.Sort {
-	case "year":
-		orderCol = "b.year"
-	case "added_at":
-		orderCol = "b.added_at"
-	case "lib_rate":
-		orderCol = "b.lib_rate"
-	case "lang":
-		orderCol = "b.lang"
-	case "format":
-		orderCol = "b.format"
-	case "file_size":
-		orderCol = "b.file_size"
-	}
-	orderDir := "ASC"
-	if strings.EqualFold(f.Order, "desc") {
-		orderDir = "DESC"
+		return nil, page, fmt.Errorf("count books: %w", err)
+	}
+
+	if cursor != nil {
+		cond, cursorArgs, next := keysetCondition(key, "b.id", order == "desc", cursor, argIdx)
+		conditions = append(conditions, cond)
+		args = append(args, cursorArgs...)
+		argIdx = next
+		where = "WHERE " + strings.Join(conditions, " AND ")
 	}
 
+	orderDir := strings.ToUpper(order)
 	listQuery := fmt.Sprintf(
-		`SELECT b.id, b.title, b.lang, b.year, b.format, b.file_size, b.lib_rate, b.is_deleted
+		`SELECT b.id, b.title, b.lang, b.year, b.format, b.file_size, b.lib_rate,
+			b.household_rate, b.household_rate_count, b.is_deleted, %s::text
 		 FROM books b %s
-		 ORDER BY %s %s NULLS LAST
+		 ORDER BY %s %s NULLS LAST, b.id %s
 		 LIMIT $%d OFFSET $%d`,
-		where, orderCol, orderDir, argIdx, argIdx+1,
+		key.expr, where, key.expr, orderDir, orderDir, argIdx, argIdx+1,
 	)
-	args = append(args, f.Limit, f.Offset())
+	// Fetch one extra row to detect whether a next page exists
+	args = append(args, f.Limit+1, f.Offset())
 
 	rows, err := r.pool.Query(ctx, listQuery, args...)
 	if err != nil {
-		return nil, 0, fmt.Errorf("list books: %w", err)
+		return nil, page, fmt.Errorf("list books: %w", err)
 	}
 	defer rows.Close()
 
 	var items []models.BookListItem
+	var keys []*string
+	var ids []int64
 	for rows.Next() {
 		var item models.BookListItem
+		var sortVal *string
 		if err := rows.Scan(&item.ID, &item.Title, &item.Lang, &item.Year,
-			&item.Format, &item.FileSize, &item.LibRate, &item.IsDeleted); err != nil {
-			return nil, 0, fmt.Errorf("scan book: %w", err)
+			&item.Format, &item.FileSize, &item.LibRate,
+			&item.HouseholdRate, &item.HouseholdRateCount, &item.IsDeleted, &sortVal); err != nil {
+			return nil, page, fmt.Errorf("scan book: %w", err)
 		}
 		items = append(items, item)
+		keys = append(keys, sortVal)
+		ids = append(ids, item.ID)
 	}
 	if err := rows.Err(); err != nil {
-		return nil, 0, err
+		return nil, page, err
+	}
+
+	page.NextCursor = nextCursor(sortName, order, f.Limit, keys, ids)
+	if len(items) > f.Limit {
+		items = items[:f.Limit]
 	}
 
 	// Batch load authors, genres, series for all books (3 queries instead of 3*N)
@@ -387,15 +508,15 @@ func (r *BookRepo) List(ctx context.Context, f models.BookFilter) ([]models.Book
 
 		authorsMap, err := r.getBookAuthorRefsBatch(ctx, bookIDs)
 		if err != nil {
-			return nil, 0, fmt.Errorf("batch load authors: %w", err)
+			return nil, page, fmt.Errorf("batch load authors: %w", err)
 		}
 		genresMap, err := r.getBookGenreRefsBatch(ctx, bookIDs)
 		if err != nil {
-			return nil, 0, fmt.Errorf("batch load genres: %w", err)
+			return nil, page, fmt.Errorf("batch load genres: %w", err)
 		}
 		seriesMap, err := r.getBookSeriesRefsBatch(ctx, bookIDs)
 		if err != nil {
-			return nil, 0, fmt.Errorf("batch load series: %w", err)
+			return nil, page, fmt.Errorf("batch load series: %w", err)
 		}
 
 		for i := range items {
@@ -405,7 +526,7 @@ func (r *BookRepo) List(ctx context.Context, f models.BookFilter) ([]models.Book
 		}
 	}
 
-	return items, total, nil
+	return items, page, nil
 }
 
 // GetBookForDownload returns archive and file info for downloading.
@@ -0,0 +1,191 @@
+package repository
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/grom-alex/homelib/backend/internal/models"
+)
+
+func intPtr(v int) *int { return &v }
+
+func TestBuildBookConditions_Empty(t *testing.T) {
+	conds, args, next := buildBookConditions(models.BookFilter{})
+
+	assert.Equal(t, []string{"NOT b.is_deleted"}, conds)
+	assert.Empty(t, args)
+	assert.Equal(t, 1, next)
+}
+
+func TestBuildBookConditions_IncludeDeleted(t *testing.T) {
+	conds, _, _ := buildBookConditions(models.BookFilter{IncludeDeleted: true})
+	assert.Empty(t, conds)
+}
+
+func TestBuildBookConditions_MultiValued(t *testing.T) {
+	conds, args, next := buildBookConditions(models.BookFilter{
+		IncludeDeleted: true,
+		Langs:          []string{"ru", "uk"},
+		Formats:        []string{"fb2", "epub"},
+		Keywords:       []string{"магия"},
+	})
+
+	assert.Equal(t, []string{
+		"b.lang = ANY($1::text[])",
+		"b.format = ANY($2::text[])",
+		"b.keywords @> $3::text[]",
+	}, conds)
+	assert.Equal(t, []any{[]string{"ru", "uk"}, []string{"fb2", "epub"}, []string{"магия"}}, args)
+	assert.Equal(t, 4, next)
+}
+
+func TestBuildBookConditions_GenreIncludeExclude(t *testing.T) {
+	conds, args, _ := buildBookConditions(models.BookFilter{
+		IncludeDeleted: true,
+		GenreIDs:       []int{1, 2},
+		NotGenreIDs:    []int{7},
+	})
+
+	if assert.Len(t, conds, 2) {
+		assert.True(t, strings.HasPrefix(conds[0], "EXISTS (SELECT 1 FROM book_genres bg "))
+		assert.Contains(t, conds[0], "root.id = ANY($1::int[])")
+		assert.Contains(t, conds[0], "g.position LIKE root.position || '.%'")
+		assert.True(t, strings.HasPrefix(conds[1], "NOT EXISTS (SELECT 1 FROM book_genres bg3 "))
+		assert.Contains(t, conds[1], "root.id = ANY($2::int[])")
+	}
+	assert.Equal(t, []any{[]int{1, 2}, []int{7}}, args)
+}
+
+func TestBuildBookConditions_Ranges(t *testing.T) {
+	size := int64(1024)
+	from := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2021, 12, 31, 0, 0, 0, 0, time.UTC)
+
+	conds, args, next := buildBookConditions(models.BookFilter{
+		IncludeDeleted: true,
+		YearFrom:       intPtr(1960),
+		YearTo:         intPtr(1980),
+		LibRateMin:     intPtr(3),
+		LibRateMax:     intPtr(5),
+		SizeMin:        &size,
+		SizeMax:        &size,
+		DateAddedFrom:  &from,
+		DateAddedTo:    &to,
+	})
+
+	assert.Equal(t, []string{
+		"b.year >= $1",
+		"b.year <= $2",
+		"b.lib_rate >= $3",
+		"b.lib_rate <= $4",
+		"b.file_size >= $5",
+		"b.file_size <= $6",
+		"b.date_added >= $7::date",
+		"b.date_added <= $8::date",
+	}, conds)
+	assert.Equal(t, []any{1960, 1980, 3, 5, size, size, from, to}, args)
+	assert.Equal(t, 9, next)
+}
+
+func TestBuildBookConditions_SeriesAndText(t *testing.T) {
+	authorID := int64(5)
+	seriesID := int64(8)
+	conds, args, _ := buildBookConditions(models.BookFilter{
+		Query:      "пикник",
+		AuthorID:   &authorID,
+		AuthorName: "Стругацкий",
+		SeriesID:   &seriesID,
+		SeriesName: "Полдень",
+		SeriesType: "p",
+	})
+
+	assert.Equal(t, []string{
+		"NOT b.is_deleted",
+		"(b.search_vector @@ plainto_tsquery('russian', $1) OR b.title_translit LIKE '%' || $2 || '%')",
+		"EXISTS (SELECT 1 FROM book_authors ba WHERE ba.book_id = b.id AND ba.author_id = $3)",
+		"EXISTS (SELECT 1 FROM book_authors ba JOIN authors a ON a.id = ba.author_id WHERE ba.book_id = b.id AND (a.name ILIKE '%' || $4 || '%' OR a.name_translit LIKE '%' || $5 || '%'))",
+		"b.series_id = $6",
+		"EXISTS (SELECT 1 FROM series s WHERE s.id = b.series_id AND (s.name ILIKE '%' || $7 || '%' OR s.name_translit LIKE '%' || $8 || '%'))",
+		"b.series_type = $9",
+	}, conds)
+	assert.Equal(t, []any{"пикник", "piknik", authorID, "Стругацкий", "strugacki", seriesID, "Полдень", "polden", "p"}, args)
+}
+
+func TestBuildBookConditions_LatinQueryMatchesTranslit(t *testing.T) {
+	conds, args, next := buildBookConditions(models.BookFilter{
+		IncludeDeleted: true,
+		Query:          "Piknik na obochine",
+		AuthorName:     "Strugatsky",
+	})
+
+	assert.Equal(t, []string{
+		"(b.search_vector @@ plainto_tsquery('russian', $1) OR b.title_translit LIKE '%' || $2 || '%')",
+		"EXISTS (SELECT 1 FROM book_authors ba JOIN authors a ON a.id = ba.author_id WHERE ba.book_id = b.id AND (a.name ILIKE '%' || $3 || '%' OR a.name_translit LIKE '%' || $4 || '%'))",
+	}, conds)
+	assert.Equal(t, []any{"Piknik na obochine", "piknik na obochine", "Strugatsky", "strugacki"}, args)
+	assert.Equal(t, 5, next)
+}
+
+func TestBuildBookConditions_ShortQuerySkipsTranslit(t *testing.T) {
+	conds, args, _ := buildBookConditions(models.BookFilter{IncludeDeleted: true, Query: "ок"})
+
+	assert.Equal(t, []string{"b.search_vector @@ plainto_tsquery('russian', $1)"}, conds)
+	assert.Equal(t, []any{"ок"}, args)
+}
+
+func TestBuildBookConditions_ParentalExclusionLast(t *testing.T) {
+	conds, args, next := buildBookConditions(models.BookFilter{
+		Langs:           []string{"ru"},
+		ExcludeGenreIDs: []int{3, 4},
+	})
+
+	assert.Equal(t, "NOT EXISTS (SELECT 1 FROM book_genres bg2 WHERE bg2.book_id = b.id AND bg2.genre_id = ANY($2::int[]))", conds[len(conds)-1])
+	assert.Equal(t, []int{3, 4}, args[len(args)-1])
+	assert.Equal(t, 3, next)
+}
+
+func TestBuildBookConditions_Shelf(t *testing.T) {
+	shelfID := int64(5)
+	conds, args, next := buildBookConditions(models.BookFilter{
+		IncludeDeleted: true,
+		ShelfID:        &shelfID,
+		Shelf:          models.ShelfReading,
+		UserID:         "user-1",
+	})
+
+	assert.Equal(t, []string{"EXISTS (SELECT 1 FROM shelf_books sb JOIN shelves sh ON sh.id = sb.shelf_id WHERE sb.book_id = b.id AND " +
+		"sh.user_id = $1::uuid AND sh.id = $2 AND sh.status = $3)"}, conds)
+	assert.Equal(t, []any{"user-1", int64(5), "reading"}, args)
+	assert.Equal(t, 4, next)
+}
+
+func TestBuildBookConditions_GenreCodes(t *testing.T) {
+	conds, args, next := buildBookConditions(models.BookFilter{
+		IncludeDeleted: true,
+		GenreCodes:     []string{"sf"},
+		NotGenreCodes:  []string{"sf_horror"},
+	})
+
+	if assert.Len(t, conds, 2) {
+		assert.True(t, strings.HasPrefix(conds[0], "EXISTS (SELECT 1 FROM book_genres bg4 "))
+		assert.Contains(t, conds[0], "root.code = ANY($1::text[])")
+		assert.True(t, strings.HasPrefix(conds[1], "NOT EXISTS (SELECT 1 FROM book_genres bg5 "))
+		assert.Contains(t, conds[1], "root.code = ANY($2::text[])")
+	}
+	assert.Equal(t, []any{[]string{"sf"}, []string{"sf_horror"}}, args)
+	assert.Equal(t, 3, next)
+}
+
+func TestBuildBookConditions_AdvancedSyntaxUsesWebsearch(t *testing.T) {
+	conds, args, _ := buildBookConditions(models.BookFilter{
+		IncludeDeleted: true,
+		Query:          `"пикник на обочине" -зона`,
+		Syntax:         "advanced",
+	})
+
+	assert.Equal(t, []string{"b.search_vector @@ websearch_to_tsquery('russian', $1)"}, conds)
+	assert.Equal(t, []any{`"пикник на обочине" -зона`}, args)
+}
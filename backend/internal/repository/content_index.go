@@ -0,0 +1,304 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/grom-alex/homelib/backend/internal/models"
+)
+
+// Markers put around matched words by ts_headline. They are private-use
+// characters, which do not occur in book text.
+const (
+	HeadlineStart = "\ue000"
+	HeadlineStop  = "\ue001"
+)
+
+const headlineOptions = "StartSel=" + HeadlineStart + ", StopSel=" + HeadlineStop +
+	", MaxWords=35, MinWords=15, MaxFragments=1"
+
+// contentQuery parses a content search query; ё is folded like in the index.
+const contentQuery = `websearch_to_tsquery('russian', translate($1, 'Ёё', 'Ее'))`
+
+// ContentIndexRepo stores the full-text index of book contents.
+type ContentIndexRepo struct {
+	pool Pool
+}
+
+func NewContentIndexRepo(pool Pool) *ContentIndexRepo {
+	return &ContentIndexRepo{pool: pool}
+}
+
+// ListPending returns IDs of books after afterID in collections with
+// content indexing enabled that were not indexed by the given converter
+// format version, in ID order.
+func (r *ContentIndexRepo) ListPending(ctx context.Context, afterID int64, limit, formatVersion int) ([]int64, error) {
+	rows, err := r.pool.Query(ctx,
+		`SELECT b.id FROM books b JOIN collections c ON c.id = b.collection_id
+		 WHERE b.id > $1 AND NOT b.is_deleted AND c.content_index_enabled
+		   AND NOT EXISTS (SELECT 1 FROM book_content_index i WHERE i.book_id = b.id AND i.format_version = $3)
+		 ORDER BY b.id LIMIT $2`,
+		afterID, limit, formatVersion,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("list books to index: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("scan book id: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// Replace stores the chunks of a book in place of its previous ones. A
+// book that could not be read is recorded with indexErr and no chunks.
+func (r *ContentIndexRepo) Replace(ctx context.Context, bookID int64, formatVersion int, chunks []models.ContentIndexChunk, indexErr string) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("begin content index: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	if _, err := tx.Exec(ctx, `DELETE FROM book_content_chunks WHERE book_id = $1`, bookID); err != nil {
+		return fmt.Errorf("delete content chunks: %w", err)
+	}
+
+	if len(chunks) > 0 {
+		chapterIDs := make([]string, len(chunks))
+		paragraphs := make([]int32, len(chunks))
+		bodies := make([]string, len(chunks))
+		for i, ch := range chunks {
+			chapterIDs[i] = ch.ChapterID
+			paragraphs[i] = int32(ch.Paragraph)
+			bodies[i] = ch.Body
+		}
+		if _, err := tx.Exec(ctx,
+			`INSERT INTO book_content_chunks (book_id, chunk, chapter_id, paragraph, body, tsv)
+			 SELECT $1, t.n - 1, t.chapter_id, t.paragraph, t.body, to_tsvector('russian', translate(t.body, 'Ёё', 'Ее'))
+			 FROM unnest($2::text[], $3::int[], $4::text[]) WITH ORDINALITY AS t(chapter_id, paragraph, body, n)`,
+			bookID, chapterIDs, paragraphs, bodies,
+		); err != nil {
+			return fmt.Errorf("insert content chunks: %w", err)
+		}
+	}
+
+	if _, err := tx.Exec(ctx,
+		`INSERT INTO book_content_index (book_id, format_version, chunk_count, size_bytes, error)
+		 SELECT $1, $2, COUNT(*), COALESCE(SUM(pg_column_size(body) + pg_column_size(tsv)), 0), $3
+		 FROM book_content_chunks WHERE book_id = $1
+		 ON CONFLICT (book_id) DO UPDATE SET
+			format_version = EXCLUDED.format_version,
+			chunk_count = EXCLUDED.chunk_count,
+			size_bytes = EXCLUDED.size_bytes,
+			error = EXCLUDED.error,
+			indexed_at = NOW()`,
+		bookID, formatVersion, indexErr,
+	); err != nil {
+		return fmt.Errorf("record content index: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("commit content index: %w", err)
+	}
+	return nil
+}
+
+// PurgeDisabled removes the index of deleted books and of books in
+// collections with content indexing disabled. Returns the number of books
+// removed.
+func (r *ContentIndexRepo) PurgeDisabled(ctx context.Context) (int64, error) {
+	var n int64
+	err := r.pool.QueryRow(ctx,
+		`WITH gone AS (
+			DELETE FROM book_content_index i
+			WHERE NOT EXISTS (
+				SELECT 1 FROM books b JOIN collections c ON c.id = b.collection_id
+				WHERE b.id = i.book_id AND NOT b.is_deleted AND c.content_index_enabled)
+			RETURNING i.book_id
+		 ), chunks AS (
+			DELETE FROM book_content_chunks ch USING gone WHERE ch.book_id = gone.book_id
+		 )
+		 SELECT COUNT(*) FROM gone`,
+	).Scan(&n)
+	if err != nil {
+		return 0, fmt.Errorf("purge content index: %w", err)
+	}
+	return n, nil
+}
+
+// SetCollectionEnabled turns content indexing of a collection on or off.
+// Returns false if the collection does not exist.
+func (r *ContentIndexRepo) SetCollectionEnabled(ctx context.Context, collectionID int, enabled bool) (bool, error) {
+	tag, err := r.pool.Exec(ctx,
+		`UPDATE collections SET content_index_enabled = $2, updated_at = NOW() WHERE id = $1`,
+		collectionID, enabled,
+	)
+	if err != nil {
+		return false, fmt.Errorf("set collection content index: %w", err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// Usage reports the index state of every collection and the disk space
+// taken by the index.
+func (r *ContentIndexRepo) Usage(ctx context.Context) (*models.ContentIndexUsage, error) {
+	rows, err := r.pool.Query(ctx,
+		`SELECT c.id, c.name, c.content_index_enabled,
+			COUNT(b.id) FILTER (WHERE NOT b.is_deleted),
+			COUNT(i.book_id) FILTER (WHERE i.error = ''),
+			COUNT(i.book_id) FILTER (WHERE i.error <> ''),
+			COALESCE(SUM(i.chunk_count), 0),
+			COALESCE(SUM(i.size_bytes), 0)
+		 FROM collections c
+		 LEFT JOIN books b ON b.collection_id = c.id
+		 LEFT JOIN book_content_index i ON i.book_id = b.id
+		 GROUP BY c.id
+		 ORDER BY c.name`,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("content index usage: %w", err)
+	}
+	defer rows.Close()
+
+	usage := &models.ContentIndexUsage{Collections: []models.ContentIndexCollection{}}
+	for rows.Next() {
+		var c models.ContentIndexCollection
+		if err := rows.Scan(&c.ID, &c.Name, &c.Enabled, &c.Books, &c.IndexedBooks,
+			&c.FailedBooks, &c.Chunks, &c.Bytes); err != nil {
+			return nil, fmt.Errorf("scan content index usage: %w", err)
+		}
+		usage.Collections = append(usage.Collections, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	err = r.pool.QueryRow(ctx,
+		`SELECT pg_total_relation_size('book_content_chunks') + pg_total_relation_size('book_content_index')`,
+	).Scan(&usage.TotalBytes)
+	if err != nil {
+		return nil, fmt.Errorf("content index size: %w", err)
+	}
+	return usage, nil
+}
+
+// Search returns one page of books whose text matches the query, best
+// first, with up to passages best passages each, and the total number of
+// books found. Snippets keep the HeadlineStart/HeadlineStop markers.
+func (r *ContentIndexRepo) Search(ctx context.Context, f models.ContentSearchFilter, passages int) ([]models.ContentSearchResult, int, error) {
+	args := []any{f.Query, f.Limit, f.Offset()}
+	restrict := ""
+	if len(f.ExcludeGenreIDs) > 0 {
+		args = append(args, f.ExcludeGenreIDs)
+		restrict = ` AND NOT EXISTS (SELECT 1 FROM book_genres bg2 WHERE bg2.book_id = b.id AND bg2.genre_id = ANY($4::int[]))`
+	}
+
+	rows, err := r.pool.Query(ctx,
+		`WITH found AS (
+			SELECT ch.book_id, COUNT(*) AS matches, MAX(ts_rank(ch.tsv, q)) AS rank
+			FROM book_content_chunks ch, `+contentQuery+` q
+			WHERE ch.tsv @@ q
+			GROUP BY ch.book_id
+		 )
+		 SELECT f.book_id, b.title, f.matches, COUNT(*) OVER ()
+		 FROM found f JOIN books b ON b.id = f.book_id
+		 WHERE NOT b.is_deleted`+restrict+`
+		 ORDER BY f.rank DESC, f.book_id
+		 LIMIT $2 OFFSET $3`,
+		args...,
+	)
+	if err != nil {
+		return nil, 0, fmt.Errorf("search content: %w", err)
+	}
+	defer rows.Close()
+
+	results := []models.ContentSearchResult{}
+	total := 0
+	for rows.Next() {
+		res := models.ContentSearchResult{Authors: []models.BookAuthorRef{}, Passages: []models.ContentPassage{}}
+		if err := rows.Scan(&res.BookID, &res.Title, &res.Matches, &total); err != nil {
+			return nil, 0, fmt.Errorf("scan content search result: %w", err)
+		}
+		results = append(results, res)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+	if len(results) == 0 {
+		return results, total, nil
+	}
+
+	byID := make(map[int64]*models.ContentSearchResult, len(results))
+	ids := make([]int64, len(results))
+	for i := range results {
+		byID[results[i].BookID] = &results[i]
+		ids[i] = results[i].BookID
+	}
+	if err := r.loadPassages(ctx, f.Query, ids, passages, byID); err != nil {
+		return nil, 0, err
+	}
+	if err := r.loadAuthors(ctx, ids, byID); err != nil {
+		return nil, 0, err
+	}
+	return results, total, nil
+}
+
+func (r *ContentIndexRepo) loadPassages(ctx context.Context, query string, ids []int64, limit int, byID map[int64]*models.ContentSearchResult) error {
+	rows, err := r.pool.Query(ctx,
+		`SELECT p.book_id, p.chapter_id, p.paragraph, ts_headline('russian', p.body, p.q, $4)
+		 FROM (
+			SELECT ch.book_id, ch.chunk, ch.chapter_id, ch.paragraph, ch.body, q,
+				ROW_NUMBER() OVER (PARTITION BY ch.book_id ORDER BY ts_rank(ch.tsv, q) DESC, ch.chunk) AS n
+			FROM book_content_chunks ch, `+contentQuery+` q
+			WHERE ch.book_id = ANY($2) AND ch.tsv @@ q
+		 ) p
+		 WHERE p.n <= $3
+		 ORDER BY p.book_id, p.chunk`,
+		query, ids, limit, headlineOptions,
+	)
+	if err != nil {
+		return fmt.Errorf("load content passages: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var bookID int64
+		var p models.ContentPassage
+		if err := rows.Scan(&bookID, &p.ChapterID, &p.Locator.Paragraph, &p.Snippet); err != nil {
+			return fmt.Errorf("scan content passage: %w", err)
+		}
+		if res, ok := byID[bookID]; ok {
+			res.Passages = append(res.Passages, p)
+		}
+	}
+	return rows.Err()
+}
+
+func (r *ContentIndexRepo) loadAuthors(ctx context.Context, ids []int64, byID map[int64]*models.ContentSearchResult) error {
+	rows, err := r.pool.Query(ctx,
+		`SELECT ba.book_id, a.id, a.name FROM authors a
+		 JOIN book_authors ba ON ba.author_id = a.id
+		 WHERE ba.book_id = ANY($1) ORDER BY a.name_sort`, ids)
+	if err != nil {
+		return fmt.Errorf("load content search authors: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var bookID int64
+		var ref models.BookAuthorRef
+		if err := rows.Scan(&bookID, &ref.ID, &ref.Name); err != nil {
+			return fmt.Errorf("scan content search author: %w", err)
+		}
+		if res, ok := byID[bookID]; ok {
+			res.Authors = append(res.Authors, ref)
+		}
+	}
+	return rows.Err()
+}
@@ -0,0 +1,158 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grom-alex/homelib/backend/internal/models"
+)
+
+func TestContentIndexRepo_ListPending(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	mock.ExpectQuery("SELECT b.id FROM books b JOIN collections c .+ c.content_index_enabled .+ i.format_version = \\$3").
+		WithArgs(int64(10), 100, 3).
+		WillReturnRows(pgxmock.NewRows([]string{"id"}).AddRow(int64(11)).AddRow(int64(15)))
+
+	ids, err := NewContentIndexRepo(mock).ListPending(context.Background(), 10, 100, 3)
+	require.NoError(t, err)
+	assert.Equal(t, []int64{11, 15}, ids)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestContentIndexRepo_Replace(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	chunks := []models.ContentIndexChunk{
+		{ChapterID: "ch1", Paragraph: 0, Body: "Глава первая"},
+		{ChapterID: "ch1", Paragraph: 4, Body: "Ёлка"},
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE FROM book_content_chunks WHERE book_id = \\$1").
+		WithArgs(int64(42)).
+		WillReturnResult(pgxmock.NewResult("DELETE", 3))
+	mock.ExpectExec("INSERT INTO book_content_chunks .+ translate\\(t.body, 'Ёё', 'Ее'\\)").
+		WithArgs(int64(42), []string{"ch1", "ch1"}, []int32{0, 4}, []string{"Глава первая", "Ёлка"}).
+		WillReturnResult(pgxmock.NewResult("INSERT", 2))
+	mock.ExpectExec("INSERT INTO book_content_index .+ ON CONFLICT \\(book_id\\) DO UPDATE").
+		WithArgs(int64(42), 3, "").
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mock.ExpectCommit()
+	mock.ExpectRollback()
+
+	require.NoError(t, NewContentIndexRepo(mock).Replace(context.Background(), 42, 3, chunks, ""))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestContentIndexRepo_Replace_Failed(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE FROM book_content_chunks").
+		WithArgs(int64(42)).
+		WillReturnResult(pgxmock.NewResult("DELETE", 0))
+	mock.ExpectExec("INSERT INTO book_content_index").
+		WithArgs(int64(42), 3, "malformed file").
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mock.ExpectCommit()
+	mock.ExpectRollback()
+
+	require.NoError(t, NewContentIndexRepo(mock).Replace(context.Background(), 42, 3, nil, "malformed file"))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestContentIndexRepo_SetCollectionEnabled(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	mock.ExpectExec("UPDATE collections SET content_index_enabled = \\$2").
+		WithArgs(7, true).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 0))
+
+	found, err := NewContentIndexRepo(mock).SetCollectionEnabled(context.Background(), 7, true)
+	require.NoError(t, err)
+	assert.False(t, found)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestContentIndexRepo_Usage(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	mock.ExpectQuery("SELECT c.id, c.name, c.content_index_enabled").
+		WillReturnRows(pgxmock.NewRows([]string{"id", "name", "enabled", "books", "indexed", "failed", "chunks", "bytes"}).
+			AddRow(1, "Флибуста", true, 100, 90, 2, int64(4500), int64(9000000)))
+	mock.ExpectQuery("SELECT pg_total_relation_size").
+		WillReturnRows(pgxmock.NewRows([]string{"size"}).AddRow(int64(12000000)))
+
+	usage, err := NewContentIndexRepo(mock).Usage(context.Background())
+	require.NoError(t, err)
+	require.Len(t, usage.Collections, 1)
+	assert.Equal(t, models.ContentIndexCollection{
+		ID: 1, Name: "Флибуста", Enabled: true, Books: 100, IndexedBooks: 90, FailedBooks: 2, Chunks: 4500, Bytes: 9000000,
+	}, usage.Collections[0])
+	assert.Equal(t, int64(12000000), usage.TotalBytes)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestContentIndexRepo_Search(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	f := models.ContentSearchFilter{Query: "ёлка", Page: 1, Limit: 20, ExcludeGenreIDs: []int{5}}
+
+	mock.ExpectQuery("WITH found AS .+ websearch_to_tsquery\\('russian', translate\\(\\$1, 'Ёё', 'Ее'\\)\\) .+ bg2.genre_id = ANY\\(\\$4::int\\[\\]\\)").
+		WithArgs("ёлка", 20, 0, []int{5}).
+		WillReturnRows(pgxmock.NewRows([]string{"book_id", "title", "matches", "total"}).
+			AddRow(int64(42), "Ёлка", 3, 1))
+	mock.ExpectQuery("SELECT p.book_id, p.chapter_id, p.paragraph, ts_headline").
+		WithArgs("ёлка", []int64{42}, 3, headlineOptions).
+		WillReturnRows(pgxmock.NewRows([]string{"book_id", "chapter_id", "paragraph", "snippet"}).
+			AddRow(int64(42), "ch2", 7, "под "+HeadlineStart+"ёлкой"+HeadlineStop))
+	mock.ExpectQuery("SELECT ba.book_id, a.id, a.name FROM authors a").
+		WithArgs([]int64{42}).
+		WillReturnRows(pgxmock.NewRows([]string{"book_id", "id", "name"}).AddRow(int64(42), int64(9), "Чехов Антон"))
+
+	results, total, err := NewContentIndexRepo(mock).Search(context.Background(), f, 3)
+	require.NoError(t, err)
+	assert.Equal(t, 1, total)
+	require.Len(t, results, 1)
+	assert.Equal(t, 3, results[0].Matches)
+	assert.Equal(t, []models.BookAuthorRef{{ID: 9, Name: "Чехов Антон"}}, results[0].Authors)
+	require.Len(t, results[0].Passages, 1)
+	assert.Equal(t, "ch2", results[0].Passages[0].ChapterID)
+	assert.Equal(t, models.Locator{Paragraph: 7}, results[0].Passages[0].Locator)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestContentIndexRepo_Search_NoResults(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	f := models.ContentSearchFilter{Query: "нет", Page: 2, Limit: 10}
+
+	mock.ExpectQuery("WITH found AS").
+		WithArgs("нет", 10, 10).
+		WillReturnRows(pgxmock.NewRows([]string{"book_id", "title", "matches", "total"}))
+
+	results, total, err := NewContentIndexRepo(mock).Search(context.Background(), f, 3)
+	require.NoError(t, err)
+	assert.Empty(t, results)
+	assert.Equal(t, 0, total)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// DocumentHashRepo maps KOReader document digests to books.
+type DocumentHashRepo struct {
+	pool Pool
+}
+
+func NewDocumentHashRepo(pool Pool) *DocumentHashRepo {
+	return &DocumentHashRepo{pool: pool}
+}
+
+// Register maps a digest to a book. Filename digests are not unique across
+// collections; the most recently served book wins.
+func (r *DocumentHashRepo) Register(ctx context.Context, document string, bookID int64, kind string) error {
+	_, err := r.pool.Exec(ctx,
+		`INSERT INTO document_hashes (document, book_id, kind)
+		 VALUES ($1, $2, $3)
+		 ON CONFLICT (document) DO UPDATE SET
+			book_id = EXCLUDED.book_id,
+			kind = EXCLUDED.kind,
+			updated_at = NOW()`,
+		document, bookID, kind,
+	)
+	if err != nil {
+		return fmt.Errorf("register document hash: %w", err)
+	}
+	return nil
+}
+
+// FindBook returns the book a digest belongs to; false if it is unknown.
+func (r *DocumentHashRepo) FindBook(ctx context.Context, document string) (int64, bool, error) {
+	var bookID int64
+	err := r.pool.QueryRow(ctx,
+		`SELECT book_id FROM document_hashes WHERE document = $1`, document,
+	).Scan(&bookID)
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, fmt.Errorf("find book by document hash: %w", err)
+	}
+	return bookID, true, nil
+}
+
+// ListUnhashedBooks returns IDs of books after afterID that have no binary
+// digest yet, in ID order. Used by the digest backfill.
+func (r *DocumentHashRepo) ListUnhashedBooks(ctx context.Context, afterID int64, limit int) ([]int64, error) {
+	rows, err := r.pool.Query(ctx,
+		`SELECT b.id FROM books b
+		 WHERE b.id > $1 AND NOT b.is_deleted
+		   AND NOT EXISTS (SELECT 1 FROM document_hashes h WHERE h.book_id = b.id AND h.kind = 'binary')
+		 ORDER BY b.id LIMIT $2`,
+		afterID, limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("list unhashed books: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("scan book id: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
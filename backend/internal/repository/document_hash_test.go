@@ -0,0 +1,60 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grom-alex/homelib/backend/internal/models"
+)
+
+func TestDocumentHashRepo_RegisterAndFind(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	repo := NewDocumentHashRepo(mock)
+
+	mock.ExpectExec("INSERT INTO document_hashes .+ ON CONFLICT \\(document\\) DO UPDATE").
+		WithArgs("abc", int64(42), models.DocumentHashBinary).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mock.ExpectQuery("SELECT book_id FROM document_hashes WHERE document = \\$1").
+		WithArgs("abc").
+		WillReturnRows(pgxmock.NewRows([]string{"book_id"}).AddRow(int64(42)))
+	mock.ExpectQuery("SELECT book_id FROM document_hashes").
+		WithArgs("unknown").
+		WillReturnError(pgx.ErrNoRows)
+
+	require.NoError(t, repo.Register(context.Background(), "abc", 42, models.DocumentHashBinary))
+
+	id, found, err := repo.FindBook(context.Background(), "abc")
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, int64(42), id)
+
+	_, found, err = repo.FindBook(context.Background(), "unknown")
+	require.NoError(t, err)
+	assert.False(t, found)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDocumentHashRepo_ListUnhashedBooks(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	repo := NewDocumentHashRepo(mock)
+
+	mock.ExpectQuery("SELECT b.id FROM books b\\s+WHERE b.id > \\$1 AND NOT b.is_deleted").
+		WithArgs(int64(10), 100).
+		WillReturnRows(pgxmock.NewRows([]string{"id"}).AddRow(int64(11)).AddRow(int64(15)))
+
+	ids, err := repo.ListUnhashedBooks(context.Background(), 10, 100)
+	require.NoError(t, err)
+	assert.Equal(t, []int64{11, 15}, ids)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
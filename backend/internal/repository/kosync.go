@@ -0,0 +1,134 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"github.com/grom-alex/homelib/backend/internal/models"
+)
+
+// pgUniqueViolation is the PostgreSQL error code for unique constraint violations.
+const pgUniqueViolation = "23505"
+
+type KosyncRepo struct {
+	pool Pool
+}
+
+func NewKosyncRepo(pool Pool) *KosyncRepo {
+	return &KosyncRepo{pool: pool}
+}
+
+const kosyncAccountQuery = `SELECT k.user_id, k.username, k.key_hash, COALESCE(u.is_active, FALSE),
+	k.created_at, k.updated_at
+	FROM kosync_accounts k JOIN users u ON u.id = k.user_id`
+
+func scanKosyncAccount(row pgx.Row) (*models.KosyncAccount, error) {
+	var a models.KosyncAccount
+	err := row.Scan(&a.UserID, &a.Username, &a.KeyHash, &a.UserActive, &a.CreatedAt, &a.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+// GetAccount returns the user's sync account, or nil if not set up.
+func (r *KosyncRepo) GetAccount(ctx context.Context, userID string) (*models.KosyncAccount, error) {
+	a, err := scanKosyncAccount(r.pool.QueryRow(ctx,
+		kosyncAccountQuery+` WHERE k.user_id = $1`, userID))
+	if err != nil {
+		return nil, fmt.Errorf("get kosync account: %w", err)
+	}
+	return a, nil
+}
+
+// GetAccountByUsername returns the sync account with the username, or nil.
+func (r *KosyncRepo) GetAccountByUsername(ctx context.Context, username string) (*models.KosyncAccount, error) {
+	a, err := scanKosyncAccount(r.pool.QueryRow(ctx,
+		kosyncAccountQuery+` WHERE k.username = $1`, username))
+	if err != nil {
+		return nil, fmt.Errorf("get kosync account by username: %w", err)
+	}
+	return a, nil
+}
+
+// SaveAccount creates or replaces the user's sync account and fills its
+// timestamps. Returns false if the username belongs to another user.
+func (r *KosyncRepo) SaveAccount(ctx context.Context, a *models.KosyncAccount) (bool, error) {
+	err := r.pool.QueryRow(ctx,
+		`INSERT INTO kosync_accounts (user_id, username, key_hash)
+		 VALUES ($1, $2, $3)
+		 ON CONFLICT (user_id) DO UPDATE SET
+			username = EXCLUDED.username,
+			key_hash = EXCLUDED.key_hash,
+			updated_at = NOW()
+		 RETURNING created_at, updated_at`,
+		a.UserID, a.Username, a.KeyHash,
+	).Scan(&a.CreatedAt, &a.UpdatedAt)
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == pgUniqueViolation {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("save kosync account: %w", err)
+	}
+	return true, nil
+}
+
+// DeleteAccount removes the user's sync account and the positions synced
+// through it. Returns false if there was no account.
+func (r *KosyncRepo) DeleteAccount(ctx context.Context, userID string) (bool, error) {
+	tag, err := r.pool.Exec(ctx, `DELETE FROM kosync_accounts WHERE user_id = $1`, userID)
+	if err != nil {
+		return false, fmt.Errorf("delete kosync account: %w", err)
+	}
+	if _, err := r.pool.Exec(ctx, `DELETE FROM kosync_progress WHERE user_id = $1`, userID); err != nil {
+		return false, fmt.Errorf("delete kosync progress: %w", err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// GetProgress returns the last position KOReader reported for a document, or nil.
+func (r *KosyncRepo) GetProgress(ctx context.Context, userID, document string) (*models.KosyncProgress, error) {
+	var p models.KosyncProgress
+	err := r.pool.QueryRow(ctx,
+		`SELECT user_id, document, progress, percentage, device, device_id, updated_at
+		 FROM kosync_progress WHERE user_id = $1 AND document = $2`,
+		userID, document,
+	).Scan(&p.UserID, &p.Document, &p.Progress, &p.Percentage, &p.Device, &p.DeviceID, &p.UpdatedAt)
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get kosync progress: %w", err)
+	}
+	return &p, nil
+}
+
+// SaveProgress stores a position reported by KOReader. UpdatedAt is set by
+// the caller so that it can be matched with the reading progress it produced.
+func (r *KosyncRepo) SaveProgress(ctx context.Context, p *models.KosyncProgress) error {
+	_, err := r.pool.Exec(ctx,
+		`INSERT INTO kosync_progress (user_id, document, progress, percentage, device, device_id, updated_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7)
+		 ON CONFLICT (user_id, document) DO UPDATE SET
+			progress = EXCLUDED.progress,
+			percentage = EXCLUDED.percentage,
+			device = EXCLUDED.device,
+			device_id = EXCLUDED.device_id,
+			updated_at = EXCLUDED.updated_at`,
+		p.UserID, p.Document, p.Progress, p.Percentage, p.Device, p.DeviceID, p.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("save kosync progress: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grom-alex/homelib/backend/internal/models"
+)
+
+func TestKosyncRepo_GetAccountByUsername(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	repo := NewKosyncRepo(mock)
+	now := time.Now()
+
+	mock.ExpectQuery("SELECT .+ FROM kosync_accounts k JOIN users u ON u.id = k.user_id WHERE k.username = \\$1").
+		WithArgs("reader").
+		WillReturnRows(pgxmock.NewRows([]string{"user_id", "username", "key_hash", "is_active", "created_at", "updated_at"}).
+			AddRow("user-1", "reader", "hash", true, now, now))
+	mock.ExpectQuery("SELECT .+ FROM kosync_accounts").
+		WithArgs("nobody").
+		WillReturnError(pgx.ErrNoRows)
+
+	a, err := repo.GetAccountByUsername(context.Background(), "reader")
+	require.NoError(t, err)
+	require.NotNil(t, a)
+	assert.Equal(t, "user-1", a.UserID)
+	assert.True(t, a.UserActive)
+
+	a, err = repo.GetAccountByUsername(context.Background(), "nobody")
+	require.NoError(t, err)
+	assert.Nil(t, a)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestKosyncRepo_SaveAccount(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	repo := NewKosyncRepo(mock)
+	now := time.Now()
+
+	mock.ExpectQuery("INSERT INTO kosync_accounts .+ ON CONFLICT \\(user_id\\) DO UPDATE").
+		WithArgs("user-1", "reader", "hash").
+		WillReturnRows(pgxmock.NewRows([]string{"created_at", "updated_at"}).AddRow(now, now))
+	mock.ExpectQuery("INSERT INTO kosync_accounts").
+		WithArgs("user-2", "reader", "hash").
+		WillReturnError(&pgconn.PgError{Code: pgUniqueViolation})
+
+	a := &models.KosyncAccount{UserID: "user-1", Username: "reader", KeyHash: "hash"}
+	ok, err := repo.SaveAccount(context.Background(), a)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, now, a.UpdatedAt)
+
+	ok, err = repo.SaveAccount(context.Background(), &models.KosyncAccount{UserID: "user-2", Username: "reader", KeyHash: "hash"})
+	require.NoError(t, err)
+	assert.False(t, ok)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestKosyncRepo_DeleteAccount(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	repo := NewKosyncRepo(mock)
+
+	mock.ExpectExec("DELETE FROM kosync_accounts WHERE user_id = \\$1").
+		WithArgs("user-1").
+		WillReturnResult(pgxmock.NewResult("DELETE", 1))
+	mock.ExpectExec("DELETE FROM kosync_progress WHERE user_id = \\$1").
+		WithArgs("user-1").
+		WillReturnResult(pgxmock.NewResult("DELETE", 3))
+
+	found, err := repo.DeleteAccount(context.Background(), "user-1")
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestKosyncRepo_Progress(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	repo := NewKosyncRepo(mock)
+	now := time.Now()
+	p := &models.KosyncProgress{
+		UserID: "user-1", Document: "abc", Progress: "/body/p[2]", Percentage: 0.4,
+		Device: "Kobo", DeviceID: "A1", UpdatedAt: now,
+	}
+
+	mock.ExpectExec("INSERT INTO kosync_progress .+ ON CONFLICT \\(user_id, document\\) DO UPDATE").
+		WithArgs("user-1", "abc", "/body/p[2]", 0.4, "Kobo", "A1", now).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mock.ExpectQuery("SELECT .+ FROM kosync_progress WHERE user_id = \\$1 AND document = \\$2").
+		WithArgs("user-1", "abc").
+		WillReturnRows(pgxmock.NewRows([]string{"user_id", "document", "progress", "percentage", "device", "device_id", "updated_at"}).
+			AddRow("user-1", "abc", "/body/p[2]", 0.4, "Kobo", "A1", now))
+	mock.ExpectQuery("SELECT .+ FROM kosync_progress").
+		WithArgs("user-1", "missing").
+		WillReturnError(pgx.ErrNoRows)
+
+	require.NoError(t, repo.SaveProgress(context.Background(), p))
+
+	got, err := repo.GetProgress(context.Background(), "user-1", "abc")
+	require.NoError(t, err)
+	assert.Equal(t, p, got)
+
+	got, err = repo.GetProgress(context.Background(), "user-1", "missing")
+	require.NoError(t, err)
+	assert.Nil(t, got)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,122 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/grom-alex/homelib/backend/internal/models"
+)
+
+// sortKey describes a column that list queries can be ordered and
+// keyset-paginated by. Rows are always ordered by (expr, id) so that
+// the position of the last row is unique.
+type sortKey struct {
+	expr     string // SQL expression used in ORDER BY
+	sqlType  string // type the cursor key is cast to when compared
+	nullable bool   // column may be NULL (sorted NULLS LAST)
+}
+
+// keysetCondition returns a WHERE fragment selecting rows strictly after the
+// cursor position for ORDER BY key.expr dir NULLS LAST, idExpr dir.
+// Placeholders are numbered from argIdx; the next free index is returned.
+func keysetCondition(key sortKey, idExpr string, desc bool, c *models.Cursor, argIdx int) (string, []any, int) {
+	op := ">"
+	if desc {
+		op = "<"
+	}
+
+	// Last row had a NULL key: only the remaining NULL rows follow it.
+	if c.Key == nil {
+		return fmt.Sprintf("(%s IS NULL AND %s %s $%d)", key.expr, idExpr, op, argIdx),
+			[]any{c.ID}, argIdx + 1
+	}
+
+	args := []any{*c.Key, c.ID}
+	if !key.nullable {
+		// Row comparison lets PostgreSQL use a composite index range scan.
+		return fmt.Sprintf("(%s, %s) %s ($%d::%s, $%d)", key.expr, idExpr, op, argIdx, key.sqlType, argIdx+1),
+			args, argIdx + 2
+	}
+	return fmt.Sprintf("(%s %s $%d::%s OR (%s = $%d::%s AND %s %s $%d) OR %s IS NULL)",
+			key.expr, op, argIdx, key.sqlType,
+			key.expr, argIdx, key.sqlType, idExpr, op, argIdx+1,
+			key.expr),
+		args, argIdx + 2
+}
+
+// orderDirection normalizes a user-supplied order to "asc" or "desc".
+func orderDirection(order string) string {
+	if strings.EqualFold(order, "desc") {
+		return "desc"
+	}
+	return "asc"
+}
+
+// decodeCursorFor decodes a cursor and checks that it was issued for the
+// given sort and order. Returns nil for an empty cursor.
+func decodeCursorFor(raw, sort, order string) (*models.Cursor, error) {
+	if raw == "" {
+		return nil, nil
+	}
+	c, err := models.DecodeCursor(raw)
+	if err != nil {
+		return nil, err
+	}
+	if c.Sort != sort || c.Order != order {
+		return nil, fmt.Errorf("%w: issued for sort %s %s", models.ErrInvalidCursor, c.Sort, c.Order)
+	}
+	return c, nil
+}
+
+// countRows computes the total for a list query in the requested mode.
+// fromWhere is the query tail starting after FROM (tables and WHERE clause).
+func countRows(ctx context.Context, pool Pool, mode, fromWhere string, args []any) (total int, estimated bool, err error) {
+	switch mode {
+	case models.CountNone:
+		return -1, false, nil
+	case models.CountEstimate:
+		var plan []byte
+		if err := pool.QueryRow(ctx, "EXPLAIN (FORMAT JSON) SELECT 1 FROM "+fromWhere, args...).Scan(&plan); err != nil {
+			return 0, false, fmt.Errorf("estimate rows: %w", err)
+		}
+		n, err := parsePlanRows(plan)
+		if err != nil {
+			return 0, false, err
+		}
+		return n, true, nil
+	default:
+		if err := pool.QueryRow(ctx, "SELECT COUNT(*) FROM "+fromWhere, args...).Scan(&total); err != nil {
+			return 0, false, err
+		}
+		return total, false, nil
+	}
+}
+
+// parsePlanRows extracts the top-level "Plan Rows" estimate from
+// EXPLAIN (FORMAT JSON) output.
+func parsePlanRows(plan []byte) (int, error) {
+	var out []struct {
+		Plan struct {
+			PlanRows float64 `json:"Plan Rows"`
+		} `json:"Plan"`
+	}
+	if err := json.Unmarshal(plan, &out); err != nil {
+		return 0, fmt.Errorf("parse explain output: %w", err)
+	}
+	if len(out) == 0 {
+		return 0, fmt.Errorf("parse explain output: empty plan")
+	}
+	return int(out[0].Plan.PlanRows), nil
+}
+
+// nextCursor returns the cursor pointing after the last of the fetched rows
+// when more rows exist than the page limit, or an empty string otherwise.
+// keys and ids hold the sort key and id of each fetched row.
+func nextCursor(sort, order string, limit int, keys []*string, ids []int64) string {
+	if len(ids) <= limit || limit <= 0 {
+		return ""
+	}
+	return models.Cursor{Sort: sort, Order: order, Key: keys[limit-1], ID: ids[limit-1]}.Encode()
+}
@@ -0,0 +1,122 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grom-alex/homelib/backend/internal/models"
+)
+
+func strPtr(s string) *string { return &s }
+
+func TestKeysetCondition_NotNullableAsc(t *testing.T) {
+	key := sortKey{expr: "b.title", sqlType: "text"}
+	cond, args, next := keysetCondition(key, "b.id", false, &models.Cursor{Key: strPtr("Alpha"), ID: 10}, 3)
+
+	assert.Equal(t, "(b.title, b.id) > ($3::text, $4)", cond)
+	assert.Equal(t, []any{"Alpha", int64(10)}, args)
+	assert.Equal(t, 5, next)
+}
+
+func TestKeysetCondition_NullableDesc(t *testing.T) {
+	key := sortKey{expr: "b.year", sqlType: "int", nullable: true}
+	cond, args, next := keysetCondition(key, "b.id", true, &models.Cursor{Key: strPtr("1999"), ID: 5}, 1)
+
+	assert.Equal(t, "(b.year < $1::int OR (b.year = $1::int AND b.id < $2) OR b.year IS NULL)", cond)
+	assert.Equal(t, []any{"1999", int64(5)}, args)
+	assert.Equal(t, 3, next)
+}
+
+func TestKeysetCondition_NullKey(t *testing.T) {
+	key := sortKey{expr: "b.year", sqlType: "int", nullable: true}
+	cond, args, next := keysetCondition(key, "b.id", false, &models.Cursor{ID: 77}, 2)
+
+	assert.Equal(t, "(b.year IS NULL AND b.id > $2)", cond)
+	assert.Equal(t, []any{int64(77)}, args)
+	assert.Equal(t, 3, next)
+}
+
+func TestDecodeCursorFor(t *testing.T) {
+	c, err := decodeCursorFor("", "title", "asc")
+	require.NoError(t, err)
+	assert.Nil(t, c)
+
+	raw := models.Cursor{Sort: "title", Order: "asc", Key: strPtr("A"), ID: 1}.Encode()
+	c, err = decodeCursorFor(raw, "title", "asc")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), c.ID)
+
+	_, err = decodeCursorFor(raw, "year", "asc")
+	assert.ErrorIs(t, err, models.ErrInvalidCursor)
+
+	_, err = decodeCursorFor(raw, "title", "desc")
+	assert.ErrorIs(t, err, models.ErrInvalidCursor)
+
+	_, err = decodeCursorFor("garbage!", "title", "asc")
+	assert.ErrorIs(t, err, models.ErrInvalidCursor)
+}
+
+func TestNextCursor(t *testing.T) {
+	keys := []*string{strPtr("a"), strPtr("b"), nil}
+	ids := []int64{1, 2, 3}
+
+	// Fewer rows than limit+1: last page
+	assert.Empty(t, nextCursor("title", "asc", 3, keys, ids))
+
+	raw := nextCursor("title", "asc", 2, keys, ids)
+	require.NotEmpty(t, raw)
+	c, err := models.DecodeCursor(raw)
+	require.NoError(t, err)
+	assert.Equal(t, "b", *c.Key)
+	assert.Equal(t, int64(2), c.ID)
+}
+
+func TestParsePlanRows(t *testing.T) {
+	n, err := parsePlanRows([]byte(`[{"Plan": {"Node Type": "Seq Scan", "Plan Rows": 123456, "Plan Width": 4}}]`))
+	require.NoError(t, err)
+	assert.Equal(t, 123456, n)
+
+	_, err = parsePlanRows([]byte(`[]`))
+	assert.Error(t, err)
+
+	_, err = parsePlanRows([]byte(`not json`))
+	assert.Error(t, err)
+}
+
+func TestCountRows_Modes(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+	ctx := context.Background()
+
+	mock.ExpectQuery(`SELECT COUNT\(\*\) FROM books b WHERE b.lang = \$1`).
+		WithArgs("ru").
+		WillReturnRows(pgxmock.NewRows([]string{"count"}).AddRow(42))
+	total, estimated, err := countRows(ctx, mock, models.CountExact, "books b WHERE b.lang = $1", []any{"ru"})
+	require.NoError(t, err)
+	assert.Equal(t, 42, total)
+	assert.False(t, estimated)
+
+	mock.ExpectQuery(`EXPLAIN \(FORMAT JSON\) SELECT 1 FROM books b`).
+		WillReturnRows(pgxmock.NewRows([]string{"QUERY PLAN"}).AddRow([]byte(`[{"Plan": {"Plan Rows": 600000}}]`)))
+	total, estimated, err = countRows(ctx, mock, models.CountEstimate, "books b ", nil)
+	require.NoError(t, err)
+	assert.Equal(t, 600000, total)
+	assert.True(t, estimated)
+
+	total, estimated, err = countRows(ctx, mock, models.CountNone, "books b ", nil)
+	require.NoError(t, err)
+	assert.Equal(t, -1, total)
+	assert.False(t, estimated)
+
+	mock.ExpectQuery(`SELECT COUNT`).WillReturnError(fmt.Errorf("connection refused"))
+	_, _, err = countRows(ctx, mock, models.CountExact, "books b ", nil)
+	assert.Error(t, err)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,135 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/grom-alex/homelib/backend/internal/models"
+)
+
+type RatingRepo struct {
+	pool Pool
+}
+
+func NewRatingRepo(pool Pool) *RatingRepo {
+	return &RatingRepo{pool: pool}
+}
+
+// refreshHouseholdRate recomputes the household average of a book on the
+// 5-point scale of lib_rate.
+const refreshHouseholdRate = `UPDATE books SET
+	household_rate = (SELECT ROUND(AVG(r.rating * 5.0 / r.scale), 1)
+		FROM book_ratings r WHERE r.book_id = $1 AND r.rating IS NOT NULL),
+	household_rate_count = (SELECT COUNT(r.rating) FROM book_ratings r WHERE r.book_id = $1)
+ WHERE id = $1`
+
+// Get returns the user's rating of a book, or nil if there is none.
+func (r *RatingRepo) Get(ctx context.Context, userID string, bookID int64) (*models.BookRating, error) {
+	rt := models.BookRating{UserID: userID, BookID: bookID}
+	err := r.pool.QueryRow(ctx,
+		`SELECT rating, scale, review, created_at, updated_at
+		 FROM book_ratings WHERE user_id = $1 AND book_id = $2`,
+		userID, bookID,
+	).Scan(&rt.Rating, &rt.Scale, &rt.Review, &rt.CreatedAt, &rt.UpdatedAt)
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get rating: %w", err)
+	}
+	return &rt, nil
+}
+
+// ListByBook returns the household's ratings of a book, newest first.
+func (r *RatingRepo) ListByBook(ctx context.Context, bookID int64) ([]models.BookReview, error) {
+	rows, err := r.pool.Query(ctx,
+		`SELECT r.user_id, COALESCE(NULLIF(u.display_name, ''), u.username),
+			r.rating, r.scale, r.review, r.created_at, r.updated_at
+		 FROM book_ratings r JOIN users u ON u.id = r.user_id
+		 WHERE r.book_id = $1
+		 ORDER BY r.updated_at DESC`,
+		bookID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("list ratings: %w", err)
+	}
+	defer rows.Close()
+
+	result := []models.BookReview{}
+	for rows.Next() {
+		rv := models.BookReview{BookRating: models.BookRating{BookID: bookID}}
+		if err := rows.Scan(&rv.UserID, &rv.UserName, &rv.Rating, &rv.Scale, &rv.Review,
+			&rv.CreatedAt, &rv.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("scan rating: %w", err)
+		}
+		result = append(result, rv)
+	}
+	return result, rows.Err()
+}
+
+// Save creates or replaces the user's rating of a book, fills its
+// timestamps and refreshes the book's household average. Returns false
+// if the book does not exist.
+func (r *RatingRepo) Save(ctx context.Context, rt *models.BookRating) (bool, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return false, fmt.Errorf("begin save rating: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	err = tx.QueryRow(ctx,
+		`INSERT INTO book_ratings (user_id, book_id, rating, scale, review)
+		 SELECT $1, b.id, $3, $4, $5 FROM books b WHERE b.id = $2
+		 ON CONFLICT (user_id, book_id) DO UPDATE SET
+			rating = EXCLUDED.rating, scale = EXCLUDED.scale, review = EXCLUDED.review, updated_at = NOW()
+		 RETURNING created_at, updated_at`,
+		rt.UserID, rt.BookID, rt.Rating, rt.Scale, rt.Review,
+	).Scan(&rt.CreatedAt, &rt.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("save rating: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx, refreshHouseholdRate, rt.BookID); err != nil {
+		return false, fmt.Errorf("refresh household rate: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return false, fmt.Errorf("commit rating: %w", err)
+	}
+	return true, nil
+}
+
+// Delete removes the user's rating of a book and refreshes the book's
+// household average. Returns false if there was no rating.
+func (r *RatingRepo) Delete(ctx context.Context, userID string, bookID int64) (bool, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return false, fmt.Errorf("begin delete rating: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	tag, err := tx.Exec(ctx,
+		`DELETE FROM book_ratings WHERE user_id = $1 AND book_id = $2`,
+		userID, bookID,
+	)
+	if err != nil {
+		return false, fmt.Errorf("delete rating: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return false, nil
+	}
+
+	if _, err := tx.Exec(ctx, refreshHouseholdRate, bookID); err != nil {
+		return false, fmt.Errorf("refresh household rate: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return false, fmt.Errorf("commit rating: %w", err)
+	}
+	return true, nil
+}
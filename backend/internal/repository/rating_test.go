@@ -0,0 +1,116 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grom-alex/homelib/backend/internal/models"
+)
+
+func TestRatingRepo_Get(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	now := time.Now()
+	four := 4
+	mock.ExpectQuery("SELECT rating, scale, review, created_at, updated_at FROM book_ratings WHERE user_id = \\$1 AND book_id = \\$2").
+		WithArgs("user-1", int64(42)).
+		WillReturnRows(pgxmock.NewRows([]string{"rating", "scale", "review", "created_at", "updated_at"}).
+			AddRow(&four, 5, "", now, now))
+	mock.ExpectQuery("SELECT rating, scale, review").
+		WithArgs("user-1", int64(43)).
+		WillReturnError(pgx.ErrNoRows)
+
+	repo := NewRatingRepo(mock)
+	r, err := repo.Get(context.Background(), "user-1", 42)
+	require.NoError(t, err)
+	require.NotNil(t, r)
+	require.NotNil(t, r.Rating)
+	assert.Equal(t, 4, *r.Rating)
+
+	r, err = repo.Get(context.Background(), "user-1", 43)
+	require.NoError(t, err)
+	assert.Nil(t, r)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRatingRepo_Save_RefreshesAverage(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	now := time.Now()
+	eight := 8
+	mock.ExpectBegin()
+	mock.ExpectQuery("INSERT INTO book_ratings .+ ON CONFLICT \\(user_id, book_id\\) DO UPDATE").
+		WithArgs("user-1", int64(42), &eight, 10, "Сильно").
+		WillReturnRows(pgxmock.NewRows([]string{"created_at", "updated_at"}).AddRow(now, now))
+	mock.ExpectExec("UPDATE books SET household_rate = .+ WHERE id = \\$1").
+		WithArgs(int64(42)).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mock.ExpectCommit()
+	mock.ExpectRollback()
+
+	r := &models.BookRating{UserID: "user-1", BookID: 42, Rating: &eight, Scale: 10, Review: "Сильно"}
+	saved, err := NewRatingRepo(mock).Save(context.Background(), r)
+	require.NoError(t, err)
+	assert.True(t, saved)
+	assert.Equal(t, now, r.UpdatedAt)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRatingRepo_Save_MissingBook(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("INSERT INTO book_ratings").
+		WithArgs("user-1", int64(404), pgxmock.AnyArg(), 5, "").
+		WillReturnError(pgx.ErrNoRows)
+	mock.ExpectRollback()
+
+	three := 3
+	saved, err := NewRatingRepo(mock).Save(context.Background(), &models.BookRating{UserID: "user-1", BookID: 404, Rating: &three, Scale: 5})
+	require.NoError(t, err)
+	assert.False(t, saved)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRatingRepo_Delete(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE FROM book_ratings WHERE user_id = \\$1 AND book_id = \\$2").
+		WithArgs("user-1", int64(42)).
+		WillReturnResult(pgxmock.NewResult("DELETE", 1))
+	mock.ExpectExec("UPDATE books SET household_rate").
+		WithArgs(int64(42)).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mock.ExpectCommit()
+	mock.ExpectRollback()
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE FROM book_ratings").
+		WithArgs("user-1", int64(43)).
+		WillReturnResult(pgxmock.NewResult("DELETE", 0))
+	mock.ExpectRollback()
+
+	repo := NewRatingRepo(mock)
+	deleted, err := repo.Delete(context.Background(), "user-1", 42)
+	require.NoError(t, err)
+	assert.True(t, deleted)
+
+	deleted, err = repo.Delete(context.Background(), "user-1", 43)
+	require.NoError(t, err)
+	assert.False(t, deleted)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,132 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/grom-alex/homelib/backend/internal/models"
+)
+
+// ReaderCacheRepo is the reader cache backend in Postgres large objects,
+// shared by all API replicas.
+type ReaderCacheRepo struct {
+	pool Pool
+}
+
+func NewReaderCacheRepo(pool Pool) *ReaderCacheRepo {
+	return &ReaderCacheRepo{pool: pool}
+}
+
+// Get returns a cached file; found is false on a cache miss.
+func (r *ReaderCacheRepo) Get(ctx context.Context, bookID int64, name string) ([]byte, bool, error) {
+	var data []byte
+	err := r.pool.QueryRow(ctx,
+		`SELECT lo_get(blob) FROM reader_cache WHERE book_id = $1 AND name = $2`,
+		bookID, name,
+	).Scan(&data)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, false, nil
+		}
+		return nil, false, fmt.Errorf("get cache file: %w", err)
+	}
+	return data, true, nil
+}
+
+// Put stores a file in a new large object and unlinks the one it replaces.
+func (r *ReaderCacheRepo) Put(ctx context.Context, bookID int64, name string, data []byte) (int64, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("begin cache write: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	var old uint32
+	err = tx.QueryRow(ctx,
+		`SELECT blob FROM reader_cache WHERE book_id = $1 AND name = $2 FOR UPDATE`,
+		bookID, name,
+	).Scan(&old)
+	switch {
+	case err == nil:
+		if _, err := tx.Exec(ctx,
+			`UPDATE reader_cache SET blob = lo_from_bytea(0, $3), size_bytes = $4, accessed_at = NOW()
+			 WHERE book_id = $1 AND name = $2`,
+			bookID, name, data, int64(len(data)),
+		); err != nil {
+			return 0, fmt.Errorf("update cache file: %w", err)
+		}
+		if _, err := tx.Exec(ctx, `SELECT lo_unlink($1)`, old); err != nil {
+			return 0, fmt.Errorf("unlink cache file: %w", err)
+		}
+	case errors.Is(err, pgx.ErrNoRows):
+		tag, err := tx.Exec(ctx,
+			`INSERT INTO reader_cache (book_id, name, blob, size_bytes)
+			 VALUES ($1, $2, lo_from_bytea(0, $3), $4)
+			 ON CONFLICT (book_id, name) DO NOTHING`,
+			bookID, name, data, int64(len(data)),
+		)
+		if err != nil {
+			return 0, fmt.Errorf("insert cache file: %w", err)
+		}
+		if tag.RowsAffected() == 0 {
+			// A concurrent write stored the file first; rolling back
+			// drops the large object created for this one.
+			return 0, nil
+		}
+	default:
+		return 0, fmt.Errorf("lock cache file: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("commit cache write: %w", err)
+	}
+	return int64(len(data)), nil
+}
+
+// Touch marks the book as recently used.
+func (r *ReaderCacheRepo) Touch(ctx context.Context, bookID int64) error {
+	if _, err := r.pool.Exec(ctx, `UPDATE reader_cache SET accessed_at = NOW() WHERE book_id = $1`, bookID); err != nil {
+		return fmt.Errorf("touch cache: %w", err)
+	}
+	return nil
+}
+
+// List returns one entry per cached book.
+func (r *ReaderCacheRepo) List(ctx context.Context) ([]models.ReaderCacheEntry, error) {
+	rows, err := r.pool.Query(ctx,
+		`SELECT book_id, SUM(size_bytes)::bigint, COUNT(*), MAX(accessed_at)
+		 FROM reader_cache GROUP BY book_id`,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("list cache: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []models.ReaderCacheEntry
+	for rows.Next() {
+		var e models.ReaderCacheEntry
+		if err := rows.Scan(&e.BookID, &e.Size, &e.Files, &e.Accessed); err != nil {
+			return nil, fmt.Errorf("scan cache entry: %w", err)
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+// Delete removes all files of a book with their large objects.
+// Returns false if nothing was cached.
+func (r *ReaderCacheRepo) Delete(ctx context.Context, bookID int64) (bool, error) {
+	var removed int
+	err := r.pool.QueryRow(ctx,
+		`WITH removed AS (DELETE FROM reader_cache WHERE book_id = $1 RETURNING blob)
+		 SELECT COUNT(lo_unlink(blob)) FROM removed`,
+		bookID,
+	).Scan(&removed)
+	if err != nil {
+		return false, fmt.Errorf("delete cache: %w", err)
+	}
+	return removed > 0, nil
+}
@@ -0,0 +1,169 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReaderCacheRepo_Get(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	mock.ExpectQuery("SELECT lo_get\\(blob\\) FROM reader_cache WHERE book_id = \\$1 AND name = \\$2").
+		WithArgs(int64(42), "content.json").
+		WillReturnRows(pgxmock.NewRows([]string{"lo_get"}).AddRow([]byte(`{"title":"x"}`)))
+
+	data, found, err := NewReaderCacheRepo(mock).Get(context.Background(), 42, "content.json")
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, `{"title":"x"}`, string(data))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestReaderCacheRepo_Get_Miss(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	mock.ExpectQuery("SELECT lo_get\\(blob\\) FROM reader_cache").
+		WithArgs(int64(42), "ch_1.html").
+		WillReturnError(pgx.ErrNoRows)
+
+	data, found, err := NewReaderCacheRepo(mock).Get(context.Background(), 42, "ch_1.html")
+	require.NoError(t, err)
+	assert.False(t, found)
+	assert.Nil(t, data)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestReaderCacheRepo_Put_Insert(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	data := []byte("<p>Глава</p>")
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT blob FROM reader_cache WHERE book_id = \\$1 AND name = \\$2 FOR UPDATE").
+		WithArgs(int64(42), "ch_1.html").
+		WillReturnError(pgx.ErrNoRows)
+	mock.ExpectExec("INSERT INTO reader_cache .+ lo_from_bytea\\(0, \\$3\\), \\$4\\) ON CONFLICT \\(book_id, name\\) DO NOTHING").
+		WithArgs(int64(42), "ch_1.html", data, int64(len(data))).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mock.ExpectCommit()
+	mock.ExpectRollback()
+
+	stored, err := NewReaderCacheRepo(mock).Put(context.Background(), 42, "ch_1.html", data)
+	require.NoError(t, err)
+	assert.Equal(t, int64(len(data)), stored)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestReaderCacheRepo_Put_Replace(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	data := []byte("<p>Глава</p>")
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT blob FROM reader_cache .+ FOR UPDATE").
+		WithArgs(int64(42), "ch_1.html").
+		WillReturnRows(pgxmock.NewRows([]string{"blob"}).AddRow(uint32(16400)))
+	mock.ExpectExec("UPDATE reader_cache SET blob = lo_from_bytea\\(0, \\$3\\), size_bytes = \\$4").
+		WithArgs(int64(42), "ch_1.html", data, int64(len(data))).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mock.ExpectExec("SELECT lo_unlink\\(\\$1\\)").
+		WithArgs(uint32(16400)).
+		WillReturnResult(pgxmock.NewResult("SELECT", 1))
+	mock.ExpectCommit()
+	mock.ExpectRollback()
+
+	stored, err := NewReaderCacheRepo(mock).Put(context.Background(), 42, "ch_1.html", data)
+	require.NoError(t, err)
+	assert.Equal(t, int64(len(data)), stored)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestReaderCacheRepo_Put_ConcurrentInsert(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	data := []byte("x")
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT blob FROM reader_cache .+ FOR UPDATE").
+		WithArgs(int64(42), "text.json").
+		WillReturnError(pgx.ErrNoRows)
+	mock.ExpectExec("INSERT INTO reader_cache").
+		WithArgs(int64(42), "text.json", data, int64(1)).
+		WillReturnResult(pgxmock.NewResult("INSERT", 0))
+	// No commit: the rollback drops the large object of the lost write
+	mock.ExpectRollback()
+
+	stored, err := NewReaderCacheRepo(mock).Put(context.Background(), 42, "text.json", data)
+	require.NoError(t, err)
+	assert.Zero(t, stored)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestReaderCacheRepo_Touch(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	mock.ExpectExec("UPDATE reader_cache SET accessed_at = NOW\\(\\) WHERE book_id = \\$1").
+		WithArgs(int64(42)).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 3))
+
+	require.NoError(t, NewReaderCacheRepo(mock).Touch(context.Background(), 42))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestReaderCacheRepo_List(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	accessed := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	mock.ExpectQuery("SELECT book_id, SUM\\(size_bytes\\)::bigint, COUNT\\(\\*\\), MAX\\(accessed_at\\) FROM reader_cache GROUP BY book_id").
+		WillReturnRows(pgxmock.NewRows([]string{"book_id", "sum", "count", "max"}).
+			AddRow(int64(42), int64(4096), 3, accessed))
+
+	entries, err := NewReaderCacheRepo(mock).List(context.Background())
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, int64(42), entries[0].BookID)
+	assert.Equal(t, int64(4096), entries[0].Size)
+	assert.Equal(t, 3, entries[0].Files)
+	assert.Equal(t, accessed, entries[0].Accessed)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestReaderCacheRepo_Delete(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	mock.ExpectQuery("DELETE FROM reader_cache WHERE book_id = \\$1 RETURNING blob\\) SELECT COUNT\\(lo_unlink\\(blob\\)\\) FROM removed").
+		WithArgs(int64(42)).
+		WillReturnRows(pgxmock.NewRows([]string{"count"}).AddRow(3))
+	mock.ExpectQuery("DELETE FROM reader_cache").
+		WithArgs(int64(7)).
+		WillReturnRows(pgxmock.NewRows([]string{"count"}).AddRow(0))
+
+	repo := NewReaderCacheRepo(mock)
+	found, err := repo.Delete(context.Background(), 42)
+	require.NoError(t, err)
+	assert.True(t, found)
+
+	found, err = repo.Delete(context.Background(), 7)
+	require.NoError(t, err)
+	assert.False(t, found)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -18,14 +18,37 @@ func NewReadingProgressRepo(pool Pool) *ReadingProgressRepo {
 	return &ReadingProgressRepo{pool: pool}
 }
 
+const progressColumns = `id, user_id, book_id, chapter_id, paragraph, char_offset,
+	chapter_progress, total_progress, device, updated_at`
+
+func scanProgress(row pgx.Row, p *models.ReadingProgress) error {
+	var paragraph, offset *int
+	if err := row.Scan(&p.ID, &p.UserID, &p.BookID, &p.ChapterID, &paragraph, &offset,
+		&p.ChapterProgress, &p.TotalProgress, &p.Device, &p.UpdatedAt); err != nil {
+		return err
+	}
+	p.Locator = nil
+	if paragraph != nil && offset != nil {
+		p.Locator = &models.Locator{Paragraph: *paragraph, Offset: *offset}
+	}
+	return nil
+}
+
+// locatorArgs returns the paragraph and offset columns of a locator, NULL if absent.
+func locatorArgs(l *models.Locator) (paragraph, offset *int) {
+	if l == nil {
+		return nil, nil
+	}
+	return &l.Paragraph, &l.Offset
+}
+
 // Get returns the reading progress for a user and book, or nil if not found.
 func (r *ReadingProgressRepo) Get(ctx context.Context, userID string, bookID int64) (*models.ReadingProgress, error) {
 	var p models.ReadingProgress
-	err := r.pool.QueryRow(ctx,
-		`SELECT id, user_id, book_id, chapter_id, chapter_progress, total_progress, device, updated_at
-		 FROM reading_progress WHERE user_id = $1 AND book_id = $2`,
+	err := scanProgress(r.pool.QueryRow(ctx,
+		`SELECT `+progressColumns+` FROM reading_progress WHERE user_id = $1 AND book_id = $2`,
 		userID, bookID,
-	).Scan(&p.ID, &p.UserID, &p.BookID, &p.ChapterID, &p.ChapterProgress, &p.TotalProgress, &p.Device, &p.UpdatedAt)
+	), &p)
 
 	if errors.Is(err, pgx.ErrNoRows) {
 		return nil, nil
@@ -38,17 +61,21 @@ func (r *ReadingProgressRepo) Get(ctx context.Context, userID string, bookID int
 
 // Upsert inserts or updates reading progress using ON CONFLICT (user_id, book_id).
 func (r *ReadingProgressRepo) Upsert(ctx context.Context, p *models.ReadingProgress) error {
+	paragraph, offset := locatorArgs(p.Locator)
 	err := r.pool.QueryRow(ctx,
-		`INSERT INTO reading_progress (user_id, book_id, chapter_id, chapter_progress, total_progress, device, updated_at)
-		 VALUES ($1, $2, $3, $4, $5, $6, NOW())
+		`INSERT INTO reading_progress (user_id, book_id, chapter_id, paragraph, char_offset,
+			chapter_progress, total_progress, device, updated_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NOW())
 		 ON CONFLICT (user_id, book_id) DO UPDATE SET
 			chapter_id = EXCLUDED.chapter_id,
+			paragraph = EXCLUDED.paragraph,
+			char_offset = EXCLUDED.char_offset,
 			chapter_progress = EXCLUDED.chapter_progress,
 			total_progress = EXCLUDED.total_progress,
 			device = EXCLUDED.device,
 			updated_at = NOW()
 		 RETURNING id, updated_at`,
-		p.UserID, p.BookID, p.ChapterID, p.ChapterProgress, p.TotalProgress, p.Device,
+		p.UserID, p.BookID, p.ChapterID, paragraph, offset, p.ChapterProgress, p.TotalProgress, p.Device,
 	).Scan(&p.ID, &p.UpdatedAt)
 
 	if err != nil {
@@ -57,11 +84,42 @@ func (r *ReadingProgressRepo) Upsert(ctx context.Context, p *models.ReadingProgr
 	return nil
 }
 
+// UpsertIfNewer stores progress recorded offline at p.UpdatedAt, unless the
+// stored progress is newer. On equal timestamps the save wins only if it
+// comes from the same device. Returns false if the stored progress was kept.
+func (r *ReadingProgressRepo) UpsertIfNewer(ctx context.Context, p *models.ReadingProgress) (bool, error) {
+	paragraph, offset := locatorArgs(p.Locator)
+	err := r.pool.QueryRow(ctx,
+		`INSERT INTO reading_progress (user_id, book_id, chapter_id, paragraph, char_offset,
+			chapter_progress, total_progress, device, updated_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		 ON CONFLICT (user_id, book_id) DO UPDATE SET
+			chapter_id = EXCLUDED.chapter_id,
+			paragraph = EXCLUDED.paragraph,
+			char_offset = EXCLUDED.char_offset,
+			chapter_progress = EXCLUDED.chapter_progress,
+			total_progress = EXCLUDED.total_progress,
+			device = EXCLUDED.device,
+			updated_at = EXCLUDED.updated_at
+		 WHERE reading_progress.updated_at < EXCLUDED.updated_at
+			OR (reading_progress.updated_at = EXCLUDED.updated_at AND reading_progress.device = EXCLUDED.device)
+		 RETURNING id, updated_at`,
+		p.UserID, p.BookID, p.ChapterID, paragraph, offset, p.ChapterProgress, p.TotalProgress, p.Device, p.UpdatedAt,
+	).Scan(&p.ID, &p.UpdatedAt)
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("upsert offline reading progress: %w", err)
+	}
+	return true, nil
+}
+
 // GetByUser returns all reading progress entries for a user.
 func (r *ReadingProgressRepo) GetByUser(ctx context.Context, userID string) ([]models.ReadingProgress, error) {
 	rows, err := r.pool.Query(ctx,
-		`SELECT id, user_id, book_id, chapter_id, chapter_progress, total_progress, device, updated_at
-		 FROM reading_progress WHERE user_id = $1 ORDER BY updated_at DESC`,
+		`SELECT `+progressColumns+` FROM reading_progress WHERE user_id = $1 ORDER BY updated_at DESC`,
 		userID,
 	)
 	if err != nil {
@@ -72,10 +130,49 @@ func (r *ReadingProgressRepo) GetByUser(ctx context.Context, userID string) ([]m
 	var result []models.ReadingProgress
 	for rows.Next() {
 		var p models.ReadingProgress
-		if err := rows.Scan(&p.ID, &p.UserID, &p.BookID, &p.ChapterID, &p.ChapterProgress, &p.TotalProgress, &p.Device, &p.UpdatedAt); err != nil {
+		if err := scanProgress(rows, &p); err != nil {
+			return nil, fmt.Errorf("scan reading progress: %w", err)
+		}
+		result = append(result, p)
+	}
+	return result, rows.Err()
+}
+
+// ListWithoutLocator returns progress entries saved before locators were
+// introduced, in ID order after afterID. Used by the locator migration.
+func (r *ReadingProgressRepo) ListWithoutLocator(ctx context.Context, afterID int64, limit int) ([]models.ReadingProgress, error) {
+	rows, err := r.pool.Query(ctx,
+		`SELECT `+progressColumns+` FROM reading_progress
+		 WHERE paragraph IS NULL AND id > $1 ORDER BY id LIMIT $2`,
+		afterID, limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("list progress without locator: %w", err)
+	}
+	defer rows.Close()
+
+	var result []models.ReadingProgress
+	for rows.Next() {
+		var p models.ReadingProgress
+		if err := scanProgress(rows, &p); err != nil {
 			return nil, fmt.Errorf("scan reading progress: %w", err)
 		}
 		result = append(result, p)
 	}
 	return result, rows.Err()
 }
+
+// SetLocator stores the locator migrated from p's percentage position,
+// unless the entry has been updated meanwhile. updated_at is kept so that
+// sync order is unaffected.
+func (r *ReadingProgressRepo) SetLocator(ctx context.Context, p *models.ReadingProgress, l models.Locator) error {
+	_, err := r.pool.Exec(ctx,
+		`UPDATE reading_progress SET paragraph = $2, char_offset = $3
+		 WHERE id = $1 AND paragraph IS NULL AND chapter_id = $4 AND chapter_progress = $5`,
+		p.ID, l.Paragraph, l.Offset, p.ChapterID, p.ChapterProgress,
+	)
+	if err != nil {
+		return fmt.Errorf("set progress locator: %w", err)
+	}
+	return nil
+}
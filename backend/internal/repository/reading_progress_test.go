@@ -14,6 +14,11 @@ import (
 	"github.com/grom-alex/homelib/backend/internal/models"
 )
 
+var progressRowColumns = []string{
+	"id", "user_id", "book_id", "chapter_id", "paragraph", "char_offset",
+	"chapter_progress", "total_progress", "device", "updated_at",
+}
+
 func TestReadingProgressRepo_Get_Found(t *testing.T) {
 	mock, err := pgxmock.NewPool()
 	require.NoError(t, err)
@@ -24,10 +29,7 @@ func TestReadingProgressRepo_Get_Found(t *testing.T) {
 
 	mock.ExpectQuery("SELECT .+ FROM reading_progress WHERE user_id = \\$1 AND book_id = \\$2").
 		WithArgs("user-1", int64(42)).
-		WillReturnRows(pgxmock.NewRows([]string{
-			"id", "user_id", "book_id", "chapter_id",
-			"chapter_progress", "total_progress", "device", "updated_at",
-		}).AddRow(int64(1), "user-1", int64(42), "ch3", 55, 30, "desktop", now))
+		WillReturnRows(pgxmock.NewRows(progressRowColumns).AddRow(int64(1), "user-1", int64(42), "ch3", intPtr(4), intPtr(17), 55, 30, "desktop", now))
 
 	p, err := repo.Get(context.Background(), "user-1", 42)
 	require.NoError(t, err)
@@ -36,6 +38,7 @@ func TestReadingProgressRepo_Get_Found(t *testing.T) {
 	assert.Equal(t, "user-1", p.UserID)
 	assert.Equal(t, int64(42), p.BookID)
 	assert.Equal(t, "ch3", p.ChapterID)
+	assert.Equal(t, &models.Locator{Paragraph: 4, Offset: 17}, p.Locator)
 	assert.Equal(t, 55, p.ChapterProgress)
 	assert.Equal(t, 30, p.TotalProgress)
 	assert.Equal(t, "desktop", p.Device)
@@ -90,13 +93,14 @@ func TestReadingProgressRepo_Upsert_Insert(t *testing.T) {
 		UserID:          "user-1",
 		BookID:          42,
 		ChapterID:       "ch2",
+		Locator:         &models.Locator{Paragraph: 12, Offset: 3},
 		ChapterProgress: 75,
 		TotalProgress:   50,
 		Device:          "mobile",
 	}
 
 	mock.ExpectQuery("INSERT INTO reading_progress .+ ON CONFLICT .+ RETURNING id, updated_at").
-		WithArgs("user-1", int64(42), "ch2", 75, 50, "mobile").
+		WithArgs("user-1", int64(42), "ch2", pgxmock.AnyArg(), pgxmock.AnyArg(), 75, 50, "mobile").
 		WillReturnRows(pgxmock.NewRows([]string{"id", "updated_at"}).AddRow(int64(1), now))
 
 	err = repo.Upsert(context.Background(), p)
@@ -124,7 +128,7 @@ func TestReadingProgressRepo_Upsert_Update(t *testing.T) {
 	}
 
 	mock.ExpectQuery("INSERT INTO reading_progress .+ ON CONFLICT .+ RETURNING id, updated_at").
-		WithArgs("user-1", int64(42), "ch5", 90, 80, "desktop").
+		WithArgs("user-1", int64(42), "ch5", pgxmock.AnyArg(), pgxmock.AnyArg(), 90, 80, "desktop").
 		WillReturnRows(pgxmock.NewRows([]string{"id", "updated_at"}).AddRow(int64(1), now))
 
 	err = repo.Upsert(context.Background(), p)
@@ -133,6 +137,53 @@ func TestReadingProgressRepo_Upsert_Update(t *testing.T) {
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
+func TestReadingProgressRepo_UpsertIfNewer(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	repo := NewReadingProgressRepo(mock)
+	at := time.Date(2026, 5, 1, 8, 30, 0, 0, time.UTC)
+
+	p := &models.ReadingProgress{
+		UserID:          "user-1",
+		BookID:          42,
+		ChapterID:       "ch3",
+		ChapterProgress: 10,
+		TotalProgress:   30,
+		Device:          "mobile",
+		UpdatedAt:       at,
+	}
+
+	mock.ExpectQuery("INSERT INTO reading_progress .+ VALUES \\(\\$1, \\$2, \\$3, \\$4, \\$5, \\$6, \\$7, \\$8, \\$9\\) .+ WHERE reading_progress.updated_at < EXCLUDED.updated_at").
+		WithArgs("user-1", int64(42), "ch3", pgxmock.AnyArg(), pgxmock.AnyArg(), 10, 30, "mobile", at).
+		WillReturnRows(pgxmock.NewRows([]string{"id", "updated_at"}).AddRow(int64(7), at))
+
+	applied, err := repo.UpsertIfNewer(context.Background(), p)
+	require.NoError(t, err)
+	assert.True(t, applied)
+	assert.Equal(t, int64(7), p.ID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestReadingProgressRepo_UpsertIfNewer_StoredIsNewer(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	repo := NewReadingProgressRepo(mock)
+	p := &models.ReadingProgress{UserID: "user-1", BookID: 42, ChapterID: "ch1", Device: "mobile", UpdatedAt: time.Now()}
+
+	mock.ExpectQuery("INSERT INTO reading_progress").
+		WithArgs("user-1", int64(42), "ch1", pgxmock.AnyArg(), pgxmock.AnyArg(), 0, 0, "mobile", p.UpdatedAt).
+		WillReturnRows(pgxmock.NewRows([]string{"id", "updated_at"}))
+
+	applied, err := repo.UpsertIfNewer(context.Background(), p)
+	require.NoError(t, err)
+	assert.False(t, applied)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
 func TestReadingProgressRepo_Upsert_DBError(t *testing.T) {
 	mock, err := pgxmock.NewPool()
 	require.NoError(t, err)
@@ -147,7 +198,7 @@ func TestReadingProgressRepo_Upsert_DBError(t *testing.T) {
 	}
 
 	mock.ExpectQuery("INSERT INTO reading_progress .+ ON CONFLICT .+ RETURNING id, updated_at").
-		WithArgs("user-1", int64(42), "ch1", 0, 0, "").
+		WithArgs("user-1", int64(42), "ch1", pgxmock.AnyArg(), pgxmock.AnyArg(), 0, 0, "").
 		WillReturnError(fmt.Errorf("foreign key violation"))
 
 	err = repo.Upsert(context.Background(), p)
@@ -167,18 +218,17 @@ func TestReadingProgressRepo_GetByUser_MultipleEntries(t *testing.T) {
 
 	mock.ExpectQuery("SELECT .+ FROM reading_progress WHERE user_id = \\$1 ORDER BY updated_at DESC").
 		WithArgs("user-1").
-		WillReturnRows(pgxmock.NewRows([]string{
-			"id", "user_id", "book_id", "chapter_id",
-			"chapter_progress", "total_progress", "device", "updated_at",
-		}).
-			AddRow(int64(2), "user-1", int64(100), "ch3", 50, 25, "desktop", now).
-			AddRow(int64(1), "user-1", int64(42), "ch1", 10, 5, "mobile", earlier))
+		WillReturnRows(pgxmock.NewRows(progressRowColumns).
+			AddRow(int64(2), "user-1", int64(100), "ch3", intPtr(0), intPtr(0), 50, 25, "desktop", now).
+			AddRow(int64(1), "user-1", int64(42), "ch1", nil, nil, 10, 5, "mobile", earlier))
 
 	result, err := repo.GetByUser(context.Background(), "user-1")
 	require.NoError(t, err)
 	require.Len(t, result, 2)
 	assert.Equal(t, int64(100), result[0].BookID)
 	assert.Equal(t, int64(42), result[1].BookID)
+	assert.Equal(t, &models.Locator{}, result[0].Locator)
+	assert.Nil(t, result[1].Locator)
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
@@ -191,10 +241,7 @@ func TestReadingProgressRepo_GetByUser_Empty(t *testing.T) {
 
 	mock.ExpectQuery("SELECT .+ FROM reading_progress WHERE user_id = \\$1 ORDER BY updated_at DESC").
 		WithArgs("user-new").
-		WillReturnRows(pgxmock.NewRows([]string{
-			"id", "user_id", "book_id", "chapter_id",
-			"chapter_progress", "total_progress", "device", "updated_at",
-		}))
+		WillReturnRows(pgxmock.NewRows(progressRowColumns))
 
 	result, err := repo.GetByUser(context.Background(), "user-new")
 	require.NoError(t, err)
@@ -220,6 +267,62 @@ func TestReadingProgressRepo_GetByUser_DBError(t *testing.T) {
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
+func TestReadingProgressRepo_ListWithoutLocator(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	repo := NewReadingProgressRepo(mock)
+	now := time.Now()
+
+	mock.ExpectQuery("SELECT .+ FROM reading_progress\\s+WHERE paragraph IS NULL AND id > \\$1 ORDER BY id LIMIT \\$2").
+		WithArgs(int64(10), 100).
+		WillReturnRows(pgxmock.NewRows(progressRowColumns).
+			AddRow(int64(11), "user-1", int64(42), "ch2", nil, nil, 40, 20, "", now))
+
+	result, err := repo.ListWithoutLocator(context.Background(), 10, 100)
+	require.NoError(t, err)
+	require.Len(t, result, 1)
+	assert.Equal(t, int64(11), result[0].ID)
+	assert.Equal(t, 40, result[0].ChapterProgress)
+	assert.Nil(t, result[0].Locator)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestReadingProgressRepo_SetLocator(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	repo := NewReadingProgressRepo(mock)
+	p := &models.ReadingProgress{ID: 11, ChapterID: "ch2", ChapterProgress: 40}
+
+	mock.ExpectExec("UPDATE reading_progress SET paragraph = \\$2, char_offset = \\$3").
+		WithArgs(int64(11), 7, 15, "ch2", 40).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+
+	err = repo.SetLocator(context.Background(), p, models.Locator{Paragraph: 7, Offset: 15})
+	require.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestReadingProgressRepo_SetLocator_DBError(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	repo := NewReadingProgressRepo(mock)
+	p := &models.ReadingProgress{ID: 11, ChapterID: "ch2"}
+
+	mock.ExpectExec("UPDATE reading_progress SET paragraph").
+		WithArgs(pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg()).
+		WillReturnError(fmt.Errorf("timeout"))
+
+	err = repo.SetLocator(context.Background(), p, models.Locator{})
+	assert.ErrorContains(t, err, "set progress locator")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
 func TestNewReadingProgressRepo(t *testing.T) {
 	mock, err := pgxmock.NewPool()
 	require.NoError(t, err)
@@ -0,0 +1,340 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/grom-alex/homelib/backend/internal/models"
+)
+
+type ReadingSessionRepo struct {
+	pool Pool
+}
+
+func NewReadingSessionRepo(pool Pool) *ReadingSessionRepo {
+	return &ReadingSessionRepo{pool: pool}
+}
+
+const sessionColumns = `id, user_id, book_id, source, device, started_at, ended_at, duration,
+	start_chapter_id, end_chapter_id, start_progress, end_progress,
+	start_position, end_position, pages, external_id`
+
+// statsSessions restricts aggregates to sessions with reading time; a
+// session of a single progress update only marks where reading started.
+const statsSessions = `s.user_id = $1 AND s.duration > 0`
+
+// LastSession returns the most recent session of a book on a device, or nil.
+func (r *ReadingSessionRepo) LastSession(ctx context.Context, userID string, bookID int64, device string) (*models.ReadingSession, error) {
+	var s models.ReadingSession
+	err := r.pool.QueryRow(ctx,
+		`SELECT `+sessionColumns+` FROM reading_sessions
+		 WHERE user_id = $1 AND book_id = $2 AND device = $3
+		 ORDER BY ended_at DESC LIMIT 1`,
+		userID, bookID, device,
+	).Scan(&s.ID, &s.UserID, &s.BookID, &s.Source, &s.Device, &s.StartedAt, &s.EndedAt, &s.Duration,
+		&s.StartChapterID, &s.EndChapterID, &s.StartProgress, &s.EndProgress,
+		&s.StartPosition, &s.EndPosition, &s.Pages, &s.ExternalID)
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get last reading session: %w", err)
+	}
+	return &s, nil
+}
+
+// Create inserts a session and sets its ID.
+func (r *ReadingSessionRepo) Create(ctx context.Context, s *models.ReadingSession) error {
+	err := r.pool.QueryRow(ctx,
+		`INSERT INTO reading_sessions (user_id, book_id, source, device, started_at, ended_at, duration,
+			start_chapter_id, end_chapter_id, start_progress, end_progress,
+			start_position, end_position, pages)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+		 RETURNING id`,
+		s.UserID, s.BookID, s.Source, s.Device, s.StartedAt, s.EndedAt, s.Duration,
+		s.StartChapterID, s.EndChapterID, s.StartProgress, s.EndProgress,
+		s.StartPosition, s.EndPosition, s.Pages,
+	).Scan(&s.ID)
+	if err != nil {
+		return fmt.Errorf("create reading session: %w", err)
+	}
+	return nil
+}
+
+// Extend updates the end of a session.
+func (r *ReadingSessionRepo) Extend(ctx context.Context, s *models.ReadingSession) error {
+	_, err := r.pool.Exec(ctx,
+		`UPDATE reading_sessions SET ended_at = $2, duration = $3, end_chapter_id = $4,
+			end_progress = $5, end_position = $6, pages = $7
+		 WHERE id = $1`,
+		s.ID, s.EndedAt, s.Duration, s.EndChapterID, s.EndProgress, s.EndPosition, s.Pages,
+	)
+	if err != nil {
+		return fmt.Errorf("extend reading session: %w", err)
+	}
+	return nil
+}
+
+// Import inserts imported sessions, skipping those already imported (by
+// ExternalID). Sessions built from kosync updates that overlap an imported
+// session of the same book are removed: the imported statistics describe
+// the same reading in more detail. Returns the number of sessions added.
+func (r *ReadingSessionRepo) Import(ctx context.Context, sessions []models.ReadingSession) (int, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("begin import: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	added := 0
+	for _, s := range sessions {
+		tag, err := tx.Exec(ctx,
+			`INSERT INTO reading_sessions (user_id, book_id, source, device, started_at, ended_at, duration,
+				start_progress, end_progress, pages, external_id)
+			 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+			 ON CONFLICT (user_id, external_id) DO NOTHING`,
+			s.UserID, s.BookID, s.Source, s.Device, s.StartedAt, s.EndedAt, s.Duration,
+			s.StartProgress, s.EndProgress, s.Pages, s.ExternalID,
+		)
+		if err != nil {
+			return 0, fmt.Errorf("import reading session: %w", err)
+		}
+		if tag.RowsAffected() == 0 {
+			continue
+		}
+		added++
+		if _, err := tx.Exec(ctx,
+			`DELETE FROM reading_sessions
+			 WHERE user_id = $1 AND book_id = $2 AND source = 'kosync'
+			   AND started_at <= $4 AND ended_at >= $3`,
+			s.UserID, s.BookID, s.StartedAt, s.EndedAt,
+		); err != nil {
+			return 0, fmt.Errorf("remove synced sessions: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("commit import: %w", err)
+	}
+	return added, nil
+}
+
+// FindBookByTitle returns the only non-deleted book with the title (case
+// insensitive) and, if author is given, an author whose name contains it.
+// Returns false if there is no such book or the match is ambiguous.
+func (r *ReadingSessionRepo) FindBookByTitle(ctx context.Context, title, author string) (int64, bool, error) {
+	rows, err := r.pool.Query(ctx,
+		`SELECT b.id FROM books b
+		 WHERE NOT b.is_deleted AND lower(b.title) = lower($1)
+		   AND ($2 = '' OR EXISTS (
+			SELECT 1 FROM book_authors ba JOIN authors a ON a.id = ba.author_id
+			WHERE ba.book_id = b.id AND strpos(lower(a.name), lower($2)) > 0))
+		 ORDER BY b.id LIMIT 2`,
+		title, author,
+	)
+	if err != nil {
+		return 0, false, fmt.Errorf("find book by title: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return 0, false, fmt.Errorf("scan book id: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return 0, false, fmt.Errorf("find book by title: %w", err)
+	}
+	if len(ids) != 1 {
+		return 0, false, nil
+	}
+	return ids[0], true, nil
+}
+
+// DailyTotals returns reading per day (in time zone tz) between from and
+// to, inclusive "YYYY-MM-DD" dates, for days with reading.
+func (r *ReadingSessionRepo) DailyTotals(ctx context.Context, userID, tz, from, to string) ([]models.StatsPeriod, error) {
+	return r.periodTotals(ctx,
+		`SELECT to_char((s.started_at AT TIME ZONE $2)::date, 'YYYY-MM-DD') AS period,
+			SUM(s.duration), SUM(s.pages), COUNT(*)
+		 FROM reading_sessions s
+		 WHERE `+statsSessions+`
+		   AND (s.started_at AT TIME ZONE $2)::date BETWEEN $3::date AND $4::date
+		 GROUP BY period ORDER BY period`,
+		userID, tz, from, to)
+}
+
+// YearlyTotals returns reading per year (in time zone tz) over the whole history.
+func (r *ReadingSessionRepo) YearlyTotals(ctx context.Context, userID, tz string) ([]models.StatsPeriod, error) {
+	return r.periodTotals(ctx,
+		`SELECT to_char(s.started_at AT TIME ZONE $2, 'YYYY') AS period,
+			SUM(s.duration), SUM(s.pages), COUNT(*)
+		 FROM reading_sessions s
+		 WHERE `+statsSessions+`
+		 GROUP BY period ORDER BY period`,
+		userID, tz)
+}
+
+func (r *ReadingSessionRepo) periodTotals(ctx context.Context, query string, args ...any) ([]models.StatsPeriod, error) {
+	rows, err := r.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("reading totals: %w", err)
+	}
+	defer rows.Close()
+
+	var out []models.StatsPeriod
+	for rows.Next() {
+		var p models.StatsPeriod
+		if err := rows.Scan(&p.Period, &p.Seconds, &p.Pages, &p.Sessions); err != nil {
+			return nil, fmt.Errorf("scan reading totals: %w", err)
+		}
+		out = append(out, p)
+	}
+	return out, rows.Err()
+}
+
+// ReadingDays returns all days (in time zone tz) with reading, ascending.
+func (r *ReadingSessionRepo) ReadingDays(ctx context.Context, userID, tz string) ([]time.Time, error) {
+	rows, err := r.pool.Query(ctx,
+		`SELECT DISTINCT (s.started_at AT TIME ZONE $2)::date AS day
+		 FROM reading_sessions s
+		 WHERE `+statsSessions+`
+		 ORDER BY day`,
+		userID, tz,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("list reading days: %w", err)
+	}
+	defer rows.Close()
+
+	var days []time.Time
+	for rows.Next() {
+		var d time.Time
+		if err := rows.Scan(&d); err != nil {
+			return nil, fmt.Errorf("scan reading day: %w", err)
+		}
+		days = append(days, d)
+	}
+	return days, rows.Err()
+}
+
+// FinishedByDay returns how many books were finished per day (in time zone
+// tz), ascending. A book is finished by the first session that reaches its end.
+func (r *ReadingSessionRepo) FinishedByDay(ctx context.Context, userID, tz string) ([]models.StatsPeriod, error) {
+	rows, err := r.pool.Query(ctx,
+		`SELECT to_char(f.finished_at AT TIME ZONE $2, 'YYYY-MM-DD') AS period, COUNT(*)
+		 FROM (
+			SELECT MIN(s.ended_at) AS finished_at FROM reading_sessions s
+			WHERE s.user_id = $1 AND s.end_progress >= 100
+			GROUP BY s.book_id
+		 ) f
+		 GROUP BY period ORDER BY period`,
+		userID, tz,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("count finished books: %w", err)
+	}
+	defer rows.Close()
+
+	var out []models.StatsPeriod
+	for rows.Next() {
+		var p models.StatsPeriod
+		if err := rows.Scan(&p.Period, &p.BooksFinished); err != nil {
+			return nil, fmt.Errorf("scan finished books: %w", err)
+		}
+		out = append(out, p)
+	}
+	return out, rows.Err()
+}
+
+// GenreTotals returns reading per genre between from and to (dates in tz),
+// most read first. A book in several genres counts towards each.
+func (r *ReadingSessionRepo) GenreTotals(ctx context.Context, userID, tz, from, to string, limit int) ([]models.StatsTotal, error) {
+	return r.totals(ctx,
+		`SELECT g.id, g.name, SUM(s.duration) AS seconds, SUM(s.pages)
+		 FROM reading_sessions s
+		 JOIN book_genres bg ON bg.book_id = s.book_id
+		 JOIN genres g ON g.id = bg.genre_id
+		 WHERE `+statsSessions+`
+		   AND (s.started_at AT TIME ZONE $2)::date BETWEEN $3::date AND $4::date
+		 GROUP BY g.id, g.name ORDER BY seconds DESC, g.id LIMIT $5`,
+		userID, tz, from, to, limit)
+}
+
+// AuthorTotals returns reading per author between from and to (dates in
+// tz), most read first.
+func (r *ReadingSessionRepo) AuthorTotals(ctx context.Context, userID, tz, from, to string, limit int) ([]models.StatsTotal, error) {
+	return r.totals(ctx,
+		`SELECT a.id, a.name, SUM(s.duration) AS seconds, SUM(s.pages)
+		 FROM reading_sessions s
+		 JOIN book_authors ba ON ba.book_id = s.book_id
+		 JOIN authors a ON a.id = ba.author_id
+		 WHERE `+statsSessions+`
+		   AND (s.started_at AT TIME ZONE $2)::date BETWEEN $3::date AND $4::date
+		 GROUP BY a.id, a.name ORDER BY seconds DESC, a.id LIMIT $5`,
+		userID, tz, from, to, limit)
+}
+
+func (r *ReadingSessionRepo) totals(ctx context.Context, query string, args ...any) ([]models.StatsTotal, error) {
+	rows, err := r.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("reading totals: %w", err)
+	}
+	defer rows.Close()
+
+	var out []models.StatsTotal
+	for rows.Next() {
+		var t models.StatsTotal
+		if err := rows.Scan(&t.ID, &t.Name, &t.Seconds, &t.Pages); err != nil {
+			return nil, fmt.Errorf("scan reading totals: %w", err)
+		}
+		out = append(out, t)
+	}
+	return out, rows.Err()
+}
+
+// GenreYearTotal is the reading of a genre in a year.
+type GenreYearTotal struct {
+	Year  string
+	Genre models.StatsTotal
+}
+
+// GenresByYear returns the top genres of every year (in time zone tz).
+func (r *ReadingSessionRepo) GenresByYear(ctx context.Context, userID, tz string, perYear int) ([]GenreYearTotal, error) {
+	rows, err := r.pool.Query(ctx,
+		`SELECT year, id, name, seconds, pages FROM (
+			SELECT to_char(s.started_at AT TIME ZONE $2, 'YYYY') AS year, g.id, g.name,
+				SUM(s.duration) AS seconds, SUM(s.pages) AS pages,
+				ROW_NUMBER() OVER (PARTITION BY to_char(s.started_at AT TIME ZONE $2, 'YYYY')
+					ORDER BY SUM(s.duration) DESC, g.id) AS rank
+			FROM reading_sessions s
+			JOIN book_genres bg ON bg.book_id = s.book_id
+			JOIN genres g ON g.id = bg.genre_id
+			WHERE `+statsSessions+`
+			GROUP BY year, g.id, g.name
+		 ) t WHERE rank <= $3 ORDER BY year, rank`,
+		userID, tz, perYear,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("genres by year: %w", err)
+	}
+	defer rows.Close()
+
+	var out []GenreYearTotal
+	for rows.Next() {
+		var t GenreYearTotal
+		if err := rows.Scan(&t.Year, &t.Genre.ID, &t.Genre.Name, &t.Genre.Seconds, &t.Genre.Pages); err != nil {
+			return nil, fmt.Errorf("scan genres by year: %w", err)
+		}
+		out = append(out, t)
+	}
+	return out, rows.Err()
+}
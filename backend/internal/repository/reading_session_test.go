@@ -0,0 +1,163 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grom-alex/homelib/backend/internal/models"
+)
+
+func TestReadingSessionRepo_LastSession(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	repo := NewReadingSessionRepo(mock)
+	start := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	end := start.Add(5 * time.Minute)
+
+	mock.ExpectQuery("SELECT .+ FROM reading_sessions WHERE user_id = \\$1 AND book_id = \\$2 AND device = \\$3 ORDER BY ended_at DESC").
+		WithArgs("user-1", int64(42), "web").
+		WillReturnRows(pgxmock.NewRows([]string{"id", "user_id", "book_id", "source", "device", "started_at", "ended_at", "duration",
+			"start_chapter_id", "end_chapter_id", "start_progress", "end_progress",
+			"start_position", "end_position", "pages", "external_id"}).
+			AddRow(int64(7), "user-1", int64(42), "web", "web", start, end, 300,
+				"ch1", "ch2", 10, 20, int64(1000), int64(9000), 2, (*string)(nil)))
+	mock.ExpectQuery("SELECT .+ FROM reading_sessions").
+		WithArgs("user-1", int64(43), "web").
+		WillReturnError(pgx.ErrNoRows)
+
+	s, err := repo.LastSession(context.Background(), "user-1", 42, "web")
+	require.NoError(t, err)
+	require.NotNil(t, s)
+	assert.Equal(t, int64(7), s.ID)
+	assert.Equal(t, end, s.EndedAt)
+	assert.Equal(t, "ch2", s.EndChapterID)
+	assert.Nil(t, s.ExternalID)
+
+	s, err = repo.LastSession(context.Background(), "user-1", 43, "web")
+	require.NoError(t, err)
+	assert.Nil(t, s)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestReadingSessionRepo_CreateAndExtend(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	repo := NewReadingSessionRepo(mock)
+	start := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	s := &models.ReadingSession{
+		UserID: "user-1", BookID: 42, Source: models.SessionSourceWeb, Device: "web",
+		StartedAt: start, EndedAt: start, StartChapterID: "ch1", EndChapterID: "ch1",
+		StartProgress: 10, EndProgress: 10, StartPosition: 1000, EndPosition: 1000,
+	}
+
+	mock.ExpectQuery("INSERT INTO reading_sessions .+ RETURNING id").
+		WithArgs("user-1", int64(42), "web", "web", start, start, 0,
+			"ch1", "ch1", 10, 10, int64(1000), int64(1000), 0).
+		WillReturnRows(pgxmock.NewRows([]string{"id"}).AddRow(int64(7)))
+	mock.ExpectExec("UPDATE reading_sessions SET ended_at = \\$2").
+		WithArgs(int64(7), start.Add(4*time.Minute), 240, "ch2", 20, int64(9000), 2).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+
+	require.NoError(t, repo.Create(context.Background(), s))
+	assert.Equal(t, int64(7), s.ID)
+
+	s.EndedAt = start.Add(4 * time.Minute)
+	s.Duration = 240
+	s.EndChapterID = "ch2"
+	s.EndProgress = 20
+	s.EndPosition = 9000
+	s.Pages = 2
+	require.NoError(t, repo.Extend(context.Background(), s))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestReadingSessionRepo_Import(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	repo := NewReadingSessionRepo(mock)
+	start := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	end := start.Add(30 * time.Minute)
+	first, second := "koreader:1:100", "koreader:1:200"
+	sessions := []models.ReadingSession{
+		{UserID: "user-1", BookID: 42, Source: models.SessionSourceKOReader, Device: "KOReader",
+			StartedAt: start, EndedAt: end, Duration: 1500, EndProgress: 40, Pages: 12, ExternalID: &first},
+		{UserID: "user-1", BookID: 42, Source: models.SessionSourceKOReader, Device: "KOReader",
+			StartedAt: start, EndedAt: end, Duration: 1500, EndProgress: 40, Pages: 12, ExternalID: &second},
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO reading_sessions .+ ON CONFLICT \\(user_id, external_id\\) DO NOTHING").
+		WithArgs("user-1", int64(42), "koreader", "KOReader", start, end, 1500, 0, 40, 12, &first).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mock.ExpectExec("DELETE FROM reading_sessions WHERE .+ source = 'kosync'").
+		WithArgs("user-1", int64(42), start, end).
+		WillReturnResult(pgxmock.NewResult("DELETE", 1))
+	mock.ExpectExec("INSERT INTO reading_sessions").
+		WithArgs("user-1", int64(42), "koreader", "KOReader", start, end, 1500, 0, 40, 12, &second).
+		WillReturnResult(pgxmock.NewResult("INSERT", 0))
+	mock.ExpectCommit()
+	mock.ExpectRollback()
+
+	added, err := repo.Import(context.Background(), sessions)
+	require.NoError(t, err)
+	assert.Equal(t, 1, added)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestReadingSessionRepo_FindBookByTitle(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	repo := NewReadingSessionRepo(mock)
+
+	mock.ExpectQuery("SELECT b.id FROM books b WHERE NOT b.is_deleted AND lower\\(b.title\\) = lower\\(\\$1\\)").
+		WithArgs("Пикник на обочине", "Стругацкий").
+		WillReturnRows(pgxmock.NewRows([]string{"id"}).AddRow(int64(42)))
+	mock.ExpectQuery("SELECT b.id FROM books b").
+		WithArgs("Избранное", "").
+		WillReturnRows(pgxmock.NewRows([]string{"id"}).AddRow(int64(1)).AddRow(int64(2)))
+
+	id, ok, err := repo.FindBookByTitle(context.Background(), "Пикник на обочине", "Стругацкий")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, int64(42), id)
+
+	_, ok, err = repo.FindBookByTitle(context.Background(), "Избранное", "")
+	require.NoError(t, err)
+	assert.False(t, ok)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestReadingSessionRepo_DailyTotals(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	repo := NewReadingSessionRepo(mock)
+
+	mock.ExpectQuery("SELECT to_char\\(\\(s.started_at AT TIME ZONE \\$2\\)::date, 'YYYY-MM-DD'\\) AS period").
+		WithArgs("user-1", "Europe/Moscow", "2026-03-01", "2026-03-31").
+		WillReturnRows(pgxmock.NewRows([]string{"period", "sum", "sum", "count"}).
+			AddRow("2026-03-01", 1800, 12, 2).
+			AddRow("2026-03-03", 600, 4, 1))
+
+	days, err := repo.DailyTotals(context.Background(), "user-1", "Europe/Moscow", "2026-03-01", "2026-03-31")
+	require.NoError(t, err)
+	require.Len(t, days, 2)
+	assert.Equal(t, models.StatsPeriod{Period: "2026-03-01", Seconds: 1800, Pages: 12, Sessions: 2}, days[0])
+	assert.Equal(t, "2026-03-03", days[1].Period)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -3,11 +3,13 @@ package repository
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 
 	"github.com/grom-alex/homelib/backend/internal/models"
+	"github.com/grom-alex/homelib/backend/internal/translit"
 )
 
 type SeriesRepo struct {
@@ -26,14 +28,14 @@ func (r *SeriesRepo) UpsertSeries(ctx context.Context, tx pgx.Tx, names []string
 		return make(map[string]int64), nil
 	}
 
-	const upsertSQL = `INSERT INTO series (name)
-		 VALUES ($1)
-		 ON CONFLICT (name) DO UPDATE SET name = EXCLUDED.name
+	const upsertSQL = `INSERT INTO series (name, name_translit)
+		 VALUES ($1, $2)
+		 ON CONFLICT (name) DO UPDATE SET name_translit = EXCLUDED.name_translit
 		 RETURNING id`
 
 	batch := &pgx.Batch{}
 	for _, name := range names {
-		batch.Queue(upsertSQL, name)
+		batch.Queue(upsertSQL, name, translit.Key(name))
 	}
 
 	br := tx.SendBatch(ctx, batch)
@@ -51,22 +53,47 @@ func (r *SeriesRepo) UpsertSeries(ctx context.Context, tx pgx.Tx, names []string
 	return result, nil
 }
 
-func (r *SeriesRepo) ListWithBookCount(ctx context.Context, query string, limit, offset int) ([]models.SeriesListItem, int, error) {
+// seriesSortKey orders series by name (unique).
+var seriesSortKey = sortKey{expr: "s.name", sqlType: "text"}
+
+// ListWithBookCount returns series matching the filter with book counts and
+// the two most frequent authors. Supports both page/limit (OFFSET) and keyset
+// pagination via f.Cursor.
+func (r *SeriesRepo) ListWithBookCount(ctx context.Context, f models.ListFilter) ([]models.SeriesListItem, models.PageInfo, error) {
+	var page models.PageInfo
+
+	cursor, err := decodeCursorFor(f.Cursor, "name", "asc")
+	if err != nil {
+		return nil, page, err
+	}
+
+	var conditions []string
 	var args []any
-	where := ""
 	argIdx := 1
 
-	if query != "" {
-		where = fmt.Sprintf(" WHERE s.name ILIKE '%%' || $%d || '%%'", argIdx)
-		args = append(args, query)
-		argIdx++
+	if f.Query != "" {
+		cond, condArgs, next := nameMatchCondition("s.name", "s.name_translit", f.Query, argIdx)
+		conditions = append(conditions, cond)
+		args = append(args, condArgs...)
+		argIdx = next
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = " WHERE " + strings.Join(conditions, " AND ")
 	}
 
-	var total int
-	countQuery := "SELECT COUNT(*) FROM series s" + where
-	err := r.pool.QueryRow(ctx, countQuery, args...).Scan(&total)
+	page.Total, page.TotalEstimated, err = countRows(ctx, r.pool, f.Count, "series s"+where, args)
 	if err != nil {
-		return nil, 0, fmt.Errorf("count series: %w", err)
+		return nil, page, fmt.Errorf("count series: %w", err)
+	}
+
+	if cursor != nil {
+		cond, cursorArgs, next := keysetCondition(seriesSortKey, "s.id", false, cursor, argIdx)
+		conditions = append(conditions, cond)
+		args = append(args, cursorArgs...)
+		argIdx = next
+		where = " WHERE " + strings.Join(conditions, " AND ")
 	}
 
 	listQuery := fmt.Sprintf(
@@ -76,7 +103,7 @@ func (r *SeriesRepo) ListWithBookCount(ctx context.Context, query string, limit,
 		   LEFT JOIN books b ON b.series_id = s.id
 		   %s
 		   GROUP BY s.id, s.name
-		   ORDER BY s.name
+		   ORDER BY s.name, s.id
 		   LIMIT $%d OFFSET $%d
 		 ),
 		 series_authors AS (
@@ -102,25 +129,39 @@ func (r *SeriesRepo) ListWithBookCount(ctx context.Context, query string, limit,
 		        COALESCE(sa.authors, '') AS authors
 		 FROM page_series ps
 		 LEFT JOIN series_authors_agg sa ON sa.series_id = ps.id
-		 ORDER BY ps.name`,
+		 ORDER BY ps.name, ps.id`,
 		where, argIdx, argIdx+1,
 	)
-	args = append(args, limit, offset)
+	// Fetch one extra row to detect whether a next page exists
+	args = append(args, f.Limit+1, f.Offset())
 
 	rows, err := r.pool.Query(ctx, listQuery, args...)
 	if err != nil {
-		return nil, 0, fmt.Errorf("list series: %w", err)
+		return nil, page, fmt.Errorf("list series: %w", err)
 	}
 	defer rows.Close()
 
 	var items []models.SeriesListItem
+	var keys []*string
+	var ids []int64
 	for rows.Next() {
 		var item models.SeriesListItem
 		if err := rows.Scan(&item.ID, &item.Name, &item.BooksCount, &item.Authors); err != nil {
-			return nil, 0, fmt.Errorf("scan series: %w", err)
+			return nil, page, fmt.Errorf("scan series: %w", err)
 		}
 		items = append(items, item)
+		name := item.Name
+		keys = append(keys, &name)
+		ids = append(ids, item.ID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, page, err
+	}
+
+	page.NextCursor = nextCursor("name", "asc", f.Limit, keys, ids)
+	if len(items) > f.Limit {
+		items = items[:f.Limit]
 	}
 
-	return items, total, rows.Err()
+	return items, page, nil
 }
@@ -0,0 +1,322 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/grom-alex/homelib/backend/internal/models"
+)
+
+type ShelfRepo struct {
+	pool Pool
+}
+
+func NewShelfRepo(pool Pool) *ShelfRepo {
+	return &ShelfRepo{pool: pool}
+}
+
+const shelfColumns = `s.id, s.user_id, COALESCE(s.status, ''), s.name, s.note, s.position,
+	(SELECT COUNT(*) FROM shelf_books sb WHERE sb.shelf_id = s.id), s.created_at, s.updated_at`
+
+func scanShelf(row pgx.Row, s *models.Shelf) error {
+	return row.Scan(&s.ID, &s.UserID, &s.Status, &s.Name, &s.Note, &s.Position,
+		&s.BookCount, &s.CreatedAt, &s.UpdatedAt)
+}
+
+// ensureStatusShelves creates the user's missing status shelves.
+func (r *ShelfRepo) ensureStatusShelves(ctx context.Context, userID string) error {
+	names := make([]string, len(models.ShelfStatuses))
+	for i, st := range models.ShelfStatuses {
+		names[i] = models.ShelfStatusNames[st]
+	}
+	_, err := r.pool.Exec(ctx,
+		`INSERT INTO shelves (user_id, status, name, position)
+		 SELECT $1, t.status, t.name, t.n - 1
+		 FROM unnest($2::text[], $3::text[]) WITH ORDINALITY AS t(status, name, n)
+		 ON CONFLICT (user_id, status) DO NOTHING`,
+		userID, models.ShelfStatuses, names,
+	)
+	if err != nil {
+		return fmt.Errorf("create status shelves: %w", err)
+	}
+	return nil
+}
+
+// List returns the user's shelves in their order, creating the status
+// shelves on first use.
+func (r *ShelfRepo) List(ctx context.Context, userID string) ([]models.Shelf, error) {
+	if err := r.ensureStatusShelves(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	rows, err := r.pool.Query(ctx,
+		`SELECT `+shelfColumns+` FROM shelves s WHERE s.user_id = $1 ORDER BY s.position, s.id`,
+		userID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("list shelves: %w", err)
+	}
+	defer rows.Close()
+
+	result := []models.Shelf{}
+	for rows.Next() {
+		var s models.Shelf
+		if err := scanShelf(rows, &s); err != nil {
+			return nil, fmt.Errorf("scan shelf: %w", err)
+		}
+		result = append(result, s)
+	}
+	return result, rows.Err()
+}
+
+// Get returns a shelf of the user, or nil if not found.
+func (r *ShelfRepo) Get(ctx context.Context, userID string, id int64) (*models.Shelf, error) {
+	var s models.Shelf
+	err := scanShelf(r.pool.QueryRow(ctx,
+		`SELECT `+shelfColumns+` FROM shelves s WHERE s.id = $1 AND s.user_id = $2`,
+		id, userID,
+	), &s)
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get shelf: %w", err)
+	}
+	return &s, nil
+}
+
+// StatusShelfID returns the ID of the user's shelf for a reading status,
+// creating the status shelves if needed.
+func (r *ShelfRepo) StatusShelfID(ctx context.Context, userID, status string) (int64, error) {
+	if err := r.ensureStatusShelves(ctx, userID); err != nil {
+		return 0, err
+	}
+
+	var id int64
+	err := r.pool.QueryRow(ctx,
+		`SELECT id FROM shelves WHERE user_id = $1 AND status = $2`,
+		userID, status,
+	).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("get status shelf: %w", err)
+	}
+	return id, nil
+}
+
+// BookStatus returns the reading status of a book for the user: the status
+// of the status shelf it is on, or "" if it is on none.
+func (r *ShelfRepo) BookStatus(ctx context.Context, userID string, bookID int64) (string, error) {
+	var status string
+	err := r.pool.QueryRow(ctx,
+		`SELECT s.status FROM shelf_books sb JOIN shelves s ON s.id = sb.shelf_id
+		 WHERE sb.book_id = $1 AND s.user_id = $2 AND s.status IS NOT NULL
+		 LIMIT 1`,
+		bookID, userID,
+	).Scan(&status)
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("get book status: %w", err)
+	}
+	return status, nil
+}
+
+// Create inserts a custom shelf after the user's other shelves and fills
+// its ID, position and timestamps.
+func (r *ShelfRepo) Create(ctx context.Context, s *models.Shelf) error {
+	err := r.pool.QueryRow(ctx,
+		`INSERT INTO shelves (user_id, name, note, position)
+		 VALUES ($1, $2, $3, (SELECT COALESCE(MAX(position) + 1, 0) FROM shelves WHERE user_id = $1))
+		 RETURNING id, position, created_at, updated_at`,
+		s.UserID, s.Name, s.Note,
+	).Scan(&s.ID, &s.Position, &s.CreatedAt, &s.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("create shelf: %w", err)
+	}
+	return nil
+}
+
+// Update saves the name and note of a shelf and refreshes its UpdatedAt.
+// Returns false if the shelf does not belong to the user.
+func (r *ShelfRepo) Update(ctx context.Context, s *models.Shelf) (bool, error) {
+	err := r.pool.QueryRow(ctx,
+		`UPDATE shelves SET name = $3, note = $4, updated_at = NOW()
+		 WHERE id = $1 AND user_id = $2
+		 RETURNING updated_at`,
+		s.ID, s.UserID, s.Name, s.Note,
+	).Scan(&s.UpdatedAt)
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("update shelf: %w", err)
+	}
+	return true, nil
+}
+
+// Delete removes a custom shelf of the user with its entries. Status
+// shelves are never deleted. Returns false if nothing was deleted.
+func (r *ShelfRepo) Delete(ctx context.Context, userID string, id int64) (bool, error) {
+	tag, err := r.pool.Exec(ctx,
+		`DELETE FROM shelves WHERE id = $1 AND user_id = $2 AND status IS NULL`,
+		id, userID,
+	)
+	if err != nil {
+		return false, fmt.Errorf("delete shelf: %w", err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// Reorder renumbers the user's shelves: the listed IDs first, in the given
+// order, then the others in their current order.
+func (r *ShelfRepo) Reorder(ctx context.Context, userID string, ids []int64) error {
+	_, err := r.pool.Exec(ctx,
+		`UPDATE shelves s SET position = o.n - 1
+		 FROM (
+			SELECT id, ROW_NUMBER() OVER (
+				ORDER BY COALESCE(array_position($2::bigint[], id), 2147483647), position, id) AS n
+			FROM shelves WHERE user_id = $1
+		 ) o
+		 WHERE s.id = o.id AND s.position <> o.n - 1`,
+		userID, ids,
+	)
+	if err != nil {
+		return fmt.Errorf("reorder shelves: %w", err)
+	}
+	return nil
+}
+
+const shelfEntryColumns = `sb.shelf_id, sb.book_id, sb.position, sb.note, sb.added_at`
+
+func (r *ShelfRepo) listEntries(ctx context.Context, query string, args ...any) ([]models.ShelfEntry, error) {
+	rows, err := r.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list shelf entries: %w", err)
+	}
+	defer rows.Close()
+
+	result := []models.ShelfEntry{}
+	for rows.Next() {
+		var e models.ShelfEntry
+		if err := rows.Scan(&e.ShelfID, &e.BookID, &e.Position, &e.Note, &e.AddedAt); err != nil {
+			return nil, fmt.Errorf("scan shelf entry: %w", err)
+		}
+		result = append(result, e)
+	}
+	return result, rows.Err()
+}
+
+// ListEntries returns the books on a shelf of the user in shelf order.
+func (r *ShelfRepo) ListEntries(ctx context.Context, userID string, shelfID int64) ([]models.ShelfEntry, error) {
+	return r.listEntries(ctx,
+		`SELECT `+shelfEntryColumns+` FROM shelf_books sb JOIN shelves s ON s.id = sb.shelf_id
+		 WHERE sb.shelf_id = $1 AND s.user_id = $2
+		 ORDER BY sb.position, sb.added_at, sb.book_id`,
+		shelfID, userID)
+}
+
+// BookEntries returns the entries of a book on all of the user's shelves,
+// in shelf order.
+func (r *ShelfRepo) BookEntries(ctx context.Context, userID string, bookID int64) ([]models.ShelfEntry, error) {
+	return r.listEntries(ctx,
+		`SELECT `+shelfEntryColumns+` FROM shelf_books sb JOIN shelves s ON s.id = sb.shelf_id
+		 WHERE sb.book_id = $1 AND s.user_id = $2
+		 ORDER BY s.position, s.id`,
+		bookID, userID)
+}
+
+// AddBook puts a book at the end of a shelf of the user, or updates its
+// note if it is already there (a nil note keeps it). Putting a book on a
+// status shelf takes it off the other status shelves. Returns nil if the
+// shelf or the book does not exist.
+func (r *ShelfRepo) AddBook(ctx context.Context, userID string, shelfID, bookID int64, note *string) (*models.ShelfEntry, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("begin add to shelf: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	var status string
+	err = tx.QueryRow(ctx,
+		`SELECT COALESCE(status, '') FROM shelves WHERE id = $1 AND user_id = $2 FOR UPDATE`,
+		shelfID, userID,
+	).Scan(&status)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("lock shelf: %w", err)
+	}
+
+	e := models.ShelfEntry{ShelfID: shelfID, BookID: bookID}
+	err = tx.QueryRow(ctx,
+		`INSERT INTO shelf_books (shelf_id, book_id, position, note)
+		 SELECT $1, b.id, (SELECT COALESCE(MAX(position) + 1, 0) FROM shelf_books WHERE shelf_id = $1), COALESCE($3, '')
+		 FROM books b WHERE b.id = $2
+		 ON CONFLICT (shelf_id, book_id) DO UPDATE SET note = COALESCE($3, shelf_books.note)
+		 RETURNING position, note, added_at`,
+		shelfID, bookID, note,
+	).Scan(&e.Position, &e.Note, &e.AddedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("add book to shelf: %w", err)
+	}
+
+	if status != "" {
+		if _, err := tx.Exec(ctx,
+			`DELETE FROM shelf_books WHERE book_id = $1 AND shelf_id IN (
+				SELECT id FROM shelves WHERE user_id = $2 AND status IS NOT NULL AND id <> $3)`,
+			bookID, userID, shelfID,
+		); err != nil {
+			return nil, fmt.Errorf("clear reading status: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("commit add to shelf: %w", err)
+	}
+	return &e, nil
+}
+
+// RemoveBook takes a book off a shelf of the user. Returns false if it was
+// not there.
+func (r *ShelfRepo) RemoveBook(ctx context.Context, userID string, shelfID, bookID int64) (bool, error) {
+	tag, err := r.pool.Exec(ctx,
+		`DELETE FROM shelf_books sb USING shelves s
+		 WHERE sb.shelf_id = s.id AND sb.shelf_id = $1 AND sb.book_id = $2 AND s.user_id = $3`,
+		shelfID, bookID, userID,
+	)
+	if err != nil {
+		return false, fmt.Errorf("remove book from shelf: %w", err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// ReorderBooks renumbers the books on a shelf of the user: the listed book
+// IDs first, in the given order, then the others in their current order.
+func (r *ShelfRepo) ReorderBooks(ctx context.Context, userID string, shelfID int64, bookIDs []int64) error {
+	_, err := r.pool.Exec(ctx,
+		`UPDATE shelf_books sb SET position = o.n - 1
+		 FROM (
+			SELECT b.book_id, ROW_NUMBER() OVER (
+				ORDER BY COALESCE(array_position($3::bigint[], b.book_id), 2147483647), b.position, b.added_at, b.book_id) AS n
+			FROM shelf_books b JOIN shelves s ON s.id = b.shelf_id
+			WHERE b.shelf_id = $1 AND s.user_id = $2
+		 ) o
+		 WHERE sb.shelf_id = $1 AND sb.book_id = o.book_id AND sb.position <> o.n - 1`,
+		shelfID, userID, bookIDs,
+	)
+	if err != nil {
+		return fmt.Errorf("reorder shelf books: %w", err)
+	}
+	return nil
+}
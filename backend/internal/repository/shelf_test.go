@@ -0,0 +1,204 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grom-alex/homelib/backend/internal/models"
+)
+
+var shelfRowColumns = []string{"id", "user_id", "status", "name", "note", "position", "count", "created_at", "updated_at"}
+
+func TestShelfRepo_List_CreatesStatusShelves(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	repo := NewShelfRepo(mock)
+	now := time.Now()
+
+	mock.ExpectExec("INSERT INTO shelves .+ ON CONFLICT \\(user_id, status\\) DO NOTHING").
+		WithArgs("user-1", models.ShelfStatuses, []string{"Хочу прочитать", "Читаю", "Прочитано", "Брошено"}).
+		WillReturnResult(pgxmock.NewResult("INSERT", 4))
+	mock.ExpectQuery("SELECT .+ FROM shelves s WHERE s.user_id = \\$1 ORDER BY s.position").
+		WithArgs("user-1").
+		WillReturnRows(pgxmock.NewRows(shelfRowColumns).
+			AddRow(int64(1), "user-1", "want_to_read", "Хочу прочитать", "", 0, int64(2), now, now).
+			AddRow(int64(5), "user-1", "", "Лето 2026", "", 4, int64(0), now, now))
+
+	shelves, err := repo.List(context.Background(), "user-1")
+	require.NoError(t, err)
+	require.Len(t, shelves, 2)
+	assert.Equal(t, models.ShelfWantToRead, shelves[0].Status)
+	assert.Equal(t, 2, shelves[0].BookCount)
+	assert.Empty(t, shelves[1].Status)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestShelfRepo_Get_NotFound(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	mock.ExpectQuery("SELECT .+ FROM shelves s WHERE s.id = \\$1 AND s.user_id = \\$2").
+		WithArgs(int64(5), "user-1").
+		WillReturnError(pgx.ErrNoRows)
+
+	s, err := NewShelfRepo(mock).Get(context.Background(), "user-1", 5)
+	require.NoError(t, err)
+	assert.Nil(t, s)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestShelfRepo_BookStatus(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	repo := NewShelfRepo(mock)
+	mock.ExpectQuery("SELECT s.status FROM shelf_books sb JOIN shelves s .+ s.status IS NOT NULL").
+		WithArgs(int64(42), "user-1").
+		WillReturnRows(pgxmock.NewRows([]string{"status"}).AddRow("abandoned"))
+	mock.ExpectQuery("SELECT s.status FROM shelf_books sb").
+		WithArgs(int64(43), "user-1").
+		WillReturnError(pgx.ErrNoRows)
+
+	status, err := repo.BookStatus(context.Background(), "user-1", 42)
+	require.NoError(t, err)
+	assert.Equal(t, models.ShelfAbandoned, status)
+
+	status, err = repo.BookStatus(context.Background(), "user-1", 43)
+	require.NoError(t, err)
+	assert.Empty(t, status)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestShelfRepo_Create(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	now := time.Now()
+	mock.ExpectQuery("INSERT INTO shelves \\(user_id, name, note, position\\)").
+		WithArgs("user-1", "Лето 2026", "").
+		WillReturnRows(pgxmock.NewRows([]string{"id", "position", "created_at", "updated_at"}).AddRow(int64(5), 4, now, now))
+
+	s := &models.Shelf{UserID: "user-1", Name: "Лето 2026"}
+	require.NoError(t, NewShelfRepo(mock).Create(context.Background(), s))
+	assert.Equal(t, int64(5), s.ID)
+	assert.Equal(t, 4, s.Position)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestShelfRepo_Delete_KeepsStatusShelves(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	mock.ExpectExec("DELETE FROM shelves WHERE id = \\$1 AND user_id = \\$2 AND status IS NULL").
+		WithArgs(int64(1), "user-1").
+		WillReturnResult(pgxmock.NewResult("DELETE", 0))
+
+	deleted, err := NewShelfRepo(mock).Delete(context.Background(), "user-1", 1)
+	require.NoError(t, err)
+	assert.False(t, deleted)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestShelfRepo_AddBook_StatusShelf(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	now := time.Now()
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT COALESCE\\(status, ''\\) FROM shelves WHERE id = \\$1 AND user_id = \\$2 FOR UPDATE").
+		WithArgs(int64(3), "user-1").
+		WillReturnRows(pgxmock.NewRows([]string{"status"}).AddRow("finished"))
+	mock.ExpectQuery("INSERT INTO shelf_books .+ ON CONFLICT \\(shelf_id, book_id\\) DO UPDATE").
+		WithArgs(int64(3), int64(42), (*string)(nil)).
+		WillReturnRows(pgxmock.NewRows([]string{"position", "note", "added_at"}).AddRow(7, "", now))
+	mock.ExpectExec("DELETE FROM shelf_books WHERE book_id = \\$1 AND shelf_id IN .+ status IS NOT NULL").
+		WithArgs(int64(42), "user-1", int64(3)).
+		WillReturnResult(pgxmock.NewResult("DELETE", 1))
+	mock.ExpectCommit()
+	mock.ExpectRollback()
+
+	e, err := NewShelfRepo(mock).AddBook(context.Background(), "user-1", 3, 42, nil)
+	require.NoError(t, err)
+	require.NotNil(t, e)
+	assert.Equal(t, 7, e.Position)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestShelfRepo_AddBook_CustomShelf(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	note := "в отпуск"
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT COALESCE\\(status, ''\\) FROM shelves").
+		WithArgs(int64(5), "user-1").
+		WillReturnRows(pgxmock.NewRows([]string{"status"}).AddRow(""))
+	mock.ExpectQuery("INSERT INTO shelf_books").
+		WithArgs(int64(5), int64(42), &note).
+		WillReturnRows(pgxmock.NewRows([]string{"position", "note", "added_at"}).AddRow(0, note, time.Now()))
+	mock.ExpectCommit()
+	mock.ExpectRollback()
+
+	e, err := NewShelfRepo(mock).AddBook(context.Background(), "user-1", 5, 42, &note)
+	require.NoError(t, err)
+	require.NotNil(t, e)
+	assert.Equal(t, note, e.Note)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestShelfRepo_AddBook_MissingShelfOrBook(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT COALESCE\\(status, ''\\) FROM shelves").
+		WithArgs(int64(5), "user-2").
+		WillReturnError(pgx.ErrNoRows)
+	mock.ExpectRollback()
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT COALESCE\\(status, ''\\) FROM shelves").
+		WithArgs(int64(5), "user-1").
+		WillReturnRows(pgxmock.NewRows([]string{"status"}).AddRow(""))
+	mock.ExpectQuery("INSERT INTO shelf_books").
+		WithArgs(int64(5), int64(404), (*string)(nil)).
+		WillReturnError(pgx.ErrNoRows)
+	mock.ExpectRollback()
+
+	repo := NewShelfRepo(mock)
+	e, err := repo.AddBook(context.Background(), "user-2", 5, 42, nil)
+	require.NoError(t, err)
+	assert.Nil(t, e)
+
+	e, err = repo.AddBook(context.Background(), "user-1", 5, 404, nil)
+	require.NoError(t, err)
+	assert.Nil(t, e)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestShelfRepo_ReorderBooks(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	mock.ExpectExec("UPDATE shelf_books sb SET position = o.n - 1 .+array_position\\(\\$3::bigint\\[\\], b.book_id\\)").
+		WithArgs(int64(5), "user-1", []int64{43, 42}).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 2))
+
+	require.NoError(t, NewShelfRepo(mock).ReorderBooks(context.Background(), "user-1", 5, []int64{43, 42}))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,119 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/grom-alex/homelib/backend/internal/models"
+)
+
+// SuggestRepo serves autocomplete lookups over titles, authors and series.
+// Matching relies on the pg_trgm GIN indexes on books.title, authors.name
+// and series.name, which serve both ILIKE '%…%' and the % similarity operator.
+type SuggestRepo struct {
+	pool Pool
+}
+
+func NewSuggestRepo(pool Pool) *SuggestRepo {
+	return &SuggestRepo{pool: pool}
+}
+
+// escapeLike escapes LIKE wildcards so user input matches literally.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// suggestScore ranks prefix matches above any similarity-only match.
+const suggestScore = `(CASE WHEN lower(%[1]s) LIKE $%[2]d THEN 1 ELSE 0 END + similarity(%[1]s, $%[3]d))::float8`
+
+// buildSuggestQuery returns a UNION ALL query with one branch per term and
+// type. Each branch is limited separately, so the result holds at most
+// len(terms)*len(types)*limit rows. Rows carry the index of the matching term.
+func buildSuggestQuery(terms, types []string, limit int, excludeGenreIDs []int) (string, []any) {
+	var args []any
+	param := func(v any) int {
+		args = append(args, v)
+		return len(args)
+	}
+
+	limitIdx := param(limit)
+	excludeIdx := 0
+	if len(excludeGenreIDs) > 0 {
+		excludeIdx = param(excludeGenreIDs)
+	}
+
+	var branches []string
+	for ti, term := range terms {
+		t := param(term)
+		prefix := param(escapeLike(strings.ToLower(term)) + "%")
+		contains := param("%" + escapeLike(term) + "%")
+
+		for _, typ := range types {
+			switch typ {
+			case models.SuggestTitle:
+				parental := ""
+				if excludeIdx > 0 {
+					parental = fmt.Sprintf(
+						" AND NOT EXISTS (SELECT 1 FROM book_genres bg2 WHERE bg2.book_id = b.id AND bg2.genre_id = ANY($%d::int[]))",
+						excludeIdx)
+				}
+				branches = append(branches, fmt.Sprintf(
+					`(SELECT 'title', m.id, m.title,
+						(SELECT a.name FROM book_authors ba JOIN authors a ON a.id = ba.author_id
+						 WHERE ba.book_id = m.id ORDER BY a.name_sort LIMIT 1),
+						m.score, %[1]d
+					 FROM (SELECT b.id, b.title, %[2]s AS score FROM books b
+						WHERE NOT b.is_deleted AND (b.title ILIKE $%[3]d OR b.title %% $%[4]d)%[5]s
+						ORDER BY score DESC, b.title LIMIT $%[6]d) m)`,
+					ti, fmt.Sprintf(suggestScore, "b.title", prefix, t), contains, t, parental, limitIdx))
+			case models.SuggestAuthor:
+				branches = append(branches, fmt.Sprintf(
+					`(SELECT 'author', a.id, a.name, NULL::text, %[2]s AS score, %[1]d FROM authors a
+					 WHERE a.name ILIKE $%[3]d OR a.name %% $%[4]d
+					 ORDER BY score DESC, a.name LIMIT $%[5]d)`,
+					ti, fmt.Sprintf(suggestScore, "a.name", prefix, t), contains, t, limitIdx))
+			case models.SuggestSeries:
+				branches = append(branches, fmt.Sprintf(
+					`(SELECT 'series', s.id, s.name, NULL::text, %[2]s AS score, %[1]d FROM series s
+					 WHERE s.name ILIKE $%[3]d OR s.name %% $%[4]d
+					 ORDER BY score DESC, s.name LIMIT $%[5]d)`,
+					ti, fmt.Sprintf(suggestScore, "s.name", prefix, t), contains, t, limitIdx))
+			}
+		}
+	}
+
+	return strings.Join(branches, "\nUNION ALL\n"), args
+}
+
+// Suggest looks up suggestions for each term. The result has one slice per
+// term, in the order the terms were given; each slice holds up to limit
+// matches per type, unsorted across types.
+func (r *SuggestRepo) Suggest(ctx context.Context, terms, types []string, limit int, excludeGenreIDs []int) ([][]models.Suggestion, error) {
+	result := make([][]models.Suggestion, len(terms))
+	if len(terms) == 0 || len(types) == 0 {
+		return result, nil
+	}
+
+	query, args := buildSuggestQuery(terms, types, limit, excludeGenreIDs)
+	rows, err := r.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("suggest: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var s models.Suggestion
+		var term int
+		if err := rows.Scan(&s.Type, &s.ID, &s.Text, &s.Detail, &s.Score, &term); err != nil {
+			return nil, fmt.Errorf("scan suggestion: %w", err)
+		}
+		if term >= 0 && term < len(result) {
+			result[term] = append(result[term], s)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate suggestions: %w", err)
+	}
+	return result, nil
+}
@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grom-alex/homelib/backend/internal/models"
+)
+
+func TestEscapeLike(t *testing.T) {
+	assert.Equal(t, `100\% a\_b c\\d`, escapeLike(`100% a_b c\d`))
+}
+
+func TestBuildSuggestQuery(t *testing.T) {
+	query, args := buildSuggestQuery(
+		[]string{"Мас", "vfc"},
+		[]string{models.SuggestTitle, models.SuggestAuthor, models.SuggestSeries},
+		10, []int{3},
+	)
+
+	assert.Equal(t, 5, strings.Count(query, "UNION ALL"))
+	assert.Equal(t, []any{10, []int{3}, "Мас", "мас%", "%Мас%", "vfc", "vfc%", "%vfc%"}, args)
+
+	// Books: deleted and restricted genres are excluded
+	assert.Contains(t, query, "NOT b.is_deleted AND (b.title ILIKE $5 OR b.title % $3)")
+	assert.Contains(t, query, "bg2.genre_id = ANY($2::int[])")
+	assert.Contains(t, query, "lower(b.title) LIKE $4")
+	// Second term uses its own parameters and index
+	assert.Contains(t, query, "a.name ILIKE $8 OR a.name % $6")
+	assert.Contains(t, query, "s.name, NULL::text, (CASE WHEN lower(s.name) LIKE $7")
+	assert.Contains(t, query, "LIMIT $1")
+}
+
+func TestBuildSuggestQuery_NoParental(t *testing.T) {
+	query, args := buildSuggestQuery([]string{"abc"}, []string{models.SuggestTitle}, 5, nil)
+
+	assert.NotContains(t, query, "UNION ALL")
+	assert.NotContains(t, query, "bg2")
+	assert.Equal(t, []any{5, "abc", "abc%", "%abc%"}, args)
+}
+
+func TestSuggestRepo_Suggest(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	repo := NewSuggestRepo(mock)
+	author := "Булгаков Михаил"
+
+	mock.ExpectQuery("SELECT 'title'").
+		WithArgs(10, "vfcnth", "vfcnth%", "%vfcnth%", "мастер", "мастер%", "%мастер%").
+		WillReturnRows(pgxmock.NewRows([]string{"type", "id", "text", "detail", "score", "term"}).
+			AddRow("title", int64(1), "Мастер и Маргарита", &author, 1.4, 1).
+			AddRow("author", int64(2), "Мастерс Эдгар", (*string)(nil), 1.2, 1))
+
+	res, err := repo.Suggest(context.Background(), []string{"vfcnth", "мастер"}, []string{"title", "author"}, 10, nil)
+	require.NoError(t, err)
+	require.Len(t, res, 2)
+	assert.Empty(t, res[0])
+	require.Len(t, res[1], 2)
+	assert.Equal(t, "Мастер и Маргарита", res[1][0].Text)
+	assert.Equal(t, &author, res[1][0].Detail)
+	assert.Nil(t, res[1][1].Detail)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSuggestRepo_Suggest_Error(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	repo := NewSuggestRepo(mock)
+	mock.ExpectQuery("SELECT").WillReturnError(fmt.Errorf("connection refused"))
+
+	_, err = repo.Suggest(context.Background(), []string{"abc"}, []string{"series"}, 10, nil)
+	assert.ErrorContains(t, err, "suggest")
+}
+
+func TestSuggestRepo_Suggest_NoTypes(t *testing.T) {
+	repo := NewSuggestRepo(nil)
+
+	res, err := repo.Suggest(context.Background(), []string{"abc"}, nil, 10, nil)
+	require.NoError(t, err)
+	assert.Len(t, res, 1)
+}
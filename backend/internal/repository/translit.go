@@ -0,0 +1,103 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/grom-alex/homelib/backend/internal/translit"
+)
+
+// minTranslitKeyLen is the shortest search key matched against translit
+// columns; shorter keys match too much and cannot use trigram indexes.
+const minTranslitKeyLen = 3
+
+// nameMatchCondition returns a condition matching column by substring
+// (case-insensitive) or, when the query yields a usable search key, its
+// translit column by key substring. Placeholders are numbered from argIdx;
+// the next free index is returned.
+func nameMatchCondition(column, translitColumn, query string, argIdx int) (string, []any, int) {
+	key := translit.Key(query)
+	if len(key) < minTranslitKeyLen {
+		return fmt.Sprintf("%s ILIKE '%%' || $%d || '%%'", column, argIdx), []any{query}, argIdx + 1
+	}
+	return fmt.Sprintf("(%s ILIKE '%%' || $%d || '%%' OR %s LIKE '%%' || $%d || '%%')",
+			column, argIdx, translitColumn, argIdx+1),
+		[]any{query, key}, argIdx + 2
+}
+
+// translitTarget describes a column whose search key is stored alongside it.
+type translitTarget struct {
+	table, source, key string
+}
+
+var translitTargets = []translitTarget{
+	{"authors", "name", "name_translit"},
+	{"series", "name", "name_translit"},
+	{"books", "title", "title_translit"},
+}
+
+// translitBatchSize is the number of rows updated per backfill statement.
+const translitBatchSize = 5000
+
+// BackfillTranslit fills empty search key columns of authors, series and
+// books, e.g. after migration 009 on an existing catalog. Rows imported
+// later get their keys on upsert. Returns the number of updated rows.
+func BackfillTranslit(ctx context.Context, pool Pool) (int, error) {
+	total := 0
+	for _, t := range translitTargets {
+		n, err := backfillTranslitTable(ctx, pool, t)
+		total += n
+		if err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
+
+func backfillTranslitTable(ctx context.Context, pool Pool, t translitTarget) (int, error) {
+	selectSQL := fmt.Sprintf(
+		`SELECT id, %s FROM %s WHERE %s = '' AND id > $1 ORDER BY id LIMIT $2`,
+		t.source, t.table, t.key)
+	updateSQL := fmt.Sprintf(
+		`UPDATE %[1]s SET %[2]s = u.key FROM unnest($1::bigint[], $2::text[]) AS u(id, key) WHERE %[1]s.id = u.id`,
+		t.table, t.key)
+
+	updated := 0
+	var lastID int64
+	for {
+		rows, err := pool.Query(ctx, selectSQL, lastID, translitBatchSize)
+		if err != nil {
+			return updated, fmt.Errorf("select %s for translit: %w", t.table, err)
+		}
+		var ids []int64
+		var keys []string
+		fetched := 0
+		for rows.Next() {
+			var id int64
+			var source string
+			if err := rows.Scan(&id, &source); err != nil {
+				rows.Close()
+				return updated, fmt.Errorf("scan %s for translit: %w", t.table, err)
+			}
+			lastID = id
+			fetched++
+			if key := translit.Key(source); key != "" {
+				ids = append(ids, id)
+				keys = append(keys, key)
+			}
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return updated, fmt.Errorf("iterate %s for translit: %w", t.table, err)
+		}
+		if len(ids) > 0 {
+			if _, err := pool.Exec(ctx, updateSQL, ids, keys); err != nil {
+				return updated, fmt.Errorf("update %s translit: %w", t.table, err)
+			}
+			updated += len(ids)
+		}
+		if fetched < translitBatchSize {
+			return updated, nil
+		}
+	}
+}
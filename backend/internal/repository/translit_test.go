@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNameMatchCondition(t *testing.T) {
+	cond, args, next := nameMatchCondition("a.name", "a.name_translit", "Lukyanenko", 3)
+
+	assert.Equal(t, "(a.name ILIKE '%' || $3 || '%' OR a.name_translit LIKE '%' || $4 || '%')", cond)
+	assert.Equal(t, []any{"Lukyanenko", "lukianenko"}, args)
+	assert.Equal(t, 5, next)
+}
+
+func TestNameMatchCondition_ShortKey(t *testing.T) {
+	cond, args, next := nameMatchCondition("s.name", "s.name_translit", "Ли", 1)
+
+	assert.Equal(t, "s.name ILIKE '%' || $1 || '%'", cond)
+	assert.Equal(t, []any{"Ли"}, args)
+	assert.Equal(t, 2, next)
+}
+
+func TestBackfillTranslit(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	mock.ExpectQuery("SELECT id, name FROM authors WHERE name_translit = ''").
+		WithArgs(int64(0), translitBatchSize).
+		WillReturnRows(pgxmock.NewRows([]string{"id", "name"}).
+			AddRow(int64(1), "Стругацкий Аркадий").
+			AddRow(int64(2), "—"))
+	mock.ExpectExec("UPDATE authors SET name_translit = u.key").
+		WithArgs([]int64{1}, []string{"strugacki arkadi"}).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mock.ExpectQuery("SELECT id, name FROM series WHERE name_translit = ''").
+		WithArgs(int64(0), translitBatchSize).
+		WillReturnRows(pgxmock.NewRows([]string{"id", "name"}))
+	mock.ExpectQuery("SELECT id, title FROM books WHERE title_translit = ''").
+		WithArgs(int64(0), translitBatchSize).
+		WillReturnRows(pgxmock.NewRows([]string{"id", "title"}).AddRow(int64(7), "Пикник на обочине"))
+	mock.ExpectExec("UPDATE books SET title_translit = u.key").
+		WithArgs([]int64{7}, []string{"piknik na obochine"}).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+
+	n, err := BackfillTranslit(context.Background(), mock)
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestBackfillTranslit_Error(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	mock.ExpectQuery("SELECT id, name FROM authors").WillReturnError(fmt.Errorf("connection refused"))
+
+	_, err = BackfillTranslit(context.Background(), mock)
+	assert.ErrorContains(t, err, "select authors for translit")
+}
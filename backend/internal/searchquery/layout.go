@@ -0,0 +1,131 @@
+package searchquery
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Standard ЙЦУКЕН and QWERTY layouts, key by key.
+const (
+	latinKeys    = "`qwertyuiop[]asdfghjkl;'zxcvbnm,./"
+	cyrillicKeys = "ёйцукенгшщзхъфывапролджэячсмитьбю."
+)
+
+var latinToCyrillic, cyrillicToLatin = buildLayoutMaps()
+
+func buildLayoutMaps() (map[rune]rune, map[rune]rune) {
+	lat := []rune(latinKeys)
+	cyr := []rune(cyrillicKeys)
+	l2c := make(map[rune]rune, len(lat))
+	c2l := make(map[rune]rune, len(cyr))
+	for i := range lat {
+		l2c[lat[i]] = cyr[i]
+		if _, ok := c2l[cyr[i]]; !ok {
+			c2l[cyr[i]] = lat[i]
+		}
+	}
+	return l2c, c2l
+}
+
+// SwitchLayout retypes s as if it had been entered with the other keyboard
+// layout, e.g. "ьфыеук" → "vfcnth" and "vfcnth" → "мастер". The direction is
+// chosen by the script of the first letter; characters without a key mapping
+// are kept as is. Returns s unchanged if it contains no letters.
+func SwitchLayout(s string) string {
+	var table map[rune]rune
+	for _, r := range s {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		if unicode.Is(unicode.Cyrillic, r) {
+			table = cyrillicToLatin
+		} else {
+			table = latinToCyrillic
+		}
+		break
+	}
+	if table == nil {
+		return s
+	}
+
+	out := make([]rune, 0, len(s))
+	for _, r := range s {
+		lower := unicode.ToLower(r)
+		mapped, ok := table[lower]
+		if !ok {
+			out = append(out, r)
+			continue
+		}
+		if lower != r {
+			mapped = unicode.ToUpper(mapped)
+		}
+		out = append(out, mapped)
+	}
+	return string(out)
+}
+
+// phonetic maps Latin letter sequences to Cyrillic, longest first.
+var phonetic = []struct{ lat, cyr string }{
+	{"shch", "щ"}, {"sch", "щ"},
+	{"zh", "ж"}, {"kh", "х"}, {"ch", "ч"}, {"sh", "ш"}, {"ts", "ц"},
+	{"yu", "ю"}, {"ya", "я"}, {"yo", "ё"},
+	{"a", "а"}, {"b", "б"}, {"c", "к"}, {"d", "д"}, {"e", "е"}, {"f", "ф"},
+	{"g", "г"}, {"h", "х"}, {"i", "и"}, {"j", "й"}, {"k", "к"}, {"l", "л"},
+	{"m", "м"}, {"n", "н"}, {"o", "о"}, {"p", "п"}, {"q", "к"}, {"r", "р"},
+	{"s", "с"}, {"t", "т"}, {"u", "у"}, {"v", "в"}, {"w", "в"}, {"x", "кс"},
+	{"y", "ы"}, {"z", "з"},
+}
+
+// phoneticCyrillic spells lowercase Latin text with Cyrillic letters
+// ("master" → "мастер"). Other characters are kept as is.
+func phoneticCyrillic(s string) string {
+	var out []rune
+	rest := []rune(s)
+outer:
+	for len(rest) > 0 {
+		for _, p := range phonetic {
+			n := len([]rune(p.lat))
+			if len(rest) >= n && string(rest[:n]) == p.lat {
+				out = append(out, []rune(p.cyr)...)
+				rest = rest[n:]
+				continue outer
+			}
+		}
+		out = append(out, rest[0])
+		rest = rest[1:]
+	}
+	return string(out)
+}
+
+func hasLatin(s string) bool {
+	for _, r := range s {
+		if unicode.Is(unicode.Latin, r) {
+			return true
+		}
+	}
+	return false
+}
+
+// LayoutAlternatives returns spellings of q worth searching for when it may
+// have been typed with the wrong keyboard layout: the layout-switched text,
+// and, if that is Latin, its phonetic Cyrillic reading. So "vfcnth" yields
+// "мастер", and "ьфыеук" (English "master" typed on ЙЦУКЕН) yields "master"
+// and "мастер". q itself and duplicates are excluded.
+func LayoutAlternatives(q string) []string {
+	lower := strings.ToLower(q)
+	seen := map[string]bool{lower: true}
+	var alts []string
+	add := func(s string) {
+		if !seen[s] {
+			seen[s] = true
+			alts = append(alts, s)
+		}
+	}
+
+	switched := SwitchLayout(lower)
+	add(switched)
+	if hasLatin(switched) {
+		add(phoneticCyrillic(switched))
+	}
+	return alts
+}
@@ -0,0 +1,40 @@
+package searchquery
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSwitchLayout(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"vfcnth", "мастер"},
+		{"ьфыеук", "master"},
+		{"Vfcnth b Vfhufhbnf", "Мастер и Маргарита"},
+		{"ghbrk.xtybz", "приключения"},
+		{"'ktrnhjybrf", "электроника"},
+		{"`krf", "ёлка"},
+		{"руддщ", "hello"},
+		{"123", "123"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, SwitchLayout(tt.in), tt.in)
+	}
+}
+
+func TestLayoutAlternatives(t *testing.T) {
+	assert.Equal(t, []string{"master", "мастер"}, LayoutAlternatives("ьфыеук"))
+	assert.Equal(t, []string{"мастер"}, LayoutAlternatives("vfcnth"))
+	assert.Equal(t, []string{"ьфыеук"}, LayoutAlternatives("Master"))
+	assert.Empty(t, LayoutAlternatives("2024"))
+}
+
+func TestPhoneticCyrillic(t *testing.T) {
+	assert.Equal(t, "мастер", phoneticCyrillic("master"))
+	assert.Equal(t, "щукин", phoneticCyrillic("shchukin"))
+	assert.Equal(t, "жуков", phoneticCyrillic("zhukov"))
+	assert.Equal(t, "чехов", phoneticCyrillic("chekhov"))
+}
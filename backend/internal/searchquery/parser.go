@@ -0,0 +1,441 @@
+package searchquery
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/grom-alex/homelib/backend/internal/models"
+)
+
+// Parse turns an advanced search string such as
+//
+//	author:стругацкие series:"Полдень" year:>1970 lang:ru -genre:sf_horror пикник
+//
+// into filter fields and a free-text remainder ("пикник").
+//
+// Syntax:
+//   - field:value or field:"quoted value" (\" and \\ escapes inside quotes)
+//   - list fields (genre, lang, format, tag) accept comma-separated values
+//   - numeric and date fields accept N, =N, >N, >=N, <N, <=N and N..M (open ends allowed)
+//   - a leading '-' negates genre qualifiers and free-text words
+//   - a word before ':' that is not a field name is free text
+//
+// Free-text words and quoted phrases are kept in websearch_to_tsquery syntax.
+func Parse(input string) (models.BookFilter, string, error) {
+	var f models.BookFilter
+	text, err := parseInto(input, &f)
+	if err != nil {
+		return models.BookFilter{}, "", err
+	}
+	return f, text, nil
+}
+
+// Apply parses f.Query and merges its qualifiers into f, leaving only the
+// free-text remainder in f.Query. Qualifiers override scalar fields already
+// set from query parameters and extend list fields.
+func Apply(f *models.BookFilter) error {
+	text, err := parseInto(f.Query, f)
+	if err != nil {
+		return err
+	}
+	f.Query = text
+	return nil
+}
+
+type parser struct {
+	in   []rune
+	pos  int
+	f    *models.BookFilter
+	seen map[fieldKind]bool
+	text []string
+}
+
+func parseInto(input string, f *models.BookFilter) (string, error) {
+	p := &parser{in: []rune(input), f: f, seen: make(map[fieldKind]bool)}
+	if err := p.run(); err != nil {
+		return "", err
+	}
+	return strings.Join(p.text, " "), nil
+}
+
+func (p *parser) run() error {
+	for {
+		p.skipSpace()
+		if p.eof() {
+			return nil
+		}
+		start := p.pos
+
+		negated := false
+		if p.in[p.pos] == '-' && p.pos+1 < len(p.in) && !unicode.IsSpace(p.in[p.pos+1]) {
+			negated = true
+			p.pos++
+		}
+
+		// Quoted free-text phrase
+		if p.in[p.pos] == '"' {
+			phrase, err := p.readQuoted()
+			if err != nil {
+				return err
+			}
+			if phrase = strings.TrimSpace(phrase); phrase != "" {
+				p.addText(negated, `"`+strings.ReplaceAll(phrase, `"`, "")+`"`)
+			}
+			continue
+		}
+
+		word := p.readWord()
+		if word == "" {
+			// Stray ':' without a field name: skip it
+			p.pos++
+			continue
+		}
+
+		if p.eof() || p.in[p.pos] != ':' {
+			if word != "-" {
+				p.addText(negated, word)
+			}
+			continue
+		}
+
+		name := strings.ToLower(word)
+		kind, ok := fields[name]
+		p.pos++ // ':'
+		if !ok {
+			// Not a qualifier: a word followed by a colon, as in titles
+			// like "Пикник на обочине: повесть"
+			if word != "-" {
+				p.addText(negated, word)
+			}
+			continue
+		}
+
+		valuePos := p.pos
+		var value string
+		if !p.eof() && p.in[p.pos] == '"' {
+			v, err := p.readQuoted()
+			if err != nil {
+				return err
+			}
+			value = v
+		} else {
+			value = p.readValue()
+		}
+		value = strings.TrimSpace(value)
+		if value == "" {
+			return &Error{Pos: valuePos, Msg: fmt.Sprintf("empty value for field %q", name)}
+		}
+
+		if err := p.apply(kind, name, value, negated, start, valuePos); err != nil {
+			return err
+		}
+	}
+}
+
+func (p *parser) eof() bool {
+	return p.pos >= len(p.in)
+}
+
+func (p *parser) skipSpace() {
+	for !p.eof() && unicode.IsSpace(p.in[p.pos]) {
+		p.pos++
+	}
+}
+
+func (p *parser) addText(negated bool, s string) {
+	if negated {
+		s = "-" + s
+	}
+	p.text = append(p.text, s)
+}
+
+// readWord reads a bare word up to whitespace, ':' or '"'.
+func (p *parser) readWord() string {
+	start := p.pos
+	for !p.eof() {
+		r := p.in[p.pos]
+		if unicode.IsSpace(r) || r == ':' || r == '"' {
+			break
+		}
+		p.pos++
+	}
+	return string(p.in[start:p.pos])
+}
+
+// readValue reads an unquoted qualifier value up to whitespace.
+func (p *parser) readValue() string {
+	start := p.pos
+	for !p.eof() && !unicode.IsSpace(p.in[p.pos]) {
+		p.pos++
+	}
+	return string(p.in[start:p.pos])
+}
+
+// readQuoted reads a double-quoted string starting at the current position.
+func (p *parser) readQuoted() (string, error) {
+	start := p.pos
+	p.pos++ // opening quote
+	var b strings.Builder
+	for !p.eof() {
+		r := p.in[p.pos]
+		switch {
+		case r == '\\' && p.pos+1 < len(p.in) && (p.in[p.pos+1] == '"' || p.in[p.pos+1] == '\\'):
+			b.WriteRune(p.in[p.pos+1])
+			p.pos += 2
+		case r == '"':
+			p.pos++
+			return b.String(), nil
+		default:
+			b.WriteRune(r)
+			p.pos++
+		}
+	}
+	return "", &Error{Pos: start, Msg: "unterminated quoted string"}
+}
+
+func (p *parser) apply(kind fieldKind, name, value string, negated bool, fieldPos, valuePos int) error {
+	if negated && kind != kindGenre {
+		return &Error{Pos: fieldPos, Msg: fmt.Sprintf("negation is not supported for field %q", name)}
+	}
+
+	// Scalar fields may appear only once per query
+	switch kind {
+	case kindAuthor, kindSeries, kindSeriesType, kindYear, kindRate, kindSize, kindAdded:
+		if p.seen[kind] {
+			return &Error{Pos: fieldPos, Msg: fmt.Sprintf("field %q is specified more than once", name)}
+		}
+		p.seen[kind] = true
+	}
+
+	invalid := func(what string) error {
+		return &Error{Pos: valuePos, Msg: fmt.Sprintf("invalid %s value %q", what, value)}
+	}
+
+	f := p.f
+	switch kind {
+	case kindAuthor:
+		f.AuthorName = value
+	case kindSeries:
+		f.SeriesName = value
+	case kindSeriesType:
+		switch strings.ToLower(value) {
+		case "a", "author", "авторская":
+			f.SeriesType = "a"
+		case "p", "publisher", "издательская":
+			f.SeriesType = "p"
+		default:
+			return &Error{Pos: valuePos, Msg: fmt.Sprintf("invalid series type %q (expected a or p)", value)}
+		}
+	case kindGenre:
+		codes, err := splitList(value, valuePos)
+		if err != nil {
+			return err
+		}
+		if negated {
+			f.NotGenreCodes = append(f.NotGenreCodes, codes...)
+		} else {
+			f.GenreCodes = append(f.GenreCodes, codes...)
+		}
+	case kindLang:
+		langs, err := splitList(strings.ToLower(value), valuePos)
+		if err != nil {
+			return err
+		}
+		f.Langs = append(f.Langs, langs...)
+	case kindFormat:
+		formats, err := splitList(strings.ToLower(value), valuePos)
+		if err != nil {
+			return err
+		}
+		for i := range formats {
+			formats[i] = strings.TrimPrefix(formats[i], ".")
+		}
+		f.Formats = append(f.Formats, formats...)
+	case kindKeyword:
+		keywords, err := splitList(value, valuePos)
+		if err != nil {
+			return err
+		}
+		f.Keywords = append(f.Keywords, keywords...)
+	case kindYear:
+		from, to, err := parseRange(value, parseInt, succInt, predInt, lessInt)
+		if err != nil {
+			return invalid("year")
+		}
+		f.YearFrom, f.YearTo = from, to
+	case kindRate:
+		from, to, err := parseRange(value, parseInt, succInt, predInt, lessInt)
+		if err != nil {
+			return invalid("rate")
+		}
+		f.LibRateMin, f.LibRateMax = from, to
+	case kindSize:
+		from, to, err := parseRange(value, parseSize, succInt64, predInt64, lessInt64)
+		if err != nil {
+			return invalid("size")
+		}
+		f.SizeMin, f.SizeMax = from, to
+	case kindAdded:
+		from, to, err := parseRange(value, parseDate, succDate, predDate, lessDate)
+		if err != nil {
+			return invalid("date")
+		}
+		f.DateAddedFrom, f.DateAddedTo = from, to
+	}
+	return nil
+}
+
+// splitList splits a comma-separated value, rejecting empty items.
+func splitList(value string, pos int) ([]string, error) {
+	parts := strings.Split(value, ",")
+	out := make([]string, 0, len(parts))
+	for _, part := range parts {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			return nil, &Error{Pos: pos, Msg: fmt.Sprintf("empty item in list %q", value)}
+		}
+		out = append(out, part)
+	}
+	return out, nil
+}
+
+// parseRange interprets comparison and range syntax. parse returns the
+// inclusive interval a single value denotes (e.g. a whole year for "2024"
+// in a date field); succ and pred step just past either end of it.
+func parseRange[T any](
+	v string,
+	parse func(string) (lo, hi T, err error),
+	succ, pred func(T) T,
+	less func(a, b T) bool,
+) (from, to *T, err error) {
+	switch {
+	case strings.HasPrefix(v, ">="):
+		lo, _, err := parse(v[2:])
+		if err != nil {
+			return nil, nil, err
+		}
+		return &lo, nil, nil
+	case strings.HasPrefix(v, "<="):
+		_, hi, err := parse(v[2:])
+		if err != nil {
+			return nil, nil, err
+		}
+		return nil, &hi, nil
+	case strings.HasPrefix(v, ">"):
+		_, hi, err := parse(v[1:])
+		if err != nil {
+			return nil, nil, err
+		}
+		next := succ(hi)
+		return &next, nil, nil
+	case strings.HasPrefix(v, "<"):
+		lo, _, err := parse(v[1:])
+		if err != nil {
+			return nil, nil, err
+		}
+		prev := pred(lo)
+		return nil, &prev, nil
+	case strings.Contains(v, ".."):
+		left, right, _ := strings.Cut(v, "..")
+		if left == "" && right == "" {
+			return nil, nil, fmt.Errorf("empty range")
+		}
+		if left != "" {
+			lo, _, err := parse(left)
+			if err != nil {
+				return nil, nil, err
+			}
+			from = &lo
+		}
+		if right != "" {
+			_, hi, err := parse(right)
+			if err != nil {
+				return nil, nil, err
+			}
+			to = &hi
+		}
+		if from != nil && to != nil && less(*to, *from) {
+			return nil, nil, fmt.Errorf("range start is after range end")
+		}
+		return from, to, nil
+	default:
+		lo, hi, err := parse(strings.TrimPrefix(v, "="))
+		if err != nil {
+			return nil, nil, err
+		}
+		return &lo, &hi, nil
+	}
+}
+
+func parseInt(s string) (int, int, error) {
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 {
+		return 0, 0, fmt.Errorf("invalid number %q", s)
+	}
+	return n, n, nil
+}
+
+func succInt(n int) int       { return n + 1 }
+func predInt(n int) int       { return n - 1 }
+func lessInt(a, b int) bool   { return a < b }
+func succInt64(n int64) int64 { return n + 1 }
+func predInt64(n int64) int64 { return n - 1 }
+func lessInt64(a, b int64) bool {
+	return a < b
+}
+
+// sizeUnits maps size suffixes to byte multipliers.
+var sizeUnits = []struct {
+	suffix string
+	mult   float64
+}{
+	{"kb", 1 << 10}, {"mb", 1 << 20}, {"gb", 1 << 30},
+	{"k", 1 << 10}, {"m", 1 << 20}, {"g", 1 << 30},
+	{"b", 1},
+}
+
+// parseSize parses a byte size with an optional k/kb/m/mb/g/gb suffix.
+func parseSize(s string) (int64, int64, error) {
+	s = strings.ToLower(s)
+	mult := 1.0
+	for _, u := range sizeUnits {
+		if strings.HasSuffix(s, u.suffix) {
+			s = strings.TrimSuffix(s, u.suffix)
+			mult = u.mult
+			break
+		}
+	}
+	// ParseFloat also accepts "inf" and "nan"; those and sizes past int64
+	// are rejected along with negative ones
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsNaN(v) || v < 0 || v*mult >= math.MaxInt64 {
+		return 0, 0, fmt.Errorf("invalid size %q", s)
+	}
+	n := int64(v * mult)
+	return n, n, nil
+}
+
+// parseDate parses YYYY, YYYY-MM or YYYY-MM-DD into the first and last day of
+// the denoted period.
+func parseDate(s string) (time.Time, time.Time, error) {
+	if t, err := time.Parse("2006-01-02", s); err == nil {
+		return t, t, nil
+	}
+	if t, err := time.Parse("2006-01", s); err == nil {
+		return t, t.AddDate(0, 1, -1), nil
+	}
+	if len(s) == 4 {
+		if t, err := time.Parse("2006", s); err == nil {
+			return t, t.AddDate(1, 0, -1), nil
+		}
+	}
+	return time.Time{}, time.Time{}, fmt.Errorf("invalid date %q", s)
+}
+
+func succDate(t time.Time) time.Time { return t.AddDate(0, 0, 1) }
+func predDate(t time.Time) time.Time { return t.AddDate(0, 0, -1) }
+func lessDate(a, b time.Time) bool   { return a.Before(b) }
@@ -0,0 +1,234 @@
+package searchquery
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grom-alex/homelib/backend/internal/models"
+)
+
+func intPtr(v int) *int       { return &v }
+func int64Ptr(v int64) *int64 { return &v }
+func datePtr(s string) *time.Time {
+	t, _ := time.Parse("2006-01-02", s)
+	return &t
+}
+
+func TestParse_Example(t *testing.T) {
+	f, text, err := Parse(`author:стругацкие series:"Полдень" year:>1970 lang:ru -genre:sf_horror`)
+	require.NoError(t, err)
+
+	assert.Equal(t, "стругацкие", f.AuthorName)
+	assert.Equal(t, "Полдень", f.SeriesName)
+	assert.Equal(t, intPtr(1971), f.YearFrom)
+	assert.Nil(t, f.YearTo)
+	assert.Equal(t, []string{"ru"}, f.Langs)
+	assert.Equal(t, []string{"sf_horror"}, f.NotGenreCodes)
+	assert.Empty(t, f.GenreCodes)
+	assert.Equal(t, "", text)
+}
+
+func TestParse_Valid(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  models.BookFilter
+		text  string
+	}{
+		{name: "empty", input: "", want: models.BookFilter{}},
+		{name: "whitespace only", input: " \t\n ", want: models.BookFilter{}},
+		{name: "plain words", input: "пикник  на обочине", text: "пикник на обочине"},
+		{name: "quoted phrase", input: `"пикник на обочине" зона`, text: `"пикник на обочине" зона`},
+		{name: "negated word", input: "зона -сталкер", text: "зона -сталкер"},
+		{name: "negated phrase", input: `-"трудно быть"`, text: `-"трудно быть"`},
+		{name: "empty phrase dropped", input: `"" слово`, text: "слово"},
+		{name: "lone dash dropped", input: "a - b", text: "a b"},
+		{name: "stray colon dropped", input: ":слово", text: "слово"},
+		{name: "colon inside value", input: "series:a:b", want: models.BookFilter{SeriesName: "a:b"}},
+		{name: "title with colon", input: "Пикник на обочине: повесть", text: "Пикник на обочине повесть"},
+		{name: "unknown field is text", input: "пикник foo:bar", text: "пикник foo bar"},
+		{name: "negated word with colon", input: "-x:1 author:Лем", want: models.BookFilter{AuthorName: "Лем"}, text: "-x 1"},
+		{
+			name:  "mixed qualifiers and text",
+			input: "пикник author:стругацкие обочине",
+			want:  models.BookFilter{AuthorName: "стругацкие"},
+			text:  "пикник обочине",
+		},
+		{name: "field name case-insensitive", input: "AUTHOR:Лем", want: models.BookFilter{AuthorName: "Лем"}},
+		{name: "russian alias", input: "автор:Лем серия:Космос", want: models.BookFilter{AuthorName: "Лем", SeriesName: "Космос"}},
+		{name: "quoted value", input: `series:"Мир Полудня"`, want: models.BookFilter{SeriesName: "Мир Полудня"}},
+		{name: "quoted value trimmed", input: `author:"  Лем "`, want: models.BookFilter{AuthorName: "Лем"}},
+		{name: "escaped quote", input: `series:"Сага \"Кольцо\""`, want: models.BookFilter{SeriesName: `Сага "Кольцо"`}},
+		{name: "escaped backslash", input: `series:"a\\b"`, want: models.BookFilter{SeriesName: `a\b`}},
+		{name: "unknown escape kept", input: `series:"a\nb"`, want: models.BookFilter{SeriesName: `a\nb`}},
+		{name: "series type a", input: "stype:a", want: models.BookFilter{SeriesType: "a"}},
+		{name: "series type publisher", input: "seriestype:Publisher", want: models.BookFilter{SeriesType: "p"}},
+		{name: "series type russian", input: "stype:авторская", want: models.BookFilter{SeriesType: "a"}},
+		{name: "genre list", input: "genre:sf,sf_social", want: models.BookFilter{GenreCodes: []string{"sf", "sf_social"}}},
+		{name: "genre repeated", input: "genre:sf жанр:det", want: models.BookFilter{GenreCodes: []string{"sf", "det"}}},
+		{
+			name:  "genre include and exclude",
+			input: "genre:sf -genre:sf_horror,sf_cyberpunk",
+			want:  models.BookFilter{GenreCodes: []string{"sf"}, NotGenreCodes: []string{"sf_horror", "sf_cyberpunk"}},
+		},
+		{name: "lang lowercased", input: "lang:RU,En", want: models.BookFilter{Langs: []string{"ru", "en"}}},
+		{name: "lang list spaces trimmed", input: `lang:"ru, uk"`, want: models.BookFilter{Langs: []string{"ru", "uk"}}},
+		{name: "format dot stripped", input: "format:.FB2,epub", want: models.BookFilter{Formats: []string{"fb2", "epub"}}},
+		{name: "tag keeps case", input: "tag:Космос,AI", want: models.BookFilter{Keywords: []string{"Космос", "AI"}}},
+		{name: "keyword alias", input: "keyword:магия", want: models.BookFilter{Keywords: []string{"магия"}}},
+		{name: "year exact", input: "year:1965", want: models.BookFilter{YearFrom: intPtr(1965), YearTo: intPtr(1965)}},
+		{name: "year equals", input: "year:=1965", want: models.BookFilter{YearFrom: intPtr(1965), YearTo: intPtr(1965)}},
+		{name: "year greater", input: "year:>1970", want: models.BookFilter{YearFrom: intPtr(1971)}},
+		{name: "year greater or equal", input: "year:>=1970", want: models.BookFilter{YearFrom: intPtr(1970)}},
+		{name: "year less", input: "year:<1970", want: models.BookFilter{YearTo: intPtr(1969)}},
+		{name: "year less or equal", input: "год:<=1970", want: models.BookFilter{YearTo: intPtr(1970)}},
+		{name: "year range", input: "year:1960..1980", want: models.BookFilter{YearFrom: intPtr(1960), YearTo: intPtr(1980)}},
+		{name: "year open start", input: "year:..1980", want: models.BookFilter{YearTo: intPtr(1980)}},
+		{name: "year open end", input: "year:1960..", want: models.BookFilter{YearFrom: intPtr(1960)}},
+		{name: "year single-point range", input: "year:1960..1960", want: models.BookFilter{YearFrom: intPtr(1960), YearTo: intPtr(1960)}},
+		{name: "rate", input: "rate:>=4", want: models.BookFilter{LibRateMin: intPtr(4)}},
+		{name: "rating alias range", input: "rating:3..5", want: models.BookFilter{LibRateMin: intPtr(3), LibRateMax: intPtr(5)}},
+		{name: "size bytes", input: "size:>1000", want: models.BookFilter{SizeMin: int64Ptr(1001)}},
+		{name: "size kb", input: "size:<=512kb", want: models.BookFilter{SizeMax: int64Ptr(512 << 10)}},
+		{name: "size k", input: "size:<=512K", want: models.BookFilter{SizeMax: int64Ptr(512 << 10)}},
+		{name: "size mb range", input: "size:1mb..2m", want: models.BookFilter{SizeMin: int64Ptr(1 << 20), SizeMax: int64Ptr(2 << 20)}},
+		{name: "size fractional", input: "size:>=1.5MB", want: models.BookFilter{SizeMin: int64Ptr(3 << 19)}},
+		{name: "size gb", input: "size:<1g", want: models.BookFilter{SizeMax: int64Ptr(1<<30 - 1)}},
+		{name: "size b suffix", input: "size:100b", want: models.BookFilter{SizeMin: int64Ptr(100), SizeMax: int64Ptr(100)}},
+		{
+			name:  "added day",
+			input: "added:2024-03-15",
+			want:  models.BookFilter{DateAddedFrom: datePtr("2024-03-15"), DateAddedTo: datePtr("2024-03-15")},
+		},
+		{
+			name:  "added month",
+			input: "added:2024-02",
+			want:  models.BookFilter{DateAddedFrom: datePtr("2024-02-01"), DateAddedTo: datePtr("2024-02-29")},
+		},
+		{
+			name:  "added year",
+			input: "добавлена:2023",
+			want:  models.BookFilter{DateAddedFrom: datePtr("2023-01-01"), DateAddedTo: datePtr("2023-12-31")},
+		},
+		{name: "added after month", input: "added:>2024-01", want: models.BookFilter{DateAddedFrom: datePtr("2024-02-01")}},
+		{name: "added before year", input: "added:<2024", want: models.BookFilter{DateAddedTo: datePtr("2023-12-31")}},
+		{name: "added since", input: "added:>=2024-01", want: models.BookFilter{DateAddedFrom: datePtr("2024-01-01")}},
+		{name: "added until", input: "added:<=2024-01", want: models.BookFilter{DateAddedTo: datePtr("2024-01-31")}},
+		{
+			name:  "added range",
+			input: "added:2023-06..2024",
+			want:  models.BookFilter{DateAddedFrom: datePtr("2023-06-01"), DateAddedTo: datePtr("2024-12-31")},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, text, err := Parse(tt.input)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, f)
+			assert.Equal(t, tt.text, text)
+		})
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		pos     int
+		message string
+	}{
+		{name: "empty value", input: "author:", pos: 7, message: `empty value for field "author"`},
+		{name: "empty value before space", input: "author: Лем", pos: 7, message: "empty value"},
+		{name: "empty quoted value", input: `series:""`, pos: 7, message: "empty value"},
+		{name: "unterminated quoted value", input: `series:"Полдень`, pos: 7, message: "unterminated quoted string"},
+		{name: "unterminated phrase", input: `слово "пикник`, pos: 6, message: "unterminated quoted string"},
+		{name: "duplicate author", input: "author:a author:b", pos: 9, message: `field "author" is specified more than once`},
+		{name: "duplicate via alias", input: "year:1 год:2", pos: 7, message: "more than once"},
+		{name: "negated author", input: "-author:Лем", pos: 0, message: `negation is not supported for field "author"`},
+		{name: "negated lang", input: "-lang:en", pos: 0, message: "negation is not supported"},
+		{name: "bad series type", input: "stype:x", pos: 6, message: "invalid series type"},
+		{name: "empty list item", input: "genre:sf,,det", pos: 6, message: "empty item in list"},
+		{name: "trailing comma", input: "lang:ru,", pos: 5, message: "empty item in list"},
+		{name: "bad year", input: "year:abc", pos: 5, message: `invalid year value "abc"`},
+		{name: "negative year", input: "year:-5", pos: 5, message: "invalid year value"},
+		{name: "bad year operator operand", input: "year:>", pos: 5, message: "invalid year value"},
+		{name: "empty range", input: "year:..", pos: 5, message: "invalid year value"},
+		{name: "reversed range", input: "year:1980..1960", pos: 5, message: "invalid year value"},
+		{name: "bad range end", input: "year:1960..x", pos: 5, message: "invalid year value"},
+		{name: "bad rate", input: "rate:high", pos: 5, message: "invalid rate value"},
+		{name: "bad size unit", input: "size:10tb", pos: 5, message: "invalid size value"},
+		{name: "reversed size", input: "size:2mb..1mb", pos: 5, message: "invalid size value"},
+		{name: "negative size", input: "size:-1mb", pos: 5, message: "invalid size value"},
+		{name: "infinite size", input: "size:inf", pos: 5, message: "invalid size value"},
+		{name: "infinite size with unit", input: "size:<+Infkb", pos: 5, message: "invalid size value"},
+		{name: "nan size", input: "size:NaN", pos: 5, message: "invalid size value"},
+		{name: "nan size range", input: "size:1..nanmb", pos: 5, message: "invalid size value"},
+		{name: "size past int64", input: "size:1e30gb", pos: 5, message: "invalid size value"},
+		{name: "bad date", input: "added:2024-13", pos: 6, message: "invalid date value"},
+		{name: "bad date format", input: "added:15.03.2024", pos: 6, message: "invalid date value"},
+		{name: "reversed dates", input: "added:2024..2023", pos: 6, message: "invalid date value"},
+		{name: "position counts runes", input: "пикник год:x", pos: 11, message: "invalid year value"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, text, err := Parse(tt.input)
+			require.Error(t, err)
+
+			var qerr *Error
+			require.True(t, errors.As(err, &qerr))
+			assert.Equal(t, tt.pos, qerr.Pos)
+			assert.Contains(t, qerr.Msg, tt.message)
+			assert.Contains(t, err.Error(), "position")
+			assert.Equal(t, models.BookFilter{}, f)
+			assert.Empty(t, text)
+		})
+	}
+}
+
+func TestApply_MergesIntoFilter(t *testing.T) {
+	f := models.BookFilter{
+		Query:      "lang:ru genre:sf пикник",
+		Langs:      []string{"uk"},
+		AuthorName: "Лем",
+		Limit:      50,
+	}
+
+	require.NoError(t, Apply(&f))
+
+	assert.Equal(t, "пикник", f.Query)
+	assert.Equal(t, []string{"uk", "ru"}, f.Langs)
+	assert.Equal(t, []string{"sf"}, f.GenreCodes)
+	assert.Equal(t, "Лем", f.AuthorName)
+	assert.Equal(t, 50, f.Limit)
+}
+
+func TestApply_OverridesScalar(t *testing.T) {
+	f := models.BookFilter{Query: "author:Стругацкие", AuthorName: "Лем"}
+
+	require.NoError(t, Apply(&f))
+
+	assert.Equal(t, "Стругацкие", f.AuthorName)
+	assert.Empty(t, f.Query)
+}
+
+func TestApply_ErrorLeavesQuery(t *testing.T) {
+	f := models.BookFilter{Query: "year:abc"}
+
+	err := Apply(&f)
+
+	require.Error(t, err)
+	assert.Equal(t, "year:abc", f.Query)
+}
+
+func TestKnownFieldsAreRegistered(t *testing.T) {
+	for _, name := range KnownFields {
+		_, ok := fields[name]
+		assert.True(t, ok, name)
+	}
+}
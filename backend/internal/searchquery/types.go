@@ -0,0 +1,65 @@
+package searchquery
+
+import "fmt"
+
+// Error describes a malformed search query.
+// Pos is the 0-based rune offset of the offending token in the input.
+type Error struct {
+	Pos int
+	Msg string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("position %d: %s", e.Pos, e.Msg)
+}
+
+// fieldKind determines how a qualifier value is interpreted.
+type fieldKind int
+
+const (
+	kindAuthor fieldKind = iota
+	kindSeries
+	kindSeriesType
+	kindGenre
+	kindLang
+	kindFormat
+	kindKeyword
+	kindYear
+	kindRate
+	kindSize
+	kindAdded
+)
+
+// fields maps qualifier names (including Russian aliases) to their kind.
+var fields = map[string]fieldKind{
+	"author":     kindAuthor,
+	"автор":      kindAuthor,
+	"series":     kindSeries,
+	"серия":      kindSeries,
+	"seriestype": kindSeriesType,
+	"stype":      kindSeriesType,
+	"genre":      kindGenre,
+	"жанр":       kindGenre,
+	"lang":       kindLang,
+	"язык":       kindLang,
+	"format":     kindFormat,
+	"формат":     kindFormat,
+	"tag":        kindKeyword,
+	"keyword":    kindKeyword,
+	"тег":        kindKeyword,
+	"year":       kindYear,
+	"год":        kindYear,
+	"rate":       kindRate,
+	"rating":     kindRate,
+	"рейтинг":    kindRate,
+	"size":       kindSize,
+	"размер":     kindSize,
+	"added":      kindAdded,
+	"добавлена":  kindAdded,
+}
+
+// KnownFields lists the canonical qualifier names for help.
+var KnownFields = []string{
+	"author", "series", "seriestype", "genre", "lang", "format",
+	"tag", "year", "rate", "size", "added",
+}
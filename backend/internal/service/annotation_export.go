@@ -0,0 +1,392 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/grom-alex/homelib/backend/internal/bookfile"
+	"github.com/grom-alex/homelib/backend/internal/models"
+	"github.com/grom-alex/homelib/backend/internal/repository"
+)
+
+// maxExportFileNameLen limits the length (in runes) of exported file names
+// without the extension.
+const maxExportFileNameLen = 100
+
+// annotationLister abstracts the annotation repo dependency for testing.
+type annotationLister interface {
+	ListByBook(ctx context.Context, userID string, bookID int64, f models.AnnotationFilter) ([]models.Annotation, error)
+	ListByUser(ctx context.Context, userID string) ([]models.Annotation, error)
+}
+
+// exportBookProvider abstracts the book repo dependency for testing.
+type exportBookProvider interface {
+	GetByID(ctx context.Context, id int64) (*models.BookDetail, error)
+	IsBookRestricted(ctx context.Context, bookID int64, restrictedGenreIDs []int) (bool, error)
+}
+
+// bookContentProvider abstracts the reader service dependency for testing.
+type bookContentProvider interface {
+	GetBookContent(ctx context.Context, bookID int64) (*bookfile.BookContent, error)
+}
+
+// ExportFile is a single rendered export document.
+type ExportFile struct {
+	Filename string
+	Data     []byte
+}
+
+// AnnotationExportService renders a user's annotations as Markdown and JSON.
+type AnnotationExportService struct {
+	annotations annotationLister
+	books       exportBookProvider
+	contents    bookContentProvider
+	now         func() time.Time
+	logger      *slog.Logger
+}
+
+func NewAnnotationExportService(annotationRepo *repository.AnnotationRepo, bookRepo *repository.BookRepo, readerSvc *ReaderService) *AnnotationExportService {
+	return &AnnotationExportService{
+		annotations: annotationRepo,
+		books:       bookRepo,
+		contents:    readerSvc,
+		now:         time.Now,
+		logger:      slog.Default(),
+	}
+}
+
+// BookMarkdown renders the annotations of one book as a Markdown document.
+// Returns ErrBookNotFound if the book does not exist.
+func (s *AnnotationExportService) BookMarkdown(ctx context.Context, userID string, bookID int64) (*ExportFile, error) {
+	book, err := s.loadBook(ctx, bookID)
+	if err != nil {
+		return nil, err
+	}
+	items, err := s.annotations.ListByBook(ctx, userID, bookID, models.AnnotationFilter{})
+	if err != nil {
+		return nil, err
+	}
+
+	eb := s.withChapters(ctx, book, items)
+	return &ExportFile{
+		Filename: exportFileName(book) + ".md",
+		Data:     renderAnnotationsMarkdown(&eb.AnnotatedBook, eb.order, s.now()),
+	}, nil
+}
+
+// Dump returns all annotations of the user grouped by book. Books hidden by
+// the parental filter are left out.
+func (s *AnnotationExportService) Dump(ctx context.Context, userID string, restrictedGenreIDs []int) (*models.AnnotationExport, error) {
+	books, err := s.collect(ctx, userID, restrictedGenreIDs)
+	if err != nil {
+		return nil, err
+	}
+	result := &models.AnnotationExport{ExportedAt: s.now(), Books: make([]models.AnnotatedBook, len(books))}
+	for i := range books {
+		result.Books[i] = books[i].AnnotatedBook
+	}
+	return result, nil
+}
+
+// WriteMarkdownZip writes a ZIP archive with one Markdown file per annotated
+// book to w. Books hidden by the parental filter are left out.
+func (s *AnnotationExportService) WriteMarkdownZip(ctx context.Context, userID string, restrictedGenreIDs []int, w io.Writer) error {
+	books, err := s.collect(ctx, userID, restrictedGenreIDs)
+	if err != nil {
+		return err
+	}
+
+	now := s.now()
+	used := make(map[string]bool, len(books))
+	zw := zip.NewWriter(w)
+	for i := range books {
+		ab := &books[i].AnnotatedBook
+		name := exportFileName(&ab.Book)
+		if used[strings.ToLower(name)] {
+			name = fmt.Sprintf("%s (%d)", name, ab.Book.ID)
+		}
+		used[strings.ToLower(name)] = true
+
+		fw, err := zw.CreateHeader(&zip.FileHeader{
+			Name:     name + ".md",
+			Method:   zip.Deflate,
+			Modified: now,
+		})
+		if err != nil {
+			return fmt.Errorf("create zip entry: %w", err)
+		}
+		if _, err := fw.Write(renderAnnotationsMarkdown(ab, books[i].order, now)); err != nil {
+			return fmt.Errorf("write zip entry: %w", err)
+		}
+	}
+	return zw.Close()
+}
+
+// exportBook is an annotated book together with its chapter reading order
+// (nil if the book file is unavailable).
+type exportBook struct {
+	models.AnnotatedBook
+	order []string
+}
+
+// collect groups all annotations of the user by book and loads book details.
+// Restriction checks fail closed.
+func (s *AnnotationExportService) collect(ctx context.Context, userID string, restrictedGenreIDs []int) ([]exportBook, error) {
+	items, err := s.annotations.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []exportBook
+	for start := 0; start < len(items); {
+		end := start + 1
+		for end < len(items) && items[end].BookID == items[start].BookID {
+			end++
+		}
+		group := items[start:end]
+		start = end
+
+		bookID := group[0].BookID
+		if len(restrictedGenreIDs) > 0 {
+			restricted, err := s.books.IsBookRestricted(ctx, bookID, restrictedGenreIDs)
+			if err != nil {
+				return nil, fmt.Errorf("check book restriction: %w", err)
+			}
+			if restricted {
+				continue
+			}
+		}
+
+		book, err := s.loadBook(ctx, bookID)
+		if errors.Is(err, ErrBookNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, s.withChapters(ctx, book, group))
+	}
+	return result, nil
+}
+
+func (s *AnnotationExportService) loadBook(ctx context.Context, bookID int64) (*models.BookDetail, error) {
+	book, err := s.books.GetByID(ctx, bookID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrBookNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return book, nil
+}
+
+// withChapters attaches chapter titles and order from the book's TOC. The
+// export does not fail if the book file is unavailable; chapters are then
+// named by ID.
+func (s *AnnotationExportService) withChapters(ctx context.Context, book *models.BookDetail, items []models.Annotation) exportBook {
+	eb := exportBook{AnnotatedBook: models.AnnotatedBook{Book: *book, Annotations: items}}
+	content := s.bookContent(ctx, book.ID)
+	if content == nil {
+		return eb
+	}
+	eb.order = content.ChapterIDs
+	eb.Chapters = make(map[string]string)
+	for _, a := range items {
+		if _, ok := eb.Chapters[a.ChapterID]; ok {
+			continue
+		}
+		for _, entry := range content.TOC {
+			if entry.ID == a.ChapterID {
+				eb.Chapters[a.ChapterID] = entry.Title
+				break
+			}
+		}
+	}
+	return eb
+}
+
+func (s *AnnotationExportService) bookContent(ctx context.Context, bookID int64) *bookfile.BookContent {
+	content, err := s.contents.GetBookContent(ctx, bookID)
+	if err != nil {
+		s.logger.Warn("annotation export: book content unavailable", "book_id", bookID, "error", err)
+		return nil
+	}
+	return content
+}
+
+// annotationColorNames are the Russian names of highlight colours.
+var annotationColorNames = map[string]string{
+	"yellow": "жёлтый",
+	"green":  "зелёный",
+	"blue":   "синий",
+	"pink":   "розовый",
+	"purple": "фиолетовый",
+	"orange": "оранжевый",
+}
+
+var annotationTypeNames = map[string]string{
+	models.AnnotationBookmark:  "Закладка",
+	models.AnnotationHighlight: "Выделение",
+	models.AnnotationNote:      "Заметка",
+}
+
+// renderAnnotationsMarkdown renders an Obsidian-friendly document: YAML
+// front matter with book metadata, then annotations under chapter headings
+// in reading order (chapters missing from order go last, by ID).
+func renderAnnotationsMarkdown(ab *models.AnnotatedBook, order []string, exportedAt time.Time) []byte {
+	var b bytes.Buffer
+	book := &ab.Book
+
+	b.WriteString("---\n")
+	fmt.Fprintf(&b, "title: %s\n", strconv.Quote(book.Title))
+	if len(book.Authors) > 0 {
+		b.WriteString("authors:\n")
+		for _, a := range book.Authors {
+			fmt.Fprintf(&b, "  - %s\n", strconv.Quote(a.Name))
+		}
+	}
+	if book.Series != nil {
+		fmt.Fprintf(&b, "series: %s\n", strconv.Quote(book.Series.Name))
+		if book.Series.Num != nil {
+			fmt.Fprintf(&b, "series_number: %d\n", *book.Series.Num)
+		}
+	}
+	if book.Year != nil {
+		fmt.Fprintf(&b, "year: %d\n", *book.Year)
+	}
+	if book.Lang != "" {
+		fmt.Fprintf(&b, "lang: %s\n", strconv.Quote(book.Lang))
+	}
+	if len(book.Genres) > 0 {
+		b.WriteString("genres:\n")
+		for _, g := range book.Genres {
+			fmt.Fprintf(&b, "  - %s\n", strconv.Quote(g.Name))
+		}
+	}
+	fmt.Fprintf(&b, "homelib_id: %d\n", book.ID)
+	fmt.Fprintf(&b, "exported: %s\n", exportedAt.Format(time.DateOnly))
+	b.WriteString("tags:\n  - homelib\n")
+	b.WriteString("---\n\n")
+
+	fmt.Fprintf(&b, "# %s\n\n", book.Title)
+	if len(book.Authors) > 0 {
+		names := make([]string, len(book.Authors))
+		for i, a := range book.Authors {
+			names[i] = a.Name
+		}
+		fmt.Fprintf(&b, "**Автор:** %s\n", strings.Join(names, ", "))
+	}
+	if book.Series != nil {
+		if book.Series.Num != nil {
+			fmt.Fprintf(&b, "**Серия:** %s #%d\n", book.Series.Name, *book.Series.Num)
+		} else {
+			fmt.Fprintf(&b, "**Серия:** %s\n", book.Series.Name)
+		}
+	}
+
+	if len(ab.Annotations) == 0 {
+		b.WriteString("\n*Нет заметок.*\n")
+		return b.Bytes()
+	}
+
+	items := slices.Clone(ab.Annotations)
+	rank := make(map[string]int, len(order))
+	for i, id := range order {
+		rank[id] = i
+	}
+	slices.SortStableFunc(items, func(x, y models.Annotation) int {
+		rx, okx := rank[x.ChapterID]
+		ry, oky := rank[y.ChapterID]
+		switch {
+		case okx && oky && rx != ry:
+			return rx - ry
+		case okx != oky:
+			if okx {
+				return -1
+			}
+			return 1
+		case x.ChapterID != y.ChapterID:
+			return strings.Compare(x.ChapterID, y.ChapterID)
+		}
+		if c := x.Locator.Start.Compare(y.Locator.Start); c != 0 {
+			return c
+		}
+		return int(x.ID - y.ID)
+	})
+
+	chapter := ""
+	for i, a := range items {
+		if i == 0 || a.ChapterID != chapter {
+			chapter = a.ChapterID
+			title := ab.Chapters[chapter]
+			if title == "" {
+				title = chapter
+			}
+			fmt.Fprintf(&b, "\n## %s\n", singleLine(title))
+		}
+		b.WriteString("\n")
+		writeAnnotationMarkdown(&b, &a)
+	}
+	return b.Bytes()
+}
+
+func writeAnnotationMarkdown(b *bytes.Buffer, a *models.Annotation) {
+	if a.Locator.Quote != "" {
+		for _, line := range strings.Split(strings.TrimSpace(a.Locator.Quote), "\n") {
+			b.WriteString(strings.TrimRight("> "+strings.TrimSpace(line), " "))
+			b.WriteString("\n")
+		}
+		b.WriteString("\n")
+	}
+	if a.Note != "" {
+		b.WriteString(strings.TrimSpace(a.Note))
+		b.WriteString("\n\n")
+	}
+
+	meta := annotationTypeNames[a.Type]
+	if name, ok := annotationColorNames[a.Color]; ok {
+		meta += ", " + name
+	}
+	fmt.Fprintf(b, "*%s · %s* ^homelib-%d\n", meta, a.CreatedAt.Format(time.DateOnly), a.ID)
+}
+
+// singleLine collapses whitespace so a title fits on one heading line.
+func singleLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// exportFileName builds "Author - Title" without characters that are invalid
+// in file names or Obsidian note names.
+func exportFileName(book *models.BookDetail) string {
+	name := book.Title
+	if len(book.Authors) > 0 {
+		name = book.Authors[0].Name + " - " + name
+	}
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) || strings.ContainsRune(`/\:*?"<>|#^[]`, r) {
+			return ' '
+		}
+		return r
+	}, name)
+	name = strings.Trim(singleLine(name), " .")
+	if utf8.RuneCountInString(name) > maxExportFileNameLen {
+		name = strings.TrimRight(string([]rune(name)[:maxExportFileNameLen]), " .")
+	}
+	if name == "" {
+		name = fmt.Sprintf("book-%d", book.ID)
+	}
+	return name
+}
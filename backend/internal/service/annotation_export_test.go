@@ -0,0 +1,268 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grom-alex/homelib/backend/internal/bookfile"
+	"github.com/grom-alex/homelib/backend/internal/models"
+)
+
+type fakeAnnotationLister struct {
+	items []models.Annotation
+	err   error
+}
+
+func (f *fakeAnnotationLister) ListByBook(_ context.Context, _ string, bookID int64, _ models.AnnotationFilter) ([]models.Annotation, error) {
+	var out []models.Annotation
+	for _, a := range f.items {
+		if a.BookID == bookID {
+			out = append(out, a)
+		}
+	}
+	return out, f.err
+}
+
+func (f *fakeAnnotationLister) ListByUser(_ context.Context, _ string) ([]models.Annotation, error) {
+	return f.items, f.err
+}
+
+type fakeExportBooks struct {
+	books      map[int64]*models.BookDetail
+	restricted map[int64]bool
+	restrErr   error
+}
+
+func (f *fakeExportBooks) GetByID(_ context.Context, id int64) (*models.BookDetail, error) {
+	if b, ok := f.books[id]; ok {
+		return b, nil
+	}
+	return nil, fmt.Errorf("get book %d: %w", id, pgx.ErrNoRows)
+}
+
+func (f *fakeExportBooks) IsBookRestricted(_ context.Context, bookID int64, _ []int) (bool, error) {
+	return f.restricted[bookID], f.restrErr
+}
+
+type fakeBookContents map[int64]*bookfile.BookContent
+
+func (f fakeBookContents) GetBookContent(_ context.Context, bookID int64) (*bookfile.BookContent, error) {
+	if c, ok := f[bookID]; ok {
+		return c, nil
+	}
+	return nil, ErrBookNotFound
+}
+
+var exportTime = time.Date(2026, 3, 15, 12, 0, 0, 0, time.UTC)
+
+func newTestExportService(items []models.Annotation) *AnnotationExportService {
+	year := 1967
+	num := 2
+	return &AnnotationExportService{
+		annotations: &fakeAnnotationLister{items: items},
+		books: &fakeExportBooks{books: map[int64]*models.BookDetail{
+			1: {
+				ID: 1, Title: "Мастер и Маргарита", Lang: "ru", Year: &year,
+				Authors: []models.BookAuthorRef{{ID: 10, Name: "Булгаков Михаил"}},
+				Genres:  []models.BookGenreDetailRef{{ID: 3, Code: "prose_classic", Name: "Классическая проза"}},
+				Series:  &models.BookSeriesDetailRef{ID: 5, Name: "Избранное", Num: &num},
+			},
+			2: {ID: 2, Title: "Пикник на обочине: повесть", Authors: []models.BookAuthorRef{{Name: "Стругацкий Аркадий"}}},
+		}},
+		contents: fakeBookContents{
+			1: {
+				TOC: []bookfile.TOCEntry{
+					{ID: "ch1", Title: "Глава 1. Никогда не разговаривайте с неизвестными", Level: 0},
+					{ID: "ch2", Title: "Глава 2. Понтий Пилат", Level: 0},
+				},
+				ChapterIDs: []string{"ch1", "ch2"},
+			},
+		},
+		now:    func() time.Time { return exportTime },
+		logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}
+}
+
+func sampleExportAnnotations() []models.Annotation {
+	created := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	return []models.Annotation{
+		{ID: 1, BookID: 1, Type: models.AnnotationHighlight, ChapterID: "ch2",
+			Locator: models.TextLocator{Start: models.Locator{Offset: 5}, End: models.Locator{Offset: 30}, Quote: "В белом плаще\nс кровавым подбоем"},
+			Color:   "yellow", CreatedAt: created},
+		{ID: 2, BookID: 1, Type: models.AnnotationNote, ChapterID: "ch1",
+			Locator: models.TextLocator{Start: models.Locator{Offset: 100}, End: models.Locator{Offset: 120}, Quote: "Аннушка уже разлила масло"},
+			Note:    "Ключевая фраза", CreatedAt: created},
+		{ID: 3, BookID: 1, Type: models.AnnotationBookmark, ChapterID: "ch1",
+			Locator: models.TextLocator{Start: models.Locator{Offset: 10}, End: models.Locator{Offset: 10}}, CreatedAt: created},
+		{ID: 4, BookID: 2, Type: models.AnnotationHighlight, ChapterID: "ch7",
+			Locator: models.TextLocator{Start: models.Locator{Offset: 1}, End: models.Locator{Offset: 9}, Quote: "Счастье для всех"}, CreatedAt: created},
+	}
+}
+
+func TestAnnotationExport_BookMarkdown(t *testing.T) {
+	svc := newTestExportService(sampleExportAnnotations())
+
+	file, err := svc.BookMarkdown(context.Background(), "user-1", 1)
+	require.NoError(t, err)
+	assert.Equal(t, "Булгаков Михаил - Мастер и Маргарита.md", file.Filename)
+
+	md := string(file.Data)
+	assert.True(t, strings.HasPrefix(md, "---\ntitle: \"Мастер и Маргарита\"\n"), md)
+	assert.Contains(t, md, "authors:\n  - \"Булгаков Михаил\"\n")
+	assert.Contains(t, md, "series: \"Избранное\"\nseries_number: 2\n")
+	assert.Contains(t, md, "year: 1967\n")
+	assert.Contains(t, md, "genres:\n  - \"Классическая проза\"\n")
+	assert.Contains(t, md, "homelib_id: 1\nexported: 2026-03-15\n")
+	assert.Contains(t, md, "# Мастер и Маргарита\n")
+	assert.Contains(t, md, "**Серия:** Избранное #2\n")
+	assert.Contains(t, md, "> В белом плаще\n> с кровавым подбоем\n")
+	assert.Contains(t, md, "*Выделение, жёлтый · 2026-03-01* ^homelib-1\n")
+	assert.Contains(t, md, "> Аннушка уже разлила масло\n\nКлючевая фраза\n\n*Заметка · 2026-03-01* ^homelib-2\n")
+
+	// Chapters follow reading order; inside a chapter annotations follow offsets
+	ch1 := strings.Index(md, "## Глава 1. Никогда не разговаривайте с неизвестными")
+	ch2 := strings.Index(md, "## Глава 2. Понтий Пилат")
+	bookmark := strings.Index(md, "^homelib-3")
+	note := strings.Index(md, "^homelib-2")
+	require.NotEqual(t, -1, ch1)
+	require.NotEqual(t, -1, ch2)
+	assert.Less(t, ch1, bookmark)
+	assert.Less(t, bookmark, note)
+	assert.Less(t, note, ch2)
+	assert.Equal(t, 1, strings.Count(md, "## Глава 1"))
+}
+
+func TestAnnotationExport_BookMarkdown_NoBookFile(t *testing.T) {
+	svc := newTestExportService(sampleExportAnnotations())
+
+	file, err := svc.BookMarkdown(context.Background(), "user-1", 2)
+	require.NoError(t, err)
+	// ':' is not allowed in file names
+	assert.Equal(t, "Стругацкий Аркадий - Пикник на обочине повесть.md", file.Filename)
+	assert.Contains(t, string(file.Data), "## ch7\n")
+}
+
+func TestAnnotationExport_BookMarkdown_Empty(t *testing.T) {
+	svc := newTestExportService(nil)
+
+	file, err := svc.BookMarkdown(context.Background(), "user-1", 1)
+	require.NoError(t, err)
+	assert.Contains(t, string(file.Data), "*Нет заметок.*")
+}
+
+func TestAnnotationExport_BookMarkdown_NotFound(t *testing.T) {
+	svc := newTestExportService(nil)
+
+	_, err := svc.BookMarkdown(context.Background(), "user-1", 99)
+	assert.ErrorIs(t, err, ErrBookNotFound)
+}
+
+func TestAnnotationExport_Dump(t *testing.T) {
+	svc := newTestExportService(sampleExportAnnotations())
+
+	dump, err := svc.Dump(context.Background(), "user-1", nil)
+	require.NoError(t, err)
+	assert.Equal(t, exportTime, dump.ExportedAt)
+	require.Len(t, dump.Books, 2)
+	assert.Equal(t, int64(1), dump.Books[0].Book.ID)
+	assert.Len(t, dump.Books[0].Annotations, 3)
+	assert.Equal(t, "Глава 2. Понтий Пилат", dump.Books[0].Chapters["ch2"])
+	assert.Nil(t, dump.Books[1].Chapters)
+
+	data, err := json.Marshal(dump)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"exportedAt":"2026-03-15T12:00:00Z"`)
+	assert.NotContains(t, string(data), "user-1")
+}
+
+func TestAnnotationExport_Dump_SkipsRestrictedAndMissing(t *testing.T) {
+	items := append(sampleExportAnnotations(), models.Annotation{ID: 5, BookID: 3, Type: models.AnnotationBookmark, ChapterID: "ch1"})
+	svc := newTestExportService(items)
+	svc.books.(*fakeExportBooks).restricted = map[int64]bool{2: true}
+
+	dump, err := svc.Dump(context.Background(), "user-1", []int{7})
+	require.NoError(t, err)
+	require.Len(t, dump.Books, 1)
+	assert.Equal(t, int64(1), dump.Books[0].Book.ID)
+}
+
+func TestAnnotationExport_Dump_RestrictionError(t *testing.T) {
+	svc := newTestExportService(sampleExportAnnotations())
+	svc.books.(*fakeExportBooks).restrErr = fmt.Errorf("db error")
+
+	_, err := svc.Dump(context.Background(), "user-1", []int{7})
+	assert.Error(t, err)
+}
+
+func TestAnnotationExport_Dump_Empty(t *testing.T) {
+	svc := newTestExportService(nil)
+
+	dump, err := svc.Dump(context.Background(), "user-1", nil)
+	require.NoError(t, err)
+	assert.NotNil(t, dump.Books)
+	assert.Empty(t, dump.Books)
+}
+
+func TestAnnotationExport_WriteMarkdownZip(t *testing.T) {
+	items := sampleExportAnnotations()
+	svc := newTestExportService(items)
+	// A second edition with the same author and title must not overwrite the first
+	svc.books.(*fakeExportBooks).books[3] = &models.BookDetail{ID: 3, Title: "Мастер и Маргарита",
+		Authors: []models.BookAuthorRef{{Name: "Булгаков Михаил"}}}
+	svc.annotations.(*fakeAnnotationLister).items = append(items,
+		models.Annotation{ID: 5, BookID: 3, Type: models.AnnotationBookmark, ChapterID: "ch1"})
+
+	var buf bytes.Buffer
+	require.NoError(t, svc.WriteMarkdownZip(context.Background(), "user-1", nil, &buf))
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+	var names []string
+	for _, f := range zr.File {
+		names = append(names, f.Name)
+	}
+	assert.Equal(t, []string{
+		"Булгаков Михаил - Мастер и Маргарита.md",
+		"Стругацкий Аркадий - Пикник на обочине повесть.md",
+		"Булгаков Михаил - Мастер и Маргарита (3).md",
+	}, names)
+
+	rc, err := zr.File[0].Open()
+	require.NoError(t, err)
+	data, err := io.ReadAll(rc)
+	require.NoError(t, err)
+	_ = rc.Close()
+	assert.Contains(t, string(data), "## Глава 2. Понтий Пилат")
+}
+
+func TestExportFileName(t *testing.T) {
+	tests := []struct {
+		name string
+		book models.BookDetail
+		want string
+	}{
+		{"author and title", models.BookDetail{Title: "Эхо", Authors: []models.BookAuthorRef{{Name: "Иванов"}}}, "Иванов - Эхо"},
+		{"no author", models.BookDetail{Title: "Сборник"}, "Сборник"},
+		{"forbidden chars", models.BookDetail{Title: `a/b\c:d*e?f"g<h>i|j#k^l[m]n`}, "a b c d e f g h i j k l m n"},
+		{"trailing dots", models.BookDetail{Title: "Итак..."}, "Итак"},
+		{"empty", models.BookDetail{ID: 9, Title: "???"}, "book-9"},
+		{"long", models.BookDetail{Title: strings.Repeat("я", 150)}, strings.Repeat("я", maxExportFileNameLen)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, exportFileName(&tt.book))
+		})
+	}
+}
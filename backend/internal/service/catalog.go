@@ -37,7 +37,7 @@ func NewCatalogService(
 	}
 }
 
-func (s *CatalogService) ListBooks(ctx context.Context, f models.BookFilter) ([]models.BookListItem, int, error) {
+func (s *CatalogService) ListBooks(ctx context.Context, f models.BookFilter) ([]models.BookListItem, models.PageInfo, error) {
 	f.SetDefaults()
 	return s.bookRepo.List(ctx, f)
 }
@@ -46,15 +46,9 @@ func (s *CatalogService) GetBook(ctx context.Context, id int64) (*models.BookDet
 	return s.bookRepo.GetByID(ctx, id)
 }
 
-func (s *CatalogService) ListAuthors(ctx context.Context, query string, page, limit int) ([]models.AuthorListItem, int, error) {
-	if page < 1 {
-		page = 1
-	}
-	if limit < 1 || limit > 100 {
-		limit = 20
-	}
-	offset := (page - 1) * limit
-	return s.authorRepo.ListWithBookCount(ctx, query, limit, offset)
+func (s *CatalogService) ListAuthors(ctx context.Context, f models.ListFilter) ([]models.AuthorListItem, models.PageInfo, error) {
+	f.SetDefaults()
+	return s.authorRepo.ListWithBookCount(ctx, f)
 }
 
 func (s *CatalogService) GetAuthor(ctx context.Context, id int64) (*models.AuthorDetail, error) {
@@ -66,7 +60,7 @@ func (s *CatalogService) GetAuthor(ctx context.Context, id int64) (*models.Autho
 	// Get books by this author
 	f := models.BookFilter{AuthorID: &id, Page: 1, Limit: 100}
 	f.SetDefaults()
-	books, total, err := s.bookRepo.List(ctx, f)
+	books, page, err := s.bookRepo.List(ctx, f)
 	if err != nil {
 		return nil, fmt.Errorf("list author books: %w", err)
 	}
@@ -75,7 +69,7 @@ func (s *CatalogService) GetAuthor(ctx context.Context, id int64) (*models.Autho
 		ID:         author.ID,
 		Name:       author.Name,
 		Books:      books,
-		BooksCount: total,
+		BooksCount: page.Total,
 	}, nil
 }
 
@@ -83,15 +77,9 @@ func (s *CatalogService) ListGenres(ctx context.Context, excludeIDs []int) ([]mo
 	return s.genreRepo.GetAllFiltered(ctx, excludeIDs)
 }
 
-func (s *CatalogService) ListSeries(ctx context.Context, query string, page, limit int) ([]models.SeriesListItem, int, error) {
-	if page < 1 {
-		page = 1
-	}
-	if limit < 1 || limit > 100 {
-		limit = 20
-	}
-	offset := (page - 1) * limit
-	return s.seriesRepo.ListWithBookCount(ctx, query, limit, offset)
+func (s *CatalogService) ListSeries(ctx context.Context, f models.ListFilter) ([]models.SeriesListItem, models.PageInfo, error) {
+	f.SetDefaults()
+	return s.seriesRepo.ListWithBookCount(ctx, f)
 }
 
 type Stats struct {
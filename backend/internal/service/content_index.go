@@ -0,0 +1,163 @@
+package service
+
+import (
+	"context"
+	"html"
+	"log/slog"
+	"strings"
+
+	"github.com/grom-alex/homelib/backend/internal/bookfile"
+	"github.com/grom-alex/homelib/backend/internal/models"
+	"github.com/grom-alex/homelib/backend/internal/repository"
+)
+
+// contentIndexBatchSize is the number of books fetched per query in IndexBooks.
+const contentIndexBatchSize = 100
+
+// contentChunkSize is the size (bytes of text) a chunk grows to before the
+// next paragraph starts a new one.
+const contentChunkSize = 2000
+
+// contentIndexStore abstracts the content index repo.
+type contentIndexStore interface {
+	ListPending(ctx context.Context, afterID int64, limit, formatVersion int) ([]int64, error)
+	Replace(ctx context.Context, bookID int64, formatVersion int, chunks []models.ContentIndexChunk, indexErr string) error
+	PurgeDisabled(ctx context.Context) (int64, error)
+	SetCollectionEnabled(ctx context.Context, collectionID int, enabled bool) (bool, error)
+	Usage(ctx context.Context) (*models.ContentIndexUsage, error)
+	Search(ctx context.Context, f models.ContentSearchFilter, passages int) ([]models.ContentSearchResult, int, error)
+}
+
+// bookTextExtractor abstracts the reader service.
+type bookTextExtractor interface {
+	ExtractText(ctx context.Context, bookID int64) ([]bookfile.ChapterText, error)
+}
+
+// ContentIndexService maintains the opt-in full-text index of book
+// contents and searches it.
+type ContentIndexService struct {
+	repo   contentIndexStore
+	reader bookTextExtractor
+	logger *slog.Logger
+}
+
+func NewContentIndexService(repo *repository.ContentIndexRepo, reader *ReaderService) *ContentIndexService {
+	return &ContentIndexService{repo: repo, reader: reader, logger: slog.Default()}
+}
+
+// IndexBooks brings the index in line with the collection settings: books
+// of disabled collections are dropped, and books of enabled collections
+// not indexed with the current converters are (re)indexed. Returns how
+// many books were indexed. Unreadable books are recorded as failed.
+func (s *ContentIndexService) IndexBooks(ctx context.Context) (int, error) {
+	purged, err := s.repo.PurgeDisabled(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if purged > 0 {
+		s.logger.Info("content index: removed books of disabled collections", "books", purged)
+	}
+
+	done := 0
+	var afterID int64
+	for {
+		ids, err := s.repo.ListPending(ctx, afterID, contentIndexBatchSize, bookfile.FormatVersion)
+		if err != nil {
+			return done, err
+		}
+		if len(ids) == 0 {
+			return done, nil
+		}
+		for _, id := range ids {
+			afterID = id
+			if err := ctx.Err(); err != nil {
+				return done, err
+			}
+
+			var chunks []models.ContentIndexChunk
+			indexErr := ""
+			chapters, err := s.reader.ExtractText(ctx, id)
+			if err != nil {
+				s.logger.Warn("content index: book unreadable", "book_id", id, "error", err)
+				indexErr = err.Error()
+			} else {
+				chunks = chunkText(chapters)
+			}
+
+			if err := s.repo.Replace(ctx, id, bookfile.FormatVersion, chunks, indexErr); err != nil {
+				return done, err
+			}
+			if indexErr == "" {
+				done++
+			}
+		}
+	}
+}
+
+// chunkText splits the text of a book into chunks of consecutive
+// non-empty paragraphs of one chapter.
+func chunkText(chapters []bookfile.ChapterText) []models.ContentIndexChunk {
+	var chunks []models.ContentIndexChunk
+	for _, ch := range chapters {
+		var b strings.Builder
+		first := -1
+		flush := func() {
+			if b.Len() > 0 {
+				chunks = append(chunks, models.ContentIndexChunk{ChapterID: ch.ID, Paragraph: first, Body: b.String()})
+				b.Reset()
+			}
+		}
+		for i, p := range ch.Paragraphs {
+			p = strings.TrimSpace(p)
+			if p == "" {
+				continue
+			}
+			if b.Len() > 0 && b.Len()+len(p) > contentChunkSize {
+				flush()
+			}
+			if b.Len() == 0 {
+				first = i
+			} else {
+				b.WriteByte('\n')
+			}
+			b.WriteString(p)
+		}
+		flush()
+	}
+	return chunks
+}
+
+// Search returns one page of books whose text matches the query, with
+// their best passages.
+func (s *ContentIndexService) Search(ctx context.Context, f models.ContentSearchFilter) ([]models.ContentSearchResult, models.PageInfo, error) {
+	results, total, err := s.repo.Search(ctx, f, models.ContentPassagesPerBook)
+	if err != nil {
+		return nil, models.PageInfo{}, err
+	}
+	for i := range results {
+		for j := range results[i].Passages {
+			results[i].Passages[j].Snippet = highlightSnippet(results[i].Passages[j].Snippet)
+		}
+	}
+	return results, models.PageInfo{Total: total}, nil
+}
+
+// highlightSnippet turns a ts_headline snippet into HTML: the text is
+// escaped and the marked words are wrapped in <mark>.
+func highlightSnippet(s string) string {
+	s = html.EscapeString(s)
+	s = strings.ReplaceAll(s, repository.HeadlineStart, "<mark>")
+	return strings.ReplaceAll(s, repository.HeadlineStop, "</mark>")
+}
+
+// Usage reports the index state of every collection and its disk usage.
+func (s *ContentIndexService) Usage(ctx context.Context) (*models.ContentIndexUsage, error) {
+	return s.repo.Usage(ctx)
+}
+
+// SetCollectionEnabled turns content indexing of a collection on or off.
+// The index itself is updated by the next IndexBooks run. Returns false if
+// the collection does not exist.
+func (s *ContentIndexService) SetCollectionEnabled(ctx context.Context, collectionID int, enabled bool) (bool, error) {
+	return s.repo.SetCollectionEnabled(ctx, collectionID, enabled)
+}
@@ -0,0 +1,114 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grom-alex/homelib/backend/internal/bookfile"
+	"github.com/grom-alex/homelib/backend/internal/models"
+	"github.com/grom-alex/homelib/backend/internal/repository"
+)
+
+type fakeContentIndex struct {
+	pending  [][]int64
+	replaced map[int64][]models.ContentIndexChunk
+	errors   map[int64]string
+	results  []models.ContentSearchResult
+}
+
+func (f *fakeContentIndex) ListPending(_ context.Context, _ int64, _, formatVersion int) ([]int64, error) {
+	if formatVersion != bookfile.FormatVersion || len(f.pending) == 0 {
+		return nil, nil
+	}
+	ids := f.pending[0]
+	f.pending = f.pending[1:]
+	return ids, nil
+}
+
+func (f *fakeContentIndex) Replace(_ context.Context, bookID int64, _ int, chunks []models.ContentIndexChunk, indexErr string) error {
+	f.replaced[bookID] = chunks
+	if indexErr != "" {
+		f.errors[bookID] = indexErr
+	}
+	return nil
+}
+
+func (f *fakeContentIndex) PurgeDisabled(context.Context) (int64, error) { return 0, nil }
+
+func (f *fakeContentIndex) SetCollectionEnabled(context.Context, int, bool) (bool, error) {
+	return true, nil
+}
+
+func (f *fakeContentIndex) Usage(context.Context) (*models.ContentIndexUsage, error) {
+	return &models.ContentIndexUsage{}, nil
+}
+
+func (f *fakeContentIndex) Search(_ context.Context, _ models.ContentSearchFilter, passages int) ([]models.ContentSearchResult, int, error) {
+	return f.results, len(f.results), nil
+}
+
+type fakeTextExtractor map[int64][]bookfile.ChapterText
+
+func (f fakeTextExtractor) ExtractText(_ context.Context, bookID int64) ([]bookfile.ChapterText, error) {
+	if chapters, ok := f[bookID]; ok {
+		return chapters, nil
+	}
+	return nil, fmt.Errorf("%w: bad file", ErrMalformedFile)
+}
+
+func TestContentIndexService_IndexBooks(t *testing.T) {
+	repo := &fakeContentIndex{
+		pending:  [][]int64{{1, 2}},
+		replaced: map[int64][]models.ContentIndexChunk{},
+		errors:   map[int64]string{},
+	}
+	reader := fakeTextExtractor{1: {{ID: "ch1", Paragraphs: []string{"Текст"}}}}
+	s := &ContentIndexService{repo: repo, reader: reader, logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+
+	n, err := s.IndexBooks(context.Background())
+	require.NoError(t, err)
+
+	assert.Equal(t, 1, n)
+	assert.Equal(t, []models.ContentIndexChunk{{ChapterID: "ch1", Paragraph: 0, Body: "Текст"}}, repo.replaced[1])
+	assert.Contains(t, repo.replaced, int64(2))
+	assert.Contains(t, repo.errors[2], "bad file")
+}
+
+func TestChunkText(t *testing.T) {
+	long := strings.Repeat("а", contentChunkSize)
+	chapters := []bookfile.ChapterText{
+		{ID: "ch1", Paragraphs: []string{"Заголовок", "", " Первый абзац ", long, "После"}},
+		{ID: "ch2", Paragraphs: []string{"  "}},
+		{ID: "ch3", Paragraphs: []string{"Другая глава"}},
+	}
+
+	chunks := chunkText(chapters)
+
+	assert.Equal(t, []models.ContentIndexChunk{
+		{ChapterID: "ch1", Paragraph: 0, Body: "Заголовок\nПервый абзац"},
+		{ChapterID: "ch1", Paragraph: 3, Body: long},
+		{ChapterID: "ch1", Paragraph: 4, Body: "После"},
+		{ChapterID: "ch3", Paragraph: 0, Body: "Другая глава"},
+	}, chunks)
+}
+
+func TestContentIndexService_Search_HighlightsSnippets(t *testing.T) {
+	repo := &fakeContentIndex{results: []models.ContentSearchResult{{
+		BookID:   42,
+		Passages: []models.ContentPassage{{Snippet: "a < b и " + repository.HeadlineStart + "ёлка" + repository.HeadlineStop}},
+	}}}
+	s := &ContentIndexService{repo: repo}
+
+	results, info, err := s.Search(context.Background(), models.ContentSearchFilter{Query: "ёлка", Page: 1, Limit: 20})
+	require.NoError(t, err)
+
+	assert.Equal(t, 1, info.Total)
+	assert.Equal(t, "a &lt; b и <mark>ёлка</mark>", results[0].Passages[0].Snippet)
+}
@@ -1,24 +1,47 @@
 package service
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"io"
+	"log/slog"
 	"path/filepath"
 	"strings"
 
 	"github.com/grom-alex/homelib/backend/internal/archive"
 	"github.com/grom-alex/homelib/backend/internal/config"
+	"github.com/grom-alex/homelib/backend/internal/kosync"
+	"github.com/grom-alex/homelib/backend/internal/models"
 	"github.com/grom-alex/homelib/backend/internal/repository"
 )
 
+// hashBackfillBatchSize is the number of books digested per query in BackfillDocumentHashes.
+const hashBackfillBatchSize = 100
+
+// documentHashStore abstracts the document hash repo.
+type documentHashStore interface {
+	Register(ctx context.Context, document string, bookID int64, kind string) error
+	ListUnhashedBooks(ctx context.Context, afterID int64, limit int) ([]int64, error)
+}
+
+// downloadCache keeps downloaded book files, sharing the reader cache.
+type downloadCache interface {
+	CachedDownload(ctx context.Context, bookID int64) ([]byte, bool)
+	CacheDownload(ctx context.Context, bookID int64, data []byte)
+}
+
 type DownloadService struct {
-	bookRepo *repository.BookRepo
+	bookRepo bookDownloadInfoProvider
 	libCfg   config.LibraryConfig
+	hashes   documentHashStore
+	// cache is nil where downloads are not kept, e.g. for hash backfill.
+	cache  downloadCache
+	logger *slog.Logger
 }
 
-func NewDownloadService(bookRepo *repository.BookRepo, libCfg config.LibraryConfig) *DownloadService {
-	return &DownloadService{bookRepo: bookRepo, libCfg: libCfg}
+func NewDownloadService(bookRepo *repository.BookRepo, libCfg config.LibraryConfig, hashRepo *repository.DocumentHashRepo, cache downloadCache) *DownloadService {
+	return &DownloadService{bookRepo: bookRepo, libCfg: libCfg, hashes: hashRepo, cache: cache, logger: slog.Default()}
 }
 
 type DownloadResult struct {
@@ -29,6 +52,9 @@ type DownloadResult struct {
 }
 
 // DownloadBook returns a stream for the book file extracted from a ZIP archive.
+// The KOReader digests of the file are recorded as it is read, so that
+// devices reading the downloaded file can sync their position. Files read
+// to the end are kept in the reader cache and served from it next time.
 func (s *DownloadService) DownloadBook(ctx context.Context, bookID int64) (*DownloadResult, error) {
 	archiveName, fileInArchive, format, err := s.bookRepo.GetBookForDownload(ctx, bookID)
 	if err != nil {
@@ -39,6 +65,15 @@ func (s *DownloadService) DownloadBook(ctx context.Context, bookID int64) (*Down
 		return nil, fmt.Errorf("book not found: missing archive info")
 	}
 
+	// Registration must outlive a client that disconnects right after the last byte
+	regCtx := context.WithoutCancel(ctx)
+
+	if s.cache != nil {
+		if data, ok := s.cache.CachedDownload(ctx, bookID); ok {
+			return s.downloadResult(regCtx, bookID, fileInArchive, format, io.NopCloser(bytes.NewReader(data)), int64(len(data)), nil), nil
+		}
+	}
+
 	// Prevent path traversal: resolve symlinks and ensure path stays within ArchivesPath
 	archivePath := filepath.Join(s.libCfg.ArchivesPath, archiveName)
 	absBasePath, err := filepath.EvalSymlinks(s.libCfg.ArchivesPath)
@@ -58,10 +93,97 @@ func (s *DownloadService) DownloadBook(ctx context.Context, bookID int64) (*Down
 		return nil, fmt.Errorf("extract file: %w", err)
 	}
 
+	var copied *bytes.Buffer
+	if s.cache != nil && size <= maxBookFileSize {
+		copied = bytes.NewBuffer(make([]byte, 0, size))
+	}
+	return s.downloadResult(regCtx, bookID, fileInArchive, format, reader, size, copied), nil
+}
+
+// downloadResult wraps a book file stream so that its digests are
+// registered, and the file is cached if copied is not nil, once it has been
+// read to the end.
+func (s *DownloadService) downloadResult(ctx context.Context, bookID int64, fileInArchive, format string,
+	reader io.ReadCloser, size int64, copied *bytes.Buffer) *DownloadResult {
+	s.registerHash(ctx, kosync.FilenameMD5(filepath.Base(fileInArchive)), bookID, models.DocumentHashFilename)
+
+	digest := kosync.NewDigestWriter()
+	var w io.Writer = digest
+	if copied != nil {
+		w = io.MultiWriter(digest, copied)
+	}
 	return &DownloadResult{
-		Reader:      reader,
+		Reader: &digestReader{
+			ReadCloser: reader,
+			w:          w,
+			done: func() {
+				s.registerHash(ctx, digest.Sum(), bookID, models.DocumentHashBinary)
+				if copied != nil {
+					s.cache.CacheDownload(ctx, bookID, copied.Bytes())
+				}
+			},
+		},
 		Filename:    fileInArchive,
 		ContentType: archive.GetContentType(format),
 		Size:        size,
-	}, nil
+	}
+}
+
+// BackfillDocumentHashes records the KOReader digests of all books that
+// have none yet and returns how many were digested. Unreadable books are
+// skipped.
+func (s *DownloadService) BackfillDocumentHashes(ctx context.Context) (int, error) {
+	done := 0
+	var afterID int64
+	for {
+		ids, err := s.hashes.ListUnhashedBooks(ctx, afterID, hashBackfillBatchSize)
+		if err != nil {
+			return done, err
+		}
+		if len(ids) == 0 {
+			return done, nil
+		}
+		for _, id := range ids {
+			afterID = id
+			if err := ctx.Err(); err != nil {
+				return done, err
+			}
+			result, err := s.DownloadBook(ctx, id)
+			if err != nil {
+				s.logger.Warn("document hash backfill: book unavailable", "book_id", id, "error", err)
+				continue
+			}
+			_, err = io.Copy(io.Discard, result.Reader)
+			_ = result.Reader.Close()
+			if err != nil {
+				s.logger.Warn("document hash backfill: read failed", "book_id", id, "error", err)
+				continue
+			}
+			done++
+		}
+	}
+}
+
+func (s *DownloadService) registerHash(ctx context.Context, document string, bookID int64, kind string) {
+	if err := s.hashes.Register(ctx, document, bookID, kind); err != nil {
+		s.logger.Warn("failed to register document hash", "book_id", bookID, "kind", kind, "error", err)
+	}
+}
+
+// digestReader copies a file to w (the KOReader digest writer) while it is
+// read and calls done once the end of the file is reached.
+type digestReader struct {
+	io.ReadCloser
+	w    io.Writer
+	done func()
+}
+
+func (r *digestReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	_, _ = r.w.Write(p[:n])
+	if err == io.EOF && r.done != nil {
+		r.done()
+		r.done = nil
+	}
+	return n, err
 }
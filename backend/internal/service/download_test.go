@@ -0,0 +1,69 @@
+package service
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grom-alex/homelib/backend/internal/models"
+)
+
+type fakeHashRegistry struct {
+	registered []string
+}
+
+func (f *fakeHashRegistry) Register(_ context.Context, _ string, _ int64, kind string) error {
+	f.registered = append(f.registered, kind)
+	return nil
+}
+
+func (f *fakeHashRegistry) ListUnhashedBooks(context.Context, int64, int) ([]int64, error) {
+	return nil, nil
+}
+
+func readDownload(t *testing.T, svc *DownloadService, bookID int64) string {
+	t.Helper()
+	result, err := svc.DownloadBook(context.Background(), bookID)
+	require.NoError(t, err)
+	defer func() { _ = result.Reader.Close() }()
+	data, err := io.ReadAll(result.Reader)
+	require.NoError(t, err)
+	assert.Equal(t, int64(len(data)), result.Size)
+	return string(data)
+}
+
+func TestDownloadService_DownloadBook_UsesReaderCache(t *testing.T) {
+	repo := &mockBookRepo{archiveName: "test.zip", fileInArchive: "book.fb2", format: "fb2"}
+	readerSvc, archivesDir := setupReaderService(t, repo)
+	archivePath := createTestArchive(t, archivesDir, "test.zip", "book.fb2", simpleFB2)
+	hashes := &fakeHashRegistry{}
+	svc := &DownloadService{bookRepo: repo, libCfg: readerSvc.libCfg, hashes: hashes, cache: readerSvc, logger: slog.Default()}
+
+	assert.Equal(t, simpleFB2, readDownload(t, svc, 1))
+	assert.FileExists(t, filepath.Join(bookCacheDir(readerSvc, 1), downloadCacheName+".zst"))
+
+	// The second download is served from the cache, without the archive.
+	require.NoError(t, os.Remove(archivePath))
+	assert.Equal(t, simpleFB2, readDownload(t, svc, 1))
+	assert.Equal(t, int64(1), readerSvc.cacheHits.Load())
+	assert.Equal(t, []string{
+		models.DocumentHashFilename, models.DocumentHashBinary,
+		models.DocumentHashFilename, models.DocumentHashBinary,
+	}, hashes.registered)
+}
+
+func TestDownloadService_DownloadBook_WithoutCache(t *testing.T) {
+	repo := &mockBookRepo{archiveName: "test.zip", fileInArchive: "book.fb2", format: "fb2"}
+	readerSvc, archivesDir := setupReaderService(t, repo)
+	createTestArchive(t, archivesDir, "test.zip", "book.fb2", simpleFB2)
+	svc := &DownloadService{bookRepo: repo, libCfg: readerSvc.libCfg, hashes: &fakeHashRegistry{}, logger: slog.Default()}
+
+	assert.Equal(t, simpleFB2, readDownload(t, svc, 1))
+	assert.NoDirExists(t, bookCacheDir(readerSvc, 1))
+}
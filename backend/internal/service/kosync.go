@@ -0,0 +1,363 @@
+package service
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/grom-alex/homelib/backend/internal/bookfile"
+	"github.com/grom-alex/homelib/backend/internal/models"
+	"github.com/grom-alex/homelib/backend/internal/repository"
+)
+
+var (
+	ErrKosyncUnauthorized  = errors.New("kosync: unauthorized")
+	ErrKosyncUsernameTaken = errors.New("kosync: username is taken")
+)
+
+// kosyncDeviceID identifies positions saved in the web reader to KOReader,
+// which ignores positions coming from its own device ID.
+const kosyncDeviceID = "homelib"
+
+// kosyncAuthCacheTTL is how long a verified key is accepted without a new
+// bcrypt comparison. KOReader sends the key with every progress sync.
+const kosyncAuthCacheTTL = 5 * time.Minute
+
+// kosyncAuth is a successful key check.
+type kosyncAuth struct {
+	keyHash string // Account key hash the key was checked against
+	key     [sha256.Size]byte
+	checked time.Time
+}
+
+// kosyncStore abstracts the kosync repo for testing.
+type kosyncStore interface {
+	GetAccount(ctx context.Context, userID string) (*models.KosyncAccount, error)
+	GetAccountByUsername(ctx context.Context, username string) (*models.KosyncAccount, error)
+	SaveAccount(ctx context.Context, a *models.KosyncAccount) (bool, error)
+	DeleteAccount(ctx context.Context, userID string) (bool, error)
+	GetProgress(ctx context.Context, userID, document string) (*models.KosyncProgress, error)
+	SaveProgress(ctx context.Context, p *models.KosyncProgress) error
+}
+
+// documentBookFinder maps KOReader document digests to books.
+type documentBookFinder interface {
+	FindBook(ctx context.Context, document string) (int64, bool, error)
+}
+
+// readingProgressStore abstracts the reading progress repo.
+type readingProgressStore interface {
+	Get(ctx context.Context, userID string, bookID int64) (*models.ReadingProgress, error)
+	Upsert(ctx context.Context, p *models.ReadingProgress) error
+}
+
+// xpointerReader abstracts the reader service parts needed to translate
+// positions between KOReader and the web reader.
+type xpointerReader interface {
+	GetBookContent(ctx context.Context, bookID int64) (*bookfile.BookContent, error)
+	GetChapter(ctx context.Context, bookID int64, chapterID string) (*bookfile.ChapterContent, error)
+	XPointer(ctx context.Context, bookID int64, chapterID string, paragraph int) (string, error)
+	ResolveXPointer(ctx context.Context, bookID int64, xpointer string) (string, int, error)
+}
+
+// progressRecorder reacts to saved positions: reading sessions, statuses.
+type progressRecorder interface {
+	RecordProgress(ctx context.Context, p *models.ReadingProgress, source string)
+}
+
+// KosyncService implements the KOReader sync protocol on top of the
+// library's reading progress: positions reported by KOReader for books in
+// the library become web reader positions and vice versa.
+type KosyncService struct {
+	store    kosyncStore
+	hashes   documentBookFinder
+	progress readingProgressStore
+	reader   xpointerReader
+	sessions progressRecorder
+	now      func() time.Time
+	logger   *slog.Logger
+
+	authMu sync.Mutex
+	auths  map[string]kosyncAuth // By username
+}
+
+func NewKosyncService(kosyncRepo *repository.KosyncRepo, hashRepo *repository.DocumentHashRepo,
+	progressRepo *repository.ReadingProgressRepo, readerSvc *ReaderService, recorders ProgressRecorders) *KosyncService {
+	return &KosyncService{
+		store:    kosyncRepo,
+		hashes:   hashRepo,
+		progress: progressRepo,
+		reader:   readerSvc,
+		sessions: recorders,
+		now:      time.Now,
+		logger:   slog.Default(),
+	}
+}
+
+// kosyncKey returns the key KOReader derives from a password.
+func kosyncKey(password string) string {
+	sum := md5.Sum([]byte(password))
+	return hex.EncodeToString(sum[:])
+}
+
+// Authorize checks KOReader credentials (username and MD5 key) and returns
+// the user they belong to. A key that passed the check is accepted again
+// for kosyncAuthCacheTTL without comparing it to the hash, as long as the
+// account keeps the same hash.
+func (s *KosyncService) Authorize(ctx context.Context, username, key string) (string, error) {
+	if username == "" || key == "" {
+		return "", ErrKosyncUnauthorized
+	}
+	a, err := s.store.GetAccountByUsername(ctx, username)
+	if err != nil {
+		return "", err
+	}
+	if a == nil || !a.UserActive {
+		return "", ErrKosyncUnauthorized
+	}
+	key = strings.ToLower(key)
+	digest := sha256.Sum256([]byte(key))
+	if s.authCached(username, a.KeyHash, digest) {
+		return a.UserID, nil
+	}
+	if bcrypt.CompareHashAndPassword([]byte(a.KeyHash), []byte(key)) != nil {
+		return "", ErrKosyncUnauthorized
+	}
+
+	s.authMu.Lock()
+	if s.auths == nil {
+		s.auths = make(map[string]kosyncAuth)
+	}
+	s.auths[username] = kosyncAuth{keyHash: a.KeyHash, key: digest, checked: s.now()}
+	s.authMu.Unlock()
+	return a.UserID, nil
+}
+
+// authCached reports whether the key passed the check against keyHash
+// within kosyncAuthCacheTTL.
+func (s *KosyncService) authCached(username, keyHash string, key [sha256.Size]byte) bool {
+	s.authMu.Lock()
+	defer s.authMu.Unlock()
+	auth, ok := s.auths[username]
+	if !ok {
+		return false
+	}
+	if s.now().Sub(auth.checked) >= kosyncAuthCacheTTL {
+		delete(s.auths, username)
+		return false
+	}
+	return auth.keyHash == keyHash && auth.key == key
+}
+
+// UsernameTaken reports whether a sync account with the username exists.
+func (s *KosyncService) UsernameTaken(ctx context.Context, username string) (bool, error) {
+	a, err := s.store.GetAccountByUsername(ctx, username)
+	if err != nil {
+		return false, err
+	}
+	return a != nil, nil
+}
+
+// Account returns the user's sync account, or nil if not set up.
+func (s *KosyncService) Account(ctx context.Context, userID string) (*models.KosyncAccount, error) {
+	return s.store.GetAccount(ctx, userID)
+}
+
+// SetAccount creates or replaces the user's sync credentials.
+func (s *KosyncService) SetAccount(ctx context.Context, userID string, input models.SetKosyncAccountInput) (*models.KosyncAccount, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(kosyncKey(input.Password)), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, fmt.Errorf("hash kosync key: %w", err)
+	}
+	a := &models.KosyncAccount{
+		UserID:     userID,
+		Username:   strings.TrimSpace(input.Username),
+		KeyHash:    string(hash),
+		UserActive: true,
+	}
+	ok, err := s.store.SaveAccount(ctx, a)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrKosyncUsernameTaken
+	}
+	return a, nil
+}
+
+// DeleteAccount removes the user's sync account. Returns false if none existed.
+func (s *KosyncService) DeleteAccount(ctx context.Context, userID string) (bool, error) {
+	return s.store.DeleteAccount(ctx, userID)
+}
+
+// UpdateProgress stores a position reported by KOReader. For books in the
+// library it also becomes the web reader position; translation failures
+// are logged and do not fail the sync.
+func (s *KosyncService) UpdateProgress(ctx context.Context, userID string, p *models.KosyncProgress) error {
+	p.UserID = userID
+	p.UpdatedAt = s.now()
+
+	bookID, found, err := s.hashes.FindBook(ctx, p.Document)
+	if err != nil {
+		return err
+	}
+	if found {
+		rp, err := s.toReadingProgress(ctx, bookID, p)
+		if err != nil {
+			s.logger.Warn("kosync: cannot map position to the book",
+				"book_id", bookID, "progress", p.Progress, "error", err)
+		} else {
+			rp.UserID = userID
+			if err := s.progress.Upsert(ctx, rp); err != nil {
+				return err
+			}
+			s.sessions.RecordProgress(ctx, rp, models.SessionSourceKosync)
+			// Same timestamp as the reading progress, so GetProgress can
+			// tell whether the web reader moved on since.
+			p.UpdatedAt = rp.UpdatedAt
+		}
+	}
+	return s.store.SaveProgress(ctx, p)
+}
+
+// GetProgress returns the latest position in a document: the one reported
+// by KOReader, or the web reader position if that is newer. Returns nil if
+// there is none.
+func (s *KosyncService) GetProgress(ctx context.Context, userID, document string) (*models.KosyncProgress, error) {
+	kp, err := s.store.GetProgress(ctx, userID, document)
+	if err != nil {
+		return nil, err
+	}
+
+	bookID, found, err := s.hashes.FindBook(ctx, document)
+	if err != nil || !found {
+		return kp, err
+	}
+	rp, err := s.progress.Get(ctx, userID, bookID)
+	if err != nil {
+		return nil, err
+	}
+	if rp == nil || (kp != nil && !rp.UpdatedAt.After(kp.UpdatedAt)) {
+		return kp, nil
+	}
+
+	paragraph := 0
+	if rp.Locator != nil {
+		paragraph = rp.Locator.Paragraph
+	}
+	xp, err := s.reader.XPointer(ctx, bookID, rp.ChapterID, paragraph)
+	if err != nil {
+		s.logger.Warn("kosync: cannot map web position for KOReader",
+			"book_id", bookID, "chapter_id", rp.ChapterID, "error", err)
+		return kp, nil
+	}
+	device := rp.Device
+	if device == "" {
+		device = kosyncDeviceID
+	}
+	return &models.KosyncProgress{
+		UserID:     userID,
+		Document:   document,
+		Progress:   xp,
+		Percentage: float64(rp.TotalProgress) / 100,
+		Device:     device,
+		DeviceID:   kosyncDeviceID,
+		UpdatedAt:  rp.UpdatedAt,
+	}, nil
+}
+
+// toReadingProgress translates a KOReader position into a web reader one.
+// The XPointer gives the chapter and paragraph; if it cannot be resolved,
+// the position is estimated from the percentage.
+func (s *KosyncService) toReadingProgress(ctx context.Context, bookID int64, p *models.KosyncProgress) (*models.ReadingProgress, error) {
+	percentage := min(max(p.Percentage, 0), 1)
+	rp := &models.ReadingProgress{
+		BookID:        bookID,
+		TotalProgress: int(math.Round(percentage * 100)),
+		Device:        p.Device,
+	}
+
+	chapterID, paragraph, err := s.reader.ResolveXPointer(ctx, bookID, p.Progress)
+	if err == nil {
+		ch, err := s.reader.GetChapter(ctx, bookID, chapterID)
+		if err != nil {
+			return nil, err
+		}
+		lengths := bookfile.ParagraphLengths(ch.HTML)
+		rp.ChapterID = chapterID
+		rp.Locator = &models.Locator{Paragraph: paragraph}
+		rp.ChapterProgress = chapterPercent(lengths, paragraph)
+		return rp, nil
+	}
+
+	content, err := s.reader.GetBookContent(ctx, bookID)
+	if err != nil {
+		return nil, err
+	}
+	chapterID, fraction := chapterAtFraction(content, percentage)
+	if chapterID == "" {
+		return nil, fmt.Errorf("book has no chapters")
+	}
+	ch, err := s.reader.GetChapter(ctx, bookID, chapterID)
+	if err != nil {
+		return nil, err
+	}
+	par, off := bookfile.LocateFraction(bookfile.ParagraphLengths(ch.HTML), fraction)
+	rp.ChapterID = chapterID
+	rp.Locator = &models.Locator{Paragraph: par, Offset: off}
+	rp.ChapterProgress = int(math.Round(fraction * 100))
+	return rp, nil
+}
+
+// chapterPercent returns how much of a chapter's text precedes a paragraph (0-100).
+func chapterPercent(lengths []int, paragraph int) int {
+	before, total := 0, 0
+	for i, l := range lengths {
+		if i < paragraph {
+			before += l
+		}
+		total += l
+	}
+	if total == 0 {
+		return 0
+	}
+	return int(math.Round(float64(before) * 100 / float64(total)))
+}
+
+// chapterAtFraction finds the chapter at a fraction (0..1) of the book,
+// weighting chapters by size, and the fraction within that chapter.
+func chapterAtFraction(content *bookfile.BookContent, fraction float64) (string, float64) {
+	ids := content.ChapterIDs
+	if len(ids) == 0 {
+		return "", 0
+	}
+	weight := func(id string) float64 {
+		if size := content.ChapterSizes[id]; size > 0 {
+			return float64(size)
+		}
+		return 1
+	}
+	total := 0.0
+	for _, id := range ids {
+		total += weight(id)
+	}
+	target := fraction * total
+	for _, id := range ids {
+		w := weight(id)
+		if target < w {
+			return id, target / w
+		}
+		target -= w
+	}
+	return ids[len(ids)-1], 1
+}
@@ -0,0 +1,312 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/grom-alex/homelib/backend/internal/bookfile"
+	"github.com/grom-alex/homelib/backend/internal/models"
+)
+
+type fakeKosyncStore struct {
+	accounts map[string]*models.KosyncAccount
+	progress map[string]*models.KosyncProgress
+}
+
+func newFakeKosyncStore() *fakeKosyncStore {
+	return &fakeKosyncStore{
+		accounts: make(map[string]*models.KosyncAccount),
+		progress: make(map[string]*models.KosyncProgress),
+	}
+}
+
+func (f *fakeKosyncStore) GetAccount(_ context.Context, userID string) (*models.KosyncAccount, error) {
+	return f.accounts[userID], nil
+}
+
+func (f *fakeKosyncStore) GetAccountByUsername(_ context.Context, username string) (*models.KosyncAccount, error) {
+	for _, a := range f.accounts {
+		if a.Username == username {
+			return a, nil
+		}
+	}
+	return nil, nil
+}
+
+func (f *fakeKosyncStore) SaveAccount(_ context.Context, a *models.KosyncAccount) (bool, error) {
+	for id, other := range f.accounts {
+		if other.Username == a.Username && id != a.UserID {
+			return false, nil
+		}
+	}
+	f.accounts[a.UserID] = a
+	return true, nil
+}
+
+func (f *fakeKosyncStore) DeleteAccount(_ context.Context, userID string) (bool, error) {
+	_, ok := f.accounts[userID]
+	delete(f.accounts, userID)
+	return ok, nil
+}
+
+func (f *fakeKosyncStore) GetProgress(_ context.Context, userID, document string) (*models.KosyncProgress, error) {
+	return f.progress[userID+"/"+document], nil
+}
+
+func (f *fakeKosyncStore) SaveProgress(_ context.Context, p *models.KosyncProgress) error {
+	f.progress[p.UserID+"/"+p.Document] = p
+	return nil
+}
+
+type fakeDocumentHashes map[string]int64
+
+func (f fakeDocumentHashes) FindBook(_ context.Context, document string) (int64, bool, error) {
+	id, ok := f[document]
+	return id, ok, nil
+}
+
+type fakeReadingProgress struct {
+	saved map[int64]*models.ReadingProgress
+}
+
+func (f *fakeReadingProgress) Get(_ context.Context, _ string, bookID int64) (*models.ReadingProgress, error) {
+	return f.saved[bookID], nil
+}
+
+func (f *fakeReadingProgress) Upsert(_ context.Context, p *models.ReadingProgress) error {
+	if f.saved == nil {
+		f.saved = make(map[int64]*models.ReadingProgress)
+	}
+	p.UpdatedAt = time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	f.saved[p.BookID] = p
+	return nil
+}
+
+// fakeXPointerReader serves a two-chapter book whose paragraphs map to
+// "/FictionBook/body/section[N]/p[M]" XPointers.
+type fakeXPointerReader struct{}
+
+var fakeXPointerChapters = map[string]string{
+	"ch1": `<p data-p="0">Один</p><p data-p="1">Два</p>`,
+	"ch2": `<p data-p="0">Три</p><p data-p="1">Четыре</p><p data-p="2">Пять</p><p data-p="3">Шесть</p>`,
+}
+
+func (fakeXPointerReader) GetBookContent(_ context.Context, _ int64) (*bookfile.BookContent, error) {
+	return &bookfile.BookContent{
+		ChapterIDs:   []string{"ch1", "ch2"},
+		ChapterSizes: map[string]int{"ch1": 100, "ch2": 300},
+	}, nil
+}
+
+func (fakeXPointerReader) GetChapter(_ context.Context, _ int64, chapterID string) (*bookfile.ChapterContent, error) {
+	h, ok := fakeXPointerChapters[chapterID]
+	if !ok {
+		return nil, ErrBookNotFound
+	}
+	return &bookfile.ChapterContent{ID: chapterID, HTML: h}, nil
+}
+
+func (fakeXPointerReader) XPointer(_ context.Context, _ int64, chapterID string, paragraph int) (string, error) {
+	return fmt.Sprintf("/FictionBook/body/section[%s]/p[%d]", chapterID[2:], paragraph+1), nil
+}
+
+func (fakeXPointerReader) ResolveXPointer(_ context.Context, _ int64, xp string) (string, int, error) {
+	var section, p int
+	if _, err := fmt.Sscanf(xp, "/FictionBook/body/section[%d]/p[%d]", &section, &p); err != nil {
+		return "", 0, fmt.Errorf("unresolvable xpointer %q", xp)
+	}
+	return fmt.Sprintf("ch%d", section), p - 1, nil
+}
+
+type fakeRecorder struct {
+	recorded []string
+}
+
+func (f *fakeRecorder) RecordProgress(_ context.Context, p *models.ReadingProgress, source string) {
+	f.recorded = append(f.recorded, fmt.Sprintf("%s:%d:%s", source, p.BookID, p.ChapterID))
+}
+
+func newTestKosync(store *fakeKosyncStore, progress *fakeReadingProgress) *KosyncService {
+	return &KosyncService{
+		store:    store,
+		hashes:   fakeDocumentHashes{"doc42": 42},
+		progress: progress,
+		reader:   fakeXPointerReader{},
+		sessions: &fakeRecorder{},
+		now:      func() time.Time { return time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC) },
+		logger:   slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}
+}
+
+func TestKosync_SetAccountAndAuthorize(t *testing.T) {
+	store := newFakeKosyncStore()
+	s := newTestKosync(store, &fakeReadingProgress{})
+	ctx := context.Background()
+
+	a, err := s.SetAccount(ctx, "user-1", models.SetKosyncAccountInput{Username: " reader ", Password: "password"})
+	require.NoError(t, err)
+	assert.Equal(t, "reader", a.Username)
+	require.NoError(t, bcrypt.CompareHashAndPassword([]byte(a.KeyHash), []byte(kosyncKey("password"))))
+
+	// KOReader sends md5("password")
+	userID, err := s.Authorize(ctx, "reader", "5F4DCC3B5AA765D61D8327DEB882CF99")
+	require.NoError(t, err)
+	assert.Equal(t, "user-1", userID)
+
+	_, err = s.Authorize(ctx, "reader", kosyncKey("wrong"))
+	assert.ErrorIs(t, err, ErrKosyncUnauthorized)
+	_, err = s.Authorize(ctx, "nobody", kosyncKey("password"))
+	assert.ErrorIs(t, err, ErrKosyncUnauthorized)
+
+	_, err = s.SetAccount(ctx, "user-2", models.SetKosyncAccountInput{Username: "reader", Password: "another"})
+	assert.ErrorIs(t, err, ErrKosyncUsernameTaken)
+}
+
+func TestKosync_Authorize_InactiveUser(t *testing.T) {
+	store := newFakeKosyncStore()
+	hash, err := bcrypt.GenerateFromPassword([]byte(kosyncKey("password")), bcrypt.MinCost)
+	require.NoError(t, err)
+	store.accounts["user-1"] = &models.KosyncAccount{UserID: "user-1", Username: "reader", KeyHash: string(hash)}
+
+	_, err = newTestKosync(store, &fakeReadingProgress{}).Authorize(context.Background(), "reader", kosyncKey("password"))
+	assert.ErrorIs(t, err, ErrKosyncUnauthorized)
+}
+
+func TestKosync_Authorize_CachesCheckedKeys(t *testing.T) {
+	store := newFakeKosyncStore()
+	s := newTestKosync(store, &fakeReadingProgress{})
+	ctx := context.Background()
+	now := time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+
+	_, err := s.SetAccount(ctx, "user-1", models.SetKosyncAccountInput{Username: "reader", Password: "password"})
+	require.NoError(t, err)
+	store.accounts["user-1"].UserActive = true
+
+	userID, err := s.Authorize(ctx, "reader", kosyncKey("password"))
+	require.NoError(t, err)
+	assert.Equal(t, "user-1", userID)
+	require.Contains(t, s.auths, "reader")
+
+	// The cached check still needs the same key, an active user and an
+	// unchanged account
+	_, err = s.Authorize(ctx, "reader", kosyncKey("wrong"))
+	assert.ErrorIs(t, err, ErrKosyncUnauthorized)
+	store.accounts["user-1"].UserActive = false
+	_, err = s.Authorize(ctx, "reader", kosyncKey("password"))
+	assert.ErrorIs(t, err, ErrKosyncUnauthorized)
+
+	_, err = s.SetAccount(ctx, "user-1", models.SetKosyncAccountInput{Username: "reader", Password: "changed"})
+	require.NoError(t, err)
+	store.accounts["user-1"].UserActive = true
+	_, err = s.Authorize(ctx, "reader", kosyncKey("password"))
+	assert.ErrorIs(t, err, ErrKosyncUnauthorized)
+
+	// A cached check expires after kosyncAuthCacheTTL
+	_, err = s.Authorize(ctx, "reader", kosyncKey("changed"))
+	require.NoError(t, err)
+	assert.True(t, s.authCached("reader", store.accounts["user-1"].KeyHash, sha256.Sum256([]byte(kosyncKey("changed")))))
+	now = now.Add(kosyncAuthCacheTTL)
+	assert.False(t, s.authCached("reader", store.accounts["user-1"].KeyHash, sha256.Sum256([]byte(kosyncKey("changed")))))
+	assert.NotContains(t, s.auths, "reader")
+}
+
+func TestKosync_UpdateProgress_MapsXPointer(t *testing.T) {
+	store := newFakeKosyncStore()
+	progress := &fakeReadingProgress{}
+	s := newTestKosync(store, progress)
+
+	p := &models.KosyncProgress{Document: "doc42", Progress: "/FictionBook/body/section[2]/p[3]", Percentage: 0.6, Device: "Kobo"}
+	require.NoError(t, s.UpdateProgress(context.Background(), "user-1", p))
+	assert.Equal(t, []string{"kosync:42:ch2"}, s.sessions.(*fakeRecorder).recorded)
+
+	rp := progress.saved[42]
+	require.NotNil(t, rp)
+	assert.Equal(t, "user-1", rp.UserID)
+	assert.Equal(t, "ch2", rp.ChapterID)
+	assert.Equal(t, &models.Locator{Paragraph: 2}, rp.Locator)
+	assert.Equal(t, 60, rp.TotalProgress)
+	assert.Equal(t, "Kobo", rp.Device)
+	// "Три" + "Четыре" of 18 characters precede paragraph 2
+	assert.Equal(t, 50, rp.ChapterProgress)
+
+	assert.Equal(t, rp.UpdatedAt, p.UpdatedAt)
+	assert.Same(t, p, store.progress["user-1/doc42"])
+}
+
+func TestKosync_UpdateProgress_FallsBackToPercentage(t *testing.T) {
+	progress := &fakeReadingProgress{}
+	s := newTestKosync(newFakeKosyncStore(), progress)
+
+	p := &models.KosyncProgress{Document: "doc42", Progress: "#_doc_fragment_5", Percentage: 0.625, Device: "Kobo"}
+	require.NoError(t, s.UpdateProgress(context.Background(), "user-1", p))
+
+	rp := progress.saved[42]
+	require.NotNil(t, rp)
+	// 62.5% of 400 falls at the middle of ch2 (100..400)
+	assert.Equal(t, "ch2", rp.ChapterID)
+	assert.Equal(t, 50, rp.ChapterProgress)
+	require.NotNil(t, rp.Locator)
+}
+
+func TestKosync_UpdateProgress_UnknownDocument(t *testing.T) {
+	store := newFakeKosyncStore()
+	progress := &fakeReadingProgress{}
+	s := newTestKosync(store, progress)
+
+	p := &models.KosyncProgress{Document: "elsewhere", Progress: "/body/p", Percentage: 0.1, Device: "Kobo"}
+	require.NoError(t, s.UpdateProgress(context.Background(), "user-1", p))
+
+	assert.Empty(t, progress.saved)
+	assert.Equal(t, time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC), p.UpdatedAt)
+	assert.NotNil(t, store.progress["user-1/elsewhere"])
+}
+
+func TestKosync_GetProgress_WebNewer(t *testing.T) {
+	store := newFakeKosyncStore()
+	store.progress["user-1/doc42"] = &models.KosyncProgress{
+		Document: "doc42", Progress: "/FictionBook/body/section[1]/p[1]", Device: "Kobo",
+		UpdatedAt: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+	web := time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC)
+	progress := &fakeReadingProgress{saved: map[int64]*models.ReadingProgress{
+		42: {BookID: 42, ChapterID: "ch2", Locator: &models.Locator{Paragraph: 3, Offset: 2}, TotalProgress: 90, UpdatedAt: web},
+	}}
+
+	p, err := newTestKosync(store, progress).GetProgress(context.Background(), "user-1", "doc42")
+	require.NoError(t, err)
+	require.NotNil(t, p)
+	assert.Equal(t, "/FictionBook/body/section[2]/p[4]", p.Progress)
+	assert.InDelta(t, 0.9, p.Percentage, 1e-9)
+	assert.Equal(t, kosyncDeviceID, p.DeviceID)
+	assert.Equal(t, web, p.UpdatedAt)
+}
+
+func TestKosync_GetProgress_KOReaderCurrent(t *testing.T) {
+	store := newFakeKosyncStore()
+	synced := time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC)
+	kp := &models.KosyncProgress{Document: "doc42", Progress: "/FictionBook/body/section[1]/p[1]", Device: "Kobo", UpdatedAt: synced}
+	store.progress["user-1/doc42"] = kp
+	progress := &fakeReadingProgress{saved: map[int64]*models.ReadingProgress{
+		42: {BookID: 42, ChapterID: "ch1", UpdatedAt: synced},
+	}}
+
+	p, err := newTestKosync(store, progress).GetProgress(context.Background(), "user-1", "doc42")
+	require.NoError(t, err)
+	assert.Same(t, kp, p)
+}
+
+func TestKosync_GetProgress_None(t *testing.T) {
+	p, err := newTestKosync(newFakeKosyncStore(), &fakeReadingProgress{}).GetProgress(context.Background(), "user-1", "doc42")
+	require.NoError(t, err)
+	assert.Nil(t, p)
+}
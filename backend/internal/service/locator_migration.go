@@ -0,0 +1,90 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/grom-alex/homelib/backend/internal/bookfile"
+	"github.com/grom-alex/homelib/backend/internal/models"
+	"github.com/grom-alex/homelib/backend/internal/repository"
+)
+
+// locatorBatchSize is the number of progress entries migrated per query.
+const locatorBatchSize = 200
+
+// progressLocatorStore abstracts the reading progress repo for testing.
+type progressLocatorStore interface {
+	ListWithoutLocator(ctx context.Context, afterID int64, limit int) ([]models.ReadingProgress, error)
+	SetLocator(ctx context.Context, p *models.ReadingProgress, l models.Locator) error
+}
+
+// chapterProvider abstracts the reader service for testing.
+type chapterProvider interface {
+	GetChapter(ctx context.Context, bookID int64, chapterID string) (*bookfile.ChapterContent, error)
+}
+
+// LocatorMigrationService converts reading positions saved as chapter
+// percentages into paragraph locators.
+type LocatorMigrationService struct {
+	progress progressLocatorStore
+	chapters chapterProvider
+	logger   *slog.Logger
+}
+
+func NewLocatorMigrationService(progressRepo *repository.ReadingProgressRepo, readerSvc *ReaderService) *LocatorMigrationService {
+	return &LocatorMigrationService{
+		progress: progressRepo,
+		chapters: readerSvc,
+		logger:   slog.Default(),
+	}
+}
+
+// MigrateProgress sets locators on all progress entries that have none and
+// returns how many were migrated. Entries whose book can no longer be read
+// are skipped and left as is.
+func (s *LocatorMigrationService) MigrateProgress(ctx context.Context) (int, error) {
+	migrated := 0
+	var afterID int64
+	for {
+		batch, err := s.progress.ListWithoutLocator(ctx, afterID, locatorBatchSize)
+		if err != nil {
+			return migrated, err
+		}
+		if len(batch) == 0 {
+			return migrated, nil
+		}
+
+		// Readers of the same book usually share a chapter; parse it once.
+		lengths := make(map[string][]int)
+		for i := range batch {
+			p := &batch[i]
+			afterID = p.ID
+
+			key := fmt.Sprintf("%d/%s", p.BookID, p.ChapterID)
+			l, ok := lengths[key]
+			if !ok {
+				ch, err := s.chapters.GetChapter(ctx, p.BookID, p.ChapterID)
+				if err != nil {
+					if ctx.Err() != nil {
+						return migrated, ctx.Err()
+					}
+					s.logger.Warn("locator migration: chapter unavailable",
+						"book_id", p.BookID, "chapter_id", p.ChapterID, "error", err)
+				} else {
+					l = bookfile.ParagraphLengths(ch.HTML)
+				}
+				lengths[key] = l
+			}
+			if l == nil {
+				continue
+			}
+
+			paragraph, offset := bookfile.LocateFraction(l, float64(p.ChapterProgress)/100)
+			if err := s.progress.SetLocator(ctx, p, models.Locator{Paragraph: paragraph, Offset: offset}); err != nil {
+				return migrated, err
+			}
+			migrated++
+		}
+	}
+}
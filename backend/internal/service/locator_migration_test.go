@@ -0,0 +1,112 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grom-alex/homelib/backend/internal/bookfile"
+	"github.com/grom-alex/homelib/backend/internal/models"
+)
+
+type fakeProgressLocators struct {
+	entries []models.ReadingProgress
+	set     map[int64]models.Locator
+	setErr  error
+}
+
+func (f *fakeProgressLocators) ListWithoutLocator(_ context.Context, afterID int64, limit int) ([]models.ReadingProgress, error) {
+	var out []models.ReadingProgress
+	for _, p := range f.entries {
+		if p.ID > afterID && len(out) < limit {
+			out = append(out, p)
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeProgressLocators) SetLocator(_ context.Context, p *models.ReadingProgress, l models.Locator) error {
+	if f.setErr != nil {
+		return f.setErr
+	}
+	if f.set == nil {
+		f.set = make(map[int64]models.Locator)
+	}
+	f.set[p.ID] = l
+	return nil
+}
+
+type fakeChapters struct {
+	html  map[string]string
+	calls int
+}
+
+func (f *fakeChapters) GetChapter(_ context.Context, bookID int64, chapterID string) (*bookfile.ChapterContent, error) {
+	f.calls++
+	h, ok := f.html[fmt.Sprintf("%d/%s", bookID, chapterID)]
+	if !ok {
+		return nil, ErrBookNotFound
+	}
+	return &bookfile.ChapterContent{ID: chapterID, HTML: h}, nil
+}
+
+func newTestLocatorMigration(store *fakeProgressLocators, chapters *fakeChapters) *LocatorMigrationService {
+	return &LocatorMigrationService{
+		progress: store,
+		chapters: chapters,
+		logger:   slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}
+}
+
+func TestLocatorMigration_MigrateProgress(t *testing.T) {
+	store := &fakeProgressLocators{entries: []models.ReadingProgress{
+		{ID: 1, BookID: 42, ChapterID: "ch1", ChapterProgress: 0},
+		{ID: 2, BookID: 42, ChapterID: "ch1", ChapterProgress: 50},
+		{ID: 3, BookID: 7, ChapterID: "ch1", ChapterProgress: 30},
+		{ID: 4, BookID: 42, ChapterID: "ch1", ChapterProgress: 100},
+	}}
+	chapters := &fakeChapters{html: map[string]string{
+		"42/ch1": `<h2 data-p="0">Глава</h2><p data-p="1">Первый абзац</p><p data-p="2">Второй абзац</p>`,
+	}}
+
+	n, err := newTestLocatorMigration(store, chapters).MigrateProgress(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 3, n)
+	assert.Equal(t, map[int64]models.Locator{
+		1: {Paragraph: 0, Offset: 0},
+		2: {Paragraph: 1, Offset: 9},
+		4: {Paragraph: 2, Offset: 12},
+	}, store.set)
+	// Book 7 is unavailable and is left without a locator; ch1 of book 42 is parsed once.
+	assert.Equal(t, 2, chapters.calls)
+}
+
+func TestLocatorMigration_MigrateProgress_Batches(t *testing.T) {
+	store := &fakeProgressLocators{}
+	for i := 1; i <= locatorBatchSize+5; i++ {
+		store.entries = append(store.entries, models.ReadingProgress{ID: int64(i), BookID: 1, ChapterID: "c", ChapterProgress: 10})
+	}
+	chapters := &fakeChapters{html: map[string]string{"1/c": `<p data-p="0">0123456789</p>`}}
+
+	n, err := newTestLocatorMigration(store, chapters).MigrateProgress(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, locatorBatchSize+5, n)
+	assert.Equal(t, models.Locator{Paragraph: 0, Offset: 1}, store.set[int64(locatorBatchSize+5)])
+}
+
+func TestLocatorMigration_MigrateProgress_SetError(t *testing.T) {
+	store := &fakeProgressLocators{
+		entries: []models.ReadingProgress{{ID: 1, BookID: 1, ChapterID: "c"}},
+		setErr:  fmt.Errorf("db down"),
+	}
+	chapters := &fakeChapters{html: map[string]string{"1/c": `<p data-p="0">x</p>`}}
+
+	n, err := newTestLocatorMigration(store, chapters).MigrateProgress(context.Background())
+	assert.EqualError(t, err, "db down")
+	assert.Equal(t, 0, n)
+}
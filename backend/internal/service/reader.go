@@ -2,14 +2,15 @@ package service
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
-	"os"
 	"path/filepath"
 	"regexp"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"golang.org/x/sync/singleflight"
@@ -17,6 +18,8 @@ import (
 	"github.com/grom-alex/homelib/backend/internal/archive"
 	"github.com/grom-alex/homelib/backend/internal/bookfile"
 	"github.com/grom-alex/homelib/backend/internal/config"
+	"github.com/grom-alex/homelib/backend/internal/models"
+	"github.com/grom-alex/homelib/backend/internal/readercache"
 	"github.com/grom-alex/homelib/backend/internal/repository"
 )
 
@@ -41,32 +44,46 @@ type bookDownloadInfoProvider interface {
 }
 
 type ReaderService struct {
-	bookRepo   bookDownloadInfoProvider
-	libCfg     config.LibraryConfig
-	cachePath  string
-	cacheTTL   time.Duration
-	parseGroup singleflight.Group
-	logger     *slog.Logger
+	bookRepo     bookDownloadInfoProvider
+	libCfg       config.LibraryConfig
+	cache        readercache.Store
+	cacheTTL     time.Duration
+	maxCacheSize int64
+	parseGroup   singleflight.Group
+	// transcodeGroup deduplicates transcoding of image variants.
+	transcodeGroup singleflight.Group
+	logger         *slog.Logger
+
+	// cacheSize approximates the cache size: writes add to it and every
+	// listing of the cache resets it.
+	cacheSize   atomic.Int64
+	cacheHits   atomic.Int64
+	cacheMisses atomic.Int64
+	evicting    atomic.Bool
+	// cacheMu serializes eviction and purges.
+	cacheMu sync.Mutex
 }
 
-func NewReaderService(bookRepo *repository.BookRepo, libCfg config.LibraryConfig, readerCfg config.ReaderConfig) *ReaderService {
+func NewReaderService(bookRepo *repository.BookRepo, libCfg config.LibraryConfig, readerCfg config.ReaderConfig, cache readercache.Store) *ReaderService {
 	return &ReaderService{
-		bookRepo:  bookRepo,
-		libCfg:    libCfg,
-		cachePath: readerCfg.CachePath,
-		cacheTTL:  readerCfg.CacheTTL,
-		logger:    slog.Default(),
+		bookRepo:     bookRepo,
+		libCfg:       libCfg,
+		cache:        cache,
+		cacheTTL:     readerCfg.CacheTTL,
+		maxCacheSize: readerCfg.MaxSize,
+		logger:       slog.Default(),
 	}
 }
 
-// GetBookContent returns the book metadata and structure. Uses file cache.
+// GetBookContent returns the book metadata and structure. Uses the reader cache.
 func (s *ReaderService) GetBookContent(ctx context.Context, bookID int64) (*bookfile.BookContent, error) {
 	// Try cache
-	cached, err := s.getCachedContent(bookID)
+	cached, err := s.getCachedContent(ctx, bookID)
 	if err == nil {
-		s.touchCache(bookID)
+		s.cacheHit(ctx, bookID)
 		return cached, nil
 	}
+	s.cacheMisses.Add(1)
 
 	// Parse book (deduplicated via singleflight)
 	conv, err := s.parseBookOnce(ctx, bookID)
@@ -77,23 +94,24 @@ func (s *ReaderService) GetBookContent(ctx context.Context, bookID int64) (*book
 	content := conv.Content()
 
 	// Cache content
-	_ = s.cacheContent(bookID, content)
+	_ = s.cacheContent(ctx, bookID, content)
 
 	return content, nil
 }
 
-// GetChapter returns the HTML content of a specific chapter. Uses file cache.
+// GetChapter returns the HTML content of a specific chapter. Uses the reader cache.
 func (s *ReaderService) GetChapter(ctx context.Context, bookID int64, chapterID string) (*bookfile.ChapterContent, error) {
 	if err := ValidateResourceID(chapterID); err != nil {
 		return nil, err
 	}
 
 	// Try cache
-	cached, err := s.getCachedChapter(bookID, chapterID)
+	cached, err := s.getCachedChapter(ctx, bookID, chapterID)
 	if err == nil {
-		s.touchCache(bookID)
+		s.cacheHit(ctx, bookID)
 		return cached, nil
 	}
+	s.cacheMisses.Add(1)
 
 	// Parse book (deduplicated via singleflight)
 	conv, err := s.parseBookOnce(ctx, bookID)
@@ -107,23 +125,30 @@ func (s *ReaderService) GetChapter(ctx context.Context, bookID int64, chapterID
 	}
 
 	// Cache chapter
-	_ = s.cacheChapter(bookID, ch)
+	_ = s.cacheChapter(ctx, bookID, ch)
 
 	return ch, nil
 }
 
-// GetBookImage returns an embedded image from the book. Uses file cache.
-func (s *ReaderService) GetBookImage(ctx context.Context, bookID int64, imageID string) (*bookfile.ImageData, error) {
+// GetBookImage returns an embedded image from the book, transcoded with
+// opts unless they are zero. Images that cannot be transcoded (e.g. SVG) are
+// returned as is. Uses the reader cache for originals and variants.
+func (s *ReaderService) GetBookImage(ctx context.Context, bookID int64, imageID string, opts bookfile.ImageOptions) (*bookfile.ImageData, error) {
 	if err := ValidateResourceID(imageID); err != nil {
 		return nil, err
 	}
+	opts = opts.Normalize()
 
 	// Try cache
-	cached, err := s.getCachedImage(bookID, imageID)
+	cached, err := s.getCachedImage(ctx, bookID, imageID, opts)
 	if err == nil {
-		s.touchCache(bookID)
+		s.cacheHit(ctx, bookID)
 		return cached, nil
 	}
+	s.cacheMisses.Add(1)
+	if !opts.IsZero() {
+		return s.transcodeImageOnce(ctx, bookID, imageID, opts)
+	}
 
 	// Parse book (deduplicated via singleflight)
 	conv, err := s.parseBookOnce(ctx, bookID)
@@ -137,257 +162,278 @@ func (s *ReaderService) GetBookImage(ctx context.Context, bookID int64, imageID
 	}
 
 	// Cache image
-	_ = s.cacheImage(bookID, img)
+	_ = s.cacheImage(ctx, bookID, img, opts)
 
 	return img, nil
 }
 
-// parseBookOnce deduplicates concurrent parseBook calls for the same bookID
-// via singleflight, so multiple readers of the same book share one parse.
-func (s *ReaderService) parseBookOnce(ctx context.Context, bookID int64) (bookfile.BookConverter, error) {
-	key := fmt.Sprintf("%d", bookID)
-	v, err, _ := s.parseGroup.Do(key, func() (interface{}, error) {
-		return s.parseBook(ctx, bookID)
+// transcodeImageOnce transcodes an image and caches the variant. Concurrent
+// requests for the same variant share one transcoding.
+func (s *ReaderService) transcodeImageOnce(ctx context.Context, bookID int64, imageID string, opts bookfile.ImageOptions) (*bookfile.ImageData, error) {
+	key := fmt.Sprintf("%d/%s", bookID, imageCacheName(imageID, opts))
+	v, err, _ := s.transcodeGroup.Do(key, func() (interface{}, error) {
+		orig, err := s.GetBookImage(ctx, bookID, imageID, bookfile.ImageOptions{})
+		if err != nil {
+			return nil, err
+		}
+
+		img, err := bookfile.TranscodeImage(orig, opts)
+		if errors.Is(err, bookfile.ErrImageNotTranscodable) {
+			s.logger.Debug("serving image as is", "book_id", bookID, "image_id", imageID, "err", err)
+			return orig, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		_ = s.cacheImage(ctx, bookID, img, opts)
+		return img, nil
 	})
 	if err != nil {
 		return nil, err
 	}
-	return v.(bookfile.BookConverter), nil
+	return v.(*bookfile.ImageData), nil
 }
 
-// parseBook extracts the book from archive and parses it.
-func (s *ReaderService) parseBook(ctx context.Context, bookID int64) (bookfile.BookConverter, error) {
-	archiveName, fileInArchive, format, err := s.bookRepo.GetBookForDownload(ctx, bookID)
-	if err != nil {
-		return nil, fmt.Errorf("%w: %w", ErrBookNotFound, err)
+// GetNote returns the HTML content of a footnote. Uses the reader cache.
+// Returns ErrUnsupportedFormat for formats without separate notes.
+func (s *ReaderService) GetNote(ctx context.Context, bookID int64, noteID string) (*bookfile.NoteContent, error) {
+	if err := ValidateResourceID(noteID); err != nil {
+		return nil, err
 	}
 
-	conv, err := bookfile.GetConverter(format)
-	if err != nil {
-		return nil, fmt.Errorf("%w: %s", ErrUnsupportedFormat, format)
+	// Try cache
+	cached, err := s.getCachedNote(ctx, bookID, noteID)
+	if err == nil {
+		s.cacheHit(ctx, bookID)
+		return cached, nil
 	}
+	s.cacheMisses.Add(1)
 
-	archivePath := filepath.Join(s.libCfg.ArchivesPath, archiveName)
-
-	// Path traversal prevention
-	absBasePath, err := filepath.EvalSymlinks(s.libCfg.ArchivesPath)
-	if err != nil {
-		return nil, fmt.Errorf("invalid base path: %w", err)
-	}
-	absArchivePath, err := filepath.EvalSymlinks(archivePath)
+	// Parse book (deduplicated via singleflight)
+	conv, err := s.parseBookOnce(ctx, bookID)
 	if err != nil {
-		return nil, fmt.Errorf("book not found: invalid archive path")
-	}
-	if !strings.HasPrefix(absArchivePath, absBasePath+string(filepath.Separator)) && absArchivePath != absBasePath {
-		return nil, fmt.Errorf("book not found: invalid archive path")
+		return nil, err
 	}
 
-	rc, _, err := archive.ExtractFile(archivePath, fileInArchive)
-	if err != nil {
-		return nil, fmt.Errorf("extract file: %w", err)
+	np, ok := conv.(bookfile.NoteProvider)
+	if !ok {
+		return nil, fmt.Errorf("%w: no footnote support", ErrUnsupportedFormat)
 	}
-	defer func() { _ = rc.Close() }()
-
-	data, err := io.ReadAll(io.LimitReader(rc, maxBookFileSize))
+	note, err := np.Note(noteID)
 	if err != nil {
-		return nil, fmt.Errorf("read file: %w", err)
+		return nil, fmt.Errorf("%w: note %q: %w", ErrBookNotFound, noteID, err)
 	}
 
-	if err := conv.Parse(data, bookID); err != nil {
-		return nil, fmt.Errorf("%w: %w", ErrMalformedFile, err)
-	}
+	// Cache note
+	_ = s.cacheNote(ctx, bookID, note)
 
-	return conv, nil
+	return note, nil
 }
 
-// --- File cache ---
+// maxBookSearchHits bounds the number of matches collected by one in-book
+// search.
+const maxBookSearchHits = 1000
 
-// atomicWriteFile writes data to a temporary file and renames it into place,
-// preventing partial reads on concurrent access.
-func atomicWriteFile(path string, data []byte, perm os.FileMode) error {
-	tmp := path + ".tmp"
-	if err := os.WriteFile(tmp, data, perm); err != nil {
-		return err
+// SearchBook finds the query in the text of a book, ignoring case and the
+// difference between ё and е. Returns one page of hits in reading order and
+// the total number of hits, capped at maxBookSearchHits.
+func (s *ReaderService) SearchBook(ctx context.Context, bookID int64, f models.BookSearchFilter) ([]models.BookSearchHit, int, error) {
+	text, err := s.getBookText(ctx, bookID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	hits := []models.BookSearchHit{}
+	total := 0
+	for _, ch := range text.Chapters {
+		for _, m := range bookfile.FindMatches(ch.Paragraphs, f.Query, maxBookSearchHits-total) {
+			if total >= f.Offset() && len(hits) < f.Limit {
+				hits = append(hits, models.BookSearchHit{
+					ChapterID:    ch.ID,
+					ChapterTitle: ch.Title,
+					Locator:      models.Locator{Paragraph: m.Paragraph, Offset: m.Offset},
+					Snippet:      m.Snippet,
+					MatchOffset:  m.SnippetOffset,
+					MatchLength:  m.Length,
+				})
+			}
+			total++
+		}
+		if total >= maxBookSearchHits {
+			break
+		}
 	}
-	return os.Rename(tmp, path)
+	return hits, total, nil
 }
 
-func (s *ReaderService) bookCacheDir(bookID int64) string {
-	return filepath.Join(s.cachePath, fmt.Sprintf("%d", bookID))
+// bookText is the plain text of the chapters of a book, kept for in-book
+// search.
+type bookText struct {
+	FormatVersion int                    `json:"formatVersion"`
+	Chapters      []bookfile.ChapterText `json:"chapters"`
 }
 
-func (s *ReaderService) ensureCacheDir(bookID int64) error {
-	return os.MkdirAll(s.bookCacheDir(bookID), 0o755)
-}
-
-// Content cache
+// getBookText returns the plain text of a book, extracting it from the
+// chapters on first use. Uses the reader cache.
+func (s *ReaderService) getBookText(ctx context.Context, bookID int64) (*bookText, error) {
+	cached, err := s.getCachedText(ctx, bookID)
+	if err == nil {
+		s.cacheHit(ctx, bookID)
+		return cached, nil
+	}
+	s.cacheMisses.Add(1)
 
-func (s *ReaderService) getCachedContent(bookID int64) (*bookfile.BookContent, error) {
-	path := filepath.Join(s.bookCacheDir(bookID), "content.json")
-	data, err := os.ReadFile(path)
+	content, err := s.GetBookContent(ctx, bookID)
 	if err != nil {
 		return nil, err
 	}
-	var content bookfile.BookContent
-	if err := json.Unmarshal(data, &content); err != nil {
-		return nil, err
+
+	text := &bookText{FormatVersion: bookfile.FormatVersion, Chapters: make([]bookfile.ChapterText, 0, len(content.ChapterIDs))}
+	for _, id := range content.ChapterIDs {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		ch, err := s.GetChapter(ctx, bookID, id)
+		if err != nil {
+			return nil, err
+		}
+		text.Chapters = append(text.Chapters, bookfile.ChapterText{
+			ID:         ch.ID,
+			Title:      ch.Title,
+			Paragraphs: bookfile.ParagraphTexts(ch.HTML),
+		})
 	}
-	return &content, nil
+
+	// Cache text
+	_ = s.cacheText(ctx, bookID, text)
+
+	return text, nil
 }
 
-func (s *ReaderService) cacheContent(bookID int64, content *bookfile.BookContent) error {
-	if err := s.ensureCacheDir(bookID); err != nil {
-		return err
+// ExtractText returns the plain text of a book read straight from its file,
+// bypassing the reader cache. Used by the content indexer, which visits
+// every book once.
+func (s *ReaderService) ExtractText(ctx context.Context, bookID int64) ([]bookfile.ChapterText, error) {
+	conv, err := s.parseBook(ctx, bookID)
+	if err != nil {
+		return nil, err
 	}
-	data, err := json.Marshal(content)
+	chapters, err := bookfile.ExtractText(conv)
 	if err != nil {
-		return err
+		return nil, fmt.Errorf("%w: %w", ErrMalformedFile, err)
 	}
-	return atomicWriteFile(filepath.Join(s.bookCacheDir(bookID), "content.json"), data, 0o644)
+	return chapters, nil
 }
 
-// Chapter cache
-
-func (s *ReaderService) getCachedChapter(bookID int64, chapterID string) (*bookfile.ChapterContent, error) {
-	path := filepath.Join(s.bookCacheDir(bookID), fmt.Sprintf("ch_%s.html", chapterID))
-	data, err := os.ReadFile(path)
+// XPointer returns the KOReader XPointer of a paragraph in a chapter.
+// Returns ErrUnsupportedFormat for formats KOReader does not address by
+// document structure.
+func (s *ReaderService) XPointer(ctx context.Context, bookID int64, chapterID string, paragraph int) (string, error) {
+	m, err := s.getXPointerMap(ctx, bookID)
 	if err != nil {
-		return nil, err
+		return "", err
 	}
-	var ch bookfile.ChapterContent
-	if err := json.Unmarshal(data, &ch); err != nil {
-		return nil, err
-	}
-	return &ch, nil
+	return m.XPointer(chapterID, paragraph)
 }
 
-func (s *ReaderService) cacheChapter(bookID int64, ch *bookfile.ChapterContent) error {
-	if err := s.ensureCacheDir(bookID); err != nil {
-		return err
-	}
-	data, err := json.Marshal(ch)
+// ResolveXPointer returns the chapter and paragraph a KOReader XPointer
+// points into.
+func (s *ReaderService) ResolveXPointer(ctx context.Context, bookID int64, xpointer string) (string, int, error) {
+	m, err := s.getXPointerMap(ctx, bookID)
 	if err != nil {
-		return err
+		return "", 0, err
 	}
-	return atomicWriteFile(filepath.Join(s.bookCacheDir(bookID), fmt.Sprintf("ch_%s.html", ch.ID)), data, 0o644)
+	return m.ResolveXPointer(xpointer)
 }
 
-// Image cache
+// getXPointerMap returns the XPointer map of a book, building it from the
+// parsed book on first use. Uses the reader cache, so that KOReader syncs
+// do not parse the book.
+func (s *ReaderService) getXPointerMap(ctx context.Context, bookID int64) (*bookfile.XPointerMap, error) {
+	cached, err := s.getCachedXPointerMap(ctx, bookID)
+	if err == nil {
+		s.cacheHit(ctx, bookID)
+		return cached, nil
+	}
+	s.cacheMisses.Add(1)
 
-func (s *ReaderService) getCachedImage(bookID int64, imageID string) (*bookfile.ImageData, error) {
-	dir := s.bookCacheDir(bookID)
+	// Check the format first: books KOReader cannot address are not parsed
+	_, _, format, err := s.bookRepo.GetBookForDownload(ctx, bookID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrBookNotFound, err)
+	}
+	if !bookfile.SupportsXPointer(format) {
+		return nil, fmt.Errorf("%w: no XPointer support for %s", ErrUnsupportedFormat, format)
+	}
 
-	// Read metadata
-	metaPath := filepath.Join(dir, fmt.Sprintf("img_%s.meta", imageID))
-	contentType, err := os.ReadFile(metaPath)
+	conv, err := s.parseBookOnce(ctx, bookID)
 	if err != nil {
 		return nil, err
 	}
+	mapper, ok := conv.(bookfile.XPointerMapper)
+	if !ok {
+		return nil, fmt.Errorf("%w: no XPointer support", ErrUnsupportedFormat)
+	}
+	m := mapper.XPointerMap()
+	_ = s.cacheXPointerMap(ctx, bookID, m)
+	return m, nil
+}
 
-	// Read binary
-	dataPath := filepath.Join(dir, fmt.Sprintf("img_%s.bin", imageID))
-	data, err := os.ReadFile(dataPath)
+// parseBookOnce deduplicates concurrent parseBook calls for the same bookID
+// via singleflight, so multiple readers of the same book share one parse.
+func (s *ReaderService) parseBookOnce(ctx context.Context, bookID int64) (bookfile.BookConverter, error) {
+	key := fmt.Sprintf("%d", bookID)
+	v, err, _ := s.parseGroup.Do(key, func() (interface{}, error) {
+		return s.parseBook(ctx, bookID)
+	})
 	if err != nil {
 		return nil, err
 	}
-
-	return &bookfile.ImageData{
-		ID:          imageID,
-		ContentType: string(contentType),
-		Data:        data,
-	}, nil
+	return v.(bookfile.BookConverter), nil
 }
 
-func (s *ReaderService) cacheImage(bookID int64, img *bookfile.ImageData) error {
-	if err := s.ensureCacheDir(bookID); err != nil {
-		return err
+// parseBook extracts the book from archive and parses it.
+func (s *ReaderService) parseBook(ctx context.Context, bookID int64) (bookfile.BookConverter, error) {
+	archiveName, fileInArchive, format, err := s.bookRepo.GetBookForDownload(ctx, bookID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrBookNotFound, err)
 	}
-	dir := s.bookCacheDir(bookID)
 
-	// Write metadata
-	metaPath := filepath.Join(dir, fmt.Sprintf("img_%s.meta", img.ID))
-	if err := atomicWriteFile(metaPath, []byte(img.ContentType), 0o644); err != nil {
-		return err
+	conv, err := bookfile.GetConverter(format)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedFormat, format)
 	}
 
-	// Write binary
-	dataPath := filepath.Join(dir, fmt.Sprintf("img_%s.bin", img.ID))
-	return atomicWriteFile(dataPath, img.Data, 0o644)
-}
-
-// touchCache updates the modification time of the book cache directory on access.
-func (s *ReaderService) touchCache(bookID int64) {
-	dir := s.bookCacheDir(bookID)
-	now := time.Now()
-	_ = os.Chtimes(dir, now, now)
-}
+	archivePath := filepath.Join(s.libCfg.ArchivesPath, archiveName)
 
-// CleanupExpiredCache removes book cache directories not accessed within cacheTTL.
-func (s *ReaderService) CleanupExpiredCache() (int, error) {
-	if s.cacheTTL <= 0 {
-		return 0, nil
+	// Path traversal prevention
+	absBasePath, err := filepath.EvalSymlinks(s.libCfg.ArchivesPath)
+	if err != nil {
+		return nil, fmt.Errorf("invalid base path: %w", err)
 	}
-
-	entries, err := os.ReadDir(s.cachePath)
+	absArchivePath, err := filepath.EvalSymlinks(archivePath)
 	if err != nil {
-		if os.IsNotExist(err) {
-			return 0, nil
-		}
-		return 0, fmt.Errorf("read cache dir: %w", err)
+		return nil, fmt.Errorf("book not found: invalid archive path")
+	}
+	if !strings.HasPrefix(absArchivePath, absBasePath+string(filepath.Separator)) && absArchivePath != absBasePath {
+		return nil, fmt.Errorf("book not found: invalid archive path")
 	}
 
-	cutoff := time.Now().Add(-s.cacheTTL)
-	removed := 0
+	rc, _, err := archive.ExtractFile(archivePath, fileInArchive)
+	if err != nil {
+		return nil, fmt.Errorf("extract file: %w", err)
+	}
+	defer func() { _ = rc.Close() }()
 
-	for _, entry := range entries {
-		if !entry.IsDir() {
-			continue
-		}
-		info, err := entry.Info()
-		if err != nil {
-			continue
-		}
-		if info.ModTime().Before(cutoff) {
-			dirPath := filepath.Join(s.cachePath, entry.Name())
-			if err := os.RemoveAll(dirPath); err != nil {
-				s.logger.Error("cache cleanup: failed to remove dir", "path", dirPath, "err", err)
-				continue
-			}
-			removed++
-		}
+	data, err := io.ReadAll(io.LimitReader(rc, maxBookFileSize))
+	if err != nil {
+		return nil, fmt.Errorf("read file: %w", err)
 	}
 
-	return removed, nil
-}
+	if err := conv.Parse(data, bookID); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrMalformedFile, err)
+	}
 
-// StartCacheCleanup runs periodic cache cleanup in a background goroutine.
-// It stops when the context is cancelled.
-func (s *ReaderService) StartCacheCleanup(ctx context.Context) {
-	if s.cacheTTL <= 0 {
-		return
-	}
-
-	// Run cleanup once on startup
-	if removed, err := s.CleanupExpiredCache(); err != nil {
-		s.logger.Error("cache cleanup error", "err", err)
-	} else if removed > 0 {
-		s.logger.Info("cache cleanup: removed expired entries", "count", removed)
-	}
-
-	ticker := time.NewTicker(1 * time.Hour)
-	go func() {
-		defer ticker.Stop()
-		for {
-			select {
-			case <-ctx.Done():
-				return
-			case <-ticker.C:
-				if removed, err := s.CleanupExpiredCache(); err != nil {
-					s.logger.Error("cache cleanup error", "err", err)
-				} else if removed > 0 {
-					s.logger.Info("cache cleanup: removed expired entries", "count", removed)
-				}
-			}
-		}
-	}()
+	return conv, nil
 }
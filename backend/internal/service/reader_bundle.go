@@ -0,0 +1,121 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"regexp"
+
+	"github.com/grom-alex/homelib/backend/internal/bookfile"
+)
+
+// Offline bundle item types, in the order Bundle emits them.
+const (
+	BundleItemContent = "content"
+	BundleItemChapter = "chapter"
+	BundleItemNote    = "note"
+	BundleItemImage   = "image"
+	BundleItemEnd     = "end"
+)
+
+// BundleItem is one entry of an offline reading bundle. The payload field
+// matching Type is set.
+type BundleItem struct {
+	Type    string                   `json:"type"`
+	Content *bookfile.BookContent    `json:"content,omitempty"`
+	Chapter *bookfile.ChapterContent `json:"chapter,omitempty"`
+	Note    *bookfile.NoteContent    `json:"note,omitempty"`
+	// URL is the address chapters refer to the image by.
+	URL   string              `json:"url,omitempty"`
+	Image *bookfile.ImageData `json:"image,omitempty"`
+	// Totals of the end item, so that clients can tell a complete bundle
+	// from a cut-off stream.
+	Chapters int `json:"chapters,omitempty"`
+	Notes    int `json:"notes,omitempty"`
+	Images   int `json:"images,omitempty"`
+}
+
+var (
+	// bundleImageURL matches image addresses in chapter HTML and captures
+	// the image ID.
+	bundleImageURL = regexp.MustCompile(`/api/books/\d+/image/([A-Za-z0-9._-]+)(?:\?v=[A-Za-z0-9]+)?`)
+	// bundleNoteRef matches footnote references in chapter HTML and
+	// captures the note ID.
+	bundleNoteRef = regexp.MustCompile(`data-note-id="([^"]+)"`)
+)
+
+// Bundle passes the whole book to emit for offline reading: the book
+// content, every chapter, the notes and images they refer to, and an end
+// item with totals. Images are transcoded with opts. Errors before the
+// first item are the same as those of GetBookContent; references to missing
+// notes and images are skipped.
+func (s *ReaderService) Bundle(ctx context.Context, bookID int64, opts bookfile.ImageOptions, emit func(*BundleItem) error) error {
+	content, err := s.GetBookContent(ctx, bookID)
+	if err != nil {
+		return err
+	}
+	if err := emit(&BundleItem{Type: BundleItemContent, Content: content}); err != nil {
+		return err
+	}
+
+	end := &BundleItem{Type: BundleItemEnd}
+	var noteIDs, imageURLs []string
+	seen := make(map[string]bool)
+	collect := func(html string) {
+		for _, m := range bundleNoteRef.FindAllStringSubmatch(html, -1) {
+			if key := "note:" + m[1]; !seen[key] {
+				seen[key] = true
+				noteIDs = append(noteIDs, m[1])
+			}
+		}
+		for _, url := range bundleImageURL.FindAllString(html, -1) {
+			if key := "image:" + url; !seen[key] {
+				seen[key] = true
+				imageURLs = append(imageURLs, url)
+			}
+		}
+	}
+	collect(content.Metadata.Cover)
+
+	for _, id := range content.ChapterIDs {
+		ch, err := s.GetChapter(ctx, bookID, id)
+		if err != nil {
+			return err
+		}
+		if err := emit(&BundleItem{Type: BundleItemChapter, Chapter: ch}); err != nil {
+			return err
+		}
+		end.Chapters++
+		collect(ch.HTML)
+	}
+
+	for _, id := range noteIDs {
+		note, err := s.GetNote(ctx, bookID, id)
+		if errors.Is(err, ErrUnsupportedFormat) || errors.Is(err, ErrBookNotFound) || errors.Is(err, ErrInvalidResourceID) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if err := emit(&BundleItem{Type: BundleItemNote, Note: note}); err != nil {
+			return err
+		}
+		end.Notes++
+	}
+
+	for _, url := range imageURLs {
+		id := bundleImageURL.FindStringSubmatch(url)[1]
+		img, err := s.GetBookImage(ctx, bookID, id, opts)
+		if errors.Is(err, ErrBookNotFound) || errors.Is(err, ErrInvalidResourceID) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if err := emit(&BundleItem{Type: BundleItemImage, URL: url, Image: img}); err != nil {
+			return err
+		}
+		end.Images++
+	}
+
+	return emit(end)
+}
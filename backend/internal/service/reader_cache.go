@@ -0,0 +1,428 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/grom-alex/homelib/backend/internal/bookfile"
+	"github.com/grom-alex/homelib/backend/internal/config"
+	"github.com/grom-alex/homelib/backend/internal/models"
+	"github.com/grom-alex/homelib/backend/internal/readercache"
+	"github.com/grom-alex/homelib/backend/internal/repository"
+)
+
+// cacheLowWatermark is the share of maxCacheSize that LRU eviction frees the
+// cache down to, so that eviction does not rerun on every following write.
+const cacheLowWatermark = 0.9
+
+var (
+	// errCacheMiss marks a file missing from the reader cache.
+	errCacheMiss = errors.New("cache miss")
+	// errStaleCache marks a cache entry written by an older converter version.
+	errStaleCache = errors.New("stale cache entry")
+)
+
+// NewReaderCacheStore creates the reader cache backend selected by
+// cfg.CacheBackend. The Postgres backend stores files in large objects
+// through pool, so that all API replicas share one cache.
+func NewReaderCacheStore(cfg config.ReaderConfig, pool repository.Pool) (readercache.Store, error) {
+	switch cfg.CacheBackend {
+	case config.CacheBackendMemory:
+		return readercache.NewMemory(), nil
+	case config.CacheBackendPostgres:
+		return repository.NewReaderCacheRepo(pool), nil
+	default:
+		return readercache.NewFS(cfg.CachePath)
+	}
+}
+
+// readCache returns a cached file or errCacheMiss.
+func (s *ReaderService) readCache(ctx context.Context, bookID int64, name string) ([]byte, error) {
+	data, found, err := s.cache.Get(ctx, bookID, name)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, errCacheMiss
+	}
+	return data, nil
+}
+
+// writeCache stores a file in the reader cache and starts LRU eviction in
+// the background once the cache outgrows maxCacheSize. The cache size grows
+// by what the backend stored, e.g. the compressed size on disk.
+func (s *ReaderService) writeCache(ctx context.Context, bookID int64, name string, data []byte) error {
+	stored, err := s.cache.Put(ctx, bookID, name, data)
+	if err != nil {
+		return err
+	}
+	size := s.cacheSize.Add(stored)
+	if s.maxCacheSize > 0 && size > s.maxCacheSize && s.evicting.CompareAndSwap(false, true) {
+		go func() {
+			defer s.evicting.Store(false)
+			s.logEviction(s.EvictCache(context.WithoutCancel(ctx)))
+		}()
+	}
+	return nil
+}
+
+// writeCacheJSON stores v as a JSON file in the reader cache.
+func (s *ReaderService) writeCacheJSON(ctx context.Context, bookID int64, name string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return s.writeCache(ctx, bookID, name, data)
+}
+
+// readCacheJSON decodes a JSON file from the reader cache into v.
+func (s *ReaderService) readCacheJSON(ctx context.Context, bookID int64, name string, v any) error {
+	data, err := s.readCache(ctx, bookID, name)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// Content cache
+
+func (s *ReaderService) getCachedContent(ctx context.Context, bookID int64) (*bookfile.BookContent, error) {
+	var content bookfile.BookContent
+	if err := s.readCacheJSON(ctx, bookID, "content.json", &content); err != nil {
+		return nil, err
+	}
+	if content.FormatVersion != bookfile.FormatVersion {
+		return nil, errStaleCache
+	}
+	return &content, nil
+}
+
+func (s *ReaderService) cacheContent(ctx context.Context, bookID int64, content *bookfile.BookContent) error {
+	return s.writeCacheJSON(ctx, bookID, "content.json", content)
+}
+
+// Chapter cache
+
+func (s *ReaderService) getCachedChapter(ctx context.Context, bookID int64, chapterID string) (*bookfile.ChapterContent, error) {
+	var ch bookfile.ChapterContent
+	if err := s.readCacheJSON(ctx, bookID, fmt.Sprintf("ch_%s.html", chapterID), &ch); err != nil {
+		return nil, err
+	}
+	if ch.FormatVersion != bookfile.FormatVersion {
+		return nil, errStaleCache
+	}
+	return &ch, nil
+}
+
+func (s *ReaderService) cacheChapter(ctx context.Context, bookID int64, ch *bookfile.ChapterContent) error {
+	return s.writeCacheJSON(ctx, bookID, fmt.Sprintf("ch_%s.html", ch.ID), ch)
+}
+
+// Note cache
+
+func (s *ReaderService) getCachedNote(ctx context.Context, bookID int64, noteID string) (*bookfile.NoteContent, error) {
+	var note bookfile.NoteContent
+	if err := s.readCacheJSON(ctx, bookID, fmt.Sprintf("note_%s.html", noteID), &note); err != nil {
+		return nil, err
+	}
+	if note.FormatVersion != bookfile.FormatVersion {
+		return nil, errStaleCache
+	}
+	return &note, nil
+}
+
+func (s *ReaderService) cacheNote(ctx context.Context, bookID int64, note *bookfile.NoteContent) error {
+	return s.writeCacheJSON(ctx, bookID, fmt.Sprintf("note_%s.html", note.ID), note)
+}
+
+// Text cache
+
+func (s *ReaderService) getCachedText(ctx context.Context, bookID int64) (*bookText, error) {
+	var text bookText
+	if err := s.readCacheJSON(ctx, bookID, "text.json", &text); err != nil {
+		return nil, err
+	}
+	if text.FormatVersion != bookfile.FormatVersion {
+		return nil, errStaleCache
+	}
+	return &text, nil
+}
+
+func (s *ReaderService) cacheText(ctx context.Context, bookID int64, text *bookText) error {
+	return s.writeCacheJSON(ctx, bookID, "text.json", text)
+}
+
+// XPointer map cache
+
+func (s *ReaderService) getCachedXPointerMap(ctx context.Context, bookID int64) (*bookfile.XPointerMap, error) {
+	var m bookfile.XPointerMap
+	if err := s.readCacheJSON(ctx, bookID, "xpointer.json", &m); err != nil {
+		return nil, err
+	}
+	if m.FormatVersion != bookfile.FormatVersion {
+		return nil, errStaleCache
+	}
+	return &m, nil
+}
+
+func (s *ReaderService) cacheXPointerMap(ctx context.Context, bookID int64, m *bookfile.XPointerMap) error {
+	return s.writeCacheJSON(ctx, bookID, "xpointer.json", m)
+}
+
+// Image cache
+
+// imageCacheName returns the cache file name (without extension) of an
+// image or of its transcoded variant.
+func imageCacheName(imageID string, opts bookfile.ImageOptions) string {
+	if opts.IsZero() {
+		return "img_" + imageID
+	}
+	return "img_" + imageID + "@" + opts.Key()
+}
+
+func (s *ReaderService) getCachedImage(ctx context.Context, bookID int64, imageID string, opts bookfile.ImageOptions) (*bookfile.ImageData, error) {
+	name := imageCacheName(imageID, opts)
+
+	// Read metadata
+	contentType, err := s.readCache(ctx, bookID, name+".meta")
+	if err != nil {
+		return nil, err
+	}
+
+	// Read binary
+	data, err := s.readCache(ctx, bookID, name+".bin")
+	if err != nil {
+		return nil, err
+	}
+
+	return &bookfile.ImageData{
+		ID:          imageID,
+		ContentType: string(contentType),
+		Data:        data,
+	}, nil
+}
+
+func (s *ReaderService) cacheImage(ctx context.Context, bookID int64, img *bookfile.ImageData, opts bookfile.ImageOptions) error {
+	name := imageCacheName(img.ID, opts)
+
+	// Write metadata
+	if err := s.writeCache(ctx, bookID, name+".meta", []byte(img.ContentType)); err != nil {
+		return err
+	}
+
+	// Write binary
+	return s.writeCache(ctx, bookID, name+".bin", img.Data)
+}
+
+// Download cache
+
+// downloadCacheName is the cache file of a book file served for download.
+const downloadCacheName = "download.bin"
+
+// CachedDownload returns the book file kept by an earlier download, so that
+// it need not be extracted from its archive again.
+func (s *ReaderService) CachedDownload(ctx context.Context, bookID int64) ([]byte, bool) {
+	data, err := s.readCache(ctx, bookID, downloadCacheName)
+	if err != nil {
+		s.cacheMisses.Add(1)
+		return nil, false
+	}
+	s.cacheHit(ctx, bookID)
+	return data, true
+}
+
+// CacheDownload keeps a downloaded book file in the reader cache, subject
+// to the same size limit and LRU eviction as the rest of the cache.
+func (s *ReaderService) CacheDownload(ctx context.Context, bookID int64, data []byte) {
+	if err := s.writeCache(ctx, bookID, downloadCacheName, data); err != nil {
+		s.logger.Warn("failed to cache download", "book_id", bookID, "err", err)
+	}
+}
+
+// cacheHit counts a cache hit and marks the book cache as recently used.
+func (s *ReaderService) cacheHit(ctx context.Context, bookID int64) {
+	s.cacheHits.Add(1)
+	_ = s.cache.Touch(ctx, bookID)
+}
+
+// --- Cache maintenance ---
+
+// CleanupExpiredCache removes book caches not accessed within cacheTTL.
+func (s *ReaderService) CleanupExpiredCache(ctx context.Context) (int, error) {
+	if s.cacheTTL <= 0 {
+		return 0, nil
+	}
+
+	s.cacheMu.Lock()
+	defer s.cacheMu.Unlock()
+
+	entries, err := s.cache.List(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	cutoff := time.Now().Add(-s.cacheTTL)
+	removed := 0
+
+	for _, e := range entries {
+		if !e.Accessed.Before(cutoff) {
+			continue
+		}
+		if _, err := s.cache.Delete(ctx, e.BookID); err != nil {
+			s.logger.Error("cache cleanup: failed to remove book", "book_id", e.BookID, "err", err)
+			continue
+		}
+		removed++
+	}
+
+	return removed, nil
+}
+
+// EvictCache removes the least recently used book caches while the cache
+// exceeds maxCacheSize. Returns the number of removed books.
+func (s *ReaderService) EvictCache(ctx context.Context) (int, error) {
+	if s.maxCacheSize <= 0 {
+		return 0, nil
+	}
+
+	s.cacheMu.Lock()
+	defer s.cacheMu.Unlock()
+
+	entries, err := s.cache.List(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	var total int64
+	for _, e := range entries {
+		total += e.Size
+	}
+	if total <= s.maxCacheSize {
+		s.cacheSize.Store(total)
+		return 0, nil
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Accessed.Before(entries[j].Accessed) })
+
+	target := int64(float64(s.maxCacheSize) * cacheLowWatermark)
+	removed := 0
+	for _, e := range entries {
+		if total <= target {
+			break
+		}
+		if _, err := s.cache.Delete(ctx, e.BookID); err != nil {
+			s.logger.Error("cache eviction: failed to remove book", "book_id", e.BookID, "err", err)
+			continue
+		}
+		total -= e.Size
+		removed++
+	}
+	s.cacheSize.Store(total)
+
+	return removed, nil
+}
+
+// CacheStats returns the cache size and the hit ratio.
+func (s *ReaderService) CacheStats(ctx context.Context) (*models.ReaderCacheStats, error) {
+	entries, err := s.cache.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &models.ReaderCacheStats{
+		MaxSizeBytes: s.maxCacheSize,
+		Entries:      len(entries),
+		Hits:         s.cacheHits.Load(),
+		Misses:       s.cacheMisses.Load(),
+	}
+	for _, e := range entries {
+		stats.SizeBytes += e.Size
+		stats.Files += e.Files
+	}
+	s.cacheSize.Store(stats.SizeBytes)
+
+	if lookups := stats.Hits + stats.Misses; lookups > 0 {
+		stats.HitRatio = float64(stats.Hits) / float64(lookups)
+	}
+	return stats, nil
+}
+
+// PurgeBookCache removes the cache of a single book.
+// Returns false if the book was not cached.
+func (s *ReaderService) PurgeBookCache(ctx context.Context, bookID int64) (bool, error) {
+	s.cacheMu.Lock()
+	defer s.cacheMu.Unlock()
+
+	return s.cache.Delete(ctx, bookID)
+}
+
+// PurgeCache removes the caches of all books. Returns the number of removed books.
+func (s *ReaderService) PurgeCache(ctx context.Context) (int, error) {
+	s.cacheMu.Lock()
+	defer s.cacheMu.Unlock()
+
+	entries, err := s.cache.List(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	removed := 0
+	var total int64
+	for _, e := range entries {
+		if _, err := s.cache.Delete(ctx, e.BookID); err != nil {
+			s.logger.Error("cache purge: failed to remove book", "book_id", e.BookID, "err", err)
+			total += e.Size
+			continue
+		}
+		removed++
+	}
+	s.cacheSize.Store(total)
+
+	return removed, nil
+}
+
+// StartCacheCleanup runs periodic cache cleanup in a background goroutine:
+// expired entries are removed first, then the least recently used ones
+// while the cache exceeds maxCacheSize. It stops when the context is cancelled.
+func (s *ReaderService) StartCacheCleanup(ctx context.Context) {
+	if s.cacheTTL <= 0 && s.maxCacheSize <= 0 {
+		return
+	}
+
+	// Run cleanup once on startup
+	s.runCacheCleanup(ctx)
+
+	ticker := time.NewTicker(1 * time.Hour)
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				s.runCacheCleanup(ctx)
+			}
+		}
+	}()
+}
+
+func (s *ReaderService) runCacheCleanup(ctx context.Context) {
+	if removed, err := s.CleanupExpiredCache(ctx); err != nil {
+		s.logger.Error("cache cleanup error", "err", err)
+	} else if removed > 0 {
+		s.logger.Info("cache cleanup: removed expired entries", "count", removed)
+	}
+	s.logEviction(s.EvictCache(ctx))
+}
+
+func (s *ReaderService) logEviction(removed int, err error) {
+	if err != nil {
+		s.logger.Error("cache eviction error", "err", err)
+	} else if removed > 0 {
+		s.logger.Info("cache eviction: removed least recently used entries", "count", removed)
+	}
+}
@@ -0,0 +1,186 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"io"
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grom-alex/homelib/backend/internal/readercache"
+)
+
+// fillCache caches a file of about the given size on disk for a book and
+// sets the book's last access time. Random data does not compress.
+func fillCache(t *testing.T, svc *ReaderService, bookID int64, size int, accessed time.Time) {
+	t.Helper()
+	data := make([]byte, size)
+	_, _ = rand.Read(data)
+	_, err := svc.cache.Put(context.Background(), bookID, "content.json", data)
+	require.NoError(t, err)
+	require.NoError(t, os.Chtimes(bookCacheDir(svc, bookID), accessed, accessed))
+}
+
+func newCacheTestService(t *testing.T, maxSize int64) *ReaderService {
+	t.Helper()
+	store, err := readercache.NewFS(t.TempDir())
+	require.NoError(t, err)
+	return &ReaderService{
+		cache:        store,
+		maxCacheSize: maxSize,
+		logger:       slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}
+}
+
+func TestReaderService_EvictCache_RemovesLeastRecentlyUsed(t *testing.T) {
+	svc := newCacheTestService(t, 2500)
+	now := time.Now()
+	fillCache(t, svc, 1, 1000, now.Add(-3*time.Hour))
+	fillCache(t, svc, 2, 1000, now.Add(-1*time.Hour))
+	fillCache(t, svc, 3, 1000, now.Add(-2*time.Hour))
+
+	removed, err := svc.EvictCache(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, removed)
+
+	_, statErr := os.Stat(bookCacheDir(svc, 1))
+	assert.True(t, os.IsNotExist(statErr))
+	assert.DirExists(t, bookCacheDir(svc, 2))
+	assert.DirExists(t, bookCacheDir(svc, 3))
+	assert.InDelta(t, 2000, svc.cacheSize.Load(), 100)
+}
+
+func TestReaderService_EvictCache_FreesToLowWatermark(t *testing.T) {
+	svc := newCacheTestService(t, 3000)
+	now := time.Now()
+	fillCache(t, svc, 1, 1000, now.Add(-3*time.Hour))
+	fillCache(t, svc, 2, 1000, now.Add(-2*time.Hour))
+	fillCache(t, svc, 3, 1100, now.Add(-1*time.Hour))
+
+	// About 3100 bytes exceed the limit; evicting book 1 leaves about
+	// 2100 bytes, below the 2700-byte low watermark.
+	removed, err := svc.EvictCache(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, removed)
+	assert.DirExists(t, bookCacheDir(svc, 2))
+}
+
+func TestReaderService_EvictCache_UnderLimit(t *testing.T) {
+	svc := newCacheTestService(t, 10000)
+	fillCache(t, svc, 1, 1000, time.Now())
+
+	removed, err := svc.EvictCache(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 0, removed)
+	assert.InDelta(t, 1000, svc.cacheSize.Load(), 50)
+}
+
+func TestReaderService_EvictCache_Unlimited(t *testing.T) {
+	svc := newCacheTestService(t, 0)
+	fillCache(t, svc, 1, 1000, time.Now())
+
+	removed, err := svc.EvictCache(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 0, removed)
+	assert.DirExists(t, bookCacheDir(svc, 1))
+}
+
+func TestReaderService_WriteCacheFile_EvictsOverLimit(t *testing.T) {
+	svc := newCacheTestService(t, 1500)
+	fillCache(t, svc, 1, 1000, time.Now().Add(-time.Hour))
+	svc.cacheSize.Store(1000)
+
+	data := make([]byte, 1000)
+	_, _ = rand.Read(data)
+	require.NoError(t, svc.writeCache(context.Background(), 2, "content.json", data))
+
+	assert.Eventually(t, func() bool {
+		_, err := os.Stat(bookCacheDir(svc, 1))
+		return os.IsNotExist(err) && !svc.evicting.Load()
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.DirExists(t, bookCacheDir(svc, 2))
+}
+
+func TestReaderService_WriteCacheFile_CountsStoredBytes(t *testing.T) {
+	svc := newCacheTestService(t, 0)
+
+	html := bytes.Repeat([]byte("<p>Глава первая</p>\n"), 500)
+	require.NoError(t, svc.writeCache(context.Background(), 1, "ch_1.html", html))
+
+	entries, err := svc.cache.List(context.Background())
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, entries[0].Size, svc.cacheSize.Load())
+	assert.Less(t, svc.cacheSize.Load(), int64(len(html)))
+}
+
+func TestReaderService_CacheStats(t *testing.T) {
+	repo := &mockBookRepo{
+		archiveName:   "test.zip",
+		fileInArchive: "book.fb2",
+		format:        "fb2",
+	}
+	svc, archivesDir := setupReaderService(t, repo)
+	svc.maxCacheSize = 1 << 30
+	createTestArchive(t, archivesDir, "test.zip", "book.fb2", simpleFB2)
+
+	// Miss, then hit
+	_, err := svc.GetBookContent(context.Background(), 1)
+	require.NoError(t, err)
+	_, err = svc.GetBookContent(context.Background(), 1)
+	require.NoError(t, err)
+
+	stats, err := svc.CacheStats(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, stats.Entries)
+	assert.Equal(t, 1, stats.Files)
+	assert.Positive(t, stats.SizeBytes)
+	assert.Equal(t, int64(1<<30), stats.MaxSizeBytes)
+	assert.Equal(t, int64(1), stats.Hits)
+	assert.Equal(t, int64(1), stats.Misses)
+	assert.InDelta(t, 0.5, stats.HitRatio, 1e-9)
+}
+
+func TestReaderService_CacheStats_NoCacheDir(t *testing.T) {
+	store, err := readercache.NewFS("/nonexistent/path")
+	require.NoError(t, err)
+	svc := &ReaderService{cache: store}
+	stats, err := svc.CacheStats(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 0, stats.Entries)
+	assert.Zero(t, stats.HitRatio)
+}
+
+func TestReaderService_PurgeBookCache(t *testing.T) {
+	svc := newCacheTestService(t, 0)
+	fillCache(t, svc, 1, 1000, time.Now())
+	fillCache(t, svc, 2, 1000, time.Now())
+
+	found, err := svc.PurgeBookCache(context.Background(), 1)
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.NoDirExists(t, bookCacheDir(svc, 1))
+	assert.DirExists(t, bookCacheDir(svc, 2))
+
+	found, err = svc.PurgeBookCache(context.Background(), 1)
+	require.NoError(t, err)
+	assert.False(t, found)
+}
+
+func TestReaderService_PurgeCache(t *testing.T) {
+	svc := newCacheTestService(t, 0)
+	fillCache(t, svc, 1, 1000, time.Now())
+	fillCache(t, svc, 2, 1000, time.Now())
+
+	removed, err := svc.PurgeCache(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 2, removed)
+	assert.NoDirExists(t, bookCacheDir(svc, 1))
+	assert.Equal(t, int64(0), svc.cacheSize.Load())
+}
@@ -2,9 +2,15 @@ package service
 
 import (
 	"archive/zip"
+	"bytes"
 	"context"
+	"encoding/base64"
 	"errors"
 	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"testing"
@@ -13,7 +19,11 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/grom-alex/homelib/backend/internal/bookfile"
 	"github.com/grom-alex/homelib/backend/internal/config"
+	"github.com/grom-alex/homelib/backend/internal/models"
+	"github.com/grom-alex/homelib/backend/internal/readercache"
+	"github.com/grom-alex/homelib/backend/internal/repository"
 )
 
 // --- Mock BookRepo ---
@@ -72,15 +82,25 @@ func setupReaderService(t *testing.T, repo bookDownloadInfoProvider) (*ReaderSer
 	archivesDir := filepath.Join(tmpDir, "archives")
 	require.NoError(t, os.MkdirAll(archivesDir, 0o755))
 
+	store, err := readercache.NewFS(cacheDir)
+	require.NoError(t, err)
+
 	svc := &ReaderService{
-		bookRepo:  repo,
-		libCfg:    config.LibraryConfig{ArchivesPath: archivesDir},
-		cachePath: cacheDir,
-		cacheTTL:  30 * 24 * time.Hour,
+		bookRepo: repo,
+		libCfg:   config.LibraryConfig{ArchivesPath: archivesDir},
+		cache:    store,
+		cacheTTL: 30 * 24 * time.Hour,
+		logger:   slog.Default(),
 	}
 	return svc, archivesDir
 }
 
+// bookCacheDir returns the cache directory of a book for services set up
+// by setupReaderService.
+func bookCacheDir(svc *ReaderService, bookID int64) string {
+	return svc.cache.(*readercache.FS).BookDir(bookID)
+}
+
 // --- Tests ---
 
 func TestReaderService_GetBookContent_Success(t *testing.T) {
@@ -117,7 +137,7 @@ func TestReaderService_GetBookContent_CacheHit(t *testing.T) {
 	require.NoError(t, err)
 
 	// Verify cache file exists
-	cachePath := filepath.Join(svc.cachePath, "1", "content.json")
+	cachePath := filepath.Join(bookCacheDir(svc, 1), "content.json.zst")
 	_, statErr := os.Stat(cachePath)
 	assert.NoError(t, statErr, "cache file should exist")
 
@@ -217,6 +237,139 @@ func TestReaderService_GetChapter_NotFound(t *testing.T) {
 	assert.Contains(t, err.Error(), "not found")
 }
 
+const notesFB2 = `<?xml version="1.0" encoding="UTF-8"?>
+<FictionBook xmlns="http://www.gribuser.ru/xml/fictionbook/2.0" xmlns:l="http://www.w3.org/1999/xlink">
+  <description>
+    <title-info>
+      <book-title>Notes Book</book-title>
+      <lang>ru</lang>
+    </title-info>
+  </description>
+  <body>
+    <section>
+      <p>Текст<a l:href="#n1" type="note">1</a></p>
+    </section>
+  </body>
+  <body name="notes">
+    <section id="n1">
+      <title><p>1</p></title>
+      <p>Текст сноски</p>
+    </section>
+  </body>
+</FictionBook>`
+
+func TestReaderService_GetNote_CacheHit(t *testing.T) {
+	repo := &mockBookRepo{
+		archiveName:   "test.zip",
+		fileInArchive: "book.fb2",
+		format:        "fb2",
+	}
+	svc, archivesDir := setupReaderService(t, repo)
+	createTestArchive(t, archivesDir, "test.zip", "book.fb2", notesFB2)
+
+	// First call — parse
+	note1, err := svc.GetNote(context.Background(), 1, "n1")
+	require.NoError(t, err)
+	assert.Equal(t, "1", note1.Title)
+	assert.Contains(t, note1.HTML, "Текст сноски")
+
+	_, err = os.Stat(filepath.Join(bookCacheDir(svc, 1), "note_n1.html.zst"))
+	require.NoError(t, err)
+
+	// Delete archive
+	require.NoError(t, os.Remove(filepath.Join(archivesDir, "test.zip")))
+
+	// Second call — cache hit
+	note2, err := svc.GetNote(context.Background(), 1, "n1")
+	require.NoError(t, err)
+	assert.Equal(t, note1.HTML, note2.HTML)
+}
+
+func TestReaderService_GetNote_NotFound(t *testing.T) {
+	repo := &mockBookRepo{
+		archiveName:   "test.zip",
+		fileInArchive: "book.fb2",
+		format:        "fb2",
+	}
+	svc, archivesDir := setupReaderService(t, repo)
+	createTestArchive(t, archivesDir, "test.zip", "book.fb2", notesFB2)
+
+	_, err := svc.GetNote(context.Background(), 1, "n2")
+	assert.ErrorIs(t, err, ErrBookNotFound)
+}
+
+func TestReaderService_GetNote_InvalidID(t *testing.T) {
+	svc, _ := setupReaderService(t, &mockBookRepo{})
+
+	_, err := svc.GetNote(context.Background(), 1, "../n1")
+	assert.ErrorIs(t, err, ErrInvalidResourceID)
+}
+
+const searchFB2 = `<?xml version="1.0" encoding="UTF-8"?>
+<FictionBook xmlns="http://www.gribuser.ru/xml/fictionbook/2.0">
+  <description>
+    <title-info>
+      <book-title>Search Book</book-title>
+      <lang>ru</lang>
+    </title-info>
+  </description>
+  <body>
+    <section>
+      <title><p>Первая</p></title>
+      <p>Ёжик шёл по лесу.</p>
+    </section>
+    <section>
+      <title><p>Вторая</p></title>
+      <p>Тут ничего.</p>
+      <p>Ещё один ЕЖИК и ёжик.</p>
+    </section>
+  </body>
+</FictionBook>`
+
+func TestReaderService_SearchBook(t *testing.T) {
+	repo := &mockBookRepo{
+		archiveName:   "test.zip",
+		fileInArchive: "book.fb2",
+		format:        "fb2",
+	}
+	svc, archivesDir := setupReaderService(t, repo)
+	createTestArchive(t, archivesDir, "test.zip", "book.fb2", searchFB2)
+
+	f := models.BookSearchFilter{Query: "ежик"}
+	f.SetDefaults()
+	hits, total, err := svc.SearchBook(context.Background(), 1, f)
+	require.NoError(t, err)
+
+	assert.Equal(t, 3, total)
+	require.Len(t, hits, 3)
+	assert.Equal(t, "Первая", hits[0].ChapterTitle)
+	assert.Equal(t, models.Locator{Paragraph: 1, Offset: 0}, hits[0].Locator)
+	assert.Equal(t, "Ёжик шёл по лесу.", hits[0].Snippet)
+	assert.Equal(t, 4, hits[0].MatchLength)
+	assert.Equal(t, "Вторая", hits[1].ChapterTitle)
+	assert.Equal(t, models.Locator{Paragraph: 2, Offset: 9}, hits[1].Locator)
+	assert.Equal(t, models.Locator{Paragraph: 2, Offset: 16}, hits[2].Locator)
+
+	// Plain text is cached: search works without the archive
+	require.NoError(t, os.Remove(filepath.Join(archivesDir, "test.zip")))
+
+	f = models.BookSearchFilter{Query: "ЁЖИК", Page: 2, Limit: 2}
+	f.SetDefaults()
+	hits, total, err = svc.SearchBook(context.Background(), 1, f)
+	require.NoError(t, err)
+	assert.Equal(t, 3, total)
+	require.Len(t, hits, 1)
+	assert.Equal(t, models.Locator{Paragraph: 2, Offset: 16}, hits[0].Locator)
+}
+
+func TestReaderService_SearchBook_BookNotFound(t *testing.T) {
+	repo := &mockBookRepo{err: fmt.Errorf("not found")}
+	svc, _ := setupReaderService(t, repo)
+
+	_, _, err := svc.SearchBook(context.Background(), 999, models.BookSearchFilter{Query: "x", Page: 1, Limit: 20})
+	assert.ErrorIs(t, err, ErrBookNotFound)
+}
+
 func TestReaderService_GetBookImage_Success(t *testing.T) {
 	fb2WithImage := `<?xml version="1.0" encoding="UTF-8"?>
 <FictionBook xmlns="http://www.gribuser.ru/xml/fictionbook/2.0">
@@ -240,7 +393,7 @@ func TestReaderService_GetBookImage_Success(t *testing.T) {
 	svc, archivesDir := setupReaderService(t, repo)
 	createTestArchive(t, archivesDir, "test.zip", "book.fb2", fb2WithImage)
 
-	img, err := svc.GetBookImage(context.Background(), 1, "img1.png")
+	img, err := svc.GetBookImage(context.Background(), 1, "img1.png", bookfile.ImageOptions{})
 	require.NoError(t, err)
 
 	assert.Equal(t, "img1.png", img.ID)
@@ -269,14 +422,14 @@ func TestReaderService_GetBookImage_CacheHit(t *testing.T) {
 	createTestArchive(t, archivesDir, "test.zip", "book.fb2", fb2WithImage)
 
 	// First call
-	img1, err := svc.GetBookImage(context.Background(), 1, "img1.png")
+	img1, err := svc.GetBookImage(context.Background(), 1, "img1.png", bookfile.ImageOptions{})
 	require.NoError(t, err)
 
 	// Delete archive
 	require.NoError(t, os.Remove(filepath.Join(archivesDir, "test.zip")))
 
 	// Second call — cache hit
-	img2, err := svc.GetBookImage(context.Background(), 1, "img1.png")
+	img2, err := svc.GetBookImage(context.Background(), 1, "img1.png", bookfile.ImageOptions{})
 	require.NoError(t, err)
 
 	assert.Equal(t, img1.ContentType, img2.ContentType)
@@ -292,11 +445,74 @@ func TestReaderService_GetBookImage_NotFound(t *testing.T) {
 	svc, archivesDir := setupReaderService(t, repo)
 	createTestArchive(t, archivesDir, "test.zip", "book.fb2", simpleFB2)
 
-	_, err := svc.GetBookImage(context.Background(), 1, "nonexistent.png")
+	_, err := svc.GetBookImage(context.Background(), 1, "nonexistent.png", bookfile.ImageOptions{})
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "not found")
 }
 
+// fb2WithBinary returns a book with one embedded binary "img1".
+func fb2WithBinary(contentType string, data []byte) string {
+	return `<?xml version="1.0" encoding="UTF-8"?>
+<FictionBook xmlns="http://www.gribuser.ru/xml/fictionbook/2.0">
+  <description>
+    <title-info><book-title>Image Book</book-title><lang>en</lang></title-info>
+  </description>
+  <body><section><p>Text</p></section></body>
+  <binary id="img1" content-type="` + contentType + `">` + base64.StdEncoding.EncodeToString(data) + `</binary>
+</FictionBook>`
+}
+
+func TestReaderService_GetBookImage_Transcoded(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, image.NewGray(image.Rect(0, 0, 1000, 500))))
+
+	repo := &mockBookRepo{
+		archiveName:   "test.zip",
+		fileInArchive: "book.fb2",
+		format:        "fb2",
+	}
+	svc, archivesDir := setupReaderService(t, repo)
+	createTestArchive(t, archivesDir, "test.zip", "book.fb2", fb2WithBinary("image/png", buf.Bytes()))
+
+	opts := bookfile.ImageOptions{Width: 500, Format: bookfile.ImageFormatJPEG}
+	img, err := svc.GetBookImage(context.Background(), 1, "img1", opts)
+	require.NoError(t, err)
+	assert.Equal(t, "image/jpeg", img.ContentType)
+
+	cfg, err := jpeg.DecodeConfig(bytes.NewReader(img.Data))
+	require.NoError(t, err)
+	assert.Equal(t, 600, cfg.Width, "width is rounded up to the next allowed width")
+	assert.Equal(t, 300, cfg.Height)
+
+	// The variant is cached next to the original
+	_, err = os.Stat(filepath.Join(bookCacheDir(svc, 1), "img_img1.meta.zst"))
+	assert.NoError(t, err)
+	_, err = os.Stat(filepath.Join(bookCacheDir(svc, 1), "img_img1@w600-jpeg.bin.zst"))
+	assert.NoError(t, err)
+
+	require.NoError(t, os.Remove(filepath.Join(archivesDir, "test.zip")))
+	cached, err := svc.GetBookImage(context.Background(), 1, "img1", opts)
+	require.NoError(t, err)
+	assert.Equal(t, img.Data, cached.Data)
+	assert.Equal(t, "image/jpeg", cached.ContentType)
+}
+
+func TestReaderService_GetBookImage_NotTranscodable(t *testing.T) {
+	svg := []byte(`<svg xmlns="http://www.w3.org/2000/svg"/>`)
+	repo := &mockBookRepo{
+		archiveName:   "test.zip",
+		fileInArchive: "book.fb2",
+		format:        "fb2",
+	}
+	svc, archivesDir := setupReaderService(t, repo)
+	createTestArchive(t, archivesDir, "test.zip", "book.fb2", fb2WithBinary("image/svg+xml", svg))
+
+	img, err := svc.GetBookImage(context.Background(), 1, "img1", bookfile.ImageOptions{Format: bookfile.ImageFormatWebP})
+	require.NoError(t, err)
+	assert.Equal(t, "image/svg+xml", img.ContentType)
+	assert.Equal(t, svg, img.Data)
+}
+
 func TestReaderService_MalformedFB2(t *testing.T) {
 	malformedFB2 := `<?xml version="1.0"?><FictionBook><body><section><p>unclosed`
 
@@ -313,6 +529,20 @@ func TestReaderService_MalformedFB2(t *testing.T) {
 	assert.True(t, errors.Is(err, ErrMalformedFile))
 }
 
+func TestNewReaderCacheStore(t *testing.T) {
+	store, err := NewReaderCacheStore(config.ReaderConfig{CacheBackend: config.CacheBackendFS, CachePath: t.TempDir()}, nil)
+	require.NoError(t, err)
+	assert.IsType(t, &readercache.FS{}, store)
+
+	store, err = NewReaderCacheStore(config.ReaderConfig{CacheBackend: config.CacheBackendMemory}, nil)
+	require.NoError(t, err)
+	assert.IsType(t, &readercache.Memory{}, store)
+
+	store, err = NewReaderCacheStore(config.ReaderConfig{CacheBackend: config.CacheBackendPostgres}, nil)
+	require.NoError(t, err)
+	assert.IsType(t, &repository.ReaderCacheRepo{}, store)
+}
+
 func TestReaderService_CacheDir_Structure(t *testing.T) {
 	repo := &mockBookRepo{
 		archiveName:   "test.zip",
@@ -326,23 +556,25 @@ func TestReaderService_CacheDir_Structure(t *testing.T) {
 	require.NoError(t, err)
 
 	// Cache dir: {cachePath}/42/
-	expectedDir := filepath.Join(svc.cachePath, "42")
+	expectedDir := bookCacheDir(svc, 42)
 	info, statErr := os.Stat(expectedDir)
 	require.NoError(t, statErr)
 	assert.True(t, info.IsDir())
 
 	// content.json exists
-	_, statErr = os.Stat(filepath.Join(expectedDir, "content.json"))
+	_, statErr = os.Stat(filepath.Join(expectedDir, "content.json.zst"))
 	assert.NoError(t, statErr)
 }
 
 func TestNewReaderService(t *testing.T) {
+	store := readercache.NewMemory()
 	svc := NewReaderService(nil, config.LibraryConfig{}, config.ReaderConfig{
-		CachePath: "/tmp/test",
-		CacheTTL:  48 * time.Hour,
-	})
-	assert.Equal(t, "/tmp/test", svc.cachePath)
+		CacheTTL: 48 * time.Hour,
+		MaxSize:  1 << 30,
+	}, store)
+	assert.Same(t, store, svc.cache)
 	assert.Equal(t, 48*time.Hour, svc.cacheTTL)
+	assert.Equal(t, int64(1<<30), svc.maxCacheSize)
 }
 
 // --- Test bookfile.GetConverter via ReaderService ---
@@ -362,7 +594,7 @@ func TestReaderService_GetBookContent_CachesBookContent(t *testing.T) {
 	require.NotNil(t, content)
 
 	// Verify cached content is valid JSON by reading it
-	cached, err := svc.getCachedContent(1)
+	cached, err := svc.getCachedContent(context.Background(), 1)
 	require.NoError(t, err)
 	assert.Equal(t, content.Metadata.Title, cached.Metadata.Title)
 }
@@ -382,11 +614,47 @@ func TestReaderService_GetChapter_CachesChapterContent(t *testing.T) {
 	ch, err := svc.GetChapter(context.Background(), 1, content.ChapterIDs[0])
 	require.NoError(t, err)
 
-	cached, cacheErr := svc.getCachedChapter(1, content.ChapterIDs[0])
+	cached, cacheErr := svc.getCachedChapter(context.Background(), 1, content.ChapterIDs[0])
 	require.NoError(t, cacheErr)
 	assert.Equal(t, ch.HTML, cached.HTML)
 }
 
+func TestReaderService_XPointer_CachesMap(t *testing.T) {
+	repo := &mockBookRepo{
+		archiveName:   "test.zip",
+		fileInArchive: "book.fb2",
+		format:        "fb2",
+	}
+	svc, archivesDir := setupReaderService(t, repo)
+	archivePath := createTestArchive(t, archivesDir, "test.zip", "book.fb2", simpleFB2)
+
+	content, err := svc.GetBookContent(context.Background(), 1)
+	require.NoError(t, err)
+	xp, err := svc.XPointer(context.Background(), 1, content.ChapterIDs[0], 1)
+	require.NoError(t, err)
+	assert.Equal(t, "/FictionBook/body/section/p", xp)
+
+	// Later syncs map positions without the book file
+	require.NoError(t, os.Remove(archivePath))
+	chapterID, paragraph, err := svc.ResolveXPointer(context.Background(), 1, xp+"/text().3")
+	require.NoError(t, err)
+	assert.Equal(t, content.ChapterIDs[0], chapterID)
+	assert.Equal(t, 1, paragraph)
+}
+
+func TestReaderService_XPointer_UnsupportedFormat(t *testing.T) {
+	repo := &mockBookRepo{
+		archiveName:   "test.zip",
+		fileInArchive: "book.txt",
+		format:        "txt",
+	}
+	svc, _ := setupReaderService(t, repo)
+
+	// The format is rejected before the (missing) archive is opened
+	_, err := svc.XPointer(context.Background(), 1, "ch1", 0)
+	assert.True(t, errors.Is(err, ErrUnsupportedFormat))
+}
+
 // --- Cache cleanup tests ---
 
 func TestReaderService_CleanupExpiredCache_RemovesOld(t *testing.T) {
@@ -407,20 +675,20 @@ func TestReaderService_CleanupExpiredCache_RemovesOld(t *testing.T) {
 
 	// Set book 1 cache dir mtime to 2 hours ago (expired)
 	oldTime := time.Now().Add(-2 * time.Hour)
-	require.NoError(t, os.Chtimes(svc.bookCacheDir(1), oldTime, oldTime))
+	require.NoError(t, os.Chtimes(bookCacheDir(svc, 1), oldTime, oldTime))
 
 	// Book 2 stays fresh (just created)
 
-	removed, err := svc.CleanupExpiredCache()
+	removed, err := svc.CleanupExpiredCache(context.Background())
 	require.NoError(t, err)
 	assert.Equal(t, 1, removed)
 
 	// Book 1 cache should be gone
-	_, statErr := os.Stat(svc.bookCacheDir(1))
+	_, statErr := os.Stat(bookCacheDir(svc, 1))
 	assert.True(t, os.IsNotExist(statErr))
 
 	// Book 2 cache should remain
-	_, statErr = os.Stat(svc.bookCacheDir(2))
+	_, statErr = os.Stat(bookCacheDir(svc, 2))
 	assert.NoError(t, statErr)
 }
 
@@ -438,31 +706,33 @@ func TestReaderService_CleanupExpiredCache_KeepsFresh(t *testing.T) {
 	require.NoError(t, err)
 
 	// Everything is fresh — nothing should be removed
-	removed, err := svc.CleanupExpiredCache()
+	removed, err := svc.CleanupExpiredCache(context.Background())
 	require.NoError(t, err)
 	assert.Equal(t, 0, removed)
 
-	_, statErr := os.Stat(svc.bookCacheDir(1))
+	_, statErr := os.Stat(bookCacheDir(svc, 1))
 	assert.NoError(t, statErr)
 }
 
 func TestReaderService_CleanupExpiredCache_ZeroTTL(t *testing.T) {
-	svc := &ReaderService{cachePath: t.TempDir(), cacheTTL: 0}
+	svc := &ReaderService{cache: readercache.NewMemory(), cacheTTL: 0}
 
-	removed, err := svc.CleanupExpiredCache()
+	removed, err := svc.CleanupExpiredCache(context.Background())
 	require.NoError(t, err)
 	assert.Equal(t, 0, removed)
 }
 
 func TestReaderService_CleanupExpiredCache_NoCacheDir(t *testing.T) {
-	svc := &ReaderService{cachePath: "/nonexistent/path", cacheTTL: 1 * time.Hour}
+	store, err := readercache.NewFS("/nonexistent/path")
+	require.NoError(t, err)
+	svc := &ReaderService{cache: store, cacheTTL: 1 * time.Hour}
 
-	removed, err := svc.CleanupExpiredCache()
+	removed, err := svc.CleanupExpiredCache(context.Background())
 	require.NoError(t, err)
 	assert.Equal(t, 0, removed)
 }
 
-func TestReaderService_TouchCache_UpdatesMtime(t *testing.T) {
+func TestReaderService_CacheHit_UpdatesMtime(t *testing.T) {
 	repo := &mockBookRepo{
 		archiveName:   "test.zip",
 		fileInArchive: "book.fb2",
@@ -477,12 +747,80 @@ func TestReaderService_TouchCache_UpdatesMtime(t *testing.T) {
 
 	// Set mtime to the past
 	oldTime := time.Now().Add(-48 * time.Hour)
-	require.NoError(t, os.Chtimes(svc.bookCacheDir(1), oldTime, oldTime))
+	require.NoError(t, os.Chtimes(bookCacheDir(svc, 1), oldTime, oldTime))
 
-	// Touch should update mtime
-	svc.touchCache(1)
+	// A cache hit should update mtime
+	svc.cacheHit(context.Background(), 1)
 
-	info, err := os.Stat(svc.bookCacheDir(1))
+	info, err := os.Stat(bookCacheDir(svc, 1))
 	require.NoError(t, err)
 	assert.True(t, info.ModTime().After(oldTime))
 }
+
+func TestReaderService_Bundle(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, image.NewGray(image.Rect(0, 0, 800, 400))))
+	fb2 := `<?xml version="1.0" encoding="UTF-8"?>
+<FictionBook xmlns="http://www.gribuser.ru/xml/fictionbook/2.0" xmlns:l="http://www.w3.org/1999/xlink">
+  <description>
+    <title-info><book-title>Bundle Book</book-title><lang>en</lang></title-info>
+  </description>
+  <body>
+    <section><title><p>One</p></title><p>Text<a l:href="#n1" type="note">1</a></p><image l:href="#pic"/></section>
+    <section><title><p>Two</p></title><p>More<a l:href="#n1" type="note">1</a><a l:href="#n2" type="note">2</a></p><image l:href="#missing"/></section>
+  </body>
+  <body name="notes">
+    <section id="n1"><p>Note one</p></section>
+  </body>
+  <binary id="pic" content-type="image/png">` + base64.StdEncoding.EncodeToString(buf.Bytes()) + `</binary>
+</FictionBook>`
+
+	repo := &mockBookRepo{
+		archiveName:   "test.zip",
+		fileInArchive: "book.fb2",
+		format:        "fb2",
+	}
+	svc, archivesDir := setupReaderService(t, repo)
+	createTestArchive(t, archivesDir, "test.zip", "book.fb2", fb2)
+
+	var items []*BundleItem
+	err := svc.Bundle(context.Background(), 1, bookfile.ImageOptions{Width: 400}, func(item *BundleItem) error {
+		items = append(items, item)
+		return nil
+	})
+	require.NoError(t, err)
+
+	var types []string
+	for _, item := range items {
+		types = append(types, item.Type)
+	}
+	assert.Equal(t, []string{"content", "chapter", "chapter", "note", "image", "end"}, types)
+
+	assert.Equal(t, "Bundle Book", items[0].Content.Metadata.Title)
+	assert.Equal(t, items[0].Content.ChapterIDs[1], items[2].Chapter.ID)
+	assert.Equal(t, "n1", items[3].Note.ID)
+	assert.Equal(t, "/api/books/1/image/pic?v=2", items[4].URL)
+	assert.Equal(t, "image/png", items[4].Image.ContentType)
+	cfg, err := png.DecodeConfig(bytes.NewReader(items[4].Image.Data))
+	require.NoError(t, err)
+	assert.Equal(t, 400, cfg.Width, "images are transcoded")
+	assert.Equal(t, BundleItem{Type: "end", Chapters: 2, Notes: 1, Images: 1}, *items[5])
+}
+
+func TestReaderService_Bundle_EmitError(t *testing.T) {
+	repo := &mockBookRepo{
+		archiveName:   "test.zip",
+		fileInArchive: "book.fb2",
+		format:        "fb2",
+	}
+	svc, archivesDir := setupReaderService(t, repo)
+	createTestArchive(t, archivesDir, "test.zip", "book.fb2", simpleFB2)
+
+	calls := 0
+	err := svc.Bundle(context.Background(), 1, bookfile.ImageOptions{}, func(*BundleItem) error {
+		calls++
+		return context.Canceled
+	})
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, 1, calls)
+}
@@ -0,0 +1,453 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	// Time zones are validated with time.LoadLocation; the runtime images
+	// do not ship a zoneinfo database.
+	_ "time/tzdata"
+
+	"github.com/grom-alex/homelib/backend/internal/bookfile"
+	"github.com/grom-alex/homelib/backend/internal/kosync"
+	"github.com/grom-alex/homelib/backend/internal/models"
+	"github.com/grom-alex/homelib/backend/internal/repository"
+)
+
+var (
+	ErrInvalidStatsQuery = errors.New("invalid statistics query")
+	ErrInvalidStatistics = errors.New("invalid KOReader statistics file")
+)
+
+const (
+	// sessionIdleGap is the longest pause between progress updates (or
+	// KOReader page turns) that still counts as one session.
+	sessionIdleGap = 10 * time.Minute
+
+	// statsPageBytes converts chapter sizes (bytes of HTML) to pages: about
+	// a printed page of 1800 characters of Cyrillic text with markup.
+	statsPageBytes = 4000
+
+	defaultStatsDays = 365
+	maxStatsDays     = 3660
+	statsTopLimit    = 10
+	genresPerYear    = 5
+
+	statsDateLayout = "2006-01-02"
+	koreaderDevice  = "KOReader"
+)
+
+// sessionStore abstracts the reading session repo for testing.
+type sessionStore interface {
+	LastSession(ctx context.Context, userID string, bookID int64, device string) (*models.ReadingSession, error)
+	Create(ctx context.Context, s *models.ReadingSession) error
+	Extend(ctx context.Context, s *models.ReadingSession) error
+	Import(ctx context.Context, sessions []models.ReadingSession) (int, error)
+	FindBookByTitle(ctx context.Context, title, author string) (int64, bool, error)
+	DailyTotals(ctx context.Context, userID, tz, from, to string) ([]models.StatsPeriod, error)
+	YearlyTotals(ctx context.Context, userID, tz string) ([]models.StatsPeriod, error)
+	ReadingDays(ctx context.Context, userID, tz string) ([]time.Time, error)
+	FinishedByDay(ctx context.Context, userID, tz string) ([]models.StatsPeriod, error)
+	GenreTotals(ctx context.Context, userID, tz, from, to string, limit int) ([]models.StatsTotal, error)
+	AuthorTotals(ctx context.Context, userID, tz, from, to string, limit int) ([]models.StatsTotal, error)
+	GenresByYear(ctx context.Context, userID, tz string, perYear int) ([]repository.GenreYearTotal, error)
+}
+
+// bookContentReader provides book structure for page estimates.
+type bookContentReader interface {
+	GetBookContent(ctx context.Context, bookID int64) (*bookfile.BookContent, error)
+}
+
+// ReadingStatsService records reading sessions and aggregates them into
+// personal reading statistics.
+type ReadingStatsService struct {
+	sessions sessionStore
+	hashes   documentBookFinder
+	content  bookContentReader
+	now      func() time.Time
+	logger   *slog.Logger
+}
+
+func NewReadingStatsService(sessionRepo *repository.ReadingSessionRepo, hashRepo *repository.DocumentHashRepo,
+	readerSvc *ReaderService) *ReadingStatsService {
+	return &ReadingStatsService{
+		sessions: sessionRepo,
+		hashes:   hashRepo,
+		content:  readerSvc,
+		now:      time.Now,
+		logger:   slog.Default(),
+	}
+}
+
+// RecordProgress extends the current session of the book on the device
+// with a saved position, or starts a new one after a pause. Failures are
+// logged: statistics must not break saving progress.
+func (s *ReadingStatsService) RecordProgress(ctx context.Context, p *models.ReadingProgress, source string) {
+	if err := s.recordProgress(ctx, p, source); err != nil {
+		s.logger.Warn("failed to record reading session",
+			"user_id", p.UserID, "book_id", p.BookID, "error", err)
+	}
+}
+
+func (s *ReadingStatsService) recordProgress(ctx context.Context, p *models.ReadingProgress, source string) error {
+	at := p.UpdatedAt
+	if at.IsZero() {
+		at = s.now()
+	}
+	pos := s.position(ctx, p)
+
+	last, err := s.sessions.LastSession(ctx, p.UserID, p.BookID, p.Device)
+	if err != nil {
+		return err
+	}
+	if last != nil && last.Source == source && !at.Before(last.EndedAt) && at.Sub(last.EndedAt) <= sessionIdleGap {
+		last.EndedAt = at
+		last.Duration = int(at.Sub(last.StartedAt).Seconds())
+		last.EndChapterID = p.ChapterID
+		last.EndProgress = p.TotalProgress
+		last.EndPosition = pos
+		last.Pages = pagesBetween(last.StartPosition, pos)
+		return s.sessions.Extend(ctx, last)
+	}
+
+	return s.sessions.Create(ctx, &models.ReadingSession{
+		UserID:         p.UserID,
+		BookID:         p.BookID,
+		Source:         source,
+		Device:         p.Device,
+		StartedAt:      at,
+		EndedAt:        at,
+		StartChapterID: p.ChapterID,
+		EndChapterID:   p.ChapterID,
+		StartProgress:  p.TotalProgress,
+		EndProgress:    p.TotalProgress,
+		StartPosition:  pos,
+		EndPosition:    pos,
+	})
+}
+
+// position estimates the offset of a reading position from the start of
+// the book in chapter bytes, or -1 if the book structure is unavailable.
+func (s *ReadingStatsService) position(ctx context.Context, p *models.ReadingProgress) int64 {
+	content, err := s.content.GetBookContent(ctx, p.BookID)
+	if err != nil || len(content.ChapterSizes) == 0 {
+		return -1
+	}
+	var pos int64
+	for _, id := range content.ChapterIDs {
+		size := int64(content.ChapterSizes[id])
+		if id == p.ChapterID {
+			return pos + size*int64(p.ChapterProgress)/100
+		}
+		pos += size
+	}
+	return -1
+}
+
+// pagesBetween returns the pages read between two positions. Going back
+// counts as nothing read.
+func pagesBetween(from, to int64) int {
+	if from < 0 || to <= from {
+		return 0
+	}
+	return int((to - from + statsPageBytes/2) / statsPageBytes)
+}
+
+// Stats aggregates the user's reading sessions.
+func (s *ReadingStatsService) Stats(ctx context.Context, userID string, q models.StatsQuery) (*models.ReadingStats, error) {
+	tz := q.TZ
+	if tz == "" {
+		tz = "UTC"
+	}
+	loc, err := time.LoadLocation(tz)
+	if err != nil || tz == "Local" {
+		return nil, fmt.Errorf("%w: time zone %q", ErrInvalidStatsQuery, tz)
+	}
+	today := s.now().In(loc)
+	today = time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, time.UTC)
+
+	to, err := parseStatsDate(q.To, today)
+	if err != nil {
+		return nil, err
+	}
+	from, err := parseStatsDate(q.From, to.AddDate(0, 0, -(defaultStatsDays-1)))
+	if err != nil {
+		return nil, err
+	}
+	if from.After(to) || to.Sub(from) > maxStatsDays*24*time.Hour {
+		return nil, fmt.Errorf("%w: range %s..%s", ErrInvalidStatsQuery, q.From, q.To)
+	}
+	fromDay, toDay := from.Format(statsDateLayout), to.Format(statsDateLayout)
+
+	daily, err := s.sessions.DailyTotals(ctx, userID, tz, fromDay, toDay)
+	if err != nil {
+		return nil, err
+	}
+	yearly, err := s.sessions.YearlyTotals(ctx, userID, tz)
+	if err != nil {
+		return nil, err
+	}
+	finished, err := s.sessions.FinishedByDay(ctx, userID, tz)
+	if err != nil {
+		return nil, err
+	}
+	days, err := s.sessions.ReadingDays(ctx, userID, tz)
+	if err != nil {
+		return nil, err
+	}
+	genres, err := s.sessions.GenreTotals(ctx, userID, tz, fromDay, toDay, statsTopLimit)
+	if err != nil {
+		return nil, err
+	}
+	authors, err := s.sessions.AuthorTotals(ctx, userID, tz, fromDay, toDay, statsTopLimit)
+	if err != nil {
+		return nil, err
+	}
+	byYear, err := s.sessions.GenresByYear(ctx, userID, tz, genresPerYear)
+	if err != nil {
+		return nil, err
+	}
+
+	// Finished books within the range, for totals, weeks and months
+	var finishedInRange []models.StatsPeriod
+	for _, f := range finished {
+		if f.Period >= fromDay && f.Period <= toDay {
+			finishedInRange = append(finishedInRange, f)
+		}
+	}
+
+	stats := &models.ReadingStats{
+		From:    fromDay,
+		To:      toDay,
+		TZ:      tz,
+		Totals:  models.StatsPeriod{Period: fromDay + ".." + toDay},
+		Streak:  readingStreak(days, today),
+		Daily:   nonNil(daily),
+		Weekly:  rollUp(daily, finishedInRange, weekOf),
+		Monthly: rollUp(daily, finishedInRange, func(day string) string { return day[:7] }),
+		Yearly:  rollUp(yearly, finished, func(period string) string { return period[:4] }),
+		Genres:  nonNil(genres),
+		Authors: nonNil(authors),
+	}
+	for _, d := range daily {
+		addPeriod(&stats.Totals, d)
+	}
+	for _, f := range finishedInRange {
+		stats.Totals.BooksFinished += f.BooksFinished
+	}
+	stats.Totals.Minutes = minutes(stats.Totals.Seconds)
+
+	stats.GenresByYear = []models.StatsYearGenres{}
+	for _, t := range byYear {
+		n := len(stats.GenresByYear)
+		if n == 0 || stats.GenresByYear[n-1].Year != t.Year {
+			stats.GenresByYear = append(stats.GenresByYear, models.StatsYearGenres{Year: t.Year})
+			n++
+		}
+		stats.GenresByYear[n-1].Genres = append(stats.GenresByYear[n-1].Genres, t.Genre)
+	}
+
+	for _, list := range [][]models.StatsPeriod{stats.Daily, stats.Weekly, stats.Monthly, stats.Yearly} {
+		for i := range list {
+			list[i].Minutes = minutes(list[i].Seconds)
+		}
+	}
+	for _, list := range [][]models.StatsTotal{stats.Genres, stats.Authors} {
+		for i := range list {
+			list[i].Minutes = minutes(list[i].Seconds)
+		}
+	}
+	for _, y := range stats.GenresByYear {
+		for i := range y.Genres {
+			y.Genres[i].Minutes = minutes(y.Genres[i].Seconds)
+		}
+	}
+	return stats, nil
+}
+
+func parseStatsDate(value string, def time.Time) (time.Time, error) {
+	if value == "" {
+		return def, nil
+	}
+	d, err := time.Parse(statsDateLayout, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: date %q", ErrInvalidStatsQuery, value)
+	}
+	return d, nil
+}
+
+// weekOf returns the Monday of the week of a "YYYY-MM-DD" day.
+func weekOf(day string) string {
+	d, err := time.Parse(statsDateLayout, day)
+	if err != nil {
+		return day
+	}
+	offset := (int(d.Weekday()) + 6) % 7
+	return d.AddDate(0, 0, -offset).Format(statsDateLayout)
+}
+
+// rollUp sums periods into coarser ones and adds finished book counts.
+func rollUp(periods, finished []models.StatsPeriod, key func(string) string) []models.StatsPeriod {
+	out := []models.StatsPeriod{}
+	index := make(map[string]int)
+	at := func(period string) *models.StatsPeriod {
+		k := key(period)
+		i, ok := index[k]
+		if !ok {
+			i = len(out)
+			index[k] = i
+			out = append(out, models.StatsPeriod{Period: k})
+		}
+		return &out[i]
+	}
+	for _, p := range periods {
+		addPeriod(at(p.Period), p)
+	}
+	for _, f := range finished {
+		at(f.Period).BooksFinished += f.BooksFinished
+	}
+	// Finished books may add periods without reading out of order
+	sort.Slice(out, func(i, j int) bool { return out[i].Period < out[j].Period })
+	return out
+}
+
+func addPeriod(dst *models.StatsPeriod, p models.StatsPeriod) {
+	dst.Seconds += p.Seconds
+	dst.Pages += p.Pages
+	dst.Sessions += p.Sessions
+}
+
+// readingStreak counts runs of consecutive reading days. The current
+// streak is kept alive until the end of the day after the last reading.
+func readingStreak(days []time.Time, today time.Time) models.StatsStreak {
+	var streak models.StatsStreak
+	run := 0
+	var prev time.Time
+	for i, d := range days {
+		d = time.Date(d.Year(), d.Month(), d.Day(), 0, 0, 0, 0, time.UTC)
+		if i > 0 && d.Equal(prev.AddDate(0, 0, 1)) {
+			run++
+		} else {
+			run = 1
+		}
+		streak.Longest = max(streak.Longest, run)
+		prev = d
+	}
+	if run > 0 && !prev.Before(today.AddDate(0, 0, -1)) {
+		streak.Current = run
+	}
+	return streak
+}
+
+func minutes(seconds int64) int {
+	return int(math.Round(float64(seconds) / 60))
+}
+
+func nonNil[T any](s []T) []T {
+	if s == nil {
+		return []T{}
+	}
+	return s
+}
+
+// ImportKOReaderStats merges the reading history of a KOReader
+// statistics.sqlite3 file into the user's sessions. Books are matched by
+// document digest, then by title and author; unmatched books are reported.
+// Importing the same file again adds nothing.
+func (s *ReadingStatsService) ImportKOReaderStats(ctx context.Context, userID, path string) (*models.StatsImportResult, error) {
+	stats, err := kosync.ReadStatistics(ctx, path)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidStatistics, err)
+	}
+
+	viewed := make(map[int64]bool)
+	for _, v := range stats.Views {
+		viewed[v.BookID] = true
+	}
+
+	result := &models.StatsImportResult{Unmatched: []string{}}
+	books := make(map[int64]int64)
+	for _, b := range stats.Books {
+		if !viewed[b.ID] {
+			continue
+		}
+		bookID, found, err := s.matchBook(ctx, b)
+		if err != nil {
+			return nil, err
+		}
+		if !found {
+			result.Unmatched = append(result.Unmatched, b.Title)
+			continue
+		}
+		books[b.ID] = bookID
+	}
+	result.Books = len(books)
+
+	var sessions []models.ReadingSession
+	for _, ks := range kosync.Sessions(stats.Views, sessionIdleGap) {
+		bookID, ok := books[ks.BookID]
+		if !ok {
+			continue
+		}
+		externalID := fmt.Sprintf("koreader:%d:%d", bookID, ks.Start.Unix())
+		sessions = append(sessions, models.ReadingSession{
+			UserID:        userID,
+			BookID:        bookID,
+			Source:        models.SessionSourceKOReader,
+			Device:        koreaderDevice,
+			StartedAt:     ks.Start,
+			EndedAt:       ks.End,
+			Duration:      int(ks.Duration.Seconds()),
+			StartProgress: pagePercent(ks.FirstPage, ks.TotalPages),
+			EndProgress:   pagePercent(ks.LastPage, ks.TotalPages),
+			Pages:         ks.Pages,
+			ExternalID:    &externalID,
+		})
+	}
+
+	if len(sessions) > 0 {
+		result.Sessions, err = s.sessions.Import(ctx, sessions)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+func (s *ReadingStatsService) matchBook(ctx context.Context, b kosync.StatsBook) (int64, bool, error) {
+	if b.MD5 != "" {
+		bookID, found, err := s.hashes.FindBook(ctx, b.MD5)
+		if err != nil || found {
+			return bookID, found, err
+		}
+	}
+	if b.Title == "" {
+		return 0, false, nil
+	}
+	return s.sessions.FindBookByTitle(ctx, b.Title, authorSurname(b.Authors))
+}
+
+// authorSurname guesses the surname of the first of KOReader's
+// newline-separated "First Last" authors.
+func authorSurname(authors string) string {
+	first, _, _ := strings.Cut(authors, "\n")
+	words := strings.Fields(first)
+	if len(words) == 0 {
+		return ""
+	}
+	return words[len(words)-1]
+}
+
+// pagePercent converts a page number to a percentage of the book.
+func pagePercent(page, total int) int {
+	if total <= 0 {
+		return 0
+	}
+	return min(max(page*100/total, 0), 100)
+}
@@ -0,0 +1,333 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grom-alex/homelib/backend/internal/bookfile"
+	"github.com/grom-alex/homelib/backend/internal/models"
+	"github.com/grom-alex/homelib/backend/internal/repository"
+)
+
+type fakeSessionStore struct {
+	sessions []*models.ReadingSession
+	imported []models.ReadingSession
+	titles   map[string]int64
+
+	daily    []models.StatsPeriod
+	yearly   []models.StatsPeriod
+	days     []time.Time
+	finished []models.StatsPeriod
+	genres   []models.StatsTotal
+	byYear   []repository.GenreYearTotal
+}
+
+func (f *fakeSessionStore) LastSession(_ context.Context, userID string, bookID int64, device string) (*models.ReadingSession, error) {
+	for i := len(f.sessions) - 1; i >= 0; i-- {
+		s := f.sessions[i]
+		if s.UserID == userID && s.BookID == bookID && s.Device == device {
+			cp := *s
+			return &cp, nil
+		}
+	}
+	return nil, nil
+}
+
+func (f *fakeSessionStore) Create(_ context.Context, s *models.ReadingSession) error {
+	s.ID = int64(len(f.sessions) + 1)
+	cp := *s
+	f.sessions = append(f.sessions, &cp)
+	return nil
+}
+
+func (f *fakeSessionStore) Extend(_ context.Context, s *models.ReadingSession) error {
+	cp := *s
+	f.sessions[s.ID-1] = &cp
+	return nil
+}
+
+func (f *fakeSessionStore) Import(_ context.Context, sessions []models.ReadingSession) (int, error) {
+	f.imported = append(f.imported, sessions...)
+	return len(sessions), nil
+}
+
+func (f *fakeSessionStore) FindBookByTitle(_ context.Context, title, author string) (int64, bool, error) {
+	id, ok := f.titles[title+"/"+author]
+	return id, ok, nil
+}
+
+func (f *fakeSessionStore) DailyTotals(_ context.Context, _, _, _, _ string) ([]models.StatsPeriod, error) {
+	return f.daily, nil
+}
+
+func (f *fakeSessionStore) YearlyTotals(_ context.Context, _, _ string) ([]models.StatsPeriod, error) {
+	return f.yearly, nil
+}
+
+func (f *fakeSessionStore) ReadingDays(_ context.Context, _, _ string) ([]time.Time, error) {
+	return f.days, nil
+}
+
+func (f *fakeSessionStore) FinishedByDay(_ context.Context, _, _ string) ([]models.StatsPeriod, error) {
+	return f.finished, nil
+}
+
+func (f *fakeSessionStore) GenreTotals(_ context.Context, _, _, _, _ string, _ int) ([]models.StatsTotal, error) {
+	return f.genres, nil
+}
+
+func (f *fakeSessionStore) AuthorTotals(_ context.Context, _, _, _, _ string, _ int) ([]models.StatsTotal, error) {
+	return nil, nil
+}
+
+func (f *fakeSessionStore) GenresByYear(_ context.Context, _, _ string, _ int) ([]repository.GenreYearTotal, error) {
+	return f.byYear, nil
+}
+
+type fakeBookContent map[int64]*bookfile.BookContent
+
+func (f fakeBookContent) GetBookContent(_ context.Context, bookID int64) (*bookfile.BookContent, error) {
+	c, ok := f[bookID]
+	if !ok {
+		return nil, ErrBookNotFound
+	}
+	return c, nil
+}
+
+func newTestStats(store *fakeSessionStore, now time.Time) *ReadingStatsService {
+	return &ReadingStatsService{
+		sessions: store,
+		hashes:   fakeDocumentHashes{"0123456789abcdef0123456789abcdef": 42},
+		content: fakeBookContent{42: {
+			ChapterIDs:   []string{"ch1", "ch2"},
+			ChapterSizes: map[string]int{"ch1": 40000, "ch2": 80000},
+		}},
+		now:    func() time.Time { return now },
+		logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}
+}
+
+func TestReadingStats_RecordProgress(t *testing.T) {
+	t0 := time.Date(2026, 1, 10, 20, 0, 0, 0, time.UTC)
+	store := &fakeSessionStore{}
+	s := newTestStats(store, t0)
+	ctx := context.Background()
+
+	save := func(at time.Time, chapter string, chapterProgress int, source string) {
+		s.RecordProgress(ctx, &models.ReadingProgress{
+			UserID: "user-1", BookID: 42, ChapterID: chapter, ChapterProgress: chapterProgress,
+			TotalProgress: 10, Device: "web", UpdatedAt: at,
+		}, source)
+	}
+
+	save(t0, "ch1", 50, models.SessionSourceWeb)
+	save(t0.Add(5*time.Minute), "ch2", 0, models.SessionSourceWeb)
+	save(t0.Add(12*time.Minute), "ch2", 25, models.SessionSourceWeb)
+
+	require.Len(t, store.sessions, 1)
+	sess := store.sessions[0]
+	assert.Equal(t, t0, sess.StartedAt)
+	assert.Equal(t, t0.Add(12*time.Minute), sess.EndedAt)
+	assert.Equal(t, 12*60, sess.Duration)
+	assert.Equal(t, "ch1", sess.StartChapterID)
+	assert.Equal(t, "ch2", sess.EndChapterID)
+	assert.Equal(t, int64(20000), sess.StartPosition)
+	assert.Equal(t, int64(60000), sess.EndPosition)
+	// 40000 bytes of chapter text read
+	assert.Equal(t, 10, sess.Pages)
+
+	// A pause longer than the idle gap starts a new session
+	save(t0.Add(40*time.Minute), "ch2", 30, models.SessionSourceWeb)
+	require.Len(t, store.sessions, 2)
+	assert.Equal(t, 0, store.sessions[1].Duration)
+
+	// Positions synced from KOReader are not merged into web sessions
+	save(t0.Add(41*time.Minute), "ch2", 40, models.SessionSourceKosync)
+	require.Len(t, store.sessions, 3)
+	assert.Equal(t, models.SessionSourceKosync, store.sessions[2].Source)
+}
+
+func TestReadingStats_RecordProgress_UnknownStructure(t *testing.T) {
+	t0 := time.Date(2026, 1, 10, 20, 0, 0, 0, time.UTC)
+	store := &fakeSessionStore{}
+	s := newTestStats(store, t0)
+
+	for i, at := range []time.Time{t0, t0.Add(time.Minute)} {
+		s.RecordProgress(context.Background(), &models.ReadingProgress{
+			UserID: "user-1", BookID: 7, ChapterID: "ch1", ChapterProgress: i * 50, UpdatedAt: at,
+		}, models.SessionSourceWeb)
+	}
+
+	require.Len(t, store.sessions, 1)
+	assert.Equal(t, int64(-1), store.sessions[0].StartPosition)
+	assert.Equal(t, 0, store.sessions[0].Pages)
+	assert.Equal(t, 60, store.sessions[0].Duration)
+}
+
+func TestReadingStats_Stats(t *testing.T) {
+	day := func(s string) time.Time {
+		d, err := time.Parse(statsDateLayout, s)
+		require.NoError(t, err)
+		return d
+	}
+	store := &fakeSessionStore{
+		daily: []models.StatsPeriod{
+			{Period: "2025-11-02", Seconds: 600, Pages: 5, Sessions: 1},
+			{Period: "2026-01-04", Seconds: 1800, Pages: 20, Sessions: 1}, // Sunday
+			{Period: "2026-01-05", Seconds: 2700, Pages: 30, Sessions: 2}, // Monday
+		},
+		yearly: []models.StatsPeriod{
+			{Period: "2025", Seconds: 36000, Pages: 400, Sessions: 30},
+			{Period: "2026", Seconds: 5100, Pages: 55, Sessions: 4},
+		},
+		finished: []models.StatsPeriod{
+			{Period: "2025-01-02", BooksFinished: 2},
+			{Period: "2026-01-05", BooksFinished: 1},
+		},
+		days:   []time.Time{day("2025-12-30"), day("2025-12-31"), day("2026-01-01"), day("2026-01-04"), day("2026-01-05")},
+		genres: []models.StatsTotal{{ID: 3, Name: "Фантастика", Seconds: 3000}},
+		byYear: []repository.GenreYearTotal{
+			{Year: "2025", Genre: models.StatsTotal{ID: 1, Name: "Детектив", Seconds: 20000}},
+			{Year: "2025", Genre: models.StatsTotal{ID: 3, Name: "Фантастика", Seconds: 16000}},
+			{Year: "2026", Genre: models.StatsTotal{ID: 3, Name: "Фантастика", Seconds: 3000}},
+		},
+	}
+	// 2026-01-06 01:00 UTC is still January 5th in New York
+	s := newTestStats(store, time.Date(2026, 1, 6, 1, 0, 0, 0, time.UTC))
+
+	stats, err := s.Stats(context.Background(), "user-1", models.StatsQuery{TZ: "America/New_York"})
+	require.NoError(t, err)
+
+	assert.Equal(t, "2025-01-06", stats.From)
+	assert.Equal(t, "2026-01-05", stats.To)
+	assert.Equal(t, models.StatsPeriod{Period: "2025-01-06..2026-01-05", Seconds: 5100, Minutes: 85, Pages: 55, Sessions: 4, BooksFinished: 1}, stats.Totals)
+	assert.Equal(t, models.StatsStreak{Current: 2, Longest: 3}, stats.Streak)
+
+	require.Len(t, stats.Weekly, 3)
+	assert.Equal(t, "2025-10-27", stats.Weekly[0].Period)
+	assert.Equal(t, "2025-12-29", stats.Weekly[1].Period)
+	assert.Equal(t, 30, stats.Weekly[1].Minutes)
+	assert.Equal(t, "2026-01-05", stats.Weekly[2].Period)
+	assert.Equal(t, 1, stats.Weekly[2].BooksFinished)
+
+	require.Len(t, stats.Monthly, 2)
+	assert.Equal(t, models.StatsPeriod{Period: "2026-01", Seconds: 4500, Minutes: 75, Pages: 50, Sessions: 3, BooksFinished: 1}, stats.Monthly[1])
+
+	require.Len(t, stats.Yearly, 2)
+	assert.Equal(t, 2, stats.Yearly[0].BooksFinished)
+	assert.Equal(t, 600, stats.Yearly[0].Minutes)
+
+	assert.Equal(t, 50, stats.Genres[0].Minutes)
+	assert.Empty(t, stats.Authors)
+	require.Len(t, stats.GenresByYear, 2)
+	assert.Len(t, stats.GenresByYear[0].Genres, 2)
+	assert.Equal(t, 50, stats.GenresByYear[1].Genres[0].Minutes)
+}
+
+func TestReadingStats_Stats_InvalidQuery(t *testing.T) {
+	s := newTestStats(&fakeSessionStore{}, time.Date(2026, 1, 6, 0, 0, 0, 0, time.UTC))
+
+	for _, q := range []models.StatsQuery{
+		{TZ: "Mars/Olympus"},
+		{TZ: "Local"},
+		{From: "2026-02-01", To: "2026-01-01"},
+		{From: "01.01.2026"},
+		{From: "2000-01-01", To: "2026-01-01"},
+	} {
+		_, err := s.Stats(context.Background(), "user-1", q)
+		assert.ErrorIs(t, err, ErrInvalidStatsQuery, "%+v", q)
+	}
+}
+
+func TestReadingStreak(t *testing.T) {
+	today := time.Date(2026, 1, 10, 0, 0, 0, 0, time.UTC)
+	d := func(day int) time.Time { return time.Date(2026, 1, day, 0, 0, 0, 0, time.UTC) }
+
+	assert.Equal(t, models.StatsStreak{}, readingStreak(nil, today))
+	assert.Equal(t, models.StatsStreak{Current: 1, Longest: 2}, readingStreak([]time.Time{d(1), d(2), d(10)}, today))
+	assert.Equal(t, models.StatsStreak{Current: 3, Longest: 3}, readingStreak([]time.Time{d(7), d(8), d(9)}, today))
+	assert.Equal(t, models.StatsStreak{Current: 0, Longest: 2}, readingStreak([]time.Time{d(7), d(8)}, today))
+}
+
+// writeKOReaderStats creates a statistics.sqlite3 with KOReader's schema.
+func writeKOReaderStats(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "statistics.sqlite3")
+	db, err := sql.Open("sqlite", path)
+	require.NoError(t, err)
+	defer db.Close()
+
+	t0 := time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC).Unix()
+	for _, stmt := range []string{
+		`CREATE TABLE book (id integer PRIMARY KEY autoincrement, title text, authors text, notes integer,
+			last_open integer, highlights integer, pages integer, series text, language text, md5 text,
+			total_read_time integer, total_read_pages integer)`,
+		`CREATE TABLE page_stat_data (id_book integer, page integer NOT NULL DEFAULT 0,
+			start_time integer NOT NULL DEFAULT 0, duration integer NOT NULL DEFAULT 0,
+			total_pages integer NOT NULL DEFAULT 0, UNIQUE (id_book, page, start_time))`,
+		`INSERT INTO book (id, title, authors, pages, md5) VALUES
+			(1, 'Мастер и Маргарита', 'Михаил Булгаков', 400, '0123456789abcdef0123456789abcdef'),
+			(2, 'Белая гвардия', 'Михаил Булгаков', 300, 'fedcba9876543210fedcba9876543210'),
+			(3, 'Чужая книга', 'Кто-то', 100, ''),
+			(4, 'Не открывалась', '', 50, '')`,
+	} {
+		_, err := db.Exec(stmt)
+		require.NoError(t, err)
+	}
+	for _, v := range [][5]int64{
+		{1, 10, t0, 120, 400}, {1, 11, t0 + 120, 180, 400},
+		{1, 400, t0 + 7200, 60, 400},
+		{2, 1, t0, 60, 300},
+		{3, 1, t0, 60, 100},
+	} {
+		_, err := db.Exec(`INSERT INTO page_stat_data VALUES (?, ?, ?, ?, ?)`, v[0], v[1], v[2], v[3], v[4])
+		require.NoError(t, err)
+	}
+	return path
+}
+
+func TestReadingStats_ImportKOReaderStats(t *testing.T) {
+	store := &fakeSessionStore{titles: map[string]int64{"Белая гвардия/Булгаков": 77}}
+	s := newTestStats(store, time.Now())
+
+	result, err := s.ImportKOReaderStats(context.Background(), "user-1", writeKOReaderStats(t))
+	require.NoError(t, err)
+
+	assert.Equal(t, 2, result.Books)
+	assert.Equal(t, 3, result.Sessions)
+	assert.Equal(t, []string{"Чужая книга"}, result.Unmatched)
+
+	require.Len(t, store.imported, 3)
+	first := store.imported[0]
+	assert.Equal(t, int64(42), first.BookID)
+	assert.Equal(t, models.SessionSourceKOReader, first.Source)
+	assert.Equal(t, 300, first.Duration)
+	assert.Equal(t, 2, first.Pages)
+	assert.Equal(t, 2, first.StartProgress)
+	require.NotNil(t, first.ExternalID)
+	assert.Equal(t, "koreader:42:1767261600", *first.ExternalID)
+
+	// The last page read finishes the book
+	assert.Equal(t, 100, store.imported[1].EndProgress)
+	assert.Equal(t, int64(77), store.imported[2].BookID)
+}
+
+func TestReadingStats_ImportKOReaderStats_InvalidFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "empty.sqlite3")
+	db, err := sql.Open("sqlite", path)
+	require.NoError(t, err)
+	_, err = db.Exec(`CREATE TABLE notes (id integer)`)
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+
+	_, err = newTestStats(&fakeSessionStore{}, time.Now()).ImportKOReaderStats(context.Background(), "user-1", path)
+	assert.ErrorIs(t, err, ErrInvalidStatistics)
+}
@@ -0,0 +1,65 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/grom-alex/homelib/backend/internal/models"
+	"github.com/grom-alex/homelib/backend/internal/repository"
+)
+
+// statusShelves moves books between the reading status shelves.
+type statusShelves interface {
+	BookStatus(ctx context.Context, userID string, bookID int64) (string, error)
+	StatusShelfID(ctx context.Context, userID, status string) (int64, error)
+	AddBook(ctx context.Context, userID string, shelfID, bookID int64, note *string) (*models.ShelfEntry, error)
+}
+
+// ShelfService keeps reading statuses in step with reading progress.
+type ShelfService struct {
+	shelves statusShelves
+	logger  *slog.Logger
+}
+
+func NewShelfService(shelfRepo *repository.ShelfRepo) *ShelfService {
+	return &ShelfService{shelves: shelfRepo, logger: slog.Default()}
+}
+
+// RecordProgress marks a book finished once its progress reaches 100%,
+// unless the user has already given it a status other than "reading": a
+// finished book moved to another shelf stays there. Failures are logged:
+// shelves must not break saving progress.
+func (s *ShelfService) RecordProgress(ctx context.Context, p *models.ReadingProgress, _ string) {
+	if p.TotalProgress < 100 {
+		return
+	}
+	if err := s.markFinished(ctx, p.UserID, p.BookID); err != nil {
+		s.logger.Warn("failed to mark book finished",
+			"user_id", p.UserID, "book_id", p.BookID, "error", err)
+	}
+}
+
+func (s *ShelfService) markFinished(ctx context.Context, userID string, bookID int64) error {
+	status, err := s.shelves.BookStatus(ctx, userID, bookID)
+	if err != nil {
+		return err
+	}
+	if status != "" && status != models.ShelfReading {
+		return nil
+	}
+	shelfID, err := s.shelves.StatusShelfID(ctx, userID, models.ShelfFinished)
+	if err != nil {
+		return err
+	}
+	_, err = s.shelves.AddBook(ctx, userID, shelfID, bookID, nil)
+	return err
+}
+
+// ProgressRecorders passes a saved position to each recorder in turn.
+type ProgressRecorders []progressRecorder
+
+func (rs ProgressRecorders) RecordProgress(ctx context.Context, p *models.ReadingProgress, source string) {
+	for _, r := range rs {
+		r.RecordProgress(ctx, p, source)
+	}
+}
@@ -0,0 +1,70 @@
+package service
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/grom-alex/homelib/backend/internal/models"
+)
+
+type fakeStatusShelves struct {
+	added []string
+	// statuses maps book IDs to the status shelf they are on.
+	statuses map[int64]string
+}
+
+func (f *fakeStatusShelves) BookStatus(_ context.Context, _ string, bookID int64) (string, error) {
+	return f.statuses[bookID], nil
+}
+
+func (f *fakeStatusShelves) StatusShelfID(_ context.Context, _ string, status string) (int64, error) {
+	return int64(len(status)), nil
+}
+
+func (f *fakeStatusShelves) AddBook(_ context.Context, userID string, shelfID, bookID int64, note *string) (*models.ShelfEntry, error) {
+	f.added = append(f.added, userID)
+	return &models.ShelfEntry{ShelfID: shelfID, BookID: bookID}, nil
+}
+
+func TestShelfService_RecordProgress_MarksFinished(t *testing.T) {
+	shelves := &fakeStatusShelves{}
+	s := &ShelfService{shelves: shelves, logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+	ctx := context.Background()
+
+	s.RecordProgress(ctx, &models.ReadingProgress{UserID: "user-1", BookID: 42, TotalProgress: 99}, models.SessionSourceWeb)
+	assert.Empty(t, shelves.added)
+
+	s.RecordProgress(ctx, &models.ReadingProgress{UserID: "user-1", BookID: 42, TotalProgress: 100}, models.SessionSourceKosync)
+	assert.Equal(t, []string{"user-1"}, shelves.added)
+}
+
+func TestShelfService_RecordProgress_KeepsUserStatus(t *testing.T) {
+	shelves := &fakeStatusShelves{statuses: map[int64]string{
+		1: models.ShelfFinished,
+		2: models.ShelfWantToRead,
+		3: models.ShelfAbandoned,
+		4: models.ShelfReading,
+	}}
+	s := &ShelfService{shelves: shelves, logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+	ctx := context.Background()
+
+	for bookID := int64(1); bookID <= 4; bookID++ {
+		s.RecordProgress(ctx, &models.ReadingProgress{UserID: "user-1", BookID: bookID, TotalProgress: 100}, models.SessionSourceKosync)
+	}
+	// Only the book being read moves to the finished shelf; a finished
+	// book re-shelved by the user stays where it was put.
+	assert.Equal(t, []string{"user-1"}, shelves.added)
+}
+
+func TestProgressRecorders_CallsEach(t *testing.T) {
+	a, b := &fakeRecorder{}, &fakeRecorder{}
+	ProgressRecorders{a, b}.RecordProgress(context.Background(),
+		&models.ReadingProgress{BookID: 42, ChapterID: "ch1"}, models.SessionSourceWeb)
+
+	assert.Equal(t, []string{"web:42:ch1"}, a.recorded)
+	assert.Equal(t, []string{"web:42:ch1"}, b.recorded)
+}
@@ -0,0 +1,101 @@
+package service
+
+import (
+	"context"
+	"sort"
+	"unicode/utf8"
+
+	"github.com/grom-alex/homelib/backend/internal/models"
+	"github.com/grom-alex/homelib/backend/internal/repository"
+	"github.com/grom-alex/homelib/backend/internal/searchquery"
+)
+
+// alternativePenalty scales scores of matches found by a keyboard-layout
+// alternative so that equally good matches of the query as typed win.
+const alternativePenalty = 0.9
+
+// suggestLookup abstracts the suggest repo dependency for testing.
+type suggestLookup interface {
+	Suggest(ctx context.Context, terms, types []string, limit int, excludeGenreIDs []int) ([][]models.Suggestion, error)
+}
+
+type SuggestService struct {
+	repo suggestLookup
+}
+
+func NewSuggestService(repo *repository.SuggestRepo) *SuggestService {
+	return &SuggestService{repo: repo}
+}
+
+// Suggest returns up to q.Limit autocomplete entries for q.Query, ranked by
+// prefix match and trigram similarity. The query is also tried with the
+// other keyboard layout, so "vfcnth" finds "Мастер".
+func (s *SuggestService) Suggest(ctx context.Context, q models.SuggestQuery) (*models.SuggestResult, error) {
+	q.SetDefaults()
+	if utf8.RuneCountInString(q.Query) < models.SuggestMinQueryLen {
+		return &models.SuggestResult{Items: []models.Suggestion{}}, nil
+	}
+
+	terms := append([]string{q.Query}, searchquery.LayoutAlternatives(q.Query)...)
+	matches, err := s.repo.Suggest(ctx, terms, q.Types, q.Limit, q.ExcludeGenreIDs)
+	if err != nil {
+		return nil, err
+	}
+	return mergeSuggestions(terms, matches, q.Limit), nil
+}
+
+// mergeSuggestions combines per-term matches into a single ranked list
+// capped at limit. An entry found by several terms keeps its best score.
+// Corrected is set when the top entry came from a layout alternative.
+func mergeSuggestions(terms []string, matches [][]models.Suggestion, limit int) *models.SuggestResult {
+	type key struct {
+		typ string
+		id  int64
+	}
+	best := make(map[key]int)
+	var items []models.Suggestion
+	var sources []int
+
+	for ti, list := range matches {
+		for _, m := range list {
+			if ti > 0 {
+				m.Score *= alternativePenalty
+			}
+			k := key{m.Type, m.ID}
+			if i, ok := best[k]; ok {
+				if m.Score > items[i].Score {
+					items[i] = m
+					sources[i] = ti
+				}
+				continue
+			}
+			best[k] = len(items)
+			items = append(items, m)
+			sources = append(sources, ti)
+		}
+	}
+
+	order := make([]int, len(items))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		ia, ib := items[order[a]], items[order[b]]
+		if ia.Score != ib.Score {
+			return ia.Score > ib.Score
+		}
+		return ia.Text < ib.Text
+	})
+	if len(order) > limit {
+		order = order[:limit]
+	}
+
+	result := &models.SuggestResult{Items: make([]models.Suggestion, 0, len(order))}
+	for _, i := range order {
+		result.Items = append(result.Items, items[i])
+	}
+	if len(order) > 0 && sources[order[0]] > 0 {
+		result.Corrected = terms[sources[order[0]]]
+	}
+	return result
+}
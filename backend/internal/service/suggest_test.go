@@ -0,0 +1,101 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grom-alex/homelib/backend/internal/models"
+)
+
+type mockSuggestLookup struct {
+	terms [][]string
+	fn    func(terms []string) [][]models.Suggestion
+	err   error
+}
+
+func (m *mockSuggestLookup) Suggest(_ context.Context, terms, _ []string, _ int, _ []int) ([][]models.Suggestion, error) {
+	m.terms = append(m.terms, terms)
+	if m.err != nil {
+		return nil, m.err
+	}
+	return m.fn(terms), nil
+}
+
+func TestSuggestService_ShortQuerySkipsLookup(t *testing.T) {
+	repo := &mockSuggestLookup{}
+	svc := &SuggestService{repo: repo}
+
+	res, err := svc.Suggest(context.Background(), models.SuggestQuery{Query: " м "})
+	require.NoError(t, err)
+	assert.Empty(t, res.Items)
+	assert.NotNil(t, res.Items)
+	assert.Empty(t, repo.terms)
+}
+
+func TestSuggestService_LayoutCorrection(t *testing.T) {
+	repo := &mockSuggestLookup{fn: func(terms []string) [][]models.Suggestion {
+		out := make([][]models.Suggestion, len(terms))
+		for i, term := range terms {
+			if term == "мастер" {
+				out[i] = []models.Suggestion{{Type: models.SuggestTitle, ID: 1, Text: "Мастер и Маргарита", Score: 1.4}}
+			}
+		}
+		return out
+	}}
+	svc := &SuggestService{repo: repo}
+
+	res, err := svc.Suggest(context.Background(), models.SuggestQuery{Query: "ьфыеук"})
+	require.NoError(t, err)
+
+	require.Len(t, repo.terms, 1)
+	assert.Equal(t, []string{"ьфыеук", "master", "мастер"}, repo.terms[0])
+	require.Len(t, res.Items, 1)
+	assert.Equal(t, "Мастер и Маргарита", res.Items[0].Text)
+	assert.Equal(t, "мастер", res.Corrected)
+}
+
+func TestSuggestService_RepoError(t *testing.T) {
+	svc := &SuggestService{repo: &mockSuggestLookup{err: fmt.Errorf("boom")}}
+
+	_, err := svc.Suggest(context.Background(), models.SuggestQuery{Query: "мастер"})
+	assert.Error(t, err)
+}
+
+func TestMergeSuggestions(t *testing.T) {
+	terms := []string{"стр", "cnh"}
+	matches := [][]models.Suggestion{
+		{
+			{Type: models.SuggestAuthor, ID: 1, Text: "Стругацкий Аркадий", Score: 1.3},
+			{Type: models.SuggestTitle, ID: 5, Text: "Страж", Score: 1.3},
+			{Type: models.SuggestSeries, ID: 2, Text: "Мир", Score: 0.2},
+		},
+		{
+			// Same author found by the alternative: kept once with the better score
+			{Type: models.SuggestAuthor, ID: 1, Text: "Стругацкий Аркадий", Score: 1.0},
+			{Type: models.SuggestTitle, ID: 9, Text: "Cnh", Score: 1.2},
+		},
+	}
+
+	res := mergeSuggestions(terms, matches, 3)
+
+	require.Len(t, res.Items, 3)
+	// Equal scores are ordered by text
+	assert.Equal(t, "Страж", res.Items[0].Text)
+	assert.Equal(t, "Стругацкий Аркадий", res.Items[1].Text)
+	assert.InDelta(t, 1.3, res.Items[1].Score, 1e-9)
+	// Alternative score is penalized: 1.2 * 0.9
+	assert.Equal(t, "Cnh", res.Items[2].Text)
+	assert.InDelta(t, 1.08, res.Items[2].Score, 1e-9)
+	assert.Empty(t, res.Corrected)
+}
+
+func TestMergeSuggestions_Empty(t *testing.T) {
+	res := mergeSuggestions([]string{"abc"}, [][]models.Suggestion{nil}, 10)
+	assert.NotNil(t, res.Items)
+	assert.Empty(t, res.Items)
+	assert.Empty(t, res.Corrected)
+}
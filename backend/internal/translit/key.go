@@ -0,0 +1,103 @@
+package translit
+
+import (
+	"strings"
+	"unicode"
+)
+
+// latinFold spells accented Latin letters (ISO 9 and common European ones)
+// with plain ASCII.
+var latinFold = map[rune]string{
+	'ž': "zh", 'č': "ch", 'š': "sh", 'ŝ': "shch",
+	'û': "iu", 'â': "ia", 'ǔ': "u",
+	'ë': "e", 'è': "e", 'é': "e", 'ê': "e",
+	'ì': "i", 'ï': "i", 'í': "i", 'î': "i",
+	'à': "a", 'á': "a", 'ä': "a", 'å': "a",
+	'ò': "o", 'ó': "o", 'ö': "o", 'ô': "o",
+	'ù': "u", 'ú': "u", 'ü': "u",
+	'ç': "c", 'ñ': "n", 'ý': "y", 'ÿ': "y", 'ł': "l",
+}
+
+// Placeholders keep sibilant digraphs intact while single letters are
+// rewritten; they are spelled out again at the end.
+const (
+	phShch = '\uE000'
+	phZh   = '\uE001'
+	phCh   = '\uE002'
+	phSh   = '\uE003'
+)
+
+// keyRules collapse spelling variants of the same Cyrillic sound. They are
+// applied in order to lowercase ASCII text.
+var keyRules = strings.NewReplacer(
+	"shch", string(phShch), "shh", string(phShch), "sch", string(phShch),
+	"tch", string(phCh),
+	"zh", string(phZh), "ch", string(phCh), "sh", string(phSh),
+	"kh", "h", "ph", "f",
+	"tc", "c", "ts", "c", "tz", "c", "cz", "c",
+	"x", "ks", "w", "v", "q", "k",
+	"j", "i", "y", "i",
+)
+
+// vowelRules merge iotated vowels: GOST "ie" for ъ before я/ю ("Obieiavlenie"),
+// and "ye"/"yo" spellings of е/ё.
+var vowelRules = strings.NewReplacer("iei", "i", "ie", "e", "io", "e")
+
+var restorePlaceholders = strings.NewReplacer(
+	string(phShch), "shch", string(phZh), "zh", string(phCh), "ch", string(phSh), "sh",
+)
+
+// Key returns the normalized Latin search key of s. Names written in
+// Cyrillic or romanized with GOST, ISO 9 or the usual informal spellings
+// produce the same key:
+//
+//	Key("Стругацкий") == Key("Strugatsky") == Key("Strugatskij") == "strugacki"
+//
+// Keys contain only lowercase ASCII letters, digits and single spaces.
+// They are lossy and meant for matching, not for display.
+func Key(s string) string {
+	// Romanize Cyrillic and fold diacritics
+	var b strings.Builder
+	b.Grow(len(s))
+	for _, r := range strings.ToLower(s) {
+		if repl, ok := keyTable[r]; ok {
+			b.WriteString(repl)
+			continue
+		}
+		if repl, ok := latinFold[r]; ok {
+			b.WriteString(repl)
+			continue
+		}
+		switch {
+		case unicode.Is(unicode.Mn, r):
+			// combining marks (e.g. ISO 9 g̀)
+		case r == '\'' || r == '`' || r == 'ʹ' || r == 'ʺ' || r == '’' || r == '"':
+			// soft/hard sign marks
+		case r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)):
+			b.WriteRune(r)
+		default:
+			b.WriteRune(' ')
+		}
+	}
+
+	out := collapseRepeats(keyRules.Replace(b.String()))
+	out = collapseRepeats(vowelRules.Replace(out))
+	out = restorePlaceholders.Replace(out)
+	return strings.Join(strings.Fields(out), " ")
+}
+
+// collapseRepeats squeezes runs of the same letter ("Anna" → "ana",
+// "-ii" → "-i"). Spaces and digits are left as is.
+func collapseRepeats(s string) string {
+	var b strings.Builder
+	b.Grow(len(s))
+	var prev rune
+	for _, r := range s {
+		if r == prev && unicode.IsLetter(r) {
+			continue
+		}
+		b.WriteRune(r)
+		prev = r
+	}
+	return b.String()
+}
@@ -0,0 +1,16 @@
+// Package translit normalizes names written in Cyrillic or Latin script to
+// a common search key, so that "Strugatsky", "Strugatskij" and "Стругацкий"
+// match each other.
+package translit
+
+// keyTable romanizes lowercase Cyrillic for Key: GOST R 52535.1-2006
+// without the hard sign, which other schemes drop or spell differently.
+var keyTable = map[rune]string{
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "e", 'ж': "zh",
+	'з': "z", 'и': "i", 'й': "i", 'к': "k", 'л': "l", 'м': "m", 'н': "n", 'о': "o",
+	'п': "p", 'р': "r", 'с': "s", 'т': "t", 'у': "u", 'ф': "f", 'х': "kh", 'ц': "tc",
+	'ч': "ch", 'ш': "sh", 'щ': "shch", 'ъ': "", 'ы': "y", 'ь': "", 'э': "e", 'ю': "iu",
+	'я': "ia",
+	// Ukrainian and Belarusian letters
+	'і': "i", 'ї': "i", 'є': "ie", 'ґ': "g", 'ў': "u",
+}
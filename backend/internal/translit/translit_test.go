@@ -0,0 +1,64 @@
+package translit
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKey_Examples(t *testing.T) {
+	assert.Equal(t, "strugacki", Key("Стругацкий"))
+	assert.Equal(t, "strugacki", Key("Strugatsky"))
+	assert.Equal(t, "lukianenko", Key("Lukyanenko"))
+	assert.Equal(t, "piknik na obochine", Key("Пикник на обочине"))
+	assert.Equal(t, "tolstoi lev", Key("Толстой, Лев"))
+	assert.Equal(t, "", Key(" — ... "))
+}
+
+func TestKey_SpellingVariants(t *testing.T) {
+	groups := [][]string{
+		{"Стругацкий", "Strugatsky", "Strugatskiy", "Strugatskii", "Strugatskij", "Strugackij", "STRUGATSKY"},
+		{"Лукьяненко", "Lukyanenko", "Lukianenko", "Lukjanenko", "Luk'yanenko"},
+		{"Достоевский", "Dostoevsky", "Dostoyevsky", "Dostoevskii", "Dostojevskij"},
+		{"Фёдор", "Fyodor", "Fedor", "Fëdor", "Fjodor"},
+		{"Чайковский", "Tchaikovsky", "Chaikovsky", "Čajkovskij"},
+		{"Хармс", "Kharms", "Harms"},
+		{"Солженицын", "Solzhenitsyn", "Solzhenicyn", "Solženicyn"},
+		{"Максим Горький", "Maxim Gorky", "Maksim Gorkii"},
+		{"Щербаков", "Shcherbakov", "Shherbakov", "Ŝerbakov", "Scherbakov"},
+		{"Евгений", "Yevgeny", "Evgenii", "Evgenij"},
+	}
+	for _, g := range groups {
+		want := Key(g[0])
+		for _, v := range g[1:] {
+			assert.Equal(t, want, Key(v), "%s vs %s", g[0], v)
+		}
+	}
+}
+
+func TestKey_Romanizations(t *testing.T) {
+	// Cyrillic, GOST, ISO 9 and informal spellings
+	names := [][]string{
+		{"Стругацкий", "Strugatckii", "Strugackij", "Strugatsky"},
+		{"Лукьяненко", "Lukianenko", "Lukʹânenko", "Lukyanenko"},
+		{"Фёдор Достоевский", "Fedor Dostoevskii", "Fëdor Dostoevskij", "Fyodor Dostoevsky"},
+		{"Щука и Подъезд", "Shchuka i Podieezd", "Ŝuka i Podʺezd", "Shchuka i Podezd"},
+		{"Юрий Хайям", "Iurii Khaiiam", "Ûrij Hajâm", "Yury Khayyam"},
+		{"Эхо ЖЖ", "Ekho ZhZh", "Èho ŽŽ", "Ekho ZhZh"},
+		{"Тарас Шевченко, їжак", "Taras Shevchenko, izhak", "Taras Ševčenko, ïžak", "Taras Shevchenko, yizhak"},
+		{"Объявление", "Obieiavlenie", "Obʺâvlenie", "Obyavlenie"},
+	}
+	for _, g := range names {
+		want := Key(g[0])
+		for _, v := range g[1:] {
+			assert.Equal(t, want, Key(v), "%s vs %s", g[0], v)
+		}
+	}
+}
+
+func TestKey_Idempotent(t *testing.T) {
+	for _, s := range []string{"Стругацкий", "Щербаков Жан", "Чайковский", "Xarms 2024"} {
+		k := Key(s)
+		assert.Equal(t, k, Key(k), s)
+	}
+}
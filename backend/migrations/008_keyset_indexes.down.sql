@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_series_name_id;
+DROP INDEX IF EXISTS idx_authors_name_sort_id;
+DROP INDEX IF EXISTS idx_books_year_id;
+DROP INDEX IF EXISTS idx_books_added_at_id;
+DROP INDEX IF EXISTS idx_books_title_id;
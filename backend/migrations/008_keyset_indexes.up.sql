@@ -0,0 +1,8 @@
+-- Composite (sort key, id) indexes for keyset (cursor) pagination.
+-- Lists are ordered by (key, id), so these allow index range scans
+-- instead of sorting the whole filtered set on every page.
+CREATE INDEX IF NOT EXISTS idx_books_title_id     ON books (title, id);
+CREATE INDEX IF NOT EXISTS idx_books_added_at_id  ON books (added_at, id);
+CREATE INDEX IF NOT EXISTS idx_books_year_id      ON books (year, id);
+CREATE INDEX IF NOT EXISTS idx_authors_name_sort_id ON authors (name_sort, id);
+CREATE INDEX IF NOT EXISTS idx_series_name_id     ON series (name, id);
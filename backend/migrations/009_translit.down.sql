@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_books_title_translit_trgm;
+DROP INDEX IF EXISTS idx_series_name_translit_trgm;
+DROP INDEX IF EXISTS idx_authors_name_translit_trgm;
+
+ALTER TABLE books   DROP COLUMN IF EXISTS title_translit;
+ALTER TABLE series  DROP COLUMN IF EXISTS name_translit;
+ALTER TABLE authors DROP COLUMN IF EXISTS name_translit;
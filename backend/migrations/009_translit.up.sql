@@ -0,0 +1,10 @@
+-- Normalized Latin search keys (see internal/translit.Key) so that names
+-- typed in Latin ("Strugatsky") match Cyrillic catalog data and vice versa.
+-- Filled on import; existing rows are backfilled by the application.
+ALTER TABLE authors ADD COLUMN name_translit TEXT NOT NULL DEFAULT '';
+ALTER TABLE series  ADD COLUMN name_translit TEXT NOT NULL DEFAULT '';
+ALTER TABLE books   ADD COLUMN title_translit TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX idx_authors_name_translit_trgm ON authors USING gin (name_translit gin_trgm_ops);
+CREATE INDEX idx_series_name_translit_trgm  ON series  USING gin (name_translit gin_trgm_ops);
+CREATE INDEX idx_books_title_translit_trgm  ON books   USING gin (title_translit gin_trgm_ops);
@@ -0,0 +1,22 @@
+-- Bookmarks, highlights and notes. Anchored to a chapter ID and a text
+-- locator (character offsets plus quoted context), so they do not depend
+-- on the reader cache and survive chapters being converted again.
+CREATE TABLE annotations (
+  id BIGSERIAL PRIMARY KEY,
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  book_id BIGINT NOT NULL REFERENCES books(id) ON DELETE CASCADE,
+  type TEXT NOT NULL CHECK (type IN ('bookmark', 'highlight', 'note')),
+  chapter_id TEXT NOT NULL,
+  start_offset INT NOT NULL DEFAULT 0 CHECK (start_offset >= 0),
+  end_offset INT NOT NULL DEFAULT 0,
+  quote TEXT NOT NULL DEFAULT '',
+  prefix TEXT NOT NULL DEFAULT '',
+  suffix TEXT NOT NULL DEFAULT '',
+  color TEXT NOT NULL DEFAULT '',
+  note TEXT NOT NULL DEFAULT '',
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  CHECK (end_offset >= start_offset)
+);
+
+CREATE INDEX idx_annotations_user_book ON annotations(user_id, book_id, chapter_id, start_offset);
@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS idx_annotations_user_book;
+ALTER TABLE annotations DROP CONSTRAINT IF EXISTS annotations_range_check;
+ALTER TABLE annotations DROP COLUMN IF EXISTS start_paragraph, DROP COLUMN IF EXISTS end_paragraph;
+-- Ranges spanning paragraphs may violate the old check; keep them
+ALTER TABLE annotations ADD CONSTRAINT annotations_check CHECK (end_offset >= start_offset) NOT VALID;
+CREATE INDEX idx_annotations_user_book ON annotations(user_id, book_id, chapter_id, start_offset);
+
+ALTER TABLE reading_progress DROP COLUMN IF EXISTS paragraph, DROP COLUMN IF EXISTS char_offset;
@@ -0,0 +1,21 @@
+-- Stable reading locators: a paragraph index within the chapter plus an
+-- offset inside that paragraph (see models.Locator).
+
+-- Progress saved earlier only has percentages. The server derives locators
+-- from chapter_progress in the background; until then they are NULL.
+ALTER TABLE reading_progress
+  ADD COLUMN paragraph INT CHECK (paragraph >= 0),
+  ADD COLUMN char_offset INT CHECK (char_offset >= 0);
+
+-- Annotation offsets used to be counted from the chapter start. They stay
+-- valid as offsets into paragraph 0 that continue into following paragraphs.
+ALTER TABLE annotations
+  ADD COLUMN start_paragraph INT NOT NULL DEFAULT 0 CHECK (start_paragraph >= 0),
+  ADD COLUMN end_paragraph INT NOT NULL DEFAULT 0;
+
+ALTER TABLE annotations DROP CONSTRAINT annotations_check;
+ALTER TABLE annotations ADD CONSTRAINT annotations_range_check
+  CHECK ((end_paragraph, end_offset) >= (start_paragraph, start_offset));
+
+DROP INDEX idx_annotations_user_book;
+CREATE INDEX idx_annotations_user_book ON annotations(user_id, book_id, chapter_id, start_paragraph, start_offset);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS kosync_progress;
+DROP TABLE IF EXISTS document_hashes;
+DROP TABLE IF EXISTS kosync_accounts;
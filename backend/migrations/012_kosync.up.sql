@@ -0,0 +1,35 @@
+-- KOReader sync (kosync protocol). KOReader authenticates with a username
+-- and the MD5 of a password; both are separate from the web login because
+-- the web password is never sent in that form.
+CREATE TABLE kosync_accounts (
+  user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+  username TEXT NOT NULL UNIQUE,
+  key_hash TEXT NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- KOReader identifies documents by a digest of the file ("binary") or of
+-- its name ("filename"). Digests are recorded when a book file is served.
+CREATE TABLE document_hashes (
+  document TEXT PRIMARY KEY,
+  book_id BIGINT NOT NULL REFERENCES books(id) ON DELETE CASCADE,
+  kind TEXT NOT NULL CHECK (kind IN ('binary', 'filename')),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_document_hashes_book ON document_hashes(book_id);
+
+-- Positions as reported by KOReader, kept verbatim so devices can sync
+-- documents that are not in the library too. Positions in known books are
+-- also written to reading_progress.
+CREATE TABLE kosync_progress (
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  document TEXT NOT NULL,
+  progress TEXT NOT NULL DEFAULT '',
+  percentage DOUBLE PRECISION NOT NULL DEFAULT 0,
+  device TEXT NOT NULL DEFAULT '',
+  device_id TEXT NOT NULL DEFAULT '',
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  PRIMARY KEY (user_id, document)
+);
@@ -0,0 +1,26 @@
+-- Reading sessions: continuous reading of one book on one device. Web and
+-- kosync sessions are built from progress updates; KOReader statistics
+-- imports carry an external_id so that importing again adds nothing.
+CREATE TABLE reading_sessions (
+  id BIGSERIAL PRIMARY KEY,
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  book_id BIGINT NOT NULL REFERENCES books(id) ON DELETE CASCADE,
+  source TEXT NOT NULL CHECK (source IN ('web', 'kosync', 'koreader')),
+  device TEXT NOT NULL DEFAULT '',
+  started_at TIMESTAMPTZ NOT NULL,
+  ended_at TIMESTAMPTZ NOT NULL,
+  duration INT NOT NULL DEFAULT 0 CHECK (duration >= 0),
+  start_chapter_id TEXT NOT NULL DEFAULT '',
+  end_chapter_id TEXT NOT NULL DEFAULT '',
+  start_progress SMALLINT NOT NULL DEFAULT 0 CHECK (start_progress BETWEEN 0 AND 100),
+  end_progress SMALLINT NOT NULL DEFAULT 0 CHECK (end_progress BETWEEN 0 AND 100),
+  start_position BIGINT NOT NULL DEFAULT 0,
+  end_position BIGINT NOT NULL DEFAULT 0,
+  pages INT NOT NULL DEFAULT 0 CHECK (pages >= 0),
+  external_id TEXT,
+  CHECK (ended_at >= started_at),
+  UNIQUE (user_id, external_id)
+);
+
+CREATE INDEX idx_reading_sessions_user_started ON reading_sessions(user_id, started_at);
+CREATE INDEX idx_reading_sessions_user_book ON reading_sessions(user_id, book_id, device, ended_at);
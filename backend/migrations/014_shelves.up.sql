@@ -0,0 +1,28 @@
+-- Personal shelves. Status shelves (want to read, reading, finished,
+-- abandoned) have a non-null status, exist once per user and hold a book
+-- in at most one of them; custom shelves have a NULL status.
+CREATE TABLE shelves (
+  id BIGSERIAL PRIMARY KEY,
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  status TEXT CHECK (status IN ('want_to_read', 'reading', 'finished', 'abandoned')),
+  name TEXT NOT NULL,
+  note TEXT NOT NULL DEFAULT '',
+  position INT NOT NULL DEFAULT 0,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  UNIQUE (user_id, status)
+);
+
+CREATE INDEX idx_shelves_user ON shelves(user_id, position);
+
+CREATE TABLE shelf_books (
+  shelf_id BIGINT NOT NULL REFERENCES shelves(id) ON DELETE CASCADE,
+  book_id BIGINT NOT NULL REFERENCES books(id) ON DELETE CASCADE,
+  position INT NOT NULL DEFAULT 0,
+  note TEXT NOT NULL DEFAULT '',
+  added_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  PRIMARY KEY (shelf_id, book_id)
+);
+
+-- Book filters look up shelf membership by book
+CREATE INDEX idx_shelf_books_book ON shelf_books(book_id, shelf_id);
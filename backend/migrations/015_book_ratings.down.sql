@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_books_household_rate_id;
+ALTER TABLE books
+  DROP COLUMN IF EXISTS household_rate_count,
+  DROP COLUMN IF EXISTS household_rate;
+DROP TABLE IF EXISTS book_ratings;
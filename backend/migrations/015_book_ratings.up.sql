@@ -0,0 +1,23 @@
+-- Household ratings and reviews. A rating is stored on the scale it was
+-- given in (5 stars or 10 points); books keep the average of all ratings
+-- converted to the 5-point scale of lib_rate, for display and sorting.
+CREATE TABLE book_ratings (
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  book_id BIGINT NOT NULL REFERENCES books(id) ON DELETE CASCADE,
+  rating SMALLINT,
+  scale SMALLINT NOT NULL DEFAULT 5 CHECK (scale IN (5, 10)),
+  review TEXT NOT NULL DEFAULT '',
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  PRIMARY KEY (user_id, book_id),
+  CHECK (rating BETWEEN 1 AND scale),
+  CHECK (rating IS NOT NULL OR review <> '')
+);
+
+CREATE INDEX idx_book_ratings_book ON book_ratings(book_id, updated_at);
+
+ALTER TABLE books
+  ADD COLUMN household_rate NUMERIC(2,1),
+  ADD COLUMN household_rate_count INT NOT NULL DEFAULT 0;
+
+CREATE INDEX idx_books_household_rate_id ON books (household_rate, id);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS book_content_chunks;
+DROP TABLE IF EXISTS book_content_index;
+ALTER TABLE collections DROP COLUMN IF EXISTS content_index_enabled;
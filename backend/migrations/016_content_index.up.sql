@@ -0,0 +1,28 @@
+-- Opt-in full-text index of book contents. Only books of collections with
+-- content_index_enabled are indexed (by the worker, --index-content). The
+-- plain text of each book is stored in chunks of consecutive paragraphs of
+-- one chapter; ё is folded to е so that either spelling finds both.
+ALTER TABLE collections ADD COLUMN content_index_enabled BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- One row per processed book, also for books that could not be read, so
+-- that the worker does not retry them until the converters change.
+CREATE TABLE book_content_index (
+  book_id BIGINT PRIMARY KEY REFERENCES books(id) ON DELETE CASCADE,
+  format_version INT NOT NULL,
+  chunk_count INT NOT NULL DEFAULT 0,
+  size_bytes BIGINT NOT NULL DEFAULT 0,
+  error TEXT NOT NULL DEFAULT '',
+  indexed_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE book_content_chunks (
+  book_id BIGINT NOT NULL REFERENCES books(id) ON DELETE CASCADE,
+  chunk INT NOT NULL,
+  chapter_id TEXT NOT NULL,
+  paragraph INT NOT NULL,
+  body TEXT NOT NULL,
+  tsv TSVECTOR NOT NULL,
+  PRIMARY KEY (book_id, chunk)
+);
+
+CREATE INDEX idx_book_content_chunks_tsv ON book_content_chunks USING GIN (tsv);
@@ -0,0 +1,2 @@
+SELECT lo_unlink(blob) FROM reader_cache;
+DROP TABLE IF EXISTS reader_cache;
@@ -0,0 +1,11 @@
+-- Reader cache in Postgres large objects (reader.cache_backend: postgres),
+-- shared by all API replicas. Each row is one cached file of a book; a book
+-- is evicted as a whole by the age of its most recently accessed file.
+CREATE TABLE reader_cache (
+    book_id     BIGINT      NOT NULL,
+    name        TEXT        NOT NULL,
+    blob        OID         NOT NULL,
+    size_bytes  BIGINT      NOT NULL,
+    accessed_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (book_id, name)
+);
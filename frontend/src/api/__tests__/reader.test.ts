@@ -7,12 +7,15 @@ vi.mock('../client', () => ({
     get: (...args: unknown[]) => mockGet(...args),
     put: (...args: unknown[]) => mockPut(...args),
   },
+  getAccessToken: () => 'token',
 }))
 
 import {
   getBookContent,
   getChapter,
   getBookImageUrl,
+  imageWidth,
+  downloadBookBundle,
   getReadingProgress,
   saveReadingProgress,
   getUserSettings,
@@ -67,6 +70,63 @@ describe('reader API', () => {
     expect(url).toBe('/api/books/10/image/img_cover')
   })
 
+  it('getBookImageUrl adds transcoding parameters', () => {
+    const url = getBookImageUrl(10, 'img_cover', { w: 600, format: 'webp', grayscale: true })
+
+    expect(url).toBe('/api/books/10/image/img_cover?w=600&format=webp&grayscale=1')
+  })
+
+  it('imageWidth picks the smallest allowed width covering the screen', () => {
+    expect(imageWidth(1)).toBe(400)
+    expect(imageWidth(600)).toBe(600)
+    expect(imageWidth(1170)).toBe(1200)
+    expect(imageWidth(5120)).toBe(4096)
+  })
+
+  it('downloadBookBundle parses the NDJSON stream across chunk boundaries', async () => {
+    const body = '{"type":"content","content":{"chapters":["ch1"]}}\n{"type":"chap' +
+      'ter","chapter":{"id":"ch1","title":"","html":"<p>Hi</p>"}}\n{"type":"end","chapters":1}\n'
+    const encoder = new TextEncoder()
+    const chunks = [body.slice(0, 60), body.slice(60)].map((c) => encoder.encode(c))
+    const fetchMock = vi.fn().mockResolvedValue({
+      ok: true,
+      status: 200,
+      body: {
+        getReader: () => ({
+          read: async () => (chunks.length ? { done: false, value: chunks.shift() } : { done: true, value: undefined }),
+        }),
+      },
+    })
+    vi.stubGlobal('fetch', fetchMock)
+
+    const types: string[] = []
+    await downloadBookBundle(3, (item) => {
+      types.push(item.type)
+    }, { w: 800 })
+
+    expect(fetchMock).toHaveBeenCalledWith('/api/books/3/bundle?w=800', expect.objectContaining({
+      headers: { Authorization: 'Bearer token' },
+    }))
+    expect(types).toEqual(['content', 'chapter', 'end'])
+    vi.unstubAllGlobals()
+  })
+
+  it('downloadBookBundle rejects a cut-off stream', async () => {
+    const chunks = [new TextEncoder().encode('{"type":"content","content":{}}\n')]
+    vi.stubGlobal('fetch', vi.fn().mockResolvedValue({
+      ok: true,
+      status: 200,
+      body: {
+        getReader: () => ({
+          read: async () => (chunks.length ? { done: false, value: chunks.shift() } : { done: true, value: undefined }),
+        }),
+      },
+    }))
+
+    await expect(downloadBookBundle(3, () => {})).rejects.toThrow('cut off')
+    vi.unstubAllGlobals()
+  })
+
   it('getReadingProgress returns null on 204', async () => {
     mockGet.mockResolvedValue({ status: 204, data: '' })
 
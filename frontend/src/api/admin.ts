@@ -31,3 +31,52 @@ export async function getImportStatus(): Promise<ImportStatus> {
   const { data } = await api.get<ImportStatus>('/admin/import/status')
   return data
 }
+
+export interface ContentIndexCollection {
+  id: number
+  name: string
+  enabled: boolean
+  books: number
+  indexed_books: number
+  failed_books: number
+  chunks: number
+  bytes: number
+}
+
+export interface ContentIndexUsage {
+  collections: ContentIndexCollection[]
+  total_bytes: number
+}
+
+export async function getContentIndexUsage(): Promise<ContentIndexUsage> {
+  const { data } = await api.get<ContentIndexUsage>('/admin/content-index')
+  return data
+}
+
+export async function setContentIndexEnabled(collectionId: number, enabled: boolean): Promise<void> {
+  await api.put(`/admin/content-index/collections/${collectionId}`, { enabled })
+}
+
+export interface ReaderCacheStats {
+  size_bytes: number
+  max_size_bytes: number
+  entries: number
+  files: number
+  hits: number
+  misses: number
+  hit_ratio: number
+}
+
+export async function getReaderCacheStats(): Promise<ReaderCacheStats> {
+  const { data } = await api.get<ReaderCacheStats>('/admin/reader-cache')
+  return data
+}
+
+export async function purgeReaderCache(): Promise<number> {
+  const { data } = await api.delete<{ removed: number }>('/admin/reader-cache')
+  return data.removed
+}
+
+export async function purgeBookReaderCache(bookId: number): Promise<void> {
+  await api.delete(`/admin/reader-cache/books/${bookId}`)
+}
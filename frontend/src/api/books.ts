@@ -1,4 +1,6 @@
+import type { AxiosResponse } from 'axios'
 import api from './client'
+import type { ReadingStatus } from './shelves'
 
 export interface BookAuthorRef {
   id: number
@@ -24,6 +26,8 @@ export interface BookListItem {
   format: string
   file_size?: number
   lib_rate?: number
+  household_rate?: number
+  household_rate_count?: number
   is_deleted: boolean
   authors: BookAuthorRef[]
   genres: BookGenreRef[]
@@ -38,6 +42,8 @@ export interface BookDetail {
   format: string
   file_size?: number
   lib_rate?: number
+  household_rate?: number
+  household_rate_count?: number
   is_deleted: boolean
   description?: string
   keywords?: string[]
@@ -51,23 +57,54 @@ export interface BookDetail {
 export interface PaginatedResponse<T> {
   items: T[]
   total: number
+  total_estimated?: boolean
+  next_cursor?: string
   page: number
   limit: number
 }
 
+export type CountMode = 'exact' | 'estimate' | 'none'
+
+export interface ListParams {
+  q?: string
+  page?: number
+  limit?: number
+  cursor?: string
+  count?: CountMode
+}
+
 export interface BookFilters {
   q?: string
   author_id?: number
   author_name?: string
-  genre_id?: number
+  genre_id?: number | number[]
+  not_genre_id?: number[]
+  genre?: string[]
+  not_genre?: string[]
   series_id?: number
   series_name?: string
-  lang?: string
-  format?: string
+  series_type?: 'a' | 'p'
+  lang?: string | string[]
+  format?: string | string[]
+  keyword?: string[]
+  year_from?: number
+  year_to?: number
+  lib_rate_min?: number
+  lib_rate_max?: number
+  size_min?: number
+  size_max?: number
+  date_added_from?: string
+  date_added_to?: string
+  include_deleted?: boolean
+  shelf_id?: number
+  shelf?: ReadingStatus
   page?: number
   limit?: number
   sort?: string
   order?: string
+  cursor?: string
+  count?: CountMode
+  syntax?: 'plain' | 'advanced'
 }
 
 export interface AuthorListItem {
@@ -116,6 +153,33 @@ export async function getBooks(filters: BookFilters = {}, signal?: AbortSignal):
   return data
 }
 
+export interface ContentPassage {
+  chapter_id: string
+  locator: { paragraph: number; offset: number }
+  // HTML: escaped text with the matched words in <mark>
+  snippet: string
+}
+
+export interface ContentSearchResult {
+  book_id: number
+  title: string
+  authors: BookAuthorRef[]
+  matches: number
+  passages: ContentPassage[]
+}
+
+export async function searchContent(
+  q: string,
+  params: { page?: number; limit?: number } = {},
+  signal?: AbortSignal,
+): Promise<PaginatedResponse<ContentSearchResult>> {
+  const { data } = await api.get<PaginatedResponse<ContentSearchResult>>('/search/content', {
+    params: { q, ...params },
+    signal,
+  })
+  return data
+}
+
 export async function getBook(id: number): Promise<BookDetail> {
   const { data } = await api.get<BookDetail>(`/books/${id}`)
   return data
@@ -123,9 +187,14 @@ export async function getBook(id: number): Promise<BookDetail> {
 
 export async function downloadBook(id: number): Promise<void> {
   const response = await api.get(`/books/${id}/download`, { responseType: 'blob' })
+  saveResponseAsFile(response, `book_${id}`)
+}
+
+/** Saves a blob response using the file name from its Content-Disposition header. */
+export function saveResponseAsFile(response: AxiosResponse<Blob>, fallbackName: string): void {
   const disposition = response.headers['content-disposition'] || ''
 
-  let filename = `book_${id}`
+  let filename = fallbackName
   // Try RFC 6266 filename*=UTF-8''... first, then plain filename="..."
   const utf8Match = disposition.match(/filename\*=UTF-8''(.+?)(?:;|$)/i)
   if (utf8Match) {
@@ -150,7 +219,7 @@ export async function downloadBook(id: number): Promise<void> {
   }
 }
 
-export async function getAuthors(params: { q?: string; page?: number; limit?: number } = {}): Promise<PaginatedResponse<AuthorListItem>> {
+export async function getAuthors(params: ListParams = {}): Promise<PaginatedResponse<AuthorListItem>> {
   const { data } = await api.get<PaginatedResponse<AuthorListItem>>('/authors', { params })
   return data
 }
@@ -165,7 +234,7 @@ export async function getGenres(): Promise<GenreTreeItem[]> {
   return data
 }
 
-export async function getSeries(params: { q?: string; page?: number; limit?: number } = {}): Promise<PaginatedResponse<SeriesListItem>> {
+export async function getSeries(params: ListParams = {}): Promise<PaginatedResponse<SeriesListItem>> {
   const { data } = await api.get<PaginatedResponse<SeriesListItem>>('/series', { params })
   return data
 }
@@ -175,6 +244,31 @@ export async function getStats(): Promise<CatalogStats> {
   return data
 }
 
+export type SuggestType = 'title' | 'author' | 'series'
+
+export interface Suggestion {
+  type: SuggestType
+  id: number
+  text: string
+  detail?: string
+}
+
+export interface SuggestResult {
+  items: Suggestion[]
+  corrected?: string
+}
+
+export async function getSuggestions(
+  q: string,
+  types?: SuggestType[],
+  limit?: number,
+  signal?: AbortSignal,
+): Promise<SuggestResult> {
+  const params = { q, types: types?.join(','), limit }
+  const { data } = await api.get<SuggestResult>('/suggest', { params, signal })
+  return data
+}
+
 export interface GenreReloadResult {
   genres_loaded: number
   books_remapped: number
@@ -28,6 +28,8 @@ const api = axios.create({
     'Content-Type': 'application/json',
   },
   withCredentials: true,
+  // Repeat array params as `key=a&key=b` (no brackets) for Gin slice binding
+  paramsSerializer: { indexes: null },
 })
 
 api.interceptors.request.use((config) => {
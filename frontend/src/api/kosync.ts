@@ -0,0 +1,21 @@
+import api from './client'
+
+export interface KosyncAccount {
+  username: string
+  createdAt: string
+  updatedAt: string
+}
+
+export async function getKosyncAccount(): Promise<KosyncAccount | null> {
+  const { data, status } = await api.get<KosyncAccount>('/me/kosync')
+  return status === 204 ? null : data
+}
+
+export async function setKosyncAccount(username: string, password: string): Promise<KosyncAccount> {
+  const { data } = await api.put<KosyncAccount>('/me/kosync', { username, password })
+  return data
+}
+
+export async function deleteKosyncAccount(): Promise<void> {
+  await api.delete('/me/kosync')
+}
@@ -0,0 +1,39 @@
+import api from './client'
+
+export type RatingScale = 5 | 10
+
+export interface BookRating {
+  bookId: number
+  rating?: number
+  scale: RatingScale
+  review?: string
+  createdAt: string
+  updatedAt: string
+}
+
+export interface BookReview extends BookRating {
+  userName: string
+  own: boolean
+}
+
+export async function getBookReviews(bookId: number): Promise<BookReview[]> {
+  const { data } = await api.get<BookReview[]>(`/books/${bookId}/ratings`)
+  return data
+}
+
+export async function getMyRating(bookId: number): Promise<BookRating | null> {
+  const { data, status } = await api.get<BookRating>(`/me/books/${bookId}/rating`)
+  return status === 204 ? null : data
+}
+
+export async function saveMyRating(
+  bookId: number,
+  input: { rating?: number; scale?: RatingScale; review?: string },
+): Promise<BookRating> {
+  const { data } = await api.put<BookRating>(`/me/books/${bookId}/rating`, input)
+  return data
+}
+
+export async function deleteMyRating(bookId: number): Promise<void> {
+  await api.delete(`/me/books/${bookId}/rating`)
+}
@@ -1,18 +1,130 @@
-import api from './client'
-import type { BookContent, ChapterContent, ReadingPosition, ReaderSettings } from '@/types/reader'
+import axios from 'axios'
+import api, { getAccessToken } from './client'
+import { saveResponseAsFile } from './books'
+import type { PaginatedResponse } from './books'
+import type {
+  Annotation,
+  AnnotationType,
+  BookContent,
+  BookSearchHit,
+  BundleItem,
+  ChapterContent,
+  CreateAnnotationInput,
+  NoteContent,
+  ReadingPosition,
+  ReaderSettings,
+  UpdateAnnotationInput,
+} from '@/types/reader'
+import { localizeImages, readOffline } from '@/utils/offlineBooks'
+
+// getOfflineFirst loads path from the server and falls back to the copy saved
+// for offline reading when the server is unreachable.
+async function getOfflineFirst<T>(path: string): Promise<T> {
+  try {
+    const { data } = await api.get<T>(path)
+    return data
+  } catch (err) {
+    if (!axios.isAxiosError(err) || err.response) throw err
+    const saved = await readOffline<T>(path)
+    if (!saved) throw err
+    return saved
+  }
+}
 
 export async function getBookContent(bookId: number): Promise<BookContent> {
-  const { data } = await api.get<BookContent>(`/books/${bookId}/content`)
-  return data
+  return getOfflineFirst<BookContent>(`/books/${bookId}/content`)
 }
 
 export async function getChapter(bookId: number, chapterId: string): Promise<ChapterContent> {
-  const { data } = await api.get<ChapterContent>(`/books/${bookId}/chapter/${chapterId}`)
+  const chapter = await getOfflineFirst<ChapterContent>(`/books/${bookId}/chapter/${chapterId}`)
+  if (navigator.onLine) return chapter
+  return { ...chapter, html: await localizeImages(chapter.html) }
+}
+
+export async function getNote(bookId: number, noteId: string): Promise<NoteContent> {
+  return getOfflineFirst<NoteContent>(`/books/${bookId}/notes/${encodeURIComponent(noteId)}`)
+}
+
+export async function searchBook(
+  bookId: number,
+  q: string,
+  params: { page?: number; limit?: number } = {},
+): Promise<PaginatedResponse<BookSearchHit>> {
+  const { data } = await api.get<PaginatedResponse<BookSearchHit>>(`/books/${bookId}/search`, {
+    params: { q, ...params },
+  })
   return data
 }
 
-export function getBookImageUrl(bookId: number, imageId: string): string {
-  return `/api/books/${bookId}/image/${imageId}`
+// Widths the server scales images to; any other w is rejected.
+export const IMAGE_WIDTHS = [400, 600, 800, 1200, 1600, 2400, 3200, 4096]
+
+// imageWidth returns the smallest allowed width that covers px, or the
+// largest one for wider screens.
+export function imageWidth(px: number): number {
+  return IMAGE_WIDTHS.find((w) => w >= px) ?? IMAGE_WIDTHS[IMAGE_WIDTHS.length - 1]
+}
+
+// Transcoding parameters of an embedded image; w must be one of IMAGE_WIDTHS.
+export interface ImageOptions {
+  w?: number
+  format?: 'webp' | 'jpeg'
+  grayscale?: boolean
+}
+
+function imageQuery(options: ImageOptions): string {
+  const params = new URLSearchParams()
+  if (options.w) params.set('w', String(options.w))
+  if (options.format) params.set('format', options.format)
+  if (options.grayscale) params.set('grayscale', '1')
+  const query = params.toString()
+  return query ? `?${query}` : ''
+}
+
+export function getBookImageUrl(bookId: number, imageId: string, options: ImageOptions = {}): string {
+  return `/api/books/${bookId}/image/${imageId}` + imageQuery(options)
+}
+
+// downloadBookBundle streams the whole book for offline reading and passes
+// each item to onItem as it arrives. Rejects if the stream ends without the
+// end item.
+export async function downloadBookBundle(
+  bookId: number,
+  onItem: (item: BundleItem) => void | Promise<void>,
+  options: ImageOptions = {},
+): Promise<void> {
+  const url = `/api/books/${bookId}/bundle` + imageQuery(options)
+  const token = getAccessToken()
+  const response = await fetch(url, {
+    headers: token ? { Authorization: `Bearer ${token}` } : {},
+    credentials: 'include',
+  })
+  if (!response.ok || !response.body) {
+    throw new Error(`bundle request failed: ${response.status}`)
+  }
+
+  const reader = response.body.getReader()
+  const decoder = new TextDecoder()
+  let buffer = ''
+  let complete = false
+  const handle = async (line: string) => {
+    if (!line.trim()) return
+    const item = JSON.parse(line) as BundleItem
+    if (item.type === 'end') complete = true
+    await onItem(item)
+  }
+  for (;;) {
+    const { done, value } = await reader.read()
+    buffer += decoder.decode(value, { stream: !done })
+    const lines = buffer.split('\n')
+    buffer = lines.pop() ?? ''
+    for (const line of lines) await handle(line)
+    if (done) break
+  }
+  await handle(buffer)
+  if (!complete) {
+    throw new Error('bundle stream was cut off')
+  }
 }
 
 export async function getAllReadingProgress(): Promise<Record<number, number>> {
@@ -28,12 +140,48 @@ export async function getReadingProgress(bookId: number): Promise<ReadingPositio
 
 export async function saveReadingProgress(
   bookId: number,
-  position: Omit<ReadingPosition, 'updatedAt'>,
+  position: ReadingPosition,
 ): Promise<ReadingPosition> {
   const { data } = await api.put<ReadingPosition>(`/me/books/${bookId}/progress`, position)
   return data
 }
 
+export async function getAnnotations(
+  bookId: number,
+  filter: { chapterId?: string; type?: AnnotationType } = {},
+): Promise<Annotation[]> {
+  const { data } = await api.get<Annotation[]>(`/me/books/${bookId}/annotations`, { params: filter })
+  return data
+}
+
+export async function createAnnotation(bookId: number, input: CreateAnnotationInput): Promise<Annotation> {
+  const { data } = await api.post<Annotation>(`/me/books/${bookId}/annotations`, input)
+  return data
+}
+
+export async function updateAnnotation(
+  bookId: number,
+  annotationId: number,
+  input: UpdateAnnotationInput,
+): Promise<Annotation> {
+  const { data } = await api.patch<Annotation>(`/me/books/${bookId}/annotations/${annotationId}`, input)
+  return data
+}
+
+export async function deleteAnnotation(bookId: number, annotationId: number): Promise<void> {
+  await api.delete(`/me/books/${bookId}/annotations/${annotationId}`)
+}
+
+export async function exportBookAnnotations(bookId: number): Promise<void> {
+  const response = await api.get(`/me/books/${bookId}/annotations/export`, { responseType: 'blob' })
+  saveResponseAsFile(response, `annotations_${bookId}.md`)
+}
+
+export async function exportAllAnnotations(format: 'json' | 'zip'): Promise<void> {
+  const response = await api.get('/me/annotations/export', { params: { format }, responseType: 'blob' })
+  saveResponseAsFile(response, `homelib-annotations.${format}`)
+}
+
 export async function getUserSettings(): Promise<{ reader?: Partial<ReaderSettings> }> {
   const { data } = await api.get<{ reader?: Partial<ReaderSettings> }>('/me/settings')
   return data
@@ -0,0 +1,72 @@
+import api from './client'
+
+export type ReadingStatus = 'want_to_read' | 'reading' | 'finished' | 'abandoned'
+
+export interface Shelf {
+  id: number
+  status?: ReadingStatus
+  name: string
+  note: string
+  position: number
+  bookCount: number
+  createdAt: string
+  updatedAt: string
+}
+
+export interface ShelfEntry {
+  shelfId: number
+  bookId: number
+  position: number
+  note: string
+  addedAt: string
+}
+
+export interface ShelfDetail extends Shelf {
+  books: ShelfEntry[]
+}
+
+export async function getShelves(): Promise<Shelf[]> {
+  const { data } = await api.get<Shelf[]>('/me/shelves')
+  return data
+}
+
+export async function getShelf(id: number): Promise<ShelfDetail> {
+  const { data } = await api.get<ShelfDetail>(`/me/shelves/${id}`)
+  return data
+}
+
+export async function createShelf(name: string, note = ''): Promise<Shelf> {
+  const { data } = await api.post<Shelf>('/me/shelves', { name, note })
+  return data
+}
+
+export async function updateShelf(id: number, changes: { name?: string; note?: string }): Promise<Shelf> {
+  const { data } = await api.patch<Shelf>(`/me/shelves/${id}`, changes)
+  return data
+}
+
+export async function deleteShelf(id: number): Promise<void> {
+  await api.delete(`/me/shelves/${id}`)
+}
+
+export async function reorderShelves(ids: number[]): Promise<void> {
+  await api.put('/me/shelves/order', { ids })
+}
+
+export async function addToShelf(shelfId: number, bookId: number, note?: string): Promise<ShelfEntry> {
+  const { data } = await api.put<ShelfEntry>(`/me/shelves/${shelfId}/books/${bookId}`, note === undefined ? undefined : { note })
+  return data
+}
+
+export async function removeFromShelf(shelfId: number, bookId: number): Promise<void> {
+  await api.delete(`/me/shelves/${shelfId}/books/${bookId}`)
+}
+
+export async function reorderShelfBooks(shelfId: number, bookIds: number[]): Promise<void> {
+  await api.put(`/me/shelves/${shelfId}/books/order`, { ids: bookIds })
+}
+
+export async function getBookShelves(bookId: number): Promise<ShelfEntry[]> {
+  const { data } = await api.get<ShelfEntry[]>(`/me/books/${bookId}/shelves`)
+  return data
+}
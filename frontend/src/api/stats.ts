@@ -0,0 +1,50 @@
+import api from './client'
+
+export interface StatsPeriod {
+  period: string
+  minutes: number
+  pages: number
+  sessions: number
+  booksFinished?: number
+}
+
+export interface StatsTotal {
+  id: number
+  name: string
+  minutes: number
+  pages: number
+}
+
+export interface ReadingStats {
+  from: string
+  to: string
+  tz: string
+  totals: StatsPeriod
+  streak: { current: number; longest: number }
+  daily: StatsPeriod[]
+  weekly: StatsPeriod[]
+  monthly: StatsPeriod[]
+  yearly: StatsPeriod[]
+  genres: StatsTotal[]
+  authors: StatsTotal[]
+  genresByYear: { year: string; genres: StatsTotal[] }[]
+}
+
+export interface StatsImportResult {
+  books: number
+  sessions: number
+  unmatched: string[]
+}
+
+export async function getReadingStats(params: { from?: string; to?: string; tz?: string } = {}): Promise<ReadingStats> {
+  const tz = params.tz ?? Intl.DateTimeFormat().resolvedOptions().timeZone
+  const { data } = await api.get<ReadingStats>('/me/stats', { params: { ...params, tz } })
+  return data
+}
+
+export async function importKOReaderStats(file: File): Promise<StatsImportResult> {
+  const form = new FormData()
+  form.append('file', file)
+  const { data } = await api.post<StatsImportResult>('/me/stats/import/koreader', form)
+  return data
+}
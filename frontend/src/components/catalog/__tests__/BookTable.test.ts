@@ -126,12 +126,13 @@ describe('BookTable', () => {
 
     const wrapper = mountBookTable()
     const headers = wrapper.findAll('.book-table__header-cell')
-    expect(headers).toHaveLength(7)
+    expect(headers).toHaveLength(8)
     expect(headers[0].text()).toContain('Название')
     expect(headers[1].text()).toContain('Автор')
     expect(headers[4].text()).toContain('Язык')
     expect(headers[5].text()).toContain('Формат')
     expect(headers[6].text()).toContain('Размер')
+    expect(headers[7].text()).toContain('Оценка')
   })
 
   it('shows pagination when multiple pages', () => {
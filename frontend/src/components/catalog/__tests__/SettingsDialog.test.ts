@@ -1,8 +1,9 @@
 import { describe, it, expect, beforeEach, vi } from 'vitest'
-import { mount } from '@vue/test-utils'
+import { mount, flushPromises } from '@vue/test-utils'
 import { createPinia, setActivePinia } from 'pinia'
 import { createVuetify } from 'vuetify'
 import SettingsDialog from '../SettingsDialog.vue'
+import { getKosyncAccount } from '@/api/kosync'
 
 vi.mock('vuetify', async (importOriginal) => {
   const actual = await importOriginal<typeof import('vuetify')>()
@@ -22,6 +23,12 @@ vi.mock('@/api/client', () => ({
   },
 }))
 
+vi.mock('@/api/kosync', () => ({
+  getKosyncAccount: vi.fn().mockResolvedValue(null),
+  setKosyncAccount: vi.fn(),
+  deleteKosyncAccount: vi.fn(),
+}))
+
 // VDialog needs visualViewport in jsdom
 if (typeof globalThis.visualViewport === 'undefined') {
   (globalThis as Record<string, unknown>).visualViewport = {
@@ -98,4 +105,20 @@ describe('SettingsDialog', () => {
     expect(buttons.length).toBeGreaterThanOrEqual(11)
     wrapper.unmount()
   })
+
+  it('shows the configured KOReader sync account', async () => {
+    vi.mocked(getKosyncAccount).mockResolvedValueOnce({
+      username: 'kobo',
+      createdAt: '2026-01-01T00:00:00Z',
+      updatedAt: '2026-01-01T00:00:00Z',
+    })
+    const wrapper = mountSettingsDialog()
+    await flushPromises()
+    const text = document.body.textContent || ''
+    expect(text).toContain('Синхронизация KOReader')
+    expect(text).toContain('/api/kosync')
+    expect(text).toContain('kobo')
+    expect(text).toContain('Отключить')
+    wrapper.unmount()
+  })
 })
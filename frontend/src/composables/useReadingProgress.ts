@@ -2,6 +2,8 @@ import { onMounted, onUnmounted } from 'vue'
 import { useReaderStore } from '@/stores/reader'
 import { getReadingProgress, saveReadingProgress } from '@/api/reader'
 import { getAccessToken } from '@/api/client'
+import type { Locator, ReadingPosition } from '@/types/reader'
+import { flushProgress, queueProgress, queuedProgress } from '@/utils/offlineProgress'
 
 const DEBOUNCE_MS = 2000
 
@@ -12,17 +14,31 @@ function getDeviceType(): string {
   return 'desktop'
 }
 
-export function useReadingProgress(bookId: number) {
+// getLocator returns the position of the first character on screen, if known.
+export function useReadingProgress(bookId: number, getLocator: () => Locator | null = () => null) {
   const store = useReaderStore()
   let pendingSave = false
   let saveTimer: ReturnType<typeof setTimeout> | null = null
 
-  async function loadProgress(): Promise<{ chapterId: string; chapterProgress: number } | null> {
+  // Positions saved offline are sent first, so the server reconciles them
+  // with progress from other devices before we ask for it.
+  async function loadProgress(): Promise<ReadingPosition | null> {
+    await flushProgress(saveReadingProgress)
     try {
       const progress = await getReadingProgress(bookId)
       return progress
     } catch {
-      return null
+      return queuedProgress(bookId)
+    }
+  }
+
+  function currentPosition(): ReadingPosition {
+    return {
+      chapterId: store.currentChapterId!,
+      locator: getLocator() ?? undefined,
+      chapterProgress: store.chapterProgressInt,
+      totalProgress: calculateTotalProgress(),
+      device: getDeviceType(),
     }
   }
 
@@ -44,16 +60,13 @@ export function useReadingProgress(bookId: number) {
   async function doSave() {
     if (!store.currentChapterId) return
 
-    const totalProgress = calculateTotalProgress()
+    const position = currentPosition()
     try {
-      await saveReadingProgress(bookId, {
-        chapterId: store.currentChapterId,
-        chapterProgress: store.chapterProgressInt,
-        totalProgress,
-        device: getDeviceType(),
-      })
-    } catch {
-      // Ошибки сохранения игнорируются — не блокируем чтение
+      await saveReadingProgress(bookId, position)
+    } catch (err) {
+      // Ошибки сохранения не блокируют чтение; без сети позиция
+      // сохраняется локально и отправляется при подключении
+      if (!(err as { response?: unknown }).response) queueProgress(bookId, position)
     }
     pendingSave = false
   }
@@ -71,15 +84,16 @@ export function useReadingProgress(bookId: number) {
     }
     if (!pendingSave) return
 
+    const position = currentPosition()
+    if (!navigator.onLine) {
+      queueProgress(bookId, position)
+      pendingSave = false
+      return
+    }
+
     // Use fetch with keepalive for reliable delivery on page unload.
     // Unlike sendBeacon, fetch supports custom headers (Authorization) and PUT method.
-    const totalProgress = calculateTotalProgress()
-    const body = JSON.stringify({
-      chapterId: store.currentChapterId,
-      chapterProgress: store.chapterProgressInt,
-      totalProgress,
-      device: getDeviceType(),
-    })
+    const body = JSON.stringify(position)
     const token = getAccessToken()
     fetch(`/api/me/books/${bookId}/progress`, {
       method: 'PUT',
@@ -89,7 +103,7 @@ export function useReadingProgress(bookId: number) {
       },
       body,
       keepalive: true,
-    }).catch(() => {})
+    }).catch(() => queueProgress(bookId, position))
     pendingSave = false
   }
 
@@ -97,13 +111,19 @@ export function useReadingProgress(bookId: number) {
     saveNow()
   }
 
+  function handleOnline() {
+    flushProgress(saveReadingProgress)
+  }
+
   onMounted(() => {
     window.addEventListener('beforeunload', handleBeforeUnload)
+    window.addEventListener('online', handleOnline)
   })
 
   onUnmounted(() => {
     saveNow()
     window.removeEventListener('beforeunload', handleBeforeUnload)
+    window.removeEventListener('online', handleOnline)
   })
 
   return {
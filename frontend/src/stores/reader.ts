@@ -1,6 +1,6 @@
 import { defineStore } from 'pinia'
 import { ref, computed, triggerRef } from 'vue'
-import type { BookContent, ChapterContent, ReaderSettings } from '@/types/reader'
+import type { BookContent, ChapterContent, Locator, ReaderSettings } from '@/types/reader'
 import { defaultSettings } from '@/types/reader'
 
 export const useReaderStore = defineStore('reader', () => {
@@ -28,6 +28,9 @@ export const useReaderStore = defineStore('reader', () => {
   // Navigation direction for chapter transitions ('backward' → open last page)
   const navigationDirection = ref<'forward' | 'backward'>('forward')
 
+  // Position to open once the next chapter is rendered (restored progress)
+  const pendingLocator = ref<Locator | null>(null)
+
   // Settings
   const settings = ref<ReaderSettings>({ ...defaultSettings })
 
@@ -181,6 +184,7 @@ export const useReaderStore = defineStore('reader', () => {
     uiVisible.value = true
     settingsVisible.value = false
     navigationDirection.value = 'forward'
+    pendingLocator.value = null
   }
 
   return {
@@ -198,6 +202,7 @@ export const useReaderStore = defineStore('reader', () => {
     uiVisible,
     settingsVisible,
     navigationDirection,
+    pendingLocator,
     settings,
 
     // Computed
@@ -45,7 +45,7 @@ export interface CustomCatalogColors {
 
 export type TabType = 'authors' | 'series' | 'genres' | 'search'
 
-export type SortField = 'title' | 'year' | 'file_size' | 'lang' | 'format'
+export type SortField = 'title' | 'year' | 'file_size' | 'lang' | 'format' | 'household_rate'
 export type SortOrder = 'asc' | 'desc'
 export type PageSize = 25 | 50 | 75 | 100
 
@@ -14,22 +14,67 @@ export interface TOCEntry {
   level: number
 }
 
+// Virtual chapter split from an oversized section; paragraphs are numbered
+// across the whole section.
+export interface ChapterPart {
+  section: string
+  firstParagraph: number
+}
+
 export interface BookContent {
   metadata: BookMetadata
   toc: TOCEntry[]
   chapters: string[]
   totalChapters: number
   chapterSizes?: Record<string, number>
+  parts?: Record<string, ChapterPart>
+  formatVersion?: number
+}
+
+export interface BookSearchHit {
+  chapterId: string
+  chapterTitle: string
+  locator: Locator
+  snippet: string
+  matchOffset: number
+  matchLength: number
+}
+
+export interface NoteContent {
+  id: string
+  title: string
+  html: string
+  formatVersion?: number
 }
 
 export interface ChapterContent {
   id: string
   title: string
   html: string
+  formatVersion?: number
+}
+
+// One line of the NDJSON stream returned by GET /books/:id/bundle. Items come
+// in order: content, chapters, notes, images, and an end item with totals
+// that marks a complete bundle.
+export type BundleItem =
+  | { type: 'content'; content: BookContent }
+  | { type: 'chapter'; chapter: ChapterContent }
+  | { type: 'note'; note: NoteContent }
+  | { type: 'image'; url: string; image: { id: string; contentType: string; data: string } }
+  | { type: 'end'; chapters?: number; notes?: number; images?: number }
+
+// Position in a chapter: paragraph index (data-p attribute) plus an offset
+// in UTF-16 code units. Offsets past the paragraph end continue into the
+// following paragraphs.
+export interface Locator {
+  paragraph: number
+  offset: number
 }
 
 export interface ReadingPosition {
   chapterId: string
+  locator?: Locator
   chapterProgress: number // 0-100
   totalProgress: number // 0-100
   device: string
@@ -107,3 +152,37 @@ export const fontFamilies = [
 ] as const
 
 export type FontFamily = (typeof fontFamilies)[number]
+
+export type AnnotationType = 'bookmark' | 'highlight' | 'note'
+
+export type AnnotationColor = 'yellow' | 'green' | 'blue' | 'pink' | 'purple' | 'orange'
+
+export interface TextLocator {
+  start: Locator
+  end: Locator
+  quote?: string
+  prefix?: string
+  suffix?: string
+}
+
+export interface Annotation {
+  id: number
+  bookId: number
+  type: AnnotationType
+  chapterId: string
+  locator: TextLocator
+  color?: AnnotationColor | ''
+  note?: string
+  createdAt: string
+  updatedAt: string
+}
+
+export interface CreateAnnotationInput {
+  type: AnnotationType
+  chapterId: string
+  locator: TextLocator
+  color?: AnnotationColor
+  note?: string
+}
+
+export type UpdateAnnotationInput = Partial<Pick<CreateAnnotationInput, 'locator' | 'color' | 'note'>>
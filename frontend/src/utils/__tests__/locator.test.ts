@@ -0,0 +1,85 @@
+import { describe, it, expect } from 'vitest'
+import { resolveLocator, locatorOf, resolveChapter } from '../locator'
+import type { BookContent } from '@/types/reader'
+
+function chapter(html: string): HTMLElement {
+  const el = document.createElement('div')
+  el.innerHTML = html
+  return el
+}
+
+const html =
+  '<h2 data-p="0">Глава</h2>' +
+  '<p data-p="1">Текст с <em>курсивом</em></p>' +
+  '<div class="footnote-body"><p>Сноска</p></div>' +
+  '<p data-p="2">Конец</p>'
+
+describe('resolveLocator', () => {
+  it('resolves an offset inside a paragraph', () => {
+    const pos = resolveLocator(chapter(html), { paragraph: 1, offset: 3 })
+    expect(pos?.node.data).toBe('Текст с ')
+    expect(pos?.offset).toBe(3)
+  })
+
+  it('descends into inline elements', () => {
+    const pos = resolveLocator(chapter(html), { paragraph: 1, offset: 10 })
+    expect(pos?.node.data).toBe('курсивом')
+    expect(pos?.offset).toBe(2)
+  })
+
+  it('continues past the paragraph end into following paragraphs', () => {
+    const pos = resolveLocator(chapter(html), { paragraph: 0, offset: 7 })
+    expect(pos?.node.data).toBe('Текст с ')
+    expect(pos?.offset).toBe(2)
+  })
+
+  it('clamps to the end of the chapter', () => {
+    const pos = resolveLocator(chapter(html), { paragraph: 9, offset: 0 })
+    expect(pos?.node.data).toBe('Конец')
+    expect(pos?.offset).toBe(5)
+  })
+
+  it('returns null for chapters without numbered paragraphs', () => {
+    expect(resolveLocator(chapter('<p>Старая глава</p>'), { paragraph: 0, offset: 0 })).toBeNull()
+  })
+})
+
+describe('locatorOf', () => {
+  it('is the inverse of resolveLocator', () => {
+    const el = chapter(html)
+    const pos = resolveLocator(el, { paragraph: 1, offset: 10 })!
+    expect(locatorOf(el, pos)).toEqual({ paragraph: 1, offset: 10 })
+  })
+
+  it('ignores text outside numbered paragraphs', () => {
+    const el = chapter(html)
+    const note = el.querySelector('.footnote-body p')!.firstChild as Text
+    expect(locatorOf(el, { node: note, offset: 0 })).toBeNull()
+  })
+})
+
+describe('resolveChapter', () => {
+  const content: BookContent = {
+    metadata: { title: 'Книга', author: '', cover: null, language: 'ru', format: 'fb2' },
+    toc: [],
+    chapters: ['s1', 's1-part2', 's1-part3', 's2'],
+    totalChapters: 4,
+    parts: {
+      s1: { section: 's1', firstParagraph: 0 },
+      's1-part2': { section: 's1', firstParagraph: 40 },
+      's1-part3': { section: 's1', firstParagraph: 90 },
+    },
+  }
+
+  it('maps a section position to the part holding the paragraph', () => {
+    expect(resolveChapter(content, 's1', 0)).toBe('s1')
+    expect(resolveChapter(content, 's1', 39)).toBe('s1')
+    expect(resolveChapter(content, 's1', 40)).toBe('s1-part2')
+    expect(resolveChapter(content, 's1', 120)).toBe('s1-part3')
+  })
+
+  it('keeps chapters that were not split', () => {
+    expect(resolveChapter(content, 's2', 5)).toBe('s2')
+    expect(resolveChapter({ ...content, parts: undefined }, 's1', 50)).toBe('s1')
+  })
+})
@@ -0,0 +1,50 @@
+import { describe, it, expect, vi, beforeEach } from 'vitest'
+import { flushProgress, queueProgress, queuedProgress } from '../offlineProgress'
+import type { ReadingPosition } from '@/types/reader'
+
+function position(chapterId: string, updatedAt?: string): ReadingPosition {
+  return { chapterId, chapterProgress: 10, totalProgress: 5, device: 'mobile', updatedAt }
+}
+
+describe('offlineProgress', () => {
+  beforeEach(() => {
+    localStorage.clear()
+  })
+
+  it('stamps queued positions with the current time', () => {
+    queueProgress(1, position('ch1'))
+    expect(queuedProgress(1)?.chapterId).toBe('ch1')
+    expect(queuedProgress(1)?.updatedAt).toMatch(/^\d{4}-\d{2}-\d{2}T/)
+    expect(queuedProgress(2)).toBeNull()
+  })
+
+  it('keeps only the latest position per book', () => {
+    queueProgress(1, position('ch1', '2026-01-01T00:00:00Z'))
+    queueProgress(1, position('ch2', '2026-01-01T00:01:00Z'))
+    expect(queuedProgress(1)?.chapterId).toBe('ch2')
+  })
+
+  it('sends queued positions and clears them', async () => {
+    queueProgress(1, position('ch1', '2026-01-01T00:00:00Z'))
+    queueProgress(2, position('ch3', '2026-01-01T00:00:00Z'))
+    const save = vi.fn().mockResolvedValue({})
+
+    await flushProgress(save)
+
+    expect(save).toHaveBeenCalledWith(1, position('ch1', '2026-01-01T00:00:00Z'))
+    expect(save).toHaveBeenCalledWith(2, position('ch3', '2026-01-01T00:00:00Z'))
+    expect(localStorage.getItem('homelib-offline-progress')).toBeNull()
+  })
+
+  it('drops positions the server rejects as stale', async () => {
+    queueProgress(1, position('ch1', '2026-01-01T00:00:00Z'))
+    await flushProgress(vi.fn().mockRejectedValue({ response: { status: 409 } }))
+    expect(queuedProgress(1)).toBeNull()
+  })
+
+  it('keeps positions while the network is down', async () => {
+    queueProgress(1, position('ch1', '2026-01-01T00:00:00Z'))
+    await flushProgress(vi.fn().mockRejectedValue(new Error('Network Error')))
+    expect(queuedProgress(1)?.chapterId).toBe('ch1')
+  })
+})
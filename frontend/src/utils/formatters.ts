@@ -37,3 +37,10 @@ export function formatFileSize(bytes?: number): string {
   if (bytes < 1024 * 1024) return `${(bytes / 1024).toFixed(0)} KB`
   return `${(bytes / (1024 * 1024)).toFixed(1)} MB`
 }
+
+// Book formats the built-in reader can open.
+export const READABLE_FORMATS = ['fb2', 'cbz', 'cbr', 'txt', 'htm', 'html', 'md', 'markdown', 'mobi', 'azw3', 'doc', 'docx', 'rtf']
+
+export function isReadableFormat(format: string | undefined): boolean {
+  return !!format && READABLE_FORMATS.includes(format.toLowerCase())
+}
@@ -0,0 +1,119 @@
+import type { BookContent, Locator } from '@/types/reader'
+
+// Attribute the backend puts on every text block of a chapter; its value is
+// the block's paragraph index.
+export const PARAGRAPH_ATTR = 'data-p'
+
+export interface TextPosition {
+  node: Text
+  offset: number
+}
+
+function paragraphs(container: HTMLElement): HTMLElement[] {
+  return Array.from(container.querySelectorAll<HTMLElement>(`[${PARAGRAPH_ATTR}]`))
+}
+
+function paragraphIndex(el: HTMLElement): number {
+  return Number(el.getAttribute(PARAGRAPH_ATTR))
+}
+
+function textNodes(el: HTMLElement): Text[] {
+  const nodes: Text[] = []
+  const walker = document.createTreeWalker(el, NodeFilter.SHOW_TEXT)
+  while (walker.nextNode()) nodes.push(walker.currentNode as Text)
+  return nodes
+}
+
+// Maps a locator to a position in the chapter DOM. Offsets are in UTF-16
+// code units; an offset past the end of its paragraph continues into the
+// following ones. Positions past the chapter end map to its last character.
+export function resolveLocator(container: HTMLElement, locator: Locator): TextPosition | null {
+  const paras = paragraphs(container)
+  if (paras.length === 0) return null
+
+  let start = paras.findIndex((p) => paragraphIndex(p) >= locator.paragraph)
+  if (start < 0) start = paras.length - 1
+  let rest = paragraphIndex(paras[start]) === locator.paragraph ? Math.max(0, locator.offset) : 0
+
+  let last: Text | null = null
+  for (let i = start; i < paras.length; i++) {
+    for (const node of textNodes(paras[i])) {
+      if (rest < node.data.length) return { node, offset: rest }
+      rest -= node.data.length
+      last = node
+    }
+  }
+  return last ? { node: last, offset: last.data.length } : null
+}
+
+// Returns the locator of a DOM position inside a numbered paragraph.
+export function locatorOf(container: HTMLElement, pos: TextPosition): Locator | null {
+  const para = pos.node.parentElement?.closest<HTMLElement>(`[${PARAGRAPH_ATTR}]`)
+  if (!para || !container.contains(para)) return null
+
+  let offset = 0
+  for (const node of textNodes(para)) {
+    if (node === pos.node) return { paragraph: paragraphIndex(para), offset: offset + pos.offset }
+    offset += node.data.length
+  }
+  return null
+}
+
+// Horizontal position of a character relative to the container's left edge.
+function charLeft(container: HTMLElement, pos: TextPosition): number {
+  const range = document.createRange()
+  range.setStart(pos.node, pos.offset)
+  range.setEnd(pos.node, Math.min(pos.offset + 1, pos.node.data.length))
+  return range.getBoundingClientRect().left - container.getBoundingClientRect().left
+}
+
+// Finds the locator of the first character at or after x (relative to the
+// container), i.e. the first character of the column starting at x.
+export function locatorAtX(container: HTMLElement, x: number): Locator | null {
+  const containerLeft = container.getBoundingClientRect().left
+  for (const para of paragraphs(container)) {
+    const rect = para.getBoundingClientRect()
+    if (rect.right - containerLeft <= x) continue
+    if (rect.left - containerLeft >= x) return { paragraph: paragraphIndex(para), offset: 0 }
+
+    // The paragraph starts on an earlier column: binary search for the
+    // first character that is laid out in this one.
+    const chars: TextPosition[] = []
+    for (const node of textNodes(para)) {
+      for (let i = 0; i < node.data.length; i++) chars.push({ node, offset: i })
+    }
+    let lo = 0
+    let hi = chars.length
+    while (lo < hi) {
+      const mid = (lo + hi) >> 1
+      if (charLeft(container, chars[mid]) >= x) hi = mid
+      else lo = mid + 1
+    }
+    if (lo === chars.length) continue
+    return locatorOf(container, chars[lo])
+  }
+  return null
+}
+
+// Horizontal position of a locator relative to the container.
+export function locatorX(container: HTMLElement, locator: Locator): number | null {
+  const pos = resolveLocator(container, locator)
+  if (!pos) return null
+  if (pos.offset >= pos.node.data.length && pos.offset > 0) {
+    return charLeft(container, { node: pos.node, offset: pos.offset - 1 })
+  }
+  return charLeft(container, pos)
+}
+
+// Returns the chapter that holds a paragraph of the given chapter. Positions
+// saved before a section was split into parts map to the part they fall into.
+export function resolveChapter(content: BookContent, chapterId: string, paragraph: number): string {
+  const part = content.parts?.[chapterId]
+  if (!part) return chapterId
+  let resolved = part.section
+  for (const id of content.chapters) {
+    const p = content.parts?.[id]
+    if (p && p.section === part.section && p.firstParagraph <= paragraph) resolved = id
+  }
+  return resolved
+}
@@ -0,0 +1,91 @@
+import type { BundleItem } from '@/types/reader'
+
+// Books saved for offline reading live in Cache Storage under the same URLs
+// the reader API uses, so lookups need no separate index.
+const CACHE_NAME = 'homelib-offline-books'
+
+const imageUrlPattern = /\/api\/books\/\d+\/image\/[A-Za-z0-9._-]+(?:\?v=[A-Za-z0-9]+)?/g
+
+function openCache(): Promise<Cache> | null {
+  if (typeof caches === 'undefined') return null
+  return caches.open(CACHE_NAME)
+}
+
+function jsonResponse(body: unknown): Response {
+  return new Response(JSON.stringify(body), { headers: { 'Content-Type': 'application/json' } })
+}
+
+function decodeBase64(data: string): Uint8Array {
+  const binary = atob(data)
+  const bytes = new Uint8Array(binary.length)
+  for (let i = 0; i < binary.length; i++) bytes[i] = binary.charCodeAt(i)
+  return bytes
+}
+
+// cacheBundleItem stores one item of GET /books/:id/bundle.
+export async function cacheBundleItem(bookId: number, item: BundleItem): Promise<void> {
+  const cache = await openCache()
+  if (!cache) return
+  switch (item.type) {
+    case 'content':
+      await cache.put(`/api/books/${bookId}/content`, jsonResponse(item.content))
+      break
+    case 'chapter':
+      await cache.put(`/api/books/${bookId}/chapter/${item.chapter.id}`, jsonResponse(item.chapter))
+      break
+    case 'note':
+      await cache.put(`/api/books/${bookId}/notes/${encodeURIComponent(item.note.id)}`, jsonResponse(item.note))
+      break
+    case 'image':
+      await cache.put(item.url, new Response(decodeBase64(item.image.data), {
+        headers: { 'Content-Type': item.image.contentType },
+      }))
+      break
+  }
+}
+
+// readOffline returns the saved copy of an API response, path being relative
+// to /api, or null if the book was not saved.
+export async function readOffline<T>(path: string): Promise<T | null> {
+  const cache = await openCache()
+  const response = await cache?.match(`/api${path}`)
+  if (!response) return null
+  return (await response.json()) as T
+}
+
+function toDataUrl(blob: Blob): Promise<string> {
+  return new Promise((resolve, reject) => {
+    const reader = new FileReader()
+    reader.onload = () => resolve(reader.result as string)
+    reader.onerror = () => reject(reader.error)
+    reader.readAsDataURL(blob)
+  })
+}
+
+// localizeImages inlines saved images in html as data URLs, since the browser
+// cannot fetch them from the server while offline. Data URLs rather than
+// object URLs, because the reader's sanitizer only lets the former through.
+export async function localizeImages(html: string): Promise<string> {
+  const cache = await openCache()
+  if (!cache) return html
+  const urls = new Map<string, string>()
+  for (const url of new Set(html.match(imageUrlPattern) ?? [])) {
+    const response = await cache.match(url)
+    if (response) urls.set(url, await toDataUrl(await response.blob()))
+  }
+  return html.replace(imageUrlPattern, (url) => urls.get(url) ?? url)
+}
+
+export async function isBookOffline(bookId: number): Promise<boolean> {
+  const cache = await openCache()
+  return !!(await cache?.match(`/api/books/${bookId}/content`))
+}
+
+export async function removeOfflineBook(bookId: number): Promise<void> {
+  const cache = await openCache()
+  if (!cache) return
+  const prefix = `/api/books/${bookId}/`
+  for (const request of await cache.keys()) {
+    if (new URL(request.url).pathname.startsWith(prefix)) await cache.delete(request)
+  }
+}
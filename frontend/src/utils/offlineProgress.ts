@@ -0,0 +1,56 @@
+import type { ReadingPosition } from '@/types/reader'
+
+// Positions that could not be saved while offline, kept in localStorage
+// until the connection is back. Only the latest position per book is kept.
+const STORAGE_KEY = 'homelib-offline-progress'
+
+type Queue = Record<number, ReadingPosition>
+
+function load(): Queue {
+  try {
+    return JSON.parse(localStorage.getItem(STORAGE_KEY) ?? '{}') as Queue
+  } catch {
+    return {}
+  }
+}
+
+function store(queue: Queue) {
+  if (Object.keys(queue).length) {
+    localStorage.setItem(STORAGE_KEY, JSON.stringify(queue))
+  } else {
+    localStorage.removeItem(STORAGE_KEY)
+  }
+}
+
+// queueProgress remembers position with the time it was reached, so that the
+// server can tell it from newer progress saved on other devices.
+export function queueProgress(bookId: number, position: ReadingPosition) {
+  const queue = load()
+  queue[bookId] = { ...position, updatedAt: position.updatedAt ?? new Date().toISOString() }
+  store(queue)
+}
+
+export function queuedProgress(bookId: number): ReadingPosition | null {
+  return load()[bookId] ?? null
+}
+
+// flushProgress sends the queued positions with save. Positions the server
+// rejects (e.g. 409 because it has newer progress) are dropped; ones that
+// fail with a network error stay queued.
+export async function flushProgress(
+  save: (bookId: number, position: ReadingPosition) => Promise<unknown>,
+): Promise<void> {
+  for (const [id, position] of Object.entries(load())) {
+    const bookId = Number(id)
+    try {
+      await save(bookId, position)
+    } catch (err) {
+      if (!(err as { response?: unknown }).response) continue
+    }
+    const queue = load()
+    if (queue[bookId]?.updatedAt === position.updatedAt) {
+      delete queue[bookId]
+      store(queue)
+    }
+  }
+}